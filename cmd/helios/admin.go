@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/doctor"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/store"
+	testvectors "github.com/holeyfield33-art/helios/test_vectors"
+)
+
+// serveConfig is the runtime configuration store serve publishes at
+// GET /config. It names the store as store serve prints it, without
+// credentials.
+type serveConfig struct {
+	Version       string `json:"version"`
+	Store         string `json:"store"`
+	Addr          string `json:"addr"`
+	Writable      bool   `json:"writable"`
+	Metrics       bool   `json:"metrics"`
+	Tenants       bool   `json:"tenants"`
+	MaxBody       int64  `json:"max_body"`
+	TLS           bool   `json:"tls"`
+	MutualTLS     bool   `json:"mutual_tls"`
+	IdentityRules int    `json:"identity_rules"`
+	AnomalyRules  int    `json:"anomaly_rules"`
+	ChangeFeed    bool   `json:"change_feed"`
+	Similar       bool   `json:"similar"`
+	CheckpointLog string `json:"checkpoint_log,omitempty"`
+	Rules         string `json:"rules,omitempty"`
+	// WriteProfile and RulesDigest are read from the store when the
+	// configuration is served, so they follow reloads of Rules.
+	WriteProfile string          `json:"write_profile"`
+	RulesDigest  string          `json:"rules_digest,omitempty"`
+	FIPS         hash.FIPSStatus `json:"fips"`
+
+	s *store.Store
+}
+
+func (c *serveConfig) MarshalJSON() ([]byte, error) {
+	type plain serveConfig
+	live := plain(*c)
+	live.WriteProfile = c.s.WriteProfile()
+	if r := c.s.WriteRules(); r != nil {
+		live.RulesDigest = r.Digest
+	}
+	return json.Marshal(live)
+}
+
+// selfTest runs selfcheck's checks for GET /readyz.
+func selfTest() error {
+	r := doctor.SelfCheck(doctor.Options{Version: version, Vectors: testvectors.Vectors})
+	var failed []string
+	for _, c := range r.Checks {
+		if c.Status == doctor.Fail {
+			failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Detail))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("selfcheck failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// runAdmin queries the admin endpoints of a running store serve.
+func runAdmin(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected an admin subcommand: health, ready, config, or reload")
+	}
+	paths := map[string]string{"health": "/healthz", "ready": "/readyz", "config": "/config", "reload": "/admin/reload"}
+	path, ok := paths[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown admin subcommand %q (want health, ready, config, or reload)", args[0])
+	}
+	method := http.MethodGet
+	if args[0] == "reload" {
+		method = http.MethodPost
+	}
+	fs := flag.NewFlagSet("admin "+args[0], flag.ContinueOnError)
+	url := fs.String("url", "http://127.0.0.1:8080", "base URL of the server")
+	caFile := fs.String("cacert", "", "verify an https server against the CAs in this PEM file instead of the system roots")
+	certFile := fs.String("cert", "", "present this PEM client certificate, for a server that requires mutual TLS")
+	keyFile := fs.String("key", "", "PEM private key of --cert")
+	timeout := fs.Duration("timeout", 10*time.Second, "give up after this long")
+	positional, err := parseFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		return fmt.Errorf("--cert and --key go together")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if *caFile != "" {
+		pem, err := os.ReadFile(*caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in %s", *caFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	if *certFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	client := &http.Client{Timeout: *timeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	req, err := http.NewRequest(method, strings.TrimSuffix(*url, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(body)
+
+	switch {
+	case resp.StatusCode == http.StatusServiceUnavailable && path == "/readyz":
+		var h store.HealthResponse
+		json.Unmarshal(body, &h)
+		var failed []string
+		for _, c := range h.Checks {
+			if !c.OK {
+				failed = append(failed, c.Name)
+			}
+		}
+		return fmt.Errorf("not ready: %s failed", strings.Join(failed, ", "))
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return nil
+}
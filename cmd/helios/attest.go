@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// runAttest signs an in-toto statement over the content hashes of the
+// objects in a file and prints the DSSE envelope.
+func runAttest(args []string) error {
+	fs := flag.NewFlagSet("attest", flag.ContinueOnError)
+	var keys stringList
+	fs.Var(&keys, "key", "PEM PKCS#8 private key (repeatable)")
+	out := fs.String("o", "", "write the envelope to this file instead of stdout")
+	var kl keylessOptions
+	useKeyless := fs.Bool("keyless", false, "sign with an ephemeral key certified against an OIDC identity")
+	fs.StringVar(&kl.fulcio, "fulcio", "", "Fulcio URL for --keyless")
+	fs.StringVar(&kl.rekor, "rekor", "", "Rekor URL for --keyless")
+	fs.StringVar(&kl.token, "identity-token", "", "OIDC token for --keyless (default: from the environment)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *useKeyless == (len(keys) > 0) {
+		return fmt.Errorf("exactly one of --key or --keyless is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one input file, got %d", len(positional))
+	}
+
+	objs, err := loadObjects(positional[0])
+	if err != nil {
+		return err
+	}
+	var signers []signing.Signer
+	for _, k := range keys {
+		s, err := signing.LoadSigner(k)
+		if err != nil {
+			return err
+		}
+		signers = append(signers, s)
+	}
+
+	st, err := attest.NewStatement(objs)
+	if err != nil {
+		return err
+	}
+	if *useKeyless {
+		res, err := signKeyless(st, kl)
+		if err != nil {
+			return err
+		}
+		return writeJSON(*out, res)
+	}
+	env, err := attest.Sign(st, signers...)
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, env)
+}
+
+// runVerifySig verifies a DSSE envelope against trusted public keys and,
+// when object files are given, checks their hashes against the subjects.
+func runVerifySig(args []string) error {
+	fs := flag.NewFlagSet("verify-sig", flag.ContinueOnError)
+	var pubs stringList
+	fs.Var(&pubs, "pub", "trusted PEM public key (repeatable)")
+	var kl keylessOptions
+	fs.StringVar(&kl.root, "fulcio-root", "", "trusted Fulcio root certificate for keyless bundles")
+	fs.StringVar(&kl.rekorKey, "rekor-key", "", "trusted Rekor public key for keyless bundles")
+	fs.StringVar(&kl.identity, "certificate-identity", "", "required email or URI in a keyless certificate")
+	fs.StringVar(&kl.issuer, "certificate-oidc-issuer", "", "required OIDC issuer in a keyless certificate")
+	policyPath := fs.String("policy", "", "trust policy file naming the signers that count and how many are required")
+	revocationsPath := fs.String("revocations", "", "signed revocation list; signatures by keys it revokes are rejected")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pubs) == 0 && kl.root == "" && *policyPath == "" {
+		return fmt.Errorf("--pub, --policy, or --fulcio-root is required")
+	}
+	if (*policyPath != "" || *revocationsPath != "") && kl.root != "" {
+		return fmt.Errorf("--policy and --revocations do not apply to keyless bundles")
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("expected an envelope file")
+	}
+
+	var keys []crypto.PublicKey
+	for _, p := range pubs {
+		k, err := signing.LoadPublicKey(p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, k)
+	}
+	policy, err := loadTrustPolicy(*policyPath)
+	if err != nil {
+		return err
+	}
+	revs, err := loadRevocations(*revocationsPath, keys, policy)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read envelope: %w", err)
+	}
+	var st *attest.Statement
+	if kl.root != "" {
+		st, err = verifyKeyless(data, kl)
+	} else {
+		var env attest.Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return fmt.Errorf("failed to parse envelope: %w", err)
+		}
+		if policy != nil {
+			var signers []string
+			st, signers, err = attest.VerifyPolicy(&env, policy, time.Now(), keys...)
+			if err == nil {
+				fmt.Printf("Signed by %s\n", strings.Join(signers, ", "))
+			}
+		} else {
+			err = verifyUnrevoked(revs, time.Now(), keys, func(keys ...crypto.PublicKey) error {
+				var err error
+				st, err = attest.Verify(&env, keys...)
+				return err
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, path := range positional[1:] {
+		objs, err := loadObjects(path)
+		if err != nil {
+			return err
+		}
+		if err := attest.CheckSubjects(st, objs); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	for _, s := range st.Subject {
+		fmt.Printf("  %s  %s\n", s.Digest["sha256"], s.Name)
+	}
+	fmt.Printf("\nSignature verified: %d subject(s)\n", len(st.Subject))
+	return nil
+}
+
+// keylessOptions carries the Sigstore flags for attest and verify-sig.
+type keylessOptions struct {
+	fulcio, rekor, token             string
+	root, rekorKey, identity, issuer string
+}
+
+// loadObjects reads every memory object in a single- or multi-object file.
+func loadObjects(path string) ([]object.MemoryObject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	objs, _, err := ingest.ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return objs, nil
+}
+
+// writeJSON writes v as indented JSON to path, or stdout when path is empty.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
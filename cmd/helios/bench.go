@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/bench"
+	"github.com/holeyfield33-art/helios/internal/corpusgen"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// runBench measures canonicalization over a corpus, or a generated one,
+// and with --compare-stdlib sets it against encoding/json.
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	compare := fs.Bool("compare-stdlib", false, "also marshal the corpus with encoding/json and report canonicalization's overhead")
+	seed := fs.Uint64("seed", 1, "seed of the generated corpus")
+	count := fs.Int("count", 1000, "number of generated objects")
+	benchtime := fs.Duration("benchtime", time.Second, "run each contender for at least this long")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	var objs []object.MemoryObject
+	switch len(positional) {
+	case 0:
+		if *count < 1 {
+			return fmt.Errorf("--count must be positive")
+		}
+		objs = corpusgen.Generate(*seed, *count)
+	case 1:
+		if objs, err = loadObjects(positional[0]); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("expected at most one corpus file, got %d", len(positional))
+	}
+
+	r, err := bench.Run(objs, bench.Options{MinTime: *benchtime, CompareStdlib: *compare})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON("", r)
+	}
+	fmt.Printf("%d objects (%s, %s)", r.Objects, r.Go, r.Platform)
+	if r.Skipped > 0 {
+		fmt.Printf(", %d rejected by canonicalization and skipped", r.Skipped)
+	}
+	fmt.Printf("\n\n%-14s %12s %12s %12s %12s\n", "", "ns/op", "allocs/op", "B/op", "MB/s out")
+	for _, res := range r.Results {
+		fmt.Printf("%-14s %12.0f %12.1f %12.0f %12.1f\n", res.Name, res.NsPerOp, res.AllocsPerOp, res.BytesPerOp, res.OutputMBPerSec)
+	}
+	if *compare {
+		fmt.Printf("\n%s takes %.2fx the time and %.1fx the allocations of %s\n", bench.Canonical, r.TimeOverhead, r.AllocOverhead, bench.Stdlib)
+	}
+	return nil
+}
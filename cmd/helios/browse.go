@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/browse"
+	"github.com/holeyfield33-art/helios/internal/corpus"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// runBrowse opens the terminal browser on a corpus, or with --store on a
+// store directory, whose objects are verified against the hashes they are
+// stored under as they load. --filter starts it with a filter expression,
+// which / changes.
+func runBrowse(args []string) error {
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	storeDir := fs.String("store", "", "browse the objects of this store directory instead of a corpus")
+	var where filterFlag
+	fs.Var(&where, "filter", filterUsage)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*storeDir == "") == (len(positional) == 0) || len(positional) > 1 {
+		return fmt.Errorf("expected either one corpus path or --store")
+	}
+
+	var items []browse.Item
+	title := *storeDir
+	if *storeDir != "" {
+		if items, err = storeItems(*storeDir); err != nil {
+			return err
+		}
+	} else {
+		title = positional[0]
+		records, err := corpus.Load(title)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			items = append(items, browse.Check(r.Object, "", r.Origin))
+		}
+	}
+	b, err := browse.New(title, items)
+	if err != nil {
+		return err
+	}
+	b.SetFilter(where.f)
+	return browse.Run(os.Stdin, os.Stdout, b)
+}
+
+// storeItems loads the current object of every key of the store at root.
+// An object that cannot be read is browsed with its key's index entry,
+// marked unreadable, rather than failing the whole browse.
+func storeItems(root string) ([]browse.Item, error) {
+	ctx := context.Background()
+	s, err := openStoreDir(root)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]browse.Item, 0, len(entries))
+	for _, e := range entries {
+		data, err := s.Get(ctx, e.Hash)
+		var obj object.MemoryObject
+		if err == nil {
+			obj, err = ingest.ParseObject(data)
+		}
+		if err != nil {
+			items = append(items, browse.Item{
+				Object: object.MemoryObject{Key: e.Key, Category: e.Category},
+				Hash:   e.Hash, Origin: root, Status: browse.Unreadable, Detail: err.Error(),
+			})
+			continue
+		}
+		obj.Tenant, obj.Residency, obj.LegalHold = s.TenantID(), e.Residency, e.LegalHold
+		items = append(items, browse.Check(obj, e.Hash, root))
+	}
+	return items, nil
+}
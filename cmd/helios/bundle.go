@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/bundle"
+	"github.com/holeyfield33-art/helios/internal/notify"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// runBundle packages objects, signatures, keys, and vectors into a single
+// archive for offline verification.
+func runBundle(args []string) error {
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	out := fs.String("o", "", "bundle output path (required)")
+	var sigs, pubs stringList
+	fs.Var(&sigs, "sig", "DSSE envelope to include (repeatable)")
+	fs.Var(&pubs, "pub", "PEM public key to include (repeatable)")
+	vectors := fs.String("vectors", "", "vectors.json to include")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("-o is required")
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("expected at least one input file")
+	}
+
+	var c bundle.Contents
+	for _, path := range positional {
+		objs, err := loadObjects(path)
+		if err != nil {
+			return err
+		}
+		c.Objects = append(c.Objects, objs...)
+	}
+	if c.Signatures, err = readAll(sigs); err != nil {
+		return err
+	}
+	if c.Keys, err = readAll(pubs); err != nil {
+		return err
+	}
+	for i, k := range c.Keys {
+		if _, err := signing.ParsePublicKey(k); err != nil {
+			return fmt.Errorf("%s: %w", pubs[i], err)
+		}
+	}
+	if *vectors != "" {
+		if c.Vectors, err = os.ReadFile(*vectors); err != nil {
+			return fmt.Errorf("failed to read vectors: %w", err)
+		}
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := bundle.Write(f, c); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s: %d object(s), %d signature(s), %d key(s)\n",
+		*out, len(c.Objects), len(c.Signatures), len(c.Keys))
+	return nil
+}
+
+// runVerifyBundle checks a bundle without network access.
+func runVerifyBundle(args []string) error {
+	fs := flag.NewFlagSet("verify-bundle", flag.ContinueOnError)
+	var pubs stringList
+	fs.Var(&pubs, "pub", "trusted PEM public key (repeatable)")
+	trustEmbedded := fs.Bool("trust-embedded", false, "also trust the keys packaged in the bundle")
+	policyPath := fs.String("policy", "", "trust policy file naming the signers that count and how many are required")
+	revocationsPath := fs.String("revocations", "", "signed revocation list; signatures by keys it revokes are rejected")
+	var hooks hookFlags
+	hooks.register(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one bundle file, got %d", len(positional))
+	}
+
+	opts := bundle.VerifyOptions{TrustEmbedded: *trustEmbedded}
+	for _, p := range pubs {
+		k, err := signing.LoadPublicKey(p)
+		if err != nil {
+			return err
+		}
+		opts.Keys = append(opts.Keys, k)
+	}
+	if opts.Policy, err = loadTrustPolicy(*policyPath); err != nil {
+		return err
+	}
+	if opts.Revocations, err = loadRevocations(*revocationsPath, opts.Keys, opts.Policy); err != nil {
+		return err
+	}
+
+	f, err := os.Open(positional[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := bundle.Read(f)
+	if err != nil {
+		return err
+	}
+
+	rep := bundle.Verify(b, opts)
+	events := make([]notify.Event, len(rep.Mismatches))
+	for i, m := range rep.Mismatches {
+		events[i] = notify.Event{
+			Kind:     notify.KindHashMismatch,
+			Source:   "verify-bundle " + positional[0],
+			Key:      m.Key,
+			Path:     m.Path,
+			Expected: m.Expected,
+			Actual:   m.Actual,
+		}
+	}
+	hooks.fire(events)
+	for _, p := range rep.Problems {
+		fmt.Printf("  FAIL  %s\n", p)
+	}
+	summary := fmt.Sprintf("%d object(s), %d signature(s), %d vector(s)", rep.Objects, rep.Signatures, rep.Vectors)
+	if !rep.OK() {
+		return fmt.Errorf("bundle verification failed: %d problem(s) in %s", len(rep.Problems), summary)
+	}
+	fmt.Printf("Bundle verified: %s\n", summary)
+	return nil
+}
+
+// readAll reads each named file.
+func readAll(paths []string) ([][]byte, error) {
+	var out [][]byte
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		out = append(out, data)
+	}
+	return out, nil
+}
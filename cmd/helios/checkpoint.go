@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/checkpoint"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/signing"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// defaultCheckpointLog is used when --log is not given and
+// $HELIOS_CHECKPOINT_LOG is unset.
+const defaultCheckpointLog = "helios-checkpoints.jsonl"
+
+func checkpointLogFlag(fs *flag.FlagSet) *string {
+	def := os.Getenv("HELIOS_CHECKPOINT_LOG")
+	if def == "" {
+		def = defaultCheckpointLog
+	}
+	return fs.String("log", def, "checkpoint transparency log file")
+}
+
+// runCheckpoint computes the Merkle root over a store's keys, signs it,
+// appends the checkpoint to the transparency log, and prints it.
+func runCheckpoint(args []string) error {
+	fs := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+	loc := addStoreFlags(fs)
+	var keys stringList
+	fs.Var(&keys, "key", "PEM PKCS#8 private key (repeatable)")
+	var keyShares stringList
+	fs.Var(&keyShares, "key-share", "share of a private key split with helios keys split (repeatable; a threshold of one key's shares signs as that key)")
+	logPath := checkpointLogFlag(fs)
+	host, _ := os.Hostname()
+	origin := fs.String("origin", host, "name of this store in the checkpoint")
+	out := fs.String("o", "", "also write the checkpoint to this file")
+	var witnesses stringList
+	fs.Var(&witnesses, "witness", "witness URL to ask for a cosignature (repeatable)")
+	threshold := fs.Int("threshold", -1, "cosignatures required before the checkpoint is logged (default: every --witness)")
+	timeout := fs.Duration("timeout", 30*time.Second, "witness request timeout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 && len(keyShares) == 0 {
+		return fmt.Errorf("--key or --key-share is required")
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+	var signers []signing.Signer
+	for _, k := range keys {
+		s, err := signing.LoadSigner(k)
+		if err != nil {
+			return err
+		}
+		signers = append(signers, s)
+	}
+	if len(keyShares) > 0 {
+		s, err := signing.LoadKeyShares(keyShares)
+		if err != nil {
+			return err
+		}
+		signers = append(signers, s)
+	}
+	log, err := checkpoint.OpenLog(*logPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	s, err := loc.open(ctx, false)
+	if err != nil {
+		return err
+	}
+	snap, err := checkpoint.Take(ctx, s)
+	if err != nil {
+		return err
+	}
+	c := checkpoint.New(*origin, snap, time.Now())
+	log.Next(c)
+	if err := c.Sign(signers...); err != nil {
+		return err
+	}
+	if len(witnesses) > 0 {
+		k := *threshold
+		if k < 0 {
+			k = len(witnesses)
+		}
+		if err := c.Witness(ctx, &http.Client{Timeout: *timeout}, witnesses, k, log); err != nil {
+			return err
+		}
+	}
+	if err := log.Append(c); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", *logPath, err)
+	}
+	if *out != "" {
+		if err := writeJSON(*out, c); err != nil {
+			return err
+		}
+	}
+	return writeJSON("", c)
+}
+
+// runVerifyCheckpoint checks a checkpoint's signature and that the store
+// still hashes to its root. The checkpoint is read from a file or, by
+// default, taken from the log; either way the log's chain is checked and
+// must contain it.
+func runVerifyCheckpoint(args []string) error {
+	fs := flag.NewFlagSet("verify-checkpoint", flag.ContinueOnError)
+	loc := addStoreFlags(fs)
+	var pubs stringList
+	fs.Var(&pubs, "pub", "trusted PEM public key (repeatable)")
+	logPath := checkpointLogFlag(fs)
+	seq := fs.Uint64("seq", 0, "verify this checkpoint of the log instead of the newest")
+	policyPath := fs.String("policy", "", "trust policy file naming the signers that count and how many are required")
+	revocationsPath := fs.String("revocations", "", "signed revocation list; signatures by keys it revokes as of their time are rejected")
+	wp := addWitnessPolicyFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pubs) == 0 && *policyPath == "" {
+		return fmt.Errorf("--pub or --policy is required")
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("expected at most one checkpoint file, got %d", len(positional))
+	}
+	keys, err := loadPublicKeys(pubs)
+	if err != nil {
+		return err
+	}
+	policy, err := loadTrustPolicy(*policyPath)
+	if err != nil {
+		return err
+	}
+	revs, err := loadRevocations(*revocationsPath, keys, policy)
+	if err != nil {
+		return err
+	}
+
+	log, err := checkpoint.OpenLog(*logPath)
+	if err != nil {
+		return err
+	}
+	var c *checkpoint.Checkpoint
+	switch {
+	case len(positional) == 1:
+		data, err := os.ReadFile(positional[0])
+		if err != nil {
+			return fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+		if err := json.Unmarshal(data, &c); err != nil {
+			return fmt.Errorf("failed to parse checkpoint: %w", err)
+		}
+		logged, err := log.Get(c.Seq)
+		if err != nil {
+			return err
+		}
+		if logged.ID() != c.ID() {
+			return fmt.Errorf("checkpoint %d in %s is %s, not %s: the log has been rewritten", c.Seq, *logPath, logged.ID(), c.ID())
+		}
+	case *seq != 0:
+		if c, err = log.Get(*seq); err != nil {
+			return err
+		}
+	default:
+		if c = log.Latest(); c == nil {
+			return fmt.Errorf("no checkpoints in %s", *logPath)
+		}
+	}
+	if err := wp.verify(c); err != nil {
+		return err
+	}
+	if err := verifyCheckpointSigners(c, keys, policy, revs, wp.vouches()); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	s, err := loc.open(ctx, false)
+	if err != nil {
+		return err
+	}
+	if err := checkpoint.Check(ctx, s, c); err != nil {
+		return err
+	}
+	fmt.Printf("Checkpoint %d verified: %d keys, root %s (%s, %s)\n", c.Seq, c.Size, c.Root, c.Origin, c.Time)
+	return nil
+}
+
+// runProve prints an inclusion proof of a key's current object, with the
+// checkpoint from the log that it checks out against.
+func runProve(args []string) error {
+	fs := flag.NewFlagSet("prove", flag.ContinueOnError)
+	loc := addStoreFlags(fs)
+	logPath := checkpointLogFlag(fs)
+	out := fs.String("o", "", "write the proof to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one key, got %d", len(positional))
+	}
+	log, err := checkpoint.OpenLog(*logPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	s, err := loc.open(ctx, false)
+	if err != nil {
+		return err
+	}
+	p, err := checkpoint.Prover{Log: log}.Prove(ctx, s, positional[0])
+	if errors.Is(err, store.ErrUncommitted) {
+		return fmt.Errorf("%w; run helios checkpoint first", err)
+	}
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, p)
+}
+
+// runVerifyProof checks a proof written by prove against its signed
+// checkpoint and, when object files are given, that one of them is the
+// proven object. It needs neither the store nor the log.
+func runVerifyProof(args []string) error {
+	fs := flag.NewFlagSet("verify-proof", flag.ContinueOnError)
+	var pubs stringList
+	fs.Var(&pubs, "pub", "trusted PEM public key (repeatable)")
+	policyPath := fs.String("policy", "", "trust policy file naming the signers that count and how many are required")
+	revocationsPath := fs.String("revocations", "", "signed revocation list; signatures by keys it revokes as of their time are rejected")
+	wp := addWitnessPolicyFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pubs) == 0 && *policyPath == "" {
+		return fmt.Errorf("--pub or --policy is required")
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("expected a proof file")
+	}
+	keys, err := loadPublicKeys(pubs)
+	if err != nil {
+		return err
+	}
+	policy, err := loadTrustPolicy(*policyPath)
+	if err != nil {
+		return err
+	}
+	revs, err := loadRevocations(*revocationsPath, keys, policy)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read proof: %w", err)
+	}
+	var p checkpoint.Proof
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("failed to parse proof: %w", err)
+	}
+	c := p.Checkpoint
+	if c == nil {
+		return fmt.Errorf("proof carries no checkpoint")
+	}
+	if err := wp.verify(c); err != nil {
+		return err
+	}
+	if err := verifyCheckpointSigners(c, keys, policy, revs, wp.vouches()); err != nil {
+		return err
+	}
+	if p.Size != c.Size {
+		return fmt.Errorf("proof is for a tree of %d keys, checkpoint %d has %d", p.Size, c.Seq, c.Size)
+	}
+	if err := checkpoint.VerifyProof(c.Root, &p, p.Key, p.Hash); err != nil {
+		return err
+	}
+	for _, path := range positional[1:] {
+		objs, err := loadObjects(path)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, obj := range objs {
+			h, err := hash.ContentHash(obj)
+			if err != nil {
+				return fmt.Errorf("%s: object %q: %w", path, obj.Key, err)
+			}
+			found = found || (obj.Key == p.Key && h == p.Hash)
+		}
+		if !found {
+			return fmt.Errorf("%s: no object with key %q and content hash %s", path, p.Key, p.Hash)
+		}
+	}
+	fmt.Printf("  %s  %s\n\nIncluded in checkpoint %d: %d keys, root %s (%s, %s)\n", p.Hash, p.Key, c.Seq, c.Size, c.Root, c.Origin, c.Time)
+	return nil
+}
+
+// witnessPolicy holds the flags that require witness cosignatures.
+type witnessPolicy struct {
+	pubs      stringList
+	threshold *int
+}
+
+func addWitnessPolicyFlags(fs *flag.FlagSet) *witnessPolicy {
+	wp := &witnessPolicy{}
+	fs.Var(&wp.pubs, "witness-pub", "trusted witness PEM public key (repeatable)")
+	wp.threshold = fs.Int("threshold", -1, "witness cosignatures required (default: every --witness-pub)")
+	return wp
+}
+
+// verify checks c against the policy; without --witness-pub it requires
+// nothing.
+func (wp *witnessPolicy) verify(c *checkpoint.Checkpoint) error {
+	if len(wp.pubs) == 0 {
+		if *wp.threshold > 0 {
+			return fmt.Errorf("--threshold needs --witness-pub")
+		}
+		return nil
+	}
+	keys, err := loadPublicKeys(wp.pubs)
+	if err != nil {
+		return err
+	}
+	k := *wp.threshold
+	if k < 0 {
+		k = len(keys)
+	}
+	return c.VerifyWitnesses(k, keys...)
+}
+
+// vouches reports whether a checkpoint that passed verify was cosigned by
+// at least one witness, and so carries a time a witness checked.
+func (wp *witnessPolicy) vouches() bool {
+	return len(wp.pubs) > 0 && *wp.threshold != 0
+}
+
+// verifyCheckpointSigners checks c's signatures against policy, or else
+// keys, rejecting those revs revokes. Revocations and policy windows apply
+// as of c's time only if witnessed says witnesses vouched for it, and as
+// of now otherwise, so a revoked key cannot backdate a checkpoint.
+func verifyCheckpointSigners(c *checkpoint.Checkpoint, keys []crypto.PublicKey, policy *signing.TrustPolicy, revs *signing.RevocationList, witnessed bool) error {
+	at := time.Now()
+	if witnessed {
+		var err error
+		if at, err = c.SignedAt(); err != nil {
+			return err
+		}
+	}
+	if policy != nil {
+		return c.VerifyPolicy(policy, at, keys...)
+	}
+	return verifyUnrevoked(revs, at, keys, func(keys ...crypto.PublicKey) error { return c.Verify(keys...) })
+}
+
+// loadTrustPolicy reads the trust policy a --policy flag names, if any.
+func loadTrustPolicy(path string) (*signing.TrustPolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return signing.LoadTrustPolicy(path)
+}
+
+// loadRevocations reads the revocation list a --revocations flag names,
+// if any, and checks that one of keys or of the policy's keys signed it.
+// The policy then honors it.
+func loadRevocations(path string, keys []crypto.PublicKey, policy *signing.TrustPolicy) (*signing.RevocationList, error) {
+	if path == "" {
+		return nil, nil
+	}
+	revs, err := signing.LoadRevocationList(path)
+	if err != nil {
+		return nil, err
+	}
+	trusted := keys
+	if policy != nil {
+		trusted = append(policy.Keys(), keys...)
+	}
+	if err := revs.Verify(trusted...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if policy != nil {
+		policy.Revocations = revs
+	}
+	return revs, nil
+}
+
+// verifyUnrevoked calls verify with the keys revs does not revoke for a
+// signature made at at, naming the revoked ones if it fails.
+func verifyUnrevoked(revs *signing.RevocationList, at time.Time, keys []crypto.PublicKey, verify func(...crypto.PublicKey) error) error {
+	kept, revoked := revs.Filter(at, keys...)
+	err := verify(kept...)
+	if err != nil && len(revoked) > 0 {
+		return fmt.Errorf("%w; %s", err, strings.Join(revoked, "; "))
+	}
+	return err
+}
+
+func loadPublicKeys(paths []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, p := range paths {
+		k, err := signing.LoadPublicKey(p)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// runWitness serves POST /cosign, cosigning the checkpoints of the
+// origins given with --trust.
+func runWitness(args []string) error {
+	fs := flag.NewFlagSet("witness", flag.ContinueOnError)
+	key := fs.String("key", "", "PEM PKCS#8 private key to cosign with")
+	var trust stringList
+	fs.Var(&trust, "trust", "ORIGIN=PUB: cosign checkpoints of ORIGIN signed by the PEM public key PUB (repeatable)")
+	state := fs.String("state", "helios-witness.json", "file recording the newest checkpoint cosigned for each log")
+	addr := fs.String("addr", "127.0.0.1:8081", "listen address")
+	maxSkew := fs.Duration("max-skew", checkpoint.DefaultMaxSkew, "refuse checkpoints dated further than this from the witness's clock")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *key == "" || len(trust) == 0 {
+		return fmt.Errorf("--key and at least one --trust are required")
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+	signer, err := signing.LoadSigner(*key)
+	if err != nil {
+		return err
+	}
+	origins := make(map[string][]crypto.PublicKey)
+	for _, t := range trust {
+		origin, path, ok := strings.Cut(t, "=")
+		if !ok || origin == "" {
+			return fmt.Errorf("invalid --trust %q (want ORIGIN=PUB)", t)
+		}
+		pub, err := signing.LoadPublicKey(path)
+		if err != nil {
+			return err
+		}
+		origins[origin] = append(origins[origin], pub)
+	}
+	w, err := checkpoint.NewWitness(checkpoint.WitnessOptions{Signer: signer, Origins: origins, StatePath: *state, MaxSkew: *maxSkew})
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           w,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "Witnessing %d origin(s) as %s on http://%s\n", len(origins), signer.KeyID(), *addr)
+	return srv.ListenAndServe()
+}
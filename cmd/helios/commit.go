@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// runCommit prints the salted commitment of an object, storing a fresh
+// salt in its commitment_salt field first if it has none and writing the
+// salted object to -o, or with --verify checks that the object, revealed
+// with its salt, opens a commitment.
+func runCommit(args []string) error {
+	fs := flag.NewFlagSet("commit", flag.ContinueOnError)
+	out := fs.String("o", "", "write the object with its commitment_salt to this file (required when the object has no salt yet)")
+	verify := fs.String("verify", "", "check that the object opens this commitment instead of committing")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one object file, got %d arguments", len(positional))
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	obj, err := ingest.ParseObject(data)
+	if err != nil {
+		return err
+	}
+
+	if *verify != "" {
+		if *out != "" {
+			return fmt.Errorf("-o is not used with --verify")
+		}
+		if err := hash.VerifyCommitment(*verify, obj); err != nil {
+			return err
+		}
+		fmt.Println("OK: the object opens the commitment")
+		return nil
+	}
+
+	// A salt that is not kept cannot open the commitment later.
+	if obj.CommitmentSalt == "" && *out == "" {
+		return fmt.Errorf("the object has no commitment_salt; pass -o to keep the salt this commitment draws")
+	}
+	commitment, err := hash.Commit(&obj)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		if err := writeJSON(*out, obj); err != nil {
+			return err
+		}
+	}
+	fmt.Println(commitment)
+	return nil
+}
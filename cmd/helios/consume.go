@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/consume"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/kafka"
+)
+
+// runConsume validates and hashes every message on a Kafka topic, writing
+// a result per message to an output topic (or stdout) until interrupted.
+func runConsume(args []string) error {
+	fs := flag.NewFlagSet("consume", flag.ContinueOnError)
+	brokers := fs.String("brokers", "", "comma-separated bootstrap brokers (host:port)")
+	topic := fs.String("topic", "", "topic of memory objects to consume")
+	group := fs.String("group", "", "consumer group for committed offsets (default: no commits)")
+	fromBeginning := fs.Bool("from-beginning", false, "start partitions without a committed offset at the earliest message")
+	outTopic := fs.String("output-topic", "", "topic for results (default: NDJSON on stdout)")
+	rejectTopic := fs.String("reject-topic", "", "topic for rejected messages (default: the output topic)")
+	metricsAddr := fs.String("metrics-addr", "", "serve Prometheus metrics at http://ADDR/metrics")
+	statsEvery := fs.Duration("stats-interval", 0, "print throughput and lag to stderr at this interval")
+	cacheSize := fs.Int("cache-size", 0, "memoize hashes of up to N identical message values (0 disables)")
+	maxWait := fs.Duration("max-wait", 500*time.Millisecond, "how long a fetch waits for new messages")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(positional, " "))
+	}
+	if *brokers == "" || *topic == "" {
+		return fmt.Errorf("--brokers and --topic are required")
+	}
+	if *rejectTopic != "" && *outTopic == "" {
+		return fmt.Errorf("--reject-topic requires --output-topic")
+	}
+
+	client, err := kafka.Dial(kafka.Config{Brokers: strings.Split(*brokers, ",")})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	metrics := consume.NewMetrics()
+	var cache *hash.Cache
+	if *cacheSize > 0 {
+		cache = hash.NewCache(*cacheSize, 0)
+	}
+	cfg := consume.Config{
+		Topic:         *topic,
+		Group:         *group,
+		FromBeginning: *fromBeginning,
+		OutputTopic:   *outTopic,
+		RejectTopic:   *rejectTopic,
+		Output:        os.Stdout,
+		MaxWait:       *maxWait,
+		Metrics:       metrics,
+		Cache:         cache,
+		Logf: func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, "consume: "+format+"\n", args...)
+		},
+	}
+	c, err := consume.New(client, cfg)
+	if err != nil {
+		return err
+	}
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		srv := &http.Server{Addr: *metricsAddr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(os.Stderr, "consume: metrics server: %v\n", err)
+			}
+		}()
+		defer srv.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *statsEvery > 0 {
+		go func() {
+			t := time.NewTicker(*statsEvery)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					s := metrics.Snapshot()
+					fmt.Fprintf(os.Stderr, "consume: accepted=%d rejected=%d lag=%d throughput=%.1f/s\n",
+						s.Accepted, s.Rejected, s.Lag, s.Throughput)
+				}
+			}
+		}()
+	}
+	return c.Run(ctx)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/holeyfield33-art/helios/internal/verify"
+)
+
+// runCoverage prints the matrix of spec rules against the vectors and Go
+// tests that exercise them.
+func runCoverage(args []string) error {
+	fs := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	tests := fs.String("tests", ".", "scan the Go tests under this directory for rule mentions (empty: skip)")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	markdown := fs.Bool("markdown", false, "print the matrix as a Markdown table, for spec reviews")
+	strict := fs.Bool("strict", false, "fail if a rule is uncovered or a vector is dead")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *asJSON && *markdown {
+		return fmt.Errorf("--json and --markdown are exclusive")
+	}
+	if len(positional) == 0 {
+		// Every vectors file in test_vectors; single vectors and the
+		// retired format have no spec_version.
+		positional, _ = filepath.Glob(filepath.Join("test_vectors", "*.json"))
+		if len(positional) == 0 {
+			return fmt.Errorf("no vectors files given and none found in test_vectors")
+		}
+	}
+
+	var files []verify.NamedVectors
+	for _, p := range positional {
+		vf, err := verify.LoadVectors(p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if vf.SpecVersion == "" || len(vf.Vectors) == 0 {
+			continue
+		}
+		files = append(files, verify.NamedVectors{Path: p, File: vf})
+	}
+	found := map[string][]string{}
+	if *tests != "" {
+		if found, err = verify.ScanTestRules(*tests); err != nil {
+			return err
+		}
+	}
+	rep := verify.Coverage(files, found)
+
+	switch {
+	case *asJSON:
+		if err := writeJSON("", rep); err != nil {
+			return err
+		}
+	case *markdown:
+		fmt.Println("| Rule | Section | Title | Vectors | Tests |")
+		fmt.Println("| --- | --- | --- | --- | --- |")
+		for _, r := range rep.Rules {
+			fmt.Printf("| %s | %s | %s | %s | %s |\n", r.ID, r.Section, r.Title, orNone(r.Vectors), orNone(r.Tests))
+		}
+	default:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "RULE\tSECTION\tVECTORS\tTESTS\tTITLE")
+		for _, r := range rep.Rules {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", r.ID, r.Section, len(r.Vectors), len(r.Tests), r.Title)
+		}
+		tw.Flush()
+	}
+	if !*asJSON {
+		for _, id := range rep.Uncovered {
+			fmt.Fprintf(os.Stderr, "uncovered: %s has no vectors\n", id)
+		}
+		for _, d := range rep.DeadVectors {
+			fmt.Fprintf(os.Stderr, "dead vector: %s %s: %s\n", d.File, d.VectorID, d.Reason)
+		}
+	}
+	if *strict && len(rep.Uncovered)+len(rep.DeadVectors) > 0 {
+		return fmt.Errorf("%d uncovered rules, %d dead vectors", len(rep.Uncovered), len(rep.DeadVectors))
+	}
+	return nil
+}
+
+func orNone(s []string) string {
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, ", ")
+}
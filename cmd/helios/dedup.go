@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/corpus"
+	"github.com/holeyfield33-art/helios/internal/dedup"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/tabular"
+)
+
+type dedupMember struct {
+	Key    string `json:"key"`
+	Origin string `json:"origin"`
+	Hash   string `json:"hash"`
+	Keep   bool   `json:"keep"`
+}
+
+type dedupCluster struct {
+	Fingerprint string        `json:"fingerprint"`
+	Members     []dedupMember `json:"members"`
+}
+
+// runDedup reports clusters of objects in a corpus whose content is
+// identical apart from their key, with a suggested merge target for each.
+// With --filter, only the objects matching the expression are compared.
+// --format csv or tsv writes a row per object, numbered by cluster.
+func runDedup(args []string) error {
+	fs := flag.NewFlagSet("dedup", flag.ContinueOnError)
+	ignoreCreatedAt := fs.Bool("ignore-created-at", false, "also ignore created_at when comparing content")
+	output := addReportFlags(fs)
+	var where filterFlag
+	fs.Var(&where, "filter", filterUsage)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one corpus path, got %d", len(positional))
+	}
+	format, err := output.get()
+	if err != nil {
+		return err
+	}
+
+	all, err := corpus.Load(positional[0])
+	if err != nil {
+		return err
+	}
+	var records []ingest.Record
+	var objs []object.MemoryObject
+	for _, r := range all {
+		if where.match(r.Object) {
+			records = append(records, r)
+			objs = append(objs, r.Object)
+		}
+	}
+
+	clusters, err := dedup.Find(objs, dedup.Options{IgnoreCreatedAt: *ignoreCreatedAt})
+	if err != nil {
+		return err
+	}
+
+	report := make([]dedupCluster, 0, len(clusters))
+	redundant := 0
+	for _, c := range clusters {
+		dc := dedupCluster{Fingerprint: c.Fingerprint}
+		for _, i := range c.Indexes {
+			h, err := hash.ContentHash(objs[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", records[i].Origin, err)
+			}
+			dc.Members = append(dc.Members, dedupMember{
+				Key:    objs[i].Key,
+				Origin: records[i].Origin,
+				Hash:   h,
+				Keep:   i == c.Keep,
+			})
+		}
+		redundant += len(c.Indexes) - 1
+		report = append(report, dc)
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "csv", "tsv":
+		t := tabular.New("cluster", "fingerprint", "key", "origin", "hash", "keep")
+		for n, c := range report {
+			for _, m := range c.Members {
+				t.Add(n+1, c.Fingerprint, m.Key, m.Origin, m.Hash, m.Keep)
+			}
+		}
+		return tabular.Write(os.Stdout, t, format)
+	}
+
+	for n, c := range report {
+		fmt.Printf("cluster %d: %d objects (fingerprint %s)\n", n+1, len(c.Members), c.Fingerprint)
+		for _, m := range c.Members {
+			action := "merge"
+			if m.Keep {
+				action = "keep "
+			}
+			fmt.Printf("  %s %s  %s\n", action, m.Key, m.Origin)
+		}
+	}
+	fmt.Printf("\n%d duplicate cluster(s), %d redundant object(s) in %d\n", len(report), redundant, len(records))
+	return nil
+}
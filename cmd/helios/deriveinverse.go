@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/corpus"
+	"github.com/holeyfield33-art/helios/internal/graph"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// runDeriveInverse reports the inverse edges a corpus lacks, or writes
+// the revisions of its objects that add them.
+func runDeriveInverse(args []string) error {
+	fs := flag.NewFlagSet("derive-inverse", flag.ContinueOnError)
+	typesPath := fs.String("types", "", "JSON object mapping each relationship type to its inverse, e.g. {\"cites\": \"cited_by\"} (required)")
+	out := fs.String("o", "", "write the revised objects as NDJSON to this file instead of reporting the missing edges")
+	asJSON := fs.Bool("json", false, "print the report, with missing and dangling edges, as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one corpus, got %d", len(positional))
+	}
+	if *typesPath == "" {
+		return fmt.Errorf("--types is required")
+	}
+	if *out != "" && *asJSON {
+		return fmt.Errorf("--json and -o cannot be combined")
+	}
+	types, err := graph.LoadInverseTypes(*typesPath)
+	if err != nil {
+		return err
+	}
+	records, err := corpus.Load(positional[0])
+	if err != nil {
+		return err
+	}
+	objs := make([]object.MemoryObject, len(records))
+	for i, r := range records {
+		objs[i] = r.Object
+	}
+
+	report := graph.DeriveInverses(objs, types)
+	if *asJSON {
+		return writeJSON("", report)
+	}
+	for _, e := range report.Dangling {
+		fmt.Fprintf(os.Stderr, "warning: %q is not in the corpus; cannot add its %s edge to %q\n", e.From, e.Type, e.Key)
+	}
+	if *out != "" {
+		revised := graph.Revise(objs, report.Missing)
+		if err := writeCorpus(*out, revised); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %d revised objects with %d inverse edges to %s\n", len(revised), len(report.Missing), *out)
+		return nil
+	}
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, e := range report.Missing {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d missing inverse edges, %d dangling\n", len(report.Missing), len(report.Dangling))
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/batch"
+	"github.com/holeyfield33-art/helios/internal/corpus"
+)
+
+// runDifftest runs this binary and another helios binary over the same
+// corpus and reports every object whose hash differs between them.
+func runDifftest(args []string) error {
+	fs := flag.NewFlagSet("difftest", flag.ContinueOnError)
+	other := fs.String("other", "", "path to the helios binary to compare against")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *other == "" {
+		return fmt.Errorf("--other is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one corpus path, got %d", len(positional))
+	}
+	corpus := positional[0]
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot locate this binary: %w", err)
+	}
+	ours, err := batchResults(self, corpus)
+	if err != nil {
+		return fmt.Errorf("this binary: %w", err)
+	}
+	theirs, err := batchResults(*other, corpus)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *other, err)
+	}
+
+	mismatches, err := batch.Diff(ours, theirs)
+	if err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		fmt.Printf("MISMATCH [%d] %s  %s\n", m.Index, m.Key, m.Ours.Origin)
+		fmt.Printf("  ours:   %s%s\n", m.Ours.Hash, m.Ours.Error)
+		fmt.Printf("  theirs: %s%s\n", m.Theirs.Hash, m.Theirs.Error)
+		if m.Offset >= 0 {
+			fmt.Printf("  canonical bytes diverge at offset %d:\n", m.Offset)
+			fmt.Printf("    ours:   %q\n", batch.Context(m.Ours.Canonical, m.Offset, 24))
+			fmt.Printf("    theirs: %q\n", batch.Context(m.Theirs.Canonical, m.Offset, 24))
+		}
+	}
+
+	fmt.Printf("\n%d objects compared, %d mismatches\n", len(ours), len(mismatches))
+	if len(mismatches) > 0 {
+		return fmt.Errorf("binaries disagree on %d of %d objects", len(mismatches), len(ours))
+	}
+	return nil
+}
+
+// batchResults runs `bin hash-batch --continue-on-error corpus`, so
+// invalid objects are compared rather than ending the run. Binaries whose
+// hash-batch predates --continue-on-error run without it, and binaries
+// that predate hash-batch are driven one object at a time through `bin
+// hash`, in which case canonical bytes are not available for that side.
+func batchResults(bin, corpus string) ([]batch.Result, error) {
+	results, stderr, err := runBatch(bin, "hash-batch", "--continue-on-error", corpus)
+	if err != nil && strings.Contains(stderr, "flag provided but not defined") {
+		results, stderr, err = runBatch(bin, "hash-batch", corpus)
+	}
+	if err != nil && strings.Contains(stderr, "Unknown command") {
+		return perObjectResults(bin, corpus)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hash-batch failed: %v: %s", err, stderr)
+	}
+	return results, nil
+}
+
+// runBatch runs bin with args and reads its NDJSON results. Exit code 2,
+// with which hash-batch --continue-on-error reports invalid objects, is
+// not a failure.
+func runBatch(bin string, args ...string) ([]batch.Result, string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if err != nil && !(errors.As(err, &ee) && ee.ExitCode() == 2) {
+		return nil, strings.TrimSpace(stderr.String()), err
+	}
+	results, err := batch.Read(&stdout)
+	return results, strings.TrimSpace(stderr.String()), err
+}
+
+// perObjectResults hashes each object of the corpus at path with `bin
+// hash`, feeding it the object's bytes as read so that both binaries see
+// the same input.
+func perObjectResults(bin, path string) ([]batch.Result, error) {
+	records, err := corpus.LoadRaw(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "helios-difftest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	results := make([]batch.Result, len(records))
+	for i, rec := range records {
+		path := filepath.Join(dir, "object.json")
+		if err := os.WriteFile(path, rec.Raw, 0600); err != nil {
+			return nil, err
+		}
+		res := batch.Result{Index: i, Origin: rec.Origin, Key: rec.Object.Key}
+		out, err := hashFile(bin, path)
+		if err != nil {
+			res.Status = batch.StatusInvalid
+			res.Error = err.Error()
+		} else {
+			res.Status = batch.StatusOK
+			res.Hash = out
+		}
+		results[i] = res
+	}
+	return results, nil
+}
+
+// hashFile returns the hash `bin hash path` prints, or its complaint.
+func hashFile(bin, path string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, "hash", path)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
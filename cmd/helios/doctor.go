@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/doctor"
+	testvectors "github.com/holeyfield33-art/helios/test_vectors"
+)
+
+// runDoctor checks the environment for the usual causes of hash
+// mismatches between machines and runs the built-in vectors.
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	root := fs.String("root", defaultStoreRoot, "store directory whose filesystem is probed")
+	clockURL := fs.String("clock-url", "", "compare the local clock with this server's Date header")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+
+	r := doctor.Run(context.Background(), doctor.Options{
+		Version:  version,
+		Dir:      *root,
+		ClockURL: *clockURL,
+		Vectors:  testvectors.Vectors,
+	})
+	if err := printReport(r, *asJSON); err != nil {
+		return err
+	}
+	if r.Failed() {
+		return fmt.Errorf("doctor found problems that will change hashes")
+	}
+	return nil
+}
+
+// runSelfCheck checks that this build hashes as every other does: its
+// Unicode tables, key order, the built-in vectors, and the portability
+// probes for integer width, number parsing, and byte order.
+func runSelfCheck(args []string) error {
+	fs := flag.NewFlagSet("selfcheck", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+
+	r := doctor.SelfCheck(doctor.Options{Version: version, Vectors: testvectors.Vectors})
+	if err := printReport(r, *asJSON); err != nil {
+		return err
+	}
+	if r.Failed() {
+		return fmt.Errorf("selfcheck failed: this build's hashes differ from other platforms'")
+	}
+	return nil
+}
+
+func printReport(r *doctor.Report, asJSON bool) error {
+	if asJSON {
+		return writeJSON("", r)
+	}
+	fmt.Printf("helios %s (%s, %s)\n\n", r.Version, r.Go, r.Platform)
+	for _, c := range r.Checks {
+		fmt.Printf("  %-4s  %-10s  %s\n", strings.ToUpper(string(c.Status)), c.Name, c.Detail)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/verify"
+)
+
+// runEvolve compares two hashing profiles, reports the rules that changed
+// and the vectors of a vectors file the change affects, and writes
+// migration notes and a candidate vectors file moved to the new profile.
+func runEvolve(args []string) error {
+	fs := flag.NewFlagSet("evolve", flag.ContinueOnError)
+	from := fs.String("from", "", "the old profile: a profile hash or a profile JSON file (default: the vectors file's profile)")
+	to := fs.String("to", "", "the new profile: a profile hash or a profile JSON file")
+	notes := fs.String("notes", "", "write Markdown migration notes to this file")
+	out := fs.String("o", "", "write the candidate vectors file, re-frozen under the new profile, to this file (requires --reason)")
+	reason := fs.String("reason", "", "why the vectors are re-frozen, recorded in the candidate's refreezes")
+	asJSON := fs.Bool("json", false, "print the evolution as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected one vectors file, got %d arguments", len(positional))
+	}
+	if *to == "" {
+		return fmt.Errorf("--to is required")
+	}
+	if *out != "" && *reason == "" {
+		return fmt.Errorf("-o requires --reason explaining the profile change")
+	}
+	if *out == "" && *reason != "" {
+		return fmt.Errorf("--reason is only used with -o")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read vectors file: %w", err)
+	}
+	vf, err := verify.ParseVectors(data)
+	if err != nil {
+		return err
+	}
+	var fromProf hash.Profile
+	if *from == "" {
+		fromProf, err = vf.Profile()
+	} else {
+		fromProf, err = readProfile(*from)
+	}
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	toProf, err := readProfile(*to)
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+
+	evo, err := verify.Evolve(vf, fromProf, toProf)
+	if err != nil {
+		return err
+	}
+	if *notes != "" {
+		if err := os.WriteFile(*notes, []byte(evo.MigrationNotes()), 0o644); err != nil {
+			return err
+		}
+	}
+	if *out != "" {
+		cand, err := evo.Candidate(data, *reason, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, cand, 0o644); err != nil {
+			return err
+		}
+	}
+	if *asJSON {
+		return writeJSON("", evo)
+	}
+
+	if len(evo.Rules) == 0 {
+		fmt.Println("The profiles are identical; no vector is affected")
+		return nil
+	}
+	for _, r := range evo.Rules {
+		fmt.Printf("%-16s  %q -> %q\n", r.Rule, r.Old, r.New)
+	}
+	how := "predicted"
+	if evo.Exact {
+		how = "exact"
+	}
+	fmt.Printf("\n%d of %d vectors affected (%s)\n", len(evo.Vectors), evo.Checked, how)
+	for _, v := range evo.Vectors {
+		fmt.Printf("  %-10s  %s\n", v.Impact, v.VectorID)
+	}
+	if *out != "" {
+		fmt.Printf("\nWrote the candidate vectors file to %s\n", *out)
+	}
+	return nil
+}
+
+// readProfile resolves a profile hash this build knows, or reads a
+// profile from a JSON file.
+func readProfile(arg string) (hash.Profile, error) {
+	if p, err := hash.LookupID(arg); err == nil {
+		return p.Profile, nil
+	}
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return hash.Profile{}, fmt.Errorf("%q is neither a known profile hash nor a readable file: %w", arg, err)
+	}
+	var prof hash.Profile
+	if err := json.Unmarshal(data, &prof); err != nil {
+		return hash.Profile{}, fmt.Errorf("%s: %w", arg, err)
+	}
+	return prof, nil
+}
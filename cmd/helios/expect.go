@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/batch"
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// checkExpected fails unless got, the hash of obj, is expected. Given the
+// canonical bytes the expected hash was computed from, it explains a
+// mismatch: where obj's canonical form first diverges from them and
+// which fields differ.
+func checkExpected(obj object.MemoryObject, got, expected, referencePath string) error {
+	if !store.ValidHash(expected) {
+		return fmt.Errorf("--expect %q is not a lower-case hex SHA-256 hash", expected)
+	}
+	if hash.Equal(expected, got) {
+		return nil
+	}
+	mismatch := fmt.Errorf("hash mismatch: got %s, expected %s", got, expected)
+	if referencePath == "" {
+		return mismatch
+	}
+
+	ref, err := os.ReadFile(referencePath)
+	if err != nil {
+		return fmt.Errorf("failed to read reference: %w", err)
+	}
+	ref = bytes.TrimSuffix(ref, []byte("\n"))
+	if h := hash.V1.Sum(ref); !hash.Equal(expected, h) {
+		fmt.Printf("\nthe reference hashes to %s, not the expected hash, so it cannot explain the mismatch\n", h)
+		return mismatch
+	}
+	ours, err := hash.CanonicalBytes(obj)
+	if err != nil {
+		return err
+	}
+	offset := batch.FirstDifference(ours, ref)
+	fmt.Printf("\ncanonical bytes diverge from the reference at offset %d:\n", offset)
+	fmt.Printf("  ours:      %q\n", batch.Context(string(ours), offset, 24))
+	fmt.Printf("  reference: %q\n", batch.Context(string(ref), offset, 24))
+
+	fields, err := fieldDiff(ours, ref)
+	if err != nil {
+		fmt.Printf("the reference is not a JSON object, so fields cannot be compared: %v\n", err)
+		return mismatch
+	}
+	for _, f := range fields {
+		fmt.Printf("  %s\n", f)
+	}
+	return mismatch
+}
+
+// fieldDiff lists the top-level fields whose canonical forms differ
+// between two canonical objects, showing both values when they are short.
+func fieldDiff(ours, ref []byte) ([]string, error) {
+	a, err := ingest.DecodeObject(ours)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ingest.DecodeObject(ref)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		names = append(names, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	const short = 60
+	var out []string
+	for _, k := range names {
+		va, inA := a[k]
+		vb, inB := b[k]
+		switch {
+		case !inB:
+			out = append(out, fmt.Sprintf("%s: only in ours", k))
+			continue
+		case !inA:
+			out = append(out, fmt.Sprintf("%s: only in the reference", k))
+			continue
+		}
+		ca, _ := canon.CanonicalizeValue(va)
+		cb, _ := canon.CanonicalizeValue(vb)
+		if bytes.Equal(ca, cb) {
+			continue
+		}
+		if len(ca) <= short && len(cb) <= short {
+			out = append(out, fmt.Sprintf("%s: ours %s, reference %s", k, ca, cb))
+		} else {
+			out = append(out, fmt.Sprintf("%s differs at byte %d of the field", k, batch.FirstDifference(ca, cb)))
+		}
+	}
+	return out, nil
+}
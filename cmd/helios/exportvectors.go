@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/codegen"
+)
+
+// runExportVectors renders the vector corpus as test fixtures for another
+// language implementation.
+func runExportVectors(args []string) error {
+	fs := flag.NewFlagSet("export-vectors", flag.ContinueOnError)
+	lang := fs.String("lang", "", "target: "+strings.Join(codegen.Languages(), ", "))
+	module := fs.String("module", "", "module under test, for targets that import it")
+	out := fs.String("o", "", "write fixtures to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *lang == "" {
+		return fmt.Errorf("--lang is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one vectors file, got %d", len(positional))
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read vectors file: %w", err)
+	}
+	src, err := codegen.Parse(data)
+	if err != nil {
+		return err
+	}
+	code, err := codegen.Generate(src, *lang, codegen.Options{Module: *module})
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(*out, code, 0644)
+}
@@ -0,0 +1,8 @@
+//go:build fips
+
+//go:debug fips140=on
+
+package main
+
+// The fips build runs the Go Cryptographic Module in FIPS 140-3 mode
+// unless GODEBUG overrides it; see hash.FIPSStatus.
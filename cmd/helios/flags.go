@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/filter"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/tabular"
+)
+
+// parseFlags parses args with fs, allowing flags to appear before or after
+// positional arguments (e.g. "helios verify vectors.json --sort-by name").
+// It returns the positional arguments in order.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if rest[0] == "--" {
+			return append(positional, rest[1:]...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// filterUsage is the usage of a --filter flag.
+const filterUsage = `only objects matching this filter expression, e.g. 'category == "project" && created_at > "2025-01-01"'`
+
+// filterFlag is a --filter expression, parsed as it is set.
+type filterFlag struct{ f *filter.Filter }
+
+func (f *filterFlag) String() string {
+	if f.f == nil {
+		return ""
+	}
+	return f.f.String()
+}
+
+func (f *filterFlag) Set(v string) error {
+	parsed, err := filter.Parse(v)
+	if err != nil {
+		return err
+	}
+	f.f = parsed
+	return nil
+}
+
+// match reports whether obj passes the filter, as every object does if
+// none was given.
+func (f *filterFlag) match(obj object.MemoryObject) bool {
+	return f.f == nil || f.f.Match(obj)
+}
+
+// reportFormat is the --format of a report, with --json as shorthand for
+// --format json.
+type reportFormat struct {
+	format *string
+	json   *bool
+}
+
+// addReportFlags adds --format and --json to fs.
+func addReportFlags(fs *flag.FlagSet) reportFormat {
+	return reportFormat{
+		format: fs.String("format", "text", "report format: text, json, "+strings.Join(tabular.Formats, ", ")),
+		json:   fs.Bool("json", false, "print the report as JSON (--format json)"),
+	}
+}
+
+// get returns the report format asked for.
+func (r reportFormat) get() (string, error) {
+	f := *r.format
+	switch f {
+	case "text", "json", "csv", "tsv":
+	default:
+		return "", fmt.Errorf("unknown --format %q (want text, json, %s)", f, strings.Join(tabular.Formats, ", "))
+	}
+	if *r.json {
+		if f != "text" && f != "json" {
+			return "", fmt.Errorf("--json conflicts with --format %s", f)
+		}
+		f = "json"
+	}
+	return f, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// runFmt pretty-prints memory object files with canonical key order and
+// normalization applied, so that review diffs are stable.
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := fs.Bool("w", false, "write result to the source file instead of stdout")
+	check := fs.Bool("check", false, "list files whose formatting differs and exit non-zero")
+	indent := fs.String("indent", "  ", "indentation string")
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("expected at least one file")
+	}
+
+	var unformatted int
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		out, err := formatDocument(data, *indent)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		switch {
+		case *check:
+			if !bytes.Equal(data, out) {
+				fmt.Println(path)
+				unformatted++
+			}
+		case *write:
+			if !bytes.Equal(data, out) {
+				if err := os.WriteFile(path, out, 0644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", path, err)
+				}
+			}
+		default:
+			os.Stdout.Write(out)
+		}
+	}
+
+	if unformatted > 0 {
+		return fmt.Errorf("%d file(s) not formatted", unformatted)
+	}
+	return nil
+}
+
+func formatDocument(data []byte, indent string) ([]byte, error) {
+	doc, err := ingest.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := ingest.NormalizeDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	return canon.Pretty(normalized, indent)
+}
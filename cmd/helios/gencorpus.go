@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/corpusgen"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// runGenCorpus writes a corpus generated from a seed and freezes its
+// hashes, or regenerates a frozen corpus and checks its hashes.
+func runGenCorpus(args []string) error {
+	fs := flag.NewFlagSet("gen-corpus", flag.ContinueOnError)
+	seed := fs.Uint64("seed", 1, "seed of the corpus")
+	count := fs.Int("count", 1000, "number of objects")
+	out := fs.String("o", "", "write the corpus as NDJSON to this file (default stdout, unless --freeze or --check is given)")
+	freeze := fs.String("freeze", "", "write the corpus's seed, count, and hashes to this file")
+	check := fs.String("check", "", "regenerate the corpus frozen in this file and fail if any hash changed")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+	if *check != "" {
+		set := false
+		fs.Visit(func(f *flag.Flag) { set = set || f.Name == "seed" || f.Name == "count" || f.Name == "freeze" })
+		if set {
+			return fmt.Errorf("--check takes the seed and count from the freeze file; it cannot be combined with --seed, --count, or --freeze")
+		}
+		return checkCorpus(*check, *out)
+	}
+	if *count < 0 {
+		return fmt.Errorf("--count must not be negative")
+	}
+
+	objs := corpusgen.Generate(*seed, *count)
+	if *out != "" || *freeze == "" {
+		if err := writeCorpus(*out, objs); err != nil {
+			return err
+		}
+	}
+	if *freeze == "" {
+		return nil
+	}
+	f, err := corpusgen.NewFreeze(*seed, objs)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(*freeze, f); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *freeze, err)
+	}
+	fmt.Fprintf(os.Stderr, "froze %d hashes of corpus seed %d to %s\n", f.Count, f.Seed, *freeze)
+	return nil
+}
+
+func checkCorpus(path, out string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var f corpusgen.Freeze
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if out != "" {
+		if err := writeCorpus(out, corpusgen.Generate(f.Seed, f.Count)); err != nil {
+			return err
+		}
+	}
+	mismatches, err := f.Check()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, m := range mismatches {
+		fmt.Printf("  %d %q: frozen %s, now %s\n", m.Index, m.Key, m.Frozen, m.Hash)
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d of %d hashes changed", len(mismatches), f.Count)
+	}
+	fmt.Printf("All %d hashes of corpus seed %d match\n", f.Count, f.Seed)
+	return nil
+}
+
+// writeCorpus writes objs as NDJSON to path, or to stdout if path is "".
+func writeCorpus(path string, objs []object.MemoryObject) error {
+	if path == "" {
+		w := bufio.NewWriter(os.Stdout)
+		if err := corpusgen.WriteNDJSON(w, objs); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if err := corpusgen.WriteNDJSON(w, objs); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
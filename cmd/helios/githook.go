@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/manifest"
+)
+
+// runGitHook validates every memory object staged in git under --root and
+// rewrites the hash manifest from their staged content. It fails when any object is invalid, so it can be used
+// directly as a pre-commit hook. With --clean it instead acts as a git
+// clean filter, normalizing one object from stdin to stdout.
+func runGitHook(args []string) error {
+	fs := flag.NewFlagSet("git-hook", flag.ContinueOnError)
+	root := fs.String("root", "memories", "directory containing memory object files")
+	manifestPath := fs.String("manifest", "helios.manifest", "path of the tracked hash manifest")
+	stage := fs.Bool("stage", false, "git add the manifest after writing it")
+	clean := fs.Bool("clean", false, "act as a git clean filter (stdin to stdout)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *clean {
+		return runCleanFilter(os.Stdin, os.Stdout)
+	}
+
+	entries, failures, err := manifest.Scan(".", *root)
+	if err != nil {
+		return err
+	}
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, "  %s: %v\n", f.Path, f.Err)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d invalid memory object file(s) under %s", len(failures), *root)
+	}
+
+	if err := os.WriteFile(*manifestPath, manifest.Format(entries), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if *stage {
+		cmd := exec.Command("git", "add", "--", *manifestPath)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to stage manifest: %w", err)
+		}
+	}
+	return nil
+}
+
+// runCleanFilter validates a memory object and writes it back in
+// canonical form so that equivalent objects are stored identically.
+func runCleanFilter(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	input, err := ingest.DecodeObject(data)
+	if err != nil {
+		return err
+	}
+	if err := ingest.ValidateInput(input); err != nil {
+		return err
+	}
+	normalized, err := ingest.Normalize(input)
+	if err != nil {
+		return err
+	}
+	out, err := canon.CanonicalizeObject(normalized)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/corpus"
+	"github.com/holeyfield33-art/helios/internal/graph"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+func runGraph(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a graph subcommand: export, neighbors, path, reachable, khop, seal, or verify-seal")
+	}
+	switch args[0] {
+	case "export":
+		return runGraphExport(args[1:])
+	case "neighbors":
+		return runGraphNeighbors(args[1:])
+	case "path":
+		return runGraphPath(args[1:], false)
+	case "reachable":
+		return runGraphPath(args[1:], true)
+	case "khop":
+		return runGraphKHop(args[1:])
+	case "seal":
+		return runGraphSeal(args[1:])
+	case "verify-seal":
+		return runGraphVerifySeal(args[1:])
+	default:
+		return fmt.Errorf("unknown graph subcommand %q (want export, neighbors, path, reachable, khop, seal, or verify-seal)", args[0])
+	}
+}
+
+// loadGraph builds the relationship graph of the corpus at path.
+func loadGraph(path string) (*graph.Graph, error) {
+	_, g, err := loadGraphObjects(path, "")
+	return g, err
+}
+
+// loadGraphObjects loads the objects of the corpus at path, or of the
+// directory store at storeDir if it is set, and builds their graph.
+func loadGraphObjects(path, storeDir string) ([]object.MemoryObject, *graph.Graph, error) {
+	var objs []object.MemoryObject
+	if storeDir != "" {
+		ctx := context.Background()
+		s, err := openStoreDir(storeDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries, err := s.Keys(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, e := range entries {
+			data, err := s.Get(ctx, e.Hash)
+			if err != nil {
+				return nil, nil, fmt.Errorf("key %q: %w", e.Key, err)
+			}
+			obj, err := ingest.ParseObject(data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("key %q: %w", e.Key, err)
+			}
+			objs = append(objs, obj)
+		}
+	} else {
+		records, err := corpus.Load(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		objs = make([]object.MemoryObject, len(records))
+		for i, r := range records {
+			objs[i] = r.Object
+		}
+	}
+	g, err := graph.Build(objs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return objs, g, nil
+}
+
+// graphQueryArgs splits the positional arguments of a graph query into
+// the corpus, which is absent when the query reads a store, and the n
+// keys that follow it.
+func graphQueryArgs(positional []string, storeDir string, n int, usage string) (string, []string, error) {
+	if storeDir != "" {
+		if len(positional) != n {
+			return "", nil, fmt.Errorf("expected %s", usage)
+		}
+		return "", positional, nil
+	}
+	if len(positional) != n+1 {
+		return "", nil, fmt.Errorf("expected a corpus and %s, or --store", usage)
+	}
+	return positional[0], positional[1:], nil
+}
+
+// runGraphNeighbors lists the relationships of one key.
+func runGraphNeighbors(args []string) error {
+	fs := flag.NewFlagSet("graph neighbors", flag.ContinueOnError)
+	storeDir := fs.String("store", "", "read the objects of this store directory instead of a corpus")
+	direction := fs.String("direction", "out", "edges to list: out (the key's relationships), in (relationships to it), or both")
+	var types stringList
+	fs.Var(&types, "type", "list only relationships of this type (repeatable)")
+	asJSON := fs.Bool("json", false, "print the edges as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	dir, err := graph.ParseDirection(*direction)
+	if err != nil {
+		return err
+	}
+	corpus, keys, err := graphQueryArgs(positional, *storeDir, 1, "a key")
+	if err != nil {
+		return err
+	}
+	_, g, err := loadGraphObjects(corpus, *storeDir)
+	if err != nil {
+		return err
+	}
+	edges := g.Neighbors(keys[0], dir, types)
+	if *asJSON {
+		return writeJSON("", edges)
+	}
+	for _, e := range edges {
+		fmt.Println(formatEdge(e))
+	}
+	return nil
+}
+
+// runGraphPath prints a shortest path between two keys, or with
+// reachableOnly just whether there is one. Either fails when there is
+// none.
+func runGraphPath(args []string, reachableOnly bool) error {
+	name := "graph path"
+	if reachableOnly {
+		name = "graph reachable"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	storeDir := fs.String("store", "", "read the objects of this store directory instead of a corpus")
+	var types stringList
+	fs.Var(&types, "type", "follow only relationships of this type (repeatable)")
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	corpus, keys, err := graphQueryArgs(positional, *storeDir, 2, "two keys")
+	if err != nil {
+		return err
+	}
+	_, g, err := loadGraphObjects(corpus, *storeDir)
+	if err != nil {
+		return err
+	}
+	from, to := keys[0], keys[1]
+	path, ok := g.ShortestPath(from, to, types)
+	if *asJSON {
+		result := struct {
+			From      string       `json:"from"`
+			To        string       `json:"to"`
+			Reachable bool         `json:"reachable"`
+			Path      []graph.Edge `json:"path,omitempty"`
+		}{From: from, To: to, Reachable: ok}
+		if !reachableOnly {
+			result.Path = path
+		}
+		if err := writeJSON("", result); err != nil {
+			return err
+		}
+	} else if ok && reachableOnly {
+		fmt.Printf("%s reaches %s in %d hops\n", from, to, len(path))
+	} else {
+		for _, e := range path {
+			fmt.Println(formatEdge(e))
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%q does not reach %q", from, to)
+	}
+	return nil
+}
+
+// runGraphKHop extracts the objects within some hops of a root key as an
+// objects document, and with --key signs an attestation of their hashes
+// that helios verify-sig checks the document against.
+func runGraphKHop(args []string) error {
+	fs := flag.NewFlagSet("graph khop", flag.ContinueOnError)
+	storeDir := fs.String("store", "", "read the objects of this store directory instead of a corpus")
+	hops := fs.Int("hops", 1, "follow at most this many relationships from the root")
+	var types stringList
+	fs.Var(&types, "type", "follow only relationships of this type (repeatable)")
+	var signKeys stringList
+	fs.Var(&signKeys, "key", "PEM PKCS#8 private key to sign the attestation with (repeatable)")
+	envelope := fs.String("envelope", "", "with --key, write the signed attestation to this file (required)")
+	out := fs.String("o", "", "write the objects to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *hops < 0 {
+		return fmt.Errorf("--hops must not be negative")
+	}
+	if (len(signKeys) > 0) != (*envelope != "") {
+		return fmt.Errorf("--key and --envelope must be given together")
+	}
+	corpus, keys, err := graphQueryArgs(positional, *storeDir, 1, "a root key")
+	if err != nil {
+		return err
+	}
+	var signers []signing.Signer
+	for _, k := range signKeys {
+		s, err := signing.LoadSigner(k)
+		if err != nil {
+			return err
+		}
+		signers = append(signers, s)
+	}
+	objs, g, err := loadGraphObjects(corpus, *storeDir)
+	if err != nil {
+		return err
+	}
+	sub := g.Select(graph.Filter{Root: keys[0], Depth: *hops, Types: types})
+	if len(sub.Nodes) == 0 {
+		return fmt.Errorf("key %q is not in the graph", keys[0])
+	}
+	selected := sub.Objects(objs)
+
+	var doc bytes.Buffer
+	doc.WriteString("{\"objects\": [")
+	for i, obj := range selected {
+		data, err := hash.CanonicalBytes(obj)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", obj.Key, err)
+		}
+		if i > 0 {
+			doc.WriteByte(',')
+		}
+		doc.WriteString("\n  ")
+		doc.Write(data)
+	}
+	doc.WriteString("\n]}\n")
+	if *out == "" {
+		if _, err := os.Stdout.Write(doc.Bytes()); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(*out, doc.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if len(signers) > 0 {
+		st, err := attest.NewStatement(selected)
+		if err != nil {
+			return err
+		}
+		env, err := attest.Sign(st, signers...)
+		if err != nil {
+			return err
+		}
+		if err := writeJSON(*envelope, env); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "extracted %d objects within %d hops of %s (%d missing targets)\n", len(selected), *hops, keys[0], len(sub.Nodes)-len(selected))
+	return nil
+}
+
+// runGraphSeal seals the subgraph within some hops of a root key into
+// one digest over its object hashes and edges.
+func runGraphSeal(args []string) error {
+	fs := flag.NewFlagSet("graph seal", flag.ContinueOnError)
+	storeDir := fs.String("store", "", "read the objects of this store directory instead of a corpus")
+	hops := fs.Int("hops", 1, "follow at most this many relationships from the root (-1: no limit)")
+	var types stringList
+	fs.Var(&types, "type", "follow only relationships of this type (repeatable)")
+	out := fs.String("o", "", "write the seal to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	corpus, keys, err := graphQueryArgs(positional, *storeDir, 1, "a root key")
+	if err != nil {
+		return err
+	}
+	_, g, err := loadGraphObjects(corpus, *storeDir)
+	if err != nil {
+		return err
+	}
+	seal, err := graph.NewSeal(g, keys[0], *hops, types)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(*out, seal); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "sealed %d nodes and %d edges: %s\n", len(seal.Nodes), len(seal.Edges), seal.Digest)
+	return nil
+}
+
+// runGraphVerifySeal replays the traversal of a seal over a corpus, such
+// as the objects helios graph khop extracted, and checks it reproduces
+// the seal.
+func runGraphVerifySeal(args []string) error {
+	fs := flag.NewFlagSet("graph verify-seal", flag.ContinueOnError)
+	digest := fs.String("digest", "", "also require the seal to have this digest, obtained out of band")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("expected a seal and a corpus, got %d arguments", len(positional))
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read seal: %w", err)
+	}
+	var seal graph.Seal
+	if err := json.Unmarshal(data, &seal); err != nil {
+		return fmt.Errorf("failed to parse seal: %w", err)
+	}
+	if *digest != "" && seal.Digest != *digest {
+		return fmt.Errorf("seal digest is %s, expected %s", seal.Digest, *digest)
+	}
+	objs, _, err := loadGraphObjects(positional[1], "")
+	if err != nil {
+		return err
+	}
+	if err := graph.VerifySeal(&seal, objs); err != nil {
+		return err
+	}
+	fmt.Printf("Seal verified: %d nodes and %d edges within %d hops of %s\n", len(seal.Nodes), len(seal.Edges), seal.Depth, seal.Root)
+	return nil
+}
+
+// formatEdge formats an edge for the text output of the graph queries.
+func formatEdge(e graph.Edge) string {
+	return fmt.Sprintf("%s -[%s]-> %s", e.From, e.Type, e.To)
+}
+
+// runGraphExport writes the relationship graph of a corpus, or the part
+// of it the filters select, as DOT, GraphML, or JSON-LD.
+func runGraphExport(args []string) error {
+	fs := flag.NewFlagSet("graph export", flag.ContinueOnError)
+	format := fs.String("format", "dot", "output format: "+strings.Join(graph.Formats, ", "))
+	var categories stringList
+	fs.Var(&categories, "category", "keep only nodes of this category (repeatable)")
+	prefix := fs.String("prefix", "", "keep only nodes whose key starts with this prefix")
+	root := fs.String("root", "", "keep only nodes reachable from this key")
+	depth := fs.Int("depth", -1, "with --root, follow at most this many edges (-1: no limit)")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one corpus, got %d", len(positional))
+	}
+	depthSet := false
+	fs.Visit(func(f *flag.Flag) { depthSet = depthSet || f.Name == "depth" })
+	if depthSet && *root == "" {
+		return fmt.Errorf("--depth requires --root")
+	}
+	g, err := loadGraph(positional[0])
+	if err != nil {
+		return err
+	}
+	g = g.Select(graph.Filter{Categories: categories, Prefix: *prefix, Root: *root, Depth: *depth})
+
+	if *out == "" {
+		bw := bufio.NewWriter(os.Stdout)
+		if err := graph.Export(bw, g, *format); err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := graph.Export(bw, g, *format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d nodes and %d edges to %s\n", len(g.Nodes), len(g.Edges), *out)
+	return f.Close()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/batch"
+	"github.com/holeyfield33-art/helios/internal/columnar"
+	"github.com/holeyfield33-art/helios/internal/corpus"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// runHashBatch hashes every object in a corpus and prints one NDJSON
+// result per object with its key, status, hash, and canonical bytes.
+// With --continue-on-error invalid objects are reported as results with
+// status invalid and the run exits 2 if there were any, leaving exit
+// code 1 for corpora that cannot be read.
+func runHashBatch(args []string) error {
+	fs := flag.NewFlagSet("hash-batch", flag.ContinueOnError)
+	var mapping, jsonCols stringList
+	fs.Var(&mapping, "map", "Avro/Parquet column for a field, as field=column (repeatable)")
+	fs.Var(&jsonCols, "json-column", "Avro/Parquet string column holding JSON (repeatable; default relationships)")
+	keepGoing := fs.Bool("continue-on-error", false, "report objects that cannot be parsed or hashed as invalid results instead of stopping at the first")
+	intern := fs.Bool("intern", false, "share one copy of repeated map keys, relationship keys and types, categories, and sources, for corpora that repeat them heavily")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one corpus path, got %d", len(positional))
+	}
+
+	m := columnar.DefaultMapping()
+	if m.Columns, err = columnar.ParseMapping(mapping); err != nil {
+		return err
+	}
+	if len(jsonCols) > 0 {
+		m.JSON = jsonCols
+	}
+	var in *ingest.Interner
+	if *intern {
+		in = ingest.NewInterner()
+	}
+	records, err := loadCorpus(positional[0], m, in, *keepGoing)
+	if err != nil {
+		return err
+	}
+	if !*keepGoing {
+		results, err := batch.Hash(records)
+		if err != nil {
+			return err
+		}
+		return batch.Write(os.Stdout, results)
+	}
+	results, invalid := batch.HashItems(records)
+	if err := batch.Write(os.Stdout, results); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return &exitError{code: 2, err: fmt.Errorf("%d of %d objects invalid", invalid, len(results))}
+	}
+	return nil
+}
+
+// loadCorpus loads a JSON corpus (see corpus.Load), an Avro or
+// Parquet file, or a directory holding any of them. Columnar files in a
+// directory follow the JSON records. Each file's objects are interned
+// with in, if it is not nil, as the file is loaded. With keepGoing,
+// invalid JSON objects are returned as records with Err set (see
+// corpus.LoadItems); a columnar file is still loaded whole or not at all.
+func loadCorpus(path string, m columnar.Mapping, in *ingest.Interner, keepGoing bool) ([]ingest.Record, error) {
+	format, err := columnar.Detect(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	if format != columnar.None {
+		return loadColumnar(path, m, in)
+	}
+
+	load := corpus.LoadInterned
+	if keepGoing {
+		load = corpus.LoadItems
+	}
+	records, err := load(path, in)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return records, err
+	}
+	files, err := columnar.Files(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		recs, err := loadColumnar(f, m, in)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, recs...)
+	}
+	return records, nil
+}
+
+func loadColumnar(path string, m columnar.Mapping, in *ingest.Interner) ([]ingest.Record, error) {
+	records, err := columnar.Load(path, m)
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		in.Records(records)
+	}
+	return records, nil
+}
@@ -0,0 +1,50 @@
+//go:build keyless
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/keyless"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// signKeyless signs st with an ephemeral Fulcio-certified key and records
+// the signature in Rekor.
+func signKeyless(st *attest.Statement, opts keylessOptions) (interface{}, error) {
+	return keyless.Sign(keyless.Config{
+		FulcioURL: opts.fulcio,
+		RekorURL:  opts.rekor,
+		IDToken:   opts.token,
+	}, st)
+}
+
+// verifyKeyless verifies a keyless result against the Fulcio root in
+// opts.root, the Rekor key in opts.rekorKey, and the expected certificate
+// identity and issuer.
+func verifyKeyless(data []byte, opts keylessOptions) (*attest.Statement, error) {
+	var res keyless.Result
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("failed to parse keyless bundle: %w", err)
+	}
+	if opts.root == "" || opts.rekorKey == "" || opts.identity == "" {
+		return nil, fmt.Errorf("--fulcio-root, --rekor-key, and --certificate-identity are required for keyless verification")
+	}
+	rekorKey, err := signing.LoadPublicKey(opts.rekorKey)
+	if err != nil {
+		return nil, err
+	}
+	pem, err := os.ReadFile(opts.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Fulcio root: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", opts.root)
+	}
+	return keyless.Verify(&res, roots, rekorKey, opts.identity, opts.issuer)
+}
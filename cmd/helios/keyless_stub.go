@@ -0,0 +1,19 @@
+//go:build !keyless
+
+package main
+
+import (
+	"errors"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+)
+
+var errNoKeyless = errors.New("keyless signing is not available: rebuild with -tags keyless")
+
+func signKeyless(*attest.Statement, keylessOptions) (interface{}, error) {
+	return nil, errNoKeyless
+}
+
+func verifyKeyless([]byte, keylessOptions) (*attest.Statement, error) {
+	return nil, errNoKeyless
+}
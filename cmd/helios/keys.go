@@ -0,0 +1,396 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/checkpoint"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// runKeys dispatches the key management subcommands.
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a keys subcommand: generate, rotate, revoke, fingerprint, export-public, split, or combine")
+	}
+	switch args[0] {
+	case "generate":
+		return runKeysGenerate(args[1:])
+	case "rotate":
+		return runKeysRotate(args[1:])
+	case "revoke":
+		return runKeysRevoke(args[1:])
+	case "fingerprint":
+		return runKeysFingerprint(args[1:])
+	case "export-public":
+		return runKeysExportPublic(args[1:])
+	case "split":
+		return runKeysSplit(args[1:])
+	case "combine":
+		return runKeysCombine(args[1:])
+	}
+	return fmt.Errorf("unknown keys subcommand %q (want generate, rotate, revoke, fingerprint, export-public, split, or combine)", args[0])
+}
+
+// newKeyFlags are the flags of the subcommands that create a key.
+type newKeyFlags struct {
+	algorithm      *string
+	noEncrypt      *bool
+	passphraseFile *string
+}
+
+func addNewKeyFlags(fs *flag.FlagSet) newKeyFlags {
+	return newKeyFlags{
+		algorithm:      fs.String("algorithm", signing.AlgorithmEd25519, "key algorithm: ed25519 or ecdsa-p256"),
+		noEncrypt:      fs.Bool("no-encrypt", false, "write the private key unencrypted"),
+		passphraseFile: fs.String("passphrase-file", "", "file holding the passphrase to encrypt the key with (default: $HELIOS_KEY_PASSPHRASE_FILE or $HELIOS_KEY_PASSPHRASE)"),
+	}
+}
+
+// write generates a key and writes it to path, encrypted unless
+// --no-encrypt, refusing to replace an existing file.
+func (f newKeyFlags) write(path string) (signing.Signer, error) {
+	priv, err := signing.GenerateKey(*f.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if *f.noEncrypt {
+		data, err = signing.EncodePrivateKey(priv)
+	} else {
+		passphrase, perr := signing.Passphrase()
+		if *f.passphraseFile != "" {
+			passphrase, perr = signing.ReadPassphrase(*f.passphraseFile)
+		}
+		if perr != nil {
+			return nil, perr
+		}
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("a passphrase is required to encrypt the key: pass --passphrase-file, set $HELIOS_KEY_PASSPHRASE_FILE or $HELIOS_KEY_PASSPHRASE, or pass --no-encrypt")
+		}
+		data, err = signing.EncryptPrivateKey(priv, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := out.Write(data); err != nil {
+		out.Close()
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	return signing.NewSigner(priv)
+}
+
+// runKeysGenerate writes a new private key, encrypted at rest by
+// default, and prints its fingerprint.
+func runKeysGenerate(args []string) error {
+	fs := flag.NewFlagSet("keys generate", flag.ContinueOnError)
+	nk := addNewKeyFlags(fs)
+	out := fs.String("o", "", "file to write the private key to")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+	if *out == "" {
+		return fmt.Errorf("-o is required")
+	}
+	s, err := nk.write(*out)
+	if err != nil {
+		return err
+	}
+	fp, err := signing.Fingerprint(s.Public())
+	if err != nil {
+		return err
+	}
+	fmt.Println(fp)
+	return nil
+}
+
+// runKeysRotate generates a key to replace the signing key and re-signs
+// the newest checkpoint of the log under both: a checkpoint of the same
+// state is appended, signed by the old key and the new one, so verifiers
+// who trust the old key can see it hand over to the new one.
+func runKeysRotate(args []string) error {
+	fs := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+	key := fs.String("key", "", "PEM PKCS#8 private key being replaced")
+	nk := addNewKeyFlags(fs)
+	out := fs.String("o", "", "file to write the new private key to")
+	logPath := checkpointLogFlag(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+	if *key == "" || *out == "" {
+		return fmt.Errorf("--key and -o are required")
+	}
+	old, err := signing.LoadSigner(*key)
+	if err != nil {
+		return err
+	}
+	log, err := checkpoint.OpenLog(*logPath)
+	if err != nil {
+		return err
+	}
+	latest := log.Latest()
+	if latest == nil {
+		return fmt.Errorf("checkpoint log %s is empty; there is no checkpoint to re-sign", *logPath)
+	}
+	if err := latest.Verify(old.Public()); err != nil {
+		return fmt.Errorf("--key did not sign the newest checkpoint, so it cannot hand over to a new key: %w", err)
+	}
+	next, err := nk.write(*out)
+	if err != nil {
+		return err
+	}
+	c, err := log.Reaffirm(time.Now(), old, next)
+	if err != nil {
+		return fmt.Errorf("wrote %s, but re-signing the newest checkpoint failed: %w", *out, err)
+	}
+	for _, s := range []struct {
+		label  string
+		signer signing.Signer
+	}{{"old", old}, {"new", next}} {
+		fp, err := signing.Fingerprint(s.signer.Public())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s  %s\n", s.label, fp)
+	}
+	fmt.Printf("re-signed root %s as checkpoint %d under both keys\n", c.Root, c.Seq)
+	return nil
+}
+
+// runKeysRevoke appends the revocation of keys, named by key file or
+// fingerprint, to a revocation list and signs the list. An existing list
+// must already be signed by --key, so that a list signed by someone else
+// is never silently re-signed.
+func runKeysRevoke(args []string) error {
+	fs := flag.NewFlagSet("keys revoke", flag.ContinueOnError)
+	listPath := fs.String("list", "", "revocation list to append to, created if missing")
+	key := fs.String("key", "", "PEM PKCS#8 private key to sign the list with")
+	at := fs.String("at", "", "RFC 3339 time from which signatures are rejected (default: now)")
+	reason := fs.String("reason", "", "why the key is revoked, such as \"key compromise\"")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *listPath == "" || *key == "" {
+		return fmt.Errorf("--list and --key are required")
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("expected the key files or fingerprints to revoke")
+	}
+	when := time.Now()
+	if *at != "" {
+		if when, err = time.Parse(time.RFC3339, *at); err != nil {
+			return fmt.Errorf("invalid --at: %w", err)
+		}
+	}
+	signer, err := signing.LoadSigner(*key)
+	if err != nil {
+		return err
+	}
+
+	revs := &signing.RevocationList{}
+	if _, err := os.Stat(*listPath); err == nil {
+		if revs, err = signing.LoadRevocationList(*listPath); err != nil {
+			return err
+		}
+		if err := revs.Verify(signer.Public()); err != nil {
+			return fmt.Errorf("%s: --key did not sign the existing list: %w", *listPath, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, arg := range positional {
+		id := arg
+		if _, err := os.Stat(arg); err == nil {
+			pub, err := readPublicKeyOf(arg)
+			if err != nil {
+				return err
+			}
+			if id, err = signing.KeyID(pub); err != nil {
+				return err
+			}
+		}
+		if err := revs.Revoke(id, when, *reason); err != nil {
+			return err
+		}
+	}
+	if err := revs.Sign(signer); err != nil {
+		return err
+	}
+	if err := writeJSON(*listPath, revs); err != nil {
+		return err
+	}
+	for _, r := range revs.Revocations[len(revs.Revocations)-len(positional):] {
+		fmt.Println(r)
+	}
+	return nil
+}
+
+// runKeysFingerprint prints the fingerprint of a private or public key
+// file, or with --expect checks it against one obtained out of band,
+// such as read over the phone.
+func runKeysFingerprint(args []string) error {
+	fs := flag.NewFlagSet("keys fingerprint", flag.ContinueOnError)
+	expect := fs.String("expect", "", "fail unless the key has this fingerprint, or this key ID")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one key file, got %d arguments", len(positional))
+	}
+	pub, err := readPublicKeyOf(positional[0])
+	if err != nil {
+		return err
+	}
+	fp, err := signing.Fingerprint(pub)
+	if err != nil {
+		return err
+	}
+	if *expect != "" {
+		id, _ := signing.KeyID(pub)
+		want := strings.ToLower(strings.TrimSpace(*expect))
+		if want != strings.ToLower(fp) && want != id && want != "sha256:"+id {
+			return fmt.Errorf("fingerprint mismatch: key is %s, expected %s", fp, *expect)
+		}
+	}
+	fmt.Println(fp)
+	return nil
+}
+
+// runKeysExportPublic writes the PEM public key of a private key file.
+// An encrypted key's public key is read without its passphrase.
+func runKeysExportPublic(args []string) error {
+	fs := flag.NewFlagSet("keys export-public", flag.ContinueOnError)
+	out := fs.String("o", "", "write the public key to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one key file, got %d arguments", len(positional))
+	}
+	pub, err := readPublicKeyOf(positional[0])
+	if err != nil {
+		return err
+	}
+	data, err := signing.EncodePublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*out, data, 0644)
+}
+
+func readPublicKeyOf(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	pub, err := signing.PublicKeyOf(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pub, nil
+}
+
+// runKeysSplit splits a private key into Shamir shares, written as
+// <key-id prefix>-share-<i>.pem files, so that signing needs a threshold
+// of their holders.
+func runKeysSplit(args []string) error {
+	fs := flag.NewFlagSet("keys split", flag.ContinueOnError)
+	key := fs.String("key", "", "PEM PKCS#8 private key to split")
+	shares := fs.Int("shares", 0, "number of shares to write")
+	threshold := fs.Int("threshold", 0, "number of shares needed to sign")
+	dir := fs.String("o", ".", "directory to write the shares to")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", positional)
+	}
+	if *key == "" || *shares == 0 || *threshold == 0 {
+		return fmt.Errorf("--key, --shares, and --threshold are required")
+	}
+	priv, err := signing.LoadPrivateKey(*key)
+	if err != nil {
+		return err
+	}
+	s, err := signing.NewSigner(priv)
+	if err != nil {
+		return err
+	}
+	pems, err := signing.SplitKey(priv, *shares, *threshold)
+	if err != nil {
+		return err
+	}
+	for i, p := range pems {
+		path := filepath.Join(*dir, fmt.Sprintf("%s-share-%d.pem", s.KeyID()[:16], i+1))
+		if err := os.WriteFile(path, p, 0600); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	fmt.Fprintf(os.Stderr, "split key %s into %d shares, %d needed to sign; distribute them and delete %s\n", s.KeyID(), *shares, *threshold, *key)
+	return nil
+}
+
+// runKeysCombine reconstructs a private key from a threshold of its
+// shares and writes it, for re-splitting or migrating the key. Signing
+// does not need it: --key-share combines shares in memory.
+func runKeysCombine(args []string) error {
+	fs := flag.NewFlagSet("keys combine", flag.ContinueOnError)
+	out := fs.String("o", "", "file to write the reconstructed PEM PKCS#8 private key to")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("-o is required")
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("expected key share files")
+	}
+	shares, err := signing.ReadKeyShares(positional)
+	if err != nil {
+		return err
+	}
+	priv, err := signing.CombineKey(shares)
+	if err != nil {
+		return err
+	}
+	pem, err := signing.EncodePrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, pem, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "reconstructed key %s from %d shares\n", shares[0].KeyID, len(shares))
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/corpus"
+	"github.com/holeyfield33-art/helios/internal/lineage"
+)
+
+// runLineage walks the revisions of a key in a corpus along their
+// supersedes links and prints the chain, first revision first, as
+// "<n>  <hash>  <created_at>  <origin>" lines followed by any forks,
+// gaps, or other breaks. It fails if the chain is broken.
+func runLineage(args []string) error {
+	fs := flag.NewFlagSet("lineage", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the chain and problems as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("expected a key and a corpus path, got %d arguments", len(positional))
+	}
+	key, path := positional[0], positional[1]
+
+	records, err := corpus.Load(path)
+	if err != nil {
+		return err
+	}
+	report, err := lineage.Check(key, records)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := writeJSON("", report); err != nil {
+			return err
+		}
+	} else {
+		for i, rev := range report.Chain {
+			fmt.Printf("%3d  %s  %s  %s\n", i+1, rev.Hash, rev.CreatedAt, rev.Origin)
+		}
+		for _, p := range report.Problems {
+			fmt.Printf("%-11s", p.Kind)
+			for _, s := range []string{p.Origin, p.Hash} {
+				if s != "" {
+					fmt.Printf("  %s", s)
+				}
+			}
+			fmt.Printf("  (%s)\n", p.Detail)
+		}
+		fmt.Fprintf(os.Stderr, "checked %d revisions of key %q\n", report.Revisions, key)
+	}
+	if !report.OK() {
+		return fmt.Errorf("lineage of key %q has %d problems", key, len(report.Problems))
+	}
+	return nil
+}
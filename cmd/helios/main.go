@@ -1,106 +1,535 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
-	"strings"
+	"sort"
+	"time"
 
 	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/corpus"
 	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/notify"
 	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/simhash"
 	"github.com/holeyfield33-art/helios/internal/verify"
 )
 
 var version = "1.0.0"
 
+// errorStyle controls how fail renders errors; set by the global --verbose flag.
+var errorStyle = canon.Terse
+
 func main() {
-	if len(os.Args) < 2 {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "--verbose" {
+		errorStyle = canon.Verbose
+		args = args[1:]
+	}
+	if len(args) < 1 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "--version", "-v":
+		if len(args) > 1 && args[1] == "--json" {
+			if err := writeJSON("", struct {
+				Version string `json:"version"`
+				hash.Build
+			}{version, hash.BuildInfo()}); err != nil {
+				fail(err)
+			}
+			return
+		}
 		fmt.Printf("helios %s\n", version)
 		return
 	case "hash":
-		if len(os.Args) < 3 {
-			fmt.Fprintln(os.Stderr, "Usage: helios hash <file.json>")
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: helios hash <file.json> [--draft] [--simhash] [--path $.value...]")
 			os.Exit(1)
 		}
-		if err := runHash(os.Args[2]); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		if err := runHash(args[1:]); err != nil {
+			fail(err)
 		}
 	case "verify":
-		if len(os.Args) < 3 {
-			fmt.Fprintln(os.Stderr, "Usage: helios verify <vectors.json>")
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: helios verify <vectors.json> [--parallel N] [--sort-by status|name] [--endpoint URL] [--require-signature --pub PUB [--signature FILE]] [--webhook URL] [--exec-hook CMD]")
+			fmt.Fprintln(os.Stderr, "       helios verify --unicode-impact [--json] <store-dir|vectors.json>")
+			fmt.Fprintln(os.Stderr, "       helios verify --update --reason TEXT <vectors.json>")
 			os.Exit(1)
 		}
-		if err := runVerify(os.Args[2]); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		if err := runVerify(args[1:]); err != nil {
+			fail(err)
+		}
+	case "git-hook":
+		if err := runGitHook(args[1:]); err != nil {
+			fail(err)
+		}
+	case "dedup":
+		if err := runDedup(args[1:]); err != nil {
+			fail(err)
+		}
+	case "hash-batch":
+		if err := runHashBatch(args[1:]); err != nil {
+			fail(err)
+		}
+	case "difftest":
+		if err := runDifftest(args[1:]); err != nil {
+			fail(err)
+		}
+	case "fmt":
+		if err := runFmt(args[1:]); err != nil {
+			fail(err)
+		}
+	case "attest":
+		if err := runAttest(args[1:]); err != nil {
+			fail(err)
+		}
+	case "verify-sig":
+		if err := runVerifySig(args[1:]); err != nil {
+			fail(err)
+		}
+	case "timestamp":
+		if err := runTimestamp(args[1:]); err != nil {
+			fail(err)
+		}
+	case "verify-timestamp":
+		if err := runVerifyTimestamp(args[1:]); err != nil {
+			fail(err)
+		}
+	case "checkpoint":
+		if err := runCheckpoint(args[1:]); err != nil {
+			fail(err)
+		}
+	case "verify-checkpoint":
+		if err := runVerifyCheckpoint(args[1:]); err != nil {
+			fail(err)
+		}
+	case "prove":
+		if err := runProve(args[1:]); err != nil {
+			fail(err)
+		}
+	case "verify-proof":
+		if err := runVerifyProof(args[1:]); err != nil {
+			fail(err)
+		}
+	case "witness":
+		if err := runWitness(args[1:]); err != nil {
+			fail(err)
+		}
+	case "bundle":
+		if err := runBundle(args[1:]); err != nil {
+			fail(err)
+		}
+	case "verify-bundle":
+		if err := runVerifyBundle(args[1:]); err != nil {
+			fail(err)
+		}
+	case "doctor":
+		if err := runDoctor(args[1:]); err != nil {
+			fail(err)
+		}
+	case "admin":
+		if err := runAdmin(args[1:]); err != nil {
+			fail(err)
+		}
+	case "selfcheck":
+		if err := runSelfCheck(args[1:]); err != nil {
+			fail(err)
+		}
+	case "schema":
+		if err := runSchema(args[1:]); err != nil {
+			fail(err)
+		}
+	case "export-vectors":
+		if err := runExportVectors(args[1:]); err != nil {
+			fail(err)
+		}
+	case "derive-inverse":
+		if err := runDeriveInverse(args[1:]); err != nil {
+			fail(err)
+		}
+	case "graph":
+		if err := runGraph(args[1:]); err != nil {
+			fail(err)
+		}
+	case "browse":
+		if err := runBrowse(args[1:]); err != nil {
+			fail(err)
+		}
+	case "repl":
+		if err := runRepl(args[1:]); err != nil {
+			fail(err)
+		}
+	case "merge":
+		if err := runMerge(args[1:]); err != nil {
+			fail(err)
+		}
+	case "lineage":
+		if err := runLineage(args[1:]); err != nil {
+			fail(err)
+		}
+	case "evolve":
+		if err := runEvolve(args[1:]); err != nil {
+			fail(err)
+		}
+	case "commit":
+		if err := runCommit(args[1:]); err != nil {
+			fail(err)
+		}
+	case "value-tree":
+		if err := runValueTree(args[1:]); err != nil {
+			fail(err)
+		}
+	case "validate":
+		if err := runValidate(args[1:]); err != nil {
+			fail(err)
+		}
+	case "seal":
+		if err := runSeal(args[1:]); err != nil {
+			fail(err)
+		}
+	case "open":
+		if err := runOpen(args[1:]); err != nil {
+			fail(err)
+		}
+	case "keys":
+		if err := runKeys(args[1:]); err != nil {
+			fail(err)
+		}
+	case "bench":
+		if err := runBench(args[1:]); err != nil {
+			fail(err)
+		}
+	case "gen-corpus":
+		if err := runGenCorpus(args[1:]); err != nil {
+			fail(err)
+		}
+	case "coverage":
+		if err := runCoverage(args[1:]); err != nil {
+			fail(err)
+		}
+	case "sign-vectors":
+		if err := runSignVectors(args[1:]); err != nil {
+			fail(err)
+		}
+	case "consume":
+		if err := runConsume(args[1:]); err != nil {
+			fail(err)
+		}
+	case "store":
+		if err := runStore(args[1:]); err != nil {
+			fail(err)
+		}
+	case "search":
+		if err := runSearch(args[1:]); err != nil {
+			fail(err)
+		}
+	case "shard-stats":
+		if err := runShardStats(args[1:]); err != nil {
+			fail(err)
 		}
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", os.Args[1])
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
 		printUsage()
 		os.Exit(1)
 	}
 }
 
+// fail reports err on stderr in the selected style and exits non-zero:
+// with the code of an exitError, 1 otherwise.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %s\n", canon.FormatError(err, errorStyle))
+	var ee *exitError
+	if errors.As(err, &ee) {
+		os.Exit(ee.code)
+	}
+	os.Exit(1)
+}
+
+// exitError is an error that fail exits with a code other than 1 for, so
+// scripts can tell kinds of failure apart.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
 func printUsage() {
 	fmt.Fprintln(os.Stderr, "Helios Core — Canonical Hash Tool")
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Usage:")
-	fmt.Fprintln(os.Stderr, "  helios hash <file.json>      Compute content hash for a memory object")
-	fmt.Fprintln(os.Stderr, "  helios verify <vectors.json>  Verify test vectors")
-	fmt.Fprintln(os.Stderr, "  helios --version             Show version")
+	fmt.Fprintln(os.Stderr, "  helios hash <file.json>      Compute content hash for a memory object (or each object in an array; --draft, --simhash, --path, --relationships-from FILE|DIR, --parallel-threshold N, --expect HASH [--reference FILE], --profile PROFILE)")
+	fmt.Fprintln(os.Stderr, "  helios verify <vectors.json>  Verify test vectors (--parallel N, --sort-by status|name, --top-slow N, --endpoint URL, --require-signature --pub PUB, --webhook URL; --unicode-impact <store-dir|vectors.json> reports hashes this build's Unicode tables change; --update --reason TEXT re-freezes failing vectors)")
+	fmt.Fprintln(os.Stderr, "  helios git-hook [flags]      Validate memory files and update the hash manifest")
+	fmt.Fprintln(os.Stderr, "  helios dedup [--filter EXPR] [--format text|json|csv|tsv] <corpus>  Report objects with identical content under different keys")
+	fmt.Fprintln(os.Stderr, "  helios hash-batch <corpus>   Print NDJSON hash and canonical bytes for every object (JSON, NDJSON, Avro, Parquet; --map field=column, --intern; --continue-on-error reports invalid objects and exits 2 if there were any)")
+	fmt.Fprintln(os.Stderr, "  helios difftest --other BIN <corpus>  Compare hashes with another helios binary")
+	fmt.Fprintln(os.Stderr, "  helios fmt [-w|--check] <file.json>...  Pretty-print with canonical key order")
+	fmt.Fprintln(os.Stderr, "  helios attest --key KEY|--keyless <file.json>  Sign an in-toto/DSSE attestation of content hashes")
+	fmt.Fprintln(os.Stderr, "  helios verify-sig --pub PUB|--policy FILE [--revocations FILE] <envelope.json> [file.json...]  Verify an attestation")
+	fmt.Fprintln(os.Stderr, "  helios timestamp --tsa URL <file.json>  Obtain an RFC 3161 timestamp token over the content hash")
+	fmt.Fprintln(os.Stderr, "  helios verify-timestamp --tsa-root PEM <file.json>  Verify a stored timestamp token")
+	fmt.Fprintln(os.Stderr, "  helios checkpoint --key KEY|--key-share SHARE... [--log FILE] [--witness URL... --threshold K] [store flags]  Sign the Merkle root of a store's keys and append it to the checkpoint log")
+	fmt.Fprintln(os.Stderr, "  helios verify-checkpoint --pub PUB|--policy FILE [--revocations FILE] [--witness-pub PUB... --threshold K] [--log FILE] [--seq N | <checkpoint.json>] [store flags]  Check a store against a signed checkpoint")
+	fmt.Fprintln(os.Stderr, "  helios witness --key KEY --trust ORIGIN=PUB [--state FILE] [--addr ADDR]  Cosign other stores' checkpoints over HTTP")
+	fmt.Fprintln(os.Stderr, "  helios prove [--log FILE] [store flags] <key>  Print an inclusion proof of a key in the latest checkpoint")
+	fmt.Fprintln(os.Stderr, "  helios verify-proof --pub PUB|--policy FILE [--revocations FILE] [--witness-pub PUB... --threshold K] <proof.json> [file.json...]  Verify an inclusion proof without the store")
+	fmt.Fprintln(os.Stderr, "  helios bundle -o OUT <file.json>...  Package objects, signatures, keys, and vectors for offline verification")
+	fmt.Fprintln(os.Stderr, "  helios verify-bundle [--pub PUB] [--policy FILE] [--revocations FILE] <bundle>  Verify a bundle without network access (--webhook URL, --exec-hook CMD)")
+	fmt.Fprintln(os.Stderr, "  helios export-vectors --lang python|jest|rust <vectors.json>  Generate test fixtures for other implementations")
+	fmt.Fprintln(os.Stderr, "  helios coverage [--tests DIR] [--json|--markdown] [--strict] [vectors.json...]  Matrix of spec rules against the vectors and tests covering them")
+	fmt.Fprintln(os.Stderr, "  helios derive-inverse --types TYPES.json [-o revised.ndjson | --json] <corpus>  Report the inverse edges a corpus lacks as NDJSON for hash --relationships-from, or write the revised objects")
+	fmt.Fprintln(os.Stderr, "  helios graph export [--format dot|graphml|jsonld] [--category C]... [--prefix P] [--root KEY [--depth N]] [-o FILE] <corpus>  Export the relationship graph, nodes annotated with category and hash")
+	fmt.Fprintln(os.Stderr, "  helios graph neighbors [--direction out|in|both] [--type T]... [--json] <corpus>|--store DIR <key>  List the relationships of a key")
+	fmt.Fprintln(os.Stderr, "  helios graph path|reachable [--type T]... [--json] <corpus>|--store DIR <from> <to>  Print a shortest relationship path between two keys, or whether there is one")
+	fmt.Fprintln(os.Stderr, "  helios browse [--filter EXPR] <corpus>|--store DIR  Browse keys, categories, and relationships in a terminal, with each object's value, hash, and verification status")
+	fmt.Fprintln(os.Stderr, "  helios repl [--history FILE]  Paste JSON fragments to see their canonical form, normalization changes, validation errors, and hash (:help for commands)")
+	fmt.Fprintln(os.Stderr, "  helios graph khop [--hops N] [--type T]... [--key KEY --envelope FILE] [-o FILE] <corpus>|--store DIR <root>  Extract the objects within N hops of a key, with a signed attestation of their hashes")
+	fmt.Fprintln(os.Stderr, "  helios graph seal [--hops N] [--type T]... [-o FILE] <corpus>|--store DIR <root>  Seal the subgraph within N hops of a key into one digest over its object hashes and edges")
+	fmt.Fprintln(os.Stderr, "  helios graph verify-seal [--digest D] <seal> <corpus>  Replay a seal's traversal over a corpus and check it reproduces the seal")
+	fmt.Fprintln(os.Stderr, "  helios merge [-o FILE] <base.json> <ours.json> <theirs.json>  Three-way merge two revisions of an object, rehashing the result; conflicts are marked in place and exit 2")
+	fmt.Fprintln(os.Stderr, "  helios lineage [--json] <key> <corpus>  Walk a key's revisions along their supersedes hashes and verify they form one chain, reporting forks and gaps")
+	fmt.Fprintln(os.Stderr, "  helios evolve --to PROFILE [--from PROFILE] [--notes FILE] [-o FILE --reason TEXT] [--json] <vectors.json>  Diff two hashing profiles, predict which vectors' hashes change, and write migration notes and a candidate vectors file")
+	fmt.Fprintln(os.Stderr, "  helios keys generate|rotate|fingerprint|export-public  Manage signing keys: generate -o KEY [--algorithm ed25519|ecdsa-p256] [--no-encrypt] [--passphrase-file F] writes a key encrypted at rest; rotate --key OLD -o NEW [--log FILE] re-signs the newest checkpoint under both; fingerprint [--expect FPR] KEY; export-public [-o FILE] KEY")
+	fmt.Fprintln(os.Stderr, "  helios keys revoke --list FILE --key KEY [--at TIME] [--reason TEXT] <key|fingerprint>...  Append revocations to a signed revocation list; verify commands take it with --revocations FILE")
+	fmt.Fprintln(os.Stderr, "  helios keys split --key KEY --shares N --threshold K [-o DIR] | combine -o KEY <share>...  Split a signing key into Shamir shares so K holders must cooperate to sign, or reconstruct it")
+	fmt.Fprintln(os.Stderr, "  helios commit [-o FILE] [--verify COMMITMENT] <file.json>  Print a salted commitment that hides the object's content until it is revealed with its commitment_salt, or verify a revealed object opens one")
+	fmt.Fprintln(os.Stderr, "  helios value-tree root|disclose|verify  Hash a value as a Merkle tree over its leaves: root [--salted] FILE prints the value digest; disclose --path P... [--salted] [-o FILE] FILE reveals leaves with proofs; verify --root DIGEST DISCLOSURE checks them")
+	fmt.Fprintln(os.Stderr, "  helios validate [--pii [--pii-dict FILE]... [--pii-action warn|reject|seal --encrypt-to PUB... --sealed-dir DIR]] <file.json>...  Validate memory object files and flag likely PII in their values (reject exits 2)")
+	fmt.Fprintln(os.Stderr, "  helios seal --encrypt-to PUB... [-o FILE] <file.json>  Encrypt an object's value to X25519 recipients, keeping its content hash in the clear")
+	fmt.Fprintln(os.Stderr, "  helios open --identity KEY... [-o FILE] <sealed.json>  Decrypt a sealed object and check it against its content hash")
+	fmt.Fprintln(os.Stderr, "  helios bench [--compare-stdlib] [--benchtime D] [--seed S --count N | <corpus>] [--json]  Measure canonicalization time and allocations per object, against encoding/json with --compare-stdlib")
+	fmt.Fprintln(os.Stderr, "  helios gen-corpus [--seed S] [--count N] [-o corpus.ndjson] [--freeze hashes.json | --check hashes.json]  Generate a reproducible pseudo-random corpus and freeze or check its hashes")
+	fmt.Fprintln(os.Stderr, "  helios sign-vectors --key KEY [--author NAME] [-o FILE] <vectors.json>  Sign a vectors file into a detached envelope (default FILE: vectors.json.sig)")
+	fmt.Fprintln(os.Stderr, "  helios doctor [--root DIR] [--clock-url URL] [--json]  Diagnose Unicode tables, locale, filesystem, and clock, and run the built-in vectors")
+	fmt.Fprintln(os.Stderr, "  helios admin health|ready|config|reload [--url URL] [--cacert FILE] [--cert FILE --key FILE] [--timeout D]  Query the /healthz, /readyz, and /config endpoints of store serve, or reload its --rules; ready fails unless the store answers and the self-check passes")
+	fmt.Fprintln(os.Stderr, "  helios selfcheck [--json]     Check that this build hashes like every platform: Unicode tables, key order, built-in vectors, and integer, number, and byte-order probes")
+	fmt.Fprintln(os.Stderr, "  helios schema [NAME...] [-o DIR] [--validate FILE]  List, print, or write the JSON Schemas of Helios's wire formats, or validate a file")
+	fmt.Fprintln(os.Stderr, "  helios consume --brokers HOSTS --topic T  Validate and hash each Kafka message (--output-topic, --reject-topic, --metrics-addr)")
+	fmt.Fprintln(os.Stderr, "  helios store put|get|ls|serve|migrate|compact|fsck|tenants|export|usage|apply-policy|hold|holds|similar|history|changes [--root DIR [--engine files|log] | --postgres DSN] [--tenant ID] [--quotas FILE] [--search-index FILE] [--vectors FILE [--embedder NAME]] [--changes FILE] [--key-policy permissive|strict] [--extensions] [--max-size N] [--regions LIST] [--audit-log FILE]  Content-addressed object store and HTTP gateway (get accepts hash prefixes, --as-of TIME, --version N; ls --abbrev --prefix --category --limit --cursor --filter EXPR; export --residency REGION --filter EXPR; fsck --format text|json|csv|tsv; hold --reason TEXT|--release KEY...; holds --policy FILE --json; changes --since N --follow; serve --writable --metrics --anomaly-rules FILE --webhook URL --exec-hook CMD --tenants --checkpoint-log FILE --max-body N --tls-cert FILE --tls-key FILE --client-ca FILE --identities FILE --rules FILE; --verify-reads)")
+	fmt.Fprintln(os.Stderr, "  helios search --search-index FILE [--tenant ID] <query>  Find keys whose values contain every word (--reindex, --limit N, --json)")
+	fmt.Fprintln(os.Stderr, "  helios shard-stats [--filter EXPR] [--format text|json|csv|tsv] [--root DIR | <corpus>]  Check hash prefix distribution and recommend a shard width")
+	fmt.Fprintln(os.Stderr, "  helios --version [--json]    Show version; --json adds the compiler, platform, cgo status, and module versions")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Global flags:")
+	fmt.Fprintln(os.Stderr, "  --verbose                    Show multi-line errors with code, path, and input excerpt")
 }
 
-func runHash(path string) error {
-	data, err := os.ReadFile(path)
+func runHash(args []string) error {
+	fs := flag.NewFlagSet("hash", flag.ContinueOnError)
+	draft := fs.Bool("draft", false, "compute a draft hash, filling a missing created_at or source with its placeholder")
+	withSimhash := fs.Bool("simhash", false, "also print the similarity digest of each value")
+	path := fs.String("path", "", "hash only the sub-value at this path (e.g. $.value.config)")
+	relsFrom := fs.String("relationships-from", "", "merge relationships {from, key, type} from this JSON array, NDJSON file, or directory into the objects before hashing")
+	parallelThreshold := fs.Int("parallel-threshold", canon.DefaultParallelThreshold, "serialize the elements of arrays and maps at least this long concurrently (0: never)")
+	expect := fs.String("expect", "", "fail unless the object hashes to this hash")
+	reference := fs.String("reference", "", "with --expect, the canonical bytes the expected hash was computed from, to explain a mismatch")
+	profile := fs.String("profile", "", "hash under this profile, a profile hash or a profile JSON file, e.g. one with category_domains")
+	positional, err := parseFlags(fs, args)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one input file, got %d", len(positional))
 	}
 
-	dec := json.NewDecoder(strings.NewReader(string(data)))
-	dec.UseNumber()
-
-	var input map[string]interface{}
-	if err := dec.Decode(&input); err != nil {
-		return fmt.Errorf("failed to parse JSON: %w", err)
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	if err := canon.ValidateIngestValue(input["value"]); err != nil {
+	objs, batch, err := ingest.ParseDocument(data)
+	if err != nil {
 		return err
 	}
+	if *relsFrom != "" {
+		edges, err := corpus.LoadEdges(*relsFrom)
+		if err != nil {
+			return err
+		}
+		ingest.MergeEdges(objs, edges)
+	}
 
-	obj := mapToMemoryObject(input)
-	h, err := hash.ContentHash(obj)
-	if err != nil {
-		return fmt.Errorf("hash computation failed: %w", err)
+	if *expect != "" && len(objs) != 1 {
+		return fmt.Errorf("--expect checks a single object, got %d", len(objs))
+	}
+	if *reference != "" && (*expect == "" || *draft || *path != "") {
+		return fmt.Errorf("--reference explains a content hash mismatch and requires --expect without --draft or --path")
+	}
+
+	hashFn := hash.ContentHash
+	if *parallelThreshold > 0 {
+		opts := canon.ParallelOptions{Threshold: *parallelThreshold}
+		hashFn = func(obj object.MemoryObject) (string, error) { return hash.ContentHashParallel(obj, opts) }
+	}
+	switch {
+	case *draft && *path != "":
+		return fmt.Errorf("--draft and --path cannot be combined")
+	case *draft:
+		hashFn = hash.DraftHash
+	case *path != "":
+		hashFn = func(obj object.MemoryObject) (string, error) { return hash.PathHash(obj, *path) }
+	}
+	if *profile != "" {
+		if *draft || *path != "" {
+			return fmt.Errorf("--profile computes content hashes and cannot be combined with --draft or --path")
+		}
+		prof, err := readProfile(*profile)
+		if err != nil {
+			return fmt.Errorf("--profile: %w", err)
+		}
+		p, err := hash.LookupProfile(prof)
+		if err != nil {
+			return fmt.Errorf("--profile: %w", err)
+		}
+		hashFn = p.ContentHash
+	}
+	hashes := make([]string, len(objs))
+	for i, obj := range objs {
+		if hashes[i], err = hashFn(obj); err != nil {
+			if batch {
+				err = fmt.Errorf("object %d (key %q): %w", i, obj.Key, err)
+			}
+			return fmt.Errorf("hash computation failed: %w", err)
+		}
 	}
 
-	fmt.Println(h)
+	for i, h := range hashes {
+		line := h
+		if *withSimhash {
+			d, err := simhash.Value(objs[i].Value)
+			if err != nil {
+				return fmt.Errorf("simhash computation failed: %w", err)
+			}
+			line += "  " + d.String()
+		}
+		if batch {
+			line += "  " + objs[i].Key
+		}
+		fmt.Println(line)
+	}
+	if *expect != "" {
+		return checkExpected(objs[0], hashes[0], *expect, *reference)
+	}
 	return nil
 }
 
-func runVerify(path string) error {
-	results, err := verify.VerifyVectors(path)
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	parallel := fs.Int("parallel", 1, "number of vectors to verify concurrently")
+	sortBy := fs.String("sort-by", "", "display order: status or name (default: file order)")
+	topSlow := fs.Int("top-slow", 0, "after the results, list the N vectors that took longest to verify")
+	endpoint := fs.String("endpoint", "", "verify a running service's hash API at this base URL")
+	unicodeImpact := fs.Bool("unicode-impact", false, "report which hashes of a store directory or vectors file this build's Unicode tables change")
+	asJSON := fs.Bool("json", false, "print the --unicode-impact report as JSON")
+	update := fs.Bool("update", false, "re-freeze the positive vectors that fail with this build's hashes (requires --reason)")
+	reason := fs.String("reason", "", "why the vectors are re-frozen, recorded in the file's refreezes")
+	requireSig := fs.Bool("require-signature", false, "trust the expected hashes only if the file carries a valid signature by a --pub key")
+	var pubs stringList
+	fs.Var(&pubs, "pub", "trusted PEM public key for --require-signature (repeatable)")
+	sigPath := fs.String("signature", "", "signature envelope for --require-signature (default: the vectors file with a .sig suffix)")
+	var hooks hookFlags
+	hooks.register(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *unicodeImpact {
+		if len(positional) != 1 {
+			return fmt.Errorf("expected exactly one store directory or vectors file, got %d", len(positional))
+		}
+		return runUnicodeImpact(positional[0], *asJSON)
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one vectors file, got %d", len(positional))
+	}
+	if *update {
+		if *endpoint != "" {
+			return fmt.Errorf("--update re-freezes with this build's hashes and cannot be combined with --endpoint")
+		}
+		return runVerifyUpdate(positional[0], *reason)
+	}
+	if *reason != "" {
+		return fmt.Errorf("--reason is only used with --update")
+	}
+	if *topSlow < 0 {
+		return fmt.Errorf("--top-slow must not be negative, got %d", *topSlow)
+	}
+	if !*requireSig && (len(pubs) > 0 || *sigPath != "") {
+		return fmt.Errorf("--pub and --signature are only used with --require-signature")
+	}
 
-	for _, r := range results {
+	var vf *verify.VectorsFile
+	if *requireSig {
+		keys, err := loadPublicKeys(pubs)
+		if err != nil {
+			return err
+		}
+		if *sigPath == "" {
+			*sigPath = positional[0] + ".sig"
+		}
+		if vf, err = verify.LoadSignedVectors(positional[0], *sigPath, keys...); err != nil {
+			return err
+		}
+		fmt.Printf("signature: OK (%s)\n", *sigPath)
+	} else if vf, err = verify.LoadVectors(positional[0]); err != nil {
+		return err
+	}
+	if p := vf.Provenance; p != nil {
+		fmt.Printf("provenance: generated by %s for spec %s on %s by %s\n", p.Generator, p.SpecVersion, p.Date, p.Author)
+	}
+	if vf.UnicodeVersion != "" && vf.UnicodeVersion != canon.TablesVersion() {
+		fmt.Printf("note: vectors were hashed with Unicode %s tables, this build has %s\n", vf.UnicodeVersion, canon.TablesVersion())
+	}
+	results, err := verify.VerifyVectorsFile(vf, verify.Options{Workers: *parallel, Endpoint: *endpoint})
+
+	display := make([]verify.VerifyResult, len(results))
+	copy(display, results)
+	switch *sortBy {
+	case "":
+	case "status":
+		// Failures first; file order within each group
+		sort.SliceStable(display, func(i, j int) bool { return !display[i].Pass && display[j].Pass })
+	case "name":
+		sort.SliceStable(display, func(i, j int) bool { return display[i].VectorID < display[j].VectorID })
+	default:
+		return fmt.Errorf("unknown --sort-by value %q (want status or name)", *sortBy)
+	}
+
+	for _, r := range display {
 		status := "PASS"
 		if !r.Pass {
 			status = "FAIL"
 		}
-		fmt.Printf("  %s: %s\n", r.Name, status)
+		fmt.Printf("  %s: %s\n", r.VectorID, status)
 		if !r.Pass {
 			fmt.Printf("    expected: %s\n", r.Expected)
 			fmt.Printf("    got:      %s\n", r.Got)
 		}
 	}
 
+	var events []notify.Event
+	for _, r := range results {
+		if !r.Pass {
+			events = append(events, notify.Event{
+				Kind:     notify.KindVectorMismatch,
+				Source:   "verify " + positional[0],
+				Key:      r.VectorID,
+				Expected: r.Expected,
+				Actual:   r.Got,
+			})
+		}
+	}
+	hooks.fire(events)
+	printSlowest(results, *topSlow)
+
 	if err != nil {
 		return err
 	}
@@ -109,40 +538,47 @@ func runVerify(path string) error {
 	return nil
 }
 
-func mapToMemoryObject(input map[string]interface{}) object.MemoryObject {
-	obj := object.MemoryObject{}
-
-	if v, ok := input["category"].(string); ok {
-		obj.Category = v
-	}
-	if v, ok := input["created_at"].(string); ok {
-		obj.CreatedAt = v
+// printSlowest lists the n results that took longest to verify, slowest
+// first, with their canonical size.
+func printSlowest(results []verify.VerifyResult, n int) {
+	if n == 0 || len(results) == 0 {
+		return
 	}
-	if v, ok := input["key"].(string); ok {
-		obj.Key = v
+	slow := make([]verify.VerifyResult, len(results))
+	copy(slow, results)
+	sort.SliceStable(slow, func(i, j int) bool { return slow[i].Duration > slow[j].Duration })
+	if n < len(slow) {
+		slow = slow[:n]
 	}
-	if v, ok := input["source"].(string); ok {
-		obj.Source = v
+	fmt.Printf("\nSlowest %d vectors:\n", len(slow))
+	for _, r := range slow {
+		size := "-"
+		if r.Size > 0 {
+			size = fmt.Sprintf("%d bytes", r.Size)
+		}
+		fmt.Printf("  %10s  %12s  %s\n", r.Duration.Round(time.Microsecond), size, r.VectorID)
 	}
-	obj.Value = input["value"]
+}
 
-	if rels, ok := input["relationships"].([]interface{}); ok {
-		for _, r := range rels {
-			if rm, ok := r.(map[string]interface{}); ok {
-				rel := object.Relationship{}
-				if k, ok := rm["key"].(string); ok {
-					rel.Key = k
-				}
-				if t, ok := rm["type"].(string); ok {
-					rel.Type = t
-				}
-				obj.Relationships = append(obj.Relationships, rel)
-			}
-		}
+// runVerifyUpdate re-freezes the failing vectors of path.
+func runVerifyUpdate(path, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("--update requires --reason explaining the intentional spec change")
 	}
-	if _, exists := input["relationships"]; exists && obj.Relationships == nil {
-		obj.Relationships = []object.Relationship{}
+	rf, err := verify.UpdateVectors(path, reason, time.Now())
+	if err != nil {
+		return err
 	}
-
-	return obj
+	if rf == nil {
+		fmt.Println("All vectors: PASS; nothing to re-freeze")
+		return nil
+	}
+	for _, c := range rf.Vectors {
+		fmt.Printf("  %s: %s -> %s\n", c.VectorID, c.OldHash, c.NewHash)
+	}
+	fmt.Printf("\nRe-froze %d vectors in %s (reason recorded: %q)\n", len(rf.Vectors), path, reason)
+	if _, err := os.Stat(path + ".sig"); err == nil {
+		fmt.Printf("note: %s.sig no longer matches; re-sign with helios sign-vectors\n", path)
+	}
+	return nil
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/merge"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// runMerge merges two revisions of an object derived from a common base
+// and writes the merged object, or with conflicts the merged fields with
+// a conflict marker at each, listing the conflicts on stderr and exiting
+// 2 so scripts can tell conflicts from errors.
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	out := fs.String("o", "", "write the merged object to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 3 {
+		return fmt.Errorf("expected base, ours, and theirs files, got %d arguments", len(positional))
+	}
+	var revs [3]object.MemoryObject
+	for i, path := range positional {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if revs[i], err = ingest.ParseObject(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	r, err := merge.Objects(revs[0], revs[1], revs[2])
+	if err != nil {
+		return err
+	}
+	if len(r.Conflicts) > 0 {
+		if err := writeJSON(*out, r.Fields); err != nil {
+			return err
+		}
+		for _, c := range r.Conflicts {
+			fmt.Fprintf(os.Stderr, "conflict  %s\n", c.Path)
+		}
+		return &exitError{code: 2, err: fmt.Errorf("%d conflicts; resolve the %s markers and hash the result", len(r.Conflicts), merge.ConflictKey)}
+	}
+	if err := writeJSON(*out, r.Object); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "merged  %s\n", r.Hash)
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/notify"
+)
+
+// hookFlags are the --webhook and --exec-hook flags shared by commands
+// that detect integrity violations. The webhook HMAC secret is read from
+// HELIOS_WEBHOOK_SECRET so it stays out of process listings.
+type hookFlags struct {
+	webhooks stringList
+	execs    stringList
+}
+
+func (h *hookFlags) register(fs *flag.FlagSet) {
+	fs.Var(&h.webhooks, "webhook", "POST mismatches as JSON to this URL (repeatable)")
+	fs.Var(&h.execs, "exec-hook", "run this shell command for each mismatch (repeatable)")
+}
+
+func (h *hookFlags) notifier() *notify.Notifier {
+	n := &notify.Notifier{}
+	secret := []byte(os.Getenv("HELIOS_WEBHOOK_SECRET"))
+	for _, u := range h.webhooks {
+		n.Hooks = append(n.Hooks, &notify.Webhook{URL: u, Secret: secret})
+	}
+	for _, c := range h.execs {
+		n.Hooks = append(n.Hooks, &notify.Exec{Command: c, Stdout: os.Stderr, Stderr: os.Stderr})
+	}
+	return n
+}
+
+// fire delivers events to the configured hooks. Hook failures are
+// reported on stderr but do not replace the verification result.
+func (h *hookFlags) fire(events []notify.Event) {
+	if err := h.notifier().Fire(events); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: notification failed: %v\n", err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/repl"
+)
+
+// runRepl runs the canonicalization sandbox on standard input: each JSON
+// fragment pasted is answered with its canonical form, the changes
+// normalization made, any validation error, and its hash. Fragments are
+// kept in --history across sessions, for :history and :N to recall.
+func runRepl(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	history := fs.String("history", defaultReplHistory(), "file to keep evaluated fragments in across sessions (\"\" keeps none)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	s, err := repl.NewSession(*history)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	s.Verbose = errorStyle == canon.Verbose
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		s.Prompt = true
+		os.Stdout.WriteString("helios repl: paste JSON to canonicalize it; :help lists commands\n")
+	}
+	return s.Run(os.Stdin, os.Stdout)
+}
+
+// defaultReplHistory returns ~/.helios_history, or "" if there is no home
+// directory to keep it in.
+func defaultReplHistory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".helios_history")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/holeyfield33-art/helios/internal/schema"
+)
+
+// runSchema lists the published JSON Schemas, prints or writes them, or
+// validates a document against one.
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	dir := fs.String("o", "", "write each schema to DIR/NAME.json (every schema if no NAME is given)")
+	validate := fs.String("validate", "", "check this JSON file against the named schema")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	reg := schema.Registry{}
+	for _, name := range positional {
+		if _, ok := reg.Get(name); !ok {
+			return fmt.Errorf("unknown schema %q (have %v)", name, reg.Names())
+		}
+	}
+
+	switch {
+	case *validate != "":
+		if len(positional) != 1 {
+			return fmt.Errorf("--validate needs exactly one schema name")
+		}
+		data, err := os.ReadFile(*validate)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", *validate, err)
+		}
+		s, _ := schema.Document(positional[0])
+		if err := schema.Validate(s, data); err != nil {
+			return fmt.Errorf("%s: %w", *validate, err)
+		}
+		fmt.Printf("%s: valid %s\n", *validate, positional[0])
+	case *dir != "":
+		names := positional
+		if len(names) == 0 {
+			names = reg.Names()
+		}
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			return err
+		}
+		for _, name := range names {
+			doc, _ := reg.Get(name)
+			if err := os.WriteFile(filepath.Join(*dir, name+".json"), doc, 0o644); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d schemas to %s\n", len(names), *dir)
+	case len(positional) == 0:
+		for _, name := range reg.Names() {
+			s, _ := schema.Document(name)
+			fmt.Printf("%-14s %s\n", name, s.Title)
+		}
+	default:
+		for _, name := range positional {
+			doc, _ := reg.Get(name)
+			if _, err := os.Stdout.Write(doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
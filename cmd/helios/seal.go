@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/seal"
+)
+
+// runSeal encrypts an object's value to X25519 recipients, keeping its
+// content hash in the clear.
+func runSeal(args []string) error {
+	fs := flag.NewFlagSet("seal", flag.ContinueOnError)
+	var to stringList
+	fs.Var(&to, "encrypt-to", "PEM X25519 public key of a recipient (repeatable)")
+	out := fs.String("o", "", "write the sealed envelope to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("at least one --encrypt-to is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one object file, got %d arguments", len(positional))
+	}
+	var recipients []*ecdh.PublicKey
+	for _, p := range to {
+		r, err := seal.LoadRecipient(p)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, r)
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	obj, err := ingest.ParseObject(data)
+	if err != nil {
+		return err
+	}
+	env, err := seal.Seal(obj, recipients...)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(*out, env); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Println(env.ContentHash)
+	}
+	return nil
+}
+
+// runOpen decrypts a sealed envelope and checks the object against its
+// content hash.
+func runOpen(args []string) error {
+	fs := flag.NewFlagSet("open", flag.ContinueOnError)
+	var ids stringList
+	fs.Var(&ids, "identity", "PEM X25519 private key to decrypt with (repeatable)")
+	out := fs.String("o", "", "write the object to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("at least one --identity is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one sealed envelope, got %d arguments", len(positional))
+	}
+	var identities []*ecdh.PrivateKey
+	for _, p := range ids {
+		id, err := seal.LoadIdentity(p)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, id)
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read envelope: %w", err)
+	}
+	var env seal.Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("failed to parse sealed envelope: %w", err)
+	}
+	obj, err := seal.Open(&env, identities...)
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, obj)
+}
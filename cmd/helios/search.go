@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/search"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// runSearch prints the keys whose current values contain every word of
+// the query, best match first, as "<hash>  <key>" lines. The index is the
+// one store commands maintain with --search-index; --reindex rebuilds it
+// from every namespace of the store.
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	loc := addStoreFlags(fs)
+	reindex := fs.Bool("reindex", false, "rebuild the index from the store instead of searching")
+	limit := fs.Int("limit", 20, "print at most this many matches (0 for all)")
+	asJSON := fs.Bool("json", false, "print matches as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	path := *loc.searchIndex
+	if path == "" {
+		return fmt.Errorf("search requires --search-index FILE (or $HELIOS_SEARCH_INDEX)")
+	}
+	// The index is read or rebuilt directly, never updated through the
+	// store.
+	*loc.searchIndex = ""
+
+	if *reindex {
+		if len(positional) > 0 {
+			return fmt.Errorf("search --reindex takes no query")
+		}
+		return reindexStore(loc, path)
+	}
+
+	query := strings.Join(positional, " ")
+	if len(search.Tokenize(query)) == 0 {
+		return fmt.Errorf("expected a query with at least one word")
+	}
+	if *loc.tenant != "" && !store.ValidTenant(*loc.tenant) {
+		return fmt.Errorf("%w: %q", store.ErrInvalidTenant, *loc.tenant)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no search index at %s; build one with search --reindex", path)
+	}
+	idx, err := search.Open(path)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	hits := idx.Search(*loc.tenant, query)
+	if *limit > 0 && len(hits) > *limit {
+		hits = hits[:*limit]
+	}
+	if *asJSON {
+		if hits == nil {
+			hits = []search.Hit{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(hits)
+	}
+	for _, h := range hits {
+		fmt.Printf("%s  %s\n", h.Hash, h.Key)
+	}
+	return nil
+}
+
+// reindexStore rebuilds the index at path from the current object of every
+// key in the store's default namespace and tenants.
+func reindexStore(loc *storeLocation, path string) error {
+	if *loc.tenant != "" {
+		return fmt.Errorf("search --reindex indexes every tenant; --tenant does not apply")
+	}
+	ctx := context.Background()
+	s, err := loc.open(ctx, false)
+	if err != nil {
+		return err
+	}
+	ids, err := s.Tenants(ctx)
+	if err != nil {
+		return err
+	}
+	n := 0
+	err = search.Rebuild(path, func(idx *search.Index) error {
+		for _, id := range append([]string{""}, ids...) {
+			ts := s
+			if id != "" {
+				if ts, err = s.OpenTenant(ctx, id); err != nil {
+					return err
+				}
+			}
+			entries, err := ts.Keys(ctx)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				data, err := ts.Get(ctx, e.Hash)
+				if err != nil {
+					return fmt.Errorf("key %q: %w", e.Key, err)
+				}
+				if err := idx.Index(ctx, id, e.Key, e.Hash, data); err != nil {
+					return fmt.Errorf("key %q: %w", e.Key, err)
+				}
+				n++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "indexed %d keys from %s into %s\n", n, loc, path)
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/corpus"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/store"
+	"github.com/holeyfield33-art/helios/internal/tabular"
+)
+
+type shardReport struct {
+	Objects     int                `json:"objects"`
+	Width       int                `json:"width"`
+	Recommended int                `json:"recommended_width"`
+	MaxPerShard int                `json:"max_per_shard"`
+	Widths      []store.ShardStats `json:"widths"`
+}
+
+// runShardStats analyzes the hash prefix distribution of a corpus or store
+// at shard widths 1-4 (and the configured width), recommends a width, and
+// fails if the hashes cluster in a way that breaks the assumption that
+// shards fill evenly. With --filter, only the objects matching the
+// expression are counted: in a store, the current objects of its keys.
+// --format csv or tsv writes a row per width.
+func runShardStats(args []string) error {
+	fs := flag.NewFlagSet("shard-stats", flag.ContinueOnError)
+	root := fs.String("root", "", "analyze the objects in this store instead of a corpus")
+	width := fs.Int("width", 0, "shard width to validate (default: the store's, or "+fmt.Sprint(store.DefaultShardWidth)+")")
+	maxPerShard := fs.Int("max-per-shard", store.DefaultMaxPerShard, "largest acceptable mean shard size")
+	output := addReportFlags(fs)
+	var where filterFlag
+	fs.Var(&where, "filter", filterUsage)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*root == "") == (len(positional) == 0) || len(positional) > 1 {
+		return fmt.Errorf("expected either one corpus path or --root")
+	}
+	if *width < 0 || *width > store.MaxShardWidth {
+		return fmt.Errorf("--width must be between 0 and %d", store.MaxShardWidth)
+	}
+	format, err := output.get()
+	if err != nil {
+		return err
+	}
+
+	var hashes []string
+	configured := store.DefaultShardWidth
+	if *root != "" {
+		b, err := store.OpenFS(*root)
+		if err != nil {
+			return err
+		}
+		configured = b.Layout().ShardWidth
+		if hashes, err = storeHashes(context.Background(), store.New(b), where); err != nil {
+			return err
+		}
+	} else {
+		records, err := corpus.Load(positional[0])
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if !where.match(r.Object) {
+				continue
+			}
+			h, err := hash.ContentHash(r.Object)
+			if err != nil {
+				return fmt.Errorf("%s: %w", r.Origin, err)
+			}
+			hashes = append(hashes, h)
+		}
+	}
+	if *width > 0 {
+		configured = *width
+	}
+
+	report := shardReport{Width: configured, MaxPerShard: *maxPerShard}
+	for w := 1; w <= max(4, configured); w++ {
+		if w > 4 && w != configured {
+			continue
+		}
+		report.Widths = append(report.Widths, store.AnalyzeShards(hashes, w))
+	}
+	report.Objects = report.Widths[0].Objects
+	report.Recommended = store.RecommendShardWidth(report.Objects, *maxPerShard)
+
+	var clustered []store.ShardStats
+	for _, st := range report.Widths {
+		if st.Clustered {
+			clustered = append(clustered, st)
+		}
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	case "csv", "tsv":
+		if err := tabular.Write(os.Stdout, shardTable(report), format); err != nil {
+			return err
+		}
+	default:
+		printShardReport(report, clustered)
+	}
+	if len(clustered) > 0 {
+		return fmt.Errorf("hash prefixes are clustered at shard width %d", clustered[0].Width)
+	}
+	return nil
+}
+
+// shardTable returns a row of r per shard width. The hottest shards are
+// listed in one cell, as "prefix:count" separated by spaces.
+func shardTable(r shardReport) *tabular.Table {
+	t := tabular.New("width", "shards", "objects", "mean", "min", "max", "empty",
+		"tested", "chi_square", "p_value", "clustered", "hot", "configured")
+	for _, st := range r.Widths {
+		var hot []string
+		for _, h := range st.Hot {
+			hot = append(hot, fmt.Sprintf("%s:%d", h.Prefix, h.Count))
+		}
+		// Untested widths leave the test's cells empty, not zero.
+		var chi, p interface{} = "", ""
+		if st.Tested {
+			chi, p = st.ChiSquare, st.PValue
+		}
+		t.Add(st.Width, st.Shards, st.Objects, st.Mean, st.Min, st.Max, st.Empty,
+			st.Tested, chi, p, st.Clustered, strings.Join(hot, " "),
+			st.Width == r.Width)
+	}
+	return t
+}
+
+// storeHashes returns the hashes of the objects in s: all of them, or with
+// a filter the current objects of the keys that match it.
+func storeHashes(ctx context.Context, s *store.Store, where filterFlag) ([]string, error) {
+	if where.f == nil {
+		return s.Hashes(ctx)
+	}
+	entries, err := s.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, e := range entries {
+		obj, err := storeObject(ctx, s, e)
+		if err != nil {
+			return nil, err
+		}
+		if where.match(obj) {
+			hashes = append(hashes, e.Hash)
+		}
+	}
+	return hashes, nil
+}
+
+func printShardReport(r shardReport, clustered []store.ShardStats) {
+	fmt.Printf("%d unique objects\n\n", r.Objects)
+	fmt.Printf("%-6s %8s %10s %8s %8s %8s %9s\n", "width", "shards", "mean", "min", "max", "empty", "p-value")
+	for _, st := range r.Widths {
+		p := "-"
+		if st.Tested {
+			p = fmt.Sprintf("%.3g", st.PValue)
+		}
+		mark := ""
+		if st.Width == r.Width {
+			mark = "  (configured)"
+		}
+		fmt.Printf("%-6d %8d %10.1f %8d %8d %8d %9s%s\n", st.Width, st.Shards, st.Mean, st.Min, st.Max, st.Empty, p, mark)
+	}
+	fmt.Println()
+
+	mean := float64(r.Objects) / float64(int(1)<<(4*r.Width))
+	switch {
+	case mean > float64(r.MaxPerShard):
+		fmt.Printf("configured width %d: %.0f objects per shard exceeds %d; use width %d\n", r.Width, mean, r.MaxPerShard, r.Recommended)
+	case r.Width > r.Recommended:
+		fmt.Printf("configured width %d: ok, though width %d would keep shards under %d with fewer directories\n", r.Width, r.Recommended, r.MaxPerShard)
+	default:
+		fmt.Printf("configured width %d: ok (recommended %d for at most %d objects per shard)\n", r.Width, r.Recommended, r.MaxPerShard)
+	}
+	if len(clustered) == 0 {
+		fmt.Println("no clustering detected")
+		return
+	}
+	for _, st := range clustered {
+		fmt.Printf("width %d: distribution is not uniform (chi-square %.1f, p=%.3g)", st.Width, st.ChiSquare, st.PValue)
+		if len(st.Hot) > 0 {
+			fmt.Print("; hottest shards:")
+			for _, h := range st.Hot {
+				fmt.Printf(" %s (%d)", h.Prefix, h.Count)
+			}
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/signing"
+	"github.com/holeyfield33-art/helios/internal/verify"
+)
+
+// runSignVectors signs a vectors file into a detached DSSE envelope,
+// first stamping its provenance if --author is given.
+func runSignVectors(args []string) error {
+	fs := flag.NewFlagSet("sign-vectors", flag.ContinueOnError)
+	var keys stringList
+	fs.Var(&keys, "key", "PEM PKCS#8 private key (repeatable)")
+	author := fs.String("author", "", "record provenance (this generator, the spec version, today, and AUTHOR) in the file before signing")
+	out := fs.String("o", "", "write the envelope to this file (default: the vectors file with a .sig suffix)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("--key is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one vectors file, got %d", len(positional))
+	}
+	path := positional[0]
+	if *out == "" {
+		*out = path + ".sig"
+	}
+
+	var signers []signing.Signer
+	for _, k := range keys {
+		s, err := signing.LoadSigner(k)
+		if err != nil {
+			return err
+		}
+		signers = append(signers, s)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read vectors file: %w", err)
+	}
+	vf, err := verify.ParseVectors(data)
+	if err != nil {
+		return err
+	}
+	// Never sign hashes this build disagrees with.
+	if _, err := verify.VerifyVectorsFile(vf, verify.Options{}); err != nil {
+		return fmt.Errorf("refusing to sign %s: %w", path, err)
+	}
+	if *author != "" {
+		data, err = verify.StampProvenance(data, verify.Provenance{
+			Generator:   "helios " + version,
+			SpecVersion: vf.SpecVersion,
+			Date:        time.Now().UTC().Format("2006-01-02"),
+			Author:      *author,
+		})
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	env, err := verify.SignVectors(data, signers...)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(*out, env); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "signed %s (%d vectors) into %s\n", path, len(vf.Vectors), *out)
+	return nil
+}
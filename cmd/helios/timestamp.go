@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/timestamp"
+)
+
+// runTimestamp obtains an RFC 3161 token over an object's content hash
+// (or an explicit --hash, such as a Merkle root) and stores it beside the
+// object as <file>.tsr.
+func runTimestamp(args []string) error {
+	fs := flag.NewFlagSet("timestamp", flag.ContinueOnError)
+	tsa := fs.String("tsa", "", "timestamping authority URL")
+	hexHash := fs.String("hash", "", "timestamp this SHA-256 hex digest instead of an object's content hash")
+	out := fs.String("o", "", "token output path (default <file>.tsr)")
+	timeout := fs.Duration("timeout", 30*time.Second, "TSA request timeout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *tsa == "" {
+		return fmt.Errorf("--tsa is required")
+	}
+
+	digest, path, err := timestampDigest(*hexHash, positional)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		if path == "" {
+			return fmt.Errorf("-o is required with --hash")
+		}
+		*out = path + ".tsr"
+	}
+
+	token, err := timestamp.Fetch(&http.Client{Timeout: *timeout}, *tsa, digest)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, token, 0644); err != nil {
+		return fmt.Errorf("failed to write token: %w", err)
+	}
+	fmt.Printf("%x  %s\n", digest, *out)
+	return nil
+}
+
+// runVerifyTimestamp verifies a stored token against an object or hash.
+func runVerifyTimestamp(args []string) error {
+	fs := flag.NewFlagSet("verify-timestamp", flag.ContinueOnError)
+	rootPath := fs.String("tsa-root", "", "trusted TSA root certificate (PEM)")
+	hexHash := fs.String("hash", "", "verify against this SHA-256 hex digest instead of an object")
+	tokenPath := fs.String("token", "", "token path (default <file>.tsr)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *rootPath == "" {
+		return fmt.Errorf("--tsa-root is required")
+	}
+
+	digest, path, err := timestampDigest(*hexHash, positional)
+	if err != nil {
+		return err
+	}
+	if *tokenPath == "" {
+		if path == "" {
+			return fmt.Errorf("--token is required with --hash")
+		}
+		*tokenPath = path + ".tsr"
+	}
+
+	pem, err := os.ReadFile(*rootPath)
+	if err != nil {
+		return fmt.Errorf("failed to read TSA root: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates in %s", *rootPath)
+	}
+	token, err := os.ReadFile(*tokenPath)
+	if err != nil {
+		return fmt.Errorf("failed to read token: %w", err)
+	}
+
+	info, err := timestamp.Verify(token, digest, roots)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%x existed by %s (TSA %s, serial %s)\n",
+		digest, info.GenTime.UTC().Format(time.RFC3339Nano), info.Signer.Subject.CommonName, info.SerialNumber)
+	return nil
+}
+
+// timestampDigest returns the digest named by --hash, or the content hash
+// of the single object file in positional along with its path.
+func timestampDigest(hexHash string, positional []string) ([]byte, string, error) {
+	if hexHash != "" {
+		if len(positional) != 0 {
+			return nil, "", fmt.Errorf("--hash and an input file are mutually exclusive")
+		}
+		digest, err := hex.DecodeString(hexHash)
+		if err != nil || len(digest) != 32 {
+			return nil, "", fmt.Errorf("--hash must be 64 hex characters")
+		}
+		return digest, "", nil
+	}
+	if len(positional) != 1 {
+		return nil, "", fmt.Errorf("expected exactly one input file, got %d", len(positional))
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	obj, err := ingest.ParseObject(data)
+	if err != nil {
+		return nil, "", err
+	}
+	h, err := hash.ContentHash(obj)
+	if err != nil {
+		return nil, "", err
+	}
+	digest, _ := hex.DecodeString(h)
+	return digest, positional[0], nil
+}
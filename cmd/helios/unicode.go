@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+	"github.com/holeyfield33-art/helios/internal/store/logstore"
+	"github.com/holeyfield33-art/helios/internal/verify"
+)
+
+// runUnicodeImpact reports which hashes of a store directory or vectors
+// file this build's NFC tables compute differently. Run it from a build
+// with upgraded golang.org/x/text before rolling the upgrade out.
+func runUnicodeImpact(path string, asJSON bool) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	var r *verify.UnicodeImpact
+	if fi.IsDir() {
+		s, err := openStoreDir(path)
+		if err != nil {
+			return err
+		}
+		r, err = verify.StoreUnicodeImpact(context.Background(), s)
+		if err != nil {
+			return err
+		}
+	} else {
+		vf, err := verify.LoadVectors(path)
+		if err != nil {
+			return err
+		}
+		r, err = verify.VectorsUnicodeImpact(vf)
+		if err != nil {
+			return err
+		}
+		if vf.UnicodeVersion != "" {
+			r.Pinned = vf.UnicodeVersion
+		}
+	}
+
+	if asJSON {
+		if err := writeJSON("", r); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("NFC tables: Unicode %s (pinned %s); %d checked\n", r.Tables, r.Pinned, r.Checked)
+		for _, c := range r.Changed {
+			fmt.Printf("  CHANGED  %s%s\n    recorded: %s\n    now:      %s\n", c.ID, keyList(c.Keys), c.Hash, c.Rehash)
+		}
+		for _, risk := range r.AtRisk {
+			fmt.Printf("  AT RISK  %s%s: unassigned %s\n", risk.ID, keyList(risk.Keys), strings.Join(risk.CodePoints, " "))
+		}
+		fmt.Printf("\n%d changed, %d at risk\n", len(r.Changed), len(r.AtRisk))
+	}
+	if len(r.Changed) > 0 {
+		return fmt.Errorf("%d hashes change under Unicode %s tables", len(r.Changed), r.Tables)
+	}
+	return nil
+}
+
+// openStoreDir opens the directory store at root, of either engine.
+func openStoreDir(root string) (*store.Store, error) {
+	if logstore.IsStore(root) {
+		b, err := logstore.Open(root, logstore.Options{})
+		if err != nil {
+			return nil, err
+		}
+		return store.New(b), nil
+	}
+	return store.Open(root)
+}
+
+func keyList(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(keys, ", ") + ")"
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/pii"
+	"github.com/holeyfield33-art/helios/internal/seal"
+)
+
+// runValidate checks that each file holds valid memory objects, and with
+// --pii scans their values for likely personal data. Findings are
+// reported by path and detector, never by the text they flag. The
+// --pii-action policy decides what a finding does: warn only, reject the
+// file (exit 2), or seal the object to --encrypt-to recipients, writing
+// the envelope to --sealed-dir.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	scan := fs.Bool("pii", false, "scan values for likely PII (emails, phone numbers, card numbers)")
+	actionName := fs.String("pii-action", "warn", "what to do with objects with PII findings: warn, reject, or seal")
+	var dicts, to stringList
+	fs.Var(&dicts, "pii-dict", "file of terms to flag, one per line (repeatable)")
+	fs.Var(&to, "encrypt-to", "PEM X25519 public key to seal flagged objects to (repeatable; --pii-action seal)")
+	sealedDir := fs.String("sealed-dir", ".", "directory to write sealed envelopes to (--pii-action seal)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("expected at least one object file")
+	}
+	action, err := pii.ParseAction(*actionName)
+	if err != nil {
+		return err
+	}
+	var recipients []*ecdh.PublicKey
+	if action == pii.Seal {
+		if len(to) == 0 {
+			return fmt.Errorf("--pii-action seal requires at least one --encrypt-to")
+		}
+		for _, p := range to {
+			r, err := seal.LoadRecipient(p)
+			if err != nil {
+				return err
+			}
+			recipients = append(recipients, r)
+		}
+	}
+	detectors := pii.Builtin()
+	for _, p := range dicts {
+		d, err := pii.LoadDictionary(p)
+		if err != nil {
+			return err
+		}
+		detectors = append(detectors, d)
+	}
+	scanner := pii.NewScanner(detectors...)
+
+	invalid, rejected := 0, 0
+	for _, path := range positional {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		objs, _, err := ingest.ParseDocument(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", path, err)
+			invalid++
+			continue
+		}
+		if !*scan {
+			continue
+		}
+		for _, obj := range objs {
+			findings, err := scanner.Scan(obj.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", path, obj.Key, err)
+			}
+			if len(findings) == 0 {
+				continue
+			}
+			for _, f := range findings {
+				fmt.Fprintf(os.Stderr, "  %s: %s: %s\n", path, obj.Key, f)
+			}
+			switch action {
+			case pii.Reject:
+				rejected++
+			case pii.Seal:
+				env, err := seal.Seal(obj, recipients...)
+				if err != nil {
+					return err
+				}
+				out := filepath.Join(*sealedDir, env.ContentHash+".sealed.json")
+				if err := writeJSON(out, env); err != nil {
+					return err
+				}
+				fmt.Printf("%s: %s sealed to %s\n", path, obj.Key, out)
+			}
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d files invalid", invalid, len(positional))
+	}
+	if rejected > 0 {
+		return &exitError{code: 2, err: fmt.Errorf("%d objects rejected for likely PII", rejected)}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/valuetree"
+)
+
+// runValueTree dispatches the value tree subcommands.
+func runValueTree(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a value-tree subcommand: root, disclose, or verify")
+	}
+	switch args[0] {
+	case "root":
+		return runValueTreeRoot(args[1:])
+	case "disclose":
+		return runValueTreeDisclose(args[1:])
+	case "verify":
+		return runValueTreeVerify(args[1:])
+	}
+	return fmt.Errorf("unknown value-tree subcommand %q (want root, disclose, or verify)", args[0])
+}
+
+// buildValueTree reads an object and builds the tree of its value, salted
+// with its commitment_salt if salted is set.
+func buildValueTree(path string, salted bool) (*valuetree.Tree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	obj, err := ingest.ParseObject(data)
+	if err != nil {
+		return nil, err
+	}
+	var salt []byte
+	if salted {
+		if obj.CommitmentSalt == "" {
+			return nil, fmt.Errorf("%s: --salted needs a commitment_salt; add one with helios commit -o", path)
+		}
+		if salt, err = hash.DecodeSalt(obj.CommitmentSalt); err != nil {
+			return nil, err
+		}
+	}
+	return valuetree.Build(obj.Value, salt)
+}
+
+// runValueTreeRoot prints the value digest of an object.
+func runValueTreeRoot(args []string) error {
+	fs := flag.NewFlagSet("value-tree root", flag.ContinueOnError)
+	salted := fs.Bool("salted", false, "salt the leaves with the object's commitment_salt")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one object file, got %d arguments", len(positional))
+	}
+	t, err := buildValueTree(positional[0], *salted)
+	if err != nil {
+		return err
+	}
+	fmt.Println(t.Root())
+	return nil
+}
+
+// runValueTreeDisclose writes the leaves of an object's value under the
+// given paths with their proofs, redacting the rest.
+func runValueTreeDisclose(args []string) error {
+	fs := flag.NewFlagSet("value-tree disclose", flag.ContinueOnError)
+	var paths stringList
+	fs.Var(&paths, "path", "disclose the leaves at or under this path, e.g. $.patient.age (repeatable)")
+	salted := fs.Bool("salted", false, "salt the leaves with the object's commitment_salt")
+	out := fs.String("o", "", "write the disclosure to this file instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("at least one --path is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one object file, got %d arguments", len(positional))
+	}
+	t, err := buildValueTree(positional[0], *salted)
+	if err != nil {
+		return err
+	}
+	d, err := t.Disclose(paths...)
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, d)
+}
+
+// runValueTreeVerify checks a disclosure against a value digest and
+// prints the disclosed leaves.
+func runValueTreeVerify(args []string) error {
+	fs := flag.NewFlagSet("value-tree verify", flag.ContinueOnError)
+	root := fs.String("root", "", "published value digest to check against (required)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *root == "" {
+		return fmt.Errorf("--root is required")
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("expected exactly one disclosure file, got %d arguments", len(positional))
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read disclosure: %w", err)
+	}
+	var d valuetree.Disclosure
+	if err := json.Unmarshal(data, &d); err != nil {
+		return fmt.Errorf("failed to parse disclosure: %w", err)
+	}
+	if err := valuetree.Verify(*root, &d); err != nil {
+		return err
+	}
+	for _, l := range d.Leaves {
+		fmt.Printf("  %s = %s\n", l.Path, l.Value)
+	}
+	fmt.Printf("\nDisclosure verified: %d of %d leaves\n", len(d.Leaves), d.Size)
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/graph"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/schema"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// writeRulesFile is the file store serve --rules reads, and reads again
+// on SIGHUP or POST /admin/reload. File names are relative to it.
+type writeRulesFile struct {
+	// KeyPolicy selects the validation profile writes are hashed under:
+	// permissive or strict. Empty keeps --key-policy.
+	KeyPolicy string `json:"key_policy,omitempty"`
+	// RelationshipTypes names a JSON file registering the relationship
+	// types objects may use: an array of names, or an object mapping
+	// types to their inverses as helios derive-inverse reads, which
+	// registers both.
+	RelationshipTypes string `json:"relationship_types,omitempty"`
+	// Schemas maps a category to a JSON Schema file the values of its
+	// objects must match.
+	Schemas map[string]string `json:"schemas,omitempty"`
+}
+
+// loadWriteRules reads a write rules file and the files it names. The
+// rules' digest covers the contents of all of them, so editing a schema
+// in place changes it.
+func loadWriteRules(path string) (*store.WriteRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f writeRulesFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid write rules %s: %w", path, err)
+	}
+	digest := sha256.New()
+	digest.Write(data)
+	dir := filepath.Dir(path)
+	read := func(name string) ([]byte, error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(digest, "\x00%s\x00%d\x00", name, len(data))
+		digest.Write(data)
+		return data, nil
+	}
+
+	rules := &store.WriteRules{}
+	if f.KeyPolicy != "" {
+		policy, err := canon.ParseKeyPolicy(f.KeyPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if rules.Pipeline, err = hash.ForKeyPolicy(policy); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if f.RelationshipTypes != "" {
+		data, err := read(f.RelationshipTypes)
+		if err != nil {
+			return nil, err
+		}
+		if rules.RelationshipTypes, err = parseRelationshipTypes(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.RelationshipTypes, err)
+		}
+	}
+	if len(f.Schemas) > 0 {
+		categories := make([]string, 0, len(f.Schemas))
+		for c := range f.Schemas {
+			categories = append(categories, c)
+		}
+		sort.Strings(categories)
+		schemas := make(map[string]*schema.Schema, len(categories))
+		for _, c := range categories {
+			data, err := read(f.Schemas[c])
+			if err != nil {
+				return nil, err
+			}
+			var s schema.Schema
+			if err := json.Unmarshal(data, &s); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Schemas[c], err)
+			}
+			schemas[canon.NormalizeString(c)] = &s
+		}
+		rules.Validate = func(obj object.MemoryObject) error {
+			s, ok := schemas[canon.NormalizeString(obj.Category)]
+			if !ok {
+				return nil
+			}
+			value, err := canon.CanonicalizeValue(obj.Value)
+			if err != nil {
+				return err
+			}
+			if err := schema.Validate(s, value); err != nil {
+				return fmt.Errorf("value of category %q: %w", obj.Category, err)
+			}
+			return nil
+		}
+	}
+	rules.Digest = hex.EncodeToString(digest.Sum(nil))
+	return rules, nil
+}
+
+// parseRelationshipTypes reads a registry of relationship types: an
+// array of names or an inverse-types object.
+func parseRelationshipTypes(data []byte) (map[string]bool, error) {
+	types := make(map[string]bool)
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		for _, n := range names {
+			if n = canon.NormalizeString(n); n == "" {
+				return nil, fmt.Errorf("relationship types must not be empty")
+			}
+			types[n] = true
+		}
+		return types, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("expected an array of relationship types or an object mapping each type to its inverse")
+	}
+	inverses, err := graph.NewInverseTypes(m)
+	if err != nil {
+		return nil, err
+	}
+	for t := range inverses {
+		types[t] = true
+	}
+	return types, nil
+}
@@ -1,6 +1,9 @@
 """Canonical serialization primitives for Helios Core (Python conformance)."""
 
+import base64
+import binascii
 import json
+import re
 import unicodedata
 
 
@@ -86,22 +89,66 @@ def _normalize_value(v):
 
 
 def sort_relationships(rels: list) -> list:
-    """Sort relationships by key first, then type as tie-breaker."""
-    return sorted(rels, key=lambda r: (r.key, r.type))
+    """Sort relationships by key first, then type as tie-breaker, then by the
+    schema version 2 attributes: weight, absent first, then note, absent first.
+    """
+    return sorted(rels, key=lambda r: (
+        r.key,
+        r.type,
+        r.weight is not None,
+        r.weight if r.weight is not None else 0,
+        r.note is not None,
+        r.note if r.note is not None else "",
+    ))
 
 
 def relationship_to_map(r) -> dict:
-    """Convert a Relationship to an explicit dict. Never rely on dataclass ordering."""
-    return {"key": r.key, "type": r.type}
+    """Convert a Relationship to an explicit dict. Never rely on dataclass ordering.
+    Absent schema version 2 attributes are omitted, never written as null.
+    """
+    m = {"key": r.key, "type": r.type}
+    if r.weight is not None:
+        m["weight"] = r.weight
+    if r.note is not None:
+        m["note"] = r.note
+    return m
 
 
-def validate_schema_version(input: dict) -> None:
-    """Validate RULE-001: _helios_schema_version must be present and equal to \"1\"."""
+def validate_schema_version(input: dict, versions: tuple = ("1",)) -> None:
+    """Validate RULE-001: _helios_schema_version must be present and one of versions."""
     if "_helios_schema_version" not in input:
         raise ValueError("CANON_ERR_SCHEMA_VERSION_MISSING: _helios_schema_version field is required")
     v = input["_helios_schema_version"]
-    if not isinstance(v, str) or v != "1":
-        raise ValueError(f"CANON_ERR_SCHEMA_VERSION_INVALID: _helios_schema_version must be string \"1\", got {v!r}")
+    if not isinstance(v, str) or v not in versions:
+        want = " or ".join(f'\"{x}\"' for x in versions)
+        raise ValueError(f"CANON_ERR_SCHEMA_VERSION_INVALID: _helios_schema_version must be string {want}, got {v!r}")
+
+
+def validate_relationships(input: dict) -> None:
+    """Validate RULE-015 for an object that declares schema version 2.
+
+    A relationship may have only key, type, weight (an integer within
+    signed 64-bit bounds), and note (a string). Version 1 objects are not
+    checked: members besides key and type are ignored, as they always were.
+    """
+    if input.get("_helios_schema_version") != "2":
+        return
+    for i, r in enumerate(input.get("relationships") or []):
+        if not isinstance(r, dict):
+            continue
+        for name, v in r.items():
+            path = f"relationships[{i}].{name}"
+            if name in ("key", "type"):
+                continue
+            if name == "note":
+                if not isinstance(v, str):
+                    raise ValueError(f"CANON_ERR_RELATIONSHIP_ATTRIBUTE_INVALID: {type(v).__name__}, not a string, at {path}")
+            elif name == "weight":
+                if isinstance(v, bool) or isinstance(v, str):
+                    raise ValueError(f"CANON_ERR_RELATIONSHIP_ATTRIBUTE_INVALID: {type(v).__name__}, not an integer, at {path}")
+                validate_ingest_value(v, path)
+            else:
+                raise ValueError(f"CANON_ERR_RELATIONSHIP_ATTRIBUTE_INVALID: unknown attribute at {path}")
 
 
 def validate_ingest_value(v, path: str = "") -> None:
@@ -131,3 +178,106 @@ def validate_ingest_value(v, path: str = "") -> None:
         pass  # valid
     else:
         raise ValueError(f"Unsupported type {type(v)} at {path}")
+
+
+# Section 3.9: map key prefixes reserved for extensions, and the registered
+# extensions, each used as the only member of its object.
+RESERVED_KEY_PREFIXES = ("$", "_helios_")
+BYTES_KEY = "$bytes"
+EXTENSION_KEYS = (BYTES_KEY,)
+
+# Section 3.8: the length limit of a binary value, and the unpadded
+# base64url alphabet it is written in.
+MAX_BYTES_LEN = 16 << 20
+_BASE64URL = re.compile(r"[A-Za-z0-9_-]*")
+
+
+def decode_bytes(s, path: str = BYTES_KEY) -> bytes:
+    """Decode the unpadded base64url form of a binary value (RULE-013).
+
+    Accepts only the canonical encoding: no padding, no line breaks, and
+    no set bits after the last full byte.
+    """
+    if not isinstance(s, str):
+        raise ValueError(f"CANON_ERR_BYTES_INVALID: {type(s).__name__}, not a string, at {path}")
+    if len(s) > (MAX_BYTES_LEN * 8 + 5) // 6:
+        raise ValueError(f"CANON_ERR_TOO_LARGE: binary value longer than {MAX_BYTES_LEN} bytes at {path}")
+    if not _BASE64URL.fullmatch(s) or len(s) % 4 == 1:
+        raise ValueError(f"CANON_ERR_BYTES_INVALID: {s!r} at {path}")
+    try:
+        b = base64.urlsafe_b64decode(s + "=" * (-len(s) % 4))
+    except (binascii.Error, ValueError):
+        raise ValueError(f"CANON_ERR_BYTES_INVALID: {s!r} at {path}")
+    if base64.urlsafe_b64encode(b).rstrip(b"=").decode("ascii") != s:
+        raise ValueError(f"CANON_ERR_BYTES_INVALID: {s!r} at {path}")
+    return b
+
+
+def validate_extensions(v, path: str = "value") -> None:
+    """Validate a value under a profile with extensions.
+
+    Checks:
+    - RULE-014: map keys with a reserved prefix are rejected unless a
+      registered extension alone in its object (CANON_ERR_KEY_RESERVED)
+    - RULE-013: a binary value {"$bytes": ...} holds canonical unpadded
+      base64url (CANON_ERR_BYTES_INVALID, CANON_ERR_TOO_LARGE)
+
+    Version 1 without extensions accepts both as ordinary data.
+    """
+    if isinstance(v, dict):
+        bad = sorted(
+            k for k in v
+            if k.startswith(RESERVED_KEY_PREFIXES) and not (len(v) == 1 and k in EXTENSION_KEYS)
+        )
+        if bad:
+            raise ValueError(f"CANON_ERR_KEY_RESERVED: reserved key {bad[0]!r} at {path}")
+        if len(v) == 1 and BYTES_KEY in v:
+            decode_bytes(v[BYTES_KEY], f"{path}.{BYTES_KEY}")
+        for k, child in v.items():
+            validate_extensions(child, f"{path}.{k}")
+    elif isinstance(v, list):
+        for i, child in enumerate(v):
+            validate_extensions(child, f"{path}[{i}]")
+
+
+# RULE-012: key policies (Section 3.7). The permissive policy of version 1
+# accepts every key; the strict policy rejects keys holding a control
+# character (general category Cc) or U+FFFD.
+KEY_POLICIES = ("", "permissive", "strict")
+_SURROGATE = re.compile("[\ud800-\udfff]")
+
+
+def replace_surrogates(v):
+    """Replace unpaired surrogates in every string and key of a decoded value
+    with U+FFFD REPLACEMENT CHARACTER, as JSON decoders producing UTF-8 do.
+    Python's json module keeps an escape such as "\\ud800" as a lone
+    surrogate, which cannot be encoded as UTF-8.
+    """
+    if isinstance(v, str):
+        return _SURROGATE.sub("\ufffd", v)
+    if isinstance(v, dict):
+        return {replace_surrogates(k): replace_surrogates(child) for k, child in v.items()}
+    if isinstance(v, list):
+        return [replace_surrogates(child) for child in v]
+    return v
+
+
+def validate_keys(v, policy: str, path: str = "value") -> None:
+    """Validate RULE-012: check every map key in v against a key policy.
+
+    Under the strict policy a key containing a control character or U+FFFD
+    fails with CANON_ERR_KEY_CHARACTER_PROHIBITED.
+    """
+    if policy not in KEY_POLICIES:
+        raise ValueError(f"unknown key policy {policy!r} (want permissive or strict)")
+    if policy != "strict":
+        return
+    if isinstance(v, dict):
+        for k, child in v.items():
+            for c in k:
+                if c == "\ufffd" or unicodedata.category(c) == "Cc":
+                    raise ValueError(f"CANON_ERR_KEY_CHARACTER_PROHIBITED: key {k!r} at {path} holds U+{ord(c):04X}")
+            validate_keys(child, policy, f"{path}.{k}")
+    elif isinstance(v, list):
+        for i, child in enumerate(v):
+            validate_keys(child, policy, f"{path}[{i}]")
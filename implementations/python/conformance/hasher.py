@@ -45,14 +45,16 @@ def content_hash(obj: MemoryObject) -> str:
     # NFC-normalize relationship strings
     rel_maps = []
     for r in sorted_rels:
-        rel_maps.append({
-            "key": normalize_string(r.key),
-            "type": normalize_string(r.type),
-        })
+        m = relationship_to_map(r)
+        m["key"] = normalize_string(r.key)
+        m["type"] = normalize_string(r.type)
+        if r.note is not None:
+            m["note"] = normalize_string(r.note)
+        rel_maps.append(m)
 
     # Step 5: Build explicit field map with exactly 7 keys (6 data + schema version)
     fields = {
-        "_helios_schema_version": "1",
+        "_helios_schema_version": obj.schema_version,
         "category": inp.category,
         "created_at": inp.created_at,
         "key": inp.key,
@@ -1,13 +1,16 @@
 """Memory object types for Helios Core (Python conformance)."""
 
 from dataclasses import dataclass
-from typing import Any
+from typing import Any, Optional
 
 
 @dataclass
 class Relationship:
     key: str
     type: str
+    # Schema version 2 attributes (Section 8.3); None when absent.
+    weight: Optional[int] = None
+    note: Optional[str] = None
 
 
 @dataclass
@@ -21,6 +24,7 @@ class MemoryObject:
     relationships: list  # list of Relationship
     source: str
     value: Any  # nullable — None must NOT be omitted
+    schema_version: str = "1"  # hashed as _helios_schema_version
 
     # Excluded from hash:
     updated_at: str = ""
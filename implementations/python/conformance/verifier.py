@@ -6,29 +6,67 @@ from typing import Any
 
 from conformance.hasher import content_hash
 from conformance.objects import MemoryObject, Relationship
-from conformance.canon import validate_ingest_value, validate_schema_version
+from conformance.canon import (
+    replace_surrogates,
+    validate_extensions,
+    validate_ingest_value,
+    validate_keys,
+    validate_relationships,
+    validate_schema_version,
+)
+
+
+def load_vectors_file(path: str) -> dict:
+    """Load a vectors file: its profile members and its vectors.
+    Unpaired surrogate escapes decode to U+FFFD, as in the Go implementation.
+    """
+    with open(path) as f:
+        return replace_surrogates(json.load(f))
 
 
 def load_vectors(path: str) -> list:
     """Load test vectors from a JSON file."""
-    with open(path) as f:
-        data = json.load(f)
-    return data["vectors"]
+    return load_vectors_file(path)["vectors"]
 
 
-def input_to_memory_object(inp: dict) -> MemoryObject:
+def schema_versions(profile: dict) -> tuple:
+    """Return the schema versions a vectors file's objects may declare."""
+    v = profile.get("schema_version", "1")
+    if v in ("", "1"):
+        return ("1",)
+    if v == "2":
+        return ("1", "2")
+    raise ValueError(f"unknown schema_version {v!r} (want 1 or 2)")
+
+
+def input_to_memory_object(inp: dict, profile: dict = None) -> MemoryObject:
     """Convert a raw JSON dict to a MemoryObject.
-    Validates ingest rules: RULE-001 (schema version), RULE-002 (no floats), RULE-009 (integer range), RULE-010 (no nulls).
+    Validates ingest rules: RULE-001 (schema version), RULE-002 (no floats), RULE-009 (integer range), RULE-010 (no nulls),
+    RULE-012 (map keys) under the vectors file's key policy, RULE-015 (relationship attributes) for schema version 2 objects, and RULE-013 and RULE-014 (binary values,
+    reserved keys) if the vectors file's profile enables extensions.
     """
+    profile = profile or {}
+
     # RULE-001: schema version validation
-    validate_schema_version(inp)
+    validate_schema_version(inp, schema_versions(profile))
 
     # Ingest validation on the value field
     validate_ingest_value(inp.get("value"), "value")
+    validate_keys(inp.get("value"), profile.get("key_policy", ""))
+    if profile.get("extensions"):
+        validate_extensions(inp.get("value"))
+    validate_relationships(inp)
 
+    # Relationship attributes exist from schema version 2; version 1 ignores them.
+    v2 = inp["_helios_schema_version"] == "2"
     relationships = []
     for r in inp.get("relationships", []):
-        relationships.append(Relationship(key=r["key"], type=r["type"]))
+        relationships.append(Relationship(
+            key=r["key"],
+            type=r["type"],
+            weight=r.get("weight") if v2 else None,
+            note=r.get("note") if v2 else None,
+        ))
 
     return MemoryObject(
         category=inp.get("category", ""),
@@ -37,6 +75,7 @@ def input_to_memory_object(inp: dict) -> MemoryObject:
         relationships=relationships,
         source=inp.get("source", ""),
         value=inp.get("value"),
+        schema_version=inp["_helios_schema_version"],
     )
 
 
@@ -44,7 +83,8 @@ def verify_vectors(path: str) -> list:
     """Verify all test vectors. Returns list of (name, expected, got, pass) tuples.
     Raises SystemExit(1) if any vector fails.
     """
-    vectors = load_vectors(path)
+    profile = load_vectors_file(path)
+    vectors = profile["vectors"]
     results = []
     failures = 0
 
@@ -57,7 +97,7 @@ def verify_vectors(path: str) -> list:
             # Negative vectors: expect rejection
             rejection_code = vec.get("rejection_code", "")
             try:
-                obj = input_to_memory_object(vec["input"])
+                obj = input_to_memory_object(vec["input"], profile)
                 got = content_hash(obj)
                 # Should have been rejected but wasn't
                 results.append((vector_id, "REJECT", f"ACCEPT: {got}", False))
@@ -71,7 +111,7 @@ def verify_vectors(path: str) -> list:
         else:
             # Positive vectors: expect successful hash match
             expected_hash = vec["hash"]
-            obj = input_to_memory_object(vec["input"])
+            obj = input_to_memory_object(vec["input"], profile)
             got = content_hash(obj)
             passed = got == expected_hash
             results.append((vector_id, expected_hash, got, passed))
@@ -177,3 +177,66 @@ class TestSchemaVersionValidation:
     def test_accepts_valid(self):
         from conformance.canon import validate_schema_version
         validate_schema_version({"_helios_schema_version": "1", "category": "test"})  # should pass
+
+
+class TestExtensionsValidation:
+    """Tests for validate_extensions (RULE-014)."""
+
+    def test_rejects_reserved_key(self):
+        from conformance.canon import validate_extensions
+        with pytest.raises(ValueError, match="CANON_ERR_KEY_RESERVED"):
+            validate_extensions({"a": [{"$ref": "#"}]})
+
+    def test_rejects_extension_with_other_members(self):
+        from conformance.canon import validate_extensions
+        with pytest.raises(ValueError, match="CANON_ERR_KEY_RESERVED"):
+            validate_extensions({"$bytes": "aGVsbG8", "mime": "text/plain"})
+
+    def test_accepts_ordinary_keys(self):
+        from conformance.canon import validate_extensions
+        validate_extensions({"price$": 1, "_Helios_x": "a", "blob": {"$bytes": "aGVsbG8"}})  # should pass
+
+    def test_v1_accepts_reserved_key(self):
+        from conformance.canon import validate_ingest_value
+        validate_ingest_value({"$ref": "#"})  # should pass
+
+
+class TestBytesValidation:
+    """Tests for decode_bytes (RULE-013)."""
+
+    def test_decodes_unpadded_base64url(self):
+        from conformance.canon import decode_bytes
+        assert decode_bytes("aGVsbG8") == b"hello"
+        assert decode_bytes("_-8") == b"\xff\xef"
+        assert decode_bytes("") == b""
+
+    def test_rejects_noncanonical(self):
+        from conformance.canon import decode_bytes
+        for s in ("aGVsbG8=", "/+8", "aGVsbG9", "aGVs\nbG8", "a", 5):
+            with pytest.raises(ValueError, match="CANON_ERR_BYTES_INVALID"):
+                decode_bytes(s)
+
+    def test_v1_accepts_padded_wrapper(self):
+        from conformance.canon import validate_ingest_value
+        validate_ingest_value({"$bytes": "aGVsbG8="})  # should pass
+
+
+class TestKeyPolicy:
+    """Tests for validate_keys and replace_surrogates (RULE-012)."""
+
+    def test_strict_rejects_control_and_replacement(self):
+        from conformance.canon import validate_keys
+        for v in ({"a\tb": 1}, {"x\x85": 1}, {"rows": [{"�": 1}]}):
+            with pytest.raises(ValueError, match="CANON_ERR_KEY_CHARACTER_PROHIBITED"):
+                validate_keys(v, "strict")
+
+    def test_permissive_accepts_every_key(self):
+        from conformance.canon import validate_keys
+        validate_keys({"\x01": 1, "�": 2}, "permissive")  # should pass
+
+    def test_surrogate_escape_decodes_to_replacement(self):
+        import json
+        from conformance.canon import replace_surrogates
+        v = replace_surrogates(json.loads('{"\\ud800x": "\\udc00", "ok": "\\ud83d\\ude00"}'))
+        assert v == {"�x": "�", "ok": "\U0001f600"}
+        canonicalize_object(v)  # encodes as UTF-8
@@ -66,3 +66,37 @@ class TestContentHash:
         h = content_hash(obj)
         assert len(h) == 64
         assert all(c in "0123456789abcdef" for c in h)
+
+
+class TestRelationshipAttributes:
+    """Schema version 2 relationship attributes (RULE-015)."""
+
+    def _obj(self, version, rels):
+        return MemoryObject(
+            category="test",
+            created_at="2025-01-01T00:00:00.000Z",
+            key="test/attributes",
+            relationships=rels,
+            source="unit_test",
+            value="edges",
+            schema_version=version,
+        )
+
+    def test_v2_attributes_change_hash(self):
+        plain = self._obj("2", [Relationship(key="a", type="t")])
+        weighted = self._obj("2", [Relationship(key="a", type="t", weight=3)])
+        assert content_hash(plain) != content_hash(weighted)
+
+    def test_v1_ignores_attributes(self):
+        from conformance.verifier import input_to_memory_object
+        inp = {
+            "_helios_schema_version": "1",
+            "category": "test",
+            "created_at": "2025-01-01T00:00:00.000Z",
+            "key": "test/attributes",
+            "relationships": [{"key": "a", "type": "t", "weight": 3, "note": "n"}],
+            "source": "unit_test",
+            "value": "edges",
+        }
+        obj = input_to_memory_object(inp)
+        assert content_hash(obj) == content_hash(self._obj("1", [Relationship(key="a", type="t")]))
@@ -0,0 +1,130 @@
+// Package abbrev implements git-style abbreviated content hashes: any
+// prefix of at least MinLength hex digits that matches exactly one hash
+// in a set stands for that hash.
+package abbrev
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MinLength is the shortest prefix accepted as an abbreviation.
+const MinLength = 4
+
+// DefaultLength is the abbreviation length used for display when a set
+// of hashes is not known; it is unique with high probability for corpora
+// of up to a few million objects.
+const DefaultLength = 12
+
+// Errors returned by Resolve.
+var (
+	ErrTooShort = fmt.Errorf("abbreviated hash must have at least %d hex digits", MinLength)
+	ErrNotHex   = errors.New("abbreviated hash must be lowercase hex")
+	ErrNotFound = errors.New("no hash matches the abbreviation")
+)
+
+// AmbiguousError is returned when a prefix matches several hashes.
+type AmbiguousError struct {
+	Prefix  string
+	Matches []string
+}
+
+func (e *AmbiguousError) Error() string {
+	const shown = 5
+	list := e.Matches
+	if len(list) > shown {
+		list = list[:shown]
+	}
+	var short []string
+	for _, m := range list {
+		short = append(short, m[:min(len(m), DefaultLength)])
+	}
+	more := ""
+	if len(e.Matches) > shown {
+		more = fmt.Sprintf(", and %d more", len(e.Matches)-shown)
+	}
+	return fmt.Sprintf("abbreviated hash %s is ambiguous: matches %s%s", e.Prefix, strings.Join(short, ", "), more)
+}
+
+// Check validates prefix as an abbreviation without resolving it.
+func Check(prefix string) error {
+	if len(prefix) < MinLength {
+		return ErrTooShort
+	}
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ErrNotHex
+		}
+	}
+	return nil
+}
+
+// Resolve returns the single hash in sorted that starts with prefix.
+// sorted must be in ascending order.
+func Resolve(prefix string, sorted []string) (string, error) {
+	if err := Check(prefix); err != nil {
+		return "", err
+	}
+	i := sort.SearchStrings(sorted, prefix)
+	var matches []string
+	for ; i < len(sorted) && strings.HasPrefix(sorted[i], prefix); i++ {
+		matches = append(matches, sorted[i])
+	}
+	switch len(matches) {
+	case 0:
+		return "", ErrNotFound
+	case 1:
+		return matches[0], nil
+	default:
+		return "", &AmbiguousError{Prefix: prefix, Matches: matches}
+	}
+}
+
+// Shortest returns, for each hash in hashes, the length of its shortest
+// unique prefix within the set, never less than minLen. Duplicate hashes
+// are one object and do not make each other ambiguous.
+func Shortest(hashes []string, minLen int) map[string]int {
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+	uniq := sorted[:0]
+	for i, h := range sorted {
+		if i == 0 || h != sorted[i-1] {
+			uniq = append(uniq, h)
+		}
+	}
+
+	out := make(map[string]int, len(uniq))
+	for i, h := range uniq {
+		n := minLen
+		if i > 0 {
+			n = max(n, commonPrefix(h, uniq[i-1])+1)
+		}
+		if i+1 < len(uniq) {
+			n = max(n, commonPrefix(h, uniq[i+1])+1)
+		}
+		out[h] = min(n, len(h))
+	}
+	return out
+}
+
+// UniqueLength returns the shortest length, at least minLen, at which
+// every hash in hashes has a unique prefix: a single abbreviation length
+// for displaying the whole set.
+func UniqueLength(hashes []string, minLen int) int {
+	n := minLen
+	for _, l := range Shortest(hashes, minLen) {
+		n = max(n, l)
+	}
+	return n
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
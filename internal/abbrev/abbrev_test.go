@@ -0,0 +1,60 @@
+package abbrev
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var hashes = []string{
+	"abcd0000" + strings.Repeat("0", 56),
+	"abcd1111" + strings.Repeat("1", 56),
+	"abce2222" + strings.Repeat("2", 56),
+	"f0000000" + strings.Repeat("3", 56),
+}
+
+func TestResolve(t *testing.T) {
+	if h, err := Resolve("abcd0", hashes); err != nil || h != hashes[0] {
+		t.Errorf("abcd0: %s, %v", h, err)
+	}
+	if h, err := Resolve(hashes[3], hashes); err != nil || h != hashes[3] {
+		t.Errorf("full hash: %s, %v", h, err)
+	}
+	var amb *AmbiguousError
+	if _, err := Resolve("abcd", hashes); !errors.As(err, &amb) || len(amb.Matches) != 2 {
+		t.Errorf("abcd: %v", err)
+	} else if !strings.Contains(err.Error(), "abcd00000000") {
+		t.Errorf("ambiguity message %q should list candidates", err)
+	}
+	for prefix, want := range map[string]error{
+		"abc":  ErrTooShort,
+		"ABCD": ErrNotHex,
+		"abzz": ErrNotHex,
+		"1234": ErrNotFound,
+	} {
+		if _, err := Resolve(prefix, hashes); !errors.Is(err, want) {
+			t.Errorf("%s: got %v, want %v", prefix, err, want)
+		}
+	}
+}
+
+func TestShortest(t *testing.T) {
+	got := Shortest(append(hashes, hashes[3]), MinLength)
+	want := map[string]int{hashes[0]: 5, hashes[1]: 5, hashes[2]: 4, hashes[3]: 4}
+	for h, n := range want {
+		if got[h] != n {
+			t.Errorf("%s: %d, want %d", h[:8], got[h], n)
+		}
+	}
+	for h, n := range got {
+		if r, err := Resolve(h[:n], hashes); err != nil || r != h {
+			t.Errorf("shortest prefix %s does not resolve: %v", h[:n], err)
+		}
+	}
+	if n := UniqueLength(hashes, MinLength); n != 5 {
+		t.Errorf("UniqueLength %d, want 5", n)
+	}
+	if n := UniqueLength(hashes, 7); n != 7 {
+		t.Errorf("UniqueLength with min 7: %d", n)
+	}
+}
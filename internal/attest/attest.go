@@ -0,0 +1,225 @@
+// Package attest wraps Helios content hashes in in-toto statements signed
+// as DSSE envelopes, so memory snapshots can be checked by existing
+// supply-chain verification tooling.
+package attest
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+const (
+	// StatementType is the in-toto statement _type.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PayloadType is the DSSE payload type for in-toto statements.
+	PayloadType = "application/vnd.in-toto+json"
+	// PredicateType identifies Helios memory object predicates.
+	PredicateType = "https://github.com/holeyfield33-art/helios/memory-object/v1"
+)
+
+// Subject names an attested memory object by key and content hash.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate records how the subject digests were produced. Profile is the
+// hash of the canonicalization profile; statements made before profiles
+// were recorded have none and were hashed under version 1.
+type Predicate struct {
+	SpecVersion   string `json:"spec_version"`
+	HashAlgorithm string `json:"hash_algorithm"`
+	Profile       string `json:"profile,omitempty"`
+}
+
+// Statement is an in-toto v1 statement about memory objects.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Signature is one DSSE signature.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Envelope is a DSSE envelope.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// NewStatement builds a statement whose subjects are the objects' keys and
+// content hashes, in order.
+func NewStatement(objs []object.MemoryObject) (*Statement, error) {
+	st := &Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+	}
+	pipeline := hash.Current()
+	st.Predicate = Predicate{SpecVersion: "1", HashAlgorithm: pipeline.Profile.HashAlgorithm, Profile: pipeline.Profile.ID()}
+	for i, obj := range objs {
+		h, err := pipeline.ContentHash(obj)
+		if err != nil {
+			return nil, fmt.Errorf("object %d (key %q): %w", i, obj.Key, err)
+		}
+		st.Subject = append(st.Subject, Subject{Name: obj.Key, Digest: map[string]string{"sha256": h}})
+	}
+	return st, nil
+}
+
+// PAE computes the DSSE pre-authentication encoding of a payload.
+func PAE(payloadType string, payload []byte) []byte {
+	out := []byte("DSSEv1 ")
+	out = strconv.AppendInt(out, int64(len(payloadType)), 10)
+	out = append(out, ' ')
+	out = append(out, payloadType...)
+	out = append(out, ' ')
+	out = strconv.AppendInt(out, int64(len(payload)), 10)
+	out = append(out, ' ')
+	return append(out, payload...)
+}
+
+// Sign serializes st and signs it into a DSSE envelope.
+func Sign(st *Statement, signers ...signing.Signer) (*Envelope, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statement: %w", err)
+	}
+	return SignPayload(PayloadType, payload, signers...)
+}
+
+// SignPayload signs payload into a DSSE envelope of payloadType.
+func SignPayload(payloadType string, payload []byte, signers ...signing.Signer) (*Envelope, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("at least one signer is required")
+	}
+	env := &Envelope{
+		PayloadType: payloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+	}
+	pae := PAE(payloadType, payload)
+	for _, s := range signers {
+		sig, err := s.Sign(pae)
+		if err != nil {
+			return nil, fmt.Errorf("signing failed: %w", err)
+		}
+		env.Signatures = append(env.Signatures, Signature{
+			KeyID: s.KeyID(),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		})
+	}
+	return env, nil
+}
+
+// Verify checks that at least one signature in env was made by one of
+// keys, and returns the decoded statement.
+func Verify(env *Envelope, keys ...crypto.PublicKey) (*Statement, error) {
+	payload, err := VerifyPayload(env, PayloadType, keys...)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStatement(payload)
+}
+
+// VerifyPolicy checks that env's signatures meet policy, considering its
+// own keys and keys, and returns the decoded statement and the signers
+// counted. A DSSE envelope records no signing time, so signers' validity
+// windows are checked at at, normally the time of verification.
+func VerifyPolicy(env *Envelope, policy *signing.TrustPolicy, at time.Time, keys ...crypto.PublicKey) (*Statement, []string, error) {
+	if env.PayloadType != PayloadType {
+		return nil, nil, fmt.Errorf("unexpected payload type %q", env.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+	var sigs [][]byte
+	for _, sig := range env.Signatures {
+		if raw, err := base64.StdEncoding.DecodeString(sig.Sig); err == nil {
+			sigs = append(sigs, raw)
+		}
+	}
+	signers, err := policy.Check(PAE(env.PayloadType, payload), sigs, at, keys...)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := decodeStatement(payload)
+	return st, signers, err
+}
+
+func decodeStatement(payload []byte) (*Statement, error) {
+	var st Statement
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return nil, fmt.Errorf("invalid statement: %w", err)
+	}
+	if st.Type != StatementType {
+		return nil, fmt.Errorf("unexpected statement type %q", st.Type)
+	}
+	return &st, nil
+}
+
+// VerifyPayload checks that env has payloadType and at least one
+// signature made by one of keys, and returns the payload.
+func VerifyPayload(env *Envelope, payloadType string, keys ...crypto.PublicKey) ([]byte, error) {
+	if env.PayloadType != payloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", env.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+	pae := PAE(env.PayloadType, payload)
+
+	for _, sig := range env.Signatures {
+		raw, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+		for _, k := range keys {
+			if signing.Verify(k, pae, raw) == nil {
+				return payload, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no valid signature from a trusted key")
+}
+
+// CheckSubjects confirms that every object matches a subject in st by key
+// and content hash.
+func CheckSubjects(st *Statement, objs []object.MemoryObject) error {
+	pipeline, err := hash.LookupID(st.Predicate.Profile)
+	if err != nil {
+		return err
+	}
+	digests := make(map[string]string, len(st.Subject))
+	for _, s := range st.Subject {
+		digests[s.Name] = s.Digest["sha256"]
+	}
+	for _, obj := range objs {
+		want, ok := digests[obj.Key]
+		if !ok {
+			return fmt.Errorf("object %q is not a subject of the attestation", obj.Key)
+		}
+		got, err := pipeline.ContentHash(obj)
+		if err != nil {
+			return fmt.Errorf("object %q: %w", obj.Key, err)
+		}
+		if !hash.Equal(want, got) {
+			return fmt.Errorf("object %q: content hash %s does not match attested %s", obj.Key, got, want)
+		}
+	}
+	return nil
+}
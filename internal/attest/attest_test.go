@@ -0,0 +1,160 @@
+package attest
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+func testObject(key string) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "user",
+		Value:         "attested",
+	}
+}
+
+func testSigner(t *testing.T) signing.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := signing.NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPAE(t *testing.T) {
+	// Test vector from the DSSE specification
+	got := string(PAE("http://example.com/HelloWorld", []byte("hello world")))
+	want := "DSSEv1 29 http://example.com/HelloWorld 11 hello world"
+	if got != want {
+		t.Errorf("PAE mismatch:\n  got:  %s\n  want: %s", got, want)
+	}
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	objs := []object.MemoryObject{testObject("a"), testObject("b")}
+	st, err := NewStatement(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := testSigner(t)
+	env, err := Sign(st, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Survives JSON round trip
+	data, _ := json.Marshal(env)
+	var back Envelope
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Verify(&back, signer.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subject) != 2 || got.Subject[0].Name != "a" || len(got.Subject[0].Digest["sha256"]) != 64 {
+		t.Errorf("unexpected subjects: %+v", got.Subject)
+	}
+	if err := CheckSubjects(got, objs); err != nil {
+		t.Errorf("subjects should match objects: %v", err)
+	}
+
+	changed := testObject("a")
+	changed.Value = "tampered"
+	if err := CheckSubjects(got, []object.MemoryObject{changed}); err == nil {
+		t.Error("expected mismatch for modified object")
+	}
+}
+
+func TestCheckSubjectsSelectsPipeline(t *testing.T) {
+	objs := []object.MemoryObject{testObject("a")}
+	st, err := NewStatement(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Predicate.Profile != hash.Current().Profile.ID() {
+		t.Errorf("predicate profile = %q", st.Predicate.Profile)
+	}
+
+	// Statements from before profiles were recorded are version 1.
+	st.Predicate.Profile = ""
+	if err := CheckSubjects(st, objs); err != nil {
+		t.Errorf("unstamped statement: %v", err)
+	}
+	st.Predicate.Profile = strings.Repeat("0", 64)
+	if err := CheckSubjects(st, objs); !errors.Is(err, hash.ErrUnknownProfile) {
+		t.Errorf("unknown profile: %v", err)
+	}
+}
+
+func TestVerifyRejectsUntrustedKeyAndTamperedPayload(t *testing.T) {
+	st, _ := NewStatement([]object.MemoryObject{testObject("a")})
+	signer := testSigner(t)
+	env, _ := Sign(st, signer)
+
+	if _, err := Verify(env, testSigner(t).Public()); err == nil {
+		t.Error("expected failure for untrusted key")
+	}
+
+	tampered := *env
+	st.Subject[0].Digest["sha256"] = "00"
+	payload, _ := json.Marshal(st)
+	tampered.Payload = base64.StdEncoding.EncodeToString(payload)
+	if _, err := Verify(&tampered, signer.Public()); err == nil {
+		t.Error("expected failure for tampered payload")
+	}
+}
+
+func TestVerifyPolicy(t *testing.T) {
+	st, err := NewStatement([]object.MemoryObject{testObject("a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := testSigner(t), testSigner(t)
+	policy, err := signing.ParseTrustPolicy([]byte(`{"threshold": 2, "signers": [` +
+		`{"name": "a", "fingerprint": "` + a.KeyID() + `"},` +
+		`{"name": "b", "fingerprint": "` + b.KeyID() + `"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []crypto.PublicKey{a.Public(), b.Public()}
+
+	one, err := Sign(st, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := VerifyPolicy(one, policy, time.Now(), keys...); err == nil {
+		t.Error("one signature met a threshold of two")
+	}
+	both, err := Sign(st, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, signers, err := VerifyPolicy(both, policy, time.Now(), keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subject) != 1 || len(signers) != 2 {
+		t.Errorf("VerifyPolicy = %d subject(s), signers %v", len(got.Subject), signers)
+	}
+}
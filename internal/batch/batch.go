@@ -0,0 +1,118 @@
+// Package batch hashes corpora of memory objects and compares batch
+// results, e.g. between two releases of the helios binary.
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// Result is one line of `helios hash-batch` NDJSON output.
+type Result struct {
+	Index     int    `json:"index"`
+	Origin    string `json:"origin,omitempty"`
+	Key       string `json:"key"`
+	Status    string `json:"status,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Canonical string `json:"canonical,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Result statuses. Output of binaries that predate them has no status.
+const (
+	StatusOK      = "ok"
+	StatusInvalid = "invalid"
+)
+
+// Hash computes results for every record in order, as HashItems does. err
+// names the first object that could not be parsed or hashed, if any; its
+// result and those of the objects after it are still returned.
+func Hash(records []ingest.Record) (results []Result, err error) {
+	results = make([]Result, len(records))
+	for i, rec := range records {
+		r, herr := hashRecord(i, rec)
+		if herr != nil {
+			r.Status = StatusInvalid
+			r.Error = herr.Error()
+			if err == nil {
+				err = fmt.Errorf("%s: %w", rec.Origin, herr)
+			}
+		}
+		results[i] = r
+	}
+	return results, err
+}
+
+// HashItems computes results for records in order, keeping going past
+// objects that cannot be parsed or hashed: their results have status
+// StatusInvalid and the reason in Error. invalid counts them.
+func HashItems(records []ingest.Record) (results []Result, invalid int) {
+	results, _ = Hash(records)
+	for _, r := range results {
+		if r.Status == StatusInvalid {
+			invalid++
+		}
+	}
+	return results, invalid
+}
+
+// hashRecord returns the result for the record at index i. On error the
+// result still identifies the record.
+func hashRecord(i int, rec ingest.Record) (Result, error) {
+	r := Result{Index: i, Origin: rec.Origin, Key: rec.Object.Key}
+	if rec.Err != nil {
+		return r, rec.Err
+	}
+	canonical, err := hash.CanonicalBytes(rec.Object)
+	if err != nil {
+		return r, err
+	}
+	h, err := hash.ContentHash(rec.Object)
+	if err != nil {
+		return r, err
+	}
+	r.Status = StatusOK
+	r.Hash = h
+	r.Canonical = string(canonical)
+	return r, nil
+}
+
+// Write encodes results as NDJSON, one result per line.
+func Write(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read decodes NDJSON results produced by Write.
+func Read(r io.Reader) ([]Result, error) {
+	var results []Result
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 256*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var res Result
+		if err := json.Unmarshal(sc.Bytes(), &res); err != nil {
+			return nil, fmt.Errorf("batch output line %d: %w", line, err)
+		}
+		results = append(results, res)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
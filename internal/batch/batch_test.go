@@ -0,0 +1,123 @@
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func record(key, value string) ingest.Record {
+	return ingest.Record{
+		Origin: "test:" + key,
+		Object: object.MemoryObject{
+			Category:      "note",
+			CreatedAt:     "2025-01-15T10:30:00.000Z",
+			Key:           key,
+			Relationships: []object.Relationship{},
+			Source:        "test",
+			Value:         value,
+		},
+	}
+}
+
+func TestHashWriteReadRoundTrip(t *testing.T) {
+	results, err := Hash([]ingest.Record{record("a", "<one>"), record("b", "two")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`<one>`)) {
+		t.Error("canonical bytes should not be HTML-escaped")
+	}
+	back, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != 2 || back[0] != results[0] || back[1] != results[1] {
+		t.Errorf("round trip mismatch: %+v", back)
+	}
+}
+
+func TestHashRecordsInvalidObject(t *testing.T) {
+	bad := record("bad", "x")
+	bad.Object.CreatedAt = "yesterday"
+	results, err := Hash([]ingest.Record{record("a", "one"), bad, record("b", "two")})
+	if err == nil || !strings.Contains(err.Error(), "test:bad") {
+		t.Errorf("expected error naming the invalid object, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected a result per record, got %d", len(results))
+	}
+	if r := results[1]; r.Status != StatusInvalid || r.Error == "" || r.Key != "bad" {
+		t.Errorf("invalid object: %+v", r)
+	}
+	if r := results[2]; r.Status != StatusOK || r.Hash == "" {
+		t.Errorf("object after the invalid one: %+v", r)
+	}
+}
+
+func TestHashItemsReportsInvalidObjects(t *testing.T) {
+	bad := record("bad", "x")
+	bad.Object.CreatedAt = "yesterday"
+	unparsed := ingest.Record{Origin: "test:unparsed", Err: errors.New("failed to parse JSON")}
+	results, invalid := HashItems([]ingest.Record{record("a", "one"), bad, unparsed})
+	if invalid != 2 || len(results) != 3 {
+		t.Fatalf("expected 2 of 3 invalid, got %d of %d", invalid, len(results))
+	}
+	if r := results[0]; r.Status != StatusOK || r.Hash == "" || r.Error != "" {
+		t.Errorf("valid object: %+v", r)
+	}
+	for _, r := range results[1:] {
+		if r.Status != StatusInvalid || r.Hash != "" || r.Error == "" {
+			t.Errorf("invalid object: %+v", r)
+		}
+	}
+	if results[1].Key != "bad" || results[2].Origin != "test:unparsed" || results[2].Index != 2 {
+		t.Errorf("invalid results lost their identity: %+v", results[1:])
+	}
+}
+
+func TestDiffReportsCanonicalOffset(t *testing.T) {
+	ours, _ := Hash([]ingest.Record{record("a", "one"), record("b", "two")})
+	theirs, _ := Hash([]ingest.Record{record("a", "one"), record("b", "twx")})
+
+	mismatches, err := Diff(ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Key != "b" {
+		t.Fatalf("expected one mismatch for b, got %+v", mismatches)
+	}
+	off := mismatches[0].Offset
+	if off < 0 || ours[1].Canonical[off] != 'o' || theirs[1].Canonical[off] != 'x' {
+		t.Errorf("offset %d does not point at the differing byte", off)
+	}
+
+	if _, err := Diff(ours, theirs[:1]); err == nil {
+		t.Error("expected error for differing result counts")
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"abc", "abc", -1},
+		{"abc", "abd", 2},
+		{"ab", "abc", 2},
+		{"", "", -1},
+	}
+	for _, tt := range tests {
+		if got := FirstDifference([]byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("FirstDifference(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
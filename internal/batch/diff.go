@@ -0,0 +1,74 @@
+package batch
+
+import "fmt"
+
+// Mismatch describes one object whose results differ between two runs.
+type Mismatch struct {
+	Index  int
+	Key    string
+	Ours   Result
+	Theirs Result
+	// Offset is the first differing byte of the canonical forms, or -1
+	// when either side did not report canonical bytes.
+	Offset int
+}
+
+// Diff compares two runs over the same corpus, matching results by index.
+// An object mismatches when its hashes differ or only one run rejected
+// it; the wording of errors is not compared, since it changes between
+// releases. It returns an error if the runs cover different numbers of
+// objects.
+func Diff(ours, theirs []Result) ([]Mismatch, error) {
+	if len(ours) != len(theirs) {
+		return nil, fmt.Errorf("result count differs: %d vs %d", len(ours), len(theirs))
+	}
+	var mismatches []Mismatch
+	for i := range ours {
+		a, b := ours[i], theirs[i]
+		if a.Hash == b.Hash && (a.Error == "") == (b.Error == "") {
+			continue
+		}
+		m := Mismatch{Index: i, Key: a.Key, Ours: a, Theirs: b, Offset: -1}
+		if a.Canonical != "" && b.Canonical != "" {
+			m.Offset = FirstDifference([]byte(a.Canonical), []byte(b.Canonical))
+		}
+		mismatches = append(mismatches, m)
+	}
+	return mismatches, nil
+}
+
+// FirstDifference returns the offset of the first byte at which a and b
+// differ, len of the shorter input if one is a prefix of the other, or -1
+// if they are equal.
+func FirstDifference(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return n
+	}
+	return -1
+}
+
+// Context returns up to width bytes of s on either side of offset, for
+// displaying where two canonical forms diverge.
+func Context(s string, offset, width int) string {
+	start := offset - width
+	if start < 0 {
+		start = 0
+	}
+	end := offset + width
+	if end > len(s) {
+		end = len(s)
+	}
+	if start > len(s) {
+		return ""
+	}
+	return s[start:end]
+}
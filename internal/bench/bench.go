@@ -0,0 +1,154 @@
+// Package bench measures the cost of canonicalization: how long the
+// canonical bytes and content hash of an object take to compute, and how
+// much they allocate, set against encoding/json marshaling the same
+// objects. The comparison is the overhead a service absorbs by hashing
+// inline where it would otherwise only have serialized.
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Contender names.
+const (
+	Canonical = "canon"
+	Hash      = "canon+sha256"
+	Stdlib    = "encoding/json"
+)
+
+// Options configures Run.
+type Options struct {
+	// MinTime is how long each contender runs at least; the corpus is
+	// processed whole until it has elapsed. Zero means one second.
+	MinTime time.Duration
+	// CompareStdlib adds encoding/json.Marshal as a contender and fills
+	// in the report's overhead ratios.
+	CompareStdlib bool
+}
+
+// Result is the measurement of one contender. Per-op figures are per
+// object.
+type Result struct {
+	Name        string  `json:"name"`
+	Ops         int     `json:"ops"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	// OutputMBPerSec is the canonical bytes or JSON produced per second;
+	// for canon+sha256, the canonical bytes hashed.
+	OutputMBPerSec float64 `json:"output_mb_per_sec"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Go       string `json:"go"`
+	Platform string `json:"platform"`
+	Objects  int    `json:"objects"`
+	// Skipped counts objects canonicalization rejects, which no contender
+	// is run on.
+	Skipped int      `json:"skipped"`
+	Results []Result `json:"results"`
+	// TimeOverhead and AllocOverhead are the canonical contender's time
+	// and allocations per object divided by encoding/json's, with
+	// CompareStdlib.
+	TimeOverhead  float64 `json:"time_overhead,omitempty"`
+	AllocOverhead float64 `json:"alloc_overhead,omitempty"`
+}
+
+// Run measures each contender over objs.
+func Run(objs []object.MemoryObject, opts Options) (*Report, error) {
+	if opts.MinTime <= 0 {
+		opts.MinTime = time.Second
+	}
+	r := &Report{Go: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	var valid []object.MemoryObject
+	for _, obj := range objs {
+		if _, err := hash.CanonicalBytes(obj); err != nil {
+			r.Skipped++
+			continue
+		}
+		valid = append(valid, obj)
+	}
+	r.Objects = len(valid)
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("no object in the corpus canonicalizes")
+	}
+
+	contenders := []struct {
+		name string
+		fn   func(object.MemoryObject) (int, error)
+	}{
+		{Canonical, func(obj object.MemoryObject) (int, error) {
+			data, err := hash.CanonicalBytes(obj)
+			return len(data), err
+		}},
+		{Hash, func(obj object.MemoryObject) (int, error) {
+			canonical, _, err := hash.Current().Hash(obj)
+			return len(canonical), err
+		}},
+	}
+	if opts.CompareStdlib {
+		contenders = append(contenders, struct {
+			name string
+			fn   func(object.MemoryObject) (int, error)
+		}{Stdlib, func(obj object.MemoryObject) (int, error) {
+			data, err := json.Marshal(obj)
+			return len(data), err
+		}})
+	}
+	for _, c := range contenders {
+		res, err := measure(c.name, valid, c.fn, opts.MinTime)
+		if err != nil {
+			return nil, err
+		}
+		r.Results = append(r.Results, res)
+	}
+	if opts.CompareStdlib {
+		canon, std := r.Results[0], r.Results[len(r.Results)-1]
+		r.TimeOverhead = canon.NsPerOp / std.NsPerOp
+		if std.AllocsPerOp > 0 {
+			r.AllocOverhead = canon.AllocsPerOp / std.AllocsPerOp
+		}
+	}
+	return r, nil
+}
+
+// measure runs fn over objs, a whole pass at a time, until minTime has
+// elapsed, after one pass to warm up. Allocations are read from the
+// runtime's counters, which other goroutines also advance; the figures
+// are exact only in an otherwise idle process.
+func measure(name string, objs []object.MemoryObject, fn func(object.MemoryObject) (int, error), minTime time.Duration) (Result, error) {
+	for _, obj := range objs {
+		if _, err := fn(obj); err != nil {
+			return Result{}, fmt.Errorf("%s: object %q: %w", name, obj.Key, err)
+		}
+	}
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	ops, out := 0, 0
+	for ops == 0 || time.Since(start) < minTime {
+		for _, obj := range objs {
+			n, _ := fn(obj)
+			out += n
+		}
+		ops += len(objs)
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return Result{
+		Name:           name,
+		Ops:            ops,
+		NsPerOp:        float64(elapsed.Nanoseconds()) / float64(ops),
+		AllocsPerOp:    float64(after.Mallocs-before.Mallocs) / float64(ops),
+		BytesPerOp:     float64(after.TotalAlloc-before.TotalAlloc) / float64(ops),
+		OutputMBPerSec: float64(out) / 1e6 / elapsed.Seconds(),
+	}, nil
+}
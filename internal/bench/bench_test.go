@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/corpusgen"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func TestRun(t *testing.T) {
+	objs := corpusgen.Generate(1, 20)
+	bad := objs[0]
+	bad.CreatedAt = "yesterday"
+	r, err := Run(append(objs, bad), Options{MinTime: time.Millisecond, CompareStdlib: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Objects != 20 || r.Skipped != 1 {
+		t.Errorf("objects %d, skipped %d, want 20 and 1", r.Objects, r.Skipped)
+	}
+	if len(r.Results) != 3 || r.Results[0].Name != Canonical || r.Results[2].Name != Stdlib {
+		t.Fatalf("results = %+v", r.Results)
+	}
+	for _, res := range r.Results {
+		if res.Ops < 20 || res.NsPerOp <= 0 || res.AllocsPerOp <= 0 || res.OutputMBPerSec <= 0 {
+			t.Errorf("result %+v", res)
+		}
+	}
+	if r.TimeOverhead <= 0 || r.AllocOverhead <= 0 {
+		t.Errorf("overheads %g, %g", r.TimeOverhead, r.AllocOverhead)
+	}
+
+	r, _ = Run(objs, Options{MinTime: time.Millisecond})
+	if len(r.Results) != 2 || r.TimeOverhead != 0 {
+		t.Errorf("without the stdlib: %+v", r)
+	}
+	if _, err := Run([]object.MemoryObject{bad}, Options{}); err == nil {
+		t.Error("a corpus with nothing to canonicalize was measured")
+	}
+}
+
+// The benchmarks measure the same corpus as helios bench, for profiling
+// with go test -bench . -cpuprofile.
+
+var benchCorpus = corpusgen.Generate(1, 500)
+
+func BenchmarkCanonicalBytes(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := hash.CanonicalBytes(benchCorpus[i%len(benchCorpus)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkContentHash(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := hash.ContentHash(benchCorpus[i%len(benchCorpus)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodingJSON(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(benchCorpus[i%len(benchCorpus)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
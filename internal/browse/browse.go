@@ -0,0 +1,558 @@
+// Package browse is the terminal browser of helios browse: a list of the
+// keys of a store or corpus, narrowed by category or a filter expression
+// (package filter), and a view of each object showing its hash, its
+// verification status, its relationships in both directions, and its
+// value.
+//
+// A Browser is only state: Handle applies a key press and View renders
+// the screen as lines of text, so that it can be driven and checked
+// without a terminal. Run drives one on a terminal.
+package browse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/filter"
+	"github.com/holeyfield33-art/helios/internal/graph"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Verification statuses of an item.
+const (
+	// Verified is an object that hashes to the hash it is stored under.
+	Verified = "verified"
+	// Unstored is an object read from a corpus, which records no hash to
+	// check it against.
+	Unstored = "unstored"
+	// Mismatch is an object that hashes to something other than the hash
+	// it is stored under.
+	Mismatch = "mismatch"
+	// Unreadable is a stored object that could not be read or parsed.
+	Unreadable = "unreadable"
+)
+
+// Item is one object to browse.
+type Item struct {
+	Object object.MemoryObject
+	// Hash is the hash the object is stored under, or for an unstored
+	// object its content hash.
+	Hash string
+	// Origin is where the object was read from, such as a corpus file.
+	Origin string
+	Status string
+	// Detail explains a Mismatch or Unreadable status.
+	Detail string
+}
+
+// Check returns the item of obj, stored under want, verified against it.
+// An empty want makes an Unstored item.
+func Check(obj object.MemoryObject, want, origin string) Item {
+	it := Item{Object: obj, Hash: want, Origin: origin, Status: Verified}
+	h, err := hash.ContentHash(obj)
+	switch {
+	case err != nil:
+		it.Status, it.Detail = Unreadable, err.Error()
+	case want == "":
+		it.Hash, it.Status = h, Unstored
+	case h != want:
+		it.Status, it.Detail = Mismatch, "hashes to "+h
+	}
+	return it
+}
+
+// Screens of a Browser.
+const (
+	keysScreen = iota
+	categoriesScreen
+	objectScreen
+)
+
+type screen struct {
+	kind int
+	// item is the index of an object screen's item.
+	item   int
+	cursor int
+	top    int
+}
+
+// Browser is the state of a browsing session.
+type Browser struct {
+	title string
+	items []Item
+	byKey map[string]int
+	g     *graph.Graph
+
+	category string
+	filter   *filter.Filter
+	// list holds the indexes of the items the keys screen shows.
+	list []int
+	// cats holds the categories screen's rows.
+	cats []categoryCount
+
+	screens []screen
+	// editing is set while the filter prompt is open, holding its text.
+	editing *string
+	message string
+
+	// doc caches the document of the object screen last shown.
+	doc     document
+	docItem int
+}
+
+type categoryCount struct {
+	name  string
+	count int
+}
+
+// New returns a browser of items, under title. Where several items have
+// one key, as in a corpus of revisions, the last is browsed.
+func New(title string, items []Item) (*Browser, error) {
+	b := &Browser{title: title, byKey: map[string]int{}, screens: []screen{{kind: keysScreen}}, docItem: -1}
+	for _, it := range items {
+		k := canon.NormalizeString(it.Object.Key)
+		if i, ok := b.byKey[k]; ok {
+			b.items[i] = it
+			continue
+		}
+		b.byKey[k] = len(b.items)
+		b.items = append(b.items, it)
+	}
+	sort.SliceStable(b.items, func(i, j int) bool { return b.items[i].Object.Key < b.items[j].Object.Key })
+	objs := make([]object.MemoryObject, 0, len(b.items))
+	for i, it := range b.items {
+		b.byKey[canon.NormalizeString(it.Object.Key)] = i
+		if it.Status != Unreadable {
+			objs = append(objs, it.Object)
+		}
+	}
+	g, err := graph.Build(objs)
+	if err != nil {
+		return nil, err
+	}
+	b.g = g
+	b.refresh()
+	return b, nil
+}
+
+// SetFilter narrows the keys screen to the objects matching f, or shows
+// them all if f is nil.
+func (b *Browser) SetFilter(f *filter.Filter) {
+	b.filter = f
+	b.refresh()
+}
+
+// refresh recomputes the keys screen's list for the category and filter.
+func (b *Browser) refresh() {
+	b.list = b.list[:0]
+	for i, it := range b.items {
+		if b.category != "" && it.Object.Category != b.category {
+			continue
+		}
+		if b.filter != nil && (it.Status == Unreadable || !b.filter.Match(it.Object)) {
+			continue
+		}
+		b.list = append(b.list, i)
+	}
+	if s := &b.screens[0]; s.cursor >= len(b.list) {
+		s.cursor, s.top = max(0, len(b.list)-1), 0
+	}
+}
+
+func (b *Browser) cur() *screen { return &b.screens[len(b.screens)-1] }
+
+// Handle applies a key press and reports whether browsing goes on.
+func (b *Browser) Handle(k Key, height int) bool {
+	b.message = ""
+	if b.editing != nil {
+		b.edit(k)
+		return true
+	}
+	s := b.cur()
+	rows := b.rows(height)
+	switch k {
+	case "q", KeyCtrlC:
+		return false
+	case KeyUp, "k":
+		b.move(s, -1, rows)
+	case KeyDown, "j":
+		b.move(s, 1, rows)
+	case KeyPageUp:
+		b.page(s, -1, rows)
+	case KeyPageDown, " ":
+		b.page(s, 1, rows)
+	case KeyHome, "g":
+		b.move(s, -b.length(s), rows)
+	case KeyEnd, "G":
+		b.move(s, b.length(s), rows)
+	case KeyEnter, KeyRight, "l":
+		b.open(s)
+	case KeyLeft, KeyBackspace, KeyEsc, "h":
+		if len(b.screens) > 1 {
+			b.screens = b.screens[:len(b.screens)-1]
+		}
+	case "c":
+		b.cats = b.categories()
+		b.screens = append(b.screens, screen{kind: categoriesScreen})
+	case "a":
+		b.category = ""
+		b.screens = b.screens[:1]
+		b.refresh()
+	case "/":
+		text := ""
+		if b.filter != nil {
+			text = b.filter.String()
+		}
+		b.editing = &text
+	}
+	return true
+}
+
+// edit applies a key press to the filter prompt.
+func (b *Browser) edit(k Key) {
+	switch k {
+	case KeyEsc, KeyCtrlC:
+		b.editing = nil
+	case KeyBackspace:
+		if t := *b.editing; t != "" {
+			_, n := utf8.DecodeLastRuneInString(t)
+			*b.editing = t[:len(t)-n]
+		}
+	case KeyEnter:
+		text := strings.TrimSpace(*b.editing)
+		if text == "" {
+			b.editing = nil
+			b.SetFilter(nil)
+			return
+		}
+		f, err := filter.Parse(text)
+		if err != nil {
+			b.message = err.Error()
+			return
+		}
+		b.editing = nil
+		b.screens = b.screens[:1]
+		b.SetFilter(f)
+	default:
+		if r, n := utf8.DecodeRuneInString(string(k)); n == len(k) && unicode.IsPrint(r) {
+			*b.editing += string(k)
+		}
+	}
+}
+
+// length returns the number of rows of s the cursor moves over.
+func (b *Browser) length(s *screen) int {
+	switch s.kind {
+	case keysScreen:
+		return len(b.list)
+	case categoriesScreen:
+		return len(b.cats)
+	}
+	return len(b.document(s.item).lines)
+}
+
+func (b *Browser) move(s *screen, delta, rows int) {
+	if s.kind == objectScreen {
+		b.moveObject(s, delta, rows)
+		return
+	}
+	s.cursor = clamp(s.cursor+delta, 0, b.length(s)-1)
+	s.top = scrollTo(s.cursor, s.top, rows)
+}
+
+func (b *Browser) page(s *screen, dir, rows int) {
+	if s.kind == objectScreen {
+		// Pages scroll the document, and take the cursor to the first
+		// relationship in view.
+		d := b.document(s.item)
+		s.top = clamp(s.top+dir*rows, 0, max(0, len(d.lines)-rows))
+		for i, l := range d.links {
+			if l.line >= s.top && l.line < s.top+rows {
+				s.cursor = i
+				break
+			}
+		}
+		return
+	}
+	b.move(s, dir*rows, rows)
+}
+
+// moveObject moves an object screen's cursor over its relationships,
+// scrolling to keep it in view, or scrolls the document if it has none.
+func (b *Browser) moveObject(s *screen, delta, rows int) {
+	d := b.document(s.item)
+	if len(d.links) == 0 {
+		s.top = clamp(s.top+delta, 0, max(0, len(d.lines)-rows))
+		return
+	}
+	s.cursor = clamp(s.cursor+delta, 0, len(d.links)-1)
+	s.top = scrollTo(d.links[s.cursor].line, s.top, rows)
+}
+
+func (b *Browser) open(s *screen) {
+	switch s.kind {
+	case keysScreen:
+		if len(b.list) > 0 {
+			b.screens = append(b.screens, screen{kind: objectScreen, item: b.list[s.cursor]})
+		}
+	case categoriesScreen:
+		if len(b.cats) > 0 {
+			b.category = b.cats[s.cursor].name
+			b.screens = b.screens[:1]
+			b.screens[0].cursor, b.screens[0].top = 0, 0
+			b.refresh()
+		}
+	case objectScreen:
+		d := b.document(s.item)
+		if len(d.links) == 0 {
+			return
+		}
+		key := d.links[s.cursor].key
+		i, ok := b.byKey[key]
+		if !ok {
+			b.message = fmt.Sprintf("no object has key %q", key)
+			return
+		}
+		b.screens = append(b.screens, screen{kind: objectScreen, item: i})
+	}
+}
+
+// categories counts the objects of each category the filter lets
+// through.
+func (b *Browser) categories() []categoryCount {
+	counts := map[string]int{}
+	for _, it := range b.items {
+		if b.filter == nil || (it.Status != Unreadable && b.filter.Match(it.Object)) {
+			counts[it.Object.Category]++
+		}
+	}
+	out := make([]categoryCount, 0, len(counts))
+	for name, n := range counts {
+		out = append(out, categoryCount{name, n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// document is the text of an object screen.
+type document struct {
+	lines []string
+	// links are the lines naming related keys, in order.
+	links []link
+}
+
+type link struct {
+	line int
+	key  string
+}
+
+// document returns the document of item i.
+func (b *Browser) document(i int) document {
+	if b.docItem != i {
+		b.doc, b.docItem = b.render(i), i
+	}
+	return b.doc
+}
+
+func (b *Browser) render(i int) document {
+	it := b.items[i]
+	obj := it.Object
+	var d document
+	add := func(format string, args ...interface{}) {
+		d.lines = append(d.lines, fmt.Sprintf(format, args...))
+	}
+	add("key:        %s", obj.Key)
+	add("hash:       %s", it.Hash)
+	status := it.Status
+	if it.Detail != "" {
+		status += " (" + it.Detail + ")"
+	}
+	add("status:     %s", status)
+	add("category:   %s", obj.Category)
+	add("source:     %s", obj.Source)
+	add("created_at: %s", obj.CreatedAt)
+	if it.Origin != "" {
+		add("origin:     %s", it.Origin)
+	}
+	for _, f := range []struct{ name, value string }{
+		{"tenant", obj.Tenant}, {"residency", obj.Residency}, {"legal_hold", obj.LegalHold}, {"supersedes", obj.Supersedes},
+	} {
+		if f.value != "" {
+			add("%-11s %s", f.name+":", f.value)
+		}
+	}
+	key := canon.NormalizeString(obj.Key)
+	out := b.g.Neighbors(key, graph.Out, nil)
+	in := b.g.Neighbors(key, graph.In, nil)
+	add("")
+	add("relationships: %d out, %d in", len(out), len(in))
+	for _, e := range out {
+		d.links = append(d.links, link{len(d.lines), e.To})
+		add("  -> %s %s%s", e.Type, e.To, b.missing(e.To))
+	}
+	for _, e := range in {
+		d.links = append(d.links, link{len(d.lines), e.From})
+		add("  <- %s %s", e.Type, e.From)
+	}
+	add("")
+	add("value:")
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(obj.Value); err != nil {
+		add("  (%v)", err)
+	} else {
+		for _, l := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+			add("  %s", l)
+		}
+	}
+	return d
+}
+
+func (b *Browser) missing(key string) string {
+	if _, ok := b.byKey[key]; ok {
+		return ""
+	}
+	return " (missing)"
+}
+
+// rows returns the number of content rows in a view of height lines,
+// less the header and footer.
+func (b *Browser) rows(height int) int {
+	return max(1, height-3)
+}
+
+// View renders the browser as height lines of at most width characters.
+// Control characters in keys and values are shown as '?', so that an
+// object cannot drive the terminal.
+func (b *Browser) View(width, height int) []string {
+	width, height = max(width, 1), max(height, 3)
+	s := b.cur()
+	rows := b.rows(height)
+	var content []string
+	var header string
+	switch s.kind {
+	case keysScreen:
+		header = fmt.Sprintf("%s: %d of %d keys", b.title, len(b.list), len(b.items))
+		if b.category != "" {
+			header += ", category " + b.category
+		}
+		if b.filter != nil {
+			header += ", filter " + b.filter.String()
+		}
+		for i := s.top; i < len(b.list) && i < s.top+rows; i++ {
+			it := b.items[b.list[i]]
+			content = append(content, mark(i == s.cursor)+fmt.Sprintf("%s %s  %s  [%s]", statusMark(it.Status), it.Hash[:min(12, len(it.Hash))], it.Object.Key, it.Object.Category))
+		}
+		if len(b.list) == 0 {
+			content = append(content, "  no keys match")
+		}
+	case categoriesScreen:
+		header = fmt.Sprintf("%s: %d categories", b.title, len(b.cats))
+		for i := s.top; i < len(b.cats) && i < s.top+rows; i++ {
+			c := b.cats[i]
+			content = append(content, mark(i == s.cursor)+fmt.Sprintf("%-32s %d", c.name, c.count))
+		}
+	case objectScreen:
+		header = fmt.Sprintf("%s: %s", b.title, b.items[s.item].Object.Key)
+		d := b.document(s.item)
+		cursorLine := -1
+		if len(d.links) > 0 {
+			cursorLine = d.links[min(s.cursor, len(d.links)-1)].line
+		}
+		for i := s.top; i < len(d.lines) && i < s.top+rows; i++ {
+			content = append(content, mark(i == cursorLine)+d.lines[i])
+		}
+	}
+
+	lines := make([]string, 0, height)
+	lines = append(lines, header, strings.Repeat("-", width))
+	lines = append(lines, content...)
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines[:height-1], b.footer())
+	for i, l := range lines {
+		lines[i] = fit(l, width)
+	}
+	return lines
+}
+
+func (b *Browser) footer() string {
+	switch {
+	case b.editing != nil && b.message != "":
+		return "filter: " + *b.editing + "_  (" + b.message + ")"
+	case b.editing != nil:
+		return "filter: " + *b.editing + "_"
+	case b.message != "":
+		return b.message
+	case b.cur().kind == objectScreen:
+		return "up/down: relationship  enter: follow  pgup/pgdn: scroll  left: back  q: quit"
+	}
+	return "enter: open  c: categories  a: all  /: filter  left: back  q: quit"
+}
+
+func mark(selected bool) string {
+	if selected {
+		return "> "
+	}
+	return "  "
+}
+
+// statusMark abbreviates a verification status for the list of keys.
+func statusMark(status string) string {
+	switch status {
+	case Verified:
+		return "ok "
+	case Unstored:
+		return "   "
+	}
+	return "BAD"
+}
+
+// fit makes s safe to print and cuts it to width characters.
+func fit(s string, width int) string {
+	var out strings.Builder
+	n := 0
+	for _, r := range s {
+		if n == width {
+			break
+		}
+		if !unicode.IsPrint(r) {
+			r = '?'
+		}
+		out.WriteRune(r)
+		n++
+	}
+	return out.String()
+}
+
+func clamp(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
+
+// scrollTo returns the top row that keeps row in a view of rows rows,
+// moving from top as little as it can.
+func scrollTo(row, top, rows int) int {
+	if row < top {
+		return row
+	}
+	if row >= top+rows {
+		return row - rows + 1
+	}
+	return top
+}
@@ -0,0 +1,167 @@
+package browse
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/filter"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func parse(t *testing.T, doc string) object.MemoryObject {
+	t.Helper()
+	obj, err := ingest.ParseObject([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func testBrowser(t *testing.T) *Browser {
+	t.Helper()
+	a := parse(t, `{"key": "a", "category": "task", "source": "s", "created_at": "2025-01-01T00:00:00.000Z",
+		"relationships": [{"key": "b", "type": "blocks"}, {"key": "gone", "type": "cites"}],
+		"value": {"note": "next \u0085 line", "n": 1}}`)
+	b := parse(t, `{"key": "b", "category": "task", "source": "s", "created_at": "2025-01-02T00:00:00.000Z", "value": "x"}`)
+	c := parse(t, `{"key": "c", "category": "note", "source": "s", "created_at": "2025-01-03T00:00:00.000Z", "value": "y"}`)
+	hb, err := hash.ContentHash(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err := New("test", []Item{
+		Check(c, "", "c.json"),
+		Check(a, strings.Repeat("0", 64), "store"),
+		Check(b, hb, "store"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return br
+}
+
+// screenOf renders br and returns its content rows, trimmed.
+func screenOf(br *Browser) string {
+	lines := br.View(80, 20)
+	return strings.TrimSpace(strings.Join(lines[2:len(lines)-1], "\n"))
+}
+
+func press(br *Browser, keys ...Key) {
+	for _, k := range keys {
+		br.Handle(k, 20)
+	}
+}
+
+func TestBrowseKeys(t *testing.T) {
+	br := testBrowser(t)
+	got := screenOf(br)
+	for _, want := range []string{"> BAD 000000000000  a  [task]", "ok ", "  b  [task]", "  c  [note]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("keys screen lacks %q:\n%s", want, got)
+		}
+	}
+
+	// Open a, whose stored hash is wrong: its status says so, and its
+	// relationships are listed with the missing one marked.
+	press(br, KeyEnter)
+	got = screenOf(br)
+	for _, want := range []string{"status:     mismatch (hashes to ", ">   -> blocks b", "-> cites gone (missing)", `"note": "next ? line"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("object screen lacks %q:\n%s", want, got)
+		}
+	}
+
+	// Follow a -> b, and see the relationship from b's side.
+	press(br, KeyEnter)
+	if got = screenOf(br); !strings.Contains(got, "key:        b") || !strings.Contains(got, ">   <- blocks a") || !strings.Contains(got, "status:     verified") {
+		t.Errorf("after following a -> b:\n%s", got)
+	}
+
+	// A missing key cannot be followed.
+	press(br, KeyLeft, KeyDown, KeyEnter)
+	if footer := br.View(80, 20)[19]; footer != `no object has key "gone"` {
+		t.Errorf("footer = %q", footer)
+	}
+	press(br, KeyLeft)
+	if got := screenOf(br); !strings.HasPrefix(got, "> BAD") {
+		t.Errorf("back on the keys screen:\n%s", got)
+	}
+}
+
+func TestBrowseCategoriesAndFilter(t *testing.T) {
+	br := testBrowser(t)
+	press(br, "c")
+	if got := screenOf(br); !strings.Contains(got, "> note") || !strings.Contains(got, "task                             2") {
+		t.Errorf("categories screen:\n%s", got)
+	}
+	press(br, KeyDown, KeyEnter)
+	if got := br.View(80, 20)[0]; got != "test: 2 of 3 keys, category task" {
+		t.Errorf("header = %q", got)
+	}
+	press(br, "a")
+	if got := br.View(80, 20)[0]; got != "test: 3 of 3 keys" {
+		t.Errorf("header = %q", got)
+	}
+
+	// A filter that does not parse keeps the prompt open with the error.
+	press(br, "/", "k", "e", "y", " ", "=", KeyEnter)
+	if footer := br.View(80, 20)[19]; !strings.HasPrefix(footer, "filter: key =_  (filter: offset 4") {
+		t.Errorf("footer = %q", footer)
+	}
+	press(br, "=", " ", `"`, "c", `"`, KeyEnter)
+	if got := screenOf(br); got != "> "+"    "+br.items[2].Hash[:12]+"  c  [note]" {
+		t.Errorf("filtered keys:\n%s", got)
+	}
+	press(br, "/", KeyEsc)
+	if br.filter == nil {
+		t.Error("escape cleared the filter")
+	}
+	f, err := filter.Parse(`created_at > "2025-01-01"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br.SetFilter(f)
+	if got := br.View(80, 20)[0]; got != `test: 2 of 3 keys, filter created_at > "2025-01-01"` {
+		t.Errorf("header = %q", got)
+	}
+}
+
+func TestViewFits(t *testing.T) {
+	br := testBrowser(t)
+	for _, size := range [][2]int{{80, 20}, {10, 3}, {0, 0}} {
+		lines := br.View(size[0], size[1])
+		if len(lines) != max(size[1], 3) {
+			t.Errorf("%v: %d lines", size, len(lines))
+		}
+		for _, l := range lines {
+			if n := len([]rune(l)); n > max(size[0], 1) {
+				t.Errorf("%v: line of %d characters: %q", size, n, l)
+			}
+		}
+	}
+	if q := br.Handle("q", 20); q {
+		t.Error("q did not quit")
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("j\x1b[A\x1b[6~\x1bOH\r\x7f\x03é\x1b[99Z\x01"))
+	var got []string
+	for {
+		k, err := ReadKey(r)
+		if err != nil {
+			break
+		}
+		got = append(got, string(k))
+	}
+	want := "j up pgdn home enter backspace ctrl+c é  "
+	if strings.Join(got, " ") != want {
+		t.Errorf("keys %q, want %q", strings.Join(got, " "), want)
+	}
+	// An escape alone is the Esc key.
+	if k, err := ReadKey(bufio.NewReader(strings.NewReader("\x1b"))); k != KeyEsc || err != nil {
+		t.Errorf("lone escape = %q, %v", k, err)
+	}
+}
@@ -0,0 +1,89 @@
+package browse
+
+import (
+	"bufio"
+	"unicode/utf8"
+)
+
+// Key is a key press: a printable character as itself, or one of the
+// named keys.
+type Key string
+
+// Named keys.
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdn"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyEnter     Key = "enter"
+	KeyBackspace Key = "backspace"
+	KeyEsc       Key = "esc"
+	KeyCtrlC     Key = "ctrl+c"
+)
+
+// csiKeys maps the final byte, or the parameter of '~', of the escape
+// sequences terminals send for the named keys.
+var csiKeys = map[string]Key{
+	"A": KeyUp, "B": KeyDown, "C": KeyRight, "D": KeyLeft,
+	"H": KeyHome, "F": KeyEnd,
+	"1~": KeyHome, "7~": KeyHome, "4~": KeyEnd, "8~": KeyEnd,
+	"5~": KeyPageUp, "6~": KeyPageDown,
+}
+
+// ReadKey reads one key press from r, the input of a terminal in raw
+// mode. It returns "" for a key it does not know. An escape not followed
+// at once by more input is the Esc key.
+func ReadKey(r *bufio.Reader) (Key, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case c == 0x1b:
+		if r.Buffered() == 0 {
+			return KeyEsc, nil
+		}
+		intro, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if intro != '[' && intro != 'O' {
+			return "", nil
+		}
+		// Parameters, then a final byte in 0x40-0x7e.
+		var seq []byte
+		for {
+			c, err := r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			seq = append(seq, c)
+			if c >= 0x40 && c <= 0x7e {
+				break
+			}
+		}
+		return csiKeys[string(seq)], nil
+	case c == '\r' || c == '\n':
+		return KeyEnter, nil
+	case c == 0x7f || c == 0x08:
+		return KeyBackspace, nil
+	case c == 0x03:
+		return KeyCtrlC, nil
+	case c < 0x20:
+		return "", nil
+	case c < utf8.RuneSelf:
+		return Key(c), nil
+	}
+	if err := r.UnreadByte(); err != nil {
+		return "", err
+	}
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	return Key(string(ch)), nil
+}
@@ -0,0 +1,75 @@
+package browse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+)
+
+// Run shows b on the terminal of in and out until the user quits. It
+// takes over the terminal's alternate screen, and restores the terminal
+// when it returns.
+func Run(in, out *os.File, b *Browser) error {
+	restore, err := makeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	w := bufio.NewWriter(out)
+	w.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		w.WriteString("\x1b[?25h\x1b[?1049l")
+		w.Flush()
+	}()
+
+	keys := make(chan Key)
+	errs := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			k, err := ReadKey(r)
+			if err != nil {
+				errs <- err
+				return
+			}
+			keys <- k
+		}
+	}()
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+
+	for {
+		width, height := size(out)
+		if err := draw(w, b.View(width, height)); err != nil {
+			return err
+		}
+		select {
+		case k := <-keys:
+			if !b.Handle(k, height) {
+				return nil
+			}
+		case <-resized:
+		case err := <-errs:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// draw writes lines over the screen, from its top left corner.
+func draw(w *bufio.Writer, lines []string) error {
+	w.WriteString("\x1b[H")
+	for i, l := range lines {
+		if i > 0 {
+			w.WriteString("\r\n")
+		}
+		w.WriteString(l)
+		w.WriteString("\x1b[K")
+	}
+	return w.Flush()
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package browse
+
+import (
+	"errors"
+	"os"
+)
+
+var errNotTerminal = errors.New("browse: terminal browsing is supported only on Linux and BSD systems, macOS included")
+
+func makeRaw(f *os.File) (restore func(), err error) { return nil, errNotTerminal }
+
+func size(f *os.File) (width, height int) { return 80, 24 }
+
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,54 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package browse
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// errNotTerminal is returned by Run when its input is not a terminal.
+var errNotTerminal = errors.New("browse: standard input is not a terminal")
+
+func ioctl(fd uintptr, req uint, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(req), uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// makeRaw puts the terminal f into raw mode, as cfmakeraw does, and
+// returns the function that restores its mode.
+func makeRaw(f *os.File) (restore func(), err error) {
+	var old syscall.Termios
+	if err := ioctl(f.Fd(), ioctlGetTermios, unsafe.Pointer(&old)); err != nil {
+		return nil, errNotTerminal
+	}
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN], raw.Cc[syscall.VTIME] = 1, 0
+	if err := ioctl(f.Fd(), ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return func() { ioctl(f.Fd(), ioctlSetTermios, unsafe.Pointer(&old)) }, nil
+}
+
+// size returns the width and height of the terminal f, or 80x24 if it
+// does not say.
+func size(f *os.File) (width, height int) {
+	var ws struct{ Row, Col, X, Y uint16 }
+	if err := ioctl(f.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// notifyResize sends on c when the terminal is resized.
+func notifyResize(c chan<- os.Signal) { signal.Notify(c, syscall.SIGWINCH) }
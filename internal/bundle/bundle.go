@@ -0,0 +1,382 @@
+// Package bundle packages memory objects together with everything needed
+// to verify them offline: their content hashes, DSSE signatures, the public
+// keys that made them, the conformance vectors, and the verification
+// profile the hashes were computed under.
+//
+// A bundle is a gzipped tar archive. Its first entry, bundle.json, is a
+// manifest naming every other entry, so a bundle can be inspected with
+// standard tools and fully checked without network access.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+	"github.com/holeyfield33-art/helios/internal/verify"
+)
+
+// Format identifies the bundle layout.
+const Format = "helios-bundle/v1"
+
+// ManifestName is the archive entry holding the Manifest.
+const ManifestName = "bundle.json"
+
+// maxEntrySize bounds any single archive entry.
+const maxEntrySize = 64 << 20
+
+// Profile records the rules the bundled hashes were computed under. A
+// verifier checks the objects with the pipeline of the bundle's profile
+// and refuses bundles whose profile it has no pipeline for.
+type Profile = hash.Profile
+
+// CurrentProfile returns the profile new bundles are written with.
+func CurrentProfile() Profile {
+	return hash.Current().Profile
+}
+
+// ObjectEntry names one bundled object and its content hash.
+type ObjectEntry struct {
+	Path string `json:"path"`
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+// Manifest is the bundle.json entry.
+type Manifest struct {
+	Format     string        `json:"format"`
+	Profile    Profile       `json:"profile"`
+	Objects    []ObjectEntry `json:"objects"`
+	Signatures []string      `json:"signatures,omitempty"`
+	Keys       []string      `json:"keys,omitempty"`
+	Vectors    string        `json:"vectors,omitempty"`
+}
+
+// Contents is the material to package.
+type Contents struct {
+	Objects    []object.MemoryObject
+	Signatures [][]byte // DSSE envelopes
+	Keys       [][]byte // PEM public keys
+	Vectors    []byte   // vectors.json
+}
+
+// Bundle is a parsed bundle: its manifest and the raw bytes of every entry.
+type Bundle struct {
+	Manifest Manifest
+	Files    map[string][]byte
+}
+
+// Write packages c as a bundle. Objects are stored as the canonical bytes
+// that were hashed.
+func Write(w io.Writer, c Contents) error {
+	pipeline := hash.Current()
+	m := Manifest{Format: Format, Profile: pipeline.Profile}
+	files := map[string][]byte{}
+
+	for i, obj := range c.Objects {
+		b, err := pipeline.CanonicalBytes(obj)
+		if err != nil {
+			return fmt.Errorf("object %d (key %q): %w", i, obj.Key, err)
+		}
+		p := fmt.Sprintf("objects/%06d.json", i)
+		files[p] = b
+		m.Objects = append(m.Objects, ObjectEntry{Path: p, Key: obj.Key, Hash: pipeline.Sum(b)})
+	}
+	for i, sig := range c.Signatures {
+		p := fmt.Sprintf("signatures/%03d.json", i)
+		files[p] = sig
+		m.Signatures = append(m.Signatures, p)
+	}
+	for i, key := range c.Keys {
+		p := fmt.Sprintf("keys/%03d.pem", i)
+		files[p] = key
+		m.Keys = append(m.Keys, p)
+	}
+	if c.Vectors != nil {
+		m.Vectors = "vectors/vectors.json"
+		files[m.Vectors] = c.Vectors
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := writeEntry(tw, ManifestName, append(manifest, '\n')); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := writeEntry(tw, name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Format: tar.FormatPAX}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Read parses a bundle. Every entry named by the manifest must be present;
+// entries the manifest does not name are rejected.
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a helios bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("bundle entry %s is not a regular file", hdr.Name)
+		}
+		name := path.Clean(hdr.Name)
+		if name != hdr.Name || path.IsAbs(name) || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("bundle entry has unsafe path %q", hdr.Name)
+		}
+		if _, dup := files[name]; dup {
+			return nil, fmt.Errorf("duplicate bundle entry %s", name)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxEntrySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle entry %s: %w", name, err)
+		}
+		if len(data) > maxEntrySize {
+			return nil, fmt.Errorf("bundle entry %s exceeds %d bytes", name, maxEntrySize)
+		}
+		files[name] = data
+	}
+
+	raw, ok := files[ManifestName]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s", ManifestName)
+	}
+	delete(files, ManifestName)
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ManifestName, err)
+	}
+	if m.Format != Format {
+		return nil, fmt.Errorf("unsupported bundle format %q", m.Format)
+	}
+
+	named := map[string]bool{}
+	for _, o := range m.Objects {
+		named[o.Path] = true
+	}
+	for _, p := range append(append([]string{}, m.Signatures...), m.Keys...) {
+		named[p] = true
+	}
+	if m.Vectors != "" {
+		named[m.Vectors] = true
+	}
+	for p := range named {
+		if _, ok := files[p]; !ok {
+			return nil, fmt.Errorf("bundle is missing %s", p)
+		}
+	}
+	for p := range files {
+		if !named[p] {
+			return nil, fmt.Errorf("bundle entry %s is not listed in %s", p, ManifestName)
+		}
+	}
+	return &Bundle{Manifest: m, Files: files}, nil
+}
+
+// VerifyOptions controls which keys signatures are checked against.
+type VerifyOptions struct {
+	// Keys are the trusted signing keys.
+	Keys []crypto.PublicKey
+	// TrustEmbedded also accepts the keys packaged in the bundle. This
+	// proves the bundle is internally consistent, not who produced it.
+	TrustEmbedded bool
+	// Policy, if set, decides which signatures count: Keys, the policy's
+	// own keys, and the bundle's embedded keys are all only candidates,
+	// and each signature must meet the policy at time At, or now if At
+	// is zero.
+	Policy *signing.TrustPolicy
+	At     time.Time
+	// Revocations, if set, rejects signatures by the keys it revokes as
+	// of At. The caller checks the list's own signature.
+	Revocations *signing.RevocationList
+}
+
+// Report summarizes a verification. Problems lists every check that
+// failed; Mismatches repeats the object content hash failures among them
+// in structured form.
+type Report struct {
+	Objects    int
+	Signatures int
+	Vectors    int
+	Problems   []string
+	Mismatches []Mismatch
+}
+
+// Mismatch is a bundled object whose recomputed content hash or key does
+// not match the manifest.
+type Mismatch struct {
+	Path     string
+	Key      string
+	Expected string
+	Actual   string
+}
+
+// OK reports whether every check passed.
+func (r *Report) OK() bool { return len(r.Problems) == 0 }
+
+// Verify re-checks everything in b: the profile, each object's content
+// hash, each signature and its subjects, and the bundled vectors. When any
+// trust is configured (Keys, TrustEmbedded, or Policy), at least one
+// signature must verify and every bundled object must be a subject of a
+// verified statement.
+func Verify(b *Bundle, opts VerifyOptions) *Report {
+	rep := &Report{}
+	problem := func(format string, args ...interface{}) {
+		rep.Problems = append(rep.Problems, fmt.Sprintf(format, args...))
+	}
+
+	pipeline, err := hash.LookupProfile(b.Manifest.Profile)
+	if err != nil {
+		problem("%v", err)
+		pipeline = hash.Current()
+	}
+
+	hashes := map[string]string{}
+	var intact []ObjectEntry
+	for _, e := range b.Manifest.Objects {
+		rep.Objects++
+		obj, err := ingest.ParseObject(b.Files[e.Path])
+		if err != nil {
+			problem("%s: %v", e.Path, err)
+			continue
+		}
+		got, err := pipeline.ContentHash(obj)
+		if err != nil {
+			problem("%s: %v", e.Path, err)
+			continue
+		}
+		if !hash.Equal(e.Hash, got) || obj.Key != e.Key {
+			problem("%s: content hash %s (key %q) does not match manifest %s (key %q)", e.Path, got, obj.Key, e.Hash, e.Key)
+			rep.Mismatches = append(rep.Mismatches, Mismatch{Path: e.Path, Key: e.Key, Expected: e.Hash, Actual: got})
+			continue
+		}
+		hashes[e.Key] = e.Hash
+		intact = append(intact, e)
+	}
+
+	keys := append([]crypto.PublicKey{}, opts.Keys...)
+	if opts.TrustEmbedded || opts.Policy != nil {
+		for _, p := range b.Manifest.Keys {
+			k, err := signing.ParsePublicKey(b.Files[p])
+			if err != nil {
+				problem("%s: %v", p, err)
+				continue
+			}
+			keys = append(keys, k)
+		}
+	}
+	at := opts.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	policy := opts.Policy
+	if policy != nil && opts.Revocations != nil {
+		p := *policy
+		p.Revocations = opts.Revocations
+		policy = &p
+	}
+	allowed, revoked := opts.Revocations.Filter(at, keys...)
+	trusted := len(opts.Keys) > 0 || opts.TrustEmbedded || opts.Policy != nil
+	verified := 0
+	covered := map[ObjectEntry]bool{}
+	for _, p := range b.Manifest.Signatures {
+		rep.Signatures++
+		var env attest.Envelope
+		if err := json.Unmarshal(b.Files[p], &env); err != nil {
+			problem("%s: invalid envelope: %v", p, err)
+			continue
+		}
+		var st *attest.Statement
+		switch {
+		case policy != nil:
+			st, _, err = attest.VerifyPolicy(&env, policy, at, keys...)
+		case len(keys) == 0:
+			problem("%s: no trusted keys to verify against", p)
+			continue
+		default:
+			if st, err = attest.Verify(&env, allowed...); err != nil && len(revoked) > 0 {
+				err = fmt.Errorf("%w; %s", err, strings.Join(revoked, "; "))
+			}
+		}
+		if err != nil {
+			problem("%s: %v", p, err)
+			continue
+		}
+		verified++
+		for _, s := range st.Subject {
+			if hashes[s.Name] != s.Digest["sha256"] {
+				problem("%s: subject %q (%s) is not a bundled object", p, s.Name, s.Digest["sha256"])
+				continue
+			}
+			covered[ObjectEntry{Key: s.Name, Hash: s.Digest["sha256"]}] = true
+		}
+	}
+	if trusted {
+		if verified == 0 {
+			problem("no signature verified against the trusted keys")
+		}
+		for _, e := range intact {
+			if !covered[ObjectEntry{Key: e.Key, Hash: e.Hash}] {
+				problem("%s: object %q is not covered by a verified signature", e.Path, e.Key)
+			}
+		}
+	}
+
+	if b.Manifest.Vectors != "" {
+		vf, err := verify.ParseVectors(b.Files[b.Manifest.Vectors])
+		if err != nil {
+			problem("%s: %v", b.Manifest.Vectors, err)
+		} else {
+			results, err := verify.VerifyVectorsFile(vf, verify.Options{})
+			rep.Vectors = len(results)
+			if err != nil {
+				problem("%s: %v", b.Manifest.Vectors, err)
+			}
+		}
+	}
+	return rep
+}
@@ -0,0 +1,162 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+func testObject(key string) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "user",
+		Value:         "bundled",
+	}
+}
+
+func testContents(t *testing.T) (Contents, crypto.PublicKey) {
+	t.Helper()
+	objs := []object.MemoryObject{testObject("a"), testObject("b")}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, _ := signing.NewSigner(priv)
+	st, err := attest.NewStatement(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := attest.Sign(st, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, _ := json.Marshal(env)
+	pub, _ := signing.EncodePublicKey(signer.Public())
+	vectors, err := os.ReadFile(filepath.Join("..", "..", "test_vectors", "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Contents{Objects: objs, Signatures: [][]byte{sig}, Keys: [][]byte{pub}, Vectors: vectors}, signer.Public()
+}
+
+func roundTrip(t *testing.T, c Contents) *Bundle {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestWriteReadVerify(t *testing.T) {
+	c, pub := testContents(t)
+	b := roundTrip(t, c)
+
+	rep := Verify(b, VerifyOptions{Keys: []crypto.PublicKey{pub}})
+	if !rep.OK() {
+		t.Fatalf("expected clean bundle, got %v", rep.Problems)
+	}
+	if rep.Objects != 2 || rep.Signatures != 1 || rep.Vectors != 17 {
+		t.Errorf("unexpected counts: %+v", rep)
+	}
+
+	if rep := Verify(b, VerifyOptions{TrustEmbedded: true}); !rep.OK() {
+		t.Errorf("embedded keys should verify: %v", rep.Problems)
+	}
+	if rep := Verify(b, VerifyOptions{}); rep.OK() {
+		t.Error("expected failure with no trusted keys")
+	}
+}
+
+func TestVerifyDetectsTamperedObject(t *testing.T) {
+	c, pub := testContents(t)
+	b := roundTrip(t, c)
+	p := b.Manifest.Objects[0].Path
+	b.Files[p] = bytes.Replace(b.Files[p], []byte("bundled"), []byte("tampered"), 1)
+
+	rep := Verify(b, VerifyOptions{Keys: []crypto.PublicKey{pub}})
+	if rep.OK() || !strings.Contains(rep.Problems[0], "does not match manifest") {
+		t.Errorf("expected hash mismatch, got %v", rep.Problems)
+	}
+	if len(rep.Mismatches) != 1 {
+		t.Fatalf("expected one structured mismatch, got %+v", rep.Mismatches)
+	}
+	m := rep.Mismatches[0]
+	if m.Path != p || m.Key != b.Manifest.Objects[0].Key || m.Expected != b.Manifest.Objects[0].Hash || m.Actual == m.Expected {
+		t.Errorf("mismatch %+v", m)
+	}
+}
+
+func TestVerifyDetectsProfileMismatch(t *testing.T) {
+	c, pub := testContents(t)
+	b := roundTrip(t, c)
+	b.Manifest.Profile.UnicodeVersion = "9.0.0"
+	if rep := Verify(b, VerifyOptions{Keys: []crypto.PublicKey{pub}}); rep.OK() {
+		t.Error("expected profile mismatch")
+	}
+}
+
+func TestReadRejectsUnlistedAndUnsafeEntries(t *testing.T) {
+	build := func(names ...string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		writeEntry(tw, ManifestName, []byte(`{"format":"`+Format+`","objects":[]}`))
+		for _, n := range names {
+			writeEntry(tw, n, []byte("x"))
+		}
+		tw.Close()
+		gz.Close()
+		return buf.Bytes()
+	}
+	if _, err := Read(bytes.NewReader(build())); err != nil {
+		t.Fatalf("empty bundle should read: %v", err)
+	}
+	if _, err := Read(bytes.NewReader(build("extra.json"))); err == nil || !strings.Contains(err.Error(), "not listed") {
+		t.Errorf("expected unlisted entry error, got %v", err)
+	}
+	if _, err := Read(bytes.NewReader(build("../escape"))); err == nil || !strings.Contains(err.Error(), "unsafe") {
+		t.Errorf("expected unsafe path error, got %v", err)
+	}
+}
+
+func TestVerifyRequiresSignatureCoverage(t *testing.T) {
+	c, pub := testContents(t)
+
+	unsigned := c
+	unsigned.Signatures = nil
+	rep := Verify(roundTrip(t, unsigned), VerifyOptions{Keys: []crypto.PublicKey{pub}})
+	if rep.OK() || !strings.Contains(strings.Join(rep.Problems, "\n"), "no signature verified") {
+		t.Errorf("expected failure without a verified signature, got %v", rep.Problems)
+	}
+
+	extra := c
+	extra.Objects = append(append([]object.MemoryObject{}, c.Objects...), testObject("c"))
+	rep = Verify(roundTrip(t, extra), VerifyOptions{Keys: []crypto.PublicKey{pub}})
+	if len(rep.Problems) != 1 || !strings.Contains(rep.Problems[0], `object "c" is not covered`) {
+		t.Errorf("expected only the unsigned object to be reported, got %v", rep.Problems)
+	}
+
+	if rep := Verify(roundTrip(t, unsigned), VerifyOptions{}); !rep.OK() {
+		t.Errorf("an unsigned bundle checked without trust should verify: %v", rep.Problems)
+	}
+}
@@ -0,0 +1,101 @@
+package canon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// BytesKey is the only member of the object a binary value is written as
+// (Section 3.8, RULE-013).
+const BytesKey = "$bytes"
+
+// MaxBytesLen is the length limit, in bytes, of a binary value.
+const MaxBytesLen = 16 << 20
+
+// Bytes is a binary value. It canonicalizes as {"$bytes":"..."}, holding
+// the bytes in base64url (RFC 4648 Section 5) without padding: the one
+// encoding of binary data every implementation must produce, so a payload
+// no longer hashes differently depending on which base64 variant its
+// writer picked. A decoded JSON object with the single member "$bytes" is
+// the same value; a profile with extensions validates it the same way,
+// while version 1 without them hashes it as an ordinary map.
+type Bytes []byte
+
+// String returns the base64url form of b.
+func (b Bytes) String() string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Value returns b as decoded JSON: the map {"$bytes": b.String()}.
+func (b Bytes) Value() map[string]interface{} {
+	return map[string]interface{}{BytesKey: b.String()}
+}
+
+// MarshalJSON writes b in its canonical form.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Value())
+}
+
+// ParseBytes recognizes a binary value in decoded JSON. It returns false
+// if v is not an object whose only member is "$bytes", and an error with
+// CANON_ERR_BYTES_INVALID if it is one whose member is not a string in
+// canonical base64url, or CANON_ERR_TOO_LARGE if the bytes are longer
+// than MaxBytesLen.
+func ParseBytes(v interface{}) (Bytes, bool, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, false, nil
+	}
+	enc, ok := m[BytesKey]
+	if !ok {
+		return nil, false, nil
+	}
+	s, ok := enc.(string)
+	if !ok {
+		return nil, true, &Error{Code: ErrCodeBytesInvalid, Path: BytesKey, Reason: fmt.Sprintf("%T, not a string", enc)}
+	}
+	b, err := decodeBytes(s)
+	return b, true, err
+}
+
+// decodeBytes decodes the base64url form of a binary value, accepting
+// only the form Bytes.String produces: no padding, no line breaks, and
+// no set bits after the last full byte.
+func decodeBytes(s string) (Bytes, error) {
+	if len(s) > base64.RawURLEncoding.EncodedLen(MaxBytesLen) {
+		return nil, bytesTooLarge()
+	}
+	b, err := base64.RawURLEncoding.Strict().DecodeString(s)
+	if err != nil || base64.RawURLEncoding.EncodeToString(b) != s {
+		return nil, &Error{Code: ErrCodeBytesInvalid, Path: BytesKey, Value: s}
+	}
+	return b, nil
+}
+
+func bytesTooLarge() error {
+	return &Error{Code: ErrCodeTooLarge, Reason: fmt.Sprintf("binary value longer than %d bytes", MaxBytesLen)}
+}
+
+// checkBytes validates a Bytes before it is canonicalized.
+func checkBytes(b Bytes) error {
+	if len(b) > MaxBytesLen {
+		return bytesTooLarge()
+	}
+	return nil
+}
+
+// withPath sets the path of a canonicalization error raised for the
+// value at path.
+func withPath(err error, path string) error {
+	if ce, ok := err.(*Error); ok {
+		c := *ce
+		if c.Path == "" {
+			c.Path = path
+		} else {
+			c.Path = path + "." + c.Path
+		}
+		return &c
+	}
+	return err
+}
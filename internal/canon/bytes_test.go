@@ -0,0 +1,76 @@
+package canon
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// RULE-013: binary values are {"$bytes": ...} in unpadded base64url.
+func TestBytesCanonicalizesAsWrapper(t *testing.T) {
+	b := Bytes("hello")
+	got, err := CanonicalizeValue(map[string]interface{}{"payload": b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"payload":{"$bytes":"aGVsbG8"}}`; string(got) != want {
+		t.Errorf("Bytes canonicalized as %s, want %s", got, want)
+	}
+	decoded, _ := CanonicalizeValue(map[string]interface{}{"payload": map[string]interface{}{"$bytes": "aGVsbG8"}})
+	if string(decoded) != string(got) {
+		t.Errorf("decoded wrapper canonicalized as %s, want %s", decoded, got)
+	}
+	if n, err := CanonicalSize(map[string]interface{}{"payload": b}); err != nil || n != int64(len(got)) {
+		t.Errorf("CanonicalSize = %d, %v; want %d", n, err, len(got))
+	}
+	if m, _ := json.Marshal(b); string(m) != `{"$bytes":"aGVsbG8"}` {
+		t.Errorf("MarshalJSON = %s", m)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	b, ok, err := ParseBytes(map[string]interface{}{"$bytes": "_-8"})
+	if !ok || err != nil || !bytes.Equal(b, []byte{0xff, 0xef}) {
+		t.Errorf("ParseBytes(_-8) = %x, %v, %v", b, ok, err)
+	}
+	for _, v := range []interface{}{"aGVsbG8", map[string]interface{}{"$bytes": "aGVsbG8", "mime": "text/plain"}, map[string]interface{}{"bytes": "aGVsbG8"}} {
+		if _, ok, err := ParseBytes(v); ok || err != nil {
+			t.Errorf("ParseBytes(%v) = %v, %v; want not a binary value", v, ok, err)
+		}
+	}
+	for _, enc := range []interface{}{"aGVsbG8=", "/+8", "aGVsbG9", "aGVs\nbG8", "a", int64(5)} {
+		v := map[string]interface{}{"$bytes": enc}
+		if _, ok, err := ParseBytes(v); !ok || ErrorCode(err) != ErrCodeBytesInvalid {
+			t.Errorf("ParseBytes(%q) = %v, %v; want %s", enc, ok, err, ErrCodeBytesInvalid)
+		}
+		err := ValidateExtensions(map[string]interface{}{"blob": v})
+		if ErrorCode(err) != ErrCodeBytesInvalid {
+			t.Errorf("ValidateExtensions(%q): %v", enc, err)
+		} else if ce := err.(*Error); ce.Path != "value.blob.$bytes" {
+			t.Errorf("ValidateExtensions(%q) path = %q", enc, ce.Path)
+		}
+	}
+	// Version 1 without extensions hashes the wrapper as an ordinary map.
+	v := map[string]interface{}{"$bytes": "aGVsbG8="}
+	if err := ValidateIngestValue(v); err != nil {
+		t.Errorf("ValidateIngestValue(%v): %v", v, err)
+	}
+	if got, err := CanonicalizeValue(v); err != nil || string(got) != `{"$bytes":"aGVsbG8="}` {
+		t.Errorf("CanonicalizeValue(%v) = %s, %v", v, got, err)
+	}
+}
+
+func TestBytesSizeLimit(t *testing.T) {
+	big := make(Bytes, MaxBytesLen+1)
+	if _, err := CanonicalizeValue(big); ErrorCode(err) != ErrCodeTooLarge {
+		t.Errorf("CanonicalizeValue of %d bytes: %v", len(big), err)
+	}
+	if _, err := CanonicalSize(big); ErrorCode(err) != ErrCodeTooLarge {
+		t.Errorf("CanonicalSize of %d bytes: %v", len(big), err)
+	}
+	enc := map[string]interface{}{"$bytes": strings.Repeat("A", len(big[:MaxBytesLen].String())+2)}
+	if _, _, err := ParseBytes(enc); ErrorCode(err) != ErrCodeTooLarge {
+		t.Errorf("ParseBytes of an encoding over the limit: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package canon
+
+import (
+	"slices"
+	"strings"
+)
+
+// CompareCanonicalKeys orders two object keys, or two relationship keys or
+// types, as the canonical form does, returning -1, 0, or +1. The order is
+// by the bytes of the keys' UTF-8 encoding, which for valid UTF-8 is the
+// order of their Unicode code points. It never depends on the locale and
+// applies no case folding or normalization: "A" < "a" < "b" < "ä", and a
+// key is compared in the NFC form it was normalized to.
+//
+// Implementations in other languages must compare the same way: compare
+// UTF-8 encoded bytes (Python 3 str comparison also orders by code point),
+// never use locale-aware collation, and beware of languages that compare
+// UTF-16 code units, such as JavaScript's default sort and Java's
+// String.compareTo: they put U+FF61 after U+1F600, which code point
+// order puts first.
+func CompareCanonicalKeys(a, b string) int {
+	if mutant != MutantNone {
+		return mutantCompare(a, b)
+	}
+	return strings.Compare(a, b)
+}
+
+// sortKeys sorts keys into canonical order.
+func sortKeys(keys []string) {
+	slices.SortFunc(keys, CompareCanonicalKeys)
+}
@@ -0,0 +1,82 @@
+package canon
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCompareCanonicalKeys pins the pairs that locale-aware or UTF-16
+// collation orders differently from code point order.
+func TestCompareCanonicalKeys(t *testing.T) {
+	less := [][2]string{
+		{"", "a"},
+		{"A", "a"},
+		{"Z", "a"},
+		{"_", "a"},
+		{"a", "~"},
+		{"10", "9"},
+		{"a", "ab"},
+		{"ab", "a\u00e4"},
+		{"z", "\u00c4"},
+		{"\u00c4", "\u00e4"},
+		{"ss", "\u00df"},
+		{"I", "i"},
+		{"i", "\u0130"},
+		{"\u0130", "\u0131"},
+		{"e\u0301", "\u00e9"},    // NFD sorts before NFC; keys are not normalized here
+		{"\ue000", "\uff61"},     // private use is a code point like any other
+		{"\uff61", "\U0001f600"}, // UTF-16 code units order these the other way
+	}
+	for _, p := range less {
+		if got := CompareCanonicalKeys(p[0], p[1]); got != -1 {
+			t.Errorf("CompareCanonicalKeys(%+q, %+q) = %d, want -1", p[0], p[1], got)
+		}
+		if got := CompareCanonicalKeys(p[1], p[0]); got != 1 {
+			t.Errorf("CompareCanonicalKeys(%+q, %+q) = %d, want 1", p[1], p[0], got)
+		}
+	}
+	if got := CompareCanonicalKeys("\u00e4", "\u00e4"); got != 0 {
+		t.Errorf("equal keys compared %d", got)
+	}
+}
+
+// TestKeyOrderIgnoresLocale canonicalizes under locales whose collation
+// would reorder the keys and expects code point order every time.
+func TestKeyOrderIgnoresLocale(t *testing.T) {
+	v := map[string]interface{}{
+		"\u0131": 1, "i": 1, "\u0130": 1, "I": 1,
+		"\u00e4": 1, "b": 1, "\u00c4": 1, "a": 1, "\U0001f600": 1, "\uff61": 1,
+	}
+	const want = "{\"I\":1,\"a\":1,\"b\":1,\"i\":1,\"\u00c4\":1,\"\u00e4\":1,\"\u0130\":1,\"\u0131\":1,\"\uff61\":1,\"\U0001f600\":1}"
+	for _, locale := range []string{"C", "tr_TR.UTF-8", "de_DE.UTF-8", "sv_SE.UTF-8"} {
+		t.Run(locale, func(t *testing.T) {
+			t.Setenv("LC_ALL", locale)
+			t.Setenv("LANG", locale)
+			got, err := CanonicalizeValue(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSortRelationshipsUsesCodePointOrder(t *testing.T) {
+	rels := []map[string]interface{}{
+		{"key": "\U0001f600", "type": "t"},
+		{"key": "\uff61", "type": "t"},
+		{"key": "a", "type": "\u00e4"},
+		{"key": "a", "type": "b"},
+		{"key": "A", "type": "t"},
+	}
+	var got []string
+	for _, r := range SortRelationships(rels) {
+		got = append(got, r["key"].(string)+"/"+r["type"].(string))
+	}
+	want := []string{"A/t", "a/b", "a/\u00e4", "\uff61/t", "\U0001f600/t"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %+q, want %+q", got, want)
+	}
+}
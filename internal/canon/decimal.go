@@ -0,0 +1,84 @@
+package canon
+
+import (
+	"encoding/json"
+)
+
+// Decimal is an exact decimal number, such as an amount of money, carried
+// as a string. A float cannot hold 0.1 exactly, and the canonical form
+// has no floats (RULE-002): the convention for decimals is to write them
+// as strings, and a Decimal canonicalizes as the JSON string of its
+// digits, so "12.50" hashes exactly as the string "12.50" does.
+//
+// A Decimal must be in the one form ParseDecimal accepts; canonicalizing
+// one that is not fails with CANON_ERR_DECIMAL_INVALID. Trailing zeros
+// are kept and significant: "12.50" and "12.5" are different values to
+// the hash, as the scale of an amount usually is to its reader.
+type Decimal string
+
+// ParseDecimal checks that s is a decimal in canonical form: an optional
+// minus sign, an integer part without leading zeros, and optionally a
+// point followed by one or more digits. Exponents, a plus sign, a bare
+// point, and negative zero ("-0", "-0.00") are rejected.
+func ParseDecimal(s string) (Decimal, error) {
+	if !validDecimal(s) {
+		return "", &Error{Code: ErrCodeDecimalInvalid, Value: s}
+	}
+	return Decimal(s), nil
+}
+
+// DecimalFromNumber converts a JSON number as written, e.g. 12.50 in a
+// document decoded with UseNumber, to a Decimal without going through a
+// float, so ingest code can accept decimal fields RULE-002 would reject.
+func DecimalFromNumber(n json.Number) (Decimal, error) {
+	return ParseDecimal(n.String())
+}
+
+// String returns the decimal's digits.
+func (d Decimal) String() string {
+	return string(d)
+}
+
+func validDecimal(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	start := i
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	intDigits := i - start
+	if intDigits == 0 || intDigits > 1 && s[start] == '0' {
+		return false
+	}
+	zero := intDigits == 1 && s[start] == '0'
+	if i < len(s) {
+		if s[i] != '.' {
+			return false
+		}
+		i++
+		frac := i
+		for i < len(s) && isDigit(s[i]) {
+			if s[i] != '0' {
+				zero = false
+			}
+			i++
+		}
+		if i == frac || i != len(s) {
+			return false
+		}
+	}
+	return !(zero && s[0] == '-')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func canonicalizeDecimal(d Decimal) ([]byte, error) {
+	if !validDecimal(string(d)) {
+		return nil, &Error{Code: ErrCodeDecimalInvalid, Value: string(d)}
+	}
+	return canonicalizeString(string(d))
+}
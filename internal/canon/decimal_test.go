@@ -0,0 +1,52 @@
+package canon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for _, s := range []string{"0", "7", "-7", "12.50", "0.10", "-0.5", "123456789012345678901234567890.000000000000000000001"} {
+		if _, err := ParseDecimal(s); err != nil {
+			t.Errorf("ParseDecimal(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "-", ".5", "5.", "+5", "05", "-05", "1e3", "1.5E2", "-0", "-0.00", "1.2.3", " 1", "1,5", "\u0661"} {
+		_, err := ParseDecimal(s)
+		if ErrorCode(err) != ErrCodeDecimalInvalid {
+			t.Errorf("ParseDecimal(%q) = %v, want %s", s, err, ErrCodeDecimalInvalid)
+		}
+	}
+}
+
+func TestDecimalCanonicalizesAsString(t *testing.T) {
+	d, err := DecimalFromNumber(json.Number("12.50"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := CanonicalizeValue(map[string]interface{}{"price": d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := CanonicalizeValue(map[string]interface{}{"price": "12.50"})
+	if string(got) != string(want) || string(got) != `{"price":"12.50"}` {
+		t.Errorf("Decimal canonicalized as %s, want %s", got, want)
+	}
+	if n, err := CanonicalSize(map[string]interface{}{"price": d}); err != nil || n != int64(len(got)) {
+		t.Errorf("CanonicalSize = %d, %v; want %d", n, err, len(got))
+	}
+	if err := ValidateIngestValue(map[string]interface{}{"price": d}); err != nil {
+		t.Errorf("ValidateIngestValue: %v", err)
+	}
+
+	bad := map[string]interface{}{"price": Decimal("1e2")}
+	if _, err := CanonicalizeValue(bad); ErrorCode(err) != ErrCodeDecimalInvalid {
+		t.Errorf("CanonicalizeValue of an invalid Decimal: %v", err)
+	}
+	if _, err := CanonicalSize(bad); ErrorCode(err) != ErrCodeDecimalInvalid {
+		t.Errorf("CanonicalSize of an invalid Decimal: %v", err)
+	}
+	if err := ValidateIngestValue(bad); ErrorCode(err) != ErrCodeDecimalInvalid {
+		t.Errorf("ValidateIngestValue of an invalid Decimal: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package canon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error codes raised by canonicalization and ingest validation.
+// Codes are stable identifiers shared with the other implementations;
+// human-readable messages are generated from them and may change.
+const (
+	ErrCodeNullProhibited            = "CANON_ERR_NULL_PROHIBITED"
+	ErrCodeFloatProhibited           = "CANON_ERR_FLOAT_PROHIBITED"
+	ErrCodeIntegerOutOfRange         = "CANON_ERR_INTEGER_OUT_OF_RANGE"
+	ErrCodeSchemaVersionMissing      = "CANON_ERR_SCHEMA_VERSION_MISSING"
+	ErrCodeSchemaVersionInvalid      = "CANON_ERR_SCHEMA_VERSION_INVALID"
+	ErrCodeTimestampNonUTC           = "CANON_ERR_TIMESTAMP_NON_UTC"
+	ErrCodeTimestampInvalidPrecision = "CANON_ERR_TIMESTAMP_INVALID_PRECISION"
+	ErrCodeTimestampInvalidFormat    = "CANON_ERR_TIMESTAMP_INVALID_FORMAT"
+	ErrCodeUnsupportedType           = "CANON_ERR_UNSUPPORTED_TYPE"
+	ErrCodeKeyCharacter              = "CANON_ERR_KEY_CHARACTER_PROHIBITED"
+	ErrCodeTooLarge                  = "CANON_ERR_TOO_LARGE"
+	ErrCodeDecimalInvalid            = "CANON_ERR_DECIMAL_INVALID"
+	ErrCodeBytesInvalid              = "CANON_ERR_BYTES_INVALID"
+	ErrCodeKeyReserved               = "CANON_ERR_KEY_RESERVED"
+	ErrCodeRelationshipAttribute     = "CANON_ERR_RELATIONSHIP_ATTRIBUTE_INVALID"
+)
+
+var errorMessages = map[string]string{
+	ErrCodeNullProhibited:            "null values are not permitted",
+	ErrCodeFloatProhibited:           "float values are not permitted",
+	ErrCodeIntegerOutOfRange:         "integer exceeds signed 64-bit bounds",
+	ErrCodeSchemaVersionMissing:      "_helios_schema_version field is required",
+	ErrCodeSchemaVersionInvalid:      `_helios_schema_version must be string "1"`,
+	ErrCodeTimestampNonUTC:           "timestamp must end in Z",
+	ErrCodeTimestampInvalidPrecision: "timestamp must have exactly 3 fractional digits",
+	ErrCodeTimestampInvalidFormat:    "timestamp must match YYYY-MM-DDTHH:MM:SS.sssZ",
+	ErrCodeUnsupportedType:           "unsupported value type",
+	ErrCodeKeyCharacter:              "map key contains a character the key policy prohibits",
+	ErrCodeTooLarge:                  "canonical form exceeds the size limit",
+	ErrCodeDecimalInvalid:            "decimal is not in canonical form",
+	ErrCodeBytesInvalid:              "binary value is not unpadded base64url",
+	ErrCodeKeyReserved:               "map key uses a reserved prefix",
+	ErrCodeRelationshipAttribute:     "relationship attribute is invalid",
+}
+
+// Error is a structured canonicalization or ingest error. The code, the
+// path of the offending value, and the value itself are separate fields so
+// servers can return codes while the CLI renders helpful context.
+type Error struct {
+	Code   string
+	Path   string
+	Value  interface{}
+	Reason string // optional detail, e.g. "got 4 digits"
+	Err    error  // optional underlying cause
+}
+
+// Error returns the terse single-line form "CODE: message".
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message()
+}
+
+// Message returns the human-readable message generated from the code
+// and structured fields. It does not include the code itself.
+func (e *Error) Message() string {
+	msg, ok := errorMessages[e.Code]
+	if !ok {
+		msg = "canonicalization error"
+	}
+	if e.Reason != "" {
+		msg += " (" + e.Reason + ")"
+	}
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	if e.Value != nil {
+		msg += ", got " + excerpt(e.Value, 60)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code of the first *Error in err's chain, or "".
+func ErrorCode(err error) string {
+	var ce *Error
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+	return ""
+}
+
+// ErrorStyle selects how FormatError renders an error.
+type ErrorStyle int
+
+const (
+	// Terse renders a single line, identical to err.Error().
+	Terse ErrorStyle = iota
+	// Verbose renders several lines with the code, path, and an excerpt
+	// of the offending input value.
+	Verbose
+)
+
+// FormatError renders err in the requested style. Errors that do not
+// wrap an *Error are rendered as err.Error() in both styles.
+func FormatError(err error, style ErrorStyle) string {
+	var ce *Error
+	if style == Terse || !errors.As(err, &ce) {
+		return err.Error()
+	}
+
+	var b strings.Builder
+	b.WriteString(err.Error())
+	fmt.Fprintf(&b, "\n  code:    %s", ce.Code)
+	fmt.Fprintf(&b, "\n  message: %s", errorMessages[ce.Code])
+	if ce.Reason != "" {
+		fmt.Fprintf(&b, "\n  detail:  %s", ce.Reason)
+	}
+	if ce.Path != "" {
+		fmt.Fprintf(&b, "\n  path:    %s", ce.Path)
+	}
+	if ce.Value != nil {
+		fmt.Fprintf(&b, "\n  input:   %s", excerpt(ce.Value, 200))
+	}
+	return b.String()
+}
+
+// excerpt renders v as compact JSON, truncated to at most n bytes of
+// output on a rune boundary.
+func excerpt(v interface{}, n int) string {
+	var s string
+	switch val := v.(type) {
+	case json.Number:
+		s = val.String()
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			s = fmt.Sprintf("%v", val)
+		} else {
+			s = string(b)
+		}
+	}
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && (s[cut]&0xC0) == 0x80 {
+		cut--
+	}
+	return s[:cut] + "…"
+}
@@ -0,0 +1,78 @@
+package canon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIngestErrorIsStructured(t *testing.T) {
+	value := map[string]interface{}{
+		"items": []interface{}{json.Number("1"), json.Number("2.5")},
+	}
+	err := ValidateIngestValue(value)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	ce, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	if ce.Code != ErrCodeFloatProhibited {
+		t.Errorf("expected code %s, got %s", ErrCodeFloatProhibited, ce.Code)
+	}
+	if ce.Path != "value.items[1]" {
+		t.Errorf("expected path value.items[1], got %q", ce.Path)
+	}
+	if ce.Value != json.Number("2.5") {
+		t.Errorf("expected offending value 2.5, got %v", ce.Value)
+	}
+	if !strings.HasPrefix(err.Error(), "CANON_ERR_FLOAT_PROHIBITED: ") {
+		t.Errorf("terse form must start with the code: %s", err)
+	}
+}
+
+func TestErrorCodeThroughWrapping(t *testing.T) {
+	_, err := NormalizeTimestamp("2025-01-15T10:30:00.1234Z")
+	wrapped := fmt.Errorf("timestamp normalization failed: %w", err)
+	if got := ErrorCode(wrapped); got != ErrCodeTimestampInvalidPrecision {
+		t.Errorf("expected %s, got %q", ErrCodeTimestampInvalidPrecision, got)
+	}
+	if got := ErrorCode(fmt.Errorf("plain")); got != "" {
+		t.Errorf("expected empty code for plain error, got %q", got)
+	}
+}
+
+func TestFormatErrorStyles(t *testing.T) {
+	err := &Error{Code: ErrCodeNullProhibited, Path: "value.a"}
+
+	terse := FormatError(err, Terse)
+	if strings.Contains(terse, "\n") || terse != err.Error() {
+		t.Errorf("terse form should equal Error(): %q", terse)
+	}
+
+	verbose := FormatError(err, Verbose)
+	for _, want := range []string{"code:    CANON_ERR_NULL_PROHIBITED", "path:    value.a"} {
+		if !strings.Contains(verbose, want) {
+			t.Errorf("verbose form missing %q:\n%s", want, verbose)
+		}
+	}
+
+	plain := fmt.Errorf("not structured")
+	if FormatError(plain, Verbose) != "not structured" {
+		t.Error("non-structured errors should render as Error()")
+	}
+}
+
+func TestErrorExcerptTruncates(t *testing.T) {
+	long := strings.Repeat("日", 100)
+	err := &Error{Code: ErrCodeTimestampNonUTC, Value: long}
+	msg := err.Message()
+	if len(msg) > 120 {
+		t.Errorf("excerpt should be truncated, message is %d bytes", len(msg))
+	}
+	if !strings.HasSuffix(msg, "…") {
+		t.Errorf("truncated excerpt should end with an ellipsis: %s", msg)
+	}
+}
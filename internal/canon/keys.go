@@ -0,0 +1,90 @@
+package canon
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeyPolicy decides which characters map keys inside a value may contain
+// (RULE-012). Canonicalizers in other languages disagree on keys with
+// control characters or unpaired surrogate escapes: some refuse them,
+// some drop them, some keep a lone surrogate their UTF-8 encoder then
+// rejects. A profile names the policy its hashes were computed under.
+type KeyPolicy string
+
+const (
+	// KeyPolicyPermissive, the default and the behavior of version 1,
+	// accepts every key. C0 control characters are escaped as in any
+	// string (Section 3.5) and DEL and C1 controls are written raw; an
+	// unpaired surrogate escape such as "\ud800", or a byte sequence that
+	// is not UTF-8, decodes to U+FFFD REPLACEMENT CHARACTER before the
+	// key is sorted.
+	KeyPolicyPermissive KeyPolicy = ""
+	// KeyPolicyStrict rejects keys containing a control character (general
+	// category Cc: U+0000 to U+001F and U+007F to U+009F) or U+FFFD, which
+	// is all that is left of an unpaired surrogate or invalid UTF-8 once
+	// decoded, with CANON_ERR_KEY_CHARACTER_PROHIBITED.
+	KeyPolicyStrict KeyPolicy = "strict"
+)
+
+// ParseKeyPolicy parses a policy name; "permissive" and "" both name
+// KeyPolicyPermissive.
+func ParseKeyPolicy(name string) (KeyPolicy, error) {
+	switch name {
+	case "", "permissive":
+		return KeyPolicyPermissive, nil
+	case string(KeyPolicyStrict):
+		return KeyPolicyStrict, nil
+	}
+	return "", fmt.Errorf("unknown key policy %q (want permissive or strict)", name)
+}
+
+// String returns the policy's name.
+func (p KeyPolicy) String() string {
+	if p == KeyPolicyPermissive {
+		return "permissive"
+	}
+	return string(p)
+}
+
+// ValidateKeys checks every map key in v, a value as decoded by ingest,
+// against policy. Errors carry the path of the offending key.
+func ValidateKeys(v interface{}, policy KeyPolicy) error {
+	if policy == KeyPolicyPermissive {
+		return nil
+	}
+	return validateKeys(v, "value")
+}
+
+func validateKeys(v interface{}, path string) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if r, ok := prohibitedKeyRune(k); ok {
+				return &Error{Code: ErrCodeKeyCharacter, Path: path, Value: k, Reason: fmt.Sprintf("%U", r)}
+			}
+			if err := validateKeys(child, path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range val {
+			if err := validateKeys(child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// prohibitedKeyRune returns the first character of k the strict policy
+// prohibits.
+func prohibitedKeyRune(k string) (rune, bool) {
+	for _, r := range k {
+		if r == utf8.RuneError || unicode.Is(unicode.Cc, r) {
+			return r, true
+		}
+	}
+	return 0, false
+}
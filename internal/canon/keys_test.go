@@ -0,0 +1,54 @@
+package canon
+
+import "testing"
+
+// RULE-012: the strict policy rejects control characters and U+FFFD in keys.
+func TestValidateKeys(t *testing.T) {
+	ok := []interface{}{
+		"\u0001 in a string value",
+		map[string]interface{}{"": int64(1), "\u00e9t\u00e9": int64(2), "\U0001f600": int64(3), "a\u200bb": int64(4)},
+		[]interface{}{map[string]interface{}{"a": map[string]interface{}{"b": true}}},
+	}
+	for _, v := range ok {
+		if err := ValidateKeys(v, KeyPolicyStrict); err != nil {
+			t.Errorf("ValidateKeys(%+q) = %v", v, err)
+		}
+	}
+
+	bad := []struct {
+		value interface{}
+		path  string
+	}{
+		{map[string]interface{}{"\u0000": int64(1)}, "value"},
+		{map[string]interface{}{"a\tb": int64(1)}, "value"},
+		{map[string]interface{}{"\u007f": int64(1)}, "value"},
+		{map[string]interface{}{"x\u0085": int64(1)}, "value"},
+		{map[string]interface{}{"\ufffd": int64(1)}, "value"},
+		{map[string]interface{}{"a": map[string]interface{}{"\u009f": int64(1)}}, "value.a"},
+		{[]interface{}{int64(1), map[string]interface{}{"\u001f": int64(1)}}, "value[1]"},
+	}
+	for _, c := range bad {
+		err := ValidateKeys(c.value, KeyPolicyStrict)
+		e, ok := err.(*Error)
+		if !ok || e.Code != ErrCodeKeyCharacter || e.Path != c.path {
+			t.Errorf("ValidateKeys(%+q) = %v, want %s at %s", c.value, err, ErrCodeKeyCharacter, c.path)
+		}
+		if err := ValidateKeys(c.value, KeyPolicyPermissive); err != nil {
+			t.Errorf("permissive ValidateKeys(%+q) = %v", c.value, err)
+		}
+	}
+}
+
+func TestParseKeyPolicy(t *testing.T) {
+	for name, want := range map[string]KeyPolicy{"": KeyPolicyPermissive, "permissive": KeyPolicyPermissive, "strict": KeyPolicyStrict} {
+		if got, err := ParseKeyPolicy(name); err != nil || got != want {
+			t.Errorf("ParseKeyPolicy(%q) = %q, %v", name, got, err)
+		}
+		if got, _ := ParseKeyPolicy(want.String()); got != want {
+			t.Errorf("%q does not round trip through String", want)
+		}
+	}
+	if _, err := ParseKeyPolicy("lenient"); err == nil {
+		t.Error("ParseKeyPolicy accepted an unknown policy")
+	}
+}
@@ -0,0 +1,110 @@
+package canon
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Mutant names a deliberate fault in the canonicalizer, each a mistake a
+// reimplementation of the spec could plausibly make. The mutation tests
+// switch them on one at a time and require the frozen vectors to catch
+// every one: a mutant that leaves every vector passing marks behavior
+// the corpus does not pin down. Mutants only take effect in builds with
+// -tags mutation; in every other build the canonicalizer cannot be
+// perturbed and the hooks compile away.
+type Mutant string
+
+const (
+	MutantNone Mutant = ""
+
+	// Key order (Section 3.1, RULE-004, RULE-008).
+	MutantReverseKeyOrder    Mutant = "reverse-key-order"
+	MutantUTF16KeyOrder      Mutant = "utf16-key-order"
+	MutantFoldCaseKeyOrder   Mutant = "fold-case-key-order"
+	MutantUnsortedRelations  Mutant = "unsorted-relationships"
+	MutantIgnoreRelationType Mutant = "ignore-relationship-type"
+
+	// Normalization (Section 4, RULE-003).
+	MutantSkipNFC Mutant = "skip-nfc"
+	MutantNFD     Mutant = "nfd"
+
+	// String escaping (Section 3.5, RULE-006).
+	MutantEscapeNonASCII    Mutant = "escape-non-ascii"
+	MutantEscapeHTML        Mutant = "escape-html"
+	MutantEscapeSolidus     Mutant = "escape-solidus"
+	MutantEscapeDEL         Mutant = "escape-del"
+	MutantLongControlEscape Mutant = "long-control-escapes"
+	MutantUpperHexEscape    Mutant = "upper-hex-escapes"
+)
+
+// Mutants returns every mutant, in the order the mutation tests run them.
+func Mutants() []Mutant {
+	return []Mutant{
+		MutantReverseKeyOrder, MutantUTF16KeyOrder, MutantFoldCaseKeyOrder,
+		MutantUnsortedRelations, MutantIgnoreRelationType,
+		MutantSkipNFC, MutantNFD,
+		MutantEscapeNonASCII, MutantEscapeHTML, MutantEscapeSolidus,
+		MutantEscapeDEL, MutantLongControlEscape, MutantUpperHexEscape,
+	}
+}
+
+// mutantCompare is CompareCanonicalKeys under the active key order mutant.
+func mutantCompare(a, b string) int {
+	switch mutant {
+	case MutantReverseKeyOrder:
+		return strings.Compare(b, a)
+	case MutantUTF16KeyOrder:
+		ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+		for i := 0; i < len(ua) && i < len(ub); i++ {
+			if ua[i] != ub[i] {
+				if ua[i] < ub[i] {
+					return -1
+				}
+				return 1
+			}
+		}
+		return len(ua) - len(ub)
+	case MutantFoldCaseKeyOrder:
+		if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// mutantEscape returns the escape the active escaping mutant writes for
+// r, if it changes how r is written.
+func mutantEscape(r rune) (string, bool) {
+	switch mutant {
+	case MutantEscapeNonASCII:
+		if r >= 0x80 {
+			if r > 0xffff {
+				hi, lo := utf16.EncodeRune(r)
+				return fmt.Sprintf(`\u%04x\u%04x`, hi, lo), true
+			}
+			return fmt.Sprintf(`\u%04x`, r), true
+		}
+	case MutantEscapeHTML:
+		if r == '<' || r == '>' || r == '&' {
+			return fmt.Sprintf(`\u%04x`, r), true
+		}
+	case MutantEscapeSolidus:
+		if r == '/' {
+			return `\/`, true
+		}
+	case MutantEscapeDEL:
+		if r == 0x7f {
+			return `\u007f`, true
+		}
+	case MutantLongControlEscape:
+		if r < 0x20 {
+			return fmt.Sprintf(`\u%04x`, r), true
+		}
+	case MutantUpperHexEscape:
+		if r < 0x20 && !strings.ContainsRune("\b\f\n\r\t", r) {
+			return fmt.Sprintf(`\u%04X`, r), true
+		}
+	}
+	return "", false
+}
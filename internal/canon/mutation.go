@@ -0,0 +1,14 @@
+//go:build mutation
+
+package canon
+
+// mutant is the active mutant; see Mutant.
+var mutant Mutant
+
+// SetMutant activates m until the returned function restores the previous
+// mutant. It is not safe to call while canonicalizing concurrently.
+func SetMutant(m Mutant) (restore func()) {
+	prev := mutant
+	mutant = m
+	return func() { mutant = prev }
+}
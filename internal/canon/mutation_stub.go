@@ -0,0 +1,7 @@
+//go:build !mutation
+
+package canon
+
+// mutant is fixed outside mutation builds, so the compiler removes the
+// mutation hooks; see Mutant.
+const mutant = MutantNone
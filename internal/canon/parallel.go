@@ -0,0 +1,149 @@
+package canon
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultParallelThreshold is the element count from which
+// CanonicalizeValueParallel splits an array or map by default. Below it
+// the goroutines cost more than they save.
+const DefaultParallelThreshold = 4096
+
+// ParallelOptions configures CanonicalizeValueParallel.
+type ParallelOptions struct {
+	// Threshold is the number of elements from which an array's or map's
+	// elements are serialized concurrently. Zero means
+	// DefaultParallelThreshold.
+	Threshold int
+	// Workers bounds the goroutines serializing one array or map. Zero
+	// means GOMAXPROCS.
+	Workers int
+}
+
+// CanonicalizeValueParallel returns CanonicalizeValue(v), serializing the
+// elements of arrays and maps of at least opts.Threshold elements on
+// several goroutines and joining their output in order. Smaller
+// containers are serialized on the calling goroutine, and so are the
+// elements of a split container, so the goroutines in flight stay within
+// opts.Workers. The output and, for an invalid value, the error are the
+// ones CanonicalizeValue returns: the error of the first invalid element.
+func CanonicalizeValueParallel(v interface{}, opts ParallelOptions) ([]byte, error) {
+	par := &parallelism{threshold: opts.Threshold, workers: opts.Workers}
+	if par.threshold <= 0 {
+		par.threshold = DefaultParallelThreshold
+	}
+	if par.workers <= 0 {
+		par.workers = runtime.GOMAXPROCS(0)
+	}
+	if par.workers == 1 {
+		return canonicalizeValue(v)
+	}
+	return encodeValue(v, par)
+}
+
+// parallelism is the resolved ParallelOptions; nil serializes everything
+// on the calling goroutine.
+type parallelism struct {
+	threshold int
+	workers   int
+}
+
+// chunksPerWorker splits a container into more chunks than workers, so a
+// worker that drew cheap elements takes another chunk instead of idling.
+const chunksPerWorker = 4
+
+func (p *parallelism) splits(n int) bool {
+	return p != nil && n >= p.threshold && n > 1
+}
+
+// encode serializes the n elements of an array or map between open and
+// end, writing element i with elem. The elements are cut into
+// contiguous chunks that workers serialize into buffers of their own,
+// which are joined in order. A chunk stops at its first invalid element,
+// and a chunk after one that failed is abandoned; the lowest failed
+// chunk's error is the first invalid element's.
+func (p *parallelism) encode(open, end byte, n int, elem func(i int, buf *bytes.Buffer) error) ([]byte, error) {
+	chunks := min(p.workers*chunksPerWorker, n)
+	size := (n + chunks - 1) / chunks
+	chunks = (n + size - 1) / size
+	bufs := make([]bytes.Buffer, chunks)
+	errs := make([]error, chunks)
+	var next atomic.Int64
+	var failed atomic.Int64
+	failed.Store(int64(chunks))
+
+	var wg sync.WaitGroup
+	for range min(p.workers, chunks) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c := int(next.Add(1) - 1)
+				if c >= chunks {
+					return
+				}
+				if int64(c) > failed.Load() {
+					continue
+				}
+				buf := &bufs[c]
+				for i := c * size; i < min((c+1)*size, n); i++ {
+					if i > c*size {
+						buf.WriteByte(',')
+					}
+					if err := elem(i, buf); err != nil {
+						errs[c] = err
+						lowerTo(&failed, int64(c))
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if f := failed.Load(); f < int64(chunks) {
+		return nil, errs[f]
+	}
+
+	total := 2 + chunks - 1
+	for i := range bufs {
+		total += bufs[i].Len()
+	}
+	out := make([]byte, 0, total)
+	out = append(out, open)
+	for i := range bufs {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, bufs[i].Bytes()...)
+	}
+	return append(out, end), nil
+}
+
+// lowerTo sets v to n unless it is already lower.
+func lowerTo(v *atomic.Int64, n int64) {
+	for {
+		cur := v.Load()
+		if cur <= n || v.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
+
+// encodeMember writes a map member, "key":value, as canonicalizeMap does.
+func encodeMember(buf *bytes.Buffer, k string, v interface{}) error {
+	keyBytes, err := canonicalizeString(k)
+	if err != nil {
+		return err
+	}
+	buf.Write(keyBytes)
+	buf.WriteByte(':')
+	valBytes, err := canonicalizeValue(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(valBytes)
+	return nil
+}
@@ -0,0 +1,103 @@
+package canon
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomTree builds a value with wide arrays and maps nested in narrow
+// ones, the shape CanonicalizeValueParallel splits.
+func randomTree(r *rand.Rand, depth int) interface{} {
+	if depth == 0 {
+		switch r.Intn(4) {
+		case 0:
+			return r.Int63n(1000)
+		case 1:
+			return r.Intn(2) == 0
+		case 2:
+			return fmt.Sprintf("s%d\t\u00e9", r.Intn(100))
+		default:
+			return Decimal("1.5")
+		}
+	}
+	n := 1 + r.Intn(3)
+	if depth <= 2 && r.Intn(3) == 0 {
+		n = 20 + r.Intn(40)
+	}
+	if r.Intn(2) == 0 {
+		arr := make([]interface{}, n)
+		for i := range arr {
+			arr[i] = randomTree(r, depth-1)
+		}
+		return arr
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		m[fmt.Sprintf("k%d", r.Intn(1000))] = randomTree(r, depth-1)
+	}
+	return m
+}
+
+func TestCanonicalizeValueParallel(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		v := randomTree(r, 4)
+		want, err := CanonicalizeValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, opts := range []ParallelOptions{{}, {Threshold: 2, Workers: 3}, {Threshold: 30, Workers: 8}, {Workers: 1}} {
+			got, err := CanonicalizeValueParallel(v, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Fatalf("tree %d with %+v:\n got %s\nwant %s", i, opts, got, want)
+			}
+		}
+	}
+}
+
+func TestCanonicalizeValueParallelError(t *testing.T) {
+	arr := make([]interface{}, 1000)
+	for i := range arr {
+		arr[i] = map[string]interface{}{"i": i}
+	}
+	// The first invalid element wins, wherever the chunks fall.
+	arr[317] = map[string]interface{}{"i": nil}
+	arr[318] = 1.5
+	arr[900] = struct{}{}
+	v := map[string]interface{}{"items": arr}
+	_, want := CanonicalizeValue(v)
+	if ErrorCode(want) != ErrCodeNullProhibited {
+		t.Fatalf("sequential error = %v", want)
+	}
+	for _, opts := range []ParallelOptions{{Threshold: 2, Workers: 4}, {Threshold: 2, Workers: 64}} {
+		for i := 0; i < 20; i++ {
+			if _, err := CanonicalizeValueParallel(v, opts); err == nil || err.Error() != want.Error() {
+				t.Fatalf("%+v: error = %v, want %v", opts, err, want)
+			}
+		}
+	}
+}
+
+func BenchmarkCanonicalizeValueParallel(b *testing.B) {
+	rels := make([]interface{}, 200000)
+	for i := range rels {
+		rels[i] = map[string]interface{}{"key": fmt.Sprintf("memory/%d", i), "type": "cites", "weight": int64(i % 7)}
+	}
+	v := map[string]interface{}{"key": "hub", "relationships": rels}
+	for _, tc := range []struct {
+		name string
+		opts ParallelOptions
+	}{{"sequential", ParallelOptions{Workers: 1}}, {"parallel", ParallelOptions{}}} {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := CanonicalizeValueParallel(v, tc.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
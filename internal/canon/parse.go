@@ -0,0 +1,113 @@
+package canon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrNotCanonical is wrapped by the errors of VerifyRoundTrip.
+var ErrNotCanonical = errors.New("not canonical")
+
+// RoundTripError reports bytes that do not re-canonicalize to themselves.
+type RoundTripError struct {
+	// Offset is the first byte at which the input and its
+	// re-canonicalization differ.
+	Offset int
+	// Have and Want are excerpts of the input and of its canonical form
+	// from Offset.
+	Have, Want string
+}
+
+func (e *RoundTripError) Error() string {
+	return fmt.Sprintf("not canonical at byte %d: have %q, canonical form has %q", e.Offset, e.Have, e.Want)
+}
+
+func (e *RoundTripError) Unwrap() error { return ErrNotCanonical }
+
+// ParseCanonical decodes canonical bytes back into the value tree
+// CanonicalizeValue serializes: map[string]interface{}, []interface{},
+// string, int64, and bool. The canonical form has only integers, so a
+// number that is not a signed 64-bit integer fails with the same codes
+// ingest validation uses. ParseCanonical does not itself check that data
+// is canonical; see VerifyRoundTrip.
+func ParseCanonical(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the canonical value")
+	}
+	return toCanonicalTree(v, "$")
+}
+
+// toCanonicalTree replaces the json.Numbers of v with int64s.
+func toCanonicalTree(v interface{}, path string) (interface{}, error) {
+	switch val := v.(type) {
+	case json.Number:
+		n, err := strconv.ParseInt(val.String(), 10, 64)
+		if err == nil {
+			return n, nil
+		}
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, &Error{Code: ErrCodeIntegerOutOfRange, Path: path, Value: val.String()}
+		}
+		return nil, &Error{Code: ErrCodeFloatProhibited, Path: path, Value: val.String()}
+	case map[string]interface{}:
+		for k, e := range val {
+			t, err := toCanonicalTree(e, path+"."+k)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = t
+		}
+	case []interface{}:
+		for i, e := range val {
+			t, err := toCanonicalTree(e, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			val[i] = t
+		}
+	}
+	return v, nil
+}
+
+// VerifyRoundTrip checks that data is exactly the canonical encoding of
+// the value it decodes to: ParseCanonical, then CanonicalizeValue, must
+// reproduce data byte for byte. Hash comparison alone cannot see an
+// encoder that writes a form its own decoder reads back differently,
+// such as an escape the decoder does not undo or invalid UTF-8 it
+// replaces; such bytes fail here with a *RoundTripError.
+func VerifyRoundTrip(data []byte) error {
+	v, err := ParseCanonical(data)
+	if err != nil {
+		return err
+	}
+	again, err := CanonicalizeValue(v)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, again) {
+		return nil
+	}
+	i := 0
+	for i < len(data) && i < len(again) && data[i] == again[i] {
+		i++
+	}
+	return &RoundTripError{Offset: i, Have: excerptAt(data, i), Want: excerptAt(again, i)}
+}
+
+func excerptAt(b []byte, i int) string {
+	const n = 24
+	if i+n < len(b) {
+		return string(b[i:i+n]) + "..."
+	}
+	return string(b[i:])
+}
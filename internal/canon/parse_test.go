@@ -0,0 +1,88 @@
+package canon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCanonicalRoundTrip(t *testing.T) {
+	for _, v := range []interface{}{
+		"",
+		"plain",
+		"quote \" backslash \\ slash / controls \b\f\n\r\t \x00\x1f \x7f",
+		"\u00e9 \u2028 \u2029 \ufeff \U0001f600",
+		int64(0),
+		int64(-9223372036854775808),
+		int64(9223372036854775807),
+		true,
+		[]interface{}{},
+		map[string]interface{}{},
+		map[string]interface{}{
+			"b": []interface{}{int64(1), "x", false, map[string]interface{}{"\u00e4": "y"}},
+			"a": map[string]interface{}{"": int64(-1)},
+		},
+	} {
+		data, err := CanonicalizeValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ParseCanonical(data)
+		if err != nil {
+			t.Errorf("ParseCanonical(%s): %v", data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("ParseCanonical(%s) = %#v, want %#v", data, got, v)
+		}
+		if err := VerifyRoundTrip(data); err != nil {
+			t.Errorf("VerifyRoundTrip(%s): %v", data, err)
+		}
+	}
+}
+
+func TestVerifyRoundTripRejects(t *testing.T) {
+	// Invalid UTF-8 passes through the encoder but decodes to U+FFFD.
+	invalid, err := CanonicalizeValue(map[string]interface{}{"k": "a\xffb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{
+		`{"a":1, "b":2}`,
+		`{"b":2,"a":1}`,
+		`{"a":1,"a":2}`,
+		`"\/"`,
+		`"\u0041"`,
+		`"\u001F"`,
+		`"\u00e9"`,
+		`-0`,
+		`[1 ]`,
+		"\"\\n\"\n",
+		string(invalid),
+	} {
+		if err := VerifyRoundTrip([]byte(data)); !errors.Is(err, ErrNotCanonical) {
+			t.Errorf("VerifyRoundTrip(%q) = %v, want ErrNotCanonical", data, err)
+		}
+	}
+
+	var rt *RoundTripError
+	if err := VerifyRoundTrip([]byte(`{"b":2,"a":1}`)); !errors.As(err, &rt) || rt.Offset != 2 {
+		t.Errorf("error = %v, want a *RoundTripError at byte 2", err)
+	}
+
+	for data, code := range map[string]string{
+		`1.0`:                 ErrCodeFloatProhibited,
+		`1e2`:                 ErrCodeFloatProhibited,
+		`9223372036854775808`: ErrCodeIntegerOutOfRange,
+		`{"a":[null]}`:        ErrCodeNullProhibited,
+	} {
+		if err := VerifyRoundTrip([]byte(data)); ErrorCode(err) != code {
+			t.Errorf("VerifyRoundTrip(%s) = %v, want %s", data, err, code)
+		}
+	}
+	for _, data := range []string{``, `{`, `1 2`, `"a"x`} {
+		if _, err := ParseCanonical([]byte(data)); err == nil {
+			t.Errorf("ParseCanonical(%q) succeeded", data)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package canon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathSegment is one step of a value path: a map key or an array index.
+type PathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// ParsePath parses a JSONPath-style expression rooted at "$", e.g.
+// "$.value.config", "$.value.items[2]", or `$.value["key.with.dots"]`.
+// Only member and index selectors are supported; no wildcards or filters.
+func ParsePath(path string) ([]PathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	var segs []PathSegment
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q: empty member name", path)
+			}
+			segs = append(segs, PathSegment{Key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("path %q: unterminated [", path)
+			}
+			inner := rest[1:end]
+			if strings.HasPrefix(inner, `"`) {
+				// Quoted member names use JSON string syntax; find the
+				// closing quote, which may be followed by "]" later on.
+				var key string
+				dec := json.NewDecoder(strings.NewReader(rest[1:]))
+				if err := dec.Decode(&key); err != nil {
+					return nil, fmt.Errorf("path %q: invalid quoted member: %w", path, err)
+				}
+				consumed := 1 + int(dec.InputOffset())
+				if consumed >= len(rest) || rest[consumed] != ']' {
+					return nil, fmt.Errorf("path %q: expected ] after quoted member", path)
+				}
+				segs = append(segs, PathSegment{Key: key})
+				rest = rest[consumed+1:]
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("path %q: invalid array index %q", path, inner)
+			}
+			segs = append(segs, PathSegment{Index: n, IsIndex: true})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return segs, nil
+}
+
+// Lookup resolves path against v and returns the selected sub-value.
+func Lookup(v interface{}, path string) (interface{}, error) {
+	segs, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := v
+	where := "$"
+	for _, seg := range segs {
+		if seg.IsIndex {
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %s: %s is not an array", path, where)
+			}
+			if seg.Index >= len(arr) {
+				return nil, fmt.Errorf("path %s: index %d out of range at %s (length %d)", path, seg.Index, where, len(arr))
+			}
+			cur = arr[seg.Index]
+			where = fmt.Sprintf("%s[%d]", where, seg.Index)
+			continue
+		}
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("path %s: %s is not an object", path, where)
+		}
+		child, exists := m[seg.Key]
+		if !exists {
+			return nil, fmt.Errorf("path %s: no member %q at %s", path, seg.Key, where)
+		}
+		cur = child
+		where += "." + seg.Key
+	}
+	return cur, nil
+}
@@ -0,0 +1,62 @@
+package canon
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []PathSegment
+	}{
+		{"$", nil},
+		{"$.value", []PathSegment{{Key: "value"}}},
+		{"$.value.items[2]", []PathSegment{{Key: "value"}, {Key: "items"}, {Index: 2, IsIndex: true}}},
+		{`$.value["a.b"]["c]"]`, []PathSegment{{Key: "value"}, {Key: "a.b"}, {Key: "c]"}}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePath(tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestParsePathRejectsMalformed(t *testing.T) {
+	for _, p := range []string{"value", "$.", "$[", "$[-1]", "$[x]", `$["a"`, "$..a"} {
+		if _, err := ParsePath(p); err == nil {
+			t.Errorf("expected error for %q", p)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	v := map[string]interface{}{
+		"value": map[string]interface{}{
+			"config": map[string]interface{}{"retries": json.Number("3")},
+			"items":  []interface{}{"a", "b"},
+		},
+	}
+	got, err := Lookup(v, "$.value.items[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "b" {
+		t.Errorf("expected b, got %v", got)
+	}
+	if _, err := Lookup(v, "$.value.items[5]"); err == nil {
+		t.Error("expected out-of-range error")
+	}
+	if _, err := Lookup(v, "$.value.missing"); err == nil {
+		t.Error("expected missing member error")
+	}
+	if _, err := Lookup(v, "$.value.config[0]"); err == nil {
+		t.Error("expected not-an-array error")
+	}
+}
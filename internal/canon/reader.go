@@ -0,0 +1,119 @@
+package canon
+
+import (
+	"io"
+)
+
+// CanonicalReader returns a reader of CanonicalizeValue(v) that produces
+// the bytes as they are read, so the canonical form of a large value can
+// be streamed to a file or a network upload without holding it in
+// memory. Only the keys of the maps being written and one scalar at a
+// time are buffered. v must not be modified until the reader is drained.
+//
+// A value CanonicalizeValue rejects fails the Read that reaches the
+// offending element, after the bytes before it have been returned; check
+// the value with CanonicalSize first if no bytes may be written for it.
+func CanonicalReader(v interface{}) io.Reader {
+	return &canonicalReader{stack: []frame{{value: v}}}
+}
+
+// A frame is a value being written: a scalar, or a map or array with the
+// index of its next element.
+type frame struct {
+	value interface{}
+	keys  []string
+	next  int
+	open  bool
+}
+
+type canonicalReader struct {
+	stack   []frame
+	pending []byte
+	err     error
+}
+
+func (r *canonicalReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) == 0 {
+			if r.err != nil {
+				break
+			}
+			r.err = r.advance()
+			continue
+		}
+		c := copy(p[n:], r.pending)
+		r.pending = r.pending[c:]
+		n += c
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, r.err
+}
+
+// advance appends the next piece of the canonical form to pending, and
+// returns io.EOF once the value is complete.
+func (r *canonicalReader) advance() error {
+	if len(r.stack) == 0 {
+		return io.EOF
+	}
+	top := &r.stack[len(r.stack)-1]
+	switch val := top.value.(type) {
+	case map[string]interface{}:
+		if !top.open {
+			top.open = true
+			top.keys = make([]string, 0, len(val))
+			for k := range val {
+				top.keys = append(top.keys, k)
+			}
+			sortKeys(top.keys)
+			r.pending = append(r.pending[:0], '{')
+			return nil
+		}
+		if top.next == len(top.keys) {
+			r.stack = r.stack[:len(r.stack)-1]
+			r.pending = append(r.pending[:0], '}')
+			return nil
+		}
+		k := top.keys[top.next]
+		r.pending = r.pending[:0]
+		if top.next > 0 {
+			r.pending = append(r.pending, ',')
+		}
+		key, err := canonicalizeString(k)
+		if err != nil {
+			return err
+		}
+		r.pending = append(append(r.pending, key...), ':')
+		top.next++
+		r.stack = append(r.stack, frame{value: val[k]})
+		return nil
+	case []interface{}:
+		if !top.open {
+			top.open = true
+			r.pending = append(r.pending[:0], '[')
+			return nil
+		}
+		if top.next == len(val) {
+			r.stack = r.stack[:len(r.stack)-1]
+			r.pending = append(r.pending[:0], ']')
+			return nil
+		}
+		r.pending = r.pending[:0]
+		if top.next > 0 {
+			r.pending = append(r.pending, ',')
+		}
+		top.next++
+		r.stack = append(r.stack, frame{value: val[top.next-1]})
+		return nil
+	default:
+		b, err := canonicalizeValue(val)
+		if err != nil {
+			return err
+		}
+		r.stack = r.stack[:len(r.stack)-1]
+		r.pending = b
+		return nil
+	}
+}
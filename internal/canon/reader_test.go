@@ -0,0 +1,52 @@
+package canon
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCanonicalReaderMatchesCanonicalizeValue(t *testing.T) {
+	for _, v := range []interface{}{
+		"plain",
+		int64(-42),
+		true,
+		json.Number("9223372036854775807"),
+		map[string]interface{}{},
+		[]interface{}{},
+		map[string]interface{}{"b": []interface{}{int64(1), "x\n\"", map[string]interface{}{"z": false, "a": []interface{}{}}}, "a": "caf\u00e9", "\u00e4": int64(0)},
+		[]interface{}{map[string]interface{}{"\U0001f600": "\u0001", "\uff61": int64(2)}, []interface{}{[]interface{}{"deep"}}},
+	} {
+		want, err := CanonicalizeValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(CanonicalReader(v))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("CanonicalReader(%v) = %s, %v; want %s", v, got, err, want)
+		}
+		if err := iotest.TestReader(CanonicalReader(v), want); err != nil {
+			t.Errorf("%v: %v", v, err)
+		}
+		got, err = io.ReadAll(iotest.OneByteReader(CanonicalReader(v)))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("CanonicalReader(%v) one byte at a time = %s, %v", v, got, err)
+		}
+	}
+}
+
+func TestCanonicalReaderFailsAtInvalidElement(t *testing.T) {
+	v := map[string]interface{}{"a": int64(1), "b": []interface{}{"x", nil}}
+	got, err := io.ReadAll(CanonicalReader(v))
+	if ErrorCode(err) != ErrCodeNullProhibited {
+		t.Fatalf("err = %v, want %s", err, ErrCodeNullProhibited)
+	}
+	if want := `{"a":1,"b":["x",`; string(got) != want {
+		t.Errorf("bytes before the error = %s, want %s", got, want)
+	}
+	if _, err := CanonicalReader(float32(1)).Read(make([]byte, 8)); ErrorCode(err) != ErrCodeUnsupportedType {
+		t.Errorf("unsupported type: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package canon
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Relationship attributes of schema version 2 (Section 8.3, RULE-015).
+// A relationship of version 2 may carry an integer weight and a string
+// note; both take part in the hash and break ties when key and type are
+// equal. Version 1 relationships hash as exactly key and type, whatever
+// else they hold.
+const (
+	RelationshipWeight = "weight"
+	RelationshipNote   = "note"
+)
+
+// RelationshipToMapV2 is RelationshipToMap with the optional attributes
+// of schema version 2; a nil attribute is left out.
+func RelationshipToMapV2(key, typ string, weight *int64, note *string) map[string]interface{} {
+	m := RelationshipToMap(key, typ)
+	if weight != nil {
+		m[RelationshipWeight] = *weight
+	}
+	if note != nil {
+		m[RelationshipNote] = *note
+	}
+	return m
+}
+
+// ValidateRelationships checks the relationships of a decoded object
+// that declares schema version 2: a relationship may have only key,
+// type, weight (an integer within signed 64-bit bounds), and note (a
+// string). Under version 1 every member but key and type, weight and
+// note included, is ignored and left out of the hash, as it always was.
+func ValidateRelationships(input map[string]interface{}) error {
+	if input["_helios_schema_version"] != "2" {
+		return nil
+	}
+	rels, _ := input["relationships"].([]interface{})
+	for i, r := range rels {
+		rm, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := fmt.Sprintf("relationships[%d]", i)
+		for name, v := range rm {
+			attrPath := path + "." + name
+			switch name {
+			case "key", "type":
+				continue
+			case RelationshipWeight, RelationshipNote:
+			default:
+				return &Error{Code: ErrCodeRelationshipAttribute, Path: attrPath, Reason: "unknown attribute"}
+			}
+			if err := validateRelationshipAttribute(name, v, attrPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateRelationshipAttribute(name string, v interface{}, path string) error {
+	if name == RelationshipNote {
+		if _, ok := v.(string); !ok {
+			return &Error{Code: ErrCodeRelationshipAttribute, Path: path, Value: v, Reason: fmt.Sprintf("%T, not a string", v)}
+		}
+		return nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		if v == nil {
+			return &Error{Code: ErrCodeNullProhibited, Path: path}
+		}
+		return &Error{Code: ErrCodeRelationshipAttribute, Path: path, Value: v, Reason: fmt.Sprintf("%T, not an integer", v)}
+	}
+	return validateIngest(n, path)
+}
+
+// compareRelationshipAttributes orders relationships with equal key and
+// type: by weight, absent first, then by note, absent first.
+func compareRelationshipAttributes(a, b map[string]interface{}) int {
+	wa, hasA := relationshipWeight(a)
+	wb, hasB := relationshipWeight(b)
+	switch {
+	case hasA != hasB:
+		if hasA {
+			return 1
+		}
+		return -1
+	case wa != wb:
+		if wa < wb {
+			return -1
+		}
+		return 1
+	}
+	na, hasA := a[RelationshipNote].(string)
+	nb, hasB := b[RelationshipNote].(string)
+	switch {
+	case hasA != hasB:
+		if hasA {
+			return 1
+		}
+		return -1
+	case na != nb:
+		return CompareCanonicalKeys(na, nb)
+	}
+	return 0
+}
+
+func relationshipWeight(m map[string]interface{}) (int64, bool) {
+	switch w := m[RelationshipWeight].(type) {
+	case int64:
+		return w, true
+	case json.Number:
+		n, err := w.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
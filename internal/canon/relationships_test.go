@@ -0,0 +1,71 @@
+package canon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// RULE-015: schema version 2 relationships may carry an integer weight and
+// a string note.
+func TestValidateRelationships(t *testing.T) {
+	rels := func(version string, rel map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"_helios_schema_version": version, "relationships": []interface{}{rel}}
+	}
+	ok := []map[string]interface{}{
+		rels("2", map[string]interface{}{"key": "a", "type": "t", "weight": json.Number("-3"), "note": "n"}),
+		rels("2", map[string]interface{}{"key": "a", "type": "t"}),
+		rels("1", map[string]interface{}{"key": "a", "type": "t", "extra": "ignored in version 1"}),
+		rels("1", map[string]interface{}{"key": "a", "type": "t", "weight": "ignored in version 1", "note": true}),
+	}
+	for _, input := range ok {
+		if err := ValidateRelationships(input); err != nil {
+			t.Errorf("ValidateRelationships(%v): %v", input, err)
+		}
+	}
+	for _, tc := range []struct {
+		input map[string]interface{}
+		code  string
+		path  string
+	}{
+		{rels("2", map[string]interface{}{"key": "a", "type": "t", "weight": json.Number("1.5")}), ErrCodeFloatProhibited, "relationships[0].weight"},
+		{rels("2", map[string]interface{}{"key": "a", "type": "t", "weight": json.Number("9223372036854775808")}), ErrCodeIntegerOutOfRange, "relationships[0].weight"},
+		{rels("2", map[string]interface{}{"key": "a", "type": "t", "weight": "1"}), ErrCodeRelationshipAttribute, "relationships[0].weight"},
+		{rels("2", map[string]interface{}{"key": "a", "type": "t", "weight": nil}), ErrCodeNullProhibited, "relationships[0].weight"},
+		{rels("2", map[string]interface{}{"key": "a", "type": "t", "note": true}), ErrCodeRelationshipAttribute, "relationships[0].note"},
+		{rels("2", map[string]interface{}{"key": "a", "type": "t", "since": "2026"}), ErrCodeRelationshipAttribute, "relationships[0].since"},
+	} {
+		err := ValidateRelationships(tc.input)
+		ce, ok := err.(*Error)
+		if !ok || ce.Code != tc.code || ce.Path != tc.path {
+			t.Errorf("ValidateRelationships(%v) = %v, want %s at %s", tc.input, err, tc.code, tc.path)
+		}
+	}
+}
+
+func TestSortRelationshipsByAttributes(t *testing.T) {
+	w := func(n int64) *int64 { return &n }
+	s := func(v string) *string { return &v }
+	want := []map[string]interface{}{
+		RelationshipToMapV2("a", "t", nil, nil),
+		RelationshipToMapV2("a", "t", nil, s("n")),
+		RelationshipToMapV2("a", "t", w(-2), nil),
+		RelationshipToMapV2("a", "t", w(-2), s("m")),
+		RelationshipToMapV2("a", "t", w(-2), s("n")),
+		RelationshipToMapV2("a", "t", w(10), nil),
+		RelationshipToMapV2("b", "s", w(-5), nil),
+	}
+	in := []map[string]interface{}{want[6], want[5], want[4], want[3], want[2], want[1], want[0]}
+	got, _ := CanonicalizeValue(toInterfaces(SortRelationships(in)))
+	exp, _ := CanonicalizeValue(toInterfaces(want))
+	if string(got) != string(exp) {
+		t.Errorf("sorted:\n got %s\nwant %s", got, exp)
+	}
+}
+
+func toInterfaces(maps []map[string]interface{}) []interface{} {
+	out := make([]interface{}, len(maps))
+	for i, m := range maps {
+		out[i] = m
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package canon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReservedKeyPrefixes are the prefixes of map keys inside a value that
+// are reserved for extensions of the spec (Section 3.9, RULE-014). Under
+// a profile with extensions, a key with one of them is rejected unless it
+// is a registered extension used in its registered form, so an extension
+// added later cannot change the meaning of data stored under it. Version
+// 1 without extensions accepts every key, as it always has.
+var ReservedKeyPrefixes = []string{"$", "_helios_"}
+
+// extensionKeys are the registered extensions: each is written as an
+// object whose only member is its key, and is validated by the
+// canonicalizer itself.
+var extensionKeys = []string{BytesKey}
+
+// ExtensionKeys returns the reserved keys registered as extensions.
+func ExtensionKeys() []string {
+	return append([]string(nil), extensionKeys...)
+}
+
+// IsReservedKey reports whether k has a reserved prefix.
+func IsReservedKey(k string) bool {
+	for _, p := range ReservedKeyPrefixes {
+		if strings.HasPrefix(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExtensionKey(k string) bool {
+	for _, e := range extensionKeys {
+		if k == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateExtensions checks v, a value as decoded by ingest, against the
+// rules a profile with extensions adds to version 1: every map key with a
+// reserved prefix is rejected unless it is a registered extension alone
+// in its object, and every binary value must be valid (ParseBytes).
+// Errors carry the path of the offending object.
+func ValidateExtensions(v interface{}) error {
+	return validateExtensions(v, "value")
+}
+
+func validateExtensions(v interface{}, path string) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if err := checkReservedKeys(val); err != nil {
+			return withPath(err, path)
+		}
+		if _, _, err := ParseBytes(val); err != nil {
+			return withPath(err, path)
+		}
+		for k, child := range val {
+			if err := validateExtensions(child, path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range val {
+			if err := validateExtensions(child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkReservedKeys rejects the first reserved key of m, in key order,
+// that is not a registered extension alone in its object.
+func checkReservedKeys(m map[string]interface{}) error {
+	var bad []string
+	for k := range m {
+		if IsReservedKey(k) && !(len(m) == 1 && isExtensionKey(k)) {
+			bad = append(bad, k)
+		}
+	}
+	if len(bad) == 0 {
+		return nil
+	}
+	sort.Strings(bad)
+	reason := "reserved prefix"
+	if isExtensionKey(bad[0]) {
+		reason = "extension key must be the only member of its object"
+	}
+	return &Error{Code: ErrCodeKeyReserved, Value: bad[0], Reason: reason}
+}
@@ -0,0 +1,44 @@
+package canon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// RULE-014: with extensions, keys with a reserved prefix are rejected
+// unless a registered extension in its registered form.
+func TestReservedKeys(t *testing.T) {
+	for _, v := range []map[string]interface{}{
+		{"price$": json.Number("1"), "helios_id": "a", "_Helios_x": "b", "x_helios_": "c"},
+		{"blob": map[string]interface{}{"$bytes": "aGVsbG8"}},
+	} {
+		if err := ValidateExtensions(v); err != nil {
+			t.Errorf("ValidateExtensions(%v): %v", v, err)
+		}
+	}
+	for _, tc := range []struct {
+		value     interface{}
+		path, key string
+	}{
+		{map[string]interface{}{"$type": "x"}, "value", "$type"},
+		{map[string]interface{}{"a": map[string]interface{}{"_helios_meta": "x"}}, "value.a", "_helios_meta"},
+		{[]interface{}{map[string]interface{}{"$ref": "#"}}, "value[0]", "$ref"},
+		{map[string]interface{}{"$bytes": "aGVsbG8", "mime": "text/plain"}, "value", "$bytes"},
+		{map[string]interface{}{"$z": "x", "$a": "y"}, "value", "$a"},
+	} {
+		err := ValidateExtensions(tc.value)
+		ce, ok := err.(*Error)
+		if !ok || ce.Code != ErrCodeKeyReserved || ce.Path != tc.path || ce.Value != tc.key {
+			t.Errorf("ValidateExtensions(%v) = %v, want %s at %s for %q", tc.value, err, ErrCodeKeyReserved, tc.path, tc.key)
+		}
+		// Version 1 without extensions accepts reserved keys as data.
+		if err := ValidateIngestValue(tc.value); err != nil {
+			t.Errorf("ValidateIngestValue(%v): %v", tc.value, err)
+		}
+	}
+	for _, k := range ExtensionKeys() {
+		if !IsReservedKey(k) {
+			t.Errorf("extension key %q has no reserved prefix", k)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,32 +19,57 @@ import (
 // normalizeString applies NFC Unicode normalization to a string.
 // Must be called on EVERY string field value before serialization.
 func NormalizeString(s string) string {
+	switch mutant {
+	case MutantSkipNFC:
+		return s
+	case MutantNFD:
+		return norm.NFD.String(s)
+	}
+	if isASCII(s) {
+		return s
+	}
+	// norm.NFC.String returns s itself, without copying, if it is
+	// already NFC. Checking with norm.NFC.IsNormalString first scans it
+	// twice and, in BenchmarkNormalizeString, adds an allocation to NFC
+	// input and two thirds to the cost of NFD input.
 	return norm.NFC.String(s)
 }
 
+// isASCII reports whether s is all ASCII, which NFC leaves unchanged.
+// Most keys, categories, and values are, and the scan costs less than
+// consulting the normalization tables.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
 // normalizeTimestamp validates and normalizes an ISO 8601 UTC timestamp
 // to exactly YYYY-MM-DDTHH:MM:SS.sssZ (3 decimal places).
 // Rejects timestamps not ending in Z or not having exactly 3 fractional digits.
 func NormalizeTimestamp(s string) (string, error) {
 	if !strings.HasSuffix(s, "Z") {
-		return "", fmt.Errorf("CANON_ERR_TIMESTAMP_NON_UTC: timestamp must end in Z, got: %s", s)
+		return "", &Error{Code: ErrCodeTimestampNonUTC, Value: s}
 	}
 
 	// Validate exactly 3 fractional digits
 	dotIdx := strings.LastIndex(s, ".")
 	if dotIdx == -1 {
-		return "", fmt.Errorf("CANON_ERR_TIMESTAMP_INVALID_PRECISION: timestamp must have exactly 3 fractional digits, got none: %s", s)
+		return "", &Error{Code: ErrCodeTimestampInvalidPrecision, Reason: "got none", Value: s}
 	}
 	// Extract fractional part (between '.' and 'Z')
 	frac := s[dotIdx+1 : len(s)-1] // strip trailing Z
 	if len(frac) != 3 {
-		return "", fmt.Errorf("CANON_ERR_TIMESTAMP_INVALID_PRECISION: timestamp must have exactly 3 fractional digits, got %d: %s", len(frac), s)
+		return "", &Error{Code: ErrCodeTimestampInvalidPrecision, Reason: fmt.Sprintf("got %d", len(frac)), Value: s}
 	}
 
 	// Parse with explicit format — NEVER use time.RFC3339Nano
 	t, err := time.Parse("2006-01-02T15:04:05.000Z", s)
 	if err != nil {
-		return "", fmt.Errorf("invalid timestamp format: %w", err)
+		return "", &Error{Code: ErrCodeTimestampInvalidFormat, Value: s, Err: err}
 	}
 
 	return t.Format("2006-01-02T15:04:05.000Z"), nil
@@ -56,10 +82,38 @@ func CanonicalizeObject(obj map[string]interface{}) ([]byte, error) {
 	return canonicalizeValue(obj)
 }
 
+// CanonicalizeValue produces the canonical byte representation of any
+// supported value: maps, arrays, strings, booleans, and integers.
+func CanonicalizeValue(v interface{}) ([]byte, error) {
+	return canonicalizeValue(v)
+}
+
+// Pretty renders v as indented, human-readable JSON with the same key
+// order, string escaping, and number forms as the canonical encoding.
+// Removing the whitespace from Pretty's output yields CanonicalizeValue's.
+func Pretty(v interface{}, indent string) ([]byte, error) {
+	compact, err := canonicalizeValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, compact, "", indent); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
 func canonicalizeValue(v interface{}) ([]byte, error) {
+	return encodeValue(v, nil)
+}
+
+// encodeValue serializes v, splitting arrays and maps of at least
+// par.threshold elements across goroutines if par is not nil.
+func encodeValue(v interface{}, par *parallelism) ([]byte, error) {
 	switch val := v.(type) {
 	case nil:
-		return nil, fmt.Errorf("CANON_ERR_NULL_PROHIBITED: null values are not permitted")
+		return nil, &Error{Code: ErrCodeNullProhibited}
 	case bool:
 		if val {
 			return []byte("true"), nil
@@ -76,12 +130,19 @@ func canonicalizeValue(v interface{}) ([]byte, error) {
 		return []byte(strconv.FormatInt(val, 10)), nil
 	case string:
 		return canonicalizeString(val)
+	case Decimal:
+		return canonicalizeDecimal(val)
+	case Bytes:
+		if err := checkBytes(val); err != nil {
+			return nil, err
+		}
+		return canonicalizeMap(val.Value(), nil)
 	case map[string]interface{}:
-		return canonicalizeMap(val)
+		return canonicalizeMap(val, par)
 	case []interface{}:
-		return canonicalizeArray(val)
+		return canonicalizeArray(val, par)
 	default:
-		return nil, fmt.Errorf("unsupported type: %T", v)
+		return nil, &Error{Code: ErrCodeUnsupportedType, Reason: fmt.Sprintf("%T", v)}
 	}
 }
 
@@ -92,6 +153,13 @@ func canonicalizeString(s string) ([]byte, error) {
 	buf.WriteByte('"')
 	for i := 0; i < len(s); {
 		r, size := utf8.DecodeRuneInString(s[i:])
+		if mutant != MutantNone {
+			if esc, ok := mutantEscape(r); ok {
+				buf.WriteString(esc)
+				i += size
+				continue
+			}
+		}
 		switch {
 		case r == '"':
 			buf.WriteString(`\"`)
@@ -121,12 +189,17 @@ func canonicalizeString(s string) ([]byte, error) {
 }
 
 // canonicalizeMap serializes a map with explicitly sorted keys.
-func canonicalizeMap(m map[string]interface{}) ([]byte, error) {
+func canonicalizeMap(m map[string]interface{}, par *parallelism) ([]byte, error) {
 	keys := make([]string, 0, len(m))
 	for k := range m {
 		keys = append(keys, k)
 	}
-	sort.Strings(keys)
+	sortKeys(keys)
+	if par.splits(len(keys)) {
+		return par.encode('{', '}', len(keys), func(i int, buf *bytes.Buffer) error {
+			return encodeMember(buf, keys[i], m[keys[i]])
+		})
+	}
 
 	var buf bytes.Buffer
 	buf.WriteByte('{')
@@ -141,7 +214,7 @@ func canonicalizeMap(m map[string]interface{}) ([]byte, error) {
 		buf.Write(keyBytes)
 		buf.WriteByte(':')
 
-		valBytes, err := canonicalizeValue(m[k])
+		valBytes, err := encodeValue(m[k], par)
 		if err != nil {
 			return nil, err
 		}
@@ -152,14 +225,24 @@ func canonicalizeMap(m map[string]interface{}) ([]byte, error) {
 }
 
 // canonicalizeArray serializes an array, preserving insertion order.
-func canonicalizeArray(arr []interface{}) ([]byte, error) {
+func canonicalizeArray(arr []interface{}, par *parallelism) ([]byte, error) {
+	if par.splits(len(arr)) {
+		return par.encode('[', ']', len(arr), func(i int, buf *bytes.Buffer) error {
+			b, err := canonicalizeValue(arr[i])
+			if err != nil {
+				return err
+			}
+			buf.Write(b)
+			return nil
+		})
+	}
 	var buf bytes.Buffer
 	buf.WriteByte('[')
 	for i, v := range arr {
 		if i > 0 {
 			buf.WriteByte(',')
 		}
-		valBytes, err := canonicalizeValue(v)
+		valBytes, err := encodeValue(v, par)
 		if err != nil {
 			return nil, err
 		}
@@ -169,19 +252,29 @@ func canonicalizeArray(arr []interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// SortRelationships sorts relationships by Key first, then Type as tie-breaker.
+// SortRelationships sorts relationships by Key first, then Type as tie-breaker,
+// then by the schema version 2 attributes (Section 8.3).
 func SortRelationships(rels []map[string]interface{}) []map[string]interface{} {
 	sorted := make([]map[string]interface{}, len(rels))
 	copy(sorted, rels)
+	if mutant == MutantUnsortedRelations {
+		return sorted
+	}
 	sort.SliceStable(sorted, func(i, j int) bool {
 		ki, _ := sorted[i]["key"].(string)
 		kj, _ := sorted[j]["key"].(string)
 		if ki != kj {
-			return ki < kj
+			return CompareCanonicalKeys(ki, kj) < 0
+		}
+		if mutant == MutantIgnoreRelationType {
+			return false
 		}
 		ti, _ := sorted[i]["type"].(string)
 		tj, _ := sorted[j]["type"].(string)
-		return ti < tj
+		if ti != tj {
+			return CompareCanonicalKeys(ti, tj) < 0
+		}
+		return compareRelationshipAttributes(sorted[i], sorted[j]) < 0
 	})
 	return sorted
 }
@@ -197,40 +290,56 @@ func RelationshipToMap(key, typ string) map[string]interface{} {
 
 // ValidateSchemaVersion checks RULE-001: _helios_schema_version must be present and equal to "1".
 func ValidateSchemaVersion(input map[string]interface{}) error {
+	return ValidateSchemaVersionIn(input, "1")
+}
+
+// ValidateSchemaVersionIn is ValidateSchemaVersion for a context that
+// accepts the given schema versions, such as a vectors file declaring
+// schema version 2.
+func ValidateSchemaVersionIn(input map[string]interface{}, versions ...string) error {
 	v, exists := input["_helios_schema_version"]
 	if !exists {
-		return fmt.Errorf("CANON_ERR_SCHEMA_VERSION_MISSING: _helios_schema_version field is required")
+		return &Error{Code: ErrCodeSchemaVersionMissing}
 	}
 	s, ok := v.(string)
-	if !ok || s != "1" {
-		return fmt.Errorf("CANON_ERR_SCHEMA_VERSION_INVALID: _helios_schema_version must be string \"1\", got %v", v)
+	if ok && slices.Contains(versions, s) {
+		return nil
 	}
-	return nil
+	e := &Error{Code: ErrCodeSchemaVersionInvalid, Path: "_helios_schema_version", Value: v}
+	if len(versions) > 1 {
+		e.Reason = "accepted here: " + strings.Join(versions, ", ")
+	}
+	return e
 }
 
 // ValidateIngestValue recursively validates a parsed JSON value for spec compliance.
-// Checks: RULE-002 (no floats), RULE-009 (integer range), RULE-010 (no nulls).
+// Checks: RULE-002 (no floats), RULE-009 (integer range), RULE-010 (no nulls),
+// RULE-013 (binary values), RULE-014 (reserved keys).
 // Expects values from json.Decoder with UseNumber().
 func ValidateIngestValue(v interface{}) error {
-	return validateIngest(v, "")
+	return validateIngest(v, "value")
 }
 
 func validateIngest(v interface{}, path string) error {
 	switch val := v.(type) {
 	case nil:
-		return fmt.Errorf("CANON_ERR_NULL_PROHIBITED: null value at %s", path)
+		return &Error{Code: ErrCodeNullProhibited, Path: path}
 	case float64:
-		return fmt.Errorf("CANON_ERR_FLOAT_PROHIBITED: float value at %s", path)
+		return &Error{Code: ErrCodeFloatProhibited, Path: path, Value: val}
 	case json.Number:
 		s := val.String()
 		// Check for float indicators: decimal point or scientific notation
 		if strings.Contains(s, ".") || strings.Contains(s, "e") || strings.Contains(s, "E") {
-			return fmt.Errorf("CANON_ERR_FLOAT_PROHIBITED: numeric value %q at %s contains decimal or exponent", s, path)
+			return &Error{Code: ErrCodeFloatProhibited, Reason: "contains decimal or exponent", Path: path, Value: val}
 		}
 		// Check integer range (signed 64-bit)
 		_, err := val.Int64()
 		if err != nil {
-			return fmt.Errorf("CANON_ERR_INTEGER_OUT_OF_RANGE: value %q at %s exceeds int64 bounds", s, path)
+			return &Error{Code: ErrCodeIntegerOutOfRange, Path: path, Value: val}
+		}
+	case Bytes:
+		if err := checkBytes(val); err != nil {
+			return withPath(err, path)
 		}
 	case map[string]interface{}:
 		for k, child := range val {
@@ -246,10 +355,14 @@ func validateIngest(v interface{}, path string) error {
 				return err
 			}
 		}
+	case Decimal:
+		if !validDecimal(string(val)) {
+			return &Error{Code: ErrCodeDecimalInvalid, Path: path, Value: string(val)}
+		}
 	case string, bool:
 		// Valid types, no checks needed
 	default:
-		return fmt.Errorf("unsupported type %T at %s", v, path)
+		return &Error{Code: ErrCodeUnsupportedType, Reason: fmt.Sprintf("%T", v), Path: path}
 	}
 	return nil
 }
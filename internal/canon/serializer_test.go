@@ -1,6 +1,7 @@
 package canon
 
 import (
+	"bytes"
 	"encoding/json"
 	"strings"
 	"testing"
@@ -135,6 +136,32 @@ func TestNFCNormalization(t *testing.T) {
 	}
 }
 
+func TestNormalizeStringASCII(t *testing.T) {
+	for _, s := range []string{"", "plain ascii", "caf\u00e9", "e\u0301", "cafe\u0301", "\u0301", "\u1100\u1161", "\uac00", "a\u0323\u0307", "a\u0307\u0323"} {
+		if got, want := isASCII(s), norm.NFC.IsNormalString(s); got && !want {
+			t.Errorf("isASCII(%+q) = true for a string NFC changes", s)
+		}
+		if got := NormalizeString(s); got != norm.NFC.String(s) {
+			t.Errorf("NormalizeString(%+q) = %+q", s, got)
+		}
+	}
+}
+
+func BenchmarkNormalizeString(b *testing.B) {
+	for _, tc := range []struct{ name, s string }{
+		{"ascii", "project/roadmap/2025-q3 milestones and owners"},
+		{"nfc", "projet/feuille de route/\u00e9t\u00e9 2025 jalons"},
+		{"nfd", "projet/feuille de route/e\u0301te\u0301 2025 jalons"},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				NormalizeString(tc.s)
+			}
+		})
+	}
+}
+
 func TestNestedObjectKeyOrdering(t *testing.T) {
 	obj := map[string]interface{}{
 		"outer_b": map[string]interface{}{
@@ -227,6 +254,7 @@ func TestJSONNumberHandling(t *testing.T) {
 
 // --- Ingest validation tests (RULE-002, RULE-009, RULE-010) ---
 
+// RULE-002: a float value is rejected.
 func TestIngestRejectsFloat(t *testing.T) {
 	input := `{"value": 3.14}`
 	dec := json.NewDecoder(strings.NewReader(input))
@@ -244,6 +272,7 @@ func TestIngestRejectsFloat(t *testing.T) {
 	}
 }
 
+// RULE-002: scientific notation is a float.
 func TestIngestRejectsScientificNotation(t *testing.T) {
 	input := `{"value": 1e10}`
 	dec := json.NewDecoder(strings.NewReader(input))
@@ -261,6 +290,7 @@ func TestIngestRejectsScientificNotation(t *testing.T) {
 	}
 }
 
+// RULE-009: integers above the int64 range are rejected.
 func TestIngestRejectsIntegerOverflow(t *testing.T) {
 	input := `{"value": 9223372036854775808}` // int64 max + 1
 	dec := json.NewDecoder(strings.NewReader(input))
@@ -278,6 +308,7 @@ func TestIngestRejectsIntegerOverflow(t *testing.T) {
 	}
 }
 
+// RULE-009: integers below the int64 range are rejected.
 func TestIngestRejectsNegativeIntegerOverflow(t *testing.T) {
 	input := `{"value": -9223372036854775809}` // int64 min - 1
 	dec := json.NewDecoder(strings.NewReader(input))
@@ -295,6 +326,7 @@ func TestIngestRejectsNegativeIntegerOverflow(t *testing.T) {
 	}
 }
 
+// RULE-009: integers within the int64 range are accepted.
 func TestIngestAcceptsValidInteger(t *testing.T) {
 	input := `{"value": 9223372036854775807}` // int64 max
 	dec := json.NewDecoder(strings.NewReader(input))
@@ -309,6 +341,7 @@ func TestIngestAcceptsValidInteger(t *testing.T) {
 	}
 }
 
+// RULE-010: a null value is rejected.
 func TestIngestRejectsNull(t *testing.T) {
 	err := ValidateIngestValue(nil)
 	if err == nil {
@@ -319,6 +352,7 @@ func TestIngestRejectsNull(t *testing.T) {
 	}
 }
 
+// RULE-010: a null nested inside the value is rejected.
 func TestIngestRejectsNestedNull(t *testing.T) {
 	input := map[string]interface{}{
 		"outer": map[string]interface{}{
@@ -406,3 +440,32 @@ func TestRunHashRejectsFloat(t *testing.T) {
 		t.Errorf("expected CANON_ERR_FLOAT_PROHIBITED, got: %v", err)
 	}
 }
+
+func TestPrettyMatchesCanonicalWhenCompacted(t *testing.T) {
+	obj := map[string]interface{}{
+		"zebra": []interface{}{"日本語", json.Number("2")},
+		"alpha": map[string]interface{}{"b": true, "a": "x \"y\""},
+	}
+	pretty, err := Pretty(obj, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(pretty), "\n  \"alpha\": {") {
+		t.Errorf("expected indented output with sorted keys, got:\n%s", pretty)
+	}
+	if strings.Contains(string(pretty), `\u`) {
+		t.Errorf("pretty output must preserve raw UTF-8: %s", pretty)
+	}
+
+	canonical, err := CanonicalizeValue(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, pretty); err != nil {
+		t.Fatal(err)
+	}
+	if compact.String() != string(canonical) {
+		t.Errorf("compacted pretty output differs from canonical:\n  %s\n  %s", compact.String(), canonical)
+	}
+}
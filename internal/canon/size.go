@@ -0,0 +1,112 @@
+package canon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CanonicalSize returns the length in bytes of CanonicalizeValue(v)
+// without building it. It walks v once and neither sorts keys nor
+// allocates, so it is cheap enough to run on every input before
+// canonicalizing, and fails with the same errors CanonicalizeValue would.
+func CanonicalSize(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, &Error{Code: ErrCodeNullProhibited}
+	case bool:
+		if val {
+			return 4, nil
+		}
+		return 5, nil
+	case json.Number:
+		return int64(len(val)), nil
+	case float64:
+		var buf [32]byte
+		return int64(len(strconv.AppendFloat(buf[:0], val, 'f', -1, 64))), nil
+	case int:
+		var buf [20]byte
+		return int64(len(strconv.AppendInt(buf[:0], int64(val), 10))), nil
+	case int64:
+		var buf [20]byte
+		return int64(len(strconv.AppendInt(buf[:0], val, 10))), nil
+	case string:
+		return stringSize(val), nil
+	case Decimal:
+		if !validDecimal(string(val)) {
+			return 0, &Error{Code: ErrCodeDecimalInvalid, Value: string(val)}
+		}
+		return int64(len(val)) + 2, nil
+	case Bytes:
+		if err := checkBytes(val); err != nil {
+			return 0, err
+		}
+		return int64(len(`{"$bytes":""}`) + base64.RawURLEncoding.EncodedLen(len(val))), nil
+	case map[string]interface{}:
+		// Braces, a colon per entry, and a comma between entries.
+		n := int64(2 + len(val))
+		if len(val) > 1 {
+			n += int64(len(val) - 1)
+		}
+		for k, child := range val {
+			c, err := CanonicalSize(child)
+			if err != nil {
+				return 0, err
+			}
+			n += stringSize(k) + c
+		}
+		return n, nil
+	case []interface{}:
+		n := int64(2)
+		if len(val) > 1 {
+			n += int64(len(val) - 1)
+		}
+		for _, child := range val {
+			c, err := CanonicalSize(child)
+			if err != nil {
+				return 0, err
+			}
+			n += c
+		}
+		return n, nil
+	default:
+		return 0, &Error{Code: ErrCodeUnsupportedType, Reason: fmt.Sprintf("%T", v)}
+	}
+}
+
+// stringSize returns the length of canonicalizeString(s).
+func stringSize(s string) int64 {
+	n := int64(2)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"', r == '\\', r == '\b', r == '\f', r == '\n', r == '\r', r == '\t':
+			n += 2
+		case r < 0x20:
+			n += 6
+		default:
+			n += int64(size)
+		}
+		i += size
+	}
+	return n
+}
+
+// CheckSize fails with CANON_ERR_TOO_LARGE if the canonical form of v is
+// longer than max bytes; the error's Value is the measured size. A max of
+// zero or less is no limit.
+func CheckSize(v interface{}, max int64) error {
+	if max <= 0 {
+		return nil
+	}
+	n, err := CanonicalSize(v)
+	if err != nil {
+		return err
+	}
+	if n > max {
+		return &Error{Code: ErrCodeTooLarge, Value: n, Reason: fmt.Sprintf("limit %d bytes", max)}
+	}
+	return nil
+}
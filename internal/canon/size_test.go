@@ -0,0 +1,57 @@
+package canon
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalSizeMatchesCanonicalizeValue(t *testing.T) {
+	values := []interface{}{
+		true,
+		false,
+		int64(-9223372036854775808),
+		0,
+		json.Number("42"),
+		"",
+		"plain",
+		"quote \" backslash \\ newline \n tab \t bell \u0007 del \u007f",
+		"\u00e9t\u00e9 \U0001f600",
+		"invalid \xff utf-8",
+		map[string]interface{}{},
+		[]interface{}{},
+		map[string]interface{}{"b": int64(1), "a\n": []interface{}{"x", true, map[string]interface{}{"\u0001": "y"}}},
+		[]interface{}{[]interface{}{}, map[string]interface{}{"k": "v"}, int64(3)},
+	}
+	for _, v := range values {
+		want, err := CanonicalizeValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := CanonicalSize(v); err != nil || got != int64(len(want)) {
+			t.Errorf("CanonicalSize(%#v) = %d, %v; want %d", v, got, err, len(want))
+		}
+	}
+
+	if _, err := CanonicalSize(map[string]interface{}{"a": nil}); ErrorCode(err) != ErrCodeNullProhibited {
+		t.Errorf("CanonicalSize of a null = %v", err)
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	v := map[string]interface{}{"text": strings.Repeat("x", 100)}
+	n, err := CanonicalSize(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, max := range []int64{0, -1, n} {
+		if err := CheckSize(v, max); err != nil {
+			t.Errorf("CheckSize(%d) = %v", max, err)
+		}
+	}
+	err = CheckSize(v, n-1)
+	e, ok := err.(*Error)
+	if !ok || e.Code != ErrCodeTooLarge || e.Value != n {
+		t.Errorf("CheckSize(%d) = %v, want %s with the measured size %d", n-1, err, ErrCodeTooLarge, n)
+	}
+}
@@ -0,0 +1,91 @@
+package canon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/unicode/rangetable"
+)
+
+// UnicodeVersion is the Unicode version of the NFC tables the frozen
+// vectors were hashed with. Normalization of assigned code points is
+// stable across versions, but a code point unassigned in one version may
+// be given a decomposition or combining class in the next, so a string
+// containing one can normalize, and hash, differently after the
+// golang.org/x/text dependency is upgraded.
+//
+// TestUnicodeTablesPinned fails when the tables in the build no longer
+// match UnicodeVersion and UnicodeTablesDigest. Before bumping both, run
+// helios verify --unicode-impact on every store whose hashes matter.
+const UnicodeVersion = "17.0.0"
+
+// UnicodeTablesDigest is TablesDigest of the tables of UnicodeVersion.
+const UnicodeTablesDigest = "35f0979e3fa672054eb5575153de57eb63513caf351616411dc5dd08ef11c8c8"
+
+// TablesVersion returns the Unicode version of the NFC tables in the build.
+func TablesVersion() string {
+	return norm.Version
+}
+
+var tablesDigest = sync.OnceValue(func() string {
+	h := sha256.New()
+	var buf [utf8.UTFMax]byte
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if r >= 0xd800 && r <= 0xdfff {
+			continue
+		}
+		s := string(buf[:utf8.EncodeRune(buf[:], r)])
+		p := norm.NFC.PropertiesString(s)
+		nfc := norm.NFC.String(s)
+		if p.CCC() == 0 && p.Decomposition() == nil && nfc == s {
+			continue
+		}
+		fmt.Fprintf(h, "%x;%d;%x;%x\n", r, p.CCC(), p.Decomposition(), nfc)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+})
+
+// TablesDigest returns a SHA-256 over the normalization behavior of every
+// code point in the build's NFC tables: its combining class, canonical
+// decomposition, and NFC form. It changes whenever an upgrade changes
+// what NFC does to any string, even if the Unicode version does not. The
+// first call takes a fraction of a second.
+func TablesDigest() string {
+	return tablesDigest()
+}
+
+// UnassignedRunes returns the code points of s, in order of first
+// appearance, that are unassigned in the Unicode version of the build's
+// NFC tables (TablesVersion) and so may normalize differently under a
+// later version. Noncharacters are permanently unassigned and are not
+// reported.
+func UnassignedRunes(s string) []rune {
+	var out []rune
+	for _, r := range s {
+		if r == utf8.RuneError || assigned(r) || noncharacter(r) {
+			continue
+		}
+		if !slices.Contains(out, r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// assignedTable holds the code points assigned in the NFC tables'
+// version, which can differ from the standard library's unicode.Version.
+var assignedTable = rangetable.Assigned(norm.Version)
+
+func assigned(r rune) bool {
+	return unicode.Is(assignedTable, r)
+}
+
+func noncharacter(r rune) bool {
+	return r >= 0xfdd0 && r <= 0xfdef || r&0xfffe == 0xfffe
+}
@@ -0,0 +1,34 @@
+package canon
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestUnicodeTablesPinned fails when an upgrade of golang.org/x/text
+// changes what NFC does. Stored hashes of strings the new tables
+// normalize differently no longer match their contents: run
+// helios verify --unicode-impact on the stores that matter, then update
+// UnicodeVersion and UnicodeTablesDigest.
+func TestUnicodeTablesPinned(t *testing.T) {
+	if got := TablesVersion(); got != UnicodeVersion {
+		t.Errorf("NFC tables are Unicode %s, pinned %s", got, UnicodeVersion)
+	}
+	if got := TablesDigest(); got != UnicodeTablesDigest {
+		t.Errorf("NFC tables digest is %s, pinned %s", got, UnicodeTablesDigest)
+	}
+}
+
+func TestUnassignedRunes(t *testing.T) {
+	if assignedTable == nil {
+		t.Fatalf("no assigned code point table for the NFC tables' Unicode %s", TablesVersion())
+	}
+	got := UnassignedRunes("a\u0378b\U000e0000\u0378\uffff\U0010fffd\u00e9\xff")
+	want := []rune{0x378, 0xe0000}
+	if !slices.Equal(got, want) {
+		t.Errorf("UnassignedRunes = %U, want %U", got, want)
+	}
+	if got := UnassignedRunes("plain \u00e9 \U0001f600"); got != nil {
+		t.Errorf("assigned text reported %U", got)
+	}
+}
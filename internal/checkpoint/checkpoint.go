@@ -0,0 +1,218 @@
+// Package checkpoint commits to the state of a store with signed Merkle
+// roots. A checkpoint is the RFC 6962 Merkle root over every key of a
+// namespace and the hash it points at, in key order, signed by the store's
+// operator and appended to a transparency log. Anyone holding the
+// operator's public key can later check that a copy of the store still
+// matches a checkpoint, and the log shows that no checkpoint was quietly
+// replaced. Witnesses, independent parties who cosign checkpoints, keep
+// the operator from showing different logs to different verifiers.
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/merkle"
+	"github.com/holeyfield33-art/helios/internal/signing"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// bodyHeader starts the signed body of every checkpoint.
+const bodyHeader = "helios-checkpoint/v1"
+
+// ErrMismatch is returned by Check for a store whose keys no longer hash
+// to the checkpoint's root.
+var ErrMismatch = errors.New("checkpoint: store does not match the checkpoint")
+
+// Signature is one signature over a checkpoint's body.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Checkpoint is a signed commitment to the keys of one namespace.
+type Checkpoint struct {
+	// Origin names the store the checkpoint was taken of.
+	Origin string `json:"origin"`
+	Tenant string `json:"tenant,omitempty"`
+	// Seq numbers the checkpoints of a log from 1.
+	Seq uint64 `json:"seq"`
+	// Size is the number of keys, and so of tree leaves.
+	Size int `json:"size"`
+	// Root is the hex Merkle root over the keys.
+	Root string `json:"root" schema:"pattern=^[0-9a-f]{64}$"`
+	Time string `json:"time"`
+	// Prev is the ID of the checkpoint before this one in its log, ""
+	// for the first.
+	Prev       string      `json:"prev,omitempty"`
+	Signatures []Signature `json:"signatures"`
+}
+
+// Body returns the bytes a checkpoint's signatures sign: every field but
+// the signatures, one per line.
+func (c *Checkpoint) Body() []byte {
+	var b bytes.Buffer
+	b.WriteString(bodyHeader + "\n")
+	b.WriteString(strconv.Quote(c.Origin) + "\n")
+	b.WriteString(strconv.Quote(c.Tenant) + "\n")
+	b.WriteString(strconv.FormatUint(c.Seq, 10) + "\n")
+	b.WriteString(strconv.Itoa(c.Size) + "\n")
+	b.WriteString(c.Root + "\n")
+	b.WriteString(c.Time + "\n")
+	b.WriteString(c.Prev + "\n")
+	return b.Bytes()
+}
+
+// ID returns the hex SHA-256 of the checkpoint's body. Signatures are not
+// part of the ID, so adding one does not change it.
+func (c *Checkpoint) ID() string {
+	sum := sha256.Sum256(c.Body())
+	return hex.EncodeToString(sum[:])
+}
+
+// LeafData returns the tree leaf for key pointing at the hex content hash
+// h: the key's length as a 4-byte big-endian integer, the key, and the
+// 32 bytes of the hash.
+func LeafData(key, h string) ([]byte, error) {
+	digest, err := hex.DecodeString(h)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("key %q: invalid content hash %q", key, h)
+	}
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(key)))
+	out = append(out, key...)
+	return append(out, digest...), nil
+}
+
+// Leaves returns the leaf hashes of entries, which must be sorted by key.
+func Leaves(entries []store.KeyEntry) ([]merkle.Hash, error) {
+	leaves := make([]merkle.Hash, len(entries))
+	for i, e := range entries {
+		data, err := LeafData(e.Key, e.Hash)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = merkle.LeafHash(data)
+	}
+	return leaves, nil
+}
+
+// Snapshot is the Merkle root of a namespace at one moment.
+type Snapshot struct {
+	Tenant string
+	Size   int
+	Root   string
+}
+
+// Take computes the Merkle root over the keys of s.
+func Take(ctx context.Context, s *store.Store) (Snapshot, error) {
+	entries, err := s.Keys(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	leaves, err := Leaves(entries)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	root := merkle.Root(leaves)
+	return Snapshot{Tenant: s.TenantID(), Size: len(entries), Root: hex.EncodeToString(root[:])}, nil
+}
+
+// New returns an unsigned checkpoint of snap taken at t. Log.Append
+// numbers and chains it.
+func New(origin string, snap Snapshot, t time.Time) *Checkpoint {
+	return &Checkpoint{
+		Origin: origin,
+		Tenant: snap.Tenant,
+		Size:   snap.Size,
+		Root:   snap.Root,
+		Time:   t.UTC().Format("2006-01-02T15:04:05.000Z"),
+	}
+}
+
+// Sign adds a signature by each of signers.
+func (c *Checkpoint) Sign(signers ...signing.Signer) error {
+	if len(signers) == 0 {
+		return fmt.Errorf("at least one signer is required")
+	}
+	body := c.Body()
+	for _, s := range signers {
+		sig, err := s.Sign(body)
+		if err != nil {
+			return fmt.Errorf("signing failed: %w", err)
+		}
+		c.Signatures = append(c.Signatures, Signature{KeyID: s.KeyID(), Sig: base64.StdEncoding.EncodeToString(sig)})
+	}
+	return nil
+}
+
+// Verify checks that at least one of c's signatures was made by one of
+// keys.
+func (c *Checkpoint) Verify(keys ...crypto.PublicKey) error {
+	body := c.Body()
+	for _, sig := range c.Signatures {
+		raw, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+		for _, k := range keys {
+			if signing.Verify(k, body, raw) == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("checkpoint %d: no valid signature from a trusted key", c.Seq)
+}
+
+// SignedAt returns the time c records, when its signatures were made. The
+// signer chose it; only witnesses, which check it against their clocks,
+// vouch for it.
+func (c *Checkpoint) SignedAt() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, c.Time)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("checkpoint %d: invalid time %q", c.Seq, c.Time)
+	}
+	return t, nil
+}
+
+// VerifyPolicy checks that c's signatures meet policy, considering its own
+// keys and keys, with signers' validity windows and revocations checked
+// at at. Pass c's own time only once witnesses have vouched for it.
+func (c *Checkpoint) VerifyPolicy(policy *signing.TrustPolicy, at time.Time, keys ...crypto.PublicKey) error {
+	var sigs [][]byte
+	for _, sig := range c.Signatures {
+		if raw, err := base64.StdEncoding.DecodeString(sig.Sig); err == nil {
+			sigs = append(sigs, raw)
+		}
+	}
+	if _, err := policy.Check(c.Body(), sigs, at, keys...); err != nil {
+		return fmt.Errorf("checkpoint %d: %w", c.Seq, err)
+	}
+	return nil
+}
+
+// Check recomputes the root of s and fails with ErrMismatch unless it is
+// c's. It does not check signatures.
+func Check(ctx context.Context, s *store.Store, c *Checkpoint) error {
+	if s.TenantID() != c.Tenant {
+		return fmt.Errorf("%w: checkpoint is of tenant %q, store is %q", ErrMismatch, c.Tenant, s.TenantID())
+	}
+	snap, err := Take(ctx, s)
+	if err != nil {
+		return err
+	}
+	if snap.Root != strings.ToLower(c.Root) || snap.Size != c.Size {
+		return fmt.Errorf("%w: store has %d keys with root %s, checkpoint %d has %d with root %s",
+			ErrMismatch, snap.Size, snap.Root, c.Seq, c.Size, c.Root)
+	}
+	return nil
+}
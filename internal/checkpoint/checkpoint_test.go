@@ -0,0 +1,176 @@
+package checkpoint
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+func memory(key, value string) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "user",
+		Value:         value,
+	}
+}
+
+func testSigner(t *testing.T) signing.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := signing.NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	s := store.New(store.NewMemory())
+	s.Put(ctx, memory("a", "one"))
+	s.Put(ctx, memory("b", "two"))
+	signer, other := testSigner(t), testSigner(t)
+
+	snap, err := Take(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Size != 2 || len(snap.Root) != 64 {
+		t.Fatalf("Take = %+v", snap)
+	}
+	c := New("test", snap, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := c.Sign(signer); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Verify(signer.Public()); err != nil {
+		t.Error(err)
+	}
+	if err := c.Verify(other.Public()); err == nil {
+		t.Error("verified with an untrusted key")
+	}
+	forged := *c
+	forged.Root = strings.Repeat("0", 64)
+	if err := forged.Verify(signer.Public()); err == nil {
+		t.Error("verified a checkpoint with a changed root")
+	}
+
+	if err := Check(ctx, s, c); err != nil {
+		t.Errorf("Check of an unchanged store: %v", err)
+	}
+	s.Put(ctx, memory("a", "changed"))
+	if err := Check(ctx, s, c); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Check after a put: %v", err)
+	}
+	s.Put(ctx, memory("a", "one"))
+	if err := Check(ctx, s, c); err != nil {
+		t.Errorf("Check after restoring the key: %v", err)
+	}
+	acme, _ := s.Tenant(ctx, "acme")
+	if err := Check(ctx, acme, c); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Check of another tenant: %v", err)
+	}
+}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoints.jsonl")
+	s := store.New(store.NewMemory())
+	signer := testSigner(t)
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"one", "two", "three"} {
+		s.Put(ctx, memory("k", v))
+		snap, _ := Take(ctx, s)
+		c := New("test", snap, time.Now())
+		log.Next(c)
+		if err := log.Append(c); err == nil {
+			t.Fatal("appended an unsigned checkpoint")
+		}
+		c.Sign(signer)
+		if err := log.Append(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log, err = OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := log.Entries()
+	if len(entries) != 3 || log.Latest().Seq != 3 || entries[1].Prev != entries[0].ID() {
+		t.Fatalf("reopened log = %+v", entries)
+	}
+	if err := log.Verify(signer.Public()); err != nil {
+		t.Error(err)
+	}
+	if err := Check(ctx, s, log.Latest()); err != nil {
+		t.Error(err)
+	}
+	stale := *entries[2]
+	if err := log.Append(&stale); err == nil {
+		t.Error("appended a checkpoint that does not chain")
+	}
+
+	// Rewriting an entry breaks the chain after it.
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), entries[0].Root, entries[2].Root, 1)), 0o644)
+	if _, err := OpenLog(path); err == nil {
+		t.Error("opened a rewritten log")
+	}
+}
+
+func TestReaffirm(t *testing.T) {
+	ctx := context.Background()
+	log, err := OpenLog(filepath.Join(t.TempDir(), "checkpoints.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, next := testSigner(t), testSigner(t)
+	if _, err := log.Reaffirm(time.Now(), old, next); err == nil {
+		t.Fatal("reaffirmed an empty log")
+	}
+	s := store.New(store.NewMemory())
+	s.Put(ctx, memory("k", "v"))
+	snap, _ := Take(ctx, s)
+	c := New("test", snap, time.Now())
+	log.Next(c)
+	c.Sign(old)
+	if err := log.Append(c); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := log.Reaffirm(time.Now().Add(time.Minute), old, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Seq != 2 || r.Prev != c.ID() || r.Root != c.Root || r.Size != c.Size || r.Origin != c.Origin {
+		t.Errorf("reaffirmed %+v of %+v", r, c)
+	}
+	if err := r.Verify(next.Public()); err != nil {
+		t.Errorf("the new key did not sign: %v", err)
+	}
+	if err := r.Verify(old.Public()); err != nil {
+		t.Errorf("the old key did not sign: %v", err)
+	}
+	if err := Check(ctx, s, r); err != nil {
+		t.Error(err)
+	}
+}
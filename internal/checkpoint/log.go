@@ -0,0 +1,218 @@
+package checkpoint
+
+import (
+	"bufio"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// Log is a transparency log of checkpoints: a file of JSON lines, one
+// checkpoint per line, each naming the ID of the one before. Rewriting or
+// dropping an entry breaks the chain of every later one, so a reader who
+// has seen a checkpoint can tell whether the log still contains it.
+type Log struct {
+	mu      sync.Mutex
+	path    string
+	entries []*Checkpoint
+}
+
+// OpenLog reads the log at path, which need not exist yet, and checks
+// its chain. It does not check signatures; see Verify.
+func OpenLog(path string) (*Log, error) {
+	l := &Log{path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		var c Checkpoint
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("checkpoint log %s: line %d: %w", path, line, err)
+		}
+		if err := l.chains(&c); err != nil {
+			return nil, fmt.Errorf("checkpoint log %s: line %d: %w", path, line, err)
+		}
+		l.entries = append(l.entries, &c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("checkpoint log %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Reload picks up checkpoints appended to the log file since it was
+// opened, by this process or another. It fails, leaving l as it was, if
+// the file no longer begins with the checkpoints l holds.
+func (l *Log) Reload() error {
+	fresh, err := OpenLog(l.path)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(fresh.entries) < len(l.entries) {
+		return fmt.Errorf("checkpoint log %s has lost checkpoints %d to %d", l.path, len(fresh.entries)+1, len(l.entries))
+	}
+	for i, c := range l.entries {
+		if fresh.entries[i].ID() != c.ID() {
+			return fmt.Errorf("checkpoint log %s: checkpoint %d has been rewritten", l.path, c.Seq)
+		}
+	}
+	l.entries = fresh.entries
+	return nil
+}
+
+// chains reports whether c can follow the log's last entry.
+func (l *Log) chains(c *Checkpoint) error {
+	var want uint64 = 1
+	prev := ""
+	if n := len(l.entries); n > 0 {
+		want, prev = l.entries[n-1].Seq+1, l.entries[n-1].ID()
+	}
+	if c.Seq != want {
+		return fmt.Errorf("checkpoint %d follows checkpoint %d", c.Seq, want-1)
+	}
+	if c.Prev != prev {
+		return fmt.Errorf("checkpoint %d names previous checkpoint %q, not %q", c.Seq, c.Prev, prev)
+	}
+	return nil
+}
+
+// Entries returns the log's checkpoints, oldest first.
+func (l *Log) Entries() []*Checkpoint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*Checkpoint(nil), l.entries...)
+}
+
+// Latest returns the newest checkpoint, or nil if the log is empty.
+func (l *Log) Latest() *Checkpoint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 {
+		return nil
+	}
+	return l.entries[len(l.entries)-1]
+}
+
+// Since returns the checkpoints after seq, oldest first.
+func (l *Log) Since(seq uint64) []*Checkpoint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if seq >= uint64(len(l.entries)) {
+		return nil
+	}
+	return append([]*Checkpoint(nil), l.entries[seq:]...)
+}
+
+// Get returns checkpoint seq.
+func (l *Log) Get(seq uint64) (*Checkpoint, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if seq < 1 || seq > uint64(len(l.entries)) {
+		return nil, fmt.Errorf("checkpoint log %s has no checkpoint %d", l.path, seq)
+	}
+	return l.entries[seq-1], nil
+}
+
+// Verify checks the signature of every checkpoint in the log against
+// keys.
+func (l *Log) Verify(keys ...crypto.PublicKey) error {
+	for _, c := range l.Entries() {
+		if err := c.Verify(keys...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Next numbers c and chains it to the log's newest checkpoint, ready to
+// be signed and appended.
+func (l *Log) Next(c *Checkpoint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c.Seq, c.Prev = 1, ""
+	if n := len(l.entries); n > 0 {
+		c.Seq, c.Prev = l.entries[n-1].Seq+1, l.entries[n-1].ID()
+	}
+}
+
+// Append writes c, which Next must have chained, to the end of the log.
+func (l *Log) Append(c *Checkpoint) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.chains(c); err != nil {
+		return err
+	}
+	if len(c.Signatures) == 0 {
+		return fmt.Errorf("checkpoint %d is not signed", c.Seq)
+	}
+	line, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	l.entries = append(l.entries, c)
+	return nil
+}
+
+// Reaffirm appends a checkpoint of the same origin, namespace, and root
+// as the log's newest, taken at t and signed by signers, and returns it.
+// Rotating keys signs it with the old key and the new one, so a verifier
+// who trusts the old key sees the new one sign the state the old one
+// last signed. It fails if the log is empty.
+func (l *Log) Reaffirm(t time.Time, signers ...signing.Signer) (*Checkpoint, error) {
+	latest := l.Latest()
+	if latest == nil {
+		return nil, fmt.Errorf("checkpoint log %s is empty", l.path)
+	}
+	c := New(latest.Origin, Snapshot{Tenant: latest.Tenant, Size: latest.Size, Root: latest.Root}, t)
+	l.Next(c)
+	if err := c.Sign(signers...); err != nil {
+		return nil, err
+	}
+	if err := l.Append(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Covering returns the newest checkpoint of tenant's namespace with the
+// given root and size, or nil if there is none.
+func (l *Log) Covering(tenant, root string, size int) *Checkpoint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if c := l.entries[i]; c.Tenant == tenant && c.Root == root && c.Size == size {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/merkle"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// Proof shows that a key pointed at a hash in the tree with a given root,
+// without the rest of the tree. Checked against the root of a signed
+// checkpoint, it proves the object was in the store the checkpoint
+// commits to.
+type Proof struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash" schema:"pattern=^[0-9a-f]{64}$"`
+	// Index is the key's position among the tree's Size leaves.
+	Index int    `json:"index"`
+	Size  int    `json:"size"`
+	Root  string `json:"root" schema:"pattern=^[0-9a-f]{64}$"`
+	// Path holds the hex sibling hashes from the leaf to the root.
+	Path []string `json:"path"`
+	// Checkpoint, if set, is the signed checkpoint whose root the proof
+	// leads to.
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// GenerateProof proves that key is in the current tree of s. The proof
+// is against the store as it is now: it checks out against a checkpoint
+// only while the store is unchanged since that checkpoint was taken.
+func GenerateProof(ctx context.Context, s *store.Store, key string) (*Proof, error) {
+	entries, err := s.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Key >= key })
+	if i == len(entries) || entries[i].Key != key {
+		return nil, fmt.Errorf("%w: no key %q", store.ErrNotFound, key)
+	}
+	leaves, err := Leaves(entries)
+	if err != nil {
+		return nil, err
+	}
+	path, err := merkle.InclusionProof(leaves, i)
+	if err != nil {
+		return nil, err
+	}
+	root := merkle.Root(leaves)
+	p := &Proof{Key: key, Hash: entries[i].Hash, Index: i, Size: len(entries), Root: hex.EncodeToString(root[:]), Path: make([]string, len(path))}
+	for j, h := range path {
+		p.Path[j] = hex.EncodeToString(h[:])
+	}
+	return p, nil
+}
+
+// VerifyProof checks that p shows key pointing at hash in the tree with
+// the given hex root. It fails with merkle.ErrInvalidProof if not.
+func VerifyProof(root string, p *Proof, key, hash string) error {
+	if p.Key != key || p.Hash != hash {
+		return fmt.Errorf("%w: proof is for key %q at %s, not %q at %s", merkle.ErrInvalidProof, p.Key, p.Hash, key, hash)
+	}
+	want, err := decodeHash(root)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+	data, err := LeafData(key, hash)
+	if err != nil {
+		return err
+	}
+	path := make([]merkle.Hash, len(p.Path))
+	for i, s := range p.Path {
+		if path[i], err = decodeHash(s); err != nil {
+			return fmt.Errorf("%w: path entry %d: %v", merkle.ErrInvalidProof, i, err)
+		}
+	}
+	return merkle.VerifyInclusion(want, merkle.LeafHash(data), p.Index, p.Size, path)
+}
+
+func decodeHash(s string) (merkle.Hash, error) {
+	var h merkle.Hash
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(h) {
+		return h, fmt.Errorf("%q is not a hex SHA-256 hash", s)
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// Prover serves inclusion proofs with the checkpoint they check out
+// against. It implements store.Prover.
+type Prover struct {
+	Log *Log
+}
+
+// Prove returns the proof of key in s, with the newest checkpoint of s's
+// namespace whose root it leads to. It fails with store.ErrUncommitted if
+// the store has changed since its last checkpoint.
+func (pv Prover) Prove(ctx context.Context, s *store.Store, key string) (interface{}, error) {
+	p, err := GenerateProof(ctx, s, key)
+	if err != nil {
+		return nil, err
+	}
+	p.Checkpoint = pv.Log.Covering(s.TenantID(), p.Root, p.Size)
+	if p.Checkpoint == nil {
+		// The checkpoint may have been taken since the log was read.
+		if err := pv.Log.Reload(); err != nil {
+			return nil, err
+		}
+		p.Checkpoint = pv.Log.Covering(s.TenantID(), p.Root, p.Size)
+	}
+	if p.Checkpoint == nil {
+		return nil, fmt.Errorf("%w: no checkpoint has %d keys with root %s", store.ErrUncommitted, p.Size, p.Root)
+	}
+	return p, nil
+}
@@ -0,0 +1,111 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/merkle"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+func TestProofs(t *testing.T) {
+	ctx := context.Background()
+	s := store.New(store.NewMemory())
+	for i := 0; i < 7; i++ {
+		s.Put(ctx, memory(fmt.Sprintf("k%d", i), fmt.Sprint(i)))
+	}
+	snap, _ := Take(ctx, s)
+	for i := 0; i < 7; i++ {
+		key := fmt.Sprintf("k%d", i)
+		p, err := GenerateProof(ctx, s, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, _ := s.Resolve(ctx, key)
+		if p.Root != snap.Root || p.Index != i || p.Hash != e.Hash {
+			t.Errorf("proof of %s = %+v", key, p)
+		}
+		if err := VerifyProof(snap.Root, p, key, e.Hash); err != nil {
+			t.Errorf("VerifyProof(%s): %v", key, err)
+		}
+	}
+
+	p, _ := GenerateProof(ctx, s, "k3")
+	other, _ := s.Resolve(ctx, "k4")
+	if err := VerifyProof(snap.Root, p, "k3", other.Hash); !errors.Is(err, merkle.ErrInvalidProof) {
+		t.Errorf("proof verified for another hash: %v", err)
+	}
+	forged := *p
+	forged.Hash = other.Hash
+	if err := VerifyProof(snap.Root, &forged, "k3", other.Hash); !errors.Is(err, merkle.ErrInvalidProof) {
+		t.Errorf("forged proof verified: %v", err)
+	}
+	s.Put(ctx, memory("k9", "new"))
+	later, _ := Take(ctx, s)
+	if err := VerifyProof(later.Root, p, "k3", p.Hash); !errors.Is(err, merkle.ErrInvalidProof) {
+		t.Errorf("proof verified against a later root: %v", err)
+	}
+	if _, err := GenerateProof(ctx, s, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GenerateProof(missing): %v", err)
+	}
+}
+
+func TestGatewayProofs(t *testing.T) {
+	ctx := context.Background()
+	s := store.New(store.NewMemory())
+	s.Put(ctx, memory("a", "one"))
+	s.Put(ctx, memory("b", "two"))
+	signer := testSigner(t)
+	log, err := OpenLog(filepath.Join(t.TempDir(), "checkpoints.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(store.NewGateway(s, store.GatewayOptions{Proofs: Prover{Log: log}}))
+	defer srv.Close()
+	get := func(path string) (int, []byte) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	if code, body := get("/proofs/a"); code != http.StatusConflict {
+		t.Errorf("GET /proofs/a before a checkpoint: %d %s", code, body)
+	}
+	// A checkpoint appended by another process is picked up.
+	appender, _ := OpenLog(log.path)
+	snap, _ := Take(ctx, s)
+	c := New("test", snap, time.Now())
+	appender.Next(c)
+	c.Sign(signer)
+	if err := appender.Append(c); err != nil {
+		t.Fatal(err)
+	}
+
+	code, body := get("/proofs/b")
+	var p Proof
+	if err := json.Unmarshal(body, &p); err != nil || code != 200 || p.Checkpoint == nil {
+		t.Fatalf("GET /proofs/b: %d %s", code, body)
+	}
+	if err := p.Checkpoint.Verify(signer.Public()); err != nil {
+		t.Error(err)
+	}
+	if err := VerifyProof(p.Checkpoint.Root, &p, "b", p.Hash); err != nil {
+		t.Error(err)
+	}
+	if code, _ := get("/proofs/zz"); code != http.StatusNotFound {
+		t.Errorf("GET /proofs/zz: %d", code)
+	}
+}
@@ -0,0 +1,412 @@
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// Errors returned by Witness.Cosign.
+var (
+	// ErrUntrusted is returned for a checkpoint whose origin the witness
+	// does not watch, or that its origin did not sign.
+	ErrUntrusted = errors.New("witness: checkpoint is not signed by a trusted key for its origin")
+	// ErrInconsistent is returned for a checkpoint that contradicts one
+	// the witness has already cosigned.
+	ErrInconsistent = errors.New("witness: checkpoint is inconsistent with one already cosigned")
+	// ErrProofRequired is returned, as a *ProofRequiredError, for a
+	// checkpoint more than one ahead of the one last cosigned that came
+	// without the checkpoints in between.
+	ErrProofRequired = errors.New("witness: checkpoint skips ahead without the checkpoints in between")
+	// ErrClockSkew is returned for a checkpoint whose time is further
+	// from the witness's clock than WitnessOptions.MaxSkew.
+	ErrClockSkew = errors.New("witness: checkpoint time is too far from the witness's clock")
+)
+
+// DefaultMaxSkew is the WitnessOptions.MaxSkew used when it is zero.
+const DefaultMaxSkew = 5 * time.Minute
+
+// ProofRequiredError reports the checkpoint a witness last cosigned for a
+// log, after which it needs every checkpoint up to the one to cosign.
+type ProofRequiredError struct {
+	Log string
+	Seq uint64
+}
+
+func (e *ProofRequiredError) Error() string {
+	return fmt.Sprintf("%v: %s was last cosigned at checkpoint %d", ErrProofRequired, e.Log, e.Seq)
+}
+
+func (e *ProofRequiredError) Unwrap() error { return ErrProofRequired }
+
+// CosignRequest is the body of POST /cosign: the checkpoint to cosign
+// and, when it is more than one ahead of the one the witness last
+// cosigned, the checkpoints in between. A bare checkpoint is a request
+// without a proof.
+type CosignRequest struct {
+	Checkpoint
+	Proof []*Checkpoint `json:"proof,omitempty"`
+}
+
+// WitnessOptions configures NewWitness.
+type WitnessOptions struct {
+	Signer signing.Signer
+	// Origins maps each origin the witness watches to the keys that sign
+	// its checkpoints.
+	Origins map[string][]crypto.PublicKey
+	// StatePath is the file where the witness remembers the newest
+	// checkpoint it has cosigned for each origin and tenant.
+	StatePath string
+	// MaxSkew bounds how far a checkpoint's time may be from the
+	// witness's clock; zero means DefaultMaxSkew.
+	MaxSkew time.Duration
+}
+
+// witnessed is the newest checkpoint cosigned for one log. IDs lists
+// every cosigned issue of it: checkpoints re-issued with the same
+// predecessor, size, and root differ only in time, and any of them may be
+// the one the log kept.
+type witnessed struct {
+	Seq  uint64   `json:"seq"`
+	Prev string   `json:"prev,omitempty"`
+	Size int      `json:"size"`
+	Root string   `json:"root"`
+	IDs  []string `json:"ids"`
+}
+
+// reissue reports whether c commits to the same state as w.
+func (w witnessed) reissue(c *Checkpoint) bool {
+	return c.Seq == w.Seq && c.Prev == w.Prev && c.Size == w.Size && c.Root == w.Root
+}
+
+// follows reports whether c names one of w's issues as its predecessor.
+func (w witnessed) follows(c *Checkpoint) bool {
+	return slices.Contains(w.IDs, c.Prev)
+}
+
+// checkTime requires c's time to be within MaxSkew of now.
+func (w *Witness) checkTime(c *Checkpoint) error {
+	at, err := c.SignedAt()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrClockSkew, err)
+	}
+	skew := w.opts.MaxSkew
+	if skew == 0 {
+		skew = DefaultMaxSkew
+	}
+	if d := time.Since(at); d > skew || d < -skew {
+		return fmt.Errorf("%w: checkpoint %d is dated %s", ErrClockSkew, c.Seq, c.Time)
+	}
+	return nil
+}
+
+// extends checks that proof links w to c: it must hold every checkpoint
+// numbered between them, each signed by keys and naming its predecessor.
+func (w witnessed) extends(name string, c *Checkpoint, proof []*Checkpoint, keys []crypto.PublicKey) error {
+	if len(proof) == 0 && c.Seq > w.Seq+1 {
+		return &ProofRequiredError{Log: name, Seq: w.Seq}
+	}
+	links := append(append([]*Checkpoint(nil), proof...), c)
+	for i, p := range links {
+		if p.Seq != w.Seq+uint64(i)+1 {
+			return fmt.Errorf("%w: proof for checkpoint %d of %s holds checkpoint %d where %d belongs",
+				ErrInconsistent, c.Seq, name, p.Seq, w.Seq+uint64(i)+1)
+		}
+		if p.Origin != c.Origin || p.Verify(keys...) != nil {
+			return fmt.Errorf("%w: origin %q, checkpoint %d", ErrUntrusted, c.Origin, p.Seq)
+		}
+		if i == 0 && !w.follows(p) || i > 0 && p.Prev != links[i-1].ID() {
+			return fmt.Errorf("%w: checkpoint %d of %s does not follow checkpoint %d", ErrInconsistent, p.Seq, name, p.Seq-1)
+		}
+	}
+	return nil
+}
+
+// Witness cosigns the checkpoints of the stores it watches; it is meant to
+// be run by an independent party, such as the operator of another Helios
+// instance. It refuses to cosign a checkpoint that contradicts one it has
+// already cosigned for the same log, so an operator who shows different
+// logs to different verifiers, or rolls a log back, cannot gather enough
+// cosignatures for a verifier that requires k of n witnesses. Since it
+// also refuses a checkpoint whose time is not close to its own clock, its
+// cosignature vouches for the time as well, which a signer could
+// otherwise backdate.
+//
+// A Witness is an http.Handler serving POST /cosign, and is safe for
+// concurrent use.
+type Witness struct {
+	opts  WitnessOptions
+	mu    sync.Mutex
+	state map[string]witnessed
+}
+
+// NewWitness returns a witness, reading its state if the file exists.
+func NewWitness(opts WitnessOptions) (*Witness, error) {
+	w := &Witness{opts: opts, state: make(map[string]witnessed)}
+	data, err := os.ReadFile(opts.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &w.state); err != nil {
+		return nil, fmt.Errorf("witness state %s: %w", opts.StatePath, err)
+	}
+	return w, nil
+}
+
+// logName identifies the log of one namespace of one origin.
+func logName(c *Checkpoint) string {
+	if c.Tenant == "" {
+		return c.Origin
+	}
+	return c.Origin + "/" + c.Tenant
+}
+
+// Cosign checks that c is signed by its origin and consistent with the
+// newest checkpoint cosigned for its log, records it, and returns the
+// witness's signature over it. A checkpoint with the same number as the
+// recorded one must commit to the same state, so an origin can retry a
+// checkpoint that did not gather enough cosignatures. A checkpoint that
+// directly follows the recorded one must name it as Prev. One further
+// ahead needs proof that it extends the recorded one: the checkpoints in
+// between, in order, each signed by the origin and naming the one before
+// as Prev. Without them it fails with a *ProofRequiredError.
+func (w *Witness) Cosign(c *Checkpoint, proof ...*Checkpoint) (Signature, error) {
+	keys, ok := w.opts.Origins[c.Origin]
+	if !ok || c.Verify(keys...) != nil {
+		return Signature{}, fmt.Errorf("%w: origin %q", ErrUntrusted, c.Origin)
+	}
+	if err := w.checkTime(c); err != nil {
+		return Signature{}, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	name, id := logName(c), c.ID()
+	last, seen := w.state[name]
+	switch {
+	case !seen:
+	case c.Seq < last.Seq:
+		return Signature{}, fmt.Errorf("%w: %s is at checkpoint %d, not %d", ErrInconsistent, name, last.Seq, c.Seq)
+	case c.Seq == last.Seq && !last.reissue(c):
+		return Signature{}, fmt.Errorf("%w: checkpoint %d of %s was cosigned with %d keys and root %s, not %d and %s",
+			ErrInconsistent, c.Seq, name, last.Size, last.Root, c.Size, c.Root)
+	case c.Seq > last.Seq:
+		if err := last.extends(name, c, proof, keys); err != nil {
+			return Signature{}, err
+		}
+	}
+
+	sig, err := w.opts.Signer.Sign(c.Body())
+	if err != nil {
+		return Signature{}, fmt.Errorf("signing failed: %w", err)
+	}
+	next := witnessed{Seq: c.Seq, Prev: c.Prev, Size: c.Size, Root: c.Root, IDs: []string{id}}
+	if seen && c.Seq == last.Seq {
+		next = last
+		if !slices.Contains(last.IDs, id) {
+			next.IDs = append(append([]string(nil), last.IDs...), id)
+		}
+	}
+	w.state[name] = next
+	if err := w.save(); err != nil {
+		if seen {
+			w.state[name] = last
+		} else {
+			delete(w.state, name)
+		}
+		return Signature{}, fmt.Errorf("failed to record checkpoint: %w", err)
+	}
+	return Signature{KeyID: w.opts.Signer.KeyID(), Sig: base64.StdEncoding.EncodeToString(sig)}, nil
+}
+
+// save writes the state file through a temporary file, so a crash leaves
+// the old state or the new one.
+func (w *Witness) save() error {
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(w.opts.StatePath), ".witness-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), w.opts.StatePath)
+}
+
+// maxCheckpointBody bounds the size of a cosigning request.
+const maxCheckpointBody = 1 << 20
+
+// ServeHTTP answers POST /cosign, whose body is a CosignRequest, with the
+// witness's Signature as JSON. An untrusted checkpoint is refused with 403
+// WITNESS_ERR_UNTRUSTED, an inconsistent one with 409
+// WITNESS_ERR_INCONSISTENT, one that skips ahead without a proof with
+// 409 WITNESS_ERR_PROOF_REQUIRED, whose "seq" is the checkpoint the proof
+// must start after, and one dated too far from the witness's clock with
+// 422 WITNESS_ERR_CLOCK_SKEW.
+func (w *Witness) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/cosign" {
+		writeWitnessError(rw, http.StatusNotFound, "WITNESS_ERR_NOT_FOUND", "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		writeWitnessError(rw, http.StatusMethodNotAllowed, "WITNESS_ERR_METHOD", "use POST")
+		return
+	}
+	var req CosignRequest
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxCheckpointBody)).Decode(&req); err != nil {
+		writeWitnessError(rw, http.StatusBadRequest, "WITNESS_ERR_INVALID_CHECKPOINT", err.Error())
+		return
+	}
+	sig, err := w.Cosign(&req.Checkpoint, req.Proof...)
+	var pr *ProofRequiredError
+	switch {
+	case errors.As(err, &pr):
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusConflict)
+		json.NewEncoder(rw).Encode(map[string]interface{}{"code": "WITNESS_ERR_PROOF_REQUIRED", "error": err.Error(), "seq": pr.Seq})
+	case errors.Is(err, ErrUntrusted):
+		writeWitnessError(rw, http.StatusForbidden, "WITNESS_ERR_UNTRUSTED", err.Error())
+	case errors.Is(err, ErrInconsistent):
+		writeWitnessError(rw, http.StatusConflict, "WITNESS_ERR_INCONSISTENT", err.Error())
+	case errors.Is(err, ErrClockSkew):
+		writeWitnessError(rw, http.StatusUnprocessableEntity, "WITNESS_ERR_CLOCK_SKEW", err.Error())
+	case err != nil:
+		writeWitnessError(rw, http.StatusInternalServerError, "WITNESS_ERR_INTERNAL", err.Error())
+	default:
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(sig)
+	}
+}
+
+func writeWitnessError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "error": msg})
+}
+
+// RequestCosignature asks the witness at base URL to cosign c, proving
+// with proof that c extends the checkpoint it last cosigned, and returns
+// its signature, without adding it to c. A witness that needs a proof
+// starting elsewhere fails with a *ProofRequiredError.
+func RequestCosignature(ctx context.Context, client *http.Client, base string, c *Checkpoint, proof []*Checkpoint) (Signature, error) {
+	body, err := json.Marshal(CosignRequest{Checkpoint: *c, Proof: proof})
+	if err != nil {
+		return Signature{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(base, "/")+"/cosign", bytes.NewReader(body))
+	if err != nil {
+		return Signature{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return Signature{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckpointBody))
+	if err != nil {
+		return Signature{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code  string `json:"code"`
+			Error string `json:"error"`
+			Seq   uint64 `json:"seq"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = resp.Status
+		}
+		if e.Code == "WITNESS_ERR_PROOF_REQUIRED" {
+			return Signature{}, fmt.Errorf("witness %s: %w", base, &ProofRequiredError{Log: logName(c), Seq: e.Seq})
+		}
+		return Signature{}, fmt.Errorf("witness %s: %s", base, e.Error)
+	}
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return Signature{}, fmt.Errorf("witness %s: invalid response: %w", base, err)
+	}
+	return sig, nil
+}
+
+// Witness asks each of the witnesses at urls to cosign c and adds their
+// signatures. A witness that last cosigned an older checkpoint of log is
+// asked again with the checkpoints since, which must not yet include c.
+// It fails, adding none, unless at least threshold witnesses cosign; the
+// error lists every refusal.
+func (c *Checkpoint) Witness(ctx context.Context, client *http.Client, urls []string, threshold int, log *Log) error {
+	var sigs []Signature
+	var errs []error
+	for _, u := range urls {
+		sig, err := RequestCosignature(ctx, client, u, c, nil)
+		var pr *ProofRequiredError
+		if errors.As(err, &pr) && log != nil {
+			sig, err = RequestCosignature(ctx, client, u, c, log.Since(pr.Seq))
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) < threshold {
+		return fmt.Errorf("%d of %d witnesses cosigned checkpoint %d, %d required: %w", len(sigs), len(urls), c.Seq, threshold, errors.Join(errs...))
+	}
+	c.Signatures = append(c.Signatures, sigs...)
+	return nil
+}
+
+// VerifyWitnesses checks that at least threshold of witnesses have
+// signed c. Each witness key counts once however many of its signatures c
+// carries.
+func (c *Checkpoint) VerifyWitnesses(threshold int, witnesses ...crypto.PublicKey) error {
+	body := c.Body()
+	n := 0
+	counted := make(map[string]bool)
+	for _, k := range witnesses {
+		id, err := signing.KeyID(k)
+		if err != nil || counted[id] {
+			continue
+		}
+		counted[id] = true
+		for _, sig := range c.Signatures {
+			raw, err := base64.StdEncoding.DecodeString(sig.Sig)
+			if err == nil && signing.Verify(k, body, raw) == nil {
+				n++
+				break
+			}
+		}
+	}
+	if n < threshold {
+		return fmt.Errorf("checkpoint %d is cosigned by %d of %d trusted witnesses, %d required", c.Seq, n, len(counted), threshold)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package checkpoint
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+func newWitness(t *testing.T, origin signing.Signer) (*Witness, signing.Signer, string) {
+	t.Helper()
+	signer := testSigner(t)
+	state := filepath.Join(t.TempDir(), "witness.json")
+	w, err := NewWitness(WitnessOptions{
+		Signer:    signer,
+		Origins:   map[string][]crypto.PublicKey{"origin": {origin.Public()}},
+		StatePath: state,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, signer, state
+}
+
+// chain returns signed checkpoints 1..n of one log.
+func chain(origin signing.Signer, n int) []*Checkpoint {
+	var out []*Checkpoint
+	for i := 1; i <= n; i++ {
+		c := New("origin", Snapshot{Size: i, Root: strings.Repeat("ab", 32)}, time.Now())
+		c.Seq = uint64(i)
+		if i > 1 {
+			c.Prev = out[i-2].ID()
+		}
+		c.Sign(origin)
+		out = append(out, c)
+	}
+	return out
+}
+
+func TestWitnessCosign(t *testing.T) {
+	origin := testSigner(t)
+	w, signer, state := newWitness(t, origin)
+	cps := chain(origin, 4)
+
+	// Skipping ahead needs the checkpoints in between.
+	if _, err := w.Cosign(cps[0]); err != nil {
+		t.Fatal(err)
+	}
+	var pr *ProofRequiredError
+	if _, err := w.Cosign(cps[2]); !errors.As(err, &pr) || pr.Seq != 1 {
+		t.Fatalf("Cosign(3) without a proof: %v", err)
+	}
+	if _, err := w.Cosign(cps[2], cps[2]); !errors.Is(err, ErrInconsistent) {
+		t.Errorf("Cosign(3) with a proof missing checkpoint 2: %v", err)
+	}
+	forged := *cps[1]
+	forged.Root = strings.Repeat("cd", 32)
+	if _, err := w.Cosign(cps[2], &forged); err == nil {
+		t.Error("Cosign(3) accepted a proof the origin did not sign")
+	}
+	for _, i := range []int{1, 3} {
+		sig, err := w.Cosign(cps[i], cps[2:max(i, 2)]...)
+		if err != nil {
+			t.Fatalf("Cosign(%d): %v", i+1, err)
+		}
+		cps[i].Signatures = append(cps[i].Signatures, sig)
+		if err := cps[i].VerifyWitnesses(1, signer.Public()); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Going back, or a second checkpoint 4 with another root, is refused.
+	if _, err := w.Cosign(cps[2]); !errors.Is(err, ErrInconsistent) {
+		t.Errorf("Cosign of an older checkpoint: %v", err)
+	}
+	fork := *cps[3]
+	fork.Root = strings.Repeat("cd", 32)
+	fork.Signatures = nil
+	fork.Sign(origin)
+	if _, err := w.Cosign(&fork); !errors.Is(err, ErrInconsistent) {
+		t.Errorf("Cosign of a fork: %v", err)
+	}
+	// A re-issue of checkpoint 4 at another time is not a fork.
+	reissue := *cps[3]
+	reissue.Time = time.Now().Add(time.Second).UTC().Format("2006-01-02T15:04:05.000Z")
+	reissue.Signatures = nil
+	reissue.Sign(origin)
+	if _, err := w.Cosign(&reissue); err != nil {
+		t.Errorf("Cosign of a re-issue: %v", err)
+	}
+
+	// The state survives a restart, and either issue may be followed.
+	w2, err := NewWitness(WitnessOptions{Signer: signer, Origins: w.opts.Origins, StatePath: state})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := New("origin", Snapshot{Size: 5, Root: strings.Repeat("ab", 32)}, time.Now())
+	next.Seq, next.Prev = 5, reissue.ID()
+	next.Sign(origin)
+	if _, err := w2.Cosign(next); err != nil {
+		t.Errorf("Cosign after restart: %v", err)
+	}
+	bad := New("origin", Snapshot{Size: 6}, time.Now())
+	bad.Seq, bad.Prev = 6, "nope"
+	bad.Sign(origin)
+	if _, err := w2.Cosign(bad); !errors.Is(err, ErrInconsistent) {
+		t.Errorf("Cosign of a checkpoint that does not chain: %v", err)
+	}
+
+	// A checkpoint dated away from the witness's clock is refused, so a
+	// cosignature vouches for its time.
+	late := New("origin", Snapshot{Size: 6}, time.Now().Add(-time.Hour))
+	late.Seq, late.Prev = 6, next.ID()
+	late.Sign(origin)
+	if _, err := w2.Cosign(late); !errors.Is(err, ErrClockSkew) {
+		t.Errorf("Cosign of a backdated checkpoint: %v", err)
+	}
+
+	stranger := chain(testSigner(t), 1)[0]
+	if _, err := w2.Cosign(stranger); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("Cosign of an untrusted checkpoint: %v", err)
+	}
+}
+
+func TestWitnessThreshold(t *testing.T) {
+	ctx := context.Background()
+	origin := testSigner(t)
+	var urls []string
+	var pubs []crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		w, signer, _ := newWitness(t, origin)
+		srv := httptest.NewServer(w)
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+		pubs = append(pubs, signer.Public())
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	c := chain(origin, 1)[0]
+	if err := c.Witness(ctx, http.DefaultClient, append(urls[:1:1], down.URL), 2, nil); err == nil {
+		t.Fatal("met a threshold of 2 with one witness")
+	}
+	if len(c.Signatures) != 1 {
+		t.Fatalf("a failed Witness added signatures: %d", len(c.Signatures))
+	}
+	if err := c.Witness(ctx, http.DefaultClient, append(urls[:2:2], down.URL), 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.VerifyWitnesses(2, pubs...); err != nil {
+		t.Error(err)
+	}
+	if err := c.VerifyWitnesses(3, pubs...); err == nil {
+		t.Error("verified 3 of 3 with two cosignatures")
+	}
+	// A key listed twice counts once.
+	if err := c.VerifyWitnesses(2, pubs[0], pubs[0]); err == nil {
+		t.Error("counted one witness twice")
+	}
+	if err := c.Verify(origin.Public()); err != nil {
+		t.Errorf("cosignatures broke the origin signature: %v", err)
+	}
+
+	resp, err := http.Get(urls[0] + "/cosign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /cosign: %d", resp.StatusCode)
+	}
+}
+
+func TestWitnessCatchesUpFromLog(t *testing.T) {
+	ctx := context.Background()
+	origin := testSigner(t)
+	w, signer, _ := newWitness(t, origin)
+	srv := httptest.NewServer(w)
+	defer srv.Close()
+
+	log, err := OpenLog(filepath.Join(t.TempDir(), "log.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cps := chain(origin, 4)
+	if err := cps[0].Witness(ctx, http.DefaultClient, []string{srv.URL}, 1, log); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cps[:3] {
+		if err := log.Append(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without the log the witness cannot tell checkpoint 4 extends 1.
+	if err := cps[3].Witness(ctx, http.DefaultClient, []string{srv.URL}, 1, nil); !errors.Is(err, ErrProofRequired) {
+		t.Errorf("Witness without a log: %v", err)
+	}
+	if err := cps[3].Witness(ctx, http.DefaultClient, []string{srv.URL}, 1, log); err != nil {
+		t.Fatal(err)
+	}
+	if err := cps[3].VerifyWitnesses(1, signer.Public()); err != nil {
+		t.Error(err)
+	}
+}
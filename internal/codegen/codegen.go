@@ -0,0 +1,239 @@
+// Package codegen exports the conformance vectors as ready-to-run test
+// fixtures for other language implementations, so ports are generated
+// from the same vectors.json instead of transcribed by hand.
+//
+// Each fixture passes the raw JSON text of a vector input to the port,
+// preserving key order and number spelling (1.0 stays a float), and checks
+// either the expected hash or that the rejection message carries the
+// expected CANON_ERR_ code.
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// generator renders fixtures for one language.
+type generator func(src Source, opts Options) []byte
+
+var generators = map[string]generator{
+	"python": genPython,
+	"jest":   genJest,
+	"rust":   genRust,
+}
+
+// Languages returns the supported target names.
+func Languages() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options customize the generated code.
+type Options struct {
+	// Module is the import path of the implementation under test for
+	// targets that import it (jest). Empty selects the target's default.
+	Module string
+}
+
+// Fixture is one vector reduced to what a test needs.
+type Fixture struct {
+	ID       string
+	Input    string // compact JSON text, original key order
+	Positive bool
+	Hash     string // expected hash, positive vectors only
+	Code     string // expected rejection code, negative vectors only
+}
+
+// Source is the parsed vector corpus.
+type Source struct {
+	SpecVersion    string
+	VectorsVersion string
+	Fixtures       []Fixture
+}
+
+// Parse reads a vectors.json file into fixtures.
+func Parse(data []byte) (Source, error) {
+	var vf struct {
+		SpecVersion    string `json:"spec_version"`
+		VectorsVersion string `json:"vectors_version"`
+		Vectors        []struct {
+			VectorID      string          `json:"vector_id"`
+			VectorType    string          `json:"vector_type"`
+			Input         json.RawMessage `json:"input"`
+			Hash          string          `json:"hash"`
+			RejectionCode *string         `json:"rejection_code"`
+		} `json:"vectors"`
+	}
+	if err := json.Unmarshal(data, &vf); err != nil {
+		return Source{}, fmt.Errorf("failed to parse vectors file: %w", err)
+	}
+	src := Source{SpecVersion: vf.SpecVersion, VectorsVersion: vf.VectorsVersion}
+	for _, v := range vf.Vectors {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, v.Input); err != nil {
+			return Source{}, fmt.Errorf("vector %s: %w", v.VectorID, err)
+		}
+		f := Fixture{ID: v.VectorID, Input: buf.String(), Positive: v.VectorType != "negative"}
+		if f.Positive {
+			f.Hash = v.Hash
+		} else if v.RejectionCode != nil {
+			f.Code = *v.RejectionCode
+		}
+		src.Fixtures = append(src.Fixtures, f)
+	}
+	return src, nil
+}
+
+// Generate renders fixtures for lang.
+func Generate(src Source, lang string, opts Options) ([]byte, error) {
+	gen, ok := generators[lang]
+	if !ok {
+		return nil, fmt.Errorf("unknown language %q (supported: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	return gen(src, opts), nil
+}
+
+func header(comment string, src Source) string {
+	return fmt.Sprintf("%s Code generated by helios export-vectors from vectors v%s (spec %s). DO NOT EDIT.\n",
+		comment, src.VectorsVersion, src.SpecVersion)
+}
+
+// quote renders s as a double-quoted string literal valid in Python,
+// JavaScript, and TypeScript.
+func quote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func genPython(src Source, _ Options) []byte {
+	var b strings.Builder
+	b.WriteString(header("#", src))
+	b.WriteString(`import json
+
+import pytest
+
+from conformance.hasher import content_hash
+from conformance.verifier import input_to_memory_object
+
+POSITIVE = [
+`)
+	for _, f := range src.Fixtures {
+		if f.Positive {
+			fmt.Fprintf(&b, "    pytest.param(%s, %s, id=%s),\n", quote(f.Input), quote(f.Hash), quote(f.ID))
+		}
+	}
+	b.WriteString("]\n\nNEGATIVE = [\n")
+	for _, f := range src.Fixtures {
+		if !f.Positive {
+			fmt.Fprintf(&b, "    pytest.param(%s, %s, id=%s),\n", quote(f.Input), quote(f.Code), quote(f.ID))
+		}
+	}
+	b.WriteString(`]
+
+
+@pytest.mark.parametrize("raw, expected", POSITIVE)
+def test_positive_vector(raw, expected):
+    assert content_hash(input_to_memory_object(json.loads(raw))) == expected
+
+
+@pytest.mark.parametrize("raw, code", NEGATIVE)
+def test_negative_vector(raw, code):
+    with pytest.raises(Exception, match=code):
+        content_hash(input_to_memory_object(json.loads(raw)))
+`)
+	return []byte(b.String())
+}
+
+func genJest(src Source, opts Options) []byte {
+	module := opts.Module
+	if module == "" {
+		module = "../src"
+	}
+	var b strings.Builder
+	b.WriteString(header("//", src))
+	b.WriteString("//\n// hashInput(raw) must parse the raw JSON text itself (JSON.parse cannot\n")
+	b.WriteString("// tell 1.0 from 1) and throw an error whose message contains the code.\n")
+	fmt.Fprintf(&b, "import { hashInput } from %s;\n\nconst positive = [\n", quote(module))
+	for _, f := range src.Fixtures {
+		if f.Positive {
+			fmt.Fprintf(&b, "  [%s, %s, %s],\n", quote(f.ID), quote(f.Input), quote(f.Hash))
+		}
+	}
+	b.WriteString("];\n\nconst negative = [\n")
+	for _, f := range src.Fixtures {
+		if !f.Positive {
+			fmt.Fprintf(&b, "  [%s, %s, %s],\n", quote(f.ID), quote(f.Input), quote(f.Code))
+		}
+	}
+	b.WriteString(`];
+
+test.each(positive)("%s hashes", (_id, raw, expected) => {
+  expect(hashInput(raw)).toBe(expected);
+});
+
+test.each(negative)("%s is rejected", (_id, raw, code) => {
+  expect(() => hashInput(raw)).toThrow(code);
+});
+`)
+	return []byte(b.String())
+}
+
+func genRust(src Source, _ Options) []byte {
+	var b strings.Builder
+	b.WriteString(header("//", src))
+	b.WriteString(`//
+// Include from a test module that brings hash_input into scope:
+//
+//     #[cfg(test)]
+//     mod vectors {
+//         use super::hash_input; // fn(&str) -> Result<String, impl Display>
+//         include!("vectors.rs");
+//     }
+`)
+	for _, f := range src.Fixtures {
+		fmt.Fprintf(&b, "\n#[test]\nfn %s() {\n", rustIdent(f.ID))
+		if f.Positive {
+			fmt.Fprintf(&b, "    let got = hash_input(%s).expect(\"vector should be accepted\");\n", rustRaw(f.Input))
+			fmt.Fprintf(&b, "    assert_eq!(got, %q);\n", f.Hash)
+		} else {
+			fmt.Fprintf(&b, "    let err = hash_input(%s).expect_err(\"vector should be rejected\");\n", rustRaw(f.Input))
+			fmt.Fprintf(&b, "    assert!(err.to_string().contains(%q), \"got {}\", err);\n", f.Code)
+		}
+		b.WriteString("}\n")
+	}
+	return []byte(b.String())
+}
+
+// rustIdent turns a vector ID into a snake_case test function name.
+func rustIdent(id string) string {
+	var b strings.Builder
+	b.WriteString("vector_")
+	for _, r := range strings.ToLower(id) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// rustRaw renders s as a raw string literal with enough #s to be unambiguous.
+func rustRaw(s string) string {
+	hashes := 1
+	for strings.Contains(s, "\""+strings.Repeat("#", hashes)) {
+		hashes++
+	}
+	h := strings.Repeat("#", hashes)
+	return "r" + h + "\"" + s + "\"" + h
+}
@@ -0,0 +1,79 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadSource(t *testing.T) Source {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "test_vectors", "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func TestParseKeepsRawInput(t *testing.T) {
+	src := loadSource(t)
+	if len(src.Fixtures) != 17 {
+		t.Fatalf("expected 17 fixtures, got %d", len(src.Fixtures))
+	}
+	for _, f := range src.Fixtures {
+		switch f.ID {
+		case "POS-002":
+			// Key-ordering vector must not be re-sorted
+			if !strings.HasPrefix(f.Input, `{"_helios_schema_version":"1","value":`) {
+				t.Errorf("POS-002 input was reordered: %s", f.Input)
+			}
+		case "NEG-001":
+			if f.Positive || f.Code != "CANON_ERR_FLOAT_PROHIBITED" {
+				t.Errorf("unexpected NEG-001 fixture: %+v", f)
+			}
+		}
+	}
+}
+
+func TestGenerateAllLanguages(t *testing.T) {
+	src := loadSource(t)
+	for _, lang := range Languages() {
+		out, err := Generate(src, lang, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := string(out)
+		if !strings.Contains(text, "DO NOT EDIT") {
+			t.Errorf("%s: missing generated-code header", lang)
+		}
+		for _, f := range src.Fixtures {
+			want := f.Hash
+			if !f.Positive {
+				want = f.Code
+			}
+			if !strings.Contains(text, want) {
+				t.Errorf("%s: fixture %s expectation %q missing", lang, f.ID, want)
+			}
+		}
+	}
+	if _, err := Generate(src, "cobol", Options{}); err == nil {
+		t.Error("expected error for unknown language")
+	}
+}
+
+func TestRustRawString(t *testing.T) {
+	if got := rustRaw(`{"a":"b"}`); got != `r#"{"a":"b"}"#` {
+		t.Errorf("unexpected raw string %s", got)
+	}
+	if got := rustRaw(`{"a":"#"}`); got != `r##"{"a":"#"}"##` {
+		t.Errorf("expected two hashes, got %s", got)
+	}
+	if got := rustIdent("NEG-001"); got != "vector_neg_001" {
+		t.Errorf("unexpected identifier %s", got)
+	}
+}
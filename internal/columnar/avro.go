@@ -0,0 +1,419 @@
+package columnar
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/snappy"
+)
+
+// avroMagic starts every Avro object container file.
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+// avroSchema is a parsed Avro schema node.
+type avroSchema struct {
+	Type        string // primitive name, "record", "enum", "array", "map", "fixed", or "union"
+	Name        string
+	LogicalType string
+	Fields      []avroField
+	Symbols     []string
+	Items       *avroSchema // array items or map values
+	Branches    []*avroSchema
+	Size        int
+}
+
+type avroField struct {
+	Name   string
+	Schema *avroSchema
+}
+
+// parseAvroSchema resolves a schema JSON document, including references
+// to previously defined named types.
+func parseAvroSchema(data []byte) (*avroSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("avro: invalid schema: %w", err)
+	}
+	return (&avroSchemaParser{named: map[string]*avroSchema{}}).parse(raw, "")
+}
+
+type avroSchemaParser struct {
+	named map[string]*avroSchema
+}
+
+func (p *avroSchemaParser) parse(raw interface{}, namespace string) (*avroSchema, error) {
+	switch v := raw.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroSchema{Type: v}, nil
+		}
+		if s, ok := p.named[v]; ok {
+			return s, nil
+		}
+		if s, ok := p.named[fullName(v, namespace)]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("avro: unknown type %q", v)
+	case []interface{}:
+		s := &avroSchema{Type: "union"}
+		for _, b := range v {
+			bs, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.Branches = append(s.Branches, bs)
+		}
+		return s, nil
+	case map[string]interface{}:
+		typ, _ := v["type"].(string)
+		name, _ := v["name"].(string)
+		if ns, ok := v["namespace"].(string); ok {
+			namespace = ns
+		}
+		s := &avroSchema{Type: typ, Name: name}
+		s.LogicalType, _ = v["logicalType"].(string)
+		switch typ {
+		case "record", "error":
+			s.Type = "record"
+			p.named[fullName(name, namespace)] = s
+			fields, _ := v["fields"].([]interface{})
+			for _, f := range fields {
+				fm, ok := f.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("avro: invalid field in record %s", name)
+				}
+				fname, _ := fm["name"].(string)
+				fs, err := p.parse(fm["type"], namespace)
+				if err != nil {
+					return nil, err
+				}
+				s.Fields = append(s.Fields, avroField{Name: fname, Schema: fs})
+			}
+		case "enum":
+			p.named[fullName(name, namespace)] = s
+			syms, _ := v["symbols"].([]interface{})
+			for _, sym := range syms {
+				str, _ := sym.(string)
+				s.Symbols = append(s.Symbols, str)
+			}
+		case "fixed":
+			p.named[fullName(name, namespace)] = s
+			size, _ := v["size"].(float64)
+			s.Size = int(size)
+		case "array", "map":
+			key := "items"
+			if typ == "map" {
+				key = "values"
+			}
+			items, err := p.parse(v[key], namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.Items = items
+		default:
+			// A primitive with attributes, e.g. {"type": "long", "logicalType": ...}
+			prim, err := p.parse(typ, namespace)
+			if err != nil {
+				return nil, err
+			}
+			cp := *prim
+			cp.LogicalType = s.LogicalType
+			return &cp, nil
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("avro: invalid schema node %T", raw)
+}
+
+func fullName(name, namespace string) string {
+	if namespace == "" || bytes.ContainsRune([]byte(name), '.') {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// readAvro decodes every record in an object container file. Values use
+// the ingest model: strings, bools, json.Number integers, float64 floats,
+// maps, slices, and nil.
+func readAvro(data []byte) ([]map[string]interface{}, error) {
+	if !bytes.HasPrefix(data, avroMagic) {
+		return nil, fmt.Errorf("avro: not an object container file")
+	}
+	d := &avroDecoder{buf: data, pos: len(avroMagic)}
+
+	meta := map[string][]byte{}
+	for {
+		count, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			count = -count
+			if _, err := d.long(); err != nil {
+				return nil, err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			k, err := d.bytes()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.bytes()
+			if err != nil {
+				return nil, err
+			}
+			meta[string(k)] = v
+		}
+	}
+	sync, err := d.fixed(16)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := parseAvroSchema(meta["avro.schema"])
+	if err != nil {
+		return nil, err
+	}
+	if schema.Type != "record" {
+		return nil, fmt.Errorf("avro: top-level schema must be a record, got %s", schema.Type)
+	}
+	codec := string(meta["avro.codec"])
+
+	var rows []map[string]interface{}
+	for d.pos < len(d.buf) {
+		count, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		size, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		block, err := d.fixed(int(size))
+		if err != nil {
+			return nil, err
+		}
+		if block, err = avroDecompress(codec, block); err != nil {
+			return nil, err
+		}
+		marker, err := d.fixed(16)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(marker, sync) {
+			return nil, fmt.Errorf("avro: sync marker mismatch")
+		}
+
+		bd := &avroDecoder{buf: block}
+		for i := int64(0); i < count; i++ {
+			v, err := bd.value(schema)
+			if err != nil {
+				return nil, fmt.Errorf("avro: record %d: %w", len(rows), err)
+			}
+			rows = append(rows, v.(map[string]interface{}))
+		}
+	}
+	return rows, nil
+}
+
+func avroDecompress(codec string, block []byte) ([]byte, error) {
+	switch codec {
+	case "", "null":
+		return block, nil
+	case "deflate":
+		out, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(block)), maxDecodedSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("avro: deflate: %w", err)
+		}
+		if len(out) > maxDecodedSize {
+			return nil, fmt.Errorf("avro: block exceeds %d bytes", maxDecodedSize)
+		}
+		return out, nil
+	case "snappy":
+		if len(block) < 4 {
+			return nil, fmt.Errorf("avro: truncated snappy block")
+		}
+		out, err := snappy.Decode(block[:len(block)-4], maxDecodedSize)
+		if err != nil {
+			return nil, fmt.Errorf("avro: %w", err)
+		}
+		if crc32.ChecksumIEEE(out) != binary.BigEndian.Uint32(block[len(block)-4:]) {
+			return nil, fmt.Errorf("avro: snappy block checksum mismatch")
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("avro: unsupported codec %q (supported: null, deflate, snappy)", codec)
+}
+
+type avroDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *avroDecoder) long() (int64, error) {
+	v, n := binary.Varint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("avro: invalid varint")
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *avroDecoder) fixed(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, fmt.Errorf("avro: unexpected end of data")
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	return d.fixed(int(n))
+}
+
+// blocks iterates the block-encoded items of an array or map.
+func (d *avroDecoder) blocks(item func() error) error {
+	for {
+		count, err := d.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		if count > int64(len(d.buf)-d.pos) {
+			return fmt.Errorf("avro: block count exceeds data")
+		}
+		for i := int64(0); i < count; i++ {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *avroDecoder) value(s *avroSchema) (interface{}, error) {
+	switch s.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.fixed(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		v, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		switch s.LogicalType {
+		case "timestamp-millis":
+			return formatTimestamp(time.UnixMilli(v)), nil
+		case "timestamp-micros":
+			return formatTimestamp(time.UnixMicro(v)), nil
+		}
+		return json.Number(fmt.Sprint(v)), nil
+	case "float":
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case "double":
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case "string":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "fixed":
+		b, err := d.fixed(s.Size)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case "enum":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.Symbols) {
+			return nil, fmt.Errorf("enum index %d out of range", i)
+		}
+		return s.Symbols[i], nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.Branches) {
+			return nil, fmt.Errorf("union branch %d out of range", i)
+		}
+		return d.value(s.Branches[i])
+	case "record":
+		m := make(map[string]interface{}, len(s.Fields))
+		for _, f := range s.Fields {
+			v, err := d.value(f.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			m[f.Name] = v
+		}
+		return m, nil
+	case "array":
+		list := []interface{}{}
+		err := d.blocks(func() error {
+			v, err := d.value(s.Items)
+			list = append(list, v)
+			return err
+		})
+		return list, err
+	case "map":
+		m := map[string]interface{}{}
+		err := d.blocks(func() error {
+			k, err := d.bytes()
+			if err != nil {
+				return err
+			}
+			v, err := d.value(s.Items)
+			m[string(k)] = v
+			return err
+		})
+		return m, err
+	}
+	return nil, fmt.Errorf("unsupported type %s", s.Type)
+}
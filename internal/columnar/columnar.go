@@ -0,0 +1,211 @@
+// Package columnar ingests memory objects from columnar data lake exports,
+// Avro object container files and Parquet files, so they can be hashed in
+// bulk without first converting each row to a JSON document.
+//
+// Each row becomes one memory object. A Mapping names the source column for
+// each Helios field and which text columns hold JSON (typically value and
+// relationships). Rows are validated with the same ingest rules as JSON:
+// float and null values are rejected, integers become json.Number, and
+// timestamp columns are rendered as YYYY-MM-DDTHH:MM:SS.sssZ.
+package columnar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// maxDecodedSize bounds any single decompressed block or page.
+const maxDecodedSize = 256 << 20
+
+// Fields are the memory object fields a Mapping can populate.
+var Fields = []string{
+	"category", "created_at", "key", "relationships", "source", "value",
+	"updated_at", "version", "access_count", "last_accessed", "confidence",
+	"tenant", "supersedes", "commitment_salt", "residency", "legal_hold",
+}
+
+// Mapping selects the source columns for memory object fields.
+type Mapping struct {
+	// Columns maps a field name to a column name. Fields without an entry
+	// are read from the column of the same name, if present.
+	Columns map[string]string
+	// JSON lists columns whose string contents are parsed as JSON. Parquet
+	// columns annotated as JSON are always parsed.
+	JSON []string
+}
+
+// DefaultMapping reads each field from the column of the same name and
+// parses a string relationships column as JSON.
+func DefaultMapping() Mapping {
+	return Mapping{JSON: []string{"relationships"}}
+}
+
+// ParseMapping parses "field=column" pairs.
+func ParseMapping(pairs []string) (map[string]string, error) {
+	known := map[string]bool{}
+	for _, f := range Fields {
+		known[f] = true
+	}
+	cols := map[string]string{}
+	for _, p := range pairs {
+		field, col, ok := strings.Cut(p, "=")
+		if !ok || col == "" {
+			return nil, fmt.Errorf("invalid column mapping %q: expected field=column", p)
+		}
+		if !known[field] {
+			return nil, fmt.Errorf("invalid column mapping %q: unknown field %s", p, field)
+		}
+		cols[field] = col
+	}
+	return cols, nil
+}
+
+// Format identifies a columnar file format.
+type Format int
+
+const (
+	None Format = iota
+	Avro
+	Parquet
+)
+
+// Detect reports the columnar format of the file at path from its magic
+// bytes. Directories and other files report None.
+func Detect(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return None, err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		return None, err
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(f, head); err != nil {
+		return None, nil
+	}
+	switch {
+	case bytes.Equal(head, avroMagic):
+		return Avro, nil
+	case bytes.Equal(head, parquetMagic):
+		return Parquet, nil
+	}
+	return None, nil
+}
+
+// Files lists the *.avro and *.parquet files under dir in path order,
+// skipping hidden directories.
+func Files(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(p); ext == ".avro" || ext == ".parquet" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Load reads every row of an Avro or Parquet file as a memory object.
+// Origins have the form "path#row". Loading stops at the first invalid row.
+func Load(path string, m Mapping) ([]ingest.Record, error) {
+	format, err := Detect(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus file: %w", err)
+	}
+
+	var rows []map[string]interface{}
+	switch format {
+	case Avro:
+		rows, err = readAvro(data)
+	case Parquet:
+		rows, err = readParquet(data)
+	default:
+		return nil, fmt.Errorf("%s: not an Avro or Parquet file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	records := make([]ingest.Record, 0, len(rows))
+	for i, row := range rows {
+		origin := fmt.Sprintf("%s#%d", path, i)
+		input, err := m.apply(row)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", origin, err)
+		}
+		if err := ingest.ValidateInput(input); err != nil {
+			return nil, fmt.Errorf("%s: %w", origin, err)
+		}
+		records = append(records, ingest.Record{Origin: origin, Object: ingest.ToMemoryObject(input)})
+	}
+	return records, nil
+}
+
+// apply builds the raw input map for one row. Null columns are omitted,
+// except value, whose null is rejected by ingest validation.
+func (m Mapping) apply(row map[string]interface{}) (map[string]interface{}, error) {
+	isJSON := map[string]bool{}
+	for _, c := range m.JSON {
+		isJSON[c] = true
+	}
+	input := map[string]interface{}{}
+	for _, field := range Fields {
+		col := field
+		if c, ok := m.Columns[field]; ok {
+			col = c
+		}
+		v, ok := row[col]
+		if !ok {
+			if _, mapped := m.Columns[field]; mapped {
+				return nil, fmt.Errorf("column %s (mapped to %s) not found", col, field)
+			}
+			continue
+		}
+		if s, isString := v.(string); isString && isJSON[col] {
+			parsed, err := ingest.Decode([]byte(s))
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col, err)
+			}
+			v = parsed
+		}
+		if v == nil && field != "value" {
+			continue
+		}
+		input[field] = v
+	}
+	return input, nil
+}
+
+// formatTimestamp renders t in the canonical form. Sub-millisecond
+// precision is kept, so hashing rejects it instead of silently truncating.
+func formatTimestamp(t time.Time) string {
+	t = t.UTC()
+	if t.Nanosecond()%int(time.Millisecond) != 0 {
+		return t.Format("2006-01-02T15:04:05.000000000Z")
+	}
+	return t.Format("2006-01-02T15:04:05.000Z")
+}
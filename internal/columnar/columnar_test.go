@@ -0,0 +1,176 @@
+package columnar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// The fixtures in testdata were written by independent Avro and Parquet
+// implementations (linkedin/goavro and parquet-go) by testdata/gen from the
+// rows below.
+var avroExpected = []string{
+	`{"category":"project","created_at":"2025-01-15T10:30:00.000Z","key":"lake/one","relationships":[{"key":"lake/two","type":"related_to"}],"source":"lake","value":"first"}`,
+	`{"category":"project","created_at":"2025-01-15T10:30:00.123Z","key":"lake/two","relationships":[],"source":"lake","value":"second — café"}`,
+	`{"category":"note","created_at":"2025-02-01T00:00:00.000Z","key":"lake/three","relationships":[],"source":"lake","value":{"n":42,"tags":["a","b"]}}`,
+}
+
+var parquetExpected = []string{
+	avroExpected[0],
+	avroExpected[1],
+	`{"category":"note","created_at":"2025-02-01T00:00:00.000Z","key":"lake/three","relationships":[],"source":"lake","value":"third"}`,
+}
+
+func expectHashes(t *testing.T, records []ingest.Record, expected []string) {
+	t.Helper()
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+	for i, doc := range expected {
+		want, err := ingest.ParseObject([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantHash, _ := hash.ContentHash(want)
+		got, err := hash.ContentHash(records[i].Object)
+		if err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+		if got != wantHash {
+			t.Errorf("row %d (%s): hash %s, want %s\n  got object: %+v", i, records[i].Origin, got, wantHash, records[i].Object)
+		}
+	}
+}
+
+func TestLoadAvroCodecs(t *testing.T) {
+	m := Mapping{Columns: map[string]string{"value": "payload"}, JSON: []string{"payload"}}
+	for _, name := range []string{"memories.avro", "memories-deflate.avro", "memories-snappy.avro"} {
+		t.Run(name, func(t *testing.T) {
+			records, err := Load(filepath.Join("testdata", name), m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectHashes(t, records, avroExpected)
+			if records[0].Object.UpdatedAt != "2025-03-01T00:00:00.000Z" {
+				t.Errorf("expected updated_at from union column, got %q", records[0].Object.UpdatedAt)
+			}
+		})
+	}
+}
+
+func TestLoadParquetVariants(t *testing.T) {
+	for _, name := range []string{"memories-v1.parquet", "memories-v2-snappy.parquet", "memories-gzip.parquet", "memories-delta.parquet"} {
+		t.Run(name, func(t *testing.T) {
+			records, err := Load(filepath.Join("testdata", name), DefaultMapping())
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectHashes(t, records, parquetExpected)
+			if records[2].Object.Version != 7 {
+				t.Errorf("expected version 7, got %d", records[2].Object.Version)
+			}
+			if records[1].Origin != filepath.Join("testdata", name)+"#1" {
+				t.Errorf("unexpected origin %s", records[1].Origin)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsFloatValue(t *testing.T) {
+	_, err := Load(filepath.Join("testdata", "float.parquet"), DefaultMapping())
+	if err == nil || !strings.Contains(err.Error(), "CANON_ERR_FLOAT_PROHIBITED") {
+		t.Errorf("expected float rejection, got %v", err)
+	}
+}
+
+func TestMappingErrors(t *testing.T) {
+	if _, err := ParseMapping([]string{"value"}); err == nil {
+		t.Error("expected error for pair without '='")
+	}
+	if _, err := ParseMapping([]string{"bogus=col"}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+	m := Mapping{Columns: map[string]string{"value": "missing"}}
+	if _, err := Load(filepath.Join("testdata", "memories.avro"), m); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected missing column error, got %v", err)
+	}
+}
+
+func TestDetectAndFiles(t *testing.T) {
+	cases := map[string]Format{"memories.avro": Avro, "memories-v1.parquet": Parquet}
+	for name, want := range cases {
+		if got, err := Detect(filepath.Join("testdata", name)); err != nil || got != want {
+			t.Errorf("%s: expected format %d, got %d (%v)", name, want, got, err)
+		}
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{}`), 0644)
+	if got, _ := Detect(filepath.Join(dir, "a.json")); got != None {
+		t.Errorf("expected None for JSON, got %d", got)
+	}
+	files, err := Files("testdata")
+	if err != nil || len(files) != 8 {
+		t.Errorf("expected 8 fixture files, got %v (%v)", files, err)
+	}
+}
+
+func TestParquetRejectsMalformedCounts(t *testing.T) {
+	// A bit-packed run whose group count overflows when multiplied by the
+	// width, and an RLE value outside the one-bit definition levels.
+	if _, err := rleHybrid([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, 8, 4); err == nil {
+		t.Error("rleHybrid accepted a bit-packed run longer than its data")
+	}
+	if _, err := rleHybrid(nil, 1, -1); err == nil {
+		t.Error("rleHybrid accepted a negative count")
+	}
+	if _, err := pageValues(nil, pqColumn{typ: pqInt32}, pqEncPlain, nil, []int{255}, 1); err == nil {
+		t.Error("pageValues accepted definition level 255")
+	}
+	if _, _, err := plainValues([]byte{1, 2, 3, 4}, pqColumn{typ: pqFixed, typeLen: -4}, 1); err == nil {
+		t.Error("plainValues accepted a negative fixed length")
+	}
+	// blockSize 128, 4 miniblocks, but 2^40 values for a page of one.
+	delta := []byte{0x80, 0x01, 0x04, 0x80, 0x80, 0x80, 0x80, 0x80, 0x20, 0x00}
+	if _, _, err := deltaBinaryPacked(delta, 1); err == nil {
+		t.Error("deltaBinaryPacked accepted a header count beyond the page")
+	}
+	if _, err := pageCount(tstruct{1: int64(-1)}, 3); err == nil {
+		t.Error("pageCount accepted a negative count")
+	}
+	if _, err := pageCount(tstruct{1: int64(4)}, 3); err == nil {
+		t.Error("pageCount accepted more values than rows remain")
+	}
+}
+
+func TestReadParquetRejectsCorruptFiles(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "memories-v2-snappy.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt every byte of the file in turn; each must fail or decode,
+	// never panic.
+	for i := range data {
+		for _, b := range []byte{0x00, 0xff, data[i] ^ 0x80} {
+			bad := append([]byte(nil), data...)
+			bad[i] = b
+			readParquet(bad)
+		}
+	}
+}
+
+func FuzzReadParquet(f *testing.F) {
+	for _, name := range []string{"memories-v1.parquet", "memories-v2-snappy.parquet", "memories-gzip.parquet", "memories-delta.parquet"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		readParquet(data)
+	})
+}
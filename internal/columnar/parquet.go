@@ -0,0 +1,653 @@
+package columnar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/snappy"
+)
+
+// parquetMagic starts and ends every Parquet file.
+var parquetMagic = []byte("PAR1")
+
+// maxParquetRows bounds the rows of one file. Row and value counts come
+// from the file and size allocations, and run-length encoding lets a few
+// bytes claim millions of values, so readParquet also allows no more rows
+// than the file has bytes: every row carries a distinct key, which takes
+// at least a byte however it is encoded.
+const maxParquetRows = 1 << 24
+
+// maxDeltaBlockSize bounds a DELTA_BINARY_PACKED block; writers use 128.
+const maxDeltaBlockSize = 1 << 16
+
+// Parquet enum values used by the reader (see parquet.thrift).
+const (
+	pqBoolean   = 0
+	pqInt32     = 1
+	pqInt64     = 2
+	pqFloat     = 4
+	pqDouble    = 5
+	pqByteArray = 6
+	pqFixed     = 7
+
+	pqOptional = 1
+	pqRepeated = 2
+
+	pqConvertedTimestampMillis = 9
+	pqConvertedTimestampMicros = 10
+	pqConvertedJSON            = 19
+
+	pqEncPlain          = 0
+	pqEncPlainDict      = 2
+	pqEncRLE            = 3
+	pqEncDeltaBinary    = 5
+	pqEncDeltaLength    = 6
+	pqEncDeltaByteArray = 7
+	pqEncRLEDict        = 8
+	pqPageData          = 0
+	pqPageDictionary    = 2
+	pqPageDataV2        = 3
+	pqCodecUncompressed = 0
+	pqCodecSnappy       = 1
+	pqCodecGzip         = 2
+)
+
+// pqColumn describes one leaf column of a flat schema.
+type pqColumn struct {
+	name      string
+	typ       int64
+	typeLen   int
+	optional  bool
+	timestamp time.Duration // unit for timestamp columns, else 0
+	json      bool
+}
+
+// readParquet decodes every row of a Parquet file with a flat schema.
+// Supported: PLAIN and dictionary encodings, data pages v1 and v2, and
+// UNCOMPRESSED, SNAPPY, and GZIP codecs. Nested columns are rejected;
+// store nested fields such as relationships as JSON text columns.
+func readParquet(data []byte) ([]map[string]interface{}, error) {
+	n := len(data)
+	if n < 12 || !bytes.Equal(data[:4], parquetMagic) || !bytes.Equal(data[n-4:], parquetMagic) {
+		return nil, fmt.Errorf("parquet: not a parquet file")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[n-8:]))
+	if footerLen > n-12 {
+		return nil, fmt.Errorf("parquet: invalid footer length")
+	}
+	meta, err := (&thriftReader{buf: data[n-8-footerLen : n-8]}).readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("parquet: invalid footer: %w", err)
+	}
+
+	cols, err := parquetColumns(meta.list(2))
+	if err != nil {
+		return nil, err
+	}
+
+	limit := min(maxParquetRows, n)
+	var rows []map[string]interface{}
+	for gi, g := range meta.list(4) {
+		rg, _ := g.(tstruct)
+		numRows := rg.int(3)
+		if numRows < 0 || numRows > int64(limit-len(rows)) {
+			return nil, fmt.Errorf("parquet: row group %d has %d rows, at most %d allowed", gi, numRows, limit-len(rows))
+		}
+		chunks := rg.list(1)
+		if len(chunks) != len(cols) {
+			return nil, fmt.Errorf("parquet: row group %d has %d columns, schema has %d", gi, len(chunks), len(cols))
+		}
+		groupRows := make([]map[string]interface{}, numRows)
+		for i := range groupRows {
+			groupRows[i] = make(map[string]interface{}, len(cols))
+		}
+		for ci, c := range chunks {
+			chunk, _ := c.(tstruct)
+			values, err := readColumnChunk(data, chunk.strct(3), cols[ci], int(numRows))
+			if err != nil {
+				return nil, fmt.Errorf("parquet: row group %d, column %s: %w", gi, cols[ci].name, err)
+			}
+			for i, v := range values {
+				if s, ok := v.(string); ok && cols[ci].json {
+					if v, err = ingest.Decode([]byte(s)); err != nil {
+						return nil, fmt.Errorf("parquet: column %s, row %d: %w", cols[ci].name, len(rows)+i, err)
+					}
+				}
+				groupRows[i][cols[ci].name] = v
+			}
+		}
+		rows = append(rows, groupRows...)
+	}
+	return rows, nil
+}
+
+func parquetColumns(schema []interface{}) ([]pqColumn, error) {
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("parquet: empty schema")
+	}
+	var cols []pqColumn
+	for _, e := range schema[1:] {
+		el, _ := e.(tstruct)
+		name := el.str(4)
+		if el.int(5) > 0 || el.int(3) == pqRepeated {
+			return nil, fmt.Errorf("parquet: nested or repeated column %s is not supported; store it as a JSON string column", name)
+		}
+		col := pqColumn{
+			name:     name,
+			typ:      el.int(1),
+			typeLen:  int(el.int(2)),
+			optional: el.int(3) == pqOptional,
+		}
+		switch el.int(6) {
+		case pqConvertedTimestampMillis:
+			col.timestamp = time.Millisecond
+		case pqConvertedTimestampMicros:
+			col.timestamp = time.Microsecond
+		case pqConvertedJSON:
+			col.json = true
+		}
+		if lt := el.strct(10); lt != nil {
+			if lt.has(12) {
+				col.json = true
+			}
+			if ts := lt.strct(8); ts != nil {
+				switch unit := ts.strct(2); {
+				case unit.has(1):
+					col.timestamp = time.Millisecond
+				case unit.has(2):
+					col.timestamp = time.Microsecond
+				case unit.has(3):
+					col.timestamp = time.Nanosecond
+				}
+			}
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+func readColumnChunk(data []byte, md tstruct, col pqColumn, numRows int) ([]interface{}, error) {
+	if md == nil {
+		return nil, fmt.Errorf("missing column metadata")
+	}
+	codec := md.int(4)
+	start := md.int(9)
+	if md.has(11) && md.int(11) > 0 && md.int(11) < start {
+		start = md.int(11)
+	}
+	end := start + md.int(7)
+	if start < 4 || end > int64(len(data)) || end < start {
+		return nil, fmt.Errorf("column chunk out of bounds")
+	}
+
+	r := &thriftReader{buf: data[start:end]}
+	var dict []interface{}
+	values := make([]interface{}, 0, numRows)
+	for len(values) < numRows {
+		if r.pos >= len(r.buf) {
+			return nil, fmt.Errorf("expected %d values, found %d", numRows, len(values))
+		}
+		ph, err := r.readStruct()
+		if err != nil {
+			return nil, fmt.Errorf("invalid page header: %w", err)
+		}
+		size := int(ph.int(3))
+		if size < 0 || size > len(r.buf)-r.pos {
+			return nil, fmt.Errorf("page exceeds column chunk")
+		}
+		body := r.buf[r.pos : r.pos+size]
+		r.pos += size
+		uncompressed := int(ph.int(2))
+
+		switch ph.int(1) {
+		case pqPageDictionary:
+			dh := ph.strct(7)
+			page, err := decompress(codec, body, uncompressed)
+			if err != nil {
+				return nil, err
+			}
+			if dict, _, err = plainValues(page, col, int(dh.int(1))); err != nil {
+				return nil, fmt.Errorf("dictionary page: %w", err)
+			}
+		case pqPageData:
+			dh := ph.strct(5)
+			page, err := decompress(codec, body, uncompressed)
+			if err != nil {
+				return nil, err
+			}
+			count, err := pageCount(dh, numRows-len(values))
+			if err != nil {
+				return nil, err
+			}
+			var defs []int
+			if col.optional {
+				if len(page) < 4 {
+					return nil, fmt.Errorf("truncated definition levels")
+				}
+				l := int(binary.LittleEndian.Uint32(page))
+				if l > len(page)-4 {
+					return nil, fmt.Errorf("truncated definition levels")
+				}
+				if defs, err = rleHybrid(page[4:4+l], 1, count); err != nil {
+					return nil, err
+				}
+				page = page[4+l:]
+			}
+			vals, err := pageValues(page, col, dh.int(2), dict, defs, count)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, vals...)
+		case pqPageDataV2:
+			dh := ph.strct(8)
+			count, err := pageCount(dh, numRows-len(values))
+			if err != nil {
+				return nil, err
+			}
+			repLen, defLen := dh.int(6), dh.int(5)
+			if repLen < 0 || defLen < 0 || repLen > int64(len(body)) || defLen > int64(len(body))-repLen {
+				return nil, fmt.Errorf("truncated levels")
+			}
+			var defs []int
+			if col.optional {
+				if defs, err = rleHybrid(body[repLen:repLen+defLen], 1, count); err != nil {
+					return nil, err
+				}
+			}
+			page := body[repLen+defLen:]
+			if dh.bool(7, true) {
+				if page, err = decompress(codec, page, uncompressed-int(repLen+defLen)); err != nil {
+					return nil, err
+				}
+			}
+			vals, err := pageValues(page, col, dh.int(4), dict, defs, count)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, vals...)
+		default:
+			// Index pages and unknown page types carry no row values.
+		}
+	}
+	if len(values) != numRows {
+		return nil, fmt.Errorf("expected %d values, found %d", numRows, len(values))
+	}
+	return values, nil
+}
+
+// pageCount returns the number of values a data page header declares,
+// which must fit in the remaining rows of the column chunk.
+func pageCount(dh tstruct, remaining int) (int, error) {
+	count := dh.int(1)
+	if count < 0 || count > int64(remaining) {
+		return 0, fmt.Errorf("page has %d values, %d remain in the column chunk", count, remaining)
+	}
+	return int(count), nil
+}
+
+func decompress(codec int64, body []byte, size int) ([]byte, error) {
+	if size < 0 || size > maxDecodedSize {
+		return nil, fmt.Errorf("page size %d out of range", size)
+	}
+	switch codec {
+	case pqCodecUncompressed:
+		return body, nil
+	case pqCodecSnappy:
+		return snappy.Decode(body, maxDecodedSize)
+	case pqCodecGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(io.LimitReader(zr, int64(size)))
+	}
+	return nil, fmt.Errorf("unsupported codec %d (supported: UNCOMPRESSED, SNAPPY, GZIP)", codec)
+}
+
+// pageValues decodes count row slots; defs (nil for required columns)
+// marks which slots are present.
+func pageValues(page []byte, col pqColumn, encoding int64, dict []interface{}, defs []int, count int) ([]interface{}, error) {
+	present := count
+	if defs != nil {
+		present = 0
+		for _, d := range defs {
+			if d > 1 {
+				return nil, fmt.Errorf("invalid definition level %d", d)
+			}
+			present += d
+		}
+	}
+
+	var vals []interface{}
+	switch encoding {
+	case pqEncPlain:
+		var err error
+		if vals, _, err = plainValues(page, col, present); err != nil {
+			return nil, err
+		}
+	case pqEncPlainDict, pqEncRLEDict:
+		if dict == nil {
+			return nil, fmt.Errorf("dictionary-encoded page without dictionary")
+		}
+		if len(page) < 1 {
+			return nil, fmt.Errorf("truncated dictionary indexes")
+		}
+		idx, err := rleHybrid(page[1:], int(page[0]), present)
+		if err != nil {
+			return nil, err
+		}
+		vals = make([]interface{}, present)
+		for i, x := range idx {
+			if x >= len(dict) {
+				return nil, fmt.Errorf("dictionary index %d out of range", x)
+			}
+			vals[i] = dict[x]
+		}
+	case pqEncDeltaBinary, pqEncDeltaLength, pqEncDeltaByteArray:
+		var err error
+		if vals, err = deltaValues(page, col, encoding, present); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d (supported: PLAIN, dictionary, delta)", encoding)
+	}
+
+	if defs == nil {
+		return vals, nil
+	}
+	out := make([]interface{}, count)
+	j := 0
+	for i, d := range defs {
+		if d == 1 {
+			out[i] = vals[j]
+			j++
+		}
+	}
+	return out, nil
+}
+
+// plainValues decodes n PLAIN-encoded values.
+func plainValues(b []byte, col pqColumn, n int) ([]interface{}, int, error) {
+	if n < 0 || n > len(b)*8+1 {
+		return nil, 0, fmt.Errorf("value count %d exceeds page", n)
+	}
+	if col.typ == pqBoolean && (n+7)/8 > len(b) {
+		return nil, 0, fmt.Errorf("truncated values")
+	}
+	vals := make([]interface{}, n)
+	pos := 0
+	need := func(k int) error {
+		if k < 0 || k > len(b)-pos {
+			return fmt.Errorf("truncated values")
+		}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		switch col.typ {
+		case pqBoolean:
+			vals[i] = b[i/8]>>(i%8)&1 == 1
+		case pqInt32:
+			if err := need(4); err != nil {
+				return nil, 0, err
+			}
+			vals[i] = col.integer(int64(int32(binary.LittleEndian.Uint32(b[pos:]))))
+			pos += 4
+		case pqInt64:
+			if err := need(8); err != nil {
+				return nil, 0, err
+			}
+			vals[i] = col.integer(int64(binary.LittleEndian.Uint64(b[pos:])))
+			pos += 8
+		case pqFloat:
+			if err := need(4); err != nil {
+				return nil, 0, err
+			}
+			vals[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b[pos:])))
+			pos += 4
+		case pqDouble:
+			if err := need(8); err != nil {
+				return nil, 0, err
+			}
+			vals[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[pos:]))
+			pos += 8
+		case pqByteArray, pqFixed:
+			l := col.typeLen
+			if col.typ == pqByteArray {
+				if err := need(4); err != nil {
+					return nil, 0, err
+				}
+				l = int(binary.LittleEndian.Uint32(b[pos:]))
+				pos += 4
+			}
+			if err := need(l); err != nil {
+				return nil, 0, err
+			}
+			vals[i] = byteArray(b[pos : pos+l])
+			pos += l
+		default:
+			return nil, 0, fmt.Errorf("unsupported physical type %d", col.typ)
+		}
+	}
+	if col.typ == pqBoolean {
+		pos = (n + 7) / 8
+	}
+	return vals, pos, nil
+}
+
+// byteArray returns raw as a string when it is valid UTF-8, otherwise as
+// bytes, which ingest validation rejects as an unsupported type.
+func byteArray(raw []byte) interface{} {
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+	return append([]byte(nil), raw...)
+}
+
+// deltaValues decodes the DELTA_BINARY_PACKED, DELTA_LENGTH_BYTE_ARRAY,
+// and DELTA_BYTE_ARRAY encodings.
+func deltaValues(b []byte, col pqColumn, encoding int64, n int) ([]interface{}, error) {
+	vals := make([]interface{}, n)
+	switch encoding {
+	case pqEncDeltaBinary:
+		if col.typ != pqInt32 && col.typ != pqInt64 {
+			return nil, fmt.Errorf("DELTA_BINARY_PACKED on non-integer column")
+		}
+		ints, _, err := deltaBinaryPacked(b, n)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range ints {
+			if col.typ == pqInt32 {
+				v = int64(int32(v))
+			}
+			vals[i] = col.integer(v)
+		}
+		return vals, nil
+	case pqEncDeltaLength:
+		lengths, pos, err := deltaBinaryPacked(b, n)
+		if err != nil {
+			return nil, err
+		}
+		for i, l := range lengths {
+			if l < 0 || l > int64(len(b)-pos) {
+				return nil, fmt.Errorf("truncated byte array")
+			}
+			vals[i] = byteArray(b[pos : pos+int(l)])
+			pos += int(l)
+		}
+		return vals, nil
+	case pqEncDeltaByteArray:
+		prefixes, pos, err := deltaBinaryPacked(b, n)
+		if err != nil {
+			return nil, err
+		}
+		suffixLens, k, err := deltaBinaryPacked(b[pos:], n)
+		if err != nil {
+			return nil, err
+		}
+		pos += k
+		var prev []byte
+		for i := range vals {
+			p, l := prefixes[i], suffixLens[i]
+			if p < 0 || p > int64(len(prev)) || l < 0 || l > int64(len(b)-pos) {
+				return nil, fmt.Errorf("invalid delta byte array")
+			}
+			cur := append(append([]byte(nil), prev[:p]...), b[pos:pos+int(l)]...)
+			pos += int(l)
+			vals[i] = byteArray(cur)
+			prev = cur
+		}
+		return vals, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %d", encoding)
+}
+
+// deltaBinaryPacked decodes n DELTA_BINARY_PACKED integers and returns
+// the number of bytes consumed.
+func deltaBinaryPacked(b []byte, n int) ([]int64, int, error) {
+	pos := 0
+	uv := func() (uint64, error) {
+		v, k := binary.Uvarint(b[pos:])
+		if k <= 0 {
+			return 0, fmt.Errorf("truncated delta header")
+		}
+		pos += k
+		return v, nil
+	}
+	blockSize, err := uv()
+	if err != nil {
+		return nil, 0, err
+	}
+	miniblocks, err := uv()
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := uv()
+	if err != nil {
+		return nil, 0, err
+	}
+	first, err := uv()
+	if err != nil {
+		return nil, 0, err
+	}
+	if blockSize == 0 || blockSize > maxDeltaBlockSize || miniblocks == 0 || blockSize%miniblocks != 0 || (blockSize/miniblocks)%8 != 0 {
+		return nil, 0, fmt.Errorf("invalid delta header")
+	}
+	if total != uint64(n) {
+		return nil, 0, fmt.Errorf("delta header has %d values, page has %d", total, n)
+	}
+	perMini := int(blockSize / miniblocks)
+
+	out := make([]int64, 0, n)
+	if n == 0 {
+		return out, pos, nil
+	}
+	last := int64(first>>1) ^ -int64(first&1)
+	out = append(out, last)
+	for uint64(len(out)) < total {
+		mdz, err := uv()
+		if err != nil {
+			return nil, 0, err
+		}
+		minDelta := int64(mdz>>1) ^ -int64(mdz&1)
+		if int(miniblocks) > len(b)-pos {
+			return nil, 0, fmt.Errorf("truncated delta block")
+		}
+		widths := b[pos : pos+int(miniblocks)]
+		pos += int(miniblocks)
+		for _, w := range widths {
+			if uint64(len(out)) >= total {
+				break
+			}
+			if w > 64 {
+				return nil, 0, fmt.Errorf("invalid delta bit width %d", w)
+			}
+			size := perMini * int(w) / 8
+			if size > len(b)-pos {
+				return nil, 0, fmt.Errorf("truncated delta miniblock")
+			}
+			packed := b[pos : pos+size]
+			pos += size
+			for i := 0; i < perMini && uint64(len(out)) < total; i++ {
+				var d uint64
+				for j := 0; j < int(w); j++ {
+					bit := i*int(w) + j
+					d |= uint64(packed[bit/8]>>(bit%8)&1) << j
+				}
+				last += minDelta + int64(d)
+				out = append(out, last)
+			}
+		}
+	}
+	return out[:n], pos, nil
+}
+
+func (c pqColumn) integer(v int64) interface{} {
+	if c.timestamp != 0 {
+		return formatTimestamp(time.Unix(0, 0).Add(time.Duration(v) * c.timestamp))
+	}
+	return json.Number(fmt.Sprint(v))
+}
+
+// rleHybrid decodes n values of the RLE/bit-packing hybrid encoding.
+func rleHybrid(b []byte, width, n int) ([]int, error) {
+	if width < 0 || width > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", width)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("invalid value count %d", n)
+	}
+	out := make([]int, 0, n)
+	pos := 0
+	for len(out) < n {
+		header, k := binary.Uvarint(b[pos:])
+		if k <= 0 {
+			return nil, fmt.Errorf("truncated RLE data")
+		}
+		pos += k
+		if header&1 == 0 {
+			count := int(header >> 1)
+			vb := (width + 7) / 8
+			if pos+vb > len(b) || count > n-len(out) {
+				return nil, fmt.Errorf("invalid RLE run")
+			}
+			v := 0
+			for i := vb - 1; i >= 0; i-- {
+				v = v<<8 | int(b[pos+i])
+			}
+			pos += vb
+			for i := 0; i < count; i++ {
+				out = append(out, v)
+			}
+			continue
+		}
+		groups := header >> 1
+		if width > 0 && groups > uint64((len(b)-pos)/width) {
+			return nil, fmt.Errorf("truncated bit-packed run")
+		}
+		nbytes := int(groups) * width
+		packed := b[pos : pos+nbytes]
+		pos += nbytes
+		take := n - len(out)
+		if groups < uint64(take+7)/8 {
+			take = int(groups) * 8
+		}
+		for i := 0; i < take; i++ {
+			v := 0
+			for j := 0; j < width; j++ {
+				bit := i*width + j
+				v |= int(packed[bit/8]>>(bit%8)&1) << j
+			}
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,17 @@
+module github.com/holeyfield33-art/helios/internal/columnar/testdata/gen
+
+go 1.25
+
+require (
+	github.com/linkedin/goavro/v2 v2.13.1
+	github.com/parquet-go/parquet-go v0.25.1
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
@@ -0,0 +1,164 @@
+// Command gen writes the Avro and Parquet fixtures in the parent directory
+// using independent implementations. It is a separate module so the Helios
+// module itself keeps its single dependency.
+//
+//	cd internal/columnar/testdata/gen && go run . ..
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/parquet-go/parquet-go"
+)
+
+var times = []time.Time{
+	time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+	time.Date(2025, 1, 15, 10, 30, 0, 123e6, time.UTC),
+	time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+}
+
+const avroSchema = `{
+  "type": "record", "name": "Memory", "namespace": "lake",
+  "fields": [
+    {"name": "category", "type": "string"},
+    {"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "key", "type": "string"},
+    {"name": "relationships", "type": {"type": "array", "items": {"type": "record", "name": "Rel", "fields": [
+      {"name": "key", "type": "string"}, {"name": "type", "type": "string"}]}}},
+    {"name": "source", "type": {"type": "enum", "name": "Source", "symbols": ["app", "lake"]}},
+    {"name": "payload", "type": "string"},
+    {"name": "updated_at", "type": ["null", "string"], "default": null},
+    {"name": "confidence", "type": "double"}
+  ]
+}`
+
+func avroRows() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"category": "project", "created_at": times[0], "key": "lake/one",
+			"relationships": []interface{}{map[string]interface{}{"key": "lake/two", "type": "related_to"}},
+			"source":        "lake", "payload": `"first"`, "updated_at": goavro.Union("string", "2025-03-01T00:00:00.000Z"), "confidence": 0.5},
+		{"category": "project", "created_at": times[1], "key": "lake/two", "relationships": []interface{}{},
+			"source": "lake", "payload": `"second — café"`, "updated_at": nil, "confidence": 0.9},
+		{"category": "note", "created_at": times[2], "key": "lake/three", "relationships": []interface{}{},
+			"source": "lake", "payload": `{"n":42,"tags":["a","b"]}`, "updated_at": nil, "confidence": 1.0},
+	}
+}
+
+func writeAvro(path, codec string) {
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: f, Schema: avroSchema, CompressionName: codec})
+	if err != nil {
+		panic(err)
+	}
+	var rows []interface{}
+	for _, r := range avroRows() {
+		rows = append(rows, r)
+	}
+	if err := w.Append(rows); err != nil {
+		panic(err)
+	}
+}
+
+type pqRow struct {
+	Category      string    `parquet:"category"`
+	CreatedAt     time.Time `parquet:"created_at,timestamp(millisecond)"`
+	Key           string    `parquet:"key,dict"`
+	Relationships string    `parquet:"relationships,json"`
+	Source        string    `parquet:"source,dict"`
+	Value         string    `parquet:"value"`
+	UpdatedAt     *string   `parquet:"updated_at,optional"`
+	Version       int64     `parquet:"version"`
+}
+
+func pqRows() []pqRow {
+	u := "2025-03-01T00:00:00.000Z"
+	return []pqRow{
+		{"project", times[0], "lake/one", `[{"key":"lake/two","type":"related_to"}]`, "lake", "first", &u, 3},
+		{"project", times[1], "lake/two", `[]`, "lake", "second — café", nil, 1},
+		{"note", times[2], "lake/three", `[]`, "lake", "third", nil, 7},
+	}
+}
+
+// pqRowV1 omits the optional column: parquet-go v0.25 writes a bogus
+// repetition-level section for optional columns in v1 data pages.
+type pqRowV1 struct {
+	Category      string    `parquet:"category"`
+	CreatedAt     time.Time `parquet:"created_at,timestamp(millisecond)"`
+	Key           string    `parquet:"key,dict"`
+	Relationships string    `parquet:"relationships,json"`
+	Source        string    `parquet:"source,dict"`
+	Value         string    `parquet:"value,plain"`
+	Version       int64     `parquet:"version,plain"`
+}
+
+type pqRowDelta struct {
+	Category      string    `parquet:"category,delta"`
+	CreatedAt     time.Time `parquet:"created_at,timestamp(millisecond)"`
+	Key           string    `parquet:"key,delta"`
+	Relationships string    `parquet:"relationships,json"`
+	Source        string    `parquet:"source,delta"`
+	Value         string    `parquet:"value,delta"`
+	Version       int64     `parquet:"version,delta"`
+}
+
+func pqRowsDelta() []pqRowDelta {
+	var out []pqRowDelta
+	for _, r := range pqRows() {
+		out = append(out, pqRowDelta{r.Category, r.CreatedAt, r.Key, r.Relationships, r.Source, r.Value, r.Version})
+	}
+	return out
+}
+
+func pqRowsV1() []pqRowV1 {
+	var out []pqRowV1
+	for _, r := range pqRows() {
+		out = append(out, pqRowV1{r.Category, r.CreatedAt, r.Key, r.Relationships, r.Source, r.Value, r.Version})
+	}
+	return out
+}
+
+func writeParquet[T any](path string, rows []T, opts ...parquet.WriterOption) {
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	w := parquet.NewGenericWriter[T](f, opts...)
+	if _, err := w.Write(rows); err != nil {
+		panic(err)
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+}
+
+type floatRow struct {
+	Category  string    `parquet:"category"`
+	CreatedAt time.Time `parquet:"created_at,timestamp(millisecond)"`
+	Key       string    `parquet:"key"`
+	Source    string    `parquet:"source"`
+	Value     float64   `parquet:"value"`
+}
+
+func main() {
+	dir := os.Args[1]
+	writeAvro(dir+"/memories.avro", "null")
+	writeAvro(dir+"/memories-deflate.avro", "deflate")
+	writeAvro(dir+"/memories-snappy.avro", "snappy")
+	writeParquet(dir+"/memories-v1.parquet", pqRowsV1(), parquet.DataPageVersion(1))
+	writeParquet(dir+"/memories-v2-snappy.parquet", pqRows(), parquet.DataPageVersion(2), parquet.Compression(&parquet.Snappy))
+	writeParquet(dir+"/memories-delta.parquet", pqRowsDelta(), parquet.DataPageVersion(2))
+	writeParquet(dir+"/memories-gzip.parquet", pqRowsV1(), parquet.DataPageVersion(1), parquet.Compression(&parquet.Gzip))
+
+	f, _ := os.Create(dir + "/float.parquet")
+	w := parquet.NewGenericWriter[floatRow](f)
+	w.Write([]floatRow{{"x", times[0], "lake/float", "lake", 1.5}})
+	w.Close()
+	f.Close()
+}
@@ -0,0 +1,219 @@
+package columnar
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Parquet metadata is serialized with the Thrift compact protocol. Rather
+// than generate bindings for the whole parquet.thrift IDL, the footer is
+// decoded into a generic tree and the handful of fields the reader needs
+// are looked up by field ID.
+
+// Compact protocol type codes.
+const (
+	tBoolTrue  = 1
+	tBoolFalse = 2
+	tByte      = 3
+	tI16       = 4
+	tI32       = 5
+	tI64       = 6
+	tDouble    = 7
+	tBinary    = 8
+	tList      = 9
+	tSet       = 10
+	tMap       = 11
+	tStruct    = 12
+)
+
+// tstruct is a decoded Thrift struct keyed by field ID. Values are int64,
+// bool, float64, []byte, []interface{}, or tstruct; maps are skipped.
+type tstruct map[int16]interface{}
+
+func (s tstruct) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s tstruct) has(id int16) bool {
+	_, ok := s[id]
+	return ok
+}
+
+func (s tstruct) str(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s tstruct) bool(id int16, def bool) bool {
+	if v, ok := s[id].(bool); ok {
+		return v
+	}
+	return def
+}
+
+func (s tstruct) strct(id int16) tstruct {
+	v, _ := s[id].(tstruct)
+	return v
+}
+
+func (s tstruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+type thriftReader struct {
+	buf []byte
+	pos int
+	// depth bounds nesting so malformed input cannot exhaust the stack.
+	depth int
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, fmt.Errorf("thrift: unexpected end of data")
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("thrift: invalid varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	u, err := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1), err
+}
+
+func (r *thriftReader) readStruct() (tstruct, error) {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > 64 {
+		return nil, fmt.Errorf("thrift: nesting too deep")
+	}
+
+	s := tstruct{}
+	var last int16
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		typ := b & 0x0f
+		if typ == 0 {
+			return s, nil
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		last = id
+
+		var v interface{}
+		switch typ {
+		case tBoolTrue:
+			v = true
+		case tBoolFalse:
+			v = false
+		default:
+			if v, err = r.readValue(typ); err != nil {
+				return nil, err
+			}
+		}
+		if v != nil {
+			s[id] = v
+		}
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case tBoolTrue, tBoolFalse:
+		// Only reached for list elements, which carry a value byte.
+		b, err := r.byte()
+		return b == tBoolTrue, err
+	case tByte:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case tI16, tI32, tI64:
+		return r.varint()
+	case tDouble:
+		if r.pos+8 > len(r.buf) {
+			return nil, fmt.Errorf("thrift: unexpected end of data")
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.buf[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case tBinary:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.buf)-r.pos) {
+			return nil, fmt.Errorf("thrift: binary length exceeds data")
+		}
+		v := r.buf[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return v, nil
+	case tList, tSet:
+		h, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(h >> 4)
+		if size == 15 {
+			if size, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.buf)-r.pos) {
+			return nil, fmt.Errorf("thrift: list size exceeds data")
+		}
+		elem := h & 0x0f
+		list := make([]interface{}, 0, size)
+		for i := uint64(0); i < size; i++ {
+			v, err := r.readValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case tMap:
+		size, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		kv, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < size; i++ {
+			if _, err := r.readValue(kv >> 4); err != nil {
+				return nil, err
+			}
+			if _, err := r.readValue(kv & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case tStruct:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("thrift: unknown type %d", typ)
+}
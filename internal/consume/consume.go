@@ -0,0 +1,316 @@
+// Package consume validates and hashes memory objects read from a Kafka
+// topic and routes the results to output topics. It is the engine behind
+// `helios consume`.
+package consume
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/kafka"
+)
+
+// Result is the JSON value written for each consumed message. Accepted
+// messages carry Hash; rejected ones carry Error and, for spec violations,
+// the CANON_ERR_* Code.
+type Result struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Key       string `json:"key,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Rejected reports whether the message failed validation or hashing.
+func (r Result) Rejected() bool { return r.Error != "" }
+
+// Process validates and hashes one message value. cache may be nil. A
+// message standing in for a record batch that could not be decoded is
+// rejected with its error.
+func Process(cache *hash.Cache, m kafka.Message) Result {
+	res := Result{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset}
+	if m.Err != nil {
+		res.Error = m.Err.Error()
+		return res
+	}
+	var err error
+	res.Key, res.Hash, err = cache.HashDocument(m.Value)
+	if err != nil {
+		res.Hash = ""
+		res.Error = err.Error()
+		var ce *canon.Error
+		if errors.As(err, &ce) {
+			res.Code = ce.Code
+		}
+	}
+	return res
+}
+
+// Broker is the subset of *kafka.Client a Consumer uses.
+type Broker interface {
+	Partitions(topic string) ([]int32, error)
+	ListOffset(topic string, partition int32, ts int64) (int64, error)
+	Fetch(topic string, offsets map[int32]int64, maxWait time.Duration, maxBytes int32) map[int32]kafka.FetchResult
+	Produce(topic string, partition int32, msgs []kafka.Message) error
+	CommittedOffsets(group, topic string, partitions []int32) (map[int32]int64, error)
+	CommitOffsets(group, topic string, offsets map[int32]int64) error
+}
+
+// Config configures a Consumer.
+type Config struct {
+	// Topic is read from every partition.
+	Topic string
+	// Group stores committed offsets. Empty means offsets are not
+	// committed and consumption starts at the end (or the beginning with
+	// FromBeginning) of each partition.
+	Group string
+	// FromBeginning starts partitions without a committed offset at the
+	// earliest offset instead of the latest.
+	FromBeginning bool
+
+	// OutputTopic receives a Result for every accepted message, and for
+	// rejected ones too unless RejectTopic is set. Results are written to
+	// the partition matching the source partition, modulo the output
+	// topic's partition count, keyed by the source message key. With no
+	// OutputTopic, results are written to Output as NDJSON.
+	OutputTopic string
+	RejectTopic string
+	Output      io.Writer
+
+	// MaxWait bounds how long a fetch waits for new messages. Default 500ms.
+	MaxWait time.Duration
+	// MaxBytes bounds the bytes fetched per partition. Default 1MB.
+	MaxBytes int32
+
+	// Cache, if set, memoizes hashes of identical message values, which
+	// redelivery after a restart or producer retries make common.
+	Cache *hash.Cache
+
+	Metrics *Metrics
+	// Logf, if set, receives operational messages such as offset resets.
+	Logf func(format string, args ...any)
+}
+
+// Consumer is a single-process consumer of one topic. Offsets are
+// committed after the results of a fetch are written, so delivery to the
+// output topics is at-least-once.
+type Consumer struct {
+	b   Broker
+	cfg Config
+
+	partitions []int32
+	offsets    map[int32]int64
+	outParts   map[string]int
+}
+
+// New creates a Consumer and resolves the starting offset of each
+// partition.
+func New(b Broker, cfg Config) (*Consumer, error) {
+	if cfg.Topic == "" {
+		return nil, errors.New("consume: no topic")
+	}
+	if cfg.OutputTopic == "" && cfg.Output == nil {
+		return nil, errors.New("consume: no output topic or writer")
+	}
+	if cfg.RejectTopic == "" {
+		cfg.RejectTopic = cfg.OutputTopic
+	}
+	if cfg.MaxWait == 0 {
+		cfg.MaxWait = 500 * time.Millisecond
+	}
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = 1 << 20
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = NewMetrics()
+	}
+	cfg.Metrics.cache = cfg.Cache
+	if cfg.Logf == nil {
+		cfg.Logf = func(string, ...any) {}
+	}
+	c := &Consumer{b: b, cfg: cfg, offsets: make(map[int32]int64), outParts: make(map[string]int)}
+
+	var err error
+	if c.partitions, err = b.Partitions(cfg.Topic); err != nil {
+		return nil, err
+	}
+	committed := map[int32]int64{}
+	if cfg.Group != "" {
+		if committed, err = b.CommittedOffsets(cfg.Group, cfg.Topic, c.partitions); err != nil {
+			return nil, fmt.Errorf("failed to read committed offsets: %w", err)
+		}
+	}
+	for _, p := range c.partitions {
+		if off, ok := committed[p]; ok && off >= 0 {
+			c.offsets[p] = off
+			continue
+		}
+		if c.offsets[p], err = c.reset(p); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range []string{cfg.OutputTopic, cfg.RejectTopic} {
+		if t == "" || c.outParts[t] > 0 {
+			continue
+		}
+		parts, err := b.Partitions(t)
+		if err != nil {
+			return nil, fmt.Errorf("output topic: %w", err)
+		}
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("output topic %s has no partitions", t)
+		}
+		c.outParts[t] = len(parts)
+	}
+	return c, nil
+}
+
+// Offsets returns the next offset to be consumed from each partition.
+func (c *Consumer) Offsets() map[int32]int64 {
+	out := make(map[int32]int64, len(c.offsets))
+	for p, off := range c.offsets {
+		out[p] = off
+	}
+	return out
+}
+
+func (c *Consumer) reset(p int32) (int64, error) {
+	ts := kafka.Latest
+	if c.cfg.FromBeginning {
+		ts = kafka.Earliest
+	}
+	off, err := c.b.ListOffset(c.cfg.Topic, p, ts)
+	if err != nil {
+		return 0, fmt.Errorf("partition %d: failed to list offsets: %w", p, err)
+	}
+	return off, nil
+}
+
+// Run consumes until ctx is cancelled. Fetch errors on individual
+// partitions are logged and retried; failures to write results or commit
+// offsets stop the consumer.
+func (c *Consumer) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		if _, err := c.Poll(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Poll performs one fetch across all partitions, writes the results, and
+// commits the new offsets. It returns the number of messages processed.
+func (c *Consumer) Poll() (int, error) {
+	fetched := c.b.Fetch(c.cfg.Topic, c.Offsets(), c.cfg.MaxWait, c.cfg.MaxBytes)
+
+	type output struct {
+		topic     string
+		partition int32
+	}
+	batches := make(map[output][]kafka.Message)
+	var order []output
+	var results []Result
+	advanced := make(map[int32]int64)
+	failed := false
+
+	for _, p := range c.partitions {
+		fr, ok := fetched[p]
+		if !ok {
+			continue
+		}
+		if errors.Is(fr.Err, kafka.ErrOffsetOutOfRange) {
+			off, err := c.reset(p)
+			if err != nil {
+				return 0, err
+			}
+			c.cfg.Logf("partition %d: offset %d out of range, resetting to %d", p, c.offsets[p], off)
+			c.offsets[p] = off
+			continue
+		}
+		if fr.Err != nil {
+			c.cfg.Logf("partition %d: fetch failed: %v", p, fr.Err)
+			c.cfg.Metrics.fetchError()
+			failed = true
+			continue
+		}
+
+		next := c.offsets[p]
+		for _, m := range fr.Messages {
+			res := Process(c.cfg.Cache, m)
+			results = append(results, res)
+			c.cfg.Metrics.observe(res, len(m.Value))
+			next = m.Offset + 1
+			if c.cfg.OutputTopic == "" {
+				continue
+			}
+			topic := c.cfg.OutputTopic
+			if res.Rejected() {
+				topic = c.cfg.RejectTopic
+			}
+			value, err := json.Marshal(res)
+			if err != nil {
+				return 0, err
+			}
+			n := c.outParts[topic]
+			if n <= 0 {
+				return 0, fmt.Errorf("output topic %s has no partitions", topic)
+			}
+			o := output{topic, p % int32(n)}
+			if _, ok := batches[o]; !ok {
+				order = append(order, o)
+			}
+			batches[o] = append(batches[o], kafka.Message{Key: m.Key, Value: value, Time: m.Time})
+		}
+		if next != c.offsets[p] {
+			advanced[p] = next
+		}
+		c.cfg.Metrics.setLag(p, fr.HighWatermark-next)
+	}
+
+	if c.cfg.OutputTopic == "" {
+		enc := json.NewEncoder(c.cfg.Output)
+		enc.SetEscapeHTML(false)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return 0, err
+			}
+		}
+	}
+	for _, o := range order {
+		if err := c.b.Produce(o.topic, o.partition, batches[o]); err != nil {
+			return 0, fmt.Errorf("failed to write results: %w", err)
+		}
+	}
+	if c.cfg.Group != "" && len(advanced) > 0 {
+		if err := c.b.CommitOffsets(c.cfg.Group, c.cfg.Topic, advanced); err != nil {
+			return 0, fmt.Errorf("failed to commit offsets: %w", err)
+		}
+	}
+	for p, off := range advanced {
+		c.offsets[p] = off
+	}
+	if failed && len(results) == 0 {
+		// Every partition failed or was idle; back off before retrying.
+		time.Sleep(c.cfg.MaxWait)
+	}
+	return len(results), nil
+}
+
+// sortedPartitions is used by Metrics to render lag in partition order.
+func sortedPartitions(m map[int32]int64) []int32 {
+	ps := make([]int32, 0, len(m))
+	for p := range m {
+		ps = append(ps, p)
+	}
+	slices.Sort(ps)
+	return ps
+}
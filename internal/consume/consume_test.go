@@ -0,0 +1,284 @@
+package consume
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/kafka"
+)
+
+const validObject = `{"category":"project","created_at":"2025-01-15T10:30:00.000Z","key":"my/object","relationships":[],"source":"user","value":"hello"}`
+
+// memBroker is an in-memory Broker.
+type memBroker struct {
+	logs      map[string][][]kafka.Message
+	committed map[int32]int64
+	fetchErr  map[int32]error
+}
+
+func newMemBroker(topics map[string]int) *memBroker {
+	b := &memBroker{logs: make(map[string][][]kafka.Message), committed: make(map[int32]int64), fetchErr: make(map[int32]error)}
+	for t, n := range topics {
+		b.logs[t] = make([][]kafka.Message, n)
+	}
+	return b
+}
+
+func (b *memBroker) add(topic string, p int32, value string) {
+	log := b.logs[topic][p]
+	b.logs[topic][p] = append(log, kafka.Message{Topic: topic, Partition: p, Offset: int64(len(log)), Key: []byte("k"), Value: []byte(value)})
+}
+
+func (b *memBroker) Partitions(topic string) ([]int32, error) {
+	var ps []int32
+	for i := range b.logs[topic] {
+		ps = append(ps, int32(i))
+	}
+	return ps, nil
+}
+
+func (b *memBroker) ListOffset(topic string, p int32, ts int64) (int64, error) {
+	if ts == kafka.Latest {
+		return int64(len(b.logs[topic][p])), nil
+	}
+	return 0, nil
+}
+
+func (b *memBroker) Fetch(topic string, offsets map[int32]int64, _ time.Duration, _ int32) map[int32]kafka.FetchResult {
+	out := make(map[int32]kafka.FetchResult)
+	for p, off := range offsets {
+		log := b.logs[topic][p]
+		switch {
+		case b.fetchErr[p] != nil:
+			out[p] = kafka.FetchResult{Err: b.fetchErr[p]}
+		case off > int64(len(log)):
+			out[p] = kafka.FetchResult{Err: kafka.ErrOffsetOutOfRange}
+		default:
+			out[p] = kafka.FetchResult{Messages: log[off:], HighWatermark: int64(len(log))}
+		}
+	}
+	return out
+}
+
+func (b *memBroker) Produce(topic string, p int32, msgs []kafka.Message) error {
+	for _, m := range msgs {
+		m.Offset = int64(len(b.logs[topic][p]))
+		b.logs[topic][p] = append(b.logs[topic][p], m)
+	}
+	return nil
+}
+
+func (b *memBroker) CommittedOffsets(_, _ string, ps []int32) (map[int32]int64, error) {
+	out := make(map[int32]int64)
+	for _, p := range ps {
+		out[p] = -1
+		if off, ok := b.committed[p]; ok {
+			out[p] = off
+		}
+	}
+	return out, nil
+}
+
+func (b *memBroker) CommitOffsets(_, _ string, offsets map[int32]int64) error {
+	for p, off := range offsets {
+		b.committed[p] = off
+	}
+	return nil
+}
+
+func decodeResult(t *testing.T, m kafka.Message) Result {
+	t.Helper()
+	var r Result
+	if err := json.Unmarshal(m.Value, &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestProcess(t *testing.T) {
+	r := Process(nil, kafka.Message{Topic: "t", Partition: 2, Offset: 7, Value: []byte(validObject)})
+	if r.Rejected() || r.Key != "my/object" || len(r.Hash) != 64 || r.Offset != 7 || r.Partition != 2 {
+		t.Errorf("valid object: %+v", r)
+	}
+
+	r = Process(nil, kafka.Message{Value: []byte(strings.Replace(validObject, `"hello"`, `1.5`, 1))})
+	if !r.Rejected() || r.Code != "CANON_ERR_FLOAT_PROHIBITED" || r.Hash != "" {
+		t.Errorf("float value: %+v", r)
+	}
+	r = Process(nil, kafka.Message{Value: []byte("not json")})
+	if !r.Rejected() || r.Code != "" {
+		t.Errorf("malformed message: %+v", r)
+	}
+	r = Process(nil, kafka.Message{Offset: 9, Err: kafka.ErrUnsupportedCodec})
+	if !r.Rejected() || r.Offset != 9 || !strings.Contains(r.Error, "unsupported compression codec") {
+		t.Errorf("undecodable batch: %+v", r)
+	}
+}
+
+func TestConsumerDeadLettersUndecodableBatches(t *testing.T) {
+	b := newMemBroker(map[string]int{"memories": 1, "hashed": 1, "rejected": 1})
+	b.logs["memories"][0] = []kafka.Message{{Topic: "memories", Offset: 4, Err: kafka.ErrUnsupportedCodec}}
+
+	c, err := New(b, Config{Topic: "memories", Group: "g", FromBeginning: true, OutputTopic: "hashed", RejectTopic: "rejected"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Poll(); err != nil || n != 1 {
+		t.Fatalf("Poll: %d, %v", n, err)
+	}
+	if got := b.logs["rejected"][0]; len(got) != 1 || decodeResult(t, got[0]).Offset != 4 {
+		t.Errorf("rejected topic: %+v", got)
+	}
+	if b.committed[0] != 5 {
+		t.Errorf("committed %v, want past the batch", b.committed)
+	}
+}
+
+func TestConsumerRejectsOutputTopicWithoutPartitions(t *testing.T) {
+	b := newMemBroker(map[string]int{"memories": 1, "hashed": 0})
+	if _, err := New(b, Config{Topic: "memories", OutputTopic: "hashed"}); err == nil || !strings.Contains(err.Error(), "no partitions") {
+		t.Errorf("expected an error for an output topic without partitions, got %v", err)
+	}
+}
+
+func TestConsumerRoutesResults(t *testing.T) {
+	b := newMemBroker(map[string]int{"memories": 2, "hashed": 1, "rejected": 1})
+	b.add("memories", 0, validObject)
+	b.add("memories", 1, `{"key":"bad","value":null}`)
+	b.add("memories", 1, validObject)
+
+	c, err := New(b, Config{Topic: "memories", Group: "g", FromBeginning: true, OutputTopic: "hashed", RejectTopic: "rejected"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.Poll()
+	if err != nil || n != 3 {
+		t.Fatalf("Poll: %d, %v", n, err)
+	}
+	if got := b.logs["hashed"][0]; len(got) != 2 {
+		t.Fatalf("hashed topic has %d results, want 2", len(got))
+	}
+	rejected := b.logs["rejected"][0]
+	if len(rejected) != 1 {
+		t.Fatalf("rejected topic has %d results, want 1", len(rejected))
+	}
+	if r := decodeResult(t, rejected[0]); r.Code != "CANON_ERR_NULL_PROHIBITED" || r.Partition != 1 || r.Offset != 0 {
+		t.Errorf("rejection: %+v", r)
+	}
+	if string(rejected[0].Key) != "k" {
+		t.Errorf("result key %q, want the source key", rejected[0].Key)
+	}
+	if b.committed[0] != 1 || b.committed[1] != 2 {
+		t.Errorf("committed %v", b.committed)
+	}
+
+	s := c.cfg.Metrics.Snapshot()
+	if s.Accepted != 2 || s.Rejected != 1 || s.Lag != 0 {
+		t.Errorf("metrics: %+v", s)
+	}
+
+	// A new consumer in the same group resumes after the commits.
+	b.add("memories", 0, validObject)
+	c, err = New(b, Config{Topic: "memories", Group: "g", OutputTopic: "hashed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Poll(); err != nil || n != 1 {
+		t.Errorf("resumed Poll: %d, %v", n, err)
+	}
+}
+
+func TestConsumerStdoutAndOffsets(t *testing.T) {
+	b := newMemBroker(map[string]int{"memories": 1})
+	b.add("memories", 0, validObject)
+
+	var out bytes.Buffer
+	c, err := New(b, Config{Topic: "memories", Output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without --from-beginning, existing messages are skipped.
+	if n, _ := c.Poll(); n != 0 {
+		t.Errorf("latest start processed %d messages", n)
+	}
+	b.add("memories", 0, validObject)
+	if n, _ := c.Poll(); n != 1 {
+		t.Errorf("processed %d messages, want 1", n)
+	}
+	var r Result
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil || r.Offset != 1 || r.Hash == "" {
+		t.Errorf("stdout result %q: %v", out.String(), err)
+	}
+
+	// An out-of-range position resets instead of failing.
+	c.offsets[0] = 99
+	if _, err := c.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Offsets()[0] != 2 {
+		t.Errorf("offset after reset: %d", c.Offsets()[0])
+	}
+}
+
+func TestConsumerCache(t *testing.T) {
+	b := newMemBroker(map[string]int{"memories": 1})
+	for i := 0; i < 3; i++ {
+		b.add("memories", 0, validObject)
+	}
+	var out bytes.Buffer
+	cache := hash.NewCache(16, 0)
+	c, err := New(b, Config{Topic: "memories", FromBeginning: true, Output: &out, Cache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Poll(); err != nil || n != 3 {
+		t.Fatalf("Poll: %d, %v", n, err)
+	}
+	if s := cache.Stats(); s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("cache stats %+v", s)
+	}
+	var buf bytes.Buffer
+	c.cfg.Metrics.WritePrometheus(&buf)
+	if !strings.Contains(buf.String(), `helios_hash_cache_requests_total{result="hit"} 2`) {
+		t.Errorf("cache metrics missing:\n%s", buf.String())
+	}
+}
+
+func TestMetricsPrometheus(t *testing.T) {
+	m := NewMetrics()
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+	for i := 0; i < 20; i++ {
+		m.observe(Result{}, 10)
+	}
+	m.observe(Result{Error: "x"}, 5)
+	m.setLag(1, 4)
+	m.setLag(0, -1)
+	now = now.Add(time.Second)
+
+	s := m.Snapshot()
+	if s.Throughput != 2.1 || s.Lag != 4 || s.Bytes != 205 {
+		t.Errorf("snapshot: %+v", s)
+	}
+	var buf bytes.Buffer
+	m.WritePrometheus(&buf)
+	for _, want := range []string{
+		`helios_consume_messages_total{outcome="accepted"} 20`,
+		`helios_consume_messages_total{outcome="rejected"} 1`,
+		`helios_consume_lag{partition="0"} 0` + "\n" + `helios_consume_lag{partition="1"} 4`,
+		"helios_consume_throughput 2.1",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if s := m.Snapshot(); s.Throughput != 0 {
+		t.Errorf("throughput after idle minute: %g", s.Throughput)
+	}
+}
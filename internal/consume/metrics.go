@@ -0,0 +1,159 @@
+package consume
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+// throughputWindow is the span over which Throughput is averaged.
+const throughputWindow = 10 * time.Second
+
+// Metrics counts consumed messages and tracks per-partition lag. It
+// serves the Prometheus text exposition format.
+type Metrics struct {
+	mu          sync.Mutex
+	accepted    uint64
+	rejected    uint64
+	bytes       uint64
+	fetchErrors uint64
+	lag         map[int32]int64
+
+	// buckets holds per-second message counts for the throughput window.
+	buckets [int(throughputWindow / time.Second)]uint64
+	stamps  [int(throughputWindow / time.Second)]int64
+
+	cache *hash.Cache
+	now   func() time.Time
+}
+
+// NewMetrics returns zeroed metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{lag: make(map[int32]int64), now: time.Now}
+}
+
+// Snapshot is a point-in-time copy of Metrics.
+type Snapshot struct {
+	Accepted    uint64
+	Rejected    uint64
+	Bytes       uint64
+	FetchErrors uint64
+	// Lag is the number of messages behind the high watermark, summed
+	// over partitions; PartitionLag has the per-partition values.
+	Lag          int64
+	PartitionLag map[int32]int64
+	// Throughput is messages per second over the last ten seconds.
+	Throughput float64
+}
+
+func (m *Metrics) observe(r Result, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.Rejected() {
+		m.rejected++
+	} else {
+		m.accepted++
+	}
+	m.bytes += uint64(size)
+	sec := m.now().Unix()
+	i := sec % int64(len(m.buckets))
+	if m.stamps[i] != sec {
+		m.stamps[i] = sec
+		m.buckets[i] = 0
+	}
+	m.buckets[i]++
+}
+
+func (m *Metrics) fetchError() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetchErrors++
+}
+
+func (m *Metrics) setLag(partition int32, lag int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lag[partition] = max(lag, 0)
+}
+
+// Snapshot returns the current values.
+func (m *Metrics) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := Snapshot{
+		Accepted:     m.accepted,
+		Rejected:     m.rejected,
+		Bytes:        m.bytes,
+		FetchErrors:  m.fetchErrors,
+		PartitionLag: make(map[int32]int64, len(m.lag)),
+	}
+	for p, l := range m.lag {
+		s.PartitionLag[p] = l
+		s.Lag += l
+	}
+	now := m.now().Unix()
+	var n uint64
+	for i, stamp := range m.stamps {
+		// Skip the current, still-filling second.
+		if stamp < now && now-stamp <= int64(len(m.stamps)) {
+			n += m.buckets[i]
+		}
+	}
+	s.Throughput = float64(n) / throughputWindow.Seconds()
+	return s
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+	_, err := fmt.Fprintf(w, `# HELP helios_consume_messages_total Messages consumed, by outcome.
+# TYPE helios_consume_messages_total counter
+helios_consume_messages_total{outcome="accepted"} %d
+helios_consume_messages_total{outcome="rejected"} %d
+# HELP helios_consume_bytes_total Message value bytes consumed.
+# TYPE helios_consume_bytes_total counter
+helios_consume_bytes_total %d
+# HELP helios_consume_fetch_errors_total Partition fetches that failed.
+# TYPE helios_consume_fetch_errors_total counter
+helios_consume_fetch_errors_total %d
+# HELP helios_consume_throughput Messages per second over the last 10 seconds.
+# TYPE helios_consume_throughput gauge
+helios_consume_throughput %g
+# HELP helios_consume_lag Messages between the consumer position and the high watermark.
+# TYPE helios_consume_lag gauge
+`, s.Accepted, s.Rejected, s.Bytes, s.FetchErrors, s.Throughput)
+	if err != nil {
+		return err
+	}
+	for _, p := range sortedPartitions(s.PartitionLag) {
+		if _, err := fmt.Fprintf(w, "helios_consume_lag{partition=\"%d\"} %d\n", p, s.PartitionLag[p]); err != nil {
+			return err
+		}
+	}
+	if m.cache == nil {
+		return nil
+	}
+	cs := m.cache.Stats()
+	_, err = fmt.Fprintf(w, `# HELP helios_hash_cache_requests_total Hash cache lookups, by result.
+# TYPE helios_hash_cache_requests_total counter
+helios_hash_cache_requests_total{result="hit"} %d
+helios_hash_cache_requests_total{result="miss"} %d
+# HELP helios_hash_cache_evictions_total Hash cache entries evicted.
+# TYPE helios_hash_cache_evictions_total counter
+helios_hash_cache_evictions_total %d
+# HELP helios_hash_cache_entries Documents held in the hash cache.
+# TYPE helios_hash_cache_entries gauge
+helios_hash_cache_entries %d
+`, cs.Hits, cs.Misses, cs.Evictions, cs.Entries)
+	return err
+}
+
+// ServeHTTP serves WritePrometheus for a /metrics endpoint.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WritePrometheus(w)
+}
@@ -0,0 +1,267 @@
+// Package corpus reads memory objects and relationships from files: JSON
+// documents, NDJSON files, and directories of them. Parsing is left to
+// ingest, which touches no filesystem.
+package corpus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Load reads every memory object under path. path may be a
+// directory (all *.json files beneath it, hidden directories skipped), an
+// NDJSON file (*.ndjson or *.jsonl, one object per line), or a single JSON
+// document in any form accepted by ingest.ParseDocument.
+func Load(path string) ([]ingest.Record, error) {
+	return LoadInterned(path, nil)
+}
+
+// LoadInterned is Load interning the objects of each file
+// with in as it is loaded; a nil in interns nothing.
+func LoadInterned(path string, in *ingest.Interner) ([]ingest.Record, error) {
+	return load(path, in, false, false)
+}
+
+// LoadItems is LoadInterned keeping going past invalid objects: each
+// becomes a record with Err set, as does a JSON document that cannot be
+// parsed at all. The error is reserved for paths that cannot be read.
+func LoadItems(path string, in *ingest.Interner) ([]ingest.Record, error) {
+	return load(path, in, true, false)
+}
+
+// LoadRaw is LoadItems without interning, keeping each object's bytes as
+// read in Record.Raw: the line of an NDJSON file, the whole of a single
+// object document, or the item of a multi-object document. A document
+// that cannot be parsed at all is one record with Err and Raw set.
+func LoadRaw(path string) ([]ingest.Record, error) {
+	return load(path, nil, true, true)
+}
+
+func load(path string, in *ingest.Interner, keepGoing, raw bool) ([]ingest.Record, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	if !info.IsDir() {
+		return loadFile(path, in, keepGoing, raw)
+	}
+
+	var records []ingest.Record
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(p) != ".json" && !isNDJSON(p) {
+			return nil
+		}
+		recs, err := loadFile(p, in, keepGoing, raw)
+		if err != nil {
+			return err
+		}
+		records = append(records, recs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func isNDJSON(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".ndjson" || ext == ".jsonl"
+}
+
+func loadFile(path string, in *ingest.Interner, keepGoing, raw bool) ([]ingest.Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus file: %w", err)
+	}
+
+	if isNDJSON(path) {
+		var records []ingest.Record
+		for i, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			rec := ingest.Record{Origin: fmt.Sprintf("%s:%d", path, i+1)}
+			if raw {
+				rec.Raw = line
+			}
+			rec.Object, rec.Err = ingest.ParseObject(line)
+			if rec.Err != nil {
+				if !keepGoing {
+					return nil, fmt.Errorf("%s: %w", rec.Origin, rec.Err)
+				}
+			} else if in != nil {
+				in.Object(&rec.Object)
+			}
+			records = append(records, rec)
+		}
+		return records, nil
+	}
+
+	if !keepGoing {
+		objs, batch, err := ingest.ParseDocument(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return documentRecords(path, objs, nil, batch, in), nil
+	}
+	objs, errs, batch, err := ingest.ParseDocumentItems(data)
+	if err != nil {
+		rec := ingest.Record{Origin: path, Err: err}
+		if raw {
+			rec.Raw = data
+		}
+		return []ingest.Record{rec}, nil
+	}
+	records := documentRecords(path, objs, errs, batch, in)
+	if raw {
+		items := [][]byte{data}
+		if batch {
+			if items, err = rawItems(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		if len(items) != len(records) {
+			return nil, fmt.Errorf("%s: found %d raw items for %d objects", path, len(items), len(records))
+		}
+		for i := range records {
+			records[i].Raw = items[i]
+		}
+	}
+	return records, nil
+}
+
+// rawItems returns the bytes of each item of a multi-object document:
+// a JSON array of objects or an object whose only field is such an
+// array named objects.
+func rawItems(data []byte) ([][]byte, error) {
+	var items []json.RawMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+	} else {
+		var doc struct {
+			Objects []json.RawMessage `json:"objects"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, err
+		}
+		items = doc.Objects
+	}
+	out := make([][]byte, len(items))
+	for i, item := range items {
+		out[i] = item
+	}
+	return out, nil
+}
+
+// documentRecords returns the records of a parsed document, interning
+// the valid objects with in if it is not nil.
+func documentRecords(path string, objs []object.MemoryObject, errs []error, batch bool, in *ingest.Interner) []ingest.Record {
+	records := make([]ingest.Record, len(objs))
+	for i, obj := range objs {
+		origin := path
+		if batch {
+			origin = fmt.Sprintf("%s#%d", path, i)
+		}
+		records[i] = ingest.Record{Origin: origin, Object: obj}
+		if errs != nil && errs[i] != nil {
+			records[i].Err = errs[i]
+			continue
+		}
+		if in != nil {
+			in.Object(&records[i].Object)
+		}
+	}
+	return records
+}
+
+// LoadEdges reads the edges under path: a JSON array of edges, an NDJSON
+// file (*.ndjson or *.jsonl, one edge per line), or a directory of such
+// files, read in lexical order with hidden directories skipped. Edges
+// must have exactly the fields from, key, and type, all non-empty.
+func LoadEdges(path string) ([]ingest.Edge, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relationships: %w", err)
+	}
+	if !info.IsDir() {
+		return loadEdgesFile(path)
+	}
+	var edges []ingest.Edge
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(p) != ".json" && !isNDJSON(p) {
+			return nil
+		}
+		es, err := loadEdgesFile(p)
+		if err != nil {
+			return err
+		}
+		edges = append(edges, es...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return edges, nil
+}
+
+func loadEdgesFile(path string) ([]ingest.Edge, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relationships file: %w", err)
+	}
+	var edges []ingest.Edge
+	if isNDJSON(path) {
+		for i, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var e ingest.Edge
+			if err := ingest.DecodeEdge(line, &e); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+			}
+			edges = append(edges, e)
+		}
+		return edges, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("%s: expected a JSON array of relationships: %w", path, err)
+	}
+	for i, item := range items {
+		var e ingest.Edge
+		if err := ingest.DecodeEdge(item, &e); err != nil {
+			return nil, fmt.Errorf("%s#%d: %w", path, i, err)
+		}
+		edges = append(edges, e)
+	}
+	return edges, nil
+}
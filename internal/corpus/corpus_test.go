@@ -0,0 +1,226 @@
+package corpus
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	obj := `{"category":"c","created_at":"2025-01-15T10:30:00.000Z","key":"%s","relationships":[],"source":"s","value":"v"}`
+	files := map[string]string{
+		"single.json":          strings.Replace(obj, "%s", "single", 1),
+		"batch.json":           "[" + strings.Replace(obj, "%s", "b0", 1) + "," + strings.Replace(obj, "%s", "b1", 1) + "]",
+		"lines.ndjson":         strings.Replace(obj, "%s", "l1", 1) + "\n\n" + strings.Replace(obj, "%s", "l3", 1) + "\n",
+		".hidden/ignored.json": "not json",
+		"readme.txt":           "ignored",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origins := map[string]string{}
+	for _, r := range records {
+		origins[r.Object.Key] = strings.TrimPrefix(filepath.ToSlash(r.Origin), filepath.ToSlash(dir)+"/")
+	}
+	want := map[string]string{
+		"single": "single.json",
+		"b0":     "batch.json#0",
+		"b1":     "batch.json#1",
+		"l1":     "lines.ndjson:1",
+		"l3":     "lines.ndjson:3",
+	}
+	if len(origins) != len(want) {
+		t.Fatalf("expected %d records, got %d: %v", len(want), len(origins), origins)
+	}
+	for k, o := range want {
+		if origins[k] != o {
+			t.Errorf("key %s: expected origin %s, got %s", k, o, origins[k])
+		}
+	}
+}
+
+func TestLoadItems(t *testing.T) {
+	dir := t.TempDir()
+	obj := `{"category":"c","created_at":"2025-01-15T10:30:00.000Z","key":"%s","relationships":[],"source":"s","value":"v"}`
+	files := map[string]string{
+		"a.ndjson": strings.Replace(obj, "%s", "l1", 1) + "\nnot json\n",
+		"b.json":   "[" + strings.Replace(obj, "%s", "b0", 1) + `,{"key":"b1","value":null}]`,
+		"c.json":   "{",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Load(dir); err == nil {
+		t.Fatal("Load accepted invalid objects")
+	}
+
+	records, err := LoadItems(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range records {
+		origin := strings.TrimPrefix(filepath.ToSlash(r.Origin), filepath.ToSlash(dir)+"/")
+		if r.Err != nil {
+			origin += " invalid"
+		}
+		got = append(got, origin)
+	}
+	want := []string{"a.ndjson:1", "a.ndjson:2 invalid", "b.json#0", "b.json#1 invalid", "c.json invalid"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if _, err := LoadItems(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("a missing path was not an error")
+	}
+}
+
+func TestLoadRaw(t *testing.T) {
+	dir := t.TempDir()
+	obj := `{"category":"c","created_at":"2025-01-15T10:30:00.000Z","key":"%s","relationships":[],"source":"s", "value" : "v"}`
+	b0 := strings.Replace(obj, "%s", "b0", 1)
+	files := map[string]string{
+		"a.ndjson": strings.Replace(obj, "%s", "l1", 1) + "\nnot json\n",
+		"b.json":   `{"objects": [` + b0 + `, {"key":"b1"}]}`,
+		"c.json":   " " + strings.Replace(obj, "%s", "c", 1) + "\n",
+		"d.json":   "{",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := LoadRaw(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		strings.Replace(obj, "%s", "l1", 1),
+		"not json",
+		b0,
+		`{"key":"b1"}`,
+		files["c.json"],
+		"{",
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got %d", len(want), len(records))
+	}
+	for i, r := range records {
+		if string(r.Raw) != want[i] {
+			t.Errorf("%s: expected raw %q, got %q", r.Origin, want[i], r.Raw)
+		}
+	}
+	if records[2].Err != nil || records[3].Err == nil {
+		t.Errorf("expected b.json#1 alone invalid: %v, %v", records[2].Err, records[3].Err)
+	}
+
+	if records, err := LoadItems(dir, nil); err != nil || records[0].Raw != nil {
+		t.Errorf("LoadItems kept raw bytes: %v", err)
+	}
+}
+
+func TestLoadEdges(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.json":         `[{"from":"x","key":"y","type":"cites"}]`,
+		"b.ndjson":       `{"from":"x","key":"z","type":"cites"}` + "\n\n" + `{"from":"w","key":"x","type":"cites"}` + "\n",
+		".hidden/c.json": "not json",
+		"notes.txt":      "ignored",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edges, err := LoadEdges(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ingest.Edge{{From: "x", Key: "y", Type: "cites"}, {From: "x", Key: "z", Type: "cites"}, {From: "w", Key: "x", Type: "cites"}}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("LoadEdges = %v, want %v", edges, want)
+	}
+
+	for content, msg := range map[string]string{
+		`{"from":"x","key":"y","type":"t","weight":1}`: "unknown field",
+		`{"from":"x","key":"y"}`:                       "no type",
+		`{"key":"y","type":"t"}`:                       "no from",
+	} {
+		p := filepath.Join(t.TempDir(), "bad.ndjson")
+		os.WriteFile(p, []byte(content+"\n"), 0644)
+		if _, err := LoadEdges(p); err == nil || !strings.Contains(err.Error(), msg) || !strings.Contains(err.Error(), "bad.ndjson:1") {
+			t.Errorf("LoadEdges(%s) = %v, want an error at line 1 containing %q", content, err, msg)
+		}
+	}
+}
+
+func TestLoadInterned(t *testing.T) {
+	dir := t.TempDir()
+	line := `{"category":"project","created_at":"2025-01-15T10:30:00.000Z","key":"k%","relationships":[{"key":"hub","type":"cites"}],"source":"user","value":{"owner":"a","tags":[{"name":"x"}]}}`
+	var lines []string
+	for _, k := range []string{"1", "2", "3"} {
+		lines = append(lines, strings.Replace(line, "%", k, 1))
+	}
+	path := filepath.Join(dir, "c.ndjson")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := ingest.NewInterner()
+	interned, err := LoadInterned(path, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(interned, plain) {
+		t.Errorf("interning changed the records:\n got %+v\nwant %+v", interned, plain)
+	}
+
+	same := func(a, b string) bool { return unsafe.StringData(a) == unsafe.StringData(b) }
+	a, b := interned[0].Object, interned[2].Object
+	if !same(a.Category, b.Category) || !same(a.Relationships[0].Type, b.Relationships[0].Type) || !same(a.Relationships[0].Key, b.Relationships[0].Key) {
+		t.Error("fields of different objects were not interned")
+	}
+	keyOf := func(v interface{}) string {
+		for k := range v.(map[string]interface{})["tags"].([]interface{})[0].(map[string]interface{}) {
+			return k
+		}
+		return ""
+	}
+	if !same(keyOf(a.Value), keyOf(b.Value)) {
+		t.Error("map keys of different values were not interned")
+	}
+	// The category, source, relationship key and type, and three map keys
+	// of the first object repeat in the other two.
+	if in.Hits != 2*7 {
+		t.Errorf("Hits = %d", in.Hits)
+	}
+}
@@ -0,0 +1,216 @@
+// Package corpusgen generates pseudo-random corpora of memory objects
+// from a seed, for regression runs that need many objects without
+// touching production data. The same seed and count always produce the
+// same corpus, byte for byte, so a corpus never has to be stored: its
+// hashes are frozen once and the corpus regenerated to check them.
+package corpusgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Version identifies the generator. Any change to what Generate produces
+// for a seed must bump it, or frozen hashes stop matching their corpus
+// for reasons that have nothing to do with the hash.
+const Version = "1"
+
+// Generate returns count objects generated from seed. Values are nested
+// maps and arrays of strings, int64s, and booleans; strings mix ASCII
+// with characters canonicalization treats specially: decomposed and
+// precomposed accents, characters outside the BMP, control characters,
+// quotes and backslashes, and HTML-sensitive punctuation. Every object is
+// valid and hashes without error.
+func Generate(seed uint64, count int) []object.MemoryObject {
+	g := &generator{src: rand.NewPCG(seed, 0)}
+	objs := make([]object.MemoryObject, count)
+	for i := range objs {
+		objs[i] = g.object(seed, i)
+	}
+	return objs
+}
+
+// generator draws only from its source's Uint64, through its own
+// reductions: the PCG algorithm is fixed, but the helpers of math/rand
+// are not promised to return the same values across Go releases.
+type generator struct {
+	src *rand.PCG
+}
+
+func (g *generator) intn(n int) int {
+	return int(g.src.Uint64() % uint64(n))
+}
+
+func (g *generator) pick(s []string) string {
+	return s[g.intn(len(s))]
+}
+
+var (
+	categories = []string{"fact", "preference", "project", "event", "note", "caf\u00e9", "cafe\u0301"}
+	sources    = []string{"user", "agent", "import", "gen-corpus", "\u30c6\u30b9\u30c8"}
+	relTypes   = []string{"related_to", "depends_on", "derived_from", "supersedes", "r\u00e9f\u00e9rence"}
+	// fragments are concatenated into strings and keys.
+	fragments = []string{
+		"a", "b", "Z", "key", "value", "0", "42", " ", "/", ".",
+		"\u00e9", "e\u0301", "\u00c5", "A\u030a", "\u1e9b\u0323", "\ufb01",
+		"\u4e2d\u6587", "\uff61", "\U0001f600", "\U0001d11e",
+		"\"", "\\", "\n", "\t", "\u0001", "\u001f", "\u007f", "\u0085", "\u2028",
+		"<", ">", "&",
+	}
+)
+
+func (g *generator) string(maxParts int) string {
+	n := 1 + g.intn(maxParts)
+	s := ""
+	for i := 0; i < n; i++ {
+		s += g.pick(fragments)
+	}
+	return s
+}
+
+func (g *generator) object(seed uint64, i int) object.MemoryObject {
+	obj := object.MemoryObject{
+		Key:       fmt.Sprintf("gen/%d/%d/%s", seed, i, g.string(3)),
+		Category:  g.pick(categories),
+		Source:    g.pick(sources),
+		CreatedAt: g.timestamp(),
+		Value:     g.value(0),
+	}
+	obj.Relationships = []object.Relationship{}
+	for n := g.intn(5); n > 0; n-- {
+		obj.Relationships = append(obj.Relationships, object.Relationship{
+			Key:  fmt.Sprintf("gen/%d/%d", seed, g.intn(i+1)),
+			Type: g.pick(relTypes),
+		})
+	}
+	return obj
+}
+
+// timestamp returns a millisecond timestamp in the years 2000 to 2099.
+func (g *generator) timestamp() string {
+	const from, to = 946684800000, 4102444800000
+	ms := from + int64(g.src.Uint64()%(to-from))
+	return time.UnixMilli(ms).UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+var edgeInts = []int64{0, 1, -1, math.MaxInt64, math.MinInt64, 1 << 53, 1<<53 + 1}
+
+func (g *generator) value(depth int) interface{} {
+	kind := g.intn(10)
+	if depth >= 3 && kind >= 7 {
+		kind = g.intn(7)
+	}
+	switch kind {
+	case 0, 1, 2:
+		return g.string(6)
+	case 3:
+		return int64(g.src.Uint64())
+	case 4:
+		return edgeInts[g.intn(len(edgeInts))]
+	case 5:
+		return int64(g.intn(1000))
+	case 6:
+		return g.intn(2) == 0
+	case 7, 8:
+		m := make(map[string]interface{})
+		for n := g.intn(6); n > 0; n-- {
+			m[g.string(3)] = g.value(depth + 1)
+		}
+		return m
+	default:
+		arr := make([]interface{}, g.intn(5))
+		for j := range arr {
+			arr[j] = g.value(depth + 1)
+		}
+		return arr
+	}
+}
+
+// WriteNDJSON writes objs as NDJSON, one object per line, in the form
+// helios hash-batch and the other corpus commands read. Strings are
+// written as generated, not normalized, so the corpus exercises NFC.
+func WriteNDJSON(w io.Writer, objs []object.MemoryObject) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, obj := range objs {
+		if err := enc.Encode(map[string]interface{}{
+			"_helios_schema_version": obj.Schema(),
+			"key":                    obj.Key,
+			"category":               obj.Category,
+			"source":                 obj.Source,
+			"created_at":             obj.CreatedAt,
+			"relationships":          obj.Relationships,
+			"value":                  obj.Value,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Freeze records the hashes of a generated corpus. It holds everything
+// needed to regenerate the corpus and check it: no corpus file is kept.
+type Freeze struct {
+	Generator string `json:"generator"`
+	Seed      uint64 `json:"seed"`
+	Count     int    `json:"count"`
+	// Profile is the profile hash the hashes were computed under.
+	Profile string   `json:"profile"`
+	Hashes  []string `json:"hashes"`
+}
+
+// NewFreeze hashes objs, generated from seed, under the current pipeline.
+func NewFreeze(seed uint64, objs []object.MemoryObject) (*Freeze, error) {
+	p := hash.Current()
+	f := &Freeze{Generator: Version, Seed: seed, Count: len(objs), Profile: p.Profile.ID(), Hashes: make([]string, len(objs))}
+	for i, obj := range objs {
+		h, err := p.ContentHash(obj)
+		if err != nil {
+			return nil, fmt.Errorf("object %d (key %q): %w", i, obj.Key, err)
+		}
+		f.Hashes[i] = h
+	}
+	return f, nil
+}
+
+// Mismatch is an object whose hash differs from the frozen one.
+type Mismatch struct {
+	Index  int    `json:"index"`
+	Key    string `json:"key"`
+	Frozen string `json:"frozen"`
+	Hash   string `json:"hash"`
+}
+
+// Check regenerates the corpus f was frozen from and rehashes it under
+// the pipeline of f's profile, returning the objects whose hashes
+// changed. A freeze from another generator version cannot be checked.
+func (f *Freeze) Check() ([]Mismatch, error) {
+	if f.Generator != Version {
+		return nil, fmt.Errorf("hashes were frozen with corpus generator %s, this build has %s", f.Generator, Version)
+	}
+	if len(f.Hashes) != f.Count {
+		return nil, fmt.Errorf("freeze has %d hashes for %d objects", len(f.Hashes), f.Count)
+	}
+	p, err := hash.LookupID(f.Profile)
+	if err != nil {
+		return nil, err
+	}
+	var out []Mismatch
+	for i, obj := range Generate(f.Seed, f.Count) {
+		h, err := p.ContentHash(obj)
+		if err != nil {
+			h = "error: " + err.Error()
+		}
+		if h != f.Hashes[i] {
+			out = append(out, Mismatch{Index: i, Key: obj.Key, Frozen: f.Hashes[i], Hash: h})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package corpusgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// goldenCorpus is the SHA-256 of the NDJSON of Generate(1, 200). A change
+// to it is a change to the generator and must bump Version.
+const goldenCorpus = "27ac682464d0be27c4dad862249f6bbc7b3f74f62701c810ffc9dd0e3a6cdd81"
+
+func corpusBytes(t *testing.T, seed uint64, count int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, Generate(seed, count)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	a := corpusBytes(t, 1, 200)
+	sum := sha256.Sum256(a)
+	if got := hex.EncodeToString(sum[:]); got != goldenCorpus {
+		t.Errorf("corpus of seed 1 has digest %s, want %s", got, goldenCorpus)
+	}
+	if !bytes.Equal(a, corpusBytes(t, 1, 200)) {
+		t.Error("same seed generated different corpora")
+	}
+	if bytes.Equal(a, corpusBytes(t, 2, 200)) {
+		t.Error("different seeds generated the same corpus")
+	}
+	if b := corpusBytes(t, 1, 100); !bytes.HasPrefix(a, b) {
+		t.Error("a shorter corpus is not a prefix of a longer one with the same seed")
+	}
+}
+
+func TestGeneratedCorpusReadsBack(t *testing.T) {
+	objs := Generate(3, 300)
+	f, err := NewFreeze(3, objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, objs); err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n")) {
+		obj, err := ingest.ParseObject(line)
+		if err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if h, err := hash.ContentHash(obj); err != nil || h != f.Hashes[i] {
+			t.Fatalf("line %d hashes to %s, %v after reading back, want %s", i+1, h, err, f.Hashes[i])
+		}
+	}
+}
+
+func TestFreezeCheck(t *testing.T) {
+	f, err := NewFreeze(5, Generate(5, 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := f.Check(); err != nil || len(m) != 0 {
+		t.Fatalf("Check = %v, %v; want no mismatches", m, err)
+	}
+	f.Hashes[7] = "0000"
+	m, err := f.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].Index != 7 || m[0].Frozen != "0000" {
+		t.Errorf("Check = %+v, want object 7", m)
+	}
+	f.Generator = "0"
+	if _, err := f.Check(); err == nil {
+		t.Error("Check accepted a freeze from another generator version")
+	}
+}
@@ -0,0 +1,82 @@
+// Package dedup finds memory objects whose hash-relevant content is
+// identical apart from their key (and optionally created_at).
+package dedup
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Options controls which hash fields are ignored when comparing content.
+// key is always ignored.
+type Options struct {
+	IgnoreCreatedAt bool
+}
+
+// Cluster is a group of two or more objects with identical content.
+// Indexes refer to the slice passed to Find, in ascending order.
+type Cluster struct {
+	Fingerprint string
+	Indexes     []int
+	// Keep is the index of the suggested merge target: the member with
+	// the earliest created_at, ties broken by key.
+	Keep int
+}
+
+// Fingerprint returns the content hash of obj with key blanked, and
+// created_at replaced by hash.DraftCreatedAt when IgnoreCreatedAt is set.
+// Two objects with equal fingerprints have byte-identical canonical
+// content outside the ignored fields.
+func Fingerprint(obj object.MemoryObject, opts Options) (string, error) {
+	obj.Key = ""
+	if opts.IgnoreCreatedAt {
+		obj.CreatedAt = hash.DraftCreatedAt
+	}
+	return hash.ContentHash(obj)
+}
+
+// Find groups objs by fingerprint and returns every group with more than
+// one member, ordered by the index of each group's first member.
+func Find(objs []object.MemoryObject, opts Options) ([]Cluster, error) {
+	groups := make(map[string][]int)
+	var order []string
+	for i, obj := range objs {
+		fp, err := Fingerprint(obj, opts)
+		if err != nil {
+			return nil, fmt.Errorf("object %d (key %q): %w", i, obj.Key, err)
+		}
+		if _, seen := groups[fp]; !seen {
+			order = append(order, fp)
+		}
+		groups[fp] = append(groups[fp], i)
+	}
+
+	var clusters []Cluster
+	for _, fp := range order {
+		idx := groups[fp]
+		if len(idx) < 2 {
+			continue
+		}
+		keep := idx[0]
+		for _, i := range idx[1:] {
+			if earlier(objs[i], objs[keep]) {
+				keep = i
+			}
+		}
+		clusters = append(clusters, Cluster{Fingerprint: fp, Indexes: idx, Keep: keep})
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Indexes[0] < clusters[j].Indexes[0] })
+	return clusters, nil
+}
+
+// earlier reports whether a should be preferred over b as a merge target.
+// Canonical timestamps compare correctly as strings.
+func earlier(a, b object.MemoryObject) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt < b.CreatedAt
+	}
+	return a.Key < b.Key
+}
@@ -0,0 +1,74 @@
+package dedup
+
+import (
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func obj(key, createdAt, value string) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "note",
+		CreatedAt:     createdAt,
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "agent",
+		Value:         value,
+	}
+}
+
+func TestFindGroupsAcrossKeys(t *testing.T) {
+	objs := []object.MemoryObject{
+		obj("a", "2025-01-02T00:00:00.000Z", "same"),
+		obj("b", "2025-01-02T00:00:00.000Z", "different"),
+		obj("c", "2025-01-02T00:00:00.000Z", "same"),
+		obj("d", "2025-01-01T00:00:00.000Z", "same"),
+	}
+
+	clusters, err := Find(objs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("expected 1 cluster, got %d", len(clusters))
+	}
+	if got := clusters[0].Indexes; len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Errorf("expected members [0 2], got %v", got)
+	}
+	if clusters[0].Keep != 0 {
+		t.Errorf("expected keep 0 (key tie-break), got %d", clusters[0].Keep)
+	}
+}
+
+func TestFindIgnoreCreatedAt(t *testing.T) {
+	objs := []object.MemoryObject{
+		obj("a", "2025-01-02T00:00:00.000Z", "same"),
+		obj("d", "2025-01-01T00:00:00.000Z", "same"),
+	}
+
+	clusters, err := Find(objs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 0 {
+		t.Fatalf("different created_at should not cluster by default, got %d", len(clusters))
+	}
+
+	clusters, err = Find(objs, Options{IgnoreCreatedAt: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("expected 1 cluster, got %d", len(clusters))
+	}
+	if clusters[0].Keep != 1 {
+		t.Errorf("expected earliest object to be kept, got index %d", clusters[0].Keep)
+	}
+}
+
+func TestFindPropagatesInvalidObject(t *testing.T) {
+	objs := []object.MemoryObject{obj("a", "bad-timestamp", "v")}
+	if _, err := Find(objs, Options{}); err == nil {
+		t.Error("expected error for invalid object, got nil")
+	}
+}
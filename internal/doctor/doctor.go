@@ -0,0 +1,372 @@
+// Package doctor diagnoses the environment a helios binary runs in. Hash
+// mismatches between machines almost always come from the environment,
+// not the code: a different build of the Unicode tables, a filesystem
+// that rewrites file names, or a skewed clock stamping created_at. Run
+// collects the evidence in one report that can be attached to an issue.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/verify"
+)
+
+// Status is the outcome of one check.
+type Status string
+
+const (
+	OK   Status = "ok"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	// Skip marks a check that had nothing to examine.
+	Skip Status = "skip"
+)
+
+// Check is the result of one diagnostic.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Version  string  `json:"version"`
+	Go       string  `json:"go"`
+	Platform string  `json:"platform"`
+	Checks   []Check `json:"checks"`
+}
+
+// Failed reports whether any check failed.
+func (r *Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures Run.
+type Options struct {
+	// Version is the helios version to report.
+	Version string
+	// Dir is the directory whose filesystem is probed, normally the
+	// store root; a missing directory skips the probe.
+	Dir string
+	// ClockURL, if set, is a server whose Date header the local clock is
+	// compared with.
+	ClockURL string
+	Client   *http.Client
+	// Vectors is a vectors file to verify.
+	Vectors []byte
+}
+
+// Clock skew beyond these is reported as a warning and a failure.
+const (
+	skewWarn = 2 * time.Second
+	skewFail = time.Minute
+)
+
+// Run performs every check.
+func Run(ctx context.Context, opts Options) *Report {
+	r := &Report{
+		Version:  opts.Version,
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	r.Checks = append(r.Checks,
+		checkUnicode(),
+		checkBuild(),
+		checkFIPS(),
+		checkLocale(),
+		checkFilesystem(opts.Dir),
+		checkClock(ctx, opts.Client, opts.ClockURL),
+		checkVectors(opts.Vectors),
+	)
+	r.Checks = append(r.Checks, checkPortability()...)
+	return r
+}
+
+// SelfCheck performs the checks of the hashing code alone, leaving out
+// the filesystem and the clock: the Unicode tables, key order, the
+// vectors, and the portability probes, which pin the hashes amd64
+// computes for the inputs architectures are likeliest to disagree on.
+func SelfCheck(opts Options) *Report {
+	r := &Report{
+		Version:  opts.Version,
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	r.Checks = append(r.Checks, checkUnicode(), checkBuild(), checkFIPS(), checkLocale(), checkVectors(opts.Vectors))
+	r.Checks = append(r.Checks, checkPortability()...)
+	return r
+}
+
+// nfcProbes are inputs whose NFC form has been stable across Unicode
+// versions; a build that gets one wrong has broken tables.
+var nfcProbes = []struct{ in, want string }{
+	{"e\u0301", "\u00e9"},             // a combining acute accent composes
+	{"\u212b", "\u00c5"},              // ANGSTROM SIGN is a singleton decomposition
+	{"\u1100\u1161", "\uac00"},        // conjoining jamo compose to a syllable
+	{"A\u030a\u0301", "\u01fa"},       // composition continues past the first mark
+	{"e\u0301\u0323", "\u1eb9\u0301"}, // marks are reordered by combining class
+}
+
+func checkUnicode() Check {
+	c := Check{Name: "unicode", Status: OK}
+	mod := "golang.org/x/text (version unknown)"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "golang.org/x/text" {
+				if dep.Replace != nil {
+					dep = dep.Replace
+				}
+				mod = dep.Path + " " + dep.Version
+			}
+		}
+	}
+	c.Detail = fmt.Sprintf("NFC tables: Unicode %s from %s; Go's unicode package: Unicode %s", norm.Version, mod, unicode.Version)
+	var bad []string
+	for _, p := range nfcProbes {
+		if got := canon.NormalizeString(p.in); got != p.want {
+			bad = append(bad, fmt.Sprintf("%+q normalized to %+q, want %+q", p.in, got, p.want))
+		}
+	}
+	if len(bad) > 0 {
+		c.Status = Fail
+		c.Detail += "; " + strings.Join(bad, "; ")
+		return c
+	}
+	if canon.TablesVersion() != canon.UnicodeVersion || canon.TablesDigest() != canon.UnicodeTablesDigest {
+		c.Status = Warn
+		c.Detail += fmt.Sprintf("; tables differ from the pinned Unicode %s, so stored hashes may change: run helios verify --unicode-impact", canon.UnicodeVersion)
+	}
+	return c
+}
+
+// checkBuild reports how the binary was built. The hash path is pure Go
+// whatever the build (see hash.BuildInfo), so it cannot fail; it records
+// what a FIPS or cross-compiled deployment is asked to attest.
+func checkBuild() Check {
+	b := hash.BuildInfo()
+	cgo := "cgo disabled"
+	if b.CGO {
+		cgo = "cgo enabled, unused by the hash path"
+	}
+	detail := fmt.Sprintf("%s (%s) for %s, %s", b.Go, b.Compiler, b.Platform, cgo)
+	if v, ok := b.Deps["golang.org/x/text"]; ok {
+		detail += "; golang.org/x/text " + v
+	}
+	return Check{Name: "build", Status: OK, Detail: detail}
+}
+
+// checkFIPS reports the FIPS 140-3 posture. A fips build whose
+// cryptographic module is not in FIPS mode, because GODEBUG turned it
+// off, is a warning: its digests are approved but not validated.
+func checkFIPS() Check {
+	f := hash.FIPS()
+	c := Check{Name: "fips", Status: OK}
+	mode := "off"
+	if f.Enabled {
+		mode = "on"
+	}
+	c.Detail = fmt.Sprintf("FIPS 140-3 mode %s", mode)
+	if f.Module != "" {
+		c.Detail += ", Go Cryptographic Module " + f.Module
+	}
+	if f.BoringCrypto {
+		c.Detail += ", BoringCrypto"
+	}
+	c.Detail += "; digests " + strings.Join(f.Digests, ", ")
+	if f.Build {
+		c.Detail += " (fips build: approved digests only)"
+		if !f.Enabled {
+			c.Status = Warn
+			c.Detail += "; set GODEBUG=fips140=on"
+		}
+	}
+	return c
+}
+
+// checkLocale shows that key order ignores the locale: keys sort by code
+// point whatever LANG says.
+func checkLocale() Check {
+	c := Check{Name: "locale", Status: OK}
+	var env []string
+	for _, name := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			env = append(env, name+"="+v)
+		}
+	}
+	if len(env) == 0 {
+		env = append(env, "no locale set")
+	}
+	got, err := canon.CanonicalizeValue(map[string]interface{}{"b": 1, "\u00e4": 1, "a": 1, "A": 1, "\u00c4": 1})
+	const want = "{\"A\":1,\"a\":1,\"b\":1,\"\u00c4\":1,\"\u00e4\":1}"
+	switch {
+	case err != nil:
+		c.Status, c.Detail = Fail, err.Error()
+	case string(got) != want:
+		c.Status, c.Detail = Fail, fmt.Sprintf("keys sorted as %s, want %s (code point order)", got, want)
+	default:
+		c.Detail = strings.Join(env, ", ") + " (ignored: keys sort by code point, A < a < b < Ä < ä)"
+	}
+	return c
+}
+
+// checkFilesystem probes whether the filesystem under dir folds case or
+// rewrites the Unicode form of file names. The store names its files in
+// lowercase hex and is unaffected, but keys and manifest paths taken from
+// file names then differ between machines.
+func checkFilesystem(dir string) Check {
+	c := Check{Name: "filesystem"}
+	if dir == "" {
+		c.Status, c.Detail = Skip, "no directory to probe"
+		return c
+	}
+	if _, err := os.Stat(dir); err != nil {
+		c.Status, c.Detail = Skip, fmt.Sprintf("%s: %v", dir, err)
+		return c
+	}
+	probe, err := os.MkdirTemp(dir, ".helios-doctor-")
+	if err != nil {
+		c.Status, c.Detail = Warn, fmt.Sprintf("cannot probe %s: %v", dir, err)
+		return c
+	}
+	defer os.RemoveAll(probe)
+
+	const name = "probe-\u00e9" // NFC
+	if err := os.WriteFile(filepath.Join(probe, name), nil, 0o644); err != nil {
+		c.Status, c.Detail = Warn, fmt.Sprintf("cannot probe %s: %v", dir, err)
+		return c
+	}
+	_, err = os.Stat(filepath.Join(probe, strings.ToUpper(name)))
+	caseless := err == nil
+	entries, err := os.ReadDir(probe)
+	if err != nil || len(entries) != 1 {
+		c.Status, c.Detail = Warn, fmt.Sprintf("cannot probe %s: %v", dir, err)
+		return c
+	}
+	renamed := entries[0].Name() != name
+
+	var notes []string
+	if caseless {
+		notes = append(notes, "case-insensitive")
+	} else {
+		notes = append(notes, "case-sensitive")
+	}
+	if renamed {
+		notes = append(notes, fmt.Sprintf("rewrites file names (%+q became %+q)", name, entries[0].Name()))
+	} else {
+		notes = append(notes, "preserves file names byte for byte")
+	}
+	c.Status, c.Detail = OK, dir+": "+strings.Join(notes, ", ")
+	if caseless || renamed {
+		c.Status = Warn
+		c.Detail += "; keys or manifest paths taken from file names may differ from other machines"
+	}
+	return c
+}
+
+func checkClock(ctx context.Context, client *http.Client, url string) Check {
+	c := Check{Name: "clock"}
+	if url == "" {
+		c.Status, c.Detail = Skip, "no reference server; pass --clock-url to measure skew"
+		return c
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		c.Status, c.Detail = Fail, err.Error()
+		return c
+	}
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		c.Status, c.Detail = Warn, fmt.Sprintf("cannot reach %s: %v", url, err)
+		return c
+	}
+	resp.Body.Close()
+	received := time.Now()
+	remote, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		c.Status, c.Detail = Warn, fmt.Sprintf("%s sent no usable Date header", url)
+		return c
+	}
+	// Date has whole seconds; compare it with the middle of the round
+	// trip, so the skew is known to about a second.
+	local := sent.Add(received.Sub(sent) / 2)
+	skew := local.Sub(remote).Round(time.Second)
+	c.Detail = fmt.Sprintf("local clock is %s %s %s", abs(skew), direction(skew), url)
+	switch {
+	case abs(skew) > skewFail:
+		c.Status = Fail
+		c.Detail += "; created_at stamped here will not match other machines"
+	case abs(skew) > skewWarn:
+		c.Status = Warn
+	default:
+		c.Status = OK
+	}
+	return c
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func direction(skew time.Duration) string {
+	if skew < 0 {
+		return "behind"
+	}
+	return "ahead of"
+}
+
+func checkVectors(data []byte) Check {
+	c := Check{Name: "vectors"}
+	if data == nil {
+		c.Status, c.Detail = Skip, "no vectors"
+		return c
+	}
+	vf, err := verify.ParseVectors(data)
+	if err != nil {
+		c.Status, c.Detail = Fail, err.Error()
+		return c
+	}
+	results, err := verify.VerifyVectorsFile(vf, verify.Options{})
+	var failed []string
+	for _, res := range results {
+		if !res.Pass {
+			failed = append(failed, fmt.Sprintf("%s (expected %s, got %s)", res.VectorID, res.Expected, res.Got))
+		}
+	}
+	switch {
+	case len(failed) > 0:
+		c.Status = Fail
+		c.Detail = fmt.Sprintf("%d of %d vectors failed: %s", len(failed), len(results), strings.Join(failed, "; "))
+	case err != nil:
+		c.Status, c.Detail = Fail, err.Error()
+	default:
+		c.Status = OK
+		c.Detail = fmt.Sprintf("all %d vectors of spec %s, vectors %s pass", len(results), vf.SpecVersion, vf.VectorsVersion)
+	}
+	return c
+}
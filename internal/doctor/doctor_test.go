@@ -0,0 +1,100 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func find(t *testing.T, r *Report, name string) Check {
+	t.Helper()
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %s check in %+v", name, r.Checks)
+	return Check{}
+}
+
+func TestRun(t *testing.T) {
+	vectors, err := os.ReadFile("../../test_vectors/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	r := Run(context.Background(), Options{Version: "test", Dir: dir, Vectors: vectors})
+	for _, name := range []string{"unicode", "locale", "vectors"} {
+		if c := find(t, r, name); c.Status != OK {
+			t.Errorf("%s: %s %s", name, c.Status, c.Detail)
+		}
+	}
+	if c := find(t, r, "filesystem"); c.Status == Fail || c.Status == Skip {
+		t.Errorf("filesystem: %s %s", c.Status, c.Detail)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("the filesystem probe left %d files behind", len(entries))
+	}
+	if c := find(t, r, "clock"); c.Status != Skip {
+		t.Errorf("clock without a reference: %s", c.Status)
+	}
+	if r.Failed() {
+		t.Error("a healthy environment failed")
+	}
+
+	r = Run(context.Background(), Options{Dir: dir + "/missing"})
+	if c := find(t, r, "filesystem"); c.Status != Skip {
+		t.Errorf("filesystem of a missing directory: %s", c.Status)
+	}
+}
+
+func TestVectorFailure(t *testing.T) {
+	data, err := os.ReadFile("../../test_vectors/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the first expected hash.
+	i := strings.Index(string(data), `"hash": "`) + len(`"hash": "`)
+	bad := append(append(append([]byte(nil), data[:i]...), '0'), data[i+1:]...)
+	if bad[i] == data[i] {
+		bad[i] = '1'
+	}
+	c := checkVectors(bad)
+	if c.Status != Fail || !strings.Contains(c.Detail, "1 of 17 vectors failed") {
+		t.Errorf("checkVectors: %s %s", c.Status, c.Detail)
+	}
+	if c := checkVectors([]byte("{")); c.Status != Fail {
+		t.Errorf("checkVectors of invalid JSON: %s", c.Status)
+	}
+}
+
+func TestClock(t *testing.T) {
+	var offset time.Duration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		offset time.Duration
+		want   Status
+		detail string
+	}{
+		{0, OK, ""},
+		{-10 * time.Second, Warn, "ahead of"},
+		{5 * time.Minute, Fail, "behind"},
+	} {
+		offset = tc.offset
+		c := checkClock(context.Background(), nil, srv.URL)
+		if c.Status != tc.want || !strings.Contains(c.Detail, tc.detail) {
+			t.Errorf("offset %s: %s %s", tc.offset, c.Status, c.Detail)
+		}
+	}
+	if c := checkClock(context.Background(), nil, "http://127.0.0.1:1"); c.Status != Warn {
+		t.Errorf("unreachable server: %s %s", c.Status, c.Detail)
+	}
+}
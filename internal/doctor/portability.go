@@ -0,0 +1,147 @@
+package doctor
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// A portabilityProbe is a memory object, as the JSON ingest reads, with
+// the hash it has on linux/amd64 or the error code it is rejected with
+// there. Every architecture must agree: the probes exercise what differs
+// between them, the width of int, the parsing of numbers at the edges of
+// their types, and the SHA-256 assembly each architecture has its own of.
+type portabilityProbe struct {
+	name  string
+	value string
+	hash  string
+	code  string
+}
+
+// integerProbes cross the 32-bit boundaries, where a 32-bit int or a
+// float64 would truncate or round.
+var integerProbes = []portabilityProbe{
+	{name: "int32 bounds", value: `[2147483647,-2147483648]`, hash: "5b9fc97b94c58f4dbe4468c8303c5d63ac5364d6a300e21c3a0bc8cf5d3a6601"},
+	{name: "beyond int32", value: `[2147483648,-2147483649,4294967295,4294967296]`, hash: "47e6d65695dd608529daa0ae05d7b7c4ac2e3db299b3d20606850cbbfc467fe6"},
+	{name: "int64 bounds", value: `[9223372036854775807,-9223372036854775808]`, hash: "ffa9895263fe025cf9bbdf44396014c6dfb68fc46531bfc0782d77335c323dbb"},
+	{name: "beyond float64 precision", value: `[9007199254740993,-9007199254740993]`, hash: "c8873da24a1a90dfaa0e7ec169928aea0358da920ec926859c871188d9d82638"},
+}
+
+// numberProbes are json.Number forms at the edges of what ingest accepts.
+var numberProbes = []portabilityProbe{
+	{name: "int64 max + 1", value: `9223372036854775808`, code: canon.ErrCodeIntegerOutOfRange},
+	{name: "int64 min - 1", value: `-9223372036854775809`, code: canon.ErrCodeIntegerOutOfRange},
+	{name: "uint64 max", value: `18446744073709551615`, code: canon.ErrCodeIntegerOutOfRange},
+	{name: "integral float", value: `1.0`, code: canon.ErrCodeFloatProhibited},
+	{name: "exponent", value: `1e3`, code: canon.ErrCodeFloatProhibited},
+	{name: "nested integers", value: `{"b":[0,-1,{"c":2147483648}],"a":9223372036854775807}`, hash: "98e554d1afb4592532f79d0ee92d663a10373fad4e7c38677524a1b7e1acfa77"},
+}
+
+// digestProbes are the FIPS 180-2 SHA-256 test messages, the last one
+// spanning two blocks so the big-endian length lands in the second.
+var digestProbes = []struct{ in, sum string }{
+	{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	{"abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq", "248d6a61d20638b8e5c026930c3e6039a33ce45964ff2167f6ecedd419db06c1"},
+}
+
+// utf8Probe has characters of every UTF-8 length, whose bytes must reach
+// the digest in the same order on every architecture.
+var utf8Probe = portabilityProbe{name: "UTF-8 byte order", value: "\"a\u00e9\u4e2d\U0001f600\"", hash: "c611c92d86296e7f06e972621db6be7b98aff3e0cf8c809a49a1530f4553d49e"}
+
+func checkPortability() []Check {
+	platform := fmt.Sprintf("%s, %d-bit int, %s", runtime.GOARCH, strconv.IntSize, byteOrder())
+	integers := runProbes(integerProbes)
+	nativeBad, nativeRun := nativeIntFailures()
+	integers = append(integers, nativeBad...)
+	digests := runProbes([]portabilityProbe{utf8Probe})
+	for _, p := range digestProbes {
+		sum := sha256.Sum256([]byte(p.in))
+		if got := hex.EncodeToString(sum[:]); got != p.sum {
+			digests = append(digests, fmt.Sprintf("SHA-256(%q) = %s, want %s", p.in, got, p.sum))
+		}
+	}
+	return []Check{
+		probeCheck("integers", platform, len(integerProbes)+nativeRun, integers),
+		probeCheck("numbers", platform, len(numberProbes), runProbes(numberProbes)),
+		probeCheck("byteorder", platform, len(digestProbes)+1, digests),
+	}
+}
+
+func byteOrder() string {
+	var b [2]byte
+	binary.NativeEndian.PutUint16(b[:], 1)
+	if b[0] == 1 {
+		return "little-endian"
+	}
+	return "big-endian"
+}
+
+// nativeInts are values a Go caller may pass as int rather than int64;
+// each must canonicalize alike either way where int can hold it.
+var nativeInts = []int64{math.MaxInt32, math.MinInt32, math.MaxInt64, math.MinInt64}
+
+// nativeIntFailures returns the failures and the number of values run.
+func nativeIntFailures() (bad []string, n int) {
+	for _, v := range nativeInts {
+		if v < math.MinInt || v > math.MaxInt {
+			continue
+		}
+		n++
+		a, errA := canon.CanonicalizeValue(int(v))
+		b, errB := canon.CanonicalizeValue(v)
+		if errA != nil || errB != nil || string(a) != string(b) {
+			bad = append(bad, fmt.Sprintf("int %d canonicalized as %q, int64 as %q", v, a, b))
+		}
+	}
+	return bad, n
+}
+
+// runProbes returns a description of every probe that fails.
+func runProbes(probes []portabilityProbe) []string {
+	var bad []string
+	for _, p := range probes {
+		if err := p.run(); err != nil {
+			bad = append(bad, fmt.Sprintf("%s: %v", p.name, err))
+		}
+	}
+	return bad
+}
+
+func probeCheck(name, platform string, n int, bad []string) Check {
+	if len(bad) > 0 {
+		return Check{Name: name, Status: Fail, Detail: fmt.Sprintf("%s: %d of %d probes differ from amd64: %s", platform, len(bad), n, strings.Join(bad, "; "))}
+	}
+	return Check{Name: name, Status: OK, Detail: fmt.Sprintf("%s: all %d probes match amd64", platform, n)}
+}
+
+func (p portabilityProbe) run() error {
+	doc := `{"_helios_schema_version":"1","category":"selfcheck","created_at":"2026-01-01T00:00:00.000Z","key":"selfcheck/` + p.name + `","relationships":[],"source":"helios","value":` + p.value + `}`
+	obj, err := ingest.ParseObject([]byte(doc))
+	var h string
+	if err == nil {
+		h, err = hash.ContentHash(obj)
+	}
+	switch {
+	case p.code != "" && err == nil:
+		return fmt.Errorf("accepted, want %s", p.code)
+	case p.code != "" && canon.ErrorCode(err) != p.code:
+		return fmt.Errorf("%v, want %s", err, p.code)
+	case p.code != "":
+		return nil
+	case err != nil:
+		return err
+	case h != p.hash:
+		return fmt.Errorf("hash %s, want %s", h, p.hash)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package doctor
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestPortabilityProbes is the architecture suite: the probes pin the
+// amd64 hashes, so it fails on any platform that hashes differently.
+// GOARCH=386 go test ./internal/doctor runs it with a 32-bit int on an
+// amd64 machine; other architectures need their hardware or an emulator.
+func TestPortabilityProbes(t *testing.T) {
+	for _, c := range checkPortability() {
+		if c.Status != OK {
+			t.Errorf("%s: %s %s", c.Name, c.Status, c.Detail)
+		}
+	}
+}
+
+func TestPortabilityProbeFailures(t *testing.T) {
+	p := integerProbes[0]
+	p.hash = strings.Repeat("0", 64)
+	if err := p.run(); err == nil || !strings.Contains(err.Error(), "want 0000") {
+		t.Errorf("wrong hash: %v", err)
+	}
+	p = numberProbes[0]
+	p.value = "1"
+	if err := p.run(); err == nil || !strings.Contains(err.Error(), "accepted") {
+		t.Errorf("accepted rejection probe: %v", err)
+	}
+	p.value = "1.5"
+	if err := p.run(); err == nil || !strings.Contains(err.Error(), "CANON_ERR_FLOAT_PROHIBITED") {
+		t.Errorf("wrong rejection code: %v", err)
+	}
+	c := probeCheck("integers", "test", 3, []string{"a: x"})
+	if c.Status != Fail || !strings.Contains(c.Detail, "1 of 3 probes differ") {
+		t.Errorf("probeCheck: %s %s", c.Status, c.Detail)
+	}
+}
+
+func TestSelfCheck(t *testing.T) {
+	vectors, err := os.ReadFile("../../test_vectors/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := SelfCheck(Options{Version: "test", Vectors: vectors})
+	for _, name := range []string{"unicode", "locale", "vectors", "integers", "numbers", "byteorder"} {
+		if c := find(t, r, name); c.Status != OK {
+			t.Errorf("%s: %s %s", name, c.Status, c.Detail)
+		}
+	}
+	for _, c := range r.Checks {
+		if c.Name == "filesystem" || c.Name == "clock" {
+			t.Errorf("selfcheck ran the environment check %s", c.Name)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package feed is a persistent change feed of store mutations. A Log
+// records every put and delete made through a store (see
+// store.Options.Changes) with a sequence number, so a downstream system
+// can mirror the store by replaying the feed from the last change it saw
+// instead of polling full listings.
+//
+// The log is a file of JSON lines, one store.Change per line, appended to
+// and synced on every change and replayed into memory by Open. Changes
+// are never rewritten: a feed is a record of what happened, and sequence
+// numbers are never reused.
+package feed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// Log is an append-only change log. It implements store.ChangeLog and
+// is safe for concurrent use.
+type Log struct {
+	mu      sync.RWMutex
+	f       *os.File
+	changes []store.Change
+	// wake is closed and replaced whenever a change is appended.
+	wake chan struct{}
+}
+
+// Open opens the log at path, creating it if it does not exist. A torn
+// final line, left by a crash mid-append, is dropped.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{f: f, wake: make(chan struct{})}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	var good int64
+	for line := 1; sc.Scan(); line++ {
+		var c store.Change
+		err := json.Unmarshal(sc.Bytes(), &c)
+		if err == nil && c.Seq != l.last()+1 {
+			err = fmt.Errorf("sequence number %d follows %d", c.Seq, l.last())
+		}
+		if err != nil {
+			// Only the last line may be torn.
+			if sc.Scan() {
+				f.Close()
+				return nil, fmt.Errorf("change log %s: line %d: %w", path, line, err)
+			}
+			break
+		}
+		l.changes = append(l.changes, c)
+		good += int64(len(sc.Bytes())) + 1
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("change log %s: %w", path, err)
+	}
+	// Drop a torn line, and end a complete last line that lost its
+	// newline, so the next append starts a line of its own.
+	fi, err := f.Stat()
+	if err == nil {
+		switch {
+		case fi.Size() > good:
+			err = f.Truncate(good)
+		case fi.Size() < good:
+			_, err = f.Write([]byte("\n"))
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Close closes the log file. Subscriptions stay open until their
+// contexts are done.
+func (l *Log) Close() error {
+	return l.f.Close()
+}
+
+// last returns the sequence number of the newest change; l.mu must be
+// held, or l not yet shared.
+func (l *Log) last() uint64 {
+	if len(l.changes) == 0 {
+		return 0
+	}
+	return l.changes[len(l.changes)-1].Seq
+}
+
+// Last returns the sequence number of the newest change, 0 if there are
+// none.
+func (l *Log) Last() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.last()
+}
+
+// Append numbers c, writes it to the log, and wakes waiting readers.
+func (l *Log) Append(ctx context.Context, c store.Change) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c.Seq = l.last() + 1
+	line, err := json.Marshal(c)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	if err := l.f.Sync(); err != nil {
+		return 0, err
+	}
+	l.changes = append(l.changes, c)
+	close(l.wake)
+	l.wake = make(chan struct{})
+	return c.Seq, nil
+}
+
+// Since returns up to limit changes to tenant's namespace ("" for the
+// default one) numbered above seq, oldest first; limit <= 0 means no
+// limit. It also returns the position to resume from: the sequence
+// number of the last change it looked at, which is past the returned
+// changes and any other tenants' changes between them.
+func (l *Log) Since(tenant string, seq uint64, limit int) ([]store.Change, uint64) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	i := sort.Search(len(l.changes), func(i int) bool { return l.changes[i].Seq > seq })
+	out := []store.Change{}
+	next := seq
+	for _, c := range l.changes[i:] {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		next = c.Seq
+		if c.Tenant == tenant {
+			out = append(out, c)
+		}
+	}
+	return out, next
+}
+
+// Wait blocks until the log holds a change numbered above seq, or ctx is
+// done.
+func (l *Log) Wait(ctx context.Context, seq uint64) error {
+	for {
+		l.mu.RLock()
+		last, wake := l.last(), l.wake
+		l.mu.RUnlock()
+		if last > seq {
+			return nil
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Subscribe returns a channel of tenant's changes numbered above seq:
+// first those already in the log, then each new one as it is appended.
+// The channel is closed when ctx is done. A subscriber that falls behind
+// delays only itself.
+func (l *Log) Subscribe(ctx context.Context, tenant string, seq uint64) <-chan store.Change {
+	ch := make(chan store.Change)
+	go func() {
+		defer close(ch)
+		for {
+			batch, next := l.Since(tenant, seq, 256)
+			for _, c := range batch {
+				select {
+				case ch <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+			seq = next
+			if len(batch) > 0 {
+				continue
+			}
+			if err := l.Wait(ctx, seq); err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
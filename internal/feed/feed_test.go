@@ -0,0 +1,228 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+func memory(key, value string) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "user",
+		Value:         value,
+	}
+}
+
+func openLog(t *testing.T, path string) *Log {
+	t.Helper()
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestLogRecordsStoreChanges(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "changes.jsonl")
+	l := openLog(t, path)
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Changes: l})
+	h1, _ := s.Put(ctx, memory("a", "one"))
+	h2, _ := s.Put(ctx, memory("a", "two"))
+	if _, err := s.Put(ctx, memory("a", "two")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CompareAndSwap(ctx, memory("a", "three"), h1); err == nil {
+		t.Fatal("stale CompareAndSwap succeeded")
+	}
+	s.Put(ctx, memory("b", "bee"))
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "a"); err == nil {
+		t.Fatal("second delete succeeded")
+	}
+
+	want := []store.Change{
+		{Seq: 1, Op: store.ChangePut, Key: "a", New: h1},
+		{Seq: 2, Op: store.ChangePut, Key: "a", Old: h1, New: h2},
+		{Seq: 3, Op: store.ChangePut, Key: "b"},
+		{Seq: 4, Op: store.ChangeDelete, Key: "a", Old: h2},
+	}
+	check := func(l *Log) {
+		t.Helper()
+		got, next := l.Since("", 0, 0)
+		if len(got) != len(want) || next != 4 {
+			t.Fatalf("Since = %+v, %d", got, next)
+		}
+		for i, c := range got {
+			w := want[i]
+			if c.Seq != w.Seq || c.Op != w.Op || c.Key != w.Key || c.Old != w.Old || (w.New != "" && c.New != w.New) || c.Time == "" {
+				t.Errorf("change %d = %+v, want %+v", i+1, c, w)
+			}
+		}
+	}
+	check(l)
+	if got, next := l.Since("", 1, 2); len(got) != 2 || got[0].Seq != 2 || next != 3 {
+		t.Errorf("Since(1, 2) = %+v, %d", got, next)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A torn final line is dropped on reopen and its number reused.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"seq":5,"op":"pu`)
+	f.Close()
+	l = openLog(t, path)
+	defer l.Close()
+	check(l)
+	if seq, err := l.Append(ctx, store.Change{Op: store.ChangeDelete, Key: "b"}); err != nil || seq != 5 {
+		t.Errorf("Append after reopen = %d, %v", seq, err)
+	}
+}
+
+func TestTenantChanges(t *testing.T) {
+	ctx := context.Background()
+	l := openLog(t, filepath.Join(t.TempDir(), "changes.jsonl"))
+	defer l.Close()
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Changes: l})
+	acme, _ := s.Tenant(ctx, "acme")
+	s.Put(ctx, memory("a", "default"))
+	acme.Put(ctx, memory("a", "acme"))
+	acme.Put(ctx, memory("b", "acme"))
+	s.Put(ctx, memory("c", "default"))
+
+	got, next := l.Since("acme", 0, 0)
+	if len(got) != 2 || got[0].Seq != 2 || got[1].Seq != 3 || got[0].Tenant != "acme" || next != 4 {
+		t.Errorf("Since(acme) = %+v, %d", got, next)
+	}
+	if got, next := l.Since("acme", 3, 0); len(got) != 0 || next != 4 {
+		t.Errorf("Since(acme, 3) = %+v, %d", got, next)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := openLog(t, filepath.Join(t.TempDir(), "changes.jsonl"))
+	defer l.Close()
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Changes: l})
+	s.Put(ctx, memory("a", "before"))
+
+	ch := l.Subscribe(ctx, "", 0)
+	go func() {
+		s.Put(ctx, memory("b", "after"))
+		s.Delete(ctx, "a")
+	}()
+	for _, want := range []string{"a", "b", "a"} {
+		select {
+		case c := <-ch:
+			if c.Key != want {
+				t.Errorf("got change to %q, want %q", c.Key, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+		}
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestGatewayChanges(t *testing.T) {
+	ctx := context.Background()
+	l := openLog(t, filepath.Join(t.TempDir(), "changes.jsonl"))
+	defer l.Close()
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Changes: l})
+	s.Put(ctx, memory("a", "one"))
+	s.Put(ctx, memory("b", "two"))
+	srv := httptest.NewServer(store.NewGateway(s, store.GatewayOptions{Changes: l}))
+	defer srv.Close()
+
+	type page struct {
+		Changes []store.Change `json:"changes"`
+		Next    uint64         `json:"next"`
+	}
+	poll := func(query string) (int, page) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/changes" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body page
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+	if code, body := poll("?limit=1"); code != 200 || len(body.Changes) != 1 || body.Changes[0].Key != "a" || body.Next != 1 {
+		t.Errorf("GET /changes?limit=1: %d %+v", code, body)
+	}
+	if code, body := poll("?since=2"); code != 200 || len(body.Changes) != 0 || body.Next != 2 {
+		t.Errorf("GET /changes?since=2: %d %+v", code, body)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.Put(ctx, memory("c", "three"))
+	}()
+	if code, body := poll("?since=2&wait=10s"); code != 200 || len(body.Changes) != 1 || body.Changes[0].Key != "c" {
+		t.Errorf("long poll: %d %+v", code, body)
+	}
+	for _, q := range []string{"?since=x", "?limit=0", "?wait=2h"} {
+		if code, _ := poll(q); code != http.StatusBadRequest {
+			t.Errorf("GET /changes%s: %d", q, code)
+		}
+	}
+
+	// An event stream resumes after Last-Event-ID.
+	req, _ := http.NewRequest("GET", srv.URL+"/changes", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Last-Event-ID", "1")
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resp, err := http.DefaultClient.Do(req.WithContext(reqCtx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	go s.Delete(ctx, "b")
+	r := bufio.NewReader(resp.Body)
+	var events []string
+	var last store.Change
+	for last.Seq != 4 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			events = append(events, strings.TrimSpace(id))
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &last); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if last.Op != store.ChangeDelete || last.Key != "b" {
+		t.Errorf("last event = %+v", last)
+	}
+	if strings.Join(events, ",") != "2,3,4" {
+		t.Errorf("event ids = %v", events)
+	}
+}
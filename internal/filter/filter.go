@@ -0,0 +1,457 @@
+// Package filter parses and evaluates filter expressions over memory
+// objects, so that bulk commands can select objects without piping them
+// through tools that guess at their structure:
+//
+//	category == "project" && key startswith "team/x" && created_at > "2025-01-01"
+//
+// An expression is comparisons of a field with a literal, combined with
+// && (and), || (or), ! (not), and parentheses; && binds tighter than ||.
+// The operators are ==, !=, <, <=, >, >=, startswith, endswith, and
+// contains.
+//
+// Fields are the object's top-level fields — key, category, source,
+// created_at, updated_at, last_accessed, tenant, residency, legal_hold,
+// supersedes, version, access_count, and confidence — and paths into its
+// value, written as in package canon without the leading "$":
+// value.owner.name, value.tags[0], value["a.b"].
+//
+// Literals are JSON strings, numbers, true, and false. Timestamps
+// compare as times, so a created_at may be compared with "2025-01-01" as
+// well as with a full timestamp; numbers compare as numbers. A comparison
+// whose field is missing or of another type than its literal is false,
+// except that != is always the negation of ==. On an array, contains asks
+// whether any element equals the literal.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Filter is a parsed filter expression.
+type Filter struct {
+	src  string
+	root node
+}
+
+// Parse parses a filter expression.
+func Parse(expr string) (*Filter, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, syntaxError(t, "expected && or || before "+t.String())
+	}
+	return &Filter{src: expr, root: root}, nil
+}
+
+// String returns the expression f was parsed from.
+func (f *Filter) String() string { return f.src }
+
+// Match reports whether obj satisfies f.
+func (f *Filter) Match(obj object.MemoryObject) bool {
+	return f.root.eval(obj)
+}
+
+// Fields returns the distinct top-level fields f refers to, "value" for
+// any path into the value, in order of first use. A caller holding only
+// part of an object, such as a store's key index, can use it to tell
+// whether it needs the whole object.
+func (f *Filter) Fields() []string {
+	var out []string
+	seen := map[string]bool{}
+	f.root.walk(func(c *comparison) {
+		name := c.field.name
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	})
+	return out
+}
+
+type node interface {
+	eval(obj object.MemoryObject) bool
+	walk(fn func(*comparison))
+}
+
+type and struct{ l, r node }
+type or struct{ l, r node }
+type not struct{ x node }
+
+func (n *and) eval(obj object.MemoryObject) bool { return n.l.eval(obj) && n.r.eval(obj) }
+func (n *or) eval(obj object.MemoryObject) bool  { return n.l.eval(obj) || n.r.eval(obj) }
+func (n *not) eval(obj object.MemoryObject) bool { return !n.x.eval(obj) }
+
+func (n *and) walk(fn func(*comparison)) { n.l.walk(fn); n.r.walk(fn) }
+func (n *or) walk(fn func(*comparison))  { n.l.walk(fn); n.r.walk(fn) }
+func (n *not) walk(fn func(*comparison)) { n.x.walk(fn) }
+
+// Field kinds, which decide how a field's values compare.
+const (
+	kindString = iota
+	kindTime
+	kindNumber
+	kindValue
+)
+
+// fields maps the top-level fields a filter may name to their kinds.
+var fields = map[string]int{
+	"key":           kindString,
+	"category":      kindString,
+	"source":        kindString,
+	"created_at":    kindTime,
+	"updated_at":    kindTime,
+	"last_accessed": kindTime,
+	"tenant":        kindString,
+	"residency":     kindString,
+	"legal_hold":    kindString,
+	"supersedes":    kindString,
+	"version":       kindNumber,
+	"access_count":  kindNumber,
+	"confidence":    kindNumber,
+	"value":         kindValue,
+}
+
+type field struct {
+	name string
+	kind int
+	path []canon.PathSegment // into the value, for kindValue
+}
+
+// get returns the field's value in obj: a string, a time.Time, a
+// *big.Rat, or a decoded value, and whether obj has it at all.
+func (f field) get(obj object.MemoryObject) (interface{}, bool) {
+	switch f.name {
+	case "key":
+		return obj.Key, true
+	case "category":
+		return obj.Category, true
+	case "source":
+		return obj.Source, true
+	case "tenant":
+		return obj.Tenant, true
+	case "residency":
+		return obj.Residency, true
+	case "legal_hold":
+		return obj.LegalHold, true
+	case "supersedes":
+		return obj.Supersedes, true
+	case "created_at":
+		return parseTime(obj.CreatedAt)
+	case "updated_at":
+		return parseTime(obj.UpdatedAt)
+	case "last_accessed":
+		return parseTime(obj.LastAccessed)
+	case "version":
+		return new(big.Rat).SetInt64(int64(obj.Version)), true
+	case "access_count":
+		return new(big.Rat).SetInt64(int64(obj.AccessCount)), true
+	case "confidence":
+		r, ok := new(big.Rat).SetString(fmt.Sprint(obj.Confidence))
+		return r, ok
+	}
+	return lookup(obj.Value, f.path)
+}
+
+func lookup(v interface{}, path []canon.PathSegment) (interface{}, bool) {
+	for _, seg := range path {
+		if seg.IsIndex {
+			arr, ok := v.([]interface{})
+			if !ok || seg.Index >= len(arr) {
+				return nil, false
+			}
+			v = arr[seg.Index]
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[seg.Key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// timeLayouts are the forms a timestamp literal may take, most precise
+// first; a date alone is midnight UTC.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseTime(s string) (interface{}, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Comparison operators.
+const (
+	opEq         = "=="
+	opNe         = "!="
+	opLt         = "<"
+	opLe         = "<="
+	opGt         = ">"
+	opGe         = ">="
+	opStartsWith = "startswith"
+	opEndsWith   = "endswith"
+	opContains   = "contains"
+)
+
+type comparison struct {
+	field field
+	op    string
+	// lit is the literal: a string, time.Time, *big.Rat, or bool.
+	lit interface{}
+}
+
+func (c *comparison) walk(fn func(*comparison)) { fn(c) }
+
+func (c *comparison) eval(obj object.MemoryObject) bool {
+	if c.op == opNe {
+		return !(&comparison{field: c.field, op: opEq, lit: c.lit}).eval(obj)
+	}
+	v, ok := c.field.get(obj)
+	if !ok {
+		return false
+	}
+	if c.op == opContains {
+		if arr, ok := v.([]interface{}); ok {
+			for _, el := range arr {
+				if cmp, ok := compare(normalize(el), c.lit); ok && cmp == 0 {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	v = normalize(v)
+	switch c.op {
+	case opStartsWith, opEndsWith, opContains:
+		s, ok := v.(string)
+		lit, isString := c.lit.(string)
+		if !ok || !isString {
+			return false
+		}
+		switch c.op {
+		case opStartsWith:
+			return strings.HasPrefix(s, lit)
+		case opEndsWith:
+			return strings.HasSuffix(s, lit)
+		}
+		return strings.Contains(s, lit)
+	}
+	cmp, ok := compare(v, c.lit)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case opEq:
+		return cmp == 0
+	case opLt:
+		return cmp < 0
+	case opLe:
+		return cmp <= 0
+	case opGt:
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// normalize turns a decoded JSON number into a *big.Rat, so that it
+// compares with number literals exactly.
+func normalize(v interface{}) interface{} {
+	if n, ok := v.(json.Number); ok {
+		if r, ok := new(big.Rat).SetString(string(n)); ok {
+			return r
+		}
+	}
+	return v
+}
+
+// compare orders a against b, which must be of the same type; ok is
+// false if they are not. Booleans order only as equal or not.
+func compare(a, b interface{}) (cmp int, ok bool) {
+	switch b := b.(type) {
+	case string:
+		if a, ok := a.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case time.Time:
+		if a, ok := a.(time.Time); ok {
+			return a.Compare(b), true
+		}
+	case *big.Rat:
+		if a, ok := a.(*big.Rat); ok {
+			return a.Cmp(b), true
+		}
+	case bool:
+		if a, ok := a.(bool); ok {
+			if a == b {
+				return 0, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) or() (node, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = &or{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) and() (node, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = &and{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) unary() (node, error) {
+	switch t := p.next(); t.kind {
+	case tokNot:
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &not{x}, nil
+	case tokLParen:
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, syntaxError(t, "expected ) before "+t.String())
+		}
+		return x, nil
+	case tokField:
+		return p.comparison(t)
+	default:
+		return nil, syntaxError(t, "expected a field, !, or ( before "+t.String())
+	}
+}
+
+func (p *parser) comparison(ft token) (node, error) {
+	f, err := parseField(ft)
+	if err != nil {
+		return nil, err
+	}
+	opt := p.next()
+	if opt.kind != tokOp {
+		return nil, syntaxError(opt, "expected an operator after "+ft.text+" before "+opt.String())
+	}
+	lt := p.next()
+	if lt.kind != tokLiteral {
+		return nil, syntaxError(lt, "expected a literal after "+opt.text+" before "+lt.String())
+	}
+	c := &comparison{field: f, op: opt.text, lit: lt.lit}
+	if err := c.check(lt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// check rejects comparisons that could never hold because the field's
+// kind and the literal disagree, and converts timestamp literals.
+func (c *comparison) check(lt token) error {
+	ordered := c.op == opLt || c.op == opLe || c.op == opGt || c.op == opGe
+	textual := c.op == opStartsWith || c.op == opEndsWith || c.op == opContains
+	switch c.field.kind {
+	case kindString:
+		if _, ok := c.lit.(string); !ok {
+			return syntaxError(lt, c.field.name+" is a string")
+		}
+	case kindTime:
+		s, ok := c.lit.(string)
+		if !ok || textual {
+			return syntaxError(lt, c.field.name+" is a timestamp, compared with ==, !=, <, <=, >, or >=")
+		}
+		t, ok := parseTime(s)
+		if !ok {
+			return syntaxError(lt, fmt.Sprintf("%q is not a timestamp or date", s))
+		}
+		c.lit = t
+	case kindNumber:
+		if _, ok := c.lit.(*big.Rat); !ok || textual {
+			return syntaxError(lt, c.field.name+" is a number, compared with ==, !=, <, <=, >, or >=")
+		}
+	case kindValue:
+		if _, ok := c.lit.(bool); ok && ordered {
+			return syntaxError(lt, "true and false compare only with == and !=")
+		}
+	}
+	return nil
+}
+
+func parseField(t token) (field, error) {
+	name, rest := t.text, ""
+	if i := strings.IndexAny(name, ".["); i >= 0 {
+		name, rest = name[:i], name[i:]
+	}
+	kind, ok := fields[name]
+	if !ok {
+		return field{}, syntaxError(t, fmt.Sprintf("unknown field %q", name))
+	}
+	if rest == "" {
+		return field{name: name, kind: kind}, nil
+	}
+	if kind != kindValue {
+		return field{}, syntaxError(t, fmt.Sprintf("only value has paths, not %s", name))
+	}
+	path, err := canon.ParsePath("$" + rest)
+	if err != nil {
+		return field{}, syntaxError(t, err.Error())
+	}
+	return field{name: name, kind: kind, path: path}, nil
+}
@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+func TestMatch(t *testing.T) {
+	obj, err := ingest.ParseObject([]byte(`{
+		"key": "team/x/plan",
+		"category": "project",
+		"source": "import",
+		"created_at": "2025-03-01T12:00:00.000Z",
+		"version": 3,
+		"residency": "eu",
+		"value": {"owner": {"name": "Ada"}, "tags": ["q1", "ops"], "budget": 1200, "done": false, "a.b": "dotted"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		expr string
+		want bool
+	}{
+		{`category == "project" && key startswith "team/x" && created_at > "2025-01-01"`, true},
+		{`category == "project" && created_at > "2025-03-01T12:00:00Z"`, false},
+		{`created_at >= "2025-03-01T12:00:00Z"`, true},
+		{`category == "note" || key endswith "/plan"`, true},
+		{`!(category == "project")`, false},
+		{`residency != "us"`, true},
+		{`tenant == ""`, true},
+		{`version >= 3 && version < 4`, true},
+		{`value.owner.name == "Ada"`, true},
+		{`value.tags contains "ops"`, true},
+		{`value.tags[0] == "q1"`, true},
+		{`value.budget > 1200`, false},
+		{`value.budget == 1.2e3`, true},
+		{`value.budget > 999`, true},
+		{`value.done == false`, true},
+		{`value["a.b"] == "dotted"`, true},
+		{`value.owner.name contains "d"`, true},
+		{`value.missing == "x"`, false},
+		{`value.missing != "x"`, true},
+		{`value.budget == "1200"`, false}, // a number is not a string
+		{`value.owner > "A"`, false},
+		{`category == "project" || key == "a" && key == "b"`, true}, // && binds tighter
+		{`(category == "project" || key == "a") && key == "b"`, false},
+	} {
+		f, err := Parse(tc.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if got := f.Match(obj); got != tc.want {
+			t.Errorf("%s = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		expr, want string
+		offset     int
+	}{
+		{``, "expected a field", 0},
+		{`category = "x"`, "unexpected '='", 9},
+		{`colour == "red"`, `unknown field "colour"`, 0},
+		{`category == project`, "expected a literal", 12},
+		{`category == "x" &&`, "expected a field", 18},
+		{`(category == "x"`, "expected )", 16},
+		{`category == "x" key == "y"`, "expected && or ||", 16},
+		{`category == 3`, "category is a string", 12},
+		{`created_at > "yesterday"`, "not a timestamp", 13},
+		{`created_at startswith "2025"`, "is a timestamp", 22},
+		{`version == "3"`, "is a number", 11},
+		{`value.done < true`, "only with == and !=", 13},
+		{`key.x == "y"`, "only value has paths", 0},
+		{`value.tags[x] == "y"`, "expected ]", 11},
+		{`key == "unterminated`, "invalid string", 7},
+		{`version == 1.2.3`, "invalid number", 11},
+	} {
+		_, err := Parse(tc.expr)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%s: error %v, want a SyntaxError", tc.expr, err)
+			continue
+		}
+		if !strings.Contains(se.Msg, tc.want) || se.Offset != tc.offset {
+			t.Errorf("%s: error at %d %q, want at %d %q", tc.expr, se.Offset, se.Msg, tc.offset, tc.want)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	f, err := Parse(`key startswith "a" && (value.x == 1 || category == "c" || value.y == 2 || key == "b")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(f.Fields(), " "); got != "key value category" {
+		t.Errorf("Fields() = %q", got)
+	}
+}
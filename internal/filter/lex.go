@@ -0,0 +1,179 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokField
+	tokOp
+	tokLiteral
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	lit  interface{} // for tokLiteral
+	off  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// SyntaxError reports where an expression fails to parse.
+type SyntaxError struct {
+	// Offset is the byte offset of the token at fault.
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: offset %d: %s", e.Offset, e.Msg)
+}
+
+func syntaxError(t token, msg string) error {
+	return &SyntaxError{Offset: t.off, Msg: msg}
+}
+
+// words are the operators spelled as words, and the literals that are.
+var words = map[string]token{
+	opStartsWith: {kind: tokOp},
+	opEndsWith:   {kind: tokOp},
+	opContains:   {kind: tokOp},
+	"true":       {kind: tokLiteral, lit: true},
+	"false":      {kind: tokLiteral, lit: false},
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; ; {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+			i++
+		}
+		if i == len(s) {
+			return append(toks, token{kind: tokEOF, off: i}), nil
+		}
+		t := token{off: i}
+		c := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], "&&"):
+			t.kind, t.text = tokAnd, "&&"
+		case strings.HasPrefix(s[i:], "||"):
+			t.kind, t.text = tokOr, "||"
+		case strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="),
+			strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			t.kind, t.text = tokOp, s[i:i+2]
+		case c == '<' || c == '>':
+			t.kind, t.text = tokOp, s[i:i+1]
+		case c == '!':
+			t.kind, t.text = tokNot, "!"
+		case c == '(':
+			t.kind, t.text = tokLParen, "("
+		case c == ')':
+			t.kind, t.text = tokRParen, ")"
+		case c == '"':
+			n, str, err := lexString(s[i:])
+			if err != nil {
+				return nil, &SyntaxError{Offset: i, Msg: err.Error()}
+			}
+			t.kind, t.text, t.lit = tokLiteral, s[i:i+n], str
+		case c == '-' || c >= '0' && c <= '9':
+			n := i + 1
+			for n < len(s) && strings.IndexByte("0123456789.eE+-", s[n]) >= 0 {
+				n++
+			}
+			t.kind, t.text = tokLiteral, s[i:n]
+			var num json.Number
+			r, ok := new(big.Rat).SetString(t.text)
+			if json.Unmarshal([]byte(t.text), &num) != nil || !ok {
+				return nil, &SyntaxError{Offset: i, Msg: fmt.Sprintf("invalid number %q", t.text)}
+			}
+			t.lit = r
+		case isIdentStart(c):
+			n, err := lexField(s, i)
+			if err != nil {
+				return nil, err
+			}
+			t.text = s[i:n]
+			if w, ok := words[t.text]; ok {
+				t.kind, t.lit = w.kind, w.lit
+			} else {
+				t.kind = tokField
+			}
+		default:
+			return nil, &SyntaxError{Offset: i, Msg: fmt.Sprintf("unexpected %q", c)}
+		}
+		toks = append(toks, t)
+		i += len(t.text)
+	}
+}
+
+// lexString returns the length and value of the JSON string s starts with.
+func lexString(s string) (int, string, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	var str string
+	if err := dec.Decode(&str); err != nil {
+		return 0, "", fmt.Errorf("invalid string: %w", err)
+	}
+	return int(dec.InputOffset()), str, nil
+}
+
+// lexField returns the end of the field starting at s[i]: a name, then
+// any member and index selectors, as in value.tags[0] or value["a.b"].
+func lexField(s string, i int) (int, error) {
+	for i < len(s) && isIdent(s[i]) {
+		i++
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '.':
+			i++
+			for i < len(s) && (isIdent(s[i]) || s[i] == '-') {
+				i++
+			}
+		case '[':
+			if i+1 < len(s) && s[i+1] == '"' {
+				n, _, err := lexString(s[i+1:])
+				if err != nil {
+					return 0, &SyntaxError{Offset: i + 1, Msg: err.Error()}
+				}
+				i += 1 + n
+			} else {
+				for i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9' {
+					i++
+				}
+				i++
+			}
+			if i >= len(s) || s[i] != ']' {
+				return 0, &SyntaxError{Offset: i, Msg: "expected ] in field path"}
+			}
+			i++
+		default:
+			return i, nil
+		}
+	}
+	return i, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdent(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,155 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Formats are the export formats of Export.
+var Formats = []string{"dot", "graphml", "jsonld"}
+
+// Export writes g in format: "dot" (Graphviz), "graphml", or "jsonld".
+func Export(w io.Writer, g *Graph, format string) error {
+	switch format {
+	case "dot":
+		return WriteDOT(w, g)
+	case "graphml":
+		return WriteGraphML(w, g)
+	case "jsonld":
+		return WriteJSONLD(w, g)
+	}
+	return fmt.Errorf("unknown graph format %q (want %s)", format, strings.Join(Formats, ", "))
+}
+
+// WriteDOT writes g as a Graphviz digraph. Nodes are labeled with their
+// key and category and carry the content hash as a tooltip; missing nodes
+// are dashed. Edges are labeled with their type, and weight if any.
+func WriteDOT(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph helios {")
+	fmt.Fprintln(bw, "  node [shape=box];")
+	for _, n := range g.Nodes {
+		if n.Missing {
+			fmt.Fprintf(bw, "  %s [label=%s, style=dashed];\n", dotQuote(n.Key), dotQuote(n.Key))
+			continue
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, category=%s, hash=%s, tooltip=%s];\n",
+			dotQuote(n.Key), dotQuote(n.Key+"\n"+n.Category), dotQuote(n.Category), dotQuote(n.Hash), dotQuote(n.Hash))
+	}
+	for _, e := range g.Edges {
+		label := e.Type
+		attrs := ""
+		if e.Weight != nil {
+			label += " (" + strconv.FormatInt(*e.Weight, 10) + ")"
+			attrs += ", weight=" + strconv.FormatInt(*e.Weight, 10)
+		}
+		fmt.Fprintf(bw, "  %s -> %s [label=%s, type=%s%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(label), dotQuote(e.Type), attrs)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a DOT double-quoted string.
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// WriteGraphML writes g as a GraphML document with the node attributes
+// category, hash, and missing and the edge attributes type, weight, and
+// note.
+func WriteGraphML(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	esc := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	for _, k := range []struct{ id, on, name, typ string }{
+		{"category", "node", "category", "string"},
+		{"hash", "node", "hash", "string"},
+		{"missing", "node", "missing", "boolean"},
+		{"type", "edge", "type", "string"},
+		{"weight", "edge", "weight", "long"},
+		{"note", "edge", "note", "string"},
+	} {
+		fmt.Fprintf(bw, "  <key id=%q for=%q attr.name=%q attr.type=%q/>\n", k.id, k.on, k.name, k.typ)
+	}
+	fmt.Fprintln(bw, `  <graph id="helios" edgedefault="directed">`)
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "    <node id=\"%s\">", esc(n.Key))
+		if n.Missing {
+			fmt.Fprint(bw, `<data key="missing">true</data>`)
+		} else {
+			fmt.Fprintf(bw, `<data key="category">%s</data><data key="hash">%s</data>`, esc(n.Category), n.Hash)
+		}
+		fmt.Fprintln(bw, "</node>")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "    <edge source=\"%s\" target=\"%s\"><data key=\"type\">%s</data>", esc(e.From), esc(e.To), esc(e.Type))
+		if e.Weight != nil {
+			fmt.Fprintf(bw, `<data key="weight">%d</data>`, *e.Weight)
+		}
+		if e.Note != nil {
+			fmt.Fprintf(bw, `<data key="note">%s</data>`, esc(*e.Note))
+		}
+		fmt.Fprintln(bw, "</edge>")
+	}
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
+
+// jsonldContext maps the terms of WriteJSONLD to IRIs. Nodes are named
+// urn:helios:key: followed by their percent-encoded key.
+var jsonldContext = map[string]interface{}{
+	"@vocab":        "urn:helios:vocab:",
+	"@base":         "urn:helios:key:",
+	"relationships": map[string]interface{}{"@container": "@set"},
+	"target":        map[string]interface{}{"@type": "@id"},
+	"weight":        map[string]interface{}{"@type": "http://www.w3.org/2001/XMLSchema#long"},
+}
+
+// WriteJSONLD writes g as a JSON-LD document: one MemoryObject per node,
+// with its key, category, content hash, and relationships, each naming
+// its target node.
+func WriteJSONLD(w io.Writer, g *Graph) error {
+	out := g.adjacency()
+	items := make([]map[string]interface{}, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		item := map[string]interface{}{"@id": url.PathEscape(n.Key), "@type": "MemoryObject", "key": n.Key}
+		if n.Missing {
+			item["missing"] = true
+		} else {
+			item["category"] = n.Category
+			item["hash"] = n.Hash
+		}
+		rels := make([]map[string]interface{}, 0, len(out[n.Key]))
+		for _, e := range out[n.Key] {
+			rel := map[string]interface{}{"type": e.Type, "target": url.PathEscape(e.To)}
+			if e.Weight != nil {
+				rel["weight"] = *e.Weight
+			}
+			if e.Note != nil {
+				rel["note"] = *e.Note
+			}
+			rels = append(rels, rel)
+		}
+		if len(rels) > 0 {
+			item["relationships"] = rels
+		}
+		items = append(items, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{"@context": jsonldContext, "@graph": items})
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	g, _ := Build(testObjects())
+	weight := int64(3)
+	g.Edges[0].Weight = &weight
+	g.Nodes[0].Category = `quote " and <tag>`
+
+	var dot bytes.Buffer
+	if err := Export(&dot, g, "dot"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"digraph helios {", `"docs/a" -> "docs/b" [label="cites (3)", type="cites", weight=3];`, `category="quote \" and <tag>"`, `"docs/gone" [label="docs/gone", style=dashed];`} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("DOT output lacks %s:\n%s", want, dot.String())
+		}
+	}
+
+	var gml bytes.Buffer
+	if err := Export(&gml, g, "graphml"); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(gml.Bytes(), &doc); err != nil {
+		t.Fatalf("GraphML does not parse: %v\n%s", err, gml.String())
+	}
+	if len(doc.Graph.Nodes) != 5 || len(doc.Graph.Edges) != 4 || doc.Graph.Nodes[0].Data[0].Value != `quote " and <tag>` {
+		t.Errorf("GraphML = %+v", doc.Graph)
+	}
+
+	var ld bytes.Buffer
+	if err := Export(&ld, g, "jsonld"); err != nil {
+		t.Fatal(err)
+	}
+	var jd struct {
+		Graph []map[string]interface{} `json:"@graph"`
+	}
+	if err := json.Unmarshal(ld.Bytes(), &jd); err != nil {
+		t.Fatal(err)
+	}
+	if len(jd.Graph) != 5 || jd.Graph[0]["@id"] != "docs%2Fa" || len(jd.Graph[0]["relationships"].([]interface{})) != 2 {
+		t.Errorf("JSON-LD = %s", ld.String())
+	}
+
+	if err := Export(&ld, g, "svg"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
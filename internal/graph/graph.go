@@ -0,0 +1,210 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Node is an object of the graph. A node that relationships point to but
+// the corpus does not hold has Missing set and no category or hash.
+type Node struct {
+	Key      string `json:"key"`
+	Category string `json:"category,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+	Missing  bool   `json:"missing,omitempty"`
+}
+
+// Edge is a relationship of the graph, from the object holding it to the
+// object its key names.
+type Edge struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Type   string  `json:"type"`
+	Weight *int64  `json:"weight,omitempty"`
+	Note   *string `json:"note,omitempty"`
+}
+
+// Graph is the relationship graph of a corpus, with nodes sorted by key
+// and edges by source, target, and type. Keys and types are in NFC.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Build returns the graph of objs, hashing each object. When objs holds
+// several objects with one key, as a corpus of revisions may, the last
+// is the node. Objects that fail to hash are returned as an error.
+func Build(objs []object.MemoryObject) (*Graph, error) {
+	byKey := make(map[string]object.MemoryObject, len(objs))
+	for _, obj := range objs {
+		byKey[canon.NormalizeString(obj.Key)] = obj
+	}
+	nodes := make(map[string]Node, len(byKey))
+	var edges []Edge
+	for key, obj := range byKey {
+		h, err := hash.ContentHash(obj)
+		if err != nil {
+			return nil, err
+		}
+		nodes[key] = Node{Key: key, Category: canon.NormalizeString(obj.Category), Hash: h}
+		for _, r := range obj.Relationships {
+			e := Edge{From: key, To: canon.NormalizeString(r.Key), Type: canon.NormalizeString(r.Type), Weight: r.Weight}
+			if r.Note != nil {
+				n := canon.NormalizeString(*r.Note)
+				e.Note = &n
+			}
+			edges = append(edges, e)
+		}
+	}
+	for _, e := range edges {
+		if _, ok := nodes[e.To]; !ok {
+			nodes[e.To] = Node{Key: e.To, Missing: true}
+		}
+	}
+	g := &Graph{Nodes: make([]Node, 0, len(nodes)), Edges: edges}
+	for _, n := range nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	g.sort()
+	return g, nil
+}
+
+func (g *Graph) sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool { return canon.CompareCanonicalKeys(g.Nodes[i].Key, g.Nodes[j].Key) < 0 })
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return canon.CompareCanonicalKeys(a.From, b.From) < 0
+		}
+		if a.To != b.To {
+			return canon.CompareCanonicalKeys(a.To, b.To) < 0
+		}
+		return compareEdges(a, b) < 0
+	})
+	if g.Edges == nil {
+		g.Edges = []Edge{}
+	}
+}
+
+// compareEdges orders edges of one source and target, as canonical
+// relationships are ordered: by type, then weight, then note, an absent
+// weight or note first.
+func compareEdges(a, b Edge) int {
+	if c := canon.CompareCanonicalKeys(a.Type, b.Type); c != 0 {
+		return c
+	}
+	switch {
+	case a.Weight == nil && b.Weight != nil:
+		return -1
+	case a.Weight != nil && b.Weight == nil:
+		return 1
+	case a.Weight != nil && *a.Weight != *b.Weight:
+		if *a.Weight < *b.Weight {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case a.Note == nil && b.Note != nil:
+		return -1
+	case a.Note != nil && b.Note == nil:
+		return 1
+	case a.Note != nil:
+		return canon.CompareCanonicalKeys(*a.Note, *b.Note)
+	}
+	return 0
+}
+
+// Filter selects part of a graph. The zero Filter selects everything.
+type Filter struct {
+	// Categories, if set, keeps only the nodes of these categories;
+	// missing nodes have none and are dropped.
+	Categories []string
+	// Prefix, if set, keeps only the nodes whose key starts with it.
+	Prefix string
+	// Root, if set, keeps only the nodes reachable from it by following
+	// at most Depth edges, among the nodes the other fields keep. A
+	// negative Depth is no limit.
+	Root  string
+	Depth int
+	// Types, if set, keeps only the edges of these types, and Root
+	// follows only them.
+	Types []string
+}
+
+// Select returns the subgraph of the nodes f keeps and the edges between
+// them that it keeps.
+func (g *Graph) Select(f Filter) *Graph {
+	keep := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		if f.Prefix != "" && !strings.HasPrefix(n.Key, canon.NormalizeString(f.Prefix)) {
+			continue
+		}
+		if len(f.Categories) > 0 && !containsNormalized(f.Categories, n.Category) {
+			continue
+		}
+		keep[n.Key] = true
+	}
+	if f.Root != "" {
+		keep = g.reachable(canon.NormalizeString(f.Root), f.Depth, f.Types, keep)
+	}
+	out := &Graph{Nodes: []Node{}, Edges: []Edge{}}
+	for _, n := range g.Nodes {
+		if keep[n.Key] {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if keep[e.From] && keep[e.To] && hasType(f.Types, e.Type) {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
+
+// reachable returns the nodes of allowed within depth edges of the given
+// types of root, walking only through allowed nodes.
+func (g *Graph) reachable(root string, depth int, types []string, allowed map[string]bool) map[string]bool {
+	out := make(map[string]bool)
+	if !allowed[root] {
+		return out
+	}
+	next := g.adjacency()
+	out[root] = true
+	frontier := []string{root}
+	for d := 0; len(frontier) > 0 && (depth < 0 || d < depth); d++ {
+		var level []string
+		for _, k := range frontier {
+			for _, e := range next[k] {
+				if allowed[e.To] && !out[e.To] && hasType(types, e.Type) {
+					out[e.To] = true
+					level = append(level, e.To)
+				}
+			}
+		}
+		frontier = level
+	}
+	return out
+}
+
+// adjacency returns the outgoing edges of each node.
+func (g *Graph) adjacency() map[string][]Edge {
+	next := make(map[string][]Edge)
+	for _, e := range g.Edges {
+		next[e.From] = append(next[e.From], e)
+	}
+	return next
+}
+
+func containsNormalized(list []string, s string) bool {
+	for _, v := range list {
+		if canon.NormalizeString(v) == s {
+			return true
+		}
+	}
+	return false
+}
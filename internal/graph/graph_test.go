@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func testObjects() []object.MemoryObject {
+	obj := func(key, category string, rels ...object.Relationship) object.MemoryObject {
+		return object.MemoryObject{Key: key, Category: category, CreatedAt: "2026-01-01T00:00:00.000Z", Source: "test", Value: "v", Relationships: rels}
+	}
+	rel := func(key, typ string) object.Relationship { return object.Relationship{Key: key, Type: typ} }
+	return []object.MemoryObject{
+		obj("docs/a", "doc", rel("docs/b", "cites"), rel("people/x", "author")),
+		obj("docs/b", "doc", rel("docs/c", "cites")),
+		obj("docs/c", "doc", rel("docs/gone", "cites")),
+		obj("people/x", "person"),
+	}
+}
+
+func keys(g *Graph) []string {
+	var out []string
+	for _, n := range g.Nodes {
+		out = append(out, n.Key)
+	}
+	return out
+}
+
+func TestBuild(t *testing.T) {
+	g, err := Build(testObjects())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"docs/a", "docs/b", "docs/c", "docs/gone", "people/x"}; !reflect.DeepEqual(keys(g), want) {
+		t.Errorf("nodes = %v, want %v", keys(g), want)
+	}
+	if n := g.Nodes[3]; !n.Missing || n.Hash != "" {
+		t.Errorf("docs/gone = %+v, want a missing node", n)
+	}
+	if n := g.Nodes[0]; n.Category != "doc" || len(n.Hash) != 64 {
+		t.Errorf("docs/a = %+v", n)
+	}
+	if len(g.Edges) != 4 || g.Edges[0].From != "docs/a" || g.Edges[0].To != "docs/b" {
+		t.Errorf("edges = %+v", g.Edges)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	g, _ := Build(testObjects())
+	for _, tc := range []struct {
+		f     Filter
+		nodes []string
+		edges int
+	}{
+		{Filter{}, []string{"docs/a", "docs/b", "docs/c", "docs/gone", "people/x"}, 4},
+		{Filter{Categories: []string{"doc"}}, []string{"docs/a", "docs/b", "docs/c"}, 2},
+		{Filter{Prefix: "people/"}, []string{"people/x"}, 0},
+		{Filter{Root: "docs/a", Depth: 1}, []string{"docs/a", "docs/b", "people/x"}, 2},
+		{Filter{Root: "docs/a", Depth: -1}, []string{"docs/a", "docs/b", "docs/c", "docs/gone", "people/x"}, 4},
+		{Filter{Root: "docs/a", Depth: -1, Categories: []string{"doc"}}, []string{"docs/a", "docs/b", "docs/c"}, 2},
+		{Filter{Root: "docs/b", Depth: 0}, []string{"docs/b"}, 0},
+		{Filter{Root: "nowhere", Depth: -1}, nil, 0},
+	} {
+		sub := g.Select(tc.f)
+		if !reflect.DeepEqual(keys(sub), tc.nodes) || len(sub.Edges) != tc.edges {
+			t.Errorf("Select(%+v) = %v with %d edges, want %v with %d", tc.f, keys(sub), len(sub.Edges), tc.nodes, tc.edges)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package graph treats a corpus of memory objects as a directed graph:
+// each object is a node named by its key, and each relationship an edge
+// to the object with the relationship's key.
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// InverseTypes maps a relationship type to the type of its inverse edge:
+// with "cites": "cited_by", an object A citing B means B should have a
+// cited_by relationship to A. Every entry also implies its reverse, and a
+// type may be its own inverse, as "related_to" usually is.
+type InverseTypes map[string]string
+
+// LoadInverseTypes reads InverseTypes from a JSON object of type names.
+func LoadInverseTypes(path string) (InverseTypes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inverse types: %w", err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: expected a JSON object mapping each type to its inverse: %w", path, err)
+	}
+	types, err := NewInverseTypes(m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return types, nil
+}
+
+// NewInverseTypes completes m with the reverse of each entry, NFC
+// normalizing the type names, and rejects a type given two inverses.
+func NewInverseTypes(m map[string]string) (InverseTypes, error) {
+	types := make(InverseTypes, 2*len(m))
+	add := func(from, to string) error {
+		if prev, ok := types[from]; ok && prev != to {
+			return fmt.Errorf("type %q has two inverses, %q and %q", from, prev, to)
+		}
+		types[from] = to
+		return nil
+	}
+	for _, from := range sortedKeys(m) {
+		from, to := canon.NormalizeString(from), canon.NormalizeString(m[from])
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("relationship types must not be empty (%q: %q)", from, to)
+		}
+		if err := add(from, to); err != nil {
+			return nil, err
+		}
+		if err := add(to, from); err != nil {
+			return nil, err
+		}
+	}
+	return types, nil
+}
+
+// InverseReport lists the inverse edges a corpus lacks.
+type InverseReport struct {
+	// Missing are the inverse edges whose From object is in the corpus
+	// but lacks them, in the form helios hash --relationships-from reads.
+	Missing []ingest.Edge `json:"missing"`
+	// Dangling are the inverse edges whose From object is not in the
+	// corpus, so no revision can add them.
+	Dangling []ingest.Edge `json:"dangling"`
+}
+
+// DeriveInverses finds, for every relationship of objs whose type has an
+// inverse, the inverse edge from its target back to its source, and
+// reports those the target does not already have. Keys and types are
+// compared in NFC; relationship attributes are not carried over.
+func DeriveInverses(objs []object.MemoryObject, types InverseTypes) *InverseReport {
+	type edge struct{ from, key, typ string }
+	nodes := make(map[string]bool, len(objs))
+	have := make(map[edge]bool)
+	for _, obj := range objs {
+		from := canon.NormalizeString(obj.Key)
+		nodes[from] = true
+		for _, r := range obj.Relationships {
+			have[edge{from, canon.NormalizeString(r.Key), canon.NormalizeString(r.Type)}] = true
+		}
+	}
+
+	r := &InverseReport{Missing: []ingest.Edge{}, Dangling: []ingest.Edge{}}
+	seen := make(map[edge]bool)
+	for _, obj := range objs {
+		key := canon.NormalizeString(obj.Key)
+		for _, rel := range obj.Relationships {
+			inv, ok := types[canon.NormalizeString(rel.Type)]
+			if !ok {
+				continue
+			}
+			e := edge{canon.NormalizeString(rel.Key), key, inv}
+			if have[e] || seen[e] {
+				continue
+			}
+			seen[e] = true
+			out := ingest.Edge{From: e.from, Key: e.key, Type: e.typ}
+			if nodes[e.from] {
+				r.Missing = append(r.Missing, out)
+			} else {
+				r.Dangling = append(r.Dangling, out)
+			}
+		}
+	}
+	sortEdges(r.Missing)
+	sortEdges(r.Dangling)
+	return r
+}
+
+// Revise returns a new revision of every object of objs that the edges
+// add a relationship to: a copy with the edges merged as by
+// ingest.MergeEdges. objs is not modified.
+func Revise(objs []object.MemoryObject, edges []ingest.Edge) []object.MemoryObject {
+	var revised []object.MemoryObject
+	for _, obj := range objs {
+		rev := []object.MemoryObject{obj}
+		if ingest.MergeEdges(rev, edges) == 0 {
+			continue
+		}
+		revised = append(revised, rev[0])
+	}
+	return revised
+}
+
+func sortEdges(edges []ingest.Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.From != b.From {
+			return canon.CompareCanonicalKeys(a.From, b.From) < 0
+		}
+		if a.Key != b.Key {
+			return canon.CompareCanonicalKeys(a.Key, b.Key) < 0
+		}
+		return canon.CompareCanonicalKeys(a.Type, b.Type) < 0
+	})
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,55 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func TestNewInverseTypes(t *testing.T) {
+	types, err := NewInverseTypes(map[string]string{"cites": "cited_by", "related_to": "related_to"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InverseTypes{"cites": "cited_by", "cited_by": "cites", "related_to": "related_to"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("NewInverseTypes = %v, want %v", types, want)
+	}
+	if _, err := NewInverseTypes(map[string]string{"parent_of": "child_of", "child_of": "member_of"}); err == nil {
+		t.Error("a type with two inverses was accepted")
+	}
+	if _, err := NewInverseTypes(map[string]string{"cites": ""}); err == nil {
+		t.Error("an empty inverse was accepted")
+	}
+}
+
+func TestDeriveInverses(t *testing.T) {
+	types, _ := NewInverseTypes(map[string]string{"cites": "cited_by", "related_to": "related_to"})
+	objs := []object.MemoryObject{
+		{Key: "a", Relationships: []object.Relationship{{Key: "b", Type: "cites"}, {Key: "c", Type: "related_to"}, {Key: "gone", Type: "cites"}, {Key: "b", Type: "mentions"}}},
+		{Key: "b", Relationships: []object.Relationship{}},
+		{Key: "c", Relationships: []object.Relationship{{Key: "a", Type: "related_to"}}},
+		{Key: "d", Relationships: []object.Relationship{{Key: "b", Type: "cited_by"}}},
+	}
+	r := DeriveInverses(objs, types)
+	wantMissing := []ingest.Edge{{From: "b", Key: "a", Type: "cited_by"}, {From: "b", Key: "d", Type: "cites"}}
+	if !reflect.DeepEqual(r.Missing, wantMissing) {
+		t.Errorf("Missing = %v, want %v", r.Missing, wantMissing)
+	}
+	if want := []ingest.Edge{{From: "gone", Key: "a", Type: "cited_by"}}; !reflect.DeepEqual(r.Dangling, want) {
+		t.Errorf("Dangling = %v, want %v", r.Dangling, want)
+	}
+
+	revised := Revise(objs, r.Missing)
+	if len(revised) != 1 || revised[0].Key != "b" || len(revised[0].Relationships) != 2 {
+		t.Fatalf("Revise = %+v", revised)
+	}
+	if len(objs[1].Relationships) != 0 {
+		t.Error("Revise modified its input")
+	}
+	if again := DeriveInverses(append(objs[:1:1], append(revised, objs[2:]...)...), types); len(again.Missing) != 0 {
+		t.Errorf("revised corpus still lacks %v", again.Missing)
+	}
+}
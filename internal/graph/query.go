@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Direction selects which edges of a node Neighbors follows.
+type Direction string
+
+const (
+	// Out follows the node's own relationships.
+	Out Direction = "out"
+	// In follows the relationships other nodes hold to it.
+	In Direction = "in"
+	// Both follows either.
+	Both Direction = "both"
+)
+
+// ParseDirection parses a direction name.
+func ParseDirection(name string) (Direction, error) {
+	switch d := Direction(name); d {
+	case Out, In, Both:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown direction %q (want out, in, or both)", name)
+}
+
+// Neighbors returns the edges of key in direction dir, in graph order. If
+// types is set, only edges of those types are returned.
+func (g *Graph) Neighbors(key string, dir Direction, types []string) []Edge {
+	key = canon.NormalizeString(key)
+	out := []Edge{}
+	for _, e := range g.Edges {
+		if !hasType(types, e.Type) {
+			continue
+		}
+		if (dir != In && e.From == key) || (dir != Out && e.To == key) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ShortestPath returns a shortest chain of relationships leading from one
+// key to another, following edges of the given types, or all edges if
+// types is empty. Of several shortest paths it returns the first in graph
+// order. The path from a node to itself is empty; ok is false if to is
+// not reachable from from, or from is not in the graph.
+func (g *Graph) ShortestPath(from, to string, types []string) (path []Edge, ok bool) {
+	from, to = canon.NormalizeString(from), canon.NormalizeString(to)
+	if !g.has(from) {
+		return nil, false
+	}
+	if from == to {
+		return []Edge{}, true
+	}
+	next := g.adjacency()
+	via := map[string]Edge{}
+	seen := map[string]bool{from: true}
+	frontier := []string{from}
+	for len(frontier) > 0 && !seen[to] {
+		var level []string
+		for _, k := range frontier {
+			for _, e := range next[k] {
+				if seen[e.To] || !hasType(types, e.Type) {
+					continue
+				}
+				seen[e.To] = true
+				via[e.To] = e
+				level = append(level, e.To)
+			}
+		}
+		frontier = level
+	}
+	if !seen[to] {
+		return nil, false
+	}
+	for k := to; k != from; k = via[k].From {
+		path = append(path, via[k])
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, true
+}
+
+// Reachable reports whether to can be reached from from by following
+// edges of the given types, or all edges if types is empty.
+func (g *Graph) Reachable(from, to string, types []string) bool {
+	_, ok := g.ShortestPath(from, to, types)
+	return ok
+}
+
+// Objects returns the objects of objs that are nodes of g, in node order.
+// Where objs holds several objects with one key, the last is returned, as
+// Build makes it the node. Missing nodes have no object.
+func (g *Graph) Objects(objs []object.MemoryObject) []object.MemoryObject {
+	byKey := make(map[string]object.MemoryObject, len(objs))
+	for _, obj := range objs {
+		byKey[canon.NormalizeString(obj.Key)] = obj
+	}
+	out := []object.MemoryObject{}
+	for _, n := range g.Nodes {
+		if obj, ok := byKey[n.Key]; ok && !n.Missing {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+func (g *Graph) has(key string) bool {
+	for _, n := range g.Nodes {
+		if n.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasType reports whether typ is one of types; every type is when types
+// is empty.
+func hasType(types []string, typ string) bool {
+	return len(types) == 0 || containsNormalized(types, typ)
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func edgeList(edges []Edge) [][2]string {
+	var out [][2]string
+	for _, e := range edges {
+		out = append(out, [2]string{e.From, e.To})
+	}
+	return out
+}
+
+func TestNeighbors(t *testing.T) {
+	g, _ := Build(testObjects())
+	for _, tc := range []struct {
+		key   string
+		dir   Direction
+		types []string
+		want  [][2]string
+	}{
+		{"docs/a", Out, nil, [][2]string{{"docs/a", "docs/b"}, {"docs/a", "people/x"}}},
+		{"docs/a", Out, []string{"cites"}, [][2]string{{"docs/a", "docs/b"}}},
+		{"docs/b", In, nil, [][2]string{{"docs/a", "docs/b"}}},
+		{"docs/b", Both, nil, [][2]string{{"docs/a", "docs/b"}, {"docs/b", "docs/c"}}},
+		{"people/x", Out, nil, nil},
+	} {
+		if got := edgeList(g.Neighbors(tc.key, tc.dir, tc.types)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Neighbors(%q, %s, %v) = %v, want %v", tc.key, tc.dir, tc.types, got, tc.want)
+		}
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g, _ := Build(testObjects())
+	for _, tc := range []struct {
+		from, to string
+		types    []string
+		want     [][2]string
+		ok       bool
+	}{
+		{"docs/a", "docs/gone", nil, [][2]string{{"docs/a", "docs/b"}, {"docs/b", "docs/c"}, {"docs/c", "docs/gone"}}, true},
+		{"docs/a", "people/x", nil, [][2]string{{"docs/a", "people/x"}}, true},
+		{"docs/a", "people/x", []string{"cites"}, nil, false},
+		{"docs/c", "docs/a", nil, nil, false},
+		{"docs/b", "docs/b", nil, nil, true},
+		{"nowhere", "nowhere", nil, nil, false},
+	} {
+		path, ok := g.ShortestPath(tc.from, tc.to, tc.types)
+		if ok != tc.ok || !reflect.DeepEqual(edgeList(path), tc.want) {
+			t.Errorf("ShortestPath(%q, %q, %v) = %v, %v, want %v, %v", tc.from, tc.to, tc.types, edgeList(path), ok, tc.want, tc.ok)
+		}
+		if g.Reachable(tc.from, tc.to, tc.types) != tc.ok {
+			t.Errorf("Reachable(%q, %q, %v) != %v", tc.from, tc.to, tc.types, tc.ok)
+		}
+	}
+}
+
+func TestKHopObjects(t *testing.T) {
+	objs := testObjects()
+	g, _ := Build(objs)
+	sub := g.Select(Filter{Root: "docs/a", Depth: 2, Types: []string{"cites"}})
+	if want := []string{"docs/a", "docs/b", "docs/c"}; !reflect.DeepEqual(keys(sub), want) {
+		t.Fatalf("nodes = %v, want %v", keys(sub), want)
+	}
+	if len(sub.Edges) != 2 {
+		t.Errorf("edges = %v, want the two cites edges", edgeList(sub.Edges))
+	}
+	got := sub.Objects(objs)
+	if len(got) != 3 || got[0].Key != "docs/a" || got[2].Key != "docs/c" {
+		t.Errorf("Objects = %+v", got)
+	}
+	if got := g.Objects(objs); len(got) != 4 {
+		t.Errorf("Objects of the whole graph = %d, want 4 without the missing node", len(got))
+	}
+}
@@ -0,0 +1,203 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// SealVersion is the version of the seal digest computation.
+const SealVersion = "1"
+
+// ErrSealMismatch is wrapped by the errors of VerifySeal for objects
+// that do not reproduce a seal.
+var ErrSealMismatch = errors.New("subgraph does not match its seal")
+
+// Seal commits to a k-hop subgraph: the nodes reachable from Root by
+// following at most Depth edges of Types (all types if empty), with the
+// content hash of every object among them, and every edge between them.
+// Digest is the SHA-256 of the canonical JSON of the other fields, so the
+// digest alone pins the whole slice.
+type Seal struct {
+	Version string   `json:"version"`
+	Root    string   `json:"root"`
+	Depth   int      `json:"depth"`
+	Types   []string `json:"types,omitempty"`
+	// Profile is the hash of the canonicalization profile the node
+	// hashes were computed under.
+	Profile string `json:"profile"`
+	Nodes   []Node `json:"nodes"`
+	Edges   []Edge `json:"edges"`
+	Digest  string `json:"digest"`
+}
+
+// NewSeal seals the subgraph of g within depth edges of the given types
+// of root. A negative depth is no limit.
+func NewSeal(g *Graph, root string, depth int, types []string) (*Seal, error) {
+	root = canon.NormalizeString(root)
+	if !g.has(root) {
+		return nil, fmt.Errorf("key %q is not in the graph", root)
+	}
+	if depth < 0 {
+		depth = -1
+	}
+	var norm []string
+	for _, t := range types {
+		norm = append(norm, canon.NormalizeString(t))
+	}
+	sort.Slice(norm, func(i, j int) bool { return canon.CompareCanonicalKeys(norm[i], norm[j]) < 0 })
+	norm = dedupSorted(norm)
+	sub := g.Select(Filter{Root: root, Depth: depth, Types: norm})
+	s := &Seal{
+		Version: SealVersion,
+		Root:    root,
+		Depth:   depth,
+		Types:   norm,
+		Profile: hash.Current().Profile.ID(),
+		Nodes:   sub.Nodes,
+		Edges:   sub.Edges,
+	}
+	s.Digest = s.digest()
+	return s, nil
+}
+
+// digest computes the seal digest over every field but Digest. Node
+// categories are covered by the node hashes and are left out.
+func (s *Seal) digest() string {
+	nodes := make([]interface{}, len(s.Nodes))
+	for i, n := range s.Nodes {
+		m := map[string]interface{}{"key": n.Key}
+		if n.Missing {
+			m["missing"] = true
+		} else {
+			m["hash"] = n.Hash
+		}
+		nodes[i] = m
+	}
+	edges := make([]interface{}, len(s.Edges))
+	for i, e := range s.Edges {
+		m := map[string]interface{}{"from": e.From, "to": e.To, "type": e.Type}
+		if e.Weight != nil {
+			m["weight"] = *e.Weight
+		}
+		if e.Note != nil {
+			m["note"] = *e.Note
+		}
+		edges[i] = m
+	}
+	types := make([]interface{}, len(s.Types))
+	for i, t := range s.Types {
+		types[i] = t
+	}
+	data, err := canon.CanonicalizeValue(map[string]interface{}{
+		"version": s.Version,
+		"root":    s.Root,
+		"depth":   int64(s.Depth),
+		"types":   types,
+		"profile": s.Profile,
+		"nodes":   nodes,
+		"edges":   edges,
+	})
+	if err != nil {
+		// Keys, hashes, and types come from a built graph and are
+		// valid strings, which cannot fail.
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySeal checks that s is intact and that objs reproduce it: the
+// traversal from s.Root is replayed over the graph of objs and must reach
+// the same nodes, with the same hashes, and the same edges. objs may be
+// just the sealed objects or any corpus holding them; objects the
+// traversal does not reach are ignored.
+func VerifySeal(s *Seal, objs []object.MemoryObject) error {
+	if s.Version != SealVersion {
+		return fmt.Errorf("unsupported seal version %q", s.Version)
+	}
+	if got := s.digest(); got != s.Digest {
+		return fmt.Errorf("seal digest %s does not match its contents (%s)", s.Digest, got)
+	}
+	if id := hash.Current().Profile.ID(); s.Profile != id {
+		return fmt.Errorf("seal was made under profile %s, this build hashes under %s", s.Profile, id)
+	}
+	g, err := Build(objs)
+	if err != nil {
+		return err
+	}
+	replay, err := NewSeal(g, s.Root, s.Depth, s.Types)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSealMismatch, err)
+	}
+	if replay.Digest == s.Digest {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrSealMismatch, sealDifference(s, replay))
+}
+
+// sealDifference describes the first difference between a seal and its
+// replay.
+func sealDifference(want, got *Seal) string {
+	have := make(map[string]Node, len(got.Nodes))
+	for _, n := range got.Nodes {
+		have[n.Key] = n
+	}
+	for _, n := range want.Nodes {
+		h, ok := have[n.Key]
+		switch {
+		case !ok:
+			return fmt.Sprintf("node %q is not reached", n.Key)
+		case n.Missing && !h.Missing:
+			return fmt.Sprintf("node %q was sealed missing but is present", n.Key)
+		case !n.Missing && h.Missing:
+			return fmt.Sprintf("object %q is missing", n.Key)
+		case n.Hash != h.Hash:
+			return fmt.Sprintf("object %q has hash %s, sealed %s", n.Key, h.Hash, n.Hash)
+		}
+		delete(have, n.Key)
+	}
+	for _, n := range got.Nodes {
+		if _, ok := have[n.Key]; ok {
+			return fmt.Sprintf("node %q is reached but was not sealed", n.Key)
+		}
+	}
+	for i := 0; i < len(want.Edges) || i < len(got.Edges); i++ {
+		switch {
+		case i >= len(got.Edges):
+			return fmt.Sprintf("edge %s is missing", describeEdge(want.Edges[i]))
+		case i >= len(want.Edges):
+			return fmt.Sprintf("edge %s was not sealed", describeEdge(got.Edges[i]))
+		case describeEdge(want.Edges[i]) != describeEdge(got.Edges[i]):
+			return fmt.Sprintf("edge %s was sealed as %s", describeEdge(got.Edges[i]), describeEdge(want.Edges[i]))
+		}
+	}
+	return "digest differs"
+}
+
+func describeEdge(e Edge) string {
+	s := fmt.Sprintf("%q -[%s]-> %q", e.From, e.Type, e.To)
+	if e.Weight != nil {
+		s += fmt.Sprintf(" weight %d", *e.Weight)
+	}
+	if e.Note != nil {
+		s += fmt.Sprintf(" note %q", *e.Note)
+	}
+	return s
+}
+
+func dedupSorted(list []string) []string {
+	var out []string
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
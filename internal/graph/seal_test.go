@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func TestSealVerifiesAgainstSliceAndCorpus(t *testing.T) {
+	objs := testObjects()
+	g, _ := Build(objs)
+	s, err := NewSeal(g, "docs/a", 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Nodes) != 4 || len(s.Edges) != 3 || len(s.Digest) != 64 {
+		t.Fatalf("seal = %+v", s)
+	}
+	slice := g.Select(Filter{Root: "docs/a", Depth: 2}).Objects(objs)
+	for name, set := range map[string][]object.MemoryObject{"corpus": objs, "slice": slice} {
+		if err := VerifySeal(s, set); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// Relationship order changes neither a hash nor the seal.
+	reordered := testObjects()
+	rels := reordered[0].Relationships
+	reordered[0].Relationships = []object.Relationship{rels[1], rels[0]}
+	g2, _ := Build(reordered)
+	if s2, _ := NewSeal(g2, "docs/a", 2, nil); s2.Digest != s.Digest {
+		t.Errorf("reordered relationships changed the digest")
+	}
+
+	if _, err := NewSeal(g, "nowhere", 1, nil); err == nil {
+		t.Errorf("sealing an absent root succeeded")
+	}
+}
+
+func TestSealDetectsTampering(t *testing.T) {
+	objs := testObjects()
+	g, _ := Build(objs)
+	s, _ := NewSeal(g, "docs/a", -1, []string{"cites"})
+	if len(s.Nodes) != 4 || !s.Nodes[3].Missing {
+		t.Fatalf("nodes = %+v, want docs/a to docs/gone", s.Nodes)
+	}
+
+	changed := testObjects()
+	changed[1].Value = "changed"
+	dropped := testObjects()[:2]
+	extra := append(testObjects(), object.MemoryObject{Key: "docs/gone", Category: "doc", CreatedAt: "2026-01-01T00:00:00.000Z", Source: "test", Value: "v"})
+	for _, tc := range []struct {
+		name string
+		objs []object.MemoryObject
+		want string
+	}{
+		{"changed value", changed, `object "docs/b" has hash`},
+		{"dropped object", dropped, `object "docs/c" is missing`},
+		{"added object", extra, `node "docs/gone" was sealed missing but is present`},
+	} {
+		err := VerifySeal(s, tc.objs)
+		if !errors.Is(err, ErrSealMismatch) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %s", tc.name, err, tc.want)
+		}
+	}
+
+	forged := *s
+	forged.Nodes = forged.Nodes[:3]
+	if err := VerifySeal(&forged, objs); err == nil || errors.Is(err, ErrSealMismatch) {
+		t.Errorf("edited seal: err = %v, want a digest error", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build goexperiment.boringcrypto
+
+package hash
+
+import "crypto/boring"
+
+func boringEnabled() bool { return boring.Enabled() }
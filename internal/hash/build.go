@@ -0,0 +1,70 @@
+package hash
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build describes the binary the hash path was compiled into. The hash
+// path, this package and canon, object, and ingest beneath it, is pure
+// Go: it uses no cgo, no unsafe, and nothing of the operating system, so
+// it hashes alike under CGO_ENABLED=0, cross-compiled, and on wasm.
+// TestPureGo enforces that; Build lets a deployment check the rest.
+type Build struct {
+	Go       string `json:"go"`
+	Compiler string `json:"compiler"`
+	Platform string `json:"platform"`
+	// CGO reports whether the binary was built with cgo enabled. The hash
+	// path does not use cgo either way; other packages may.
+	CGO bool `json:"cgo"`
+	// Module is the main module's path and version, if the binary records
+	// them.
+	Module string `json:"module,omitempty"`
+	// Deps maps the modules the hash path depends on to their versions.
+	Deps map[string]string `json:"deps,omitempty"`
+	// Settings are the build settings the binary records, such as
+	// GOARCH, GOAMD64, and -tags.
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// hashPathModules are the modules outside the standard library the hash
+// path imports.
+var hashPathModules = []string{"golang.org/x/text"}
+
+// BuildInfo describes the running binary.
+func BuildInfo() Build {
+	b := Build{
+		Go:       runtime.Version(),
+		Compiler: runtime.Compiler,
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		CGO:      cgoEnabled,
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	if info.Main.Path != "" {
+		b.Module = info.Main.Path + "@" + info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		for _, m := range hashPathModules {
+			if dep.Path != m {
+				continue
+			}
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			if b.Deps == nil {
+				b.Deps = make(map[string]string)
+			}
+			b.Deps[m] = dep.Version
+		}
+	}
+	for _, s := range info.Settings {
+		if b.Settings == nil {
+			b.Settings = make(map[string]string)
+		}
+		b.Settings[s.Key] = s.Value
+	}
+	return b
+}
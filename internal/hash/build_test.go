@@ -0,0 +1,86 @@
+package hash
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// forbidden are the imports that would tie the hash path to cgo or the
+// operating system.
+var forbidden = []string{"C", "runtime/cgo", "unsafe", "syscall", "os", "os/exec", "os/signal", "os/user", "io/ioutil", "path/filepath", "plugin", "net", "net/*", "golang.org/x/sys/*"}
+
+func isForbidden(path string) bool {
+	for _, f := range forbidden {
+		if path == f || strings.HasSuffix(f, "/*") && strings.HasPrefix(path, strings.TrimSuffix(f, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestPureGo checks that the hash path stays pure Go: no package it
+// depends on has cgo files, and none of its packages outside the
+// standard library imports cgo, unsafe, or the operating system. The
+// standard library beneath it may use the OS, as fmt does, but the hash
+// path never calls into that part.
+func TestPureGo(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	cmd := exec.Command(goTool, "list", "-deps", "-json", ".")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("go list: %v: %s", err, stderr.Bytes())
+	}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	var pkgs int
+	for {
+		var p struct {
+			ImportPath string
+			Standard   bool
+			CgoFiles   []string
+			Imports    []string
+		}
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		pkgs++
+		if len(p.CgoFiles) > 0 {
+			t.Errorf("%s has cgo files %v", p.ImportPath, p.CgoFiles)
+		}
+		if p.Standard {
+			continue
+		}
+		if !strings.HasPrefix(p.ImportPath, "github.com/holeyfield33-art/helios/") && !strings.HasPrefix(p.ImportPath, hashPathModules[0]+"/") {
+			t.Errorf("%s is outside the modules the hash path may use, %v", p.ImportPath, hashPathModules)
+		}
+		for _, imp := range p.Imports {
+			if isForbidden(imp) {
+				t.Errorf("%s imports %s", p.ImportPath, imp)
+			}
+		}
+	}
+	if pkgs == 0 {
+		t.Fatal("go list listed no packages")
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	b := BuildInfo()
+	if b.Go != runtime.Version() || b.Compiler != runtime.Compiler || b.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("BuildInfo = %+v", b)
+	}
+	if s, ok := b.Settings["CGO_ENABLED"]; ok && (s == "1") != b.CGO {
+		t.Errorf("CGO = %v, but CGO_ENABLED=%s", b.CGO, s)
+	}
+}
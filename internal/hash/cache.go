@@ -0,0 +1,194 @@
+package hash
+
+import (
+	"bytes"
+	"container/list"
+	"hash/maphash"
+	"sync"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Cache is an LRU memo of content hashes keyed by the raw bytes of a
+// memory object document, so re-hashing an identical payload (e.g. a
+// retried request or a redelivered message) skips parsing and
+// canonicalization. Lookups use a seeded 64-bit pre-hash of the raw
+// bytes and confirm hits by comparing the bytes themselves, so a
+// pre-hash collision can never return another document's hash.
+//
+// Only successful results are cached. A nil *Cache hashes every document.
+type Cache struct {
+	maxEntries int
+	maxBytes   int64
+	seed       maphash.Seed
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[uint64][]*list.Element
+	bytes   int64
+	stats   CacheStats
+}
+
+// CacheStats counts cache activity.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	// Bytes is the total size of the cached raw documents, and of the
+	// canonical bytes Hash keeps with them.
+	Bytes int64
+}
+
+type cacheEntry struct {
+	sum       uint64
+	raw       []byte
+	key, hash string
+	// pipeline and canonical are set on entries made by Hash, which
+	// only serves them to calls with the same pipeline; entries made by
+	// HashDocument have neither.
+	pipeline  *Pipeline
+	canonical []byte
+}
+
+// NewCache returns a cache holding at most maxEntries documents and
+// maxBytes of raw document bytes. A limit of zero or less is unbounded.
+func NewCache(maxEntries int, maxBytes int64) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		seed:       maphash.MakeSeed(),
+		order:      list.New(),
+		entries:    make(map[uint64][]*list.Element),
+	}
+}
+
+// HashDocument parses raw as a single memory object (see
+// ingest.ParseObject) and returns its key and content hash.
+func (c *Cache) HashDocument(raw []byte) (key, hash string, err error) {
+	if c == nil {
+		return hashDocument(raw)
+	}
+	sum := maphash.Bytes(c.seed, raw)
+	if e, ok := c.lookup(sum, raw, nil); ok {
+		return e.key, e.hash, nil
+	}
+	key, hash, err = hashDocument(raw)
+	if err != nil {
+		return "", "", err
+	}
+	c.add(&cacheEntry{sum: sum, raw: raw, key: key, hash: hash})
+	return key, hash, nil
+}
+
+// Hash returns the canonical bytes and content hash of obj under p, as
+// p.Hash does, memoized by raw, the document obj was parsed from, so a
+// store writing the same document again skips canonicalization. A
+// pipeline with Instrumentation is never memoized, so it sees every
+// object. The canonical bytes count towards maxBytes with raw; callers
+// must not modify them.
+func (c *Cache) Hash(p *Pipeline, raw []byte, obj object.MemoryObject) ([]byte, string, error) {
+	if c == nil || raw == nil || p.Instrumentation != nil {
+		return p.Hash(obj)
+	}
+	sum := maphash.Bytes(c.seed, raw)
+	if e, ok := c.lookup(sum, raw, p); ok {
+		return e.canonical, e.hash, nil
+	}
+	canonical, h, err := p.Hash(obj)
+	if err != nil {
+		return nil, "", err
+	}
+	c.add(&cacheEntry{sum: sum, raw: raw, key: obj.Key, hash: h, pipeline: p, canonical: canonical})
+	return canonical, h, nil
+}
+
+// lookup returns the entry for raw made with pipeline p, nil for
+// HashDocument, counting a hit or a miss.
+func (c *Cache) lookup(sum uint64, raw []byte, p *Pipeline) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el := c.find(sum, raw, p); el != nil {
+		c.order.MoveToFront(el)
+		c.stats.Hits++
+		return el.Value.(*cacheEntry), true
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *Cache) find(sum uint64, raw []byte, p *Pipeline) *list.Element {
+	for _, el := range c.entries[sum] {
+		e := el.Value.(*cacheEntry)
+		if e.pipeline == p && bytes.Equal(e.raw, raw) {
+			return el
+		}
+	}
+	return nil
+}
+
+// add caches e, copying its raw bytes, unless it alone exceeds maxBytes
+// or another goroutine cached it first, and evicts down to the limits.
+func (c *Cache) add(e *cacheEntry) {
+	size := e.size()
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.find(e.sum, e.raw, e.pipeline) != nil {
+		return
+	}
+	e.raw = bytes.Clone(e.raw)
+	c.entries[e.sum] = append(c.entries[e.sum], c.order.PushFront(e))
+	c.bytes += size
+	for (c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.evict(c.order.Back())
+	}
+}
+
+func (e *cacheEntry) size() int64 { return int64(len(e.raw) + len(e.canonical)) }
+
+func (c *Cache) evict(el *list.Element) {
+	e := c.order.Remove(el).(*cacheEntry)
+	bucket := c.entries[e.sum]
+	for i, b := range bucket {
+		if b == el {
+			bucket = append(bucket[:i], bucket[i+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(c.entries, e.sum)
+	} else {
+		c.entries[e.sum] = bucket
+	}
+	c.bytes -= e.size()
+	c.stats.Evictions++
+}
+
+// Stats returns the current counters.
+func (c *Cache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.order.Len()
+	s.Bytes = c.bytes
+	return s
+}
+
+func hashDocument(raw []byte) (string, string, error) {
+	obj, err := ingest.ParseObject(raw)
+	if err != nil {
+		return "", "", err
+	}
+	h, err := ContentHash(obj)
+	if err != nil {
+		return obj.Key, "", err
+	}
+	return obj.Key, h, nil
+}
@@ -0,0 +1,174 @@
+package hash
+
+import (
+	"container/list"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+func cacheDoc(i int) []byte {
+	return []byte(fmt.Sprintf(`{"category":"project","created_at":"2025-01-15T10:30:00.000Z","key":"k/%d","relationships":[],"source":"user","value":"v%d"}`, i, i))
+}
+
+func TestCacheMatchesContentHash(t *testing.T) {
+	c := NewCache(10, 0)
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 3; i++ {
+			key, h, err := c.HashDocument(cacheDoc(i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj, _ := ingest.ParseObject(cacheDoc(i))
+			want, _ := ContentHash(obj)
+			if h != want || key != obj.Key {
+				t.Errorf("round %d doc %d: got %s %s, want %s %s", round, i, key, h, obj.Key, want)
+			}
+		}
+	}
+	if s := c.Stats(); s.Hits != 3 || s.Misses != 3 || s.Entries != 3 {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(2, 0)
+	c.HashDocument(cacheDoc(0))
+	c.HashDocument(cacheDoc(1))
+	c.HashDocument(cacheDoc(0)) // 1 is now least recent
+	c.HashDocument(cacheDoc(2))
+	c.HashDocument(cacheDoc(0))
+	if s := c.Stats(); s.Hits != 2 || s.Evictions != 1 || s.Entries != 2 {
+		t.Errorf("stats %+v", s)
+	}
+	c.HashDocument(cacheDoc(1))
+	if s := c.Stats(); s.Misses != 4 {
+		t.Errorf("evicted document was served from cache: %+v", s)
+	}
+
+	size := int64(len(cacheDoc(0)))
+	c = NewCache(0, 2*size)
+	for i := 0; i < 5; i++ {
+		c.HashDocument(cacheDoc(i))
+	}
+	if s := c.Stats(); s.Entries != 2 || s.Bytes != 2*size {
+		t.Errorf("byte-bounded stats %+v", s)
+	}
+}
+
+func TestCacheDoesNotCacheErrors(t *testing.T) {
+	c := NewCache(10, 0)
+	bad := []byte(`{"key":"x","value":1.5}`)
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.HashDocument(bad); err == nil {
+			t.Fatal("float value accepted")
+		}
+	}
+	if s := c.Stats(); s.Entries != 0 || s.Hits != 0 {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestCachePreHashCollision(t *testing.T) {
+	c := NewCache(10, 0)
+	a, b := cacheDoc(1), cacheDoc(2)
+	// Plant b's entry under a's pre-hash, as a collision would.
+	sum := maphash.Bytes(c.seed, a)
+	c.entries[sum] = []*list.Element{c.order.PushFront(&cacheEntry{sum: sum, raw: b, key: "k/2", hash: "wrong"})}
+
+	key, h, err := c.HashDocument(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "k/1" || h == "wrong" {
+		t.Fatalf("collision returned %s %s", key, h)
+	}
+	if len(c.entries[sum]) != 2 {
+		t.Errorf("colliding documents should share a bucket, got %d", len(c.entries[sum]))
+	}
+	c.evict(c.order.Back())
+	if _, h, _ := c.HashDocument(a); h == "wrong" {
+		t.Error("evicting the colliding entry dropped the wrong document")
+	}
+}
+
+func TestCacheHashPipeline(t *testing.T) {
+	c := NewCache(10, 0)
+	doc := cacheDoc(0)
+	obj, _ := ingest.ParseObject(doc)
+	wantCanonical, want, _ := Current().Hash(obj)
+
+	c.HashDocument(doc)
+	for i := 0; i < 2; i++ {
+		canonical, h, err := c.Hash(Current(), doc, obj)
+		if err != nil || h != want || string(canonical) != string(wantCanonical) {
+			t.Errorf("call %d: %s %s, %v; want %s %s", i, canonical, h, err, wantCanonical, want)
+		}
+	}
+	// HashDocument's entry is not Hash's, and each pipeline has its own.
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 2 || s.Bytes != int64(2*len(doc)+len(wantCanonical)) {
+		t.Errorf("stats %+v", s)
+	}
+	if _, _, err := c.Hash(V1Strict, doc, obj); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.Stats(); s.Misses != 3 {
+		t.Errorf("another pipeline was served from cache: %+v", s)
+	}
+
+	// An instrumented pipeline reports every object it is asked to hash.
+	rec := &recorder{}
+	p := Current().WithInstrumentation(rec)
+	c.Hash(p, doc, obj)
+	c.Hash(p, doc, obj)
+	if len(rec.done) != 2 {
+		t.Errorf("instrumented pipeline hashed %d times, want 2", len(rec.done))
+	}
+}
+
+func TestCacheNilAndConcurrent(t *testing.T) {
+	var nilCache *Cache
+	if _, h, err := nilCache.HashDocument(cacheDoc(0)); err != nil || h == "" {
+		t.Errorf("nil cache: %q, %v", h, err)
+	}
+
+	c := NewCache(4, 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, _, err := c.HashDocument(cacheDoc(i % 6)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if s := c.Stats(); s.Entries > 4 || s.Hits+s.Misses != 400 {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func BenchmarkCacheHit(b *testing.B) {
+	doc := cacheDoc(0)
+	c := NewCache(10, 0)
+	c.HashDocument(doc)
+	b.SetBytes(int64(len(doc)))
+	for i := 0; i < b.N; i++ {
+		c.HashDocument(doc)
+	}
+}
+
+func BenchmarkCacheMiss(b *testing.B) {
+	doc := cacheDoc(0)
+	b.SetBytes(int64(len(doc)))
+	for i := 0; i < b.N; i++ {
+		hashDocument(doc)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build cgo
+
+package hash
+
+// cgoEnabled is set by the cgo build constraint, which holds when the
+// binary is built with CGO_ENABLED=1.
+const cgoEnabled = true
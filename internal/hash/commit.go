@@ -0,0 +1,120 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// SaltSize is the length in bytes of a commitment salt.
+const SaltSize = 32
+
+// commitPrefix begins the input of every commitment. Canonical bytes
+// begin with "{" and a domain-separated hash input with "helios-domain:",
+// so a commitment never equals a content hash of any object.
+const commitPrefix = "helios-commit:"
+
+var (
+	// ErrNoSalt is returned when opening an object that carries no
+	// commitment salt.
+	ErrNoSalt = errors.New("object has no commitment_salt")
+	// ErrCommitmentMismatch is returned when a revealed object does not
+	// open the commitment it is checked against.
+	ErrCommitmentMismatch = errors.New("object does not open the commitment")
+)
+
+// ContentCommit returns the salted commitment to obj's content: the hex
+// SHA-256 of "helios-commit:", the salt, and obj's canonical bytes. The
+// content hash of an object with little entropy, such as a yes or a
+// date, can be found by hashing every candidate; its commitment cannot
+// without the salt, so it can be published while the content stays
+// private, and later opened by revealing the object and salt. The salt
+// must be SaltSize random bytes, drawn afresh for every object; the same
+// content committed under two salts gives unrelated commitments.
+func ContentCommit(obj object.MemoryObject, salt []byte) (string, error) {
+	if len(salt) != SaltSize {
+		return "", fmt.Errorf("commitment salt must be %d bytes, got %d", SaltSize, len(salt))
+	}
+	canonical, err := CanonicalBytes(obj)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(commitPrefix))
+	h.Write(salt)
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewSalt returns SaltSize bytes from the system's secure random source.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("commitment salt: %w", err)
+	}
+	return salt, nil
+}
+
+// EncodeSalt returns salt in the form the commitment_salt field holds it:
+// unpadded base64url, as binary values are written (RULE-013).
+func EncodeSalt(salt []byte) string {
+	return base64.RawURLEncoding.EncodeToString(salt)
+}
+
+// DecodeSalt parses a commitment_salt field.
+func DecodeSalt(s string) ([]byte, error) {
+	salt, err := base64.RawURLEncoding.Strict().DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("commitment_salt is not unpadded base64url: %w", err)
+	}
+	if len(salt) != SaltSize {
+		return nil, fmt.Errorf("commitment_salt must be %d bytes, got %d", SaltSize, len(salt))
+	}
+	return salt, nil
+}
+
+// Commit commits to obj under the salt in its commitment_salt field,
+// first storing a fresh salt there if it has none. The field is excluded
+// from the content hash, so the content hash does not change, and the
+// salt travels with the private object, never with the commitment.
+func Commit(obj *object.MemoryObject) (string, error) {
+	if obj.CommitmentSalt == "" {
+		salt, err := NewSalt()
+		if err != nil {
+			return "", err
+		}
+		obj.CommitmentSalt = EncodeSalt(salt)
+	}
+	return Open(*obj)
+}
+
+// Open returns the commitment obj opens: its commitment under the salt in
+// its commitment_salt field.
+func Open(obj object.MemoryObject) (string, error) {
+	if obj.CommitmentSalt == "" {
+		return "", ErrNoSalt
+	}
+	salt, err := DecodeSalt(obj.CommitmentSalt)
+	if err != nil {
+		return "", err
+	}
+	return ContentCommit(obj, salt)
+}
+
+// VerifyCommitment checks that obj, revealed with its commitment_salt,
+// opens commitment, comparing in constant time.
+func VerifyCommitment(commitment string, obj object.MemoryObject) error {
+	got, err := Open(obj)
+	if err != nil {
+		return err
+	}
+	if !Equal(commitment, got) {
+		return ErrCommitmentMismatch
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package hash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCommitOpensAndHides(t *testing.T) {
+	obj := baseObject()
+	want, err := ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitment, err := Commit(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.CommitmentSalt == "" {
+		t.Fatal("Commit stored no salt")
+	}
+	if got, _ := ContentHash(obj); got != want {
+		t.Error("storing the salt changed the content hash")
+	}
+	if commitment == want {
+		t.Error("the commitment is the content hash")
+	}
+	if err := VerifyCommitment(commitment, obj); err != nil {
+		t.Errorf("the object does not open its own commitment: %v", err)
+	}
+	if again, _ := Commit(&obj); again != commitment {
+		t.Error("committing again did not reuse the stored salt")
+	}
+
+	other := baseObject()
+	other.CommitmentSalt = obj.CommitmentSalt
+	other.Value = "another value"
+	if err := VerifyCommitment(commitment, other); !errors.Is(err, ErrCommitmentMismatch) {
+		t.Errorf("other content opened the commitment: %v", err)
+	}
+	fresh := baseObject()
+	if c, _ := Commit(&fresh); c == commitment || fresh.CommitmentSalt == obj.CommitmentSalt {
+		t.Error("two commitments to the same content share a salt")
+	}
+	if err := VerifyCommitment(commitment, baseObject()); !errors.Is(err, ErrNoSalt) {
+		t.Errorf("opened without a salt: %v", err)
+	}
+}
+
+// TestContentCommitPinned pins a commitment: published commitments must
+// open under every later version.
+func TestContentCommitPinned(t *testing.T) {
+	const want = "37cb4a41a33f8789850b5aef3926397351df2b4f2fa32b31cfc2884e0b7e4b21"
+	salt := bytes.Repeat([]byte{0x5a}, SaltSize)
+	got, err := ContentCommit(baseObject(), salt)
+	if err != nil || got != want {
+		t.Fatalf("ContentCommit = %s, %v; want %s", got, err, want)
+	}
+	obj := baseObject()
+	obj.CommitmentSalt = EncodeSalt(salt)
+	if opened, err := Open(obj); err != nil || opened != got {
+		t.Errorf("Open = %s, %v; want %s", opened, err, got)
+	}
+	if _, err := ContentCommit(baseObject(), salt[:16]); err == nil {
+		t.Error("a short salt was accepted")
+	}
+	for _, bad := range []string{"not base64!", EncodeSalt(salt[:16]), EncodeSalt(salt) + "="} {
+		if _, err := DecodeSalt(bad); err == nil {
+			t.Errorf("DecodeSalt(%q) accepted", bad)
+		}
+	}
+}
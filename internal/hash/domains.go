@@ -0,0 +1,152 @@
+package hash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// CategoryDomains assigns categories domain-separation tags. The content
+// hash of an object whose category has a tag is the digest of
+// "helios-domain:" + tag + "\n" followed by its canonical bytes, so it
+// can never equal the hash of an object of another domain, or of no
+// domain, whose input starts with "{", even for identical content. An
+// object of a category without a tag hashes as it would without domains.
+//
+// The zero value assigns no tags. Otherwise it holds the assignment as a
+// canonical JSON object of NFC-normalized categories to tags, which keeps
+// Profile comparable; in JSON it is that object.
+type CategoryDomains string
+
+// maxDomainTag bounds the length of a domain tag.
+const maxDomainTag = 64
+
+// NewCategoryDomains returns the assignment of tags to categories. A tag
+// is 1 to 64 lowercase letters, digits, '.', '-', and '_'; several
+// categories may share one. Categories are NFC-normalized, as the content
+// hash normalizes them, and must not be empty.
+func NewCategoryDomains(tags map[string]string) (CategoryDomains, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+	norm := make(map[string]interface{}, len(tags))
+	for cat, tag := range tags {
+		if cat == "" {
+			return "", fmt.Errorf("category domains: empty category")
+		}
+		if err := validDomainTag(tag); err != nil {
+			return "", fmt.Errorf("category domains: category %q: %w", cat, err)
+		}
+		n := canon.NormalizeString(cat)
+		if prev, ok := norm[n]; ok && prev != tag {
+			return "", fmt.Errorf("category domains: category %q is assigned both %q and %q", n, prev, tag)
+		}
+		norm[n] = tag
+	}
+	data, err := canon.CanonicalizeValue(norm)
+	if err != nil {
+		return "", fmt.Errorf("category domains: %w", err)
+	}
+	return CategoryDomains(data), nil
+}
+
+func validDomainTag(tag string) error {
+	if tag == "" || len(tag) > maxDomainTag {
+		return fmt.Errorf("domain tag must be 1 to %d characters, got %d", maxDomainTag, len(tag))
+	}
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return fmt.Errorf("domain tag %q: character %q not allowed (want a-z, 0-9, '.', '-', '_')", tag, c)
+		}
+	}
+	return nil
+}
+
+// Tags returns the assignment as a map of categories to tags, or nil for
+// the zero value.
+func (d CategoryDomains) Tags() map[string]string {
+	if d == "" {
+		return nil
+	}
+	var tags map[string]string
+	if err := json.Unmarshal([]byte(d), &tags); err != nil {
+		// d is only ever built by NewCategoryDomains.
+		panic(err)
+	}
+	return tags
+}
+
+// MarshalJSON writes the assignment as a JSON object.
+func (d CategoryDomains) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("{}"), nil
+	}
+	return []byte(d), nil
+}
+
+// UnmarshalJSON reads the assignment from a JSON object, validating it as
+// NewCategoryDomains does.
+func (d *CategoryDomains) UnmarshalJSON(data []byte) error {
+	var tags map[string]string
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return fmt.Errorf("category domains: %w", err)
+	}
+	parsed, err := NewCategoryDomains(tags)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// WithCategoryDomains returns a copy of p whose profile assigns the
+// domain tags d, replacing any p had. Every profile of this build's
+// pipelines takes domains; LookupProfile resolves a profile with domains
+// through this.
+func (p *Pipeline) WithCategoryDomains(d CategoryDomains) *Pipeline {
+	c := *p
+	c.Profile.CategoryDomains = d
+	c.domains = d.Tags()
+	return &c
+}
+
+// sum digests the canonical bytes of obj, under the domain tag of its
+// category if the pipeline assigns one.
+func (p *Pipeline) sum(obj object.MemoryObject, canonical []byte) string {
+	return p.Sum(p.HashInput(obj, canonical))
+}
+
+// HashInput returns what the pipeline digests for obj, whose canonical
+// bytes are canonical: those bytes, preceded by the domain line of the
+// tag of obj's category if the pipeline assigns one.
+func (p *Pipeline) HashInput(obj object.MemoryObject, canonical []byte) []byte {
+	tag, ok := p.domains[canon.NormalizeString(obj.Category)]
+	if !ok {
+		return canonical
+	}
+	input := make([]byte, 0, len(domainPrefix)+len(tag)+1+len(canonical))
+	input = append(input, domainPrefix...)
+	input = append(input, tag...)
+	input = append(input, '\n')
+	return append(input, canonical...)
+}
+
+// SplitHashInput splits a hash input into its domain tag, "" if it has
+// none, and the canonical bytes that follow.
+func SplitHashInput(input []byte) (tag string, canonical []byte) {
+	rest, ok := bytes.CutPrefix(input, []byte(domainPrefix))
+	if !ok {
+		return "", input
+	}
+	line, canonical, ok := bytes.Cut(rest, []byte("\n"))
+	if !ok {
+		return "", input
+	}
+	return string(line), canonical
+}
+
+const domainPrefix = "helios-domain:"
@@ -0,0 +1,137 @@
+package hash
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCategoryDomainsSeparateHashes(t *testing.T) {
+	domains, err := NewCategoryDomains(map[string]string{"secret": "secret.v1", "vault": "secret.v1", "project": "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := V1.WithCategoryDomains(domains)
+
+	obj := baseObject()
+	plain, err := V1.ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cat := range []string{"secret", "project"} {
+		obj.Category = cat
+		undomained, err := V1.ContentHash(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := p.ContentHash(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h == undomained || h == plain {
+			t.Errorf("category %q hashes as without a domain", cat)
+		}
+	}
+	obj.Category = "note"
+	if h, _ := p.ContentHash(obj); h != must(V1.ContentHash(obj)) {
+		t.Error("a category without a tag hashes differently under domains")
+	}
+
+	// A category hashes alike under every assignment that gives it the
+	// same tag, and differently under another tag.
+	obj.Category = "secret"
+	want := must(p.ContentHash(obj))
+	if got := must(V1.WithCategoryDomains(mustDomains(t, map[string]string{"secret": "secret.v1"})).ContentHash(obj)); got != want {
+		t.Errorf("same tag: %s, want %s", got, want)
+	}
+	if got := must(V1.WithCategoryDomains(mustDomains(t, map[string]string{"secret": "secret.v2"})).ContentHash(obj)); got == want {
+		t.Error("another tag hashes alike")
+	}
+}
+
+func TestCategoryDomainsInProfile(t *testing.T) {
+	domains := mustDomains(t, map[string]string{"secret": "s"})
+	prof := V1.Profile
+	prof.CategoryDomains = domains
+	if prof.ID() == V1.Profile.ID() {
+		t.Error("the domains are not committed in the profile hash")
+	}
+	other := V1.Profile
+	other.CategoryDomains = mustDomains(t, map[string]string{"secret": "t"})
+	if prof.ID() == other.ID() {
+		t.Error("different tags give the same profile hash")
+	}
+
+	p, err := LookupProfile(prof)
+	if err != nil || p.Profile != prof {
+		t.Fatalf("LookupProfile = %v, %v", p, err)
+	}
+	data, err := json.Marshal(prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Profile
+	if err := json.Unmarshal(data, &back); err != nil || back != prof {
+		t.Errorf("round trip of %s = %+v, %v", data, back, err)
+	}
+
+	for _, bad := range []map[string]string{
+		{"secret": ""},
+		{"secret": "Secret"},
+		{"secret": "a\nb"},
+		{"": "s"},
+	} {
+		if _, err := NewCategoryDomains(bad); err == nil {
+			t.Errorf("NewCategoryDomains(%q) accepted", bad)
+		}
+	}
+}
+
+func TestCategoryDomainsStamp(t *testing.T) {
+	p := V1.WithCategoryDomains(mustDomains(t, map[string]string{"secret/x": "s"}))
+	st, err := ParseStamp(p.Stamp().String())
+	if err != nil || st != p.Stamp() {
+		t.Fatalf("ParseStamp(%q) = %+v, %v", p.Stamp().String(), st, err)
+	}
+	got, err := Lookup(st)
+	if err != nil || got.Profile != p.Profile {
+		t.Fatalf("Lookup(%v) = %v, %v", st, got, err)
+	}
+	// The tags must be the ones the profile hash commits to.
+	st.CategoryDomains = mustDomains(t, map[string]string{"secret/x": "t"})
+	if _, err := Lookup(st); err == nil {
+		t.Error("Lookup accepted domains its profile hash does not commit to")
+	}
+
+	obj := baseObject()
+	obj.Category = "secret/x"
+	canonical, h, err := p.Hash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := p.HashInput(obj, canonical)
+	if sum256(input) != h {
+		t.Error("the hash input does not digest to the content hash")
+	}
+	if tag, rest := SplitHashInput(input); tag != "s" || string(rest) != string(canonical) {
+		t.Errorf("SplitHashInput = %q, %s", tag, rest)
+	}
+	if tag, rest := SplitHashInput(canonical); tag != "" || string(rest) != string(canonical) {
+		t.Errorf("SplitHashInput of canonical bytes = %q, %s", tag, rest)
+	}
+}
+
+func mustDomains(t *testing.T, tags map[string]string) CategoryDomains {
+	t.Helper()
+	d, err := NewCategoryDomains(tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func must(h string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
@@ -0,0 +1,13 @@
+package hash
+
+import "crypto/subtle"
+
+// Equal reports whether the hashes expected and got are the same string,
+// in time that depends on their lengths but not their contents. Use it
+// wherever one side is supplied by a request, a file, or a peer: with
+// ==, the time a comparison takes reveals how long a prefix an attacker
+// has guessed right. Hex digits are compared as given, so an upper-case
+// hash does not equal its lower-case form.
+func Equal(expected, got string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(got)) == 1
+}
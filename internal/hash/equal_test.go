@@ -0,0 +1,22 @@
+package hash
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	h, _ := ContentHash(baseObject())
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{h, h, true},
+		{"", "", true},
+		{h, h[:63], false},
+		{h, h[:63] + "x", false},
+		{h, "", false},
+		{"ab", "AB", false},
+	} {
+		if got := Equal(tc.a, tc.b); got != tc.want {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
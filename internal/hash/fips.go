@@ -0,0 +1,80 @@
+package hash
+
+import (
+	"crypto/fips140"
+	"fmt"
+	"sort"
+)
+
+// fipsApproved are the digest algorithms FIPS 180-4 and FIPS 202
+// approve, by the names profiles give them.
+var fipsApproved = map[string]bool{
+	"sha224": true, "sha256": true, "sha384": true, "sha512": true,
+	"sha512-224": true, "sha512-256": true,
+	"sha3-224": true, "sha3-256": true, "sha3-384": true, "sha3-512": true,
+}
+
+// FIPSStatus is the binary's FIPS 140-3 posture.
+//
+// A build with the fips tag admits only pipelines whose digest is
+// FIPS-approved: the others are left out of the pipelines, so they can be
+// neither selected nor used to verify. A pipeline with a digest that is
+// not approved, such as BLAKE3, must also keep its implementation in a
+// file constrained to !fips, so that it is compiled out rather than
+// merely unreachable. The fips build of helios also turns on the Go
+// Cryptographic Module's FIPS 140-3 mode.
+type FIPSStatus struct {
+	// Build reports a build with the fips tag.
+	Build bool `json:"build"`
+	// Enabled reports whether the Go Cryptographic Module runs in FIPS
+	// 140-3 mode, set by GODEBUG=fips140=on or GOFIPS140 at build time.
+	Enabled bool `json:"enabled"`
+	// Module is the Go Cryptographic Module the binary was built with,
+	// the GOFIPS140 setting: "latest" for the toolchain's own, or a
+	// frozen, validated version such as v1.0.0. Empty means GOFIPS140 was
+	// not set, which builds with the toolchain's own.
+	Module string `json:"module,omitempty"`
+	// BoringCrypto reports a binary built with GOEXPERIMENT=boringcrypto,
+	// whose cryptography is provided by BoringSSL.
+	BoringCrypto bool `json:"boringcrypto"`
+	// Digests are the digest algorithms of the pipelines this binary can
+	// hash and verify with.
+	Digests []string `json:"digests"`
+}
+
+// FIPS reports the binary's FIPS 140-3 posture.
+func FIPS() FIPSStatus {
+	s := FIPSStatus{
+		Build:        fipsOnly,
+		Enabled:      fips140.Enabled(),
+		Module:       BuildInfo().Settings["GOFIPS140"],
+		BoringCrypto: boringEnabled(),
+	}
+	seen := make(map[string]bool)
+	for _, p := range pipelines {
+		if alg := p.Profile.HashAlgorithm; !seen[alg] {
+			seen[alg] = true
+			s.Digests = append(s.Digests, alg)
+		}
+	}
+	sort.Strings(s.Digests)
+	return s
+}
+
+// selectable returns the pipelines this build admits: all of them, or
+// in a fips build those whose digest is FIPS-approved.
+func selectable(ps []*Pipeline) []*Pipeline {
+	if !fipsOnly {
+		return ps
+	}
+	var out []*Pipeline
+	for _, p := range ps {
+		if fipsApproved[p.Profile.HashAlgorithm] {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 || out[0] != ps[0] {
+		panic(fmt.Sprintf("hash: the current pipeline's digest %s is not FIPS-approved", ps[0].Profile.HashAlgorithm))
+	}
+	return out
+}
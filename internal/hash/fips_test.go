@@ -0,0 +1,24 @@
+package hash
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFIPS(t *testing.T) {
+	f := FIPS()
+	if f.Build != fipsOnly || !reflect.DeepEqual(f.Digests, []string{"sha256"}) {
+		t.Errorf("FIPS = %+v", f)
+	}
+
+	blake3 := &Pipeline{Profile: V1.Profile}
+	blake3.Profile.HashAlgorithm = "blake3"
+	got := selectable([]*Pipeline{V1, blake3, V1Strict})
+	want := []*Pipeline{V1, blake3, V1Strict}
+	if fipsOnly {
+		want = []*Pipeline{V1, V1Strict}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectable admitted %d pipelines, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,6 @@
+//go:build fips
+
+package hash
+
+// fipsOnly is set by the fips build tag; see FIPSStatus.
+const fipsOnly = true
@@ -4,24 +4,106 @@ package hash
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/holeyfield33-art/helios/internal/canon"
 	"github.com/holeyfield33-art/helios/internal/object"
 )
 
 // ContentHash computes the deterministic content hash for a MemoryObject.
+// It is the SHA-256 of CanonicalBytes, hex encoded.
+func ContentHash(obj object.MemoryObject) (string, error) {
+	canonical, err := CanonicalBytes(obj)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CanonicalBytes builds the canonical hash input for a MemoryObject.
+func CanonicalBytes(obj object.MemoryObject) ([]byte, error) {
+	fields, err := HashFields(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 6: Canonicalize
+	canonical, err := canon.CanonicalizeObject(fields)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalization failed: %w", err)
+	}
+	return canonical, nil
+}
+
+// CanonicalBytesParallel returns CanonicalBytes(obj), serializing large
+// arrays and maps in obj on several goroutines; see
+// canon.CanonicalizeValueParallel.
+func CanonicalBytesParallel(obj object.MemoryObject, opts canon.ParallelOptions) ([]byte, error) {
+	fields, err := HashFields(obj)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := canon.CanonicalizeValueParallel(fields, opts)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalization failed: %w", err)
+	}
+	return canonical, nil
+}
+
+// ContentHashParallel returns ContentHash(obj), building the hash input
+// with CanonicalBytesParallel.
+func ContentHashParallel(obj object.MemoryObject, opts canon.ParallelOptions) (string, error) {
+	canonical, err := CanonicalBytesParallel(obj, opts)
+	if err != nil {
+		return "", err
+	}
+	return sum256(canonical), nil
+}
+
+// CanonicalSize returns len(CanonicalBytes(obj)) without building the
+// canonical bytes; see canon.CanonicalSize.
+func CanonicalSize(obj object.MemoryObject) (int64, error) {
+	fields, err := HashFields(obj)
+	if err != nil {
+		return 0, err
+	}
+	return canon.CanonicalSize(fields)
+}
+
+// CheckSize fails with CANON_ERR_TOO_LARGE if obj's canonical hash input
+// is longer than max bytes, measuring it without canonicalizing. A max of
+// zero or less is no limit.
+func CheckSize(obj object.MemoryObject, max int64) error {
+	if max <= 0 {
+		return nil
+	}
+	fields, err := HashFields(obj)
+	if err != nil {
+		return err
+	}
+	return canon.CheckSize(fields, max)
+}
+
+// HashFields builds the normalized field map that CanonicalBytes serializes.
 // Steps:
 //  1. Extract HashInput (6 fields only)
 //  2. Normalize timestamp
 //  3. Sort relationships by key, then type
 //  4. NFC-normalize all string fields
 //  5. Build explicit field map
-//  6. Canonicalize → SHA-256 → hex
-func ContentHash(obj object.MemoryObject) (string, error) {
+//  6. Canonicalize (done by CanonicalBytes)
+func HashFields(obj object.MemoryObject) (map[string]interface{}, error) {
 	// Step 0: Null prohibition check (RULE-010)
 	if obj.Value == nil {
-		return "", fmt.Errorf("CANON_ERR_NULL_PROHIBITED: null values are not permitted")
+		return nil, &canon.Error{Code: canon.ErrCodeNullProhibited, Path: "value"}
+	}
+
+	version := obj.Schema()
+	if version != object.SchemaV1 && version != object.SchemaV2 {
+		return nil, &canon.Error{Code: canon.ErrCodeSchemaVersionInvalid, Path: "_helios_schema_version", Value: version}
 	}
 
 	// Step 1: Extract only the 6 hash-relevant fields
@@ -30,63 +112,162 @@ func ContentHash(obj object.MemoryObject) (string, error) {
 	// Step 2: Normalize timestamp
 	ts, err := canon.NormalizeTimestamp(inp.CreatedAt)
 	if err != nil {
-		return "", fmt.Errorf("timestamp normalization failed: %w", err)
+		return nil, fmt.Errorf("timestamp normalization failed: %w", err)
 	}
 	inp.CreatedAt = ts
 
 	// Step 3: Sort relationships by key, then type as tie-breaker
-	sortedRels := make([]map[string]interface{}, len(inp.Relationships))
-	relMaps := make([]map[string]interface{}, len(inp.Relationships))
-	for i, r := range inp.Relationships {
-		relMaps[i] = canon.RelationshipToMap(r.Key, r.Type)
+	rels, err := normalizeRelationships(version, inp.Relationships)
+	if err != nil {
+		return nil, err
 	}
-	sorted := canon.SortRelationships(relMaps)
-	copy(sortedRels, sorted)
 
 	// Step 4: NFC-normalize string fields
 	inp.Category = canon.NormalizeString(inp.Category)
 	inp.Key = canon.NormalizeString(inp.Key)
 	inp.Source = canon.NormalizeString(inp.Source)
-
-	// NFC-normalize string values in relationships
-	for i := range sortedRels {
-		if k, ok := sortedRels[i]["key"].(string); ok {
-			sortedRels[i]["key"] = canon.NormalizeString(k)
-		}
-		if t, ok := sortedRels[i]["type"].(string); ok {
-			sortedRels[i]["type"] = canon.NormalizeString(t)
-		}
-	}
-
-	// NFC-normalize Value if it's a string
-	var normalizedValue interface{} = inp.Value
-	if s, ok := inp.Value.(string); ok {
-		normalizedValue = canon.NormalizeString(s)
-	}
+	value := normalizeValue(inp.Value)
 
 	// Step 5: Build EXPLICIT field map with exactly 6 keys
 	// Keys must match the canonical JSON field names
-	relsInterface := make([]interface{}, len(sortedRels))
-	for i, r := range sortedRels {
-		relsInterface[i] = r
-	}
-
 	fields := map[string]interface{}{
-		"_helios_schema_version": "1",
+		"_helios_schema_version": version,
 		"category":               inp.Category,
 		"created_at":             inp.CreatedAt,
 		"key":                    inp.Key,
-		"relationships":          relsInterface,
+		"relationships":          rels,
 		"source":                 inp.Source,
-		"value":                  normalizedValue,
+		"value":                  value,
 	}
+	return fields, nil
+}
 
-	// Step 6: Canonicalize → SHA-256 → hex
-	canonical, err := canon.CanonicalizeObject(fields)
+// normalizeRelationships returns rels as maps sorted by key then type,
+// then attributes, with NFC-normalized strings. Schema version 1 has no
+// attributes, so they are dropped from a version 1 object's relationships.
+func normalizeRelationships(version string, rels []object.Relationship) ([]interface{}, error) {
+	relMaps := make([]map[string]interface{}, len(rels))
+	for i, r := range rels {
+		if version == object.SchemaV1 {
+			relMaps[i] = canon.RelationshipToMap(r.Key, r.Type)
+			continue
+		}
+		relMaps[i] = canon.RelationshipToMapV2(r.Key, r.Type, r.Weight, r.Note)
+	}
+	sorted := canon.SortRelationships(relMaps)
+
+	out := make([]interface{}, len(sorted))
+	for i, r := range sorted {
+		// NFC-normalize string values in relationships
+		if k, ok := r["key"].(string); ok {
+			r["key"] = canon.NormalizeString(k)
+		}
+		if t, ok := r["type"].(string); ok {
+			r["type"] = canon.NormalizeString(t)
+		}
+		if n, ok := r[canon.RelationshipNote].(string); ok {
+			r[canon.RelationshipNote] = canon.NormalizeString(n)
+		}
+		out[i] = r
+	}
+	return out, nil
+}
+
+// normalizeValue NFC-normalizes v if it is a string.
+func normalizeValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		return canon.NormalizeString(s)
+	}
+	return v
+}
+
+// pathPrefix begins the input of every path hash, followed by the path's
+// segments as a canonical JSON array and a newline. Canonical bytes begin
+// with "{", so a path hash never equals a content hash, and two paths
+// selecting equal sub-values give different hashes.
+const pathPrefix = "helios-path:"
+
+// PathHash canonicalizes and hashes the sub-value of obj selected by path,
+// e.g. "$.value.config". path is resolved against the normalized hash
+// fields, so "$.value..." addresses the value and "$.relationships[0]" a
+// sorted relationship. A top-level string result is NFC-normalized like
+// a string value. The digest is the SHA-256 of "helios-path:", the path,
+// and the sub-value's canonical bytes, so it commits to the selected
+// sub-value and where it was selected from; spellings of one path, such
+// as $.value.config and $.value["config"], hash alike.
+func PathHash(obj object.MemoryObject, path string) (string, error) {
+	segs, err := canon.ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	fields, err := HashFields(obj)
+	if err != nil {
+		return "", err
+	}
+	sub, err := canon.Lookup(fields, path)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := sub.(string); ok {
+		sub = canon.NormalizeString(s)
+	}
+	canonical, err := canon.CanonicalizeValue(sub)
 	if err != nil {
 		return "", fmt.Errorf("canonicalization failed: %w", err)
 	}
+	steps := make([]interface{}, len(segs))
+	for i, seg := range segs {
+		if seg.IsIndex {
+			steps[i] = json.Number(strconv.Itoa(seg.Index))
+		} else {
+			steps[i] = seg.Key
+		}
+	}
+	where, err := canon.CanonicalizeValue(steps)
+	if err != nil {
+		return "", fmt.Errorf("path %q: %w", path, err)
+	}
+	h := sha256.New()
+	h.Write([]byte(pathPrefix))
+	h.Write(where)
+	h.Write([]byte("\n"))
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
 
-	sum := sha256.Sum256(canonical)
-	return hex.EncodeToString(sum[:]), nil
+// HashAll computes the content hash of every object, in order.
+// It stops at the first failure and reports the index of the offending object.
+func HashAll(objs []object.MemoryObject) ([]string, error) {
+	hashes := make([]string, len(objs))
+	for i, obj := range objs {
+		h, err := ContentHash(obj)
+		if err != nil {
+			return nil, fmt.Errorf("object %d (key %q): %w", i, obj.Key, err)
+		}
+		hashes[i] = h
+	}
+	return hashes, nil
+}
+
+// Placeholders substituted by DraftHash for metadata that is assigned
+// only when an object is committed.
+const (
+	DraftCreatedAt = "1970-01-01T00:00:00.000Z"
+	DraftSource    = "helios:draft"
+)
+
+// DraftHash computes a hash for an object before its final metadata is
+// known. A missing (empty) created_at or source is filled with
+// DraftCreatedAt or DraftSource; metadata that is present is kept, so a
+// draft carrying its final metadata hashes to its content hash. Draft
+// hashes are for pre-submission duplicate detection only; ContentHash
+// remains the sole committed hash.
+func DraftHash(obj object.MemoryObject) (string, error) {
+	if obj.CreatedAt == "" {
+		obj.CreatedAt = DraftCreatedAt
+	}
+	if obj.Source == "" {
+		obj.Source = DraftSource
+	}
+	return ContentHash(obj)
 }
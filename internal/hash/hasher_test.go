@@ -1,9 +1,16 @@
 package hash
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"strings"
 	"testing"
 
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/ingest"
 	"github.com/holeyfield33-art/helios/internal/object"
 )
 
@@ -135,3 +142,257 @@ func TestEmptyRelationships(t *testing.T) {
 		t.Errorf("hash should be 64 hex chars, got %d", len(h))
 	}
 }
+
+func TestHashAllMatchesContentHash(t *testing.T) {
+	a := baseObject()
+	b := baseObject()
+	b.Key = "test/other"
+
+	hashes, err := HashAll([]object.MemoryObject{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, obj := range []object.MemoryObject{a, b} {
+		want, err := ContentHash(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hashes[i] != want {
+			t.Errorf("object %d: expected %s, got %s", i, want, hashes[i])
+		}
+	}
+	if hashes[0] == hashes[1] {
+		t.Error("different keys should produce different hashes")
+	}
+}
+
+func TestHashAllReportsFailingObject(t *testing.T) {
+	bad := baseObject()
+	bad.Key = "test/bad"
+	bad.Value = nil
+
+	_, err := HashAll([]object.MemoryObject{baseObject(), bad})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "object 1") || !strings.Contains(err.Error(), "test/bad") {
+		t.Errorf("error should identify the failing object: %v", err)
+	}
+}
+
+func TestDraftHashFillsMissingMetadata(t *testing.T) {
+	a := baseObject()
+	a.CreatedAt = ""
+	a.Source = ""
+	b := baseObject()
+	b.CreatedAt = DraftCreatedAt
+	b.Source = DraftSource
+
+	ha, err := DraftHash(a)
+	if err != nil {
+		t.Fatalf("draft hash of object without metadata: %v", err)
+	}
+	hb, err := ContentHash(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ha != hb {
+		t.Error("missing metadata should be filled with the documented placeholders")
+	}
+
+	final := baseObject()
+	hf, err := ContentHash(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hd, err := DraftHash(final); err != nil || hd != hf {
+		t.Errorf("draft hash of an object with its metadata must equal its content hash: %v", err)
+	}
+
+	partial := baseObject()
+	partial.Source = ""
+	hp, err := DraftHash(partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial.Source = DraftSource
+	if want, _ := ContentHash(partial); hp != want {
+		t.Error("a present created_at must be kept when only source is filled")
+	}
+}
+
+func TestContentHashRemainsStrictForDrafts(t *testing.T) {
+	obj := baseObject()
+	obj.CreatedAt = ""
+	if _, err := ContentHash(obj); err == nil {
+		t.Error("ContentHash must reject a missing created_at")
+	}
+}
+
+func TestContentHashIsSHA256OfCanonicalBytes(t *testing.T) {
+	obj := baseObject()
+	canonical, err := CanonicalBytes(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(canonical)
+	if h != hex.EncodeToString(sum[:]) {
+		t.Error("ContentHash must equal hex(sha256(CanonicalBytes))")
+	}
+}
+
+func TestPathHashCommitsToSubValue(t *testing.T) {
+	obj := baseObject()
+	obj.Value = map[string]interface{}{
+		"config": map[string]interface{}{"region": "eu", "replicas": json.Number("3")},
+		"notes":  "large payload",
+	}
+
+	h, err := PathHash(obj, "$.value.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("helios-path:[\"value\",\"config\"]\n" + `{"region":"eu","replicas":3}`))
+	if h != hex.EncodeToString(sum[:]) {
+		t.Errorf("sub-value hash should be SHA-256 of the path prefix and its canonical bytes, got %s", h)
+	}
+	if h2, err := PathHash(obj, `$.value["config"]`); err != nil || h2 != h {
+		t.Errorf("another spelling of the path: %s, %v; want %s", h2, err, h)
+	}
+
+	// Changing data outside the path must not affect the sub-value hash
+	other := baseObject()
+	other.Value = map[string]interface{}{
+		"config": map[string]interface{}{"replicas": json.Number("3"), "region": "eu"},
+		"notes":  "different payload",
+	}
+	h2, err := PathHash(other, "$.value.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h != h2 {
+		t.Error("sub-value hash must depend only on the selected sub-value")
+	}
+
+	if _, err := PathHash(obj, "$.value.missing"); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
+
+func TestPathHashSeparatesPaths(t *testing.T) {
+	obj := baseObject()
+	obj.Value = map[string]interface{}{"a": "same", "b": "same"}
+
+	whole, err := PathHash(obj, "$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := ContentHash(obj); whole == h {
+		t.Error("the path hash of $ must not equal the content hash")
+	}
+	a, _ := PathHash(obj, "$.value.a")
+	b, _ := PathHash(obj, "$.value.b")
+	if a == b {
+		t.Error("equal sub-values at different paths must hash differently")
+	}
+}
+
+func TestCanonicalSize(t *testing.T) {
+	obj := baseObject()
+	obj.Value = "café"
+	obj.Relationships = []object.Relationship{{Key: "b", Type: "t"}, {Key: "a", Type: "t"}}
+	want, err := CanonicalBytes(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CanonicalSize(obj); err != nil || got != int64(len(want)) {
+		t.Errorf("CanonicalSize = %d, %v; want %d", got, err, len(want))
+	}
+	if err := CheckSize(obj, int64(len(want))); err != nil {
+		t.Errorf("CheckSize at the exact size: %v", err)
+	}
+	if err := CheckSize(obj, int64(len(want))-1); canon.ErrorCode(err) != canon.ErrCodeTooLarge {
+		t.Errorf("CheckSize one byte short = %v", err)
+	}
+}
+
+func TestCanonicalReaderTeeHash(t *testing.T) {
+	obj := object.MemoryObject{
+		Key: "k", Category: "fact", Source: "s", CreatedAt: "2025-01-01T00:00:00.000Z",
+		Relationships: []object.Relationship{{Key: "b", Type: "t"}, {Key: "a", Type: "t"}},
+		Value:         map[string]interface{}{"list": []interface{}{int64(1), "cafe\u0301"}},
+	}
+	r, err := CanonicalReader(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tee, sum := TeeHash(r)
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, tee); err != nil {
+		t.Fatal(err)
+	}
+	want, err := CanonicalBytes(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("streamed %s, want %s", out.Bytes(), want)
+	}
+	if h, _ := ContentHash(obj); sum() != h {
+		t.Errorf("TeeHash = %s, want %s", sum(), h)
+	}
+
+	obj.CreatedAt = "2025-01-01"
+	if _, err := CanonicalReader(obj); err == nil {
+		t.Error("CanonicalReader accepted an invalid timestamp")
+	}
+}
+
+// RULE-015: relationship attributes round-trip through ingest and are
+// ignored in schema version 1 objects.
+func TestSchemaV2RelationshipAttributes(t *testing.T) {
+	doc := `{"_helios_schema_version":"2","category":"c","created_at":"2026-01-01T00:00:00.000Z","key":"k","relationships":[{"key":"a","type":"t","weight":3,"note":"n"},{"key":"a","type":"t"}],"source":"s","value":"v"}`
+	obj, err := ingest.ParseObject([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.SchemaVersion != object.SchemaV2 || obj.Relationships[0].Weight == nil || *obj.Relationships[0].Weight != 3 || *obj.Relationships[0].Note != "n" {
+		t.Fatalf("ParseObject = %+v", obj)
+	}
+	canonical, err := CanonicalBytes(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"_helios_schema_version":"2","category":"c","created_at":"2026-01-01T00:00:00.000Z","key":"k","relationships":[{"key":"a","type":"t"},{"key":"a","note":"n","type":"t","weight":3}],"source":"s","value":"v"}`
+	if string(canonical) != want {
+		t.Errorf("CanonicalBytes:\n got %s\nwant %s", canonical, want)
+	}
+	again, err := ingest.ParseObject(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := CanonicalBytes(again); string(b) != want {
+		t.Errorf("canonical bytes do not round-trip: %s", b)
+	}
+
+	obj.SchemaVersion = "3"
+	if _, err := ContentHash(obj); canon.ErrorCode(err) != canon.ErrCodeSchemaVersionInvalid {
+		t.Errorf("ContentHash of a version 3 object: %v", err)
+	}
+}
+
+// BenchmarkHashFields measures normalization of an all-ASCII object, the
+// common case NormalizeString returns unchanged.
+func BenchmarkHashFields(b *testing.B) {
+	obj := largeObject()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := HashFields(obj); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package hash
+
+import (
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Instrumentation receives an event for every object a pipeline hashes,
+// so a program embedding Helios can feed the metrics or tracing system
+// of its choice; Helios depends on none. Methods are called on the
+// hashing goroutine, so they should be quick, and must be safe for
+// concurrent use. Embed NopInstrumentation to implement only some.
+type Instrumentation interface {
+	// OnHashStart is called before an object is canonicalized.
+	OnHashStart(HashStart)
+	// OnHashDone is called after, whether or not hashing succeeded.
+	OnHashDone(HashDone)
+	// OnValidationError is called when an object is rejected by a rule
+	// of the spec, after OnHashDone.
+	OnValidationError(ValidationError)
+}
+
+// HashStart describes an object about to be hashed.
+type HashStart struct {
+	Key string
+	// Profile is the profile hash of the pipeline.
+	Profile string
+}
+
+// HashDone describes a finished hash.
+type HashDone struct {
+	Key     string
+	Profile string
+	// Size is the length of the canonical bytes, zero if hashing failed.
+	Size     int
+	Duration time.Duration
+	// Hash is the content hash, empty if hashing failed.
+	Hash string
+	Err  error
+}
+
+// ValidationError describes an object a spec rule rejected.
+type ValidationError struct {
+	Key     string
+	Profile string
+	// Code is the CANON_ERR_ code of Err.
+	Code string
+	Err  error
+}
+
+// NopInstrumentation ignores every event. Embed it in an Instrumentation
+// that handles only some.
+type NopInstrumentation struct{}
+
+func (NopInstrumentation) OnHashStart(HashStart)             {}
+func (NopInstrumentation) OnHashDone(HashDone)               {}
+func (NopInstrumentation) OnValidationError(ValidationError) {}
+
+// WithInstrumentation returns a copy of p whose Hash and ContentHash
+// report to in. The copy has p's profile and stamp, so objects it hashes
+// verify under p.
+func (p *Pipeline) WithInstrumentation(in Instrumentation) *Pipeline {
+	c := *p
+	c.Instrumentation = in
+	return &c
+}
+
+// Hash computes obj's canonical bytes and content hash under the
+// pipeline's profile, reporting to its Instrumentation if it has one.
+func (p *Pipeline) Hash(obj object.MemoryObject) ([]byte, string, error) {
+	in := p.Instrumentation
+	if in == nil {
+		canonical, err := p.CanonicalBytes(obj)
+		if err != nil {
+			return nil, "", err
+		}
+		return canonical, p.sum(obj, canonical), nil
+	}
+
+	profile := p.Profile.ID()
+	in.OnHashStart(HashStart{Key: obj.Key, Profile: profile})
+	start := time.Now()
+	canonical, err := p.CanonicalBytes(obj)
+	var h string
+	if err == nil {
+		h = p.sum(obj, canonical)
+	}
+	in.OnHashDone(HashDone{Key: obj.Key, Profile: profile, Size: len(canonical), Duration: time.Since(start), Hash: h, Err: err})
+	if err != nil {
+		if code := canon.ErrorCode(err); code != "" {
+			in.OnValidationError(ValidationError{Key: obj.Key, Profile: profile, Code: code, Err: err})
+		}
+		return nil, "", err
+	}
+	return canonical, h, nil
+}
@@ -0,0 +1,79 @@
+package hash
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	starts  []HashStart
+	done    []HashDone
+	invalid []ValidationError
+}
+
+func (r *recorder) OnHashStart(e HashStart) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.starts = append(r.starts, e)
+}
+
+func (r *recorder) OnHashDone(e HashDone) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done = append(r.done, e)
+}
+
+func (r *recorder) OnValidationError(e ValidationError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invalid = append(r.invalid, e)
+}
+
+func TestInstrumentation(t *testing.T) {
+	rec := &recorder{}
+	p := V1.WithInstrumentation(rec)
+	if V1.Instrumentation != nil {
+		t.Fatal("WithInstrumentation modified V1")
+	}
+	if p.Stamp() != V1.Stamp() {
+		t.Errorf("instrumented stamp %v, want %v", p.Stamp(), V1.Stamp())
+	}
+
+	obj := object.MemoryObject{Key: "k", Category: "fact", Source: "s", CreatedAt: "2025-01-01T00:00:00.000Z", Value: "v"}
+	canonical, h, err := p.Hash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := ContentHash(obj); h != want {
+		t.Errorf("Hash = %s, want %s", h, want)
+	}
+	if len(rec.starts) != 1 || rec.starts[0].Key != "k" || rec.starts[0].Profile != V1.Profile.ID() {
+		t.Errorf("starts = %+v", rec.starts)
+	}
+	if len(rec.done) != 1 || rec.done[0].Size != len(canonical) || rec.done[0].Hash != h || rec.done[0].Err != nil || rec.done[0].Duration <= 0 {
+		t.Errorf("done = %+v", rec.done)
+	}
+
+	obj.CreatedAt = "2025-01-01T00:00:00Z"
+	if _, err := p.ContentHash(obj); err == nil {
+		t.Fatal("accepted a timestamp without milliseconds")
+	}
+	if len(rec.done) != 2 || rec.done[1].Err == nil || rec.done[1].Size != 0 {
+		t.Errorf("done after a failure = %+v", rec.done)
+	}
+	if len(rec.invalid) != 1 || rec.invalid[0].Code != canon.ErrCodeTimestampInvalidPrecision {
+		t.Errorf("validation errors = %+v", rec.invalid)
+	}
+}
+
+func TestNopInstrumentation(t *testing.T) {
+	var in Instrumentation = struct{ NopInstrumentation }{}
+	obj := object.MemoryObject{Key: "k", Category: "fact", Source: "s", CreatedAt: "2025-01-01T00:00:00.000Z", Value: "v"}
+	if _, err := V1.WithInstrumentation(in).ContentHash(obj); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !goexperiment.boringcrypto
+
+package hash
+
+func boringEnabled() bool { return false }
@@ -0,0 +1,5 @@
+//go:build !cgo
+
+package hash
+
+const cgoEnabled = false
@@ -0,0 +1,5 @@
+//go:build !fips
+
+package hash
+
+const fipsOnly = false
@@ -0,0 +1,265 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Profile records the rules a content hash is computed under: the
+// canonical serialization spec, the memory object schema, the digest
+// algorithm, and the Unicode version of the normalization tables.
+type Profile struct {
+	SpecVersion    string `json:"spec_version"`
+	SchemaVersion  string `json:"schema_version"`
+	HashAlgorithm  string `json:"hash_algorithm"`
+	UnicodeVersion string `json:"unicode_version"`
+	// KeyPolicy restricts the map keys inside values; empty is the
+	// permissive policy of version 1.
+	KeyPolicy canon.KeyPolicy `json:"key_policy,omitempty"`
+	// CategoryDomains assigns categories domain-separation tags; empty
+	// assigns none.
+	CategoryDomains CategoryDomains `json:"category_domains,omitempty"`
+	// Extensions enables the reserved keys of Section 3.9 and the binary
+	// values of Section 3.8; version 1 without them accepts those keys as
+	// ordinary data.
+	Extensions bool `json:"extensions,omitempty"`
+}
+
+// ID returns the profile hash: the hex SHA-256 of the profile's canonical
+// JSON. Two pipelines with the same ID hash every object alike.
+func (p Profile) ID() string {
+	fields := map[string]interface{}{
+		"spec_version":    p.SpecVersion,
+		"schema_version":  p.SchemaVersion,
+		"hash_algorithm":  p.HashAlgorithm,
+		"unicode_version": p.UnicodeVersion,
+	}
+	if p.KeyPolicy != canon.KeyPolicyPermissive {
+		fields["key_policy"] = string(p.KeyPolicy)
+	}
+	if p.CategoryDomains != "" {
+		tags := make(map[string]interface{})
+		for cat, tag := range p.CategoryDomains.Tags() {
+			tags[cat] = tag
+		}
+		fields["category_domains"] = tags
+	}
+	if p.Extensions {
+		fields["extensions"] = true
+	}
+	data, err := canon.CanonicalizeValue(fields)
+	if err != nil {
+		// Only strings and booleans are canonicalized, which cannot fail.
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Stamp is recorded with every stored object and attestation: the spec
+// version, digest algorithm, and profile hash its content hash was
+// computed under. Verification looks up the pipeline by the stamp.
+type Stamp struct {
+	SpecVersion   string `json:"spec_version"`
+	HashAlgorithm string `json:"hash_algorithm"`
+	Profile       string `json:"profile"`
+	// CategoryDomains are the domain tags of the profile, which its hash
+	// cannot be resolved back to; Lookup checks them against it.
+	CategoryDomains CategoryDomains `json:"category_domains,omitempty"`
+}
+
+// String returns the stamp as spec/algorithm/profile, the form backends
+// without JSON fields store it in, followed by /domains with the domain
+// tags as unpadded base64url JSON if it has any.
+func (s Stamp) String() string {
+	str := s.SpecVersion + "/" + s.HashAlgorithm + "/" + s.Profile
+	if s.CategoryDomains != "" {
+		str += "/" + base64.RawURLEncoding.EncodeToString([]byte(s.CategoryDomains))
+	}
+	return str
+}
+
+// ParseStamp parses the String form of a stamp.
+func ParseStamp(str string) (Stamp, error) {
+	parts := strings.Split(str, "/")
+	if len(parts) != 3 && len(parts) != 4 {
+		return Stamp{}, fmt.Errorf("invalid stamp %q", str)
+	}
+	st := Stamp{SpecVersion: parts[0], HashAlgorithm: parts[1], Profile: parts[2]}
+	if len(parts) == 4 {
+		data, err := base64.RawURLEncoding.DecodeString(parts[3])
+		if err != nil {
+			return Stamp{}, fmt.Errorf("invalid stamp %q: domains: %w", str, err)
+		}
+		if err := st.CategoryDomains.UnmarshalJSON(data); err != nil {
+			return Stamp{}, fmt.Errorf("invalid stamp %q: %w", str, err)
+		}
+		if st.CategoryDomains == "" {
+			return Stamp{}, fmt.Errorf("invalid stamp %q: empty domains", str)
+		}
+	}
+	return st, nil
+}
+
+// ErrUnknownProfile is returned for a stamp no pipeline of this build
+// matches.
+var ErrUnknownProfile = errors.New("unknown canonicalization profile")
+
+// Pipeline computes canonical bytes and content hashes under one profile.
+type Pipeline struct {
+	Profile Profile
+	// CanonicalBytes builds the hash input of an object.
+	CanonicalBytes func(object.MemoryObject) ([]byte, error)
+	// Sum digests the hash input, hex encoded.
+	Sum func([]byte) string
+	// Instrumentation, if set, is told about every object hashed through
+	// Hash and ContentHash; see WithInstrumentation.
+	Instrumentation Instrumentation
+
+	// domains is Profile.CategoryDomains as a map; see
+	// WithCategoryDomains.
+	domains map[string]string
+}
+
+// ContentHash computes obj's content hash under the pipeline's profile.
+func (p *Pipeline) ContentHash(obj object.MemoryObject) (string, error) {
+	_, h, err := p.Hash(obj)
+	return h, err
+}
+
+// Stamp returns the stamp of hashes the pipeline computes.
+func (p *Pipeline) Stamp() Stamp {
+	return Stamp{SpecVersion: p.Profile.SpecVersion, HashAlgorithm: p.Profile.HashAlgorithm, Profile: p.Profile.ID(), CategoryDomains: p.Profile.CategoryDomains}
+}
+
+func sum256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// V1 is the pipeline of version 1 of the canonical serialization spec,
+// the one CanonicalBytes and ContentHash implement. Its profile is fixed
+// because stamps refer to it: upgrading the Unicode tables past
+// canon.UnicodeVersion means adding a pipeline, not editing this one.
+var V1 = &Pipeline{
+	Profile: Profile{
+		SpecVersion:    "helios-canonical-serialization-v1",
+		SchemaVersion:  "1",
+		HashAlgorithm:  "sha256",
+		UnicodeVersion: "17.0.0",
+	},
+	CanonicalBytes: CanonicalBytes,
+	Sum:            sum256,
+}
+
+// V1Strict is V1 under the strict key policy: it rejects objects whose
+// value has a map key with a control character or U+FFFD, and hashes the
+// rest exactly as V1 does.
+var V1Strict = &Pipeline{
+	Profile: withKeyPolicy(V1.Profile, canon.KeyPolicyStrict),
+	CanonicalBytes: func(obj object.MemoryObject) ([]byte, error) {
+		if err := canon.ValidateKeys(obj.Value, canon.KeyPolicyStrict); err != nil {
+			return nil, err
+		}
+		return CanonicalBytes(obj)
+	},
+	Sum: sum256,
+}
+
+func withKeyPolicy(p Profile, policy canon.KeyPolicy) Profile {
+	p.KeyPolicy = policy
+	return p
+}
+
+// withExtensions returns p with extensions enabled: it rejects objects
+// canon.ValidateExtensions rejects, and hashes the rest exactly as p does.
+func withExtensions(p *Pipeline) *Pipeline {
+	c := *p
+	c.Profile.Extensions = true
+	c.CanonicalBytes = func(obj object.MemoryObject) ([]byte, error) {
+		if err := canon.ValidateExtensions(obj.Value); err != nil {
+			return nil, err
+		}
+		return p.CanonicalBytes(obj)
+	}
+	return &c
+}
+
+// pipelines are the pipelines this build can verify, the current one
+// first. A spec upgrade adds its pipeline in front and keeps the old
+// ones, so objects stamped by an earlier version stay verifiable.
+var pipelines = selectable([]*Pipeline{V1, V1Strict, withExtensions(V1), withExtensions(V1Strict)})
+
+// ForKeyPolicy returns the current pipeline under a key policy.
+func ForKeyPolicy(policy canon.KeyPolicy) (*Pipeline, error) {
+	want := withKeyPolicy(Current().Profile, policy)
+	return LookupProfile(want)
+}
+
+// WithExtensions returns the pipeline of p's profile with extensions
+// enabled.
+func WithExtensions(p *Pipeline) (*Pipeline, error) {
+	prof := p.Profile
+	prof.Extensions = true
+	return LookupProfile(prof)
+}
+
+// Current returns the pipeline new hashes are computed with.
+func Current() *Pipeline {
+	return pipelines[0]
+}
+
+// Lookup returns the pipeline a stamp names. The zero stamp, carried by
+// objects stored before stamping began, names V1. A stamp with category
+// domains names the pipeline with them whose profile hash it carries.
+func Lookup(s Stamp) (*Pipeline, error) {
+	if s == (Stamp{}) {
+		return V1, nil
+	}
+	for _, p := range pipelines {
+		if s.CategoryDomains != "" {
+			p = p.WithCategoryDomains(s.CategoryDomains)
+		}
+		if p.Stamp() == s {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, s)
+}
+
+// LookupID returns the pipeline whose profile hash is id; "" names V1.
+func LookupID(id string) (*Pipeline, error) {
+	if id == "" {
+		return V1, nil
+	}
+	for _, p := range pipelines {
+		if p.Profile.ID() == id {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, id)
+}
+
+// LookupProfile returns the pipeline of a profile. A profile with
+// category domains is looked up without them, and its pipeline is
+// returned with them.
+func LookupProfile(prof Profile) (*Pipeline, error) {
+	base := prof
+	base.CategoryDomains = ""
+	for _, p := range pipelines {
+		if p.Profile == base {
+			if prof.CategoryDomains != "" {
+				return p.WithCategoryDomains(prof.CategoryDomains), nil
+			}
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %+v", ErrUnknownProfile, prof)
+}
@@ -0,0 +1,130 @@
+package hash
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+)
+
+// TestV1ProfileID pins the profile hash of V1. Stored objects and
+// attestations are stamped with it, so it must never change: a change to
+// the rules is a new pipeline.
+func TestV1ProfileID(t *testing.T) {
+	const want = "9d2a8537ba57faa2f3201ebfcbb1ab5216e49a99d85dcd8981673aa4d88e3ad1"
+	if got := V1.Profile.ID(); got != want {
+		t.Errorf("V1 profile ID = %s, want %s", got, want)
+	}
+	if got := Current().Profile.UnicodeVersion; got != canon.UnicodeVersion {
+		t.Errorf("the current pipeline hashes under Unicode %s, but the build's tables are pinned to %s", got, canon.UnicodeVersion)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	st := Current().Stamp()
+	parsed, err := ParseStamp(st.String())
+	if err != nil || parsed != st {
+		t.Fatalf("ParseStamp(%q) = %+v, %v", st.String(), parsed, err)
+	}
+	for _, lookup := range []func() (*Pipeline, error){
+		func() (*Pipeline, error) { return Lookup(st) },
+		func() (*Pipeline, error) { return Lookup(Stamp{}) },
+		func() (*Pipeline, error) { return LookupID(st.Profile) },
+		func() (*Pipeline, error) { return LookupID("") },
+		func() (*Pipeline, error) { return LookupProfile(V1.Profile) },
+	} {
+		if p, err := lookup(); err != nil || p != V1 {
+			t.Errorf("lookup = %v, %v; want V1", p, err)
+		}
+	}
+
+	other := V1.Profile
+	other.UnicodeVersion = "9.0.0"
+	if _, err := LookupProfile(other); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("LookupProfile(%+v) = %v", other, err)
+	}
+	if _, err := LookupID(other.ID()); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("LookupID of an unknown profile: %v", err)
+	}
+	if _, err := Lookup(Stamp{SpecVersion: st.SpecVersion, HashAlgorithm: "sha512", Profile: st.Profile}); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Lookup with another algorithm: %v", err)
+	}
+	if _, err := ParseStamp("a/b"); err == nil {
+		t.Error("ParseStamp accepted two fields")
+	}
+}
+
+func TestPipelineHashesLikeContentHash(t *testing.T) {
+	obj := baseObject()
+	want, err := ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := V1.ContentHash(obj); err != nil || got != want {
+		t.Errorf("V1.ContentHash = %s, %v; want %s", got, err, want)
+	}
+}
+
+func TestStrictKeyPolicyPipeline(t *testing.T) {
+	if p, err := ForKeyPolicy(canon.KeyPolicyPermissive); err != nil || p != V1 {
+		t.Errorf("ForKeyPolicy(permissive) = %v, %v; want V1", p, err)
+	}
+	strict, err := ForKeyPolicy(canon.KeyPolicyStrict)
+	if err != nil || strict != V1Strict {
+		t.Fatalf("ForKeyPolicy(strict) = %v, %v; want V1Strict", strict, err)
+	}
+	if strict.Profile.ID() == V1.Profile.ID() {
+		t.Error("V1Strict has V1's profile ID")
+	}
+	if p, err := LookupID(strict.Profile.ID()); err != nil || p != V1Strict {
+		t.Errorf("LookupID(V1Strict) = %v, %v", p, err)
+	}
+
+	obj := baseObject()
+	want, err := V1.ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := V1Strict.ContentHash(obj); err != nil || got != want {
+		t.Errorf("V1Strict.ContentHash = %s, %v; want %s", got, err, want)
+	}
+	obj.Value = map[string]interface{}{"ok": []interface{}{map[string]interface{}{"a\u0000b": true}}}
+	if _, err := V1.ContentHash(obj); err != nil {
+		t.Errorf("V1 rejected a control character in a key: %v", err)
+	}
+	if _, err := V1Strict.ContentHash(obj); canon.ErrorCode(err) != canon.ErrCodeKeyCharacter {
+		t.Errorf("V1Strict.ContentHash error = %v, want %s", err, canon.ErrCodeKeyCharacter)
+	}
+}
+
+func TestExtensionsPipeline(t *testing.T) {
+	ext, err := WithExtensions(V1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext.Profile.ID() == V1.Profile.ID() {
+		t.Error("the extensions pipeline has V1's profile ID")
+	}
+	if p, err := Lookup(ext.Stamp()); err != nil || p != ext {
+		t.Errorf("Lookup(extensions stamp) = %v, %v", p, err)
+	}
+	if strict, err := WithExtensions(V1Strict); err != nil || !strict.Profile.Extensions || strict.Profile.KeyPolicy != canon.KeyPolicyStrict {
+		t.Errorf("WithExtensions(V1Strict) = %+v, %v", strict, err)
+	}
+
+	obj := baseObject()
+	want, err := V1.ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ext.ContentHash(obj); err != nil || got != want {
+		t.Errorf("extensions ContentHash = %s, %v; want V1's %s", got, err, want)
+	}
+	obj.Value = map[string]interface{}{"$ref": "#/a"}
+	if _, err := V1.ContentHash(obj); err != nil {
+		t.Errorf("V1 rejected a reserved key: %v", err)
+	}
+	if _, err := ext.ContentHash(obj); canon.ErrorCode(err) != canon.ErrCodeKeyReserved {
+		t.Errorf("extensions ContentHash error = %v, want %s", err, canon.ErrCodeKeyReserved)
+	}
+}
@@ -0,0 +1,207 @@
+package hash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// canonicalPrefixes start the canonical hash inputs of each schema
+// version.
+var canonicalPrefixes = map[string][]byte{
+	object.SchemaV1: []byte(`{"_helios_schema_version":"1",`),
+	object.SchemaV2: []byte(`{"_helios_schema_version":"2",`),
+}
+
+// schemaOf returns the schema version canonical starts with.
+func schemaOf(canonical []byte) (string, bool) {
+	for version, prefix := range canonicalPrefixes {
+		if bytes.HasPrefix(canonical, prefix) {
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// Splice returns the canonical bytes of an object after replacing one
+// top-level hash field, given the canonical bytes computed before the
+// change. The new field is normalized exactly as CanonicalBytes would and
+// written over the old one in place, so updating a relationship or the
+// timestamp of an object with a multi-megabyte value copies the value's
+// bytes but never re-parses or re-serializes it.
+//
+// field is one of "category", "created_at", "key", "source" (value a
+// string), "relationships" (value a []object.Relationship), or "value"
+// (value any value accepted in MemoryObject.Value).
+func Splice(canonical []byte, field string, value interface{}) ([]byte, error) {
+	version, _ := schemaOf(canonical)
+	enc, err := encodeField(version, field, value)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := locateField(canonical, field)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(canonical)-(end-start)+len(enc))
+	out = append(out, canonical[:start]...)
+	out = append(out, enc...)
+	return append(out, canonical[end:]...), nil
+}
+
+// SpliceHash is Splice followed by hashing; it returns the new canonical
+// bytes and content hash.
+func SpliceHash(canonical []byte, field string, value interface{}) ([]byte, string, error) {
+	out, err := Splice(canonical, field, value)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(out)
+	return out, hex.EncodeToString(sum[:]), nil
+}
+
+// encodeField produces the canonical encoding of one normalized field.
+func encodeField(version, field string, value interface{}) ([]byte, error) {
+	var v interface{}
+	var err error
+	switch field {
+	case "category", "key", "source":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("splice: %s must be a string, got %T", field, value)
+		}
+		v = canon.NormalizeString(s)
+	case "created_at":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("splice: created_at must be a string, got %T", value)
+		}
+		ts, err := canon.NormalizeTimestamp(s)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp normalization failed: %w", err)
+		}
+		v = ts
+	case "relationships":
+		rels, ok := value.([]object.Relationship)
+		if !ok {
+			return nil, fmt.Errorf("splice: relationships must be []object.Relationship, got %T", value)
+		}
+		if v, err = normalizeRelationships(version, rels); err != nil {
+			return nil, err
+		}
+	case "value":
+		if value == nil {
+			return nil, &canon.Error{Code: canon.ErrCodeNullProhibited, Path: "value"}
+		}
+		v = normalizeValue(value)
+	default:
+		return nil, fmt.Errorf("splice: %q is not a hash field", field)
+	}
+	enc, err := canon.CanonicalizeValue(v)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalization failed: %w", err)
+	}
+	return enc, nil
+}
+
+// locateField returns the byte range of field's value in canonical. The
+// scan stops at the target, and "value" is always the last member, so
+// the value itself is never scanned.
+func locateField(canonical []byte, field string) (start, end int, err error) {
+	bad := func(reason string) (int, int, error) {
+		return 0, 0, fmt.Errorf("splice: input is not canonical hash input: %s", reason)
+	}
+	version, ok := schemaOf(canonical)
+	if !ok || canonical[len(canonical)-1] != '}' {
+		return bad("missing schema version prefix or closing brace")
+	}
+	last := ""
+	for pos := len(canonicalPrefixes[version]); ; {
+		name, next, ok := scanString(canonical, pos)
+		if !ok || next >= len(canonical) || canonical[next] != ':' {
+			return bad(fmt.Sprintf("malformed member at byte %d", pos))
+		}
+		if name <= last {
+			return bad(fmt.Sprintf("member %q out of order", name))
+		}
+		last = name
+		start = next + 1
+		if name == "value" {
+			if field != "value" {
+				return bad(fmt.Sprintf("no %q member", field))
+			}
+			return start, len(canonical) - 1, nil
+		}
+		end, ok = skipValue(canonical, start)
+		if !ok || end >= len(canonical) {
+			return bad(fmt.Sprintf("malformed %q member", name))
+		}
+		if name == field {
+			return start, end, nil
+		}
+		if canonical[end] != ',' {
+			return bad(fmt.Sprintf("no %q member", field))
+		}
+		pos = end + 1
+	}
+}
+
+// scanString reads the JSON string starting at b[pos] and returns its raw
+// contents (escapes are not decoded; member names have none) and the
+// position after the closing quote.
+func scanString(b []byte, pos int) (string, int, bool) {
+	if pos >= len(b) || b[pos] != '"' {
+		return "", 0, false
+	}
+	for i := pos + 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return string(b[pos+1 : i]), i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// skipValue returns the position just after the JSON value at b[pos].
+func skipValue(b []byte, pos int) (int, bool) {
+	if pos >= len(b) {
+		return 0, false
+	}
+	switch b[pos] {
+	case '"':
+		_, next, ok := scanString(b, pos)
+		return next, ok
+	case '{', '[':
+		depth := 0
+		for i := pos; i < len(b); i++ {
+			switch b[i] {
+			case '"':
+				_, next, ok := scanString(b, i)
+				if !ok {
+					return 0, false
+				}
+				i = next - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, true
+				}
+			}
+		}
+		return 0, false
+	default:
+		i := pos
+		for i < len(b) && b[i] != ',' && b[i] != '}' && b[i] != ']' {
+			i++
+		}
+		return i, i > pos
+	}
+}
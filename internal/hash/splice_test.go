@@ -0,0 +1,182 @@
+package hash
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// awkward strings exercise escaping, NFC normalization, and characters
+// that a careless scanner would mistake for structure.
+var awkward = []string{
+	"", "plain", `quote " inside`, `back\slash`, "brace } ] , : {", "café", "tab\tnl\n", "\u0001ctl", "日本語",
+}
+
+func randomString(r *rand.Rand) string {
+	return awkward[r.Intn(len(awkward))] + awkward[r.Intn(len(awkward))]
+}
+
+func randomValue(r *rand.Rand, depth int) interface{} {
+	switch n := r.Intn(6); {
+	case n == 0 || depth > 2:
+		return randomString(r)
+	case n == 1:
+		return json.Number([]string{"0", "-7", "9223372036854775807"}[r.Intn(3)])
+	case n == 2:
+		return r.Intn(2) == 0
+	case n == 3:
+		arr := make([]interface{}, r.Intn(4))
+		for i := range arr {
+			arr[i] = randomValue(r, depth+1)
+		}
+		return arr
+	default:
+		m := map[string]interface{}{}
+		for i := r.Intn(4); i > 0; i-- {
+			m[randomString(r)] = randomValue(r, depth+1)
+		}
+		return m
+	}
+}
+
+// randomRelationships returns relationships with the attributes of
+// schema version 2 if v2 is set.
+func randomRelationships(r *rand.Rand, v2 bool) []object.Relationship {
+	rels := make([]object.Relationship, r.Intn(4))
+	for i := range rels {
+		rels[i] = object.Relationship{Key: randomString(r), Type: randomString(r)}
+		if v2 && r.Intn(2) == 0 {
+			w := int64(r.Intn(3) - 1)
+			rels[i].Weight = &w
+		}
+		if v2 && r.Intn(2) == 0 {
+			n := randomString(r)
+			rels[i].Note = &n
+		}
+	}
+	return rels
+}
+
+var timestamps = []string{"2025-01-15T10:30:00.000Z", "1999-12-31T23:59:59.999Z", "2030-06-01T00:00:00.000Z"}
+
+func TestSpliceMatchesFullRecomputation(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	fields := []string{"category", "created_at", "key", "relationships", "source", "value"}
+	for i := 0; i < 2000; i++ {
+		v2 := r.Intn(2) == 0
+		obj := object.MemoryObject{
+			Category:      randomString(r),
+			CreatedAt:     timestamps[r.Intn(len(timestamps))],
+			Key:           randomString(r),
+			Relationships: randomRelationships(r, v2),
+			Source:        randomString(r),
+			Value:         randomValue(r, 0),
+		}
+		if v2 {
+			obj.SchemaVersion = object.SchemaV2
+		}
+		before, err := CanonicalBytes(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		field := fields[r.Intn(len(fields))]
+		var v interface{}
+		switch field {
+		case "category":
+			obj.Category = randomString(r)
+			v = obj.Category
+		case "created_at":
+			obj.CreatedAt = timestamps[r.Intn(len(timestamps))]
+			v = obj.CreatedAt
+		case "key":
+			obj.Key = randomString(r)
+			v = obj.Key
+		case "relationships":
+			obj.Relationships = append(obj.Relationships, randomRelationships(r, v2)...)
+			v = obj.Relationships
+		case "source":
+			obj.Source = randomString(r)
+			v = obj.Source
+		case "value":
+			obj.Value = randomValue(r, 0)
+			v = obj.Value
+		}
+
+		got, h, err := SpliceHash(before, field, v)
+		if err != nil {
+			t.Fatalf("case %d (%s): %v\n%s", i, field, err, before)
+		}
+		want, _ := CanonicalBytes(obj)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("case %d (%s):\n got %s\nwant %s", i, field, got, want)
+		}
+		if wantHash, _ := ContentHash(obj); h != wantHash {
+			t.Fatalf("case %d (%s): hash %s, want %s", i, field, h, wantHash)
+		}
+	}
+}
+
+func TestSpliceErrors(t *testing.T) {
+	canonical, _ := CanonicalBytes(baseObject())
+	for _, tc := range []struct {
+		name      string
+		canonical []byte
+		field     string
+		value     interface{}
+		want      string
+	}{
+		{"unknown field", canonical, "version", "3", "not a hash field"},
+		{"wrong type", canonical, "relationships", "x", "must be []object.Relationship"},
+		{"null value", canonical, "value", nil, "CANON_ERR_NULL_PROHIBITED"},
+		{"bad timestamp", canonical, "created_at", "2025-01-15T10:30:00Z", "CANON_ERR_TIMESTAMP_INVALID_PRECISION"},
+		{"not canonical", []byte(`{"key":"x"}`), "key", "y", "not canonical"},
+		{"missing member", bytes.Replace(canonical, []byte(`"key"`), []byte(`"kez"`), 1), "key", "y", `no "key" member`},
+		{"unsorted", bytes.Replace(canonical, []byte(`"source"`), []byte(`"aource"`), 1), "value", "y", "out of order"},
+		{"truncated", canonical[:60], "source", "y", "not canonical"},
+	} {
+		if _, err := Splice(tc.canonical, tc.field, tc.value); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func largeObject() object.MemoryObject {
+	obj := baseObject()
+	items := make([]interface{}, 50000)
+	for i := range items {
+		items[i] = map[string]interface{}{"n": json.Number("12345"), "text": "some text for a large value"}
+	}
+	obj.Value = map[string]interface{}{"items": items}
+	return obj
+}
+
+func BenchmarkSpliceRelationships(b *testing.B) {
+	obj := largeObject()
+	canonical, _ := CanonicalBytes(obj)
+	rels := append(obj.Relationships, object.Relationship{Key: "project/new", Type: "mentions"})
+	b.SetBytes(int64(len(canonical)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := SpliceHash(canonical, "relationships", rels); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFullRehashRelationships(b *testing.B) {
+	obj := largeObject()
+	canonical, _ := CanonicalBytes(obj)
+	obj.Relationships = append(obj.Relationships, object.Relationship{Key: "project/new", Type: "mentions"})
+	b.SetBytes(int64(len(canonical)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ContentHash(obj); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
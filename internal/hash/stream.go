@@ -0,0 +1,31 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// CanonicalReader returns a reader of CanonicalBytes(obj) that produces
+// the bytes as they are read; see canon.CanonicalReader. The hash fields
+// are normalized before it returns, so an invalid timestamp fails here,
+// but a null inside the value fails the Read that reaches it.
+func CanonicalReader(obj object.MemoryObject) (io.Reader, error) {
+	fields, err := HashFields(obj)
+	if err != nil {
+		return nil, err
+	}
+	return canon.CanonicalReader(fields), nil
+}
+
+// TeeHash returns a reader of r and a function returning the hex SHA-256
+// of everything read through it so far. Reading a CanonicalReader to EOF
+// through it, e.g. while uploading the bytes, yields the content hash
+// without a second pass or a buffered copy.
+func TeeHash(r io.Reader) (io.Reader, func() string) {
+	h := sha256.New()
+	return io.TeeReader(r, h), func() string { return hex.EncodeToString(h.Sum(nil)) }
+}
@@ -0,0 +1,96 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Edge is a relationship supplied apart from the object it belongs to,
+// as produced by a pipeline that generates link data in its own stage.
+// From is the key of the object the relationship is added to.
+type Edge struct {
+	From string `json:"from"`
+	Key  string `json:"key"`
+	Type string `json:"type"`
+}
+
+// DecodeEdge decodes one edge, which must have exactly the fields from,
+// key, and type, all non-empty.
+func DecodeEdge(data []byte, e *Edge) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(e); err != nil {
+		return fmt.Errorf("invalid relationship: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid relationship: unexpected data after object")
+	}
+	switch {
+	case e.From == "":
+		return fmt.Errorf("relationship has no from key")
+	case e.Key == "":
+		return fmt.Errorf("relationship has no key")
+	case e.Type == "":
+		return fmt.Errorf("relationship has no type")
+	}
+	return nil
+}
+
+// MergeEdges adds each edge to the relationships of the objects whose
+// key is its From, compared in NFC. Every object that gains an edge has
+// its relationships normalized, deduplicated, and sorted as in the hash,
+// which does not change its hash beyond the edges added; other objects,
+// and edges no object matches, are left alone. It returns the number of
+// edges that matched an object.
+func MergeEdges(objs []object.MemoryObject, edges []Edge) int {
+	byFrom := make(map[string][]object.Relationship)
+	for _, e := range edges {
+		from := canon.NormalizeString(e.From)
+		byFrom[from] = append(byFrom[from], object.Relationship{Key: e.Key, Type: e.Type})
+	}
+	matched := 0
+	for i := range objs {
+		add := byFrom[canon.NormalizeString(objs[i].Key)]
+		if len(add) == 0 {
+			continue
+		}
+		matched += len(add)
+		objs[i].Relationships = dedupRelationships(append(append([]object.Relationship(nil), objs[i].Relationships...), add...))
+	}
+	return matched
+}
+
+func dedupRelationships(rels []object.Relationship) []object.Relationship {
+	seen := make(map[string]bool, len(rels))
+	maps := make([]map[string]interface{}, 0, len(rels))
+	for _, r := range rels {
+		if r.Note != nil {
+			n := canon.NormalizeString(*r.Note)
+			r.Note = &n
+		}
+		m := canon.RelationshipToMapV2(canon.NormalizeString(r.Key), canon.NormalizeString(r.Type), r.Weight, r.Note)
+		// Strings and an int64 always canonicalize.
+		id, _ := canon.CanonicalizeValue(m)
+		if !seen[string(id)] {
+			seen[string(id)] = true
+			maps = append(maps, m)
+		}
+	}
+	out := make([]object.Relationship, len(maps))
+	for i, m := range canon.SortRelationships(maps) {
+		r := object.Relationship{Key: m["key"].(string), Type: m["type"].(string)}
+		if w, ok := m[canon.RelationshipWeight].(int64); ok {
+			r.Weight = &w
+		}
+		if n, ok := m[canon.RelationshipNote].(string); ok {
+			r.Note = &n
+		}
+		out[i] = r
+	}
+	return out
+}
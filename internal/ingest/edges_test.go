@@ -0,0 +1,31 @@
+package ingest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func TestMergeEdges(t *testing.T) {
+	objs := []object.MemoryObject{
+		{Key: "caf\u00e9", Relationships: []object.Relationship{{Key: "b", Type: "cites"}}},
+		{Key: "other", Relationships: []object.Relationship{{Key: "z", Type: "t"}, {Key: "z", Type: "t"}}},
+	}
+	n := MergeEdges(objs, []Edge{
+		{From: "cafe\u0301", Key: "a", Type: "cites"},
+		{From: "caf\u00e9", Key: "b", Type: "cites"},
+		{From: "caf\u00e9", Key: "a", Type: "about"},
+		{From: "missing", Key: "a", Type: "cites"},
+	})
+	if n != 3 {
+		t.Errorf("matched %d edges, want 3", n)
+	}
+	want := []object.Relationship{{Key: "a", Type: "about"}, {Key: "a", Type: "cites"}, {Key: "b", Type: "cites"}}
+	if !reflect.DeepEqual(objs[0].Relationships, want) {
+		t.Errorf("merged relationships = %v, want %v", objs[0].Relationships, want)
+	}
+	if len(objs[1].Relationships) != 2 {
+		t.Errorf("an object without edges was changed: %v", objs[1].Relationships)
+	}
+}
@@ -0,0 +1,343 @@
+// Package ingest converts raw JSON documents into Helios memory objects.
+// All decoding preserves numbers as json.Number so ingest validation can
+// distinguish integers from floats (RULE-002).
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Decode parses a single JSON document with UseNumber enabled.
+// Trailing data after the document is rejected.
+func Decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("failed to parse JSON: unexpected data after document")
+	}
+	return v, nil
+}
+
+// DecodeObject parses a JSON document that must be a single object.
+func DecodeObject(data []byte) (map[string]interface{}, error) {
+	v, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON object, got %T", v)
+	}
+	return m, nil
+}
+
+// ValidateInput applies the ingest rules to a decoded object: the value
+// rules (RULE-002, RULE-009, RULE-010) and, for an object that declares
+// schema version 2, the relationship attributes (RULE-015).
+func ValidateInput(input map[string]interface{}) error {
+	if err := canon.ValidateIngestValue(input["value"]); err != nil {
+		return err
+	}
+	return canon.ValidateRelationships(input)
+}
+
+// ParseObject decodes a single memory object document and applies the
+// ingest rules (see ValidateInput) before conversion.
+func ParseObject(data []byte) (object.MemoryObject, error) {
+	input, err := DecodeObject(data)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+	if err := ValidateInput(input); err != nil {
+		return object.MemoryObject{}, err
+	}
+	return ToMemoryObject(input), nil
+}
+
+// ParseDocument decodes a document holding one or more memory objects.
+// A document may be a single object, an array of objects, or a wrapper
+// object of the form {"objects": [...]}. batch reports whether the
+// document used one of the multi-object forms.
+func ParseDocument(data []byte) (objs []object.MemoryObject, batch bool, err error) {
+	objs, errs, batch, err := ParseDocumentItems(data)
+	if err != nil {
+		return nil, batch, err
+	}
+	for i, err := range errs {
+		if err != nil {
+			return nil, true, fmt.Errorf("object %d: %w", i, err)
+		}
+	}
+	return objs, batch, nil
+}
+
+// ParseDocumentItems is ParseDocument keeping going past invalid objects
+// of a multi-object document: errs[i] is the reason objs[i] is invalid,
+// in which case objs[i] is empty, or nil. err is reserved for documents
+// that cannot be read at all and, since a single object is the whole
+// document, for a single object that is invalid.
+func ParseDocumentItems(data []byte) (objs []object.MemoryObject, errs []error, batch bool, err error) {
+	v, err := Decode(data)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var items []interface{}
+	switch doc := v.(type) {
+	case []interface{}:
+		items = doc
+	case map[string]interface{}:
+		wrapped, ok := doc["objects"].([]interface{})
+		if !ok || len(doc) != 1 {
+			if err := ValidateInput(doc); err != nil {
+				return nil, nil, false, err
+			}
+			return []object.MemoryObject{ToMemoryObject(doc)}, []error{nil}, false, nil
+		}
+		items = wrapped
+	default:
+		return nil, nil, false, fmt.Errorf("expected a JSON object or array, got %T", v)
+	}
+
+	objs = make([]object.MemoryObject, len(items))
+	errs = make([]error, len(items))
+	for i, item := range items {
+		input, ok := item.(map[string]interface{})
+		if !ok {
+			errs[i] = fmt.Errorf("expected a JSON object, got %T", item)
+			continue
+		}
+		if err := ValidateInput(input); err != nil {
+			errs[i] = err
+			continue
+		}
+		objs[i] = ToMemoryObject(input)
+	}
+	return objs, errs, true, nil
+}
+
+// ToMemoryObject converts a raw JSON map into a MemoryObject.
+// It performs no validation; callers apply the ingest rules first.
+func ToMemoryObject(input map[string]interface{}) object.MemoryObject {
+	obj := object.MemoryObject{}
+
+	if v, ok := input["_helios_schema_version"].(string); ok && v != object.SchemaV1 {
+		obj.SchemaVersion = v
+	}
+	if v, ok := input["category"].(string); ok {
+		obj.Category = v
+	}
+	if v, ok := input["created_at"].(string); ok {
+		obj.CreatedAt = v
+	}
+	if v, ok := input["key"].(string); ok {
+		obj.Key = v
+	}
+	if v, ok := input["source"].(string); ok {
+		obj.Source = v
+	}
+	obj.Value = input["value"]
+
+	// Relationship attributes exist from schema version 2; version 1
+	// ignores them.
+	v2 := obj.SchemaVersion == object.SchemaV2
+	if rels, ok := input["relationships"].([]interface{}); ok {
+		for _, r := range rels {
+			if rm, ok := r.(map[string]interface{}); ok {
+				rel := object.Relationship{}
+				if k, ok := rm["key"].(string); ok {
+					rel.Key = k
+				}
+				if t, ok := rm["type"].(string); ok {
+					rel.Type = t
+				}
+				if w, ok := rm[canon.RelationshipWeight].(json.Number); ok && v2 {
+					if n, err := w.Int64(); err == nil {
+						rel.Weight = &n
+					}
+				}
+				if n, ok := rm[canon.RelationshipNote].(string); ok && v2 {
+					rel.Note = &n
+				}
+				obj.Relationships = append(obj.Relationships, rel)
+			}
+		}
+	}
+	if _, exists := input["relationships"]; exists && obj.Relationships == nil {
+		obj.Relationships = []object.Relationship{}
+	}
+
+	if v, ok := input["updated_at"].(string); ok {
+		obj.UpdatedAt = v
+	}
+	if v, ok := input["version"]; ok {
+		switch vv := v.(type) {
+		case json.Number:
+			n, _ := vv.Int64()
+			obj.Version = int(n)
+		case float64:
+			obj.Version = int(vv)
+		}
+	}
+	if v, ok := input["access_count"]; ok {
+		switch vv := v.(type) {
+		case json.Number:
+			n, _ := vv.Int64()
+			obj.AccessCount = int(n)
+		case float64:
+			obj.AccessCount = int(vv)
+		}
+	}
+	if v, ok := input["last_accessed"].(string); ok {
+		obj.LastAccessed = v
+	}
+	if v, ok := input["tenant"].(string); ok {
+		obj.Tenant = v
+	}
+	if v, ok := input["supersedes"].(string); ok {
+		obj.Supersedes = v
+	}
+	if v, ok := input["commitment_salt"].(string); ok {
+		obj.CommitmentSalt = v
+	}
+	if v, ok := input["residency"].(string); ok {
+		obj.Residency = v
+	}
+	if v, ok := input["legal_hold"].(string); ok {
+		obj.LegalHold = v
+	}
+	if v, ok := input["confidence"]; ok {
+		switch vv := v.(type) {
+		case json.Number:
+			f, _ := vv.Float64()
+			obj.Confidence = f
+		case float64:
+			obj.Confidence = vv
+		}
+	}
+
+	return obj
+}
+
+// Normalize returns a copy of input with the hash-relevant fields in
+// canonical form: NFC-normalized strings, a normalized created_at, and
+// relationships sorted by key then type. Excluded fields are copied as-is.
+func Normalize(input map[string]interface{}) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		out[k] = v
+	}
+
+	for _, field := range []string{"category", "key", "source"} {
+		if s, ok := input[field].(string); ok {
+			out[field] = canon.NormalizeString(s)
+		}
+	}
+	if s, ok := input["value"].(string); ok {
+		out["value"] = canon.NormalizeString(s)
+	}
+	if s, ok := input["created_at"].(string); ok {
+		ts, err := canon.NormalizeTimestamp(s)
+		if err != nil {
+			return nil, err
+		}
+		out["created_at"] = ts
+	}
+
+	if rels, ok := input["relationships"].([]interface{}); ok {
+		v2 := input["_helios_schema_version"] == object.SchemaV2
+		relMaps := make([]map[string]interface{}, 0, len(rels))
+		for _, r := range rels {
+			rm, ok := r.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("relationship must be an object, got %T", r)
+			}
+			k, _ := rm["key"].(string)
+			t, _ := rm["type"].(string)
+			rel := canon.RelationshipToMap(canon.NormalizeString(k), canon.NormalizeString(t))
+			if w, ok := rm[canon.RelationshipWeight]; ok && v2 {
+				rel[canon.RelationshipWeight] = w
+			}
+			if n, ok := rm[canon.RelationshipNote].(string); ok && v2 {
+				rel[canon.RelationshipNote] = canon.NormalizeString(n)
+			}
+			relMaps = append(relMaps, rel)
+		}
+		sorted := canon.SortRelationships(relMaps)
+		relsOut := make([]interface{}, len(sorted))
+		for i, r := range sorted {
+			relsOut[i] = r
+		}
+		out["relationships"] = relsOut
+	}
+
+	return out, nil
+}
+
+// NormalizeDocument validates and normalizes every memory object in a
+// decoded document, preserving its shape: a single object, an array of
+// objects, or an {"objects": [...]} wrapper.
+func NormalizeDocument(doc interface{}) (interface{}, error) {
+	normalizeOne := func(v interface{}) (interface{}, error) {
+		input, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a JSON object, got %T", v)
+		}
+		if err := ValidateInput(input); err != nil {
+			return nil, err
+		}
+		return Normalize(input)
+	}
+	normalizeAll := func(items []interface{}) ([]interface{}, error) {
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			n, err := normalizeOne(item)
+			if err != nil {
+				return nil, fmt.Errorf("object %d: %w", i, err)
+			}
+			out[i] = n
+		}
+		return out, nil
+	}
+
+	switch d := doc.(type) {
+	case []interface{}:
+		return normalizeAll(d)
+	case map[string]interface{}:
+		if items, ok := d["objects"].([]interface{}); ok && len(d) == 1 {
+			out, err := normalizeAll(items)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"objects": out}, nil
+		}
+		return normalizeOne(d)
+	default:
+		return nil, fmt.Errorf("expected a JSON object or array, got %T", doc)
+	}
+}
+
+// Record is one memory object read from a corpus together with its origin,
+// e.g. "memories/a.json", "batch.json#3", or "corpus.ndjson:17".
+type Record struct {
+	Origin string
+	Object object.MemoryObject
+	// Err is why the object at Origin could not be parsed, in which case
+	// Object is empty. Only loaders that keep going past invalid objects
+	// return such records.
+	Err error
+	// Raw is the object's bytes as read from Origin, set only by loaders
+	// that keep them (see corpus.LoadRaw).
+	Raw []byte
+}
@@ -0,0 +1,170 @@
+package ingest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestDecodePreservesNumbers(t *testing.T) {
+	v, err := Decode([]byte(`{"n": 9007199254740993}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, ok := v.(map[string]interface{})["n"].(json.Number)
+	if !ok {
+		t.Fatalf("expected json.Number, got %T", v.(map[string]interface{})["n"])
+	}
+	if n.String() != "9007199254740993" {
+		t.Errorf("number not preserved: %s", n)
+	}
+}
+
+func TestDecodeRejectsTrailingData(t *testing.T) {
+	if _, err := Decode([]byte(`{"a": "b"} {"c": "d"}`)); err == nil {
+		t.Error("expected error for trailing data, got nil")
+	}
+}
+
+func TestParseObjectRejectsFloat(t *testing.T) {
+	_, err := ParseObject([]byte(`{"key": "k", "value": 1.5}`))
+	if err == nil || !strings.Contains(err.Error(), "CANON_ERR_FLOAT_PROHIBITED") {
+		t.Errorf("expected CANON_ERR_FLOAT_PROHIBITED, got %v", err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	nfd := norm.NFD.String("café")
+	input := map[string]interface{}{
+		"category":   nfd,
+		"created_at": "2025-01-15T10:30:00.000Z",
+		"key":        "k",
+		"relationships": []interface{}{
+			map[string]interface{}{"key": "z", "type": "a"},
+			map[string]interface{}{"key": "a", "type": "b"},
+		},
+		"source":     "user",
+		"value":      nfd,
+		"confidence": json.Number("0.5"),
+	}
+	out, err := Normalize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["category"] != "café" || out["value"] != "café" {
+		t.Errorf("strings not NFC-normalized: %q %q", out["category"], out["value"])
+	}
+	rels := out["relationships"].([]interface{})
+	if rels[0].(map[string]interface{})["key"] != "a" {
+		t.Errorf("relationships not sorted: %v", rels)
+	}
+	if out["confidence"] != json.Number("0.5") {
+		t.Error("excluded fields must be copied unchanged")
+	}
+	if input["category"] != nfd {
+		t.Error("Normalize must not modify its input")
+	}
+}
+
+func TestParseDocumentForms(t *testing.T) {
+	obj := `{"category":"c","created_at":"2025-01-15T10:30:00.000Z","key":"%s","relationships":[],"source":"s","value":"v"}`
+	one := strings.Replace(obj, "%s", "one", 1)
+	two := strings.Replace(obj, "%s", "two", 1)
+
+	tests := []struct {
+		name  string
+		doc   string
+		keys  []string
+		batch bool
+	}{
+		{"single", one, []string{"one"}, false},
+		{"array", "[" + one + "," + two + "]", []string{"one", "two"}, true},
+		{"wrapper", `{"objects":[` + one + "," + two + "]}", []string{"one", "two"}, true},
+		{"empty array", "[]", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, batch, err := ParseDocument([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if batch != tt.batch {
+				t.Errorf("batch: expected %v, got %v", tt.batch, batch)
+			}
+			if len(objs) != len(tt.keys) {
+				t.Fatalf("expected %d objects, got %d", len(tt.keys), len(objs))
+			}
+			for i, k := range tt.keys {
+				if objs[i].Key != k {
+					t.Errorf("object %d: expected key %q, got %q", i, k, objs[i].Key)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDocumentReportsIndex(t *testing.T) {
+	_, _, err := ParseDocument([]byte(`[{"key":"ok","value":"v"},{"key":"bad","value":null}]`))
+	if err == nil {
+		t.Fatal("expected error for null value, got nil")
+	}
+	if !strings.Contains(err.Error(), "object 1") || !strings.Contains(err.Error(), "CANON_ERR_NULL_PROHIBITED") {
+		t.Errorf("expected indexed null rejection, got: %v", err)
+	}
+}
+
+func TestParseDocumentItemsKeepsGoing(t *testing.T) {
+	objs, errs, batch, err := ParseDocumentItems([]byte(`[{"key":"ok","value":"v"},{"key":"bad","value":null},5]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !batch || len(objs) != 3 || len(errs) != 3 {
+		t.Fatalf("expected three batch items, got %d objects, %d errors, batch %v", len(objs), len(errs), batch)
+	}
+	if errs[0] != nil || objs[0].Key != "ok" {
+		t.Errorf("item 0: %+v, %v", objs[0], errs[0])
+	}
+	if errs[1] == nil || !strings.Contains(errs[1].Error(), "CANON_ERR_NULL_PROHIBITED") {
+		t.Errorf("item 1: expected null rejection, got %v", errs[1])
+	}
+	if errs[2] == nil {
+		t.Error("item 2: a number was accepted as an object")
+	}
+
+	if _, _, _, err := ParseDocumentItems([]byte(`{"key":"bad","value":null}`)); err == nil {
+		t.Error("an invalid single-object document was accepted")
+	}
+}
+
+func TestNormalizeDocumentPreservesShape(t *testing.T) {
+	obj := `{"category":"c","created_at":"2025-01-15T10:30:00.000Z","key":"k","relationships":[],"source":"s","value":"v"}`
+	for _, doc := range []string{obj, "[" + obj + "]", `{"objects":[` + obj + `]}`} {
+		v, err := Decode([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := NormalizeDocument(v)
+		if err != nil {
+			t.Fatalf("%s: %v", doc, err)
+		}
+		switch v.(type) {
+		case []interface{}:
+			if _, ok := out.([]interface{}); !ok {
+				t.Errorf("array document should stay an array, got %T", out)
+			}
+		case map[string]interface{}:
+			m := out.(map[string]interface{})
+			_, wrapped := v.(map[string]interface{})["objects"]
+			if _, ok := m["objects"]; ok != wrapped {
+				t.Errorf("wrapper shape not preserved for %s", doc)
+			}
+		}
+	}
+
+	bad, _ := Decode([]byte(`[{"key":"k","value":1.5}]`))
+	if _, err := NormalizeDocument(bad); err == nil || !strings.Contains(err.Error(), "object 0") {
+		t.Errorf("expected indexed float rejection, got %v", err)
+	}
+}
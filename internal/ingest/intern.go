@@ -0,0 +1,74 @@
+package ingest
+
+import "github.com/holeyfield33-art/helios/internal/object"
+
+// Interner deduplicates the strings of loaded objects that tend to repeat
+// across a corpus: map keys in values, relationship keys and types,
+// categories, sources, and schema versions. Decoding allocates every
+// occurrence separately; interning makes equal strings share one copy,
+// so the duplicates become garbage as soon as a file is loaded. String
+// values and object keys are left alone, since they rarely repeat.
+//
+// An Interner is not safe for concurrent use.
+type Interner struct {
+	strings map[string]string
+	// Hits counts the strings replaced by a copy already held.
+	Hits int
+}
+
+// NewInterner returns an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{strings: make(map[string]string)}
+}
+
+// String returns the held copy of s, holding s if there is none.
+func (in *Interner) String(s string) string {
+	if s == "" {
+		return s
+	}
+	if held, ok := in.strings[s]; ok {
+		in.Hits++
+		return held
+	}
+	in.strings[s] = s
+	return s
+}
+
+// Object interns the repeated strings of obj in place. Maps in obj.Value
+// are rebuilt with interned keys.
+func (in *Interner) Object(obj *object.MemoryObject) {
+	obj.SchemaVersion = in.String(obj.SchemaVersion)
+	obj.Category = in.String(obj.Category)
+	obj.Source = in.String(obj.Source)
+	for i := range obj.Relationships {
+		r := &obj.Relationships[i]
+		r.Key = in.String(r.Key)
+		r.Type = in.String(r.Type)
+	}
+	obj.Value = in.value(obj.Value)
+}
+
+func (in *Interner) value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			m[in.String(k)] = in.value(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range val {
+			val[i] = in.value(e)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// Records interns the objects of records in place.
+func (in *Interner) Records(records []Record) {
+	for i := range records {
+		in.Object(&records[i].Object)
+	}
+}
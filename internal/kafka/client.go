@@ -0,0 +1,704 @@
+// Package kafka is a minimal Kafka client covering what `helios consume`
+// needs: metadata, list-offsets, fetch, produce, and consumer group offset
+// commits. It speaks the pre-flexible protocol versions every broker since
+// 0.11 accepts and decodes record batch v2 with no, gzip, or snappy
+// compression.
+//
+// There is no group membership protocol: a consumer reads every partition
+// of its topic and uses the group only to store committed offsets, so run
+// one consumer per topic and group.
+package kafka
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// API keys and the versions used for them.
+const (
+	apiProduce         = 0
+	apiFetch           = 1
+	apiListOffsets     = 2
+	apiMetadata        = 3
+	apiOffsetCommit    = 8
+	apiOffsetFetch     = 9
+	apiFindCoordinator = 10
+
+	versionProduce         = 3
+	versionFetch           = 4
+	versionListOffsets     = 1
+	versionMetadata        = 1
+	versionOffsetCommit    = 2
+	versionOffsetFetch     = 1
+	versionFindCoordinator = 0
+)
+
+// Special timestamps for ListOffset.
+const (
+	Latest   int64 = -1
+	Earliest int64 = -2
+)
+
+// maxResponse bounds a single response frame.
+const maxResponse = 256 << 20
+
+// Error is a broker error code.
+type Error int16
+
+var errorNames = map[Error]string{
+	1:  "OFFSET_OUT_OF_RANGE",
+	3:  "UNKNOWN_TOPIC_OR_PARTITION",
+	5:  "LEADER_NOT_AVAILABLE",
+	6:  "NOT_LEADER_OR_FOLLOWER",
+	7:  "REQUEST_TIMED_OUT",
+	14: "COORDINATOR_LOAD_IN_PROGRESS",
+	15: "COORDINATOR_NOT_AVAILABLE",
+	16: "NOT_COORDINATOR",
+	25: "UNKNOWN_MEMBER_ID",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+	30: "GROUP_AUTHORIZATION_FAILED",
+}
+
+func (e Error) Error() string {
+	if name, ok := errorNames[e]; ok {
+		return "kafka: " + name
+	}
+	return fmt.Sprintf("kafka: broker error %d", int16(e))
+}
+
+// Retriable reports whether the request may succeed after refreshing
+// metadata or finding the coordinator again.
+func (e Error) Retriable() bool {
+	switch e {
+	case 3, 5, 6, 7, 14, 15, 16:
+		return true
+	}
+	return false
+}
+
+// ErrOffsetOutOfRange is returned by Fetch when the requested offset is
+// no longer (or not yet) in the log.
+const ErrOffsetOutOfRange Error = 1
+
+// Config configures a Client.
+type Config struct {
+	// Brokers are the bootstrap host:port addresses.
+	Brokers []string
+	// ClientID identifies the client in broker logs. Default "helios".
+	ClientID string
+	// DialTimeout bounds connection setup. Default 10s.
+	DialTimeout time.Duration
+}
+
+// Client is safe for concurrent use. Requests to one broker are
+// serialized on a single connection.
+type Client struct {
+	cfg Config
+
+	mu      sync.Mutex
+	corrID  int32
+	nodes   map[int32]string
+	leaders map[string]map[int32]int32
+	coords  map[string]string
+	conns   map[string]*conn
+}
+
+type conn struct {
+	mu sync.Mutex
+	nc net.Conn
+	r  *bufio.Reader
+}
+
+// Dial connects to the first reachable bootstrap broker and loads the
+// cluster metadata.
+func Dial(cfg Config) (*Client, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers configured")
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "helios"
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 10 * time.Second
+	}
+	c := &Client{
+		cfg:     cfg,
+		nodes:   make(map[int32]string),
+		leaders: make(map[string]map[int32]int32),
+		coords:  make(map[string]string),
+		conns:   make(map[string]*conn),
+	}
+	if err := c.refresh(nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes every broker connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for addr, cn := range c.conns {
+		cn.nc.Close()
+		delete(c.conns, addr)
+	}
+	return nil
+}
+
+func (c *Client) conn(addr string) (*conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cn, ok := c.conns[addr]; ok {
+		return cn, nil
+	}
+	nc, err := net.DialTimeout("tcp", addr, c.cfg.DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	cn := &conn{nc: nc, r: bufio.NewReader(nc)}
+	c.conns[addr] = cn
+	return cn, nil
+}
+
+func (c *Client) drop(addr string, cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conns[addr] == cn {
+		delete(c.conns, addr)
+	}
+	cn.nc.Close()
+}
+
+// call sends one request to addr and returns the response body after the
+// correlation id. A connection that fails mid-request is discarded.
+func (c *Client) call(addr string, key, version int16, body []byte, timeout time.Duration) (*decoder, error) {
+	cn, err := c.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.corrID++
+	id := c.corrID
+	c.mu.Unlock()
+
+	var e encoder
+	e.int32(0) // size, patched below
+	e.int16(key)
+	e.int16(version)
+	e.int32(id)
+	e.string(c.cfg.ClientID)
+	e.b = append(e.b, body...)
+	binary.BigEndian.PutUint32(e.b, uint32(len(e.b)-4))
+
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+	cn.nc.SetDeadline(time.Now().Add(timeout + 30*time.Second))
+	resp, err := cn.roundTrip(e.b, id)
+	if err != nil {
+		c.drop(addr, cn)
+		return nil, fmt.Errorf("kafka: %s: %w", addr, err)
+	}
+	return &decoder{b: resp}, nil
+}
+
+func (cn *conn) roundTrip(req []byte, id int32) ([]byte, error) {
+	if _, err := cn.nc.Write(req); err != nil {
+		return nil, err
+	}
+	var hdr [8]byte
+	if _, err := io.ReadFull(cn.r, hdr[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(hdr[:4]))
+	if size < 4 || size > maxResponse {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	if got := int32(binary.BigEndian.Uint32(hdr[4:])); got != id {
+		return nil, fmt.Errorf("correlation id %d does not match request %d", got, id)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(cn.r, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// bootstrap returns the address of any known broker.
+func (c *Client) bootstrap() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	addrs := make([]string, 0, len(c.nodes)+len(c.cfg.Brokers))
+	for _, a := range c.nodes {
+		addrs = append(addrs, a)
+	}
+	return append(addrs, c.cfg.Brokers...)
+}
+
+// refresh reloads metadata for topics (nil for all topics).
+func (c *Client) refresh(topics []string) error {
+	var e encoder
+	if topics == nil {
+		e.int32(-1)
+	} else {
+		e.arrayLen(len(topics))
+		for _, t := range topics {
+			e.string(t)
+		}
+	}
+
+	var lastErr error
+	for _, addr := range c.bootstrap() {
+		d, err := c.call(addr, apiMetadata, versionMetadata, e.b, 0)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return c.applyMetadata(d)
+	}
+	return lastErr
+}
+
+func (c *Client) applyMetadata(d *decoder) error {
+	nodes := make(map[int32]string)
+	for n := d.arrayLen(); n > 0; n-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		nodes[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.int32() // controller id
+	leaders := make(map[string]map[int32]int32)
+	var topicErr error
+	for n := d.arrayLen(); n > 0; n-- {
+		code := Error(d.int16())
+		name := d.string()
+		d.bool() // internal
+		parts := make(map[int32]int32)
+		for p := d.arrayLen(); p > 0; p-- {
+			d.int16() // partition error; the leader id tells us enough
+			idx := d.int32()
+			parts[idx] = d.int32()
+			for r := d.arrayLen(); r > 0; r-- {
+				d.int32()
+			}
+			for r := d.arrayLen(); r > 0; r-- {
+				d.int32()
+			}
+		}
+		if code != 0 {
+			topicErr = fmt.Errorf("topic %q: %w", name, code)
+			continue
+		}
+		leaders[name] = parts
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, addr := range nodes {
+		c.nodes[id] = addr
+	}
+	for t, parts := range leaders {
+		c.leaders[t] = parts
+	}
+	return topicErr
+}
+
+// Partitions returns the partition ids of topic in ascending order.
+func (c *Client) Partitions(topic string) ([]int32, error) {
+	if err := c.refresh([]string{topic}); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	parts := c.leaders[topic]
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("topic %q: %w", topic, Error(3))
+	}
+	ids := make([]int32, 0, len(parts))
+	for id := range parts {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// leader returns the address of the leader of topic/partition.
+func (c *Client) leader(topic string, partition int32) (string, error) {
+	c.mu.Lock()
+	id, ok := c.leaders[topic][partition]
+	addr := c.nodes[id]
+	c.mu.Unlock()
+	if ok && id >= 0 && addr != "" {
+		return addr, nil
+	}
+	if err := c.refresh([]string{topic}); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok = c.leaders[topic][partition]
+	if !ok || id < 0 || c.nodes[id] == "" {
+		return "", fmt.Errorf("topic %q partition %d: %w", topic, partition, Error(5))
+	}
+	return c.nodes[id], nil
+}
+
+// retry runs fn against the current leader, refreshing metadata and
+// retrying once when the broker reports a retriable error.
+func (c *Client) retry(topic string, fn func() error) error {
+	err := fn()
+	var ke Error
+	if errors.As(err, &ke) && ke.Retriable() {
+		if rerr := c.refresh([]string{topic}); rerr != nil {
+			return err
+		}
+		err = fn()
+	}
+	return err
+}
+
+// ListOffset returns the first offset with a timestamp at or after ts,
+// or the log start or end offset for Earliest and Latest.
+func (c *Client) ListOffset(topic string, partition int32, ts int64) (int64, error) {
+	var offset int64
+	err := c.retry(topic, func() error {
+		addr, err := c.leader(topic, partition)
+		if err != nil {
+			return err
+		}
+		var e encoder
+		e.int32(-1) // replica id
+		e.arrayLen(1)
+		e.string(topic)
+		e.arrayLen(1)
+		e.int32(partition)
+		e.int64(ts)
+		d, err := c.call(addr, apiListOffsets, versionListOffsets, e.b, 0)
+		if err != nil {
+			return err
+		}
+		var code Error
+		for n := d.arrayLen(); n > 0; n-- {
+			d.string()
+			for p := d.arrayLen(); p > 0; p-- {
+				d.int32()
+				code = Error(d.int16())
+				d.int64() // timestamp
+				offset = d.int64()
+			}
+		}
+		if d.err != nil {
+			return d.err
+		}
+		if code != 0 {
+			return code
+		}
+		return nil
+	})
+	return offset, err
+}
+
+// FetchResult is one partition's share of a Fetch.
+type FetchResult struct {
+	Messages      []Message
+	HighWatermark int64
+	Err           error
+}
+
+// Fetch reads messages from each partition in offsets, starting at the
+// given offset, waiting up to maxWait for at least one message. Partitions
+// are fetched from their leaders concurrently and failures are reported
+// per partition.
+func (c *Client) Fetch(topic string, offsets map[int32]int64, maxWait time.Duration, maxBytes int32) map[int32]FetchResult {
+	byLeader := make(map[string][]int32)
+	results := make(map[int32]FetchResult, len(offsets))
+	for p := range offsets {
+		addr, err := c.leader(topic, p)
+		if err != nil {
+			results[p] = FetchResult{Err: err}
+			continue
+		}
+		byLeader[addr] = append(byLeader[addr], p)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for addr, parts := range byLeader {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.fetchFrom(addr, topic, parts, offsets, maxWait, maxBytes)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range parts {
+				if err != nil {
+					results[p] = FetchResult{Err: err}
+				} else {
+					results[p] = res[p]
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stale := false
+	for _, r := range results {
+		var ke Error
+		if errors.As(r.Err, &ke) && ke.Retriable() {
+			stale = true
+		}
+	}
+	if stale {
+		c.refresh([]string{topic})
+	}
+	return results
+}
+
+func (c *Client) fetchFrom(addr, topic string, parts []int32, offsets map[int32]int64, maxWait time.Duration, maxBytes int32) (map[int32]FetchResult, error) {
+	var e encoder
+	e.int32(-1) // replica id
+	e.int32(int32(maxWait / time.Millisecond))
+	e.int32(1)        // min bytes
+	e.int32(maxBytes) // max bytes
+	e.int8(0)         // read uncommitted
+	e.arrayLen(1)
+	e.string(topic)
+	e.arrayLen(len(parts))
+	for _, p := range parts {
+		e.int32(p)
+		e.int64(offsets[p])
+		e.int32(maxBytes)
+	}
+	d, err := c.call(addr, apiFetch, versionFetch, e.b, maxWait)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[int32]FetchResult, len(parts))
+	d.int32() // throttle time
+	for n := d.arrayLen(); n > 0; n-- {
+		name := d.string()
+		for p := d.arrayLen(); p > 0; p-- {
+			idx := d.int32()
+			code := Error(d.int16())
+			hw := d.int64()
+			d.int64() // last stable offset
+			for a := d.int32(); a > 0 && d.err == nil; a-- {
+				d.int64()
+				d.int64()
+			}
+			records := d.bytes()
+			if d.err != nil {
+				return nil, d.err
+			}
+			res := FetchResult{HighWatermark: hw}
+			if code != 0 {
+				res.Err = code
+			} else {
+				res.Messages, res.Err = decodeRecords(name, idx, records, offsets[idx])
+			}
+			results[idx] = res
+		}
+	}
+	return results, d.err
+}
+
+// Produce appends msgs to topic/partition and waits for all in-sync
+// replicas to acknowledge them.
+func (c *Client) Produce(topic string, partition int32, msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	batch, err := encodeRecords(msgs)
+	if err != nil {
+		return err
+	}
+	return c.retry(topic, func() error {
+		addr, err := c.leader(topic, partition)
+		if err != nil {
+			return err
+		}
+		var e encoder
+		e.nullableString(nil) // transactional id
+		e.int16(-1)           // acks=all
+		e.int32(30000)
+		e.arrayLen(1)
+		e.string(topic)
+		e.arrayLen(1)
+		e.int32(partition)
+		e.bytes(batch)
+		d, err := c.call(addr, apiProduce, versionProduce, e.b, 30*time.Second)
+		if err != nil {
+			return err
+		}
+		var code Error
+		for n := d.arrayLen(); n > 0; n-- {
+			d.string()
+			for p := d.arrayLen(); p > 0; p-- {
+				d.int32()
+				if pc := Error(d.int16()); pc != 0 {
+					code = pc
+				}
+				d.int64() // base offset
+				d.int64() // log append time
+			}
+		}
+		if d.err != nil {
+			return d.err
+		}
+		if code != 0 {
+			return fmt.Errorf("produce to %s/%d: %w", topic, partition, code)
+		}
+		return nil
+	})
+}
+
+// coordinator returns the address of the group coordinator.
+func (c *Client) coordinator(group string, fresh bool) (string, error) {
+	c.mu.Lock()
+	addr, ok := c.coords[group]
+	c.mu.Unlock()
+	if ok && !fresh {
+		return addr, nil
+	}
+	var e encoder
+	e.string(group)
+	var lastErr error
+	for _, b := range c.bootstrap() {
+		d, err := c.call(b, apiFindCoordinator, versionFindCoordinator, e.b, 0)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		code := Error(d.int16())
+		d.int32() // node id
+		host := d.string()
+		port := d.int32()
+		if d.err != nil {
+			return "", d.err
+		}
+		if code != 0 {
+			return "", fmt.Errorf("group %q: %w", group, code)
+		}
+		addr = net.JoinHostPort(host, strconv.Itoa(int(port)))
+		c.mu.Lock()
+		c.coords[group] = addr
+		c.mu.Unlock()
+		return addr, nil
+	}
+	return "", lastErr
+}
+
+// withCoordinator runs fn against the group coordinator, looking it up
+// again once if it has moved.
+func (c *Client) withCoordinator(group string, fn func(addr string) error) error {
+	addr, err := c.coordinator(group, false)
+	if err != nil {
+		return err
+	}
+	err = fn(addr)
+	var ke Error
+	if errors.As(err, &ke) && ke.Retriable() {
+		if addr, err2 := c.coordinator(group, true); err2 == nil {
+			err = fn(addr)
+		}
+	}
+	return err
+}
+
+// CommittedOffsets returns the committed offset of each partition for
+// group; partitions without a commit map to -1.
+func (c *Client) CommittedOffsets(group, topic string, partitions []int32) (map[int32]int64, error) {
+	offsets := make(map[int32]int64, len(partitions))
+	err := c.withCoordinator(group, func(addr string) error {
+		var e encoder
+		e.string(group)
+		e.arrayLen(1)
+		e.string(topic)
+		e.arrayLen(len(partitions))
+		for _, p := range partitions {
+			e.int32(p)
+		}
+		d, err := c.call(addr, apiOffsetFetch, versionOffsetFetch, e.b, 0)
+		if err != nil {
+			return err
+		}
+		var code Error
+		for n := d.arrayLen(); n > 0; n-- {
+			d.string()
+			for p := d.arrayLen(); p > 0; p-- {
+				idx := d.int32()
+				off := d.int64()
+				d.string() // metadata
+				if pc := Error(d.int16()); pc != 0 {
+					code = pc
+				}
+				offsets[idx] = off
+			}
+		}
+		if d.err != nil {
+			return d.err
+		}
+		if code != 0 {
+			return code
+		}
+		return nil
+	})
+	return offsets, err
+}
+
+// CommitOffsets stores the next offset to consume for each partition.
+func (c *Client) CommitOffsets(group, topic string, offsets map[int32]int64) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	return c.withCoordinator(group, func(addr string) error {
+		var e encoder
+		e.string(group)
+		e.int32(-1) // generation: simple consumer, no membership
+		e.string("")
+		e.int64(-1) // broker default retention
+		e.arrayLen(1)
+		e.string(topic)
+		e.arrayLen(len(offsets))
+		for p, off := range offsets {
+			e.int32(p)
+			e.int64(off)
+			e.string("")
+		}
+		d, err := c.call(addr, apiOffsetCommit, versionOffsetCommit, e.b, 0)
+		if err != nil {
+			return err
+		}
+		var code Error
+		for n := d.arrayLen(); n > 0; n-- {
+			d.string()
+			for p := d.arrayLen(); p > 0; p-- {
+				d.int32()
+				if pc := Error(d.int16()); pc != 0 {
+					code = pc
+				}
+			}
+		}
+		if d.err != nil {
+			return d.err
+		}
+		if code != 0 {
+			return code
+		}
+		return nil
+	})
+}
@@ -0,0 +1,482 @@
+package kafka
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBroker is a single-node cluster speaking just the requests the
+// client sends, at the versions it sends them.
+type fakeBroker struct {
+	t  *testing.T
+	ln net.Listener
+
+	mu        sync.Mutex
+	logs      map[string][][]Message // topic -> partition -> messages
+	committed map[string]int64       // group/topic/partition -> offset
+	failNext  map[int16]Error        // api key -> error for the next request
+}
+
+func newFakeBroker(t *testing.T, topics map[string]int) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{t: t, ln: ln, logs: make(map[string][][]Message), committed: make(map[string]int64), failNext: make(map[int16]Error)}
+	for name, n := range topics {
+		b.logs[name] = make([][]Message, n)
+	}
+	go b.serve()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *fakeBroker) addr() string { return b.ln.Addr().String() }
+
+func (b *fakeBroker) append(topic string, partition int32, key, value string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	log := b.logs[topic][partition]
+	b.logs[topic][partition] = append(log, Message{Offset: int64(len(log)), Key: []byte(key), Value: []byte(value), Time: time.UnixMilli(1700000000000)})
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		nc, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(nc)
+	}
+}
+
+func (b *fakeBroker) handle(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, req); err != nil {
+			return
+		}
+		d := &decoder{b: req}
+		key := d.int16()
+		d.int16() // version
+		id := d.int32()
+		d.string() // client id
+
+		var e encoder
+		e.int32(0)
+		e.int32(id)
+		b.mu.Lock()
+		fail := b.failNext[key]
+		delete(b.failNext, key)
+		b.respond(key, d, &e, fail)
+		b.mu.Unlock()
+		binary.BigEndian.PutUint32(e.b, uint32(len(e.b)-4))
+		if _, err := nc.Write(e.b); err != nil {
+			return
+		}
+	}
+}
+
+func (b *fakeBroker) respond(key int16, d *decoder, e *encoder, fail Error) {
+	host, portStr, _ := net.SplitHostPort(b.addr())
+	port, _ := strconv.Atoi(portStr)
+	switch key {
+	case apiMetadata:
+		var want []string
+		for n := d.int32(); n > 0; n-- {
+			want = append(want, d.string())
+		}
+		e.arrayLen(1)
+		e.int32(1)
+		e.string(host)
+		e.int32(int32(port))
+		e.nullableString(nil)
+		e.int32(1)
+		if want == nil {
+			for name := range b.logs {
+				want = append(want, name)
+			}
+		}
+		e.arrayLen(len(want))
+		for _, name := range want {
+			parts, ok := b.logs[name]
+			if ok {
+				e.int16(int16(fail))
+			} else {
+				e.int16(3)
+			}
+			e.string(name)
+			e.int8(0)
+			e.arrayLen(len(parts))
+			for i := range parts {
+				e.int16(0)
+				e.int32(int32(i))
+				e.int32(1)
+				e.arrayLen(1)
+				e.int32(1)
+				e.arrayLen(1)
+				e.int32(1)
+			}
+		}
+	case apiListOffsets:
+		d.int32()
+		d.int32()
+		topic := d.string()
+		d.int32()
+		p := d.int32()
+		ts := d.int64()
+		off := int64(0)
+		if ts == Latest {
+			off = int64(len(b.logs[topic][p]))
+		}
+		e.arrayLen(1)
+		e.string(topic)
+		e.arrayLen(1)
+		e.int32(p)
+		e.int16(int16(fail))
+		e.int64(-1)
+		e.int64(off)
+	case apiFetch:
+		d.int32()
+		d.int32()
+		d.int32()
+		d.int32()
+		d.int8()
+		d.int32()
+		topic := d.string()
+		e.int32(0)
+		e.arrayLen(1)
+		e.string(topic)
+		n := d.int32()
+		e.arrayLen(int(n))
+		for ; n > 0; n-- {
+			p := d.int32()
+			off := d.int64()
+			d.int32()
+			log := b.logs[topic][p]
+			e.int32(p)
+			code := fail
+			if off > int64(len(log)) {
+				code = ErrOffsetOutOfRange
+			}
+			e.int16(int16(code))
+			e.int64(int64(len(log)))
+			e.int64(int64(len(log)))
+			e.int32(-1)
+			if code != 0 || off == int64(len(log)) {
+				e.bytes(nil)
+				continue
+			}
+			// Serve from the start of a batch holding off, as brokers do.
+			batch, _ := encodeRecords(log)
+			e.bytes(batch)
+		}
+	case apiProduce:
+		d.string()
+		d.int16()
+		d.int32()
+		d.int32()
+		topic := d.string()
+		d.int32()
+		p := d.int32()
+		msgs, err := decodeRecords(topic, p, d.bytes(), 0)
+		if err != nil {
+			b.t.Errorf("produce: %v", err)
+		}
+		base := int64(len(b.logs[topic][p]))
+		for _, m := range msgs {
+			m.Offset += base
+			b.logs[topic][p] = append(b.logs[topic][p], m)
+		}
+		e.arrayLen(1)
+		e.string(topic)
+		e.arrayLen(1)
+		e.int32(p)
+		e.int16(int16(fail))
+		e.int64(base)
+		e.int64(-1)
+		e.int32(0)
+	case apiFindCoordinator:
+		d.string()
+		e.int16(int16(fail))
+		e.int32(1)
+		e.string(host)
+		e.int32(int32(port))
+	case apiOffsetFetch:
+		group := d.string()
+		d.int32()
+		topic := d.string()
+		e.arrayLen(1)
+		e.string(topic)
+		n := d.int32()
+		e.arrayLen(int(n))
+		for ; n > 0; n-- {
+			p := d.int32()
+			off, ok := b.committed[group+"/"+topic+"/"+strconv.Itoa(int(p))]
+			if !ok {
+				off = -1
+			}
+			e.int32(p)
+			e.int64(off)
+			e.string("")
+			e.int16(int16(fail))
+		}
+	case apiOffsetCommit:
+		group := d.string()
+		d.int32()
+		d.string()
+		d.int64()
+		d.int32()
+		topic := d.string()
+		e.arrayLen(1)
+		e.string(topic)
+		n := d.int32()
+		e.arrayLen(int(n))
+		for ; n > 0; n-- {
+			p := d.int32()
+			off := d.int64()
+			d.string()
+			if fail == 0 {
+				b.committed[group+"/"+topic+"/"+strconv.Itoa(int(p))] = off
+			}
+			e.int32(p)
+			e.int16(int16(fail))
+		}
+	default:
+		b.t.Errorf("unexpected api key %d", key)
+	}
+	if d.err != nil {
+		b.t.Errorf("api key %d: %v", key, d.err)
+	}
+}
+
+func TestRecordsRoundTrip(t *testing.T) {
+	at := time.UnixMilli(1700000000123).UTC()
+	in := []Message{
+		{Key: []byte("a"), Value: []byte(`{"key":"a"}`), Time: at},
+		{Key: nil, Value: []byte("second"), Time: at.Add(5 * time.Millisecond)},
+	}
+	batch, err := encodeRecords(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := decodeRecords("t", 3, batch, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("got %d messages, want 2", len(out))
+	}
+	for i, m := range out {
+		if m.Offset != int64(i) || m.Partition != 3 || m.Topic != "t" {
+			t.Errorf("message %d: got %s/%d@%d", i, m.Topic, m.Partition, m.Offset)
+		}
+		if !bytes.Equal(m.Key, in[i].Key) || !bytes.Equal(m.Value, in[i].Value) || !m.Time.Equal(in[i].Time) {
+			t.Errorf("message %d: got %+v, want %+v", i, m, in[i])
+		}
+	}
+	if out[1].Key != nil {
+		t.Errorf("null key decoded as %q", out[1].Key)
+	}
+
+	// minOffset filters earlier records of the batch.
+	out, _ = decodeRecords("t", 0, batch, 1)
+	if len(out) != 1 || out[0].Offset != 1 {
+		t.Errorf("minOffset 1: got %+v", out)
+	}
+	// A truncated trailing batch is ignored.
+	out, err = decodeRecords("t", 0, append(batch, batch[:20]...), 0)
+	if err != nil || len(out) != 2 {
+		t.Errorf("partial trailing batch: got %d messages, err %v", len(out), err)
+	}
+
+	corrupt := append([]byte(nil), batch...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	if _, err := decodeRecords("t", 0, corrupt, 0); err == nil {
+		t.Error("corrupted batch decoded without error")
+	}
+}
+
+// gzipBatch recompresses an uncompressed batch's records with gzip.
+func gzipBatch(t *testing.T, batch []byte) []byte {
+	t.Helper()
+	var z bytes.Buffer
+	zw := gzip.NewWriter(&z)
+	zw.Write(batch[recordBatchHeaderSize:])
+	zw.Close()
+	return recompress(batch, codecGzip, z.Bytes())
+}
+
+func TestRecordsGzip(t *testing.T) {
+	batch, _ := encodeRecords([]Message{{Value: []byte("one")}, {Value: []byte("two")}})
+	out, err := decodeRecords("t", 0, gzipBatch(t, batch), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || string(out[0].Value) != "one" || string(out[1].Value) != "two" {
+		t.Errorf("got %+v", out)
+	}
+
+}
+
+// recompress rewrites an uncompressed batch's records as body under codec.
+func recompress(batch []byte, codec int16, body []byte) []byte {
+	tail := append([]byte(nil), batch[21:recordBatchHeaderSize]...)
+	binary.BigEndian.PutUint16(tail, uint16(codec))
+	tail = append(tail, body...)
+	out := append([]byte(nil), batch[:17]...)
+	binary.BigEndian.PutUint32(out[8:], uint32(9+len(tail)))
+	out = binary.BigEndian.AppendUint32(out, crc32.Checksum(tail, castagnoli))
+	return append(out, tail...)
+}
+
+// snappyLiterals encodes b as a snappy block of literals.
+func snappyLiterals(b []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(b)))
+	for len(b) > 0 {
+		n := min(len(b), 60)
+		out = append(out, byte(n-1)<<2)
+		out = append(out, b[:n]...)
+		b = b[n:]
+	}
+	return out
+}
+
+func TestRecordsSnappy(t *testing.T) {
+	batch, _ := encodeRecords([]Message{{Value: []byte("one")}, {Value: []byte("two")}})
+	records := batch[recordBatchHeaderSize:]
+
+	// Java clients use xerial framing, with the records split into blocks.
+	xerial := append([]byte(nil), xerialHeader...)
+	xerial = binary.BigEndian.AppendUint32(xerial, 1)
+	xerial = binary.BigEndian.AppendUint32(xerial, 1)
+	for _, part := range [][]byte{records[:5], records[5:]} {
+		block := snappyLiterals(part)
+		xerial = binary.BigEndian.AppendUint32(xerial, uint32(len(block)))
+		xerial = append(xerial, block...)
+	}
+
+	for name, body := range map[string][]byte{"raw": snappyLiterals(records), "xerial": xerial} {
+		out, err := decodeRecords("t", 0, recompress(batch, codecSnappy, body), 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(out) != 2 || string(out[0].Value) != "one" || string(out[1].Value) != "two" {
+			t.Errorf("%s: got %+v", name, out)
+		}
+	}
+	if _, err := decodeRecords("t", 0, recompress(batch, codecSnappy, xerial[:len(xerial)-3]), 0); err == nil {
+		t.Error("truncated xerial frame decoded without error")
+	}
+}
+
+func TestRecordsUnsupportedCodec(t *testing.T) {
+	batch, _ := encodeRecords([]Message{{Value: []byte("one")}, {Value: []byte("two")}})
+	lz4 := recompress(batch, 3, []byte("opaque"))
+	out, err := decodeRecords("t", 2, append(lz4, batch...), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 3 || !errors.Is(out[0].Err, ErrUnsupportedCodec) || !strings.Contains(out[0].Err.Error(), "lz4") {
+		t.Fatalf("expected the lz4 batch as one error message, got %+v", out)
+	}
+	if out[0].Offset != 1 || out[0].Partition != 2 || out[1].Err != nil {
+		t.Errorf("error message should stand at the batch's last offset: %+v", out)
+	}
+	if out, _ := decodeRecords("t", 2, lz4, 2); len(out) != 0 {
+		t.Errorf("batch before minOffset: got %+v", out)
+	}
+}
+
+func TestClient(t *testing.T) {
+	b := newFakeBroker(t, map[string]int{"memories": 2, "results": 1})
+	b.append("memories", 1, "k1", "v1")
+	b.append("memories", 1, "k2", "v2")
+
+	c, err := Dial(Config{Brokers: []string{"127.0.0.1:1", b.addr()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	parts, err := c.Partitions("memories")
+	if err != nil || len(parts) != 2 || parts[0] != 0 || parts[1] != 1 {
+		t.Fatalf("Partitions: %v, %v", parts, err)
+	}
+	if _, err := c.Partitions("missing"); err == nil {
+		t.Error("Partitions of a missing topic succeeded")
+	}
+	if off, err := c.ListOffset("memories", 1, Latest); err != nil || off != 2 {
+		t.Errorf("ListOffset latest: %d, %v", off, err)
+	}
+
+	res := c.Fetch("memories", map[int32]int64{0: 0, 1: 1}, 10*time.Millisecond, 1<<20)
+	if r := res[0]; r.Err != nil || len(r.Messages) != 0 || r.HighWatermark != 0 {
+		t.Errorf("partition 0: %+v", r)
+	}
+	r := res[1]
+	if r.Err != nil || len(r.Messages) != 1 || r.HighWatermark != 2 {
+		t.Fatalf("partition 1: %+v", r)
+	}
+	if m := r.Messages[0]; m.Offset != 1 || string(m.Key) != "k2" || string(m.Value) != "v2" {
+		t.Errorf("partition 1 message: %+v", m)
+	}
+	res = c.Fetch("memories", map[int32]int64{1: 9}, 0, 1<<20)
+	if !errors.Is(res[1].Err, ErrOffsetOutOfRange) {
+		t.Errorf("out of range fetch: %v", res[1].Err)
+	}
+
+	if err := c.Produce("results", 0, []Message{{Key: []byte("k"), Value: []byte("r1")}, {Value: []byte("r2")}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.logs["results"][0]; len(got) != 2 || string(got[1].Value) != "r2" || got[1].Offset != 1 {
+		t.Errorf("produced log: %+v", got)
+	}
+
+	// A retriable error refreshes metadata and retries once.
+	b.mu.Lock()
+	b.failNext[apiProduce] = 6
+	b.mu.Unlock()
+	if err := c.Produce("results", 0, []Message{{Value: []byte("r3")}}); err != nil {
+		t.Errorf("produce after NOT_LEADER: %v", err)
+	}
+
+	offs, err := c.CommittedOffsets("g", "memories", parts)
+	if err != nil || offs[0] != -1 || offs[1] != -1 {
+		t.Errorf("CommittedOffsets before commit: %v, %v", offs, err)
+	}
+	if err := c.CommitOffsets("g", "memories", map[int32]int64{1: 2}); err != nil {
+		t.Fatal(err)
+	}
+	offs, err = c.CommittedOffsets("g", "memories", parts)
+	if err != nil || offs[0] != -1 || offs[1] != 2 {
+		t.Errorf("CommittedOffsets after commit: %v, %v", offs, err)
+	}
+
+	b.mu.Lock()
+	b.failNext[apiOffsetCommit] = 30
+	b.mu.Unlock()
+	err = c.CommitOffsets("g", "memories", map[int32]int64{1: 3})
+	var ke Error
+	if !errors.As(err, &ke) || ke != 30 || ke.Retriable() {
+		t.Errorf("commit with GROUP_AUTHORIZATION_FAILED: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/snappy"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Compression codecs from the record batch attributes. None, gzip, and
+// snappy are decoded; a batch compressed with lz4 or zstd is returned as a
+// single Message carrying ErrUnsupportedCodec.
+const (
+	codecNone   = 0
+	codecGzip   = 1
+	codecSnappy = 2
+	codecMask   = 0x07
+	attrControl = 0x20
+)
+
+var codecNames = map[int16]string{3: "lz4", 4: "zstd"}
+
+// ErrUnsupportedCodec is the Err of a Message standing in for a record
+// batch compressed with a codec the client cannot decode.
+var ErrUnsupportedCodec = errors.New("kafka: unsupported compression codec")
+
+// xerialHeader starts snappy batches written by the Java client, which
+// frames the data as length-prefixed snappy blocks.
+var xerialHeader = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+// recordBatchHeaderSize is the size of a v2 record batch up to and
+// including the record count.
+const recordBatchHeaderSize = 61
+
+// maxDecompressed bounds the size of a decompressed record batch.
+const maxDecompressed = 64 << 20
+
+// Message is one Kafka record.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Time      time.Time
+	// Err is set, and Key and Value are not, on a Message standing in for
+	// a whole record batch that could not be decoded. Its Offset is the
+	// batch's last, so consuming it moves past the batch.
+	Err error
+}
+
+// decodeRecords parses a fetch response's record set, returning the
+// messages at or after minOffset. A trailing partial batch, which brokers
+// send when a batch straddles the fetch size limit, is ignored.
+func decodeRecords(topic string, partition int32, data []byte, minOffset int64) ([]Message, error) {
+	var msgs []Message
+	for len(data) >= 17 {
+		d := decoder{b: data}
+		baseOffset := d.int64()
+		length := int(d.int32())
+		if length < 0 || 12+length > len(data) {
+			break
+		}
+		batch := data[12 : 12+length]
+		data = data[12+length:]
+
+		d = decoder{b: batch}
+		d.int32() // partition leader epoch
+		if magic := d.int8(); magic != 2 {
+			return nil, fmt.Errorf("kafka: unsupported record batch magic %d (need a broker on 0.11 or newer)", magic)
+		}
+		crc := uint32(d.int32())
+		if crc32.Checksum(d.b, castagnoli) != crc {
+			return nil, fmt.Errorf("kafka: record batch at offset %d failed its CRC check", baseOffset)
+		}
+		attrs := d.int16()
+		lastDelta := d.int32()
+		baseTime := d.int64()
+		d.int64() // max timestamp
+		d.int64() // producer id
+		d.int16() // producer epoch
+		d.int32() // base sequence
+		count := d.int32()
+		if d.err != nil {
+			return nil, d.err
+		}
+		if attrs&attrControl != 0 {
+			continue
+		}
+
+		body := d.b
+		switch attrs & codecMask {
+		case codecNone:
+		case codecSnappy:
+			var err error
+			if body, err = decodeSnappy(body); err != nil {
+				return nil, fmt.Errorf("kafka: record batch at offset %d: %w", baseOffset, err)
+			}
+		case codecGzip:
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, fmt.Errorf("kafka: record batch at offset %d: %w", baseOffset, err)
+			}
+			body, err = io.ReadAll(io.LimitReader(zr, maxDecompressed+1))
+			if err != nil {
+				return nil, fmt.Errorf("kafka: record batch at offset %d: %w", baseOffset, err)
+			}
+			if len(body) > maxDecompressed {
+				return nil, fmt.Errorf("kafka: record batch at offset %d exceeds %d bytes decompressed", baseOffset, maxDecompressed)
+			}
+		default:
+			last := baseOffset + int64(lastDelta)
+			if last < minOffset {
+				continue
+			}
+			name, ok := codecNames[attrs&codecMask]
+			if !ok {
+				name = fmt.Sprintf("codec %d", attrs&codecMask)
+			}
+			msgs = append(msgs, Message{
+				Topic:     topic,
+				Partition: partition,
+				Offset:    last,
+				Time:      time.UnixMilli(baseTime).UTC(),
+				Err:       fmt.Errorf("%w: record batch at offsets %d-%d uses %s; only none, gzip, and snappy are supported", ErrUnsupportedCodec, baseOffset, last, name),
+			})
+			continue
+		}
+
+		rd := decoder{b: body}
+		for i := int32(0); i < count; i++ {
+			size := rd.varint()
+			rec := decoder{b: rd.take(int(size))}
+			if rd.err != nil {
+				return nil, fmt.Errorf("kafka: record batch at offset %d: %w", baseOffset, rd.err)
+			}
+			rec.int8() // attributes
+			tsDelta := rec.varint()
+			offDelta := rec.varint()
+			key := rec.varbytes()
+			value := rec.varbytes()
+			for h := rec.varint(); h > 0 && rec.err == nil; h-- {
+				rec.varbytes()
+				rec.varbytes()
+			}
+			if rec.err != nil {
+				return nil, fmt.Errorf("kafka: record batch at offset %d: %w", baseOffset, rec.err)
+			}
+			offset := baseOffset + offDelta
+			if offset < minOffset {
+				continue
+			}
+			msgs = append(msgs, Message{
+				Topic:     topic,
+				Partition: partition,
+				Offset:    offset,
+				Key:       key,
+				Value:     value,
+				Time:      time.UnixMilli(baseTime + tsDelta).UTC(),
+			})
+		}
+	}
+	return msgs, nil
+}
+
+// decodeSnappy decodes a snappy batch body, either a bare snappy block or
+// the Java client's xerial framing.
+func decodeSnappy(body []byte) ([]byte, error) {
+	if !bytes.HasPrefix(body, xerialHeader) {
+		return snappy.Decode(body, maxDecompressed)
+	}
+	// The header is followed by a version and a compatible version.
+	d := decoder{b: body[len(xerialHeader):]}
+	d.int32()
+	d.int32()
+	var out []byte
+	for len(d.b) > 0 && d.err == nil {
+		block := d.take(int(d.int32()))
+		if d.err != nil {
+			break
+		}
+		chunk, err := snappy.Decode(block, maxDecompressed-len(out))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("snappy: truncated xerial frame: %w", d.err)
+	}
+	return out, nil
+}
+
+// encodeRecords builds an uncompressed v2 record batch holding msgs. Only
+// Key, Value, and Time are used.
+func encodeRecords(msgs []Message) ([]byte, error) {
+	if len(msgs) == 0 {
+		return nil, errors.New("kafka: empty record batch")
+	}
+	base := msgs[0].Time
+	if base.IsZero() {
+		base = time.Now()
+	}
+	baseMs := base.UnixMilli()
+	maxMs := baseMs
+
+	var recs encoder
+	for i, m := range msgs {
+		ts := baseMs
+		if !m.Time.IsZero() {
+			ts = m.Time.UnixMilli()
+		}
+		maxMs = max(maxMs, ts)
+		var r encoder
+		r.int8(0)
+		r.varint(ts - baseMs)
+		r.varint(int64(i))
+		r.varbytes(m.Key)
+		r.varbytes(m.Value)
+		r.varint(0)
+		recs.varint(int64(len(r.b)))
+		recs.b = append(recs.b, r.b...)
+	}
+
+	// The CRC covers everything from the attributes to the end.
+	var tail encoder
+	tail.int16(0)                    // attributes
+	tail.int32(int32(len(msgs) - 1)) // last offset delta
+	tail.int64(baseMs)
+	tail.int64(maxMs)
+	tail.int64(-1) // producer id
+	tail.int16(-1) // producer epoch
+	tail.int32(-1) // base sequence
+	tail.int32(int32(len(msgs)))
+	tail.b = append(tail.b, recs.b...)
+
+	var e encoder
+	e.int64(0)
+	e.int32(int32(4 + 1 + 4 + len(tail.b)))
+	e.int32(-1) // partition leader epoch
+	e.int8(2)
+	e.int32(int32(crc32.Checksum(tail.b, castagnoli)))
+	e.b = append(e.b, tail.b...)
+	return e.b, nil
+}
@@ -0,0 +1,481 @@
+//go:build keyless
+
+// Package keyless implements Sigstore-style keyless signing of Helios
+// attestations: an ephemeral key is certified by Fulcio against an OIDC
+// identity token, used once to sign the DSSE envelope, and the signature is
+// recorded in the Rekor transparency log. No long-lived private key exists.
+//
+// The package is only built with the "keyless" build tag, so the default
+// binary makes no network calls to Sigstore services.
+package keyless
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// Default public-good Sigstore instances.
+const (
+	DefaultFulcioURL = "https://fulcio.sigstore.dev"
+	DefaultRekorURL  = "https://rekor.sigstore.dev"
+)
+
+// Fulcio certificate extensions carrying the OIDC issuer.
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Config selects the Sigstore services and identity token.
+type Config struct {
+	FulcioURL string
+	RekorURL  string
+	// IDToken is the OIDC identity token. If empty, it is read from
+	// SIGSTORE_ID_TOKEN or requested from GitHub Actions.
+	IDToken string
+	Client  *http.Client
+}
+
+// TlogEntry identifies the Rekor entry recording a signature. Body is the
+// base64 canonicalized entry and SignedEntryTimestamp is Rekor's signature
+// over the entry and its integrated time, which Verify checks against the
+// Rekor public key before trusting IntegratedTime.
+type TlogEntry struct {
+	UUID                 string `json:"uuid"`
+	LogIndex             int64  `json:"log_index"`
+	LogID                string `json:"log_id"`
+	IntegratedTime       int64  `json:"integrated_time"`
+	Body                 string `json:"body"`
+	SignedEntryTimestamp string `json:"signed_entry_timestamp"`
+}
+
+// Result is a keyless-signed attestation: the DSSE envelope, the Fulcio
+// certificate chain (leaf first, PEM), and the transparency log entry.
+type Result struct {
+	Envelope         *attest.Envelope `json:"envelope"`
+	CertificateChain []string         `json:"certificate_chain"`
+	TlogEntry        *TlogEntry       `json:"tlog_entry"`
+}
+
+// Sign certifies an ephemeral key with Fulcio, signs st, and uploads the
+// envelope to Rekor.
+func Sign(cfg Config, st *attest.Statement) (*Result, error) {
+	cfg = withDefaults(cfg)
+
+	token := cfg.IDToken
+	if token == "" {
+		var err error
+		if token, err = ambientToken(cfg.Client); err != nil {
+			return nil, err
+		}
+	}
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	signer, err := signing.NewSigner(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := requestCertificate(cfg, token, subject, signer)
+	if err != nil {
+		return nil, err
+	}
+	env, err := attest.Sign(st, signer)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := uploadEntry(cfg, env, chain[0])
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Envelope: env, CertificateChain: chain, TlogEntry: entry}, nil
+}
+
+// Verify checks the transparency log entry against rekorKey, the
+// certificate chain against roots at the logged time, requires the leaf to
+// name identity (email or URI SAN) and, if non-empty, the OIDC issuer,
+// then verifies the envelope signature with the certified key. A bundle
+// without a verifiable log entry is rejected: the short-lived certificate
+// says nothing about when the signature was made.
+func Verify(res *Result, roots *x509.CertPool, rekorKey crypto.PublicKey, identity, issuer string) (*attest.Statement, error) {
+	if res.Envelope == nil {
+		return nil, fmt.Errorf("missing envelope")
+	}
+	if len(res.CertificateChain) == 0 {
+		return nil, fmt.Errorf("missing certificate chain")
+	}
+	certs := make([]*x509.Certificate, 0, len(res.CertificateChain))
+	for _, p := range res.CertificateChain {
+		block, _ := pem.Decode([]byte(p))
+		if block == nil {
+			return nil, fmt.Errorf("invalid certificate PEM")
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, c)
+	}
+	leaf := certs[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	// Fulcio certificates live for minutes; the signature is valid if it
+	// was logged while the certificate was valid.
+	at, err := verifyTlogEntry(res.TlogEntry, rekorKey, res.Envelope, leaf)
+	if err != nil {
+		return nil, err
+	}
+	if at.Before(leaf.NotBefore) || at.After(leaf.NotAfter) {
+		return nil, fmt.Errorf("log entry time %s is outside the certificate validity window", at.UTC().Format(time.RFC3339))
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, fmt.Errorf("certificate chain verification failed: %w", err)
+	}
+
+	if !hasIdentity(leaf, identity) {
+		return nil, fmt.Errorf("certificate does not name identity %q", identity)
+	}
+	if issuer != "" {
+		if got := certIssuer(leaf); got != issuer {
+			return nil, fmt.Errorf("certificate issuer %q does not match %q", got, issuer)
+		}
+	}
+	return attest.Verify(res.Envelope, leaf.PublicKey)
+}
+
+// verifyTlogEntry checks that e is signed by rekorKey and records env
+// signed by leaf, and returns the integrated time Rekor vouched for.
+func verifyTlogEntry(e *TlogEntry, rekorKey crypto.PublicKey, env *attest.Envelope, leaf *x509.Certificate) (time.Time, error) {
+	if e == nil {
+		return time.Time{}, fmt.Errorf("missing transparency log entry")
+	}
+	if rekorKey == nil {
+		return time.Time{}, fmt.Errorf("a Rekor public key is required to verify the log entry")
+	}
+	logID, err := signing.KeyID(rekorKey)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if e.LogID != logID {
+		return time.Time{}, fmt.Errorf("log entry is from log %s, not the trusted Rekor key %s", e.LogID, logID)
+	}
+	if e.SignedEntryTimestamp == "" {
+		return time.Time{}, fmt.Errorf("log entry has no signed entry timestamp")
+	}
+	set, err := base64.StdEncoding.DecodeString(e.SignedEntryTimestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp: %w", err)
+	}
+	// Rekor signs the canonical JSON of these fields; encoding/json sorts
+	// map keys, and none of the values need escaping.
+	payload, err := json.Marshal(map[string]interface{}{
+		"body":           e.Body,
+		"integratedTime": e.IntegratedTime,
+		"logID":          e.LogID,
+		"logIndex":       e.LogIndex,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := signing.Verify(rekorKey, payload, set); err != nil {
+		return time.Time{}, fmt.Errorf("signed entry timestamp verification failed: %w", err)
+	}
+	if err := checkEntryBody(e.Body, env, leaf); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(e.IntegratedTime, 0), nil
+}
+
+// checkEntryBody requires the logged dsse entry to cover env's payload and
+// name leaf as a verifier, so a signed entry for some other envelope cannot
+// be attached to this one.
+func checkEntryBody(body string, env *attest.Envelope, leaf *x509.Certificate) error {
+	raw, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return fmt.Errorf("invalid log entry body: %w", err)
+	}
+	var entry struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			PayloadHash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"payloadHash"`
+			Signatures []struct {
+				Verifier string `json:"verifier"`
+			} `json:"signatures"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return fmt.Errorf("invalid log entry body: %w", err)
+	}
+	if entry.Kind != "dsse" {
+		return fmt.Errorf("log entry kind is %q, not dsse", entry.Kind)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid envelope payload: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if entry.Spec.PayloadHash.Algorithm != "sha256" || entry.Spec.PayloadHash.Value != hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("log entry does not record this envelope")
+	}
+	for _, s := range entry.Spec.Signatures {
+		p, err := base64.StdEncoding.DecodeString(s.Verifier)
+		if err != nil {
+			continue
+		}
+		if block, _ := pem.Decode(p); block != nil && bytes.Equal(block.Bytes, leaf.Raw) {
+			return nil
+		}
+	}
+	return fmt.Errorf("log entry does not name the signing certificate")
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.FulcioURL == "" {
+		cfg.FulcioURL = DefaultFulcioURL
+	}
+	if cfg.RekorURL == "" {
+		cfg.RekorURL = DefaultRekorURL
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return cfg
+}
+
+// ambientToken finds an OIDC token in the environment.
+func ambientToken(client *http.Client) (string, error) {
+	if tok := os.Getenv("SIGSTORE_ID_TOKEN"); tok != "" {
+		return tok, nil
+	}
+	url, reqTok := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if url == "" || reqTok == "" {
+		return "", fmt.Errorf("no OIDC token: set SIGSTORE_ID_TOKEN or run in GitHub Actions with id-token: write")
+	}
+	req, err := http.NewRequest(http.MethodGet, url+"&audience=sigstore", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "bearer "+reqTok)
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := doJSON(client, req, http.StatusOK, &out); err != nil {
+		return "", fmt.Errorf("GitHub Actions token request failed: %w", err)
+	}
+	return out.Value, nil
+}
+
+// tokenSubject extracts the identity Fulcio expects proof of possession
+// over: the email claim if present, otherwise sub. The token signature is
+// checked by Fulcio, not here.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid identity token payload: %w", err)
+	}
+	var claims struct {
+		Sub   string `json:"sub"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("invalid identity token claims: %w", err)
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Sub == "" {
+		return "", fmt.Errorf("identity token has no sub claim")
+	}
+	return claims.Sub, nil
+}
+
+func requestCertificate(cfg Config, token, subject string, signer signing.Signer) ([]string, error) {
+	pubPEM, err := signing.EncodePublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	proof, err := signer.Sign([]byte(subject))
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"credentials": map[string]string{"oidcIdentityToken": token},
+		"publicKeyRequest": map[string]interface{}{
+			"publicKey":         map[string]string{"algorithm": "ECDSA", "content": string(pubPEM)},
+			"proofOfPossession": base64.StdEncoding.EncodeToString(proof),
+		},
+	}
+	req, err := newJSONRequest(cfg.FulcioURL+"/api/v2/signingCert", body)
+	if err != nil {
+		return nil, err
+	}
+
+	type chain struct {
+		Chain struct {
+			Certificates []string `json:"certificates"`
+		} `json:"chain"`
+	}
+	var out struct {
+		Embedded *chain `json:"signedCertificateEmbeddedSct"`
+		Detached *chain `json:"signedCertificateDetachedSct"`
+	}
+	if err := doJSON(cfg.Client, req, http.StatusCreated, &out); err != nil {
+		return nil, fmt.Errorf("Fulcio certificate request failed: %w", err)
+	}
+	var certs []string
+	switch {
+	case out.Embedded != nil:
+		certs = out.Embedded.Chain.Certificates
+	case out.Detached != nil:
+		certs = out.Detached.Chain.Certificates
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("Fulcio returned no certificates")
+	}
+	return certs, nil
+}
+
+func uploadEntry(cfg Config, env *attest.Envelope, leafPEM string) (*TlogEntry, error) {
+	envJSON, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]interface{}{
+			"proposedContent": map[string]interface{}{
+				"envelope":  string(envJSON),
+				"verifiers": []string{base64.StdEncoding.EncodeToString([]byte(leafPEM))},
+			},
+		},
+	}
+	req, err := newJSONRequest(cfg.RekorURL+"/api/v1/log/entries", body)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]struct {
+		Body           string `json:"body"`
+		LogIndex       int64  `json:"logIndex"`
+		LogID          string `json:"logID"`
+		IntegratedTime int64  `json:"integratedTime"`
+		Verification   struct {
+			SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+		} `json:"verification"`
+	}
+	if err := doJSON(cfg.Client, req, http.StatusCreated, &out); err != nil {
+		return nil, fmt.Errorf("Rekor upload failed: %w", err)
+	}
+	for uuid, e := range out {
+		return &TlogEntry{
+			UUID:                 uuid,
+			LogIndex:             e.LogIndex,
+			LogID:                e.LogID,
+			IntegratedTime:       e.IntegratedTime,
+			Body:                 e.Body,
+			SignedEntryTimestamp: e.Verification.SignedEntryTimestamp,
+		}, nil
+	}
+	return nil, fmt.Errorf("Rekor returned no entry")
+}
+
+func newJSONRequest(url string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+func doJSON(client *http.Client, req *http.Request, want int, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
+
+func hasIdentity(cert *x509.Certificate, identity string) bool {
+	for _, e := range cert.EmailAddresses {
+		if e == identity {
+			return true
+		}
+	}
+	for _, u := range cert.URIs {
+		if u.String() == identity {
+			return true
+		}
+	}
+	return false
+}
+
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var s string
+			if _, err := asn1.Unmarshal(ext.Value, &s); err == nil {
+				return s
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
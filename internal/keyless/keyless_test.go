@@ -0,0 +1,311 @@
+//go:build keyless
+
+package keyless
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+const (
+	testIdentity = "ci@example.com"
+	testIssuer   = "https://token.actions.githubusercontent.com"
+)
+
+func testToken(email string) string {
+	enc := base64.RawURLEncoding
+	claims, _ := json.Marshal(map[string]string{"sub": "1234", "email": email})
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString(claims) + ".sig"
+}
+
+// fakeSigstore runs a minimal Fulcio and Rekor backed by a throwaway CA
+// and log key, and returns the trusted roots and Rekor public key.
+func fakeSigstore(t *testing.T) (*httptest.Server, *x509.CertPool, crypto.PublicKey) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logID, err := signing.KeyID(&logKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	issuerExt, _ := asn1.Marshal(testIssuer)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/signingCert", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			PublicKeyRequest struct {
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+				ProofOfPossession string `json:"proofOfPossession"`
+			} `json:"publicKeyRequest"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		block, _ := pem.Decode([]byte(req.PublicKeyRequest.PublicKey.Content))
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		proof, _ := base64.StdEncoding.DecodeString(req.PublicKeyRequest.ProofOfPossession)
+		if err := signing.Verify(pub, []byte(testIdentity), proof); err != nil {
+			http.Error(w, "bad proof of possession", http.StatusBadRequest)
+			return
+		}
+		leaf := &x509.Certificate{
+			SerialNumber:    big.NewInt(2),
+			NotBefore:       time.Now().Add(-time.Minute),
+			NotAfter:        time.Now().Add(10 * time.Minute),
+			EmailAddresses:  []string{testIdentity},
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuerExt}},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, leaf, caCert, pub, caKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		chain := []string{
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"signedCertificateEmbeddedSct": map[string]interface{}{
+				"chain": map[string]interface{}{"certificates": chain},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v1/log/entries", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Kind string `json:"kind"`
+			Spec struct {
+				ProposedContent struct {
+					Envelope  string   `json:"envelope"`
+					Verifiers []string `json:"verifiers"`
+				} `json:"proposedContent"`
+			} `json:"spec"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Kind != "dsse" {
+			http.Error(w, "expected dsse entry", http.StatusBadRequest)
+			return
+		}
+		var env attest.Envelope
+		if err := json.Unmarshal([]byte(req.Spec.ProposedContent.Envelope), &env); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload, _ := base64.StdEncoding.DecodeString(env.Payload)
+		sum := sha256.Sum256(payload)
+		var sigs []map[string]string
+		for _, v := range req.Spec.ProposedContent.Verifiers {
+			sigs = append(sigs, map[string]string{"verifier": v})
+		}
+		entry, _ := json.Marshal(map[string]interface{}{
+			"apiVersion": "0.0.1",
+			"kind":       "dsse",
+			"spec": map[string]interface{}{
+				"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(sum[:])},
+				"signatures":  sigs,
+			},
+		})
+		body := base64.StdEncoding.EncodeToString(entry)
+		now := time.Now().Unix()
+		set, _ := json.Marshal(map[string]interface{}{
+			"body": body, "integratedTime": now, "logID": logID, "logIndex": 7,
+		})
+		digest := sha256.Sum256(set)
+		sig, err := ecdsa.SignASN1(rand.Reader, logKey, digest[:])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"24296fb24b8ad77a": map[string]interface{}{
+				"body":           body,
+				"logIndex":       7,
+				"logID":          logID,
+				"integratedTime": now,
+				"verification": map[string]string{
+					"signedEntryTimestamp": base64.StdEncoding.EncodeToString(sig),
+				},
+			},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, roots, &logKey.PublicKey
+}
+
+func testStatement(t *testing.T) *attest.Statement {
+	t.Helper()
+	st, err := attest.NewStatement([]object.MemoryObject{{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           "ci/snapshot",
+		Relationships: []object.Relationship{},
+		Source:        "ci",
+		Value:         "signed without a key",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	srv, roots, rekorKey := fakeSigstore(t)
+	cfg := Config{FulcioURL: srv.URL, RekorURL: srv.URL, IDToken: testToken(testIdentity)}
+
+	res, err := Sign(cfg, testStatement(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TlogEntry == nil || res.TlogEntry.LogIndex != 7 {
+		t.Errorf("expected log index 7, got %+v", res.TlogEntry)
+	}
+	if len(res.CertificateChain) != 2 {
+		t.Errorf("expected leaf and root, got %d certificates", len(res.CertificateChain))
+	}
+
+	// Survives JSON round trip
+	data, _ := json.Marshal(res)
+	var decoded Result
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Verify(&decoded, roots, rekorKey, testIdentity, testIssuer)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if len(st.Subject) != 1 || st.Subject[0].Name != "ci/snapshot" {
+		t.Errorf("unexpected subjects: %+v", st.Subject)
+	}
+}
+
+func TestVerifyRejectsWrongIdentityAndIssuer(t *testing.T) {
+	srv, roots, rekorKey := fakeSigstore(t)
+	res, err := Sign(Config{FulcioURL: srv.URL, RekorURL: srv.URL, IDToken: testToken(testIdentity)}, testStatement(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(res, roots, rekorKey, "someone@example.com", ""); err == nil {
+		t.Error("expected identity mismatch")
+	}
+	if _, err := Verify(res, roots, rekorKey, testIdentity, "https://accounts.google.com"); err == nil {
+		t.Error("expected issuer mismatch")
+	}
+	if _, err := Verify(res, x509.NewCertPool(), rekorKey, testIdentity, ""); err == nil {
+		t.Error("expected untrusted chain to fail")
+	}
+}
+
+func TestVerifyRequiresSignedLogEntry(t *testing.T) {
+	srv, roots, rekorKey := fakeSigstore(t)
+	cfg := Config{FulcioURL: srv.URL, RekorURL: srv.URL, IDToken: testToken(testIdentity)}
+	sign := func() *Result {
+		res, err := Sign(cfg, testStatement(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	cases := map[string]func(*Result) crypto.PublicKey{
+		"no entry": func(res *Result) crypto.PublicKey {
+			res.TlogEntry = nil
+			return rekorKey
+		},
+		"no Rekor key": func(*Result) crypto.PublicKey { return nil },
+		"untrusted log": func(*Result) crypto.PublicKey {
+			return &otherKey.PublicKey
+		},
+		"no signed entry timestamp": func(res *Result) crypto.PublicKey {
+			res.TlogEntry.SignedEntryTimestamp = ""
+			return rekorKey
+		},
+		"backdated time": func(res *Result) crypto.PublicKey {
+			res.TlogEntry.IntegratedTime -= 30
+			return rekorKey
+		},
+		"entry for another certificate": func(res *Result) crypto.PublicKey {
+			res.TlogEntry = sign().TlogEntry
+			return rekorKey
+		},
+	}
+	for name, tamper := range cases {
+		t.Run(name, func(t *testing.T) {
+			res := sign()
+			key := tamper(res)
+			if _, err := Verify(res, roots, key, testIdentity, testIssuer); err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestSignRequiresProofForTokenSubject(t *testing.T) {
+	srv, _, _ := fakeSigstore(t)
+	_, err := Sign(Config{FulcioURL: srv.URL, RekorURL: srv.URL, IDToken: testToken("other@example.com")}, testStatement(t))
+	if err == nil || !strings.Contains(err.Error(), "Fulcio") {
+		t.Errorf("expected Fulcio rejection, got %v", err)
+	}
+}
+
+func TestTokenSubject(t *testing.T) {
+	if _, err := tokenSubject("not-a-jwt"); err == nil {
+		t.Error("expected error for malformed token")
+	}
+	got, err := tokenSubject(testToken(testIdentity))
+	if err != nil || got != testIdentity {
+		t.Errorf("expected %s, got %q (%v)", testIdentity, got, err)
+	}
+}
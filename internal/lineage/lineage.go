@@ -0,0 +1,216 @@
+// Package lineage verifies the revision chains of memory objects. Each
+// revision of a key names the content hash of the revision it replaces
+// in its supersedes field, which is excluded from the content hash, so
+// the revisions of a key form a chain from its first revision, which
+// supersedes nothing, to its latest. Check walks such a chain and reports
+// where it forks or breaks.
+package lineage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// Kinds of Problem.
+const (
+	// ProblemRoot is a key with no first revision, or with more than
+	// one: several revisions that supersede nothing.
+	ProblemRoot = "root"
+	// ProblemFork is a revision superseded by more than one revision.
+	ProblemFork = "fork"
+	// ProblemGap is a revision that supersedes a hash no revision of the
+	// key has: a revision is missing, or supersedes is not a hash.
+	ProblemGap = "gap"
+	// ProblemUnreachable is a revision whose predecessor exists but which
+	// the chain from the first revision never reaches: the revisions
+	// supersede each other in a cycle.
+	ProblemUnreachable = "unreachable"
+	// ProblemOrder is a revision created before the revision it
+	// supersedes.
+	ProblemOrder = "order"
+)
+
+// Revision is one revision of a key.
+type Revision struct {
+	Origin     string `json:"origin"`
+	Hash       string `json:"hash"`
+	Supersedes string `json:"supersedes,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Problem is one break in a key's lineage found by Check.
+type Problem struct {
+	Kind   string `json:"kind"`
+	Origin string `json:"origin,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the result of Check.
+type Report struct {
+	Key string `json:"key"`
+	// Chain is the key's revisions from the first to the latest. It stops
+	// at the first fork, and is empty unless the key has exactly one
+	// first revision.
+	Chain []Revision `json:"chain"`
+	// Revisions counts the distinct revisions of the key.
+	Revisions int       `json:"revisions"`
+	Problems  []Problem `json:"problems"`
+}
+
+// OK reports whether the key's revisions form one unbroken chain.
+func (r Report) OK() bool { return len(r.Problems) == 0 }
+
+// Check verifies the lineage of key over the revisions of it among
+// records, hashing each to match it with the revisions that supersede
+// it. A revision that appears more than once, with the same content and
+// the same predecessor, counts once. It fails if no record has the key
+// or a revision cannot be hashed.
+//
+// Revisions are told apart by content hash, so a key that returns to
+// earlier content and is then revised again shows as a fork: the
+// revisions that follow each occurrence of the content supersede the
+// same hash.
+func Check(key string, records []ingest.Record) (Report, error) {
+	var revs []Revision
+	seen := make(map[Revision]bool)
+	for _, rec := range records {
+		if rec.Object.Key != key {
+			continue
+		}
+		h, err := hash.ContentHash(rec.Object)
+		if err != nil {
+			return Report{}, fmt.Errorf("%s: %w", rec.Origin, err)
+		}
+		rev := Revision{Origin: rec.Origin, Hash: h, Supersedes: rec.Object.Supersedes, CreatedAt: rec.Object.CreatedAt}
+		id := Revision{Hash: rev.Hash, Supersedes: rev.Supersedes}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		revs = append(revs, rev)
+	}
+	if len(revs) == 0 {
+		return Report{}, fmt.Errorf("no revisions of key %q", key)
+	}
+
+	report := Report{Key: key, Revisions: len(revs)}
+	hashes := make(map[string]bool, len(revs))
+	successors := make(map[string][]int)
+	var roots []int
+	for i, rev := range revs {
+		hashes[rev.Hash] = true
+		if rev.Supersedes == "" {
+			roots = append(roots, i)
+			continue
+		}
+		successors[rev.Supersedes] = append(successors[rev.Supersedes], i)
+	}
+
+	gaps := make(map[int]bool)
+	for i, rev := range revs {
+		if rev.Supersedes != "" && !hashes[rev.Supersedes] {
+			gaps[i] = true
+			report.Problems = append(report.Problems, Problem{Kind: ProblemGap, Origin: rev.Origin, Hash: rev.Hash,
+				Detail: fmt.Sprintf("supersedes %s, which is not a revision of the key", rev.Supersedes)})
+		}
+	}
+	if len(roots) != 1 {
+		detail := "no revision supersedes nothing"
+		if len(roots) > 1 {
+			detail = "first revisions at " + origins(revs, roots)
+		}
+		report.Problems = append(report.Problems, Problem{Kind: ProblemRoot, Detail: detail})
+	}
+
+	// Walk from every first revision and every revision after a gap,
+	// following every successor, to find what neither reaches.
+	reached := make(map[int]bool)
+	queue := append([]int(nil), roots...)
+	for i := range gaps {
+		queue = append(queue, i)
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		if reached[i] {
+			continue
+		}
+		reached[i] = true
+		queue = append(queue, successors[revs[i].Hash]...)
+	}
+	for i, rev := range revs {
+		if !reached[i] {
+			report.Problems = append(report.Problems, Problem{Kind: ProblemUnreachable, Origin: rev.Origin, Hash: rev.Hash,
+				Detail: fmt.Sprintf("supersedes %s but the chain from the first revision never reaches it", rev.Supersedes)})
+		}
+	}
+
+	// Every revision with more than one successor is a fork, whether or
+	// not the chain reaches it.
+	var forked []string
+	for h, next := range successors {
+		if len(next) > 1 {
+			forked = append(forked, h)
+		}
+	}
+	sort.Strings(forked)
+	for _, h := range forked {
+		report.Problems = append(report.Problems, Problem{Kind: ProblemFork, Hash: h,
+			Detail: "superseded by " + origins(revs, successors[h])})
+	}
+
+	for i, rev := range revs {
+		if rev.Supersedes == "" || gaps[i] {
+			continue
+		}
+		for _, p := range revs {
+			if p.Hash == rev.Supersedes && before(rev.CreatedAt, p.CreatedAt) {
+				report.Problems = append(report.Problems, Problem{Kind: ProblemOrder, Origin: rev.Origin, Hash: rev.Hash,
+					Detail: fmt.Sprintf("created at %s, before the revision it supersedes (%s, created at %s)", rev.CreatedAt, p.Origin, p.CreatedAt)})
+				break
+			}
+		}
+	}
+
+	if len(roots) == 1 {
+		visited := make(map[int]bool)
+		for i := roots[0]; ; {
+			visited[i] = true
+			report.Chain = append(report.Chain, revs[i])
+			var next []int
+			for _, j := range successors[revs[i].Hash] {
+				if !visited[j] {
+					next = append(next, j)
+				}
+			}
+			if len(next) != 1 {
+				break
+			}
+			i = next[0]
+		}
+	}
+	return report, nil
+}
+
+// origins lists the origins of the revisions at the given indexes.
+func origins(revs []Revision, idx []int) string {
+	list := make([]string, len(idx))
+	for i, j := range idx {
+		list[i] = revs[j].Origin
+	}
+	return strings.Join(list, ", ")
+}
+
+// before reports whether timestamp a is earlier than b. Timestamps that
+// do not parse are never earlier.
+func before(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339Nano, a)
+	tb, errB := time.Parse(time.RFC3339Nano, b)
+	return errA == nil && errB == nil && ta.Before(tb)
+}
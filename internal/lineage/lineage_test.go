@@ -0,0 +1,131 @@
+package lineage
+
+import (
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// revision returns a revision of key "k" with the given value, created at
+// minute min, superseding prev (nil for a first revision).
+func revision(t *testing.T, value string, min int, prev *ingest.Record) ingest.Record {
+	t.Helper()
+	obj := object.MemoryObject{
+		Category:      "note",
+		CreatedAt:     "2025-01-15T10:" + string(rune('0'+min/10)) + string(rune('0'+min%10)) + ":00.000Z",
+		Key:           "k",
+		Relationships: []object.Relationship{},
+		Source:        "test",
+		Value:         value,
+	}
+	if prev != nil {
+		obj.Supersedes = contentHash(t, *prev)
+	}
+	return ingest.Record{Origin: value, Object: obj}
+}
+
+func contentHash(t *testing.T, rec ingest.Record) string {
+	t.Helper()
+	h, err := hash.ContentHash(rec.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func kinds(r Report) map[string]int {
+	m := make(map[string]int)
+	for _, p := range r.Problems {
+		m[p.Kind]++
+	}
+	return m
+}
+
+func chain(r Report) []string {
+	var out []string
+	for _, rev := range r.Chain {
+		out = append(out, rev.Origin)
+	}
+	return out
+}
+
+func TestCheckLinearChain(t *testing.T) {
+	a := revision(t, "a", 1, nil)
+	b := revision(t, "b", 2, &a)
+	c := revision(t, "c", 3, &b)
+	other := ingest.Record{Origin: "other", Object: object.MemoryObject{Key: "other", CreatedAt: "2025-01-15T10:00:00.000Z"}}
+
+	// Order of the records does not matter, and duplicates count once.
+	r, err := Check("k", []ingest.Record{c, other, a, b, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.OK() {
+		t.Fatalf("unexpected problems: %+v", r.Problems)
+	}
+	if got := chain(r); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("chain %v, want [a b c]", got)
+	}
+	if r.Revisions != 3 {
+		t.Errorf("%d revisions, want 3", r.Revisions)
+	}
+	if r.Chain[2].Hash != contentHash(t, c) || r.Chain[2].Supersedes != contentHash(t, b) {
+		t.Errorf("latest revision %+v", r.Chain[2])
+	}
+}
+
+func TestCheckReportsProblems(t *testing.T) {
+	a := revision(t, "a", 1, nil)
+	b := revision(t, "b", 2, &a)
+	c := revision(t, "c", 3, &b)
+	fork := revision(t, "fork", 4, &a)
+	early := revision(t, "early", 0, &c)
+	root2 := revision(t, "root2", 1, nil)
+
+	// Cycle: x and y supersede each other.
+	x := revision(t, "x", 5, nil)
+	y := revision(t, "y", 5, &x)
+	x.Object.Supersedes = contentHash(t, y)
+
+	tests := []struct {
+		name    string
+		records []ingest.Record
+		want    map[string]int
+		chain   int
+	}{
+		{"gap", []ingest.Record{a, c}, map[string]int{ProblemGap: 1}, 1},
+		{"fork", []ingest.Record{a, b, fork}, map[string]int{ProblemFork: 1}, 1},
+		{"two roots", []ingest.Record{a, b, root2}, map[string]int{ProblemRoot: 1}, 0},
+		{"no root", []ingest.Record{b, c}, map[string]int{ProblemRoot: 1, ProblemGap: 1}, 0},
+		{"cycle", []ingest.Record{a, x, y}, map[string]int{ProblemUnreachable: 2}, 1},
+		{"order", []ingest.Record{a, b, c, early}, map[string]int{ProblemOrder: 1}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Check("k", tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := kinds(r)
+			if len(got) != len(tt.want) {
+				t.Fatalf("problems %+v, want kinds %v", r.Problems, tt.want)
+			}
+			for k, n := range tt.want {
+				if got[k] != n {
+					t.Errorf("%d %s problems, want %d: %+v", got[k], k, n, r.Problems)
+				}
+			}
+			if len(r.Chain) != tt.chain {
+				t.Errorf("chain %v, want %d revisions", chain(r), tt.chain)
+			}
+		})
+	}
+}
+
+func TestCheckUnknownKey(t *testing.T) {
+	if _, err := Check("missing", []ingest.Record{revision(t, "a", 1, nil)}); err == nil {
+		t.Error("a key without revisions was accepted")
+	}
+}
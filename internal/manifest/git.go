@@ -0,0 +1,115 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// stagedFile is one entry of the git index.
+type stagedFile struct {
+	Path  string
+	ID    string
+	Stage string
+}
+
+// stagedFiles lists the regular files under root in the index of the
+// repository at dir, as `git ls-files --stage` reports them. Paths are
+// slash-separated and relative to dir.
+func stagedFiles(dir, root string) ([]stagedFile, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--stage", "--", root)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var files []stagedFile
+	for _, rec := range strings.Split(string(out), "\x00") {
+		if rec == "" {
+			continue
+		}
+		// <mode> SP <object> SP <stage> TAB <path>
+		meta, p, ok := strings.Cut(rec, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("git ls-files: unexpected entry %q", rec)
+		}
+		if fields[0] != "100644" && fields[0] != "100755" {
+			continue // symlinks and submodules hold no object file
+		}
+		files = append(files, stagedFile{Path: p, ID: fields[1], Stage: fields[2]})
+	}
+	return files, nil
+}
+
+// readBlobs returns the contents of the git objects ids in the repository
+// at dir, in order, read with one `git cat-file --batch`.
+func readBlobs(dir string, ids []string) ([][]byte, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(strings.Join(ids, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	blobs, readErr := parseBatch(bufio.NewReader(stdout), ids)
+	if readErr != nil {
+		io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("git cat-file: %w", readErr)
+	}
+	return blobs, nil
+}
+
+// parseBatch reads the `git cat-file --batch` output for ids: for each, a
+// "<object> <type> <size>" line, size bytes of content, and a newline.
+func parseBatch(r *bufio.Reader, ids []string) ([][]byte, error) {
+	blobs := make([][]byte, len(ids))
+	for i, id := range ids {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		var oid, typ string
+		var size int
+		if _, err := fmt.Sscanf(header, "%s %s %d\n", &oid, &typ, &size); err != nil || oid != id || typ != "blob" {
+			return nil, fmt.Errorf("unexpected object %q for %s", strings.TrimSpace(header), id)
+		}
+		blobs[i] = make([]byte, size+1)
+		if _, err := io.ReadFull(r, blobs[i]); err != nil {
+			return nil, err
+		}
+		blobs[i] = blobs[i][:size]
+	}
+	return blobs, nil
+}
+
+// hidden reports whether p lies in a hidden directory below root.
+func hidden(root, p string) bool {
+	rel := strings.TrimPrefix(p, path.Clean(filepath.ToSlash(root))+"/")
+	for _, d := range strings.Split(path.Dir(rel), "/") {
+		if strings.HasPrefix(d, ".") && d != "." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+// Package manifest maintains a tracked list of content hashes for memory
+// object files kept in a source tree. Each line has the form
+// "<hash>  <path>", sorted by path, so diffs of the manifest are stable.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// Entry records the content hash of one memory object file.
+type Entry struct {
+	Path string
+	Hash string
+}
+
+// Failure records a file that could not be validated or hashed.
+type Failure struct {
+	Path string
+	Err  error
+}
+
+// Scan hashes every *.json file under root that is tracked in the git
+// repository at dir, reading the content staged in its index, so the
+// manifest matches what the next commit records: untracked files and
+// unstaged edits are left out. Files in hidden directories are skipped.
+// Paths in the result are slash-separated and relative to dir, which is
+// normally the repository root.
+func Scan(dir, root string) ([]Entry, []Failure, error) {
+	files, err := stagedFiles(dir, root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list files under %s: %w", root, err)
+	}
+
+	var entries []Entry
+	var failures []Failure
+	var staged []stagedFile
+	unmerged := make(map[string]bool)
+	for _, f := range files {
+		if path.Ext(f.Path) != ".json" || hidden(root, f.Path) {
+			continue
+		}
+		if f.Stage != "0" {
+			if !unmerged[f.Path] {
+				unmerged[f.Path] = true
+				failures = append(failures, Failure{Path: f.Path, Err: fmt.Errorf("unmerged file")})
+			}
+			continue
+		}
+		staged = append(staged, f)
+	}
+
+	ids := make([]string, len(staged))
+	for i, f := range staged {
+		ids[i] = f.ID
+	}
+	blobs, err := readBlobs(dir, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read files under %s: %w", root, err)
+	}
+	for i, f := range staged {
+		h, err := Hash(blobs[i])
+		if err != nil {
+			failures = append(failures, Failure{Path: f.Path, Err: err})
+			continue
+		}
+		entries = append(entries, Entry{Path: f.Path, Hash: h})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Path < failures[j].Path })
+	return entries, failures, nil
+}
+
+// HashFile validates a single memory object file and returns its content hash.
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return Hash(data)
+}
+
+// Hash validates the contents of a memory object file and returns its
+// content hash.
+func Hash(data []byte) (string, error) {
+	obj, err := ingest.ParseObject(data)
+	if err != nil {
+		return "", err
+	}
+	return hash.ContentHash(obj)
+}
+
+// Format renders entries in manifest form, one "<hash>  <path>" line each.
+func Format(entries []Entry) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "%s  %s\n", e.Hash, e.Path)
+	}
+	return buf.Bytes()
+}
+
+// Parse reads manifest lines produced by Format. Blank lines are ignored.
+func Parse(data []byte) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for sc.Scan() {
+		line++
+		text := sc.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		h, p, ok := strings.Cut(text, "  ")
+		if !ok || len(h) != 64 || p == "" {
+			return nil, fmt.Errorf("manifest line %d: malformed entry %q", line, text)
+		}
+		entries = append(entries, Entry{Path: p, Hash: h})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
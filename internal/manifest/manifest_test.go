@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validObject = `{
+  "category": "test",
+  "created_at": "2025-01-15T10:30:00.000Z",
+  "key": "test/manifest",
+  "relationships": [],
+  "source": "user",
+  "value": "hello"
+}`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestScanHashesAndReportsFailures(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	base := t.TempDir()
+	git(t, base, "init", "-q")
+	root := filepath.Join(base, "memories")
+	writeFile(t, filepath.Join(root, "b.json"), validObject)
+	writeFile(t, filepath.Join(root, "nested", "a.json"), validObject)
+	writeFile(t, filepath.Join(root, "bad.json"), `{"created_at": "2025-01-15T10:30:00.000Z", "value": 1.5}`)
+	writeFile(t, filepath.Join(root, ".hidden", "skip.json"), `not json`)
+	writeFile(t, filepath.Join(root, "notes.txt"), `ignored`)
+	git(t, base, "add", "memories")
+	// Neither untracked files nor unstaged edits reach the manifest.
+	writeFile(t, filepath.Join(root, "untracked.json"), `not json`)
+	writeFile(t, filepath.Join(root, "b.json"), `not json`)
+
+	entries, failures, err := Scan(base, "memories")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Path != "memories/b.json" || entries[1].Path != "memories/nested/a.json" {
+		t.Errorf("entries not sorted by relative path: %+v", entries)
+	}
+	if entries[0].Hash != entries[1].Hash {
+		t.Error("identical objects should have identical hashes")
+	}
+	if len(failures) != 1 || failures[0].Path != "memories/bad.json" {
+		t.Fatalf("expected bad.json failure, got %+v", failures)
+	}
+	if !strings.Contains(failures[0].Err.Error(), "CANON_ERR_FLOAT_PROHIBITED") {
+		t.Errorf("expected float rejection, got %v", failures[0].Err)
+	}
+
+	if _, _, err := Scan(t.TempDir(), "memories"); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Path: "memories/a.json", Hash: strings.Repeat("a", 64)},
+		{Path: "memories/b c.json", Hash: strings.Repeat("b", 64)},
+	}
+	parsed, err := Parse(Format(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 2 || parsed[0] != entries[0] || parsed[1] != entries[1] {
+		t.Errorf("round trip mismatch: %+v", parsed)
+	}
+}
+
+func TestParseRejectsMalformedLine(t *testing.T) {
+	if _, err := Parse([]byte("deadbeef memories/a.json\n")); err == nil {
+		t.Error("expected error for malformed manifest line")
+	}
+}
@@ -0,0 +1,268 @@
+// Package merge reconciles divergent revisions of a memory object with a
+// structural three-way merge: each part of the object that only one
+// revision changed since their common base takes that change, and parts
+// both changed differently become conflicts.
+package merge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// ConflictKey is the member of a conflict marker: an object written in
+// place of a value both revisions changed, {"$conflict": {"base": ...,
+// "ours": ..., "theirs": ...}}, leaving out the revisions the value does
+// not exist in. The key has a reserved prefix, so a profile with
+// extensions rejects an object that still holds a marker until every
+// conflict is resolved.
+const ConflictKey = "$conflict"
+
+// Conflict is a value both revisions changed differently.
+type Conflict struct {
+	// Path locates the value in the object, e.g. "$.value.items[2]".
+	Path string `json:"path"`
+	// Sides holds the value in "base", "ours", and "theirs", without the
+	// revisions it does not exist in.
+	Sides map[string]interface{} `json:"sides"`
+}
+
+// Result is a merged object.
+type Result struct {
+	// Fields are the merged hash fields, in the form hash.HashFields
+	// returns, with a conflict marker in place of every conflict.
+	Fields    map[string]interface{}
+	Conflicts []Conflict
+	// Object and Hash are the merged object and its content hash, set
+	// when there are no conflicts. Object carries ours' excluded fields
+	// and supersedes ours.
+	Object object.MemoryObject
+	Hash   string
+}
+
+// Objects merges ours and theirs, two revisions derived from base. The
+// hashed fields are merged structurally: objects member by member,
+// arrays element by element when all three have the same length and
+// whole otherwise, and relationships as a set. Values are compared in
+// canonical form, so revisions that differ only in Unicode normalization
+// or key order do not conflict. A merge without conflicts is revalidated
+// and rehashed as a new object. It fails if the revisions are of
+// different keys or one cannot be hashed.
+func Objects(base, ours, theirs object.MemoryObject) (*Result, error) {
+	if ours.Key != base.Key || theirs.Key != base.Key {
+		return nil, fmt.Errorf("revisions of different keys: base %q, ours %q, theirs %q", base.Key, ours.Key, theirs.Key)
+	}
+	var fields [3]map[string]interface{}
+	for i, obj := range []object.MemoryObject{base, ours, theirs} {
+		f, err := hash.HashFields(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sides[i], err)
+		}
+		fields[i] = f
+	}
+
+	m := &merger{}
+	merged := make(map[string]interface{}, len(fields[1]))
+	for _, k := range union(fields[0], fields[1], fields[2]) {
+		path := "$" + member(k)
+		b, o, t := lookup(fields[0], k), lookup(fields[1], k), lookup(fields[2], k)
+		var v interface{}
+		if k == "relationships" {
+			v = m.set(path, b, o, t)
+		} else {
+			v = m.value(path, b, o, t)
+		}
+		if v != absent {
+			merged[k] = v
+		}
+	}
+	r := &Result{Fields: merged, Conflicts: m.conflicts}
+	if len(r.Conflicts) > 0 {
+		return r, nil
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := ingest.ParseObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("merged object is invalid: %w", err)
+	}
+	oursHash, err := hash.ContentHash(ours)
+	if err != nil {
+		return nil, fmt.Errorf("ours: %w", err)
+	}
+	obj.UpdatedAt = ours.UpdatedAt
+	obj.Version = ours.Version
+	obj.AccessCount = ours.AccessCount
+	obj.LastAccessed = ours.LastAccessed
+	obj.Confidence = ours.Confidence
+	obj.Tenant = ours.Tenant
+	obj.Supersedes = oursHash
+	if r.Hash, err = hash.ContentHash(obj); err != nil {
+		return nil, fmt.Errorf("merged object is invalid: %w", err)
+	}
+	r.Object = obj
+	return r, nil
+}
+
+var sides = [3]string{"base", "ours", "theirs"}
+
+// absent stands for a member that does not exist in a revision.
+var absent = &struct{}{}
+
+type merger struct {
+	conflicts []Conflict
+}
+
+// value merges one value of the three revisions, any of which may be
+// absent; it returns absent if the merged value does not exist.
+func (m *merger) value(path string, b, o, t interface{}) interface{} {
+	switch {
+	case equal(o, t):
+		return o
+	case equal(b, o):
+		return t
+	case equal(b, t):
+		return o
+	}
+
+	om, oIsMap := o.(map[string]interface{})
+	tm, tIsMap := t.(map[string]interface{})
+	if oIsMap && tIsMap {
+		bm, _ := b.(map[string]interface{})
+		merged := make(map[string]interface{})
+		for _, k := range union(bm, om, tm) {
+			if v := m.value(path+member(k), lookup(bm, k), lookup(om, k), lookup(tm, k)); v != absent {
+				merged[k] = v
+			}
+		}
+		return merged
+	}
+
+	oa, oIsArray := o.([]interface{})
+	ta, tIsArray := t.([]interface{})
+	ba, bIsArray := b.([]interface{})
+	if oIsArray && tIsArray && len(oa) == len(ta) && (b == absent || bIsArray && len(ba) == len(oa)) {
+		merged := make([]interface{}, len(oa))
+		for i := range oa {
+			be := interface{}(absent)
+			if ba != nil {
+				be = ba[i]
+			}
+			merged[i] = m.value(path+"["+strconv.Itoa(i)+"]", be, oa[i], ta[i])
+		}
+		return merged
+	}
+
+	return m.conflict(path, b, o, t)
+}
+
+// set merges arrays as sets of elements: an element is kept if both
+// revisions kept it, or one added it. Values that are not all arrays are
+// merged as values.
+func (m *merger) set(path string, b, o, t interface{}) interface{} {
+	ba, bOK := b.([]interface{})
+	oa, oOK := o.([]interface{})
+	ta, tOK := t.([]interface{})
+	if !bOK || !oOK || !tOK {
+		return m.value(path, b, o, t)
+	}
+	merged := []interface{}{}
+	for _, e := range oa {
+		if contains(ta, e) || !contains(ba, e) {
+			merged = append(merged, e)
+		}
+	}
+	for _, e := range ta {
+		if !contains(oa, e) && !contains(ba, e) {
+			merged = append(merged, e)
+		}
+	}
+	return merged
+}
+
+func (m *merger) conflict(path string, b, o, t interface{}) interface{} {
+	c := Conflict{Path: path, Sides: make(map[string]interface{}, 3)}
+	for i, v := range []interface{}{b, o, t} {
+		if v != absent {
+			c.Sides[sides[i]] = v
+		}
+	}
+	m.conflicts = append(m.conflicts, c)
+	return map[string]interface{}{ConflictKey: c.Sides}
+}
+
+// equal reports whether a and b, either of which may be absent, are the
+// same value in canonical form.
+func equal(a, b interface{}) bool {
+	if a == absent || b == absent {
+		return a == b
+	}
+	ca, errA := canon.CanonicalizeValue(a)
+	cb, errB := canon.CanonicalizeValue(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(ca, cb)
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, e := range list {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns m[k], or absent if m has no member k.
+func lookup(m map[string]interface{}, k string) interface{} {
+	if v, ok := m[k]; ok {
+		return v
+	}
+	return absent
+}
+
+// union returns the member names of maps, sorted, so conflicts are
+// reported in a stable order.
+func union(maps ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// member returns the path selector of member k, in the syntax
+// canon.ParsePath reads.
+func member(k string) string {
+	plain := k != ""
+	for _, r := range k {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return "." + k
+	}
+	quoted, _ := json.Marshal(k)
+	return "[" + string(quoted) + "]"
+}
@@ -0,0 +1,127 @@
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func parse(t *testing.T, doc string) object.MemoryObject {
+	t.Helper()
+	obj, err := ingest.ParseObject([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+const head = `"category":"note","created_at":"2025-01-15T10:30:00.000Z","key":"k","source":"agent",`
+
+func TestObjectsMergesIndependentChanges(t *testing.T) {
+	base := parse(t, `{`+head+`"relationships":[{"key":"a","type":"rel"},{"key":"b","type":"rel"}],"value":{"x":1,"y":1,"list":[1,2,3],"gone":true}}`)
+	ours := parse(t, `{`+head+`"updated_at":"2025-02-01T00:00:00.000Z","relationships":[{"key":"a","type":"rel"}],"value":{"x":2,"y":1,"list":[9,2,3],"gone":true}}`)
+	theirs := parse(t, `{`+head+`"relationships":[{"key":"a","type":"rel"},{"key":"b","type":"rel"},{"key":"c","type":"rel"}],"value":{"x":1,"y":3,"list":[1,2,8]}}`)
+
+	r, err := Objects(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", r.Conflicts)
+	}
+	want := parse(t, `{`+head+`"relationships":[{"key":"a","type":"rel"},{"key":"c","type":"rel"}],"value":{"x":2,"y":3,"list":[9,2,8]}}`)
+	wantHash, err := hash.ContentHash(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Hash != wantHash {
+		got, _ := json.Marshal(r.Fields)
+		t.Errorf("merged %s", got)
+	}
+	oursHash, _ := hash.ContentHash(ours)
+	if r.Object.Supersedes != oursHash || r.Object.UpdatedAt != ours.UpdatedAt {
+		t.Errorf("merged object should carry ours' excluded fields and supersede it: %+v", r.Object)
+	}
+}
+
+func TestObjectsReportsConflicts(t *testing.T) {
+	base := parse(t, `{`+head+`"relationships":[],"value":{"x":1,"y":1,"odd.key":1}}`)
+	ours := parse(t, `{`+head+`"relationships":[],"value":{"x":2,"odd.key":2}}`)
+	theirs := parse(t, `{`+head+`"relationships":[],"value":{"x":3,"y":2,"odd.key":2}}`)
+	ours.Category = "idea"
+	theirs.Category = "fact"
+
+	r, err := Objects(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Hash != "" {
+		t.Error("a merge with conflicts was hashed")
+	}
+	paths := map[string]map[string]interface{}{}
+	for _, c := range r.Conflicts {
+		paths[c.Path] = c.Sides
+	}
+	if len(paths) != 3 {
+		t.Fatalf("expected conflicts at $.category, $.value.x, and $.value.y, got %+v", r.Conflicts)
+	}
+	if s := paths["$.value.x"]; len(s) != 3 {
+		t.Errorf("$.value.x sides: %v", s)
+	}
+	if s := paths["$.value.y"]; len(s) != 2 || s["ours"] != nil {
+		t.Errorf("$.value.y was deleted by ours and changed by theirs: %v", s)
+	}
+	if _, ok := paths["$.category"]; !ok {
+		t.Errorf("no conflict for the category")
+	}
+
+	// The document with markers is rejected until resolved.
+	data, err := json.Marshal(r.Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext, err := hash.WithExtensions(hash.V1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := ingest.ParseObject(data)
+	if err == nil {
+		_, err = ext.ContentHash(obj)
+	}
+	if err == nil {
+		t.Error("an object with conflict markers was accepted")
+	}
+}
+
+func TestObjectsComparesCanonically(t *testing.T) {
+	base := parse(t, `{`+head+`"relationships":[],"value":{"s":"café","n":1}}`)
+	ours := parse(t, `{`+head+`"relationships":[],"value":{"s":"café","n":1}}`)
+	theirs := parse(t, `{`+head+`"relationships":[],"value":{"s":"café","n":2}}`)
+	r, err := Objects(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Conflicts) != 0 {
+		t.Errorf("normalization-only change conflicted: %+v", r.Conflicts)
+	}
+}
+
+func TestObjectsRejectsDifferentKeys(t *testing.T) {
+	a := parse(t, `{`+head+`"relationships":[],"value":1}`)
+	b := a
+	b.Key = "other"
+	if _, err := Objects(a, a, b); err == nil {
+		t.Error("revisions of different keys were merged")
+	}
+}
+
+func TestMember(t *testing.T) {
+	for k, want := range map[string]string{"plain_1": ".plain_1", "odd.key": `["odd.key"]`, "": `[""]`, "café": "[\"café\"]"} {
+		if got := member(k); got != want {
+			t.Errorf("member(%q) = %s, want %s", k, got, want)
+		}
+	}
+}
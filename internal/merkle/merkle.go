@@ -0,0 +1,118 @@
+// Package merkle computes Merkle tree hashes as defined by RFC 6962
+// (Certificate Transparency), section 2.1: leaves and interior nodes are
+// hashed with distinct prefixes so neither can be passed off as the other,
+// and a tree of n leaves splits at the largest power of two below n.
+// Following the RFC keeps Helios roots checkable with existing tooling.
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidProof is returned by VerifyInclusion for a proof that does not
+// lead to the root.
+var ErrInvalidProof = errors.New("merkle: proof does not match the root")
+
+// Hash is a node of a tree.
+type Hash = [sha256.Size]byte
+
+// LeafHash returns the hash of a leaf holding data.
+func LeafHash(data []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
+
+// NodeHash returns the hash of an interior node with children l and r.
+func NodeHash(l, r Hash) Hash {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(l[:])
+	h.Write(r[:])
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
+
+// Root returns the root of the tree whose leaf hashes are leaves, in
+// order. The root of an empty tree is the hash of no data.
+func Root(leaves []Hash) Hash {
+	if len(leaves) == 0 {
+		return sha256.Sum256(nil)
+	}
+	return root(leaves)
+}
+
+func root(leaves []Hash) Hash {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return NodeHash(root(leaves[:k]), root(leaves[k:]))
+}
+
+// split returns the largest power of two less than n, for n > 1.
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// InclusionProof returns the audit path of leaf index in the tree whose
+// leaf hashes are leaves (RFC 6962, section 2.1.1): the sibling hashes
+// from the leaf up to the root.
+func InclusionProof(leaves []Hash, index int) ([]Hash, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("merkle: leaf %d out of range for a tree of %d", index, len(leaves))
+	}
+	return path(index, leaves), nil
+}
+
+func path(m int, leaves []Hash) []Hash {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := split(len(leaves))
+	if m < k {
+		return append(path(m, leaves[:k]), root(leaves[k:]))
+	}
+	return append(path(m-k, leaves[k:]), root(leaves[:k]))
+}
+
+// VerifyInclusion checks that proof shows leaf is leaf index of a tree of
+// size leaves with the given root, as described in RFC 9162, section
+// 2.1.3.2.
+func VerifyInclusion(root, leaf Hash, index, size int, proof []Hash) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("%w: leaf %d out of range for a tree of %d", ErrInvalidProof, index, size)
+	}
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrInvalidProof
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || r != root {
+		return ErrInvalidProof
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestLeafHash(t *testing.T) {
+	// The RFC 6962 hash of an empty leaf.
+	got := LeafHash(nil)
+	if hex.EncodeToString(got[:]) != "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d" {
+		t.Errorf("LeafHash(nil) = %x", got)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	if got := Root(nil); got != sha256.Sum256(nil) {
+		t.Errorf("empty root = %x", got)
+	}
+	var l [5]Hash
+	for i := range l {
+		l[i] = LeafHash([]byte{byte(i)})
+	}
+	for n, want := range map[int]Hash{
+		1: l[0],
+		2: NodeHash(l[0], l[1]),
+		3: NodeHash(NodeHash(l[0], l[1]), l[2]),
+		5: NodeHash(NodeHash(NodeHash(l[0], l[1]), NodeHash(l[2], l[3])), l[4]),
+	} {
+		if got := Root(l[:n]); got != want {
+			t.Errorf("Root of %d leaves = %x, want %x", n, got, want)
+		}
+	}
+}
+
+func TestInclusionProof(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := make([]Hash, n)
+		for i := range leaves {
+			leaves[i] = LeafHash([]byte{byte(i)})
+		}
+		root := Root(leaves)
+		for i := range leaves {
+			proof, err := InclusionProof(leaves, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyInclusion(root, leaves[i], i, n, proof); err != nil {
+				t.Errorf("leaf %d of %d: %v", i, n, err)
+			}
+			if n > 1 {
+				if err := VerifyInclusion(root, leaves[(i+1)%n], i, n, proof); !errors.Is(err, ErrInvalidProof) {
+					t.Errorf("leaf %d of %d verified with another leaf", i, n)
+				}
+				if err := VerifyInclusion(root, leaves[i], n, n, proof); !errors.Is(err, ErrInvalidProof) {
+					t.Errorf("leaf %d of %d verified at an index out of range", i, n)
+				}
+			}
+		}
+	}
+	if _, err := InclusionProof(nil, 0); err == nil {
+		t.Error("proof of a leaf of an empty tree")
+	}
+}
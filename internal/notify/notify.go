@@ -0,0 +1,227 @@
+// Package notify delivers integrity-violation events, such as a content
+// hash that no longer matches its recorded value, to webhooks and local
+// commands so mismatches page someone instead of sitting in logs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Event kinds.
+const (
+	// KindHashMismatch: an object's recomputed content hash differs from
+	// the recorded one.
+	KindHashMismatch = "hash_mismatch"
+	// KindVectorMismatch: a conformance vector produced a different hash
+	// or outcome than expected.
+	KindVectorMismatch = "vector_mismatch"
+	// KindAnomaly: a monitored statistic, such as a category's rate of
+	// rejected writes, crossed its alert threshold. Key is the category,
+	// Path the rule, Expected the threshold and Actual the value.
+	KindAnomaly = "anomaly"
+	// KindStoreDamage: a store check found a damaged object or index
+	// entry that is not a hash mismatch, such as a key pointing at a
+	// missing object. Key is the key, if any, Path the damaged file,
+	// Expected the kind of problem and Actual what was found.
+	KindStoreDamage = "store_damage"
+)
+
+// Event describes one verification mismatch.
+type Event struct {
+	Kind string `json:"kind"`
+	// Source names the check that found the mismatch, e.g.
+	// "verify-bundle release.tar.gz".
+	Source   string `json:"source"`
+	Key      string `json:"key"`
+	Path     string `json:"path,omitempty"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Time     string `json:"time"`
+	Host     string `json:"host,omitempty"`
+}
+
+// Payload is the JSON body posted to webhooks.
+type Payload struct {
+	Events []Event `json:"events"`
+}
+
+// Hook receives a batch of events.
+type Hook interface {
+	Notify(ctx context.Context, events []Event) error
+}
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body,
+// prefixed "sha256=", when a Webhook has a Secret.
+const SignatureHeader = "X-Helios-Signature"
+
+// Webhook posts a Payload to URL. Network errors and 5xx responses are
+// retried up to Attempts times with exponential backoff.
+type Webhook struct {
+	URL    string
+	Secret []byte
+	Client *http.Client
+	// Attempts defaults to 3.
+	Attempts int
+}
+
+// Notify implements Hook.
+func (w *Webhook) Notify(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(Payload{Events: events})
+	if err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	attempts := w.Attempts
+	if attempts < 1 {
+		attempts = 3
+	}
+
+	backoff := 500 * time.Millisecond
+	for i := 1; ; i++ {
+		err = w.post(ctx, client, body)
+		var perm *permanentError
+		if err == nil || errors.As(err, &perm) || i == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", w.URL, err)
+	}
+	return nil
+}
+
+type permanentError struct{ status string }
+
+func (e *permanentError) Error() string { return "rejected with " + e.status }
+
+func (w *Webhook) post(ctx context.Context, client *http.Client, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{status: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "helios-notify")
+	if len(w.Secret) > 0 {
+		mac := hmac.New(sha256.New, w.Secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("server error %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return &permanentError{status: resp.Status}
+	}
+	return nil
+}
+
+// Exec runs Command once per event via `sh -c`, with the event as JSON on
+// stdin and its fields in HELIOS_EVENT_KIND, HELIOS_EVENT_SOURCE,
+// HELIOS_EVENT_KEY, HELIOS_EVENT_PATH, HELIOS_EVENT_EXPECTED, and
+// HELIOS_EVENT_ACTUAL.
+type Exec struct {
+	Command string
+	// Stdout and Stderr receive the command's output; nil discards it.
+	Stdout, Stderr io.Writer
+}
+
+// Notify implements Hook.
+func (x *Exec) Notify(ctx context.Context, events []Event) error {
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, "sh", "-c", x.Command)
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout = x.Stdout
+		cmd.Stderr = x.Stderr
+		cmd.Env = append(os.Environ(),
+			"HELIOS_EVENT_KIND="+e.Kind,
+			"HELIOS_EVENT_SOURCE="+e.Source,
+			"HELIOS_EVENT_KEY="+e.Key,
+			"HELIOS_EVENT_PATH="+e.Path,
+			"HELIOS_EVENT_EXPECTED="+e.Expected,
+			"HELIOS_EVENT_ACTUAL="+e.Actual,
+		)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("exec hook %q: %w", x.Command, err)
+		}
+	}
+	return nil
+}
+
+// Notifier fans events out to every hook.
+type Notifier struct {
+	Hooks []Hook
+	// Timeout bounds a whole Fire call. Default 60s.
+	Timeout time.Duration
+
+	now func() time.Time
+}
+
+// Fire stamps events with the current time and host and delivers them to
+// every hook, even when an earlier hook fails. It returns the joined
+// hook errors.
+func (n *Notifier) Fire(events []Event) error {
+	if n == nil || len(n.Hooks) == 0 || len(events) == 0 {
+		return nil
+	}
+	now := time.Now
+	if n.now != nil {
+		now = n.now
+	}
+	host, _ := os.Hostname()
+	stamp := now().UTC().Format("2006-01-02T15:04:05.000Z")
+	stamped := make([]Event, len(events))
+	for i, e := range events {
+		if e.Time == "" {
+			e.Time = stamp
+		}
+		if e.Host == "" {
+			e.Host = host
+		}
+		stamped[i] = e
+	}
+
+	timeout := n.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var errs []error
+	for _, h := range n.Hooks {
+		if err := h.Notify(ctx, stamped); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testEvent = Event{
+	Kind:     KindHashMismatch,
+	Source:   "verify-bundle b.tar.gz",
+	Key:      "my/object",
+	Path:     "objects/000000.json",
+	Expected: strings.Repeat("a", 64),
+	Actual:   strings.Repeat("b", 64),
+}
+
+func TestWebhookSignsPayload(t *testing.T) {
+	secret := []byte("s3cret")
+	var got Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get(SignatureHeader) != want {
+			t.Errorf("signature %q, want %q", r.Header.Get(SignatureHeader), want)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	n := &Notifier{
+		Hooks: []Hook{&Webhook{URL: srv.URL, Secret: secret}},
+		now:   func() time.Time { return time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC) },
+	}
+	if err := n.Fire([]Event{testEvent}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 1 {
+		t.Fatalf("got %d events", len(got.Events))
+	}
+	e := got.Events[0]
+	if e.Key != testEvent.Key || e.Expected != testEvent.Expected || e.Actual != testEvent.Actual {
+		t.Errorf("event %+v", e)
+	}
+	if e.Time != "2025-01-15T10:30:00.000Z" {
+		t.Errorf("time %q", e.Time)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	if err := (&Webhook{URL: srv.URL}).Notify(context.Background(), []Event{testEvent}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("got %d calls, want 2", calls.Load())
+	}
+
+	calls.Store(0)
+	reject := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer reject.Close()
+	if err := (&Webhook{URL: reject.URL}).Notify(context.Background(), []Event{testEvent}); err == nil {
+		t.Error("401 was not reported")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("4xx retried: %d calls", calls.Load())
+	}
+}
+
+func TestExecHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	x := &Exec{Command: `printf '%s %s ' "$HELIOS_EVENT_KEY" "$HELIOS_EVENT_ACTUAL" >> "$OUT"; cat >> "$OUT"; echo >> "$OUT"`}
+	t.Setenv("OUT", out)
+
+	second := testEvent
+	second.Key = "other"
+	if err := x.Notify(context.Background(), []Event{testEvent, second}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d runs, want 2:\n%s", len(lines), data)
+	}
+	if !strings.HasPrefix(lines[0], "my/object "+testEvent.Actual+" {") || !strings.HasPrefix(lines[1], "other ") {
+		t.Errorf("unexpected output:\n%s", data)
+	}
+}
+
+func TestFireReportsEveryHook(t *testing.T) {
+	ok := filepath.Join(t.TempDir(), "ok")
+	n := &Notifier{Hooks: []Hook{
+		&Exec{Command: "exit 3"},
+		&Exec{Command: "touch " + ok},
+	}}
+	err := n.Fire([]Event{testEvent})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("Fire error %v", err)
+	}
+	if _, err := os.Stat(ok); err != nil {
+		t.Error("a failing hook stopped later hooks")
+	}
+	if err := n.Fire(nil); err != nil {
+		t.Errorf("Fire with no events: %v", err)
+	}
+}
@@ -1,29 +1,75 @@
 // Package object defines the memory object types for Helios Core.
 package object
 
+// Schema versions of memory objects, the values of _helios_schema_version.
+const (
+	SchemaV1 = "1"
+	// SchemaV2 adds optional attributes to relationships.
+	SchemaV2 = "2"
+)
+
 // Relationship represents a typed link between memory objects.
 type Relationship struct {
 	Key  string `json:"key"`
 	Type string `json:"type"`
+	// Weight and Note are optional attributes, allowed only in objects of
+	// schema version 2; nil means absent.
+	Weight *int64  `json:"weight,omitempty" schema:"optional"`
+	Note   *string `json:"note,omitempty" schema:"optional"`
+}
+
+// HasAttributes reports whether r carries a schema version 2 attribute.
+func (r Relationship) HasAttributes() bool {
+	return r.Weight != nil || r.Note != nil
 }
 
 // MemoryObject is the full memory object with all fields.
 // Some fields are excluded from the content hash.
 type MemoryObject struct {
+	// SchemaVersion is the object's _helios_schema_version; empty means
+	// SchemaV1.
+	SchemaVersion string `json:"_helios_schema_version,omitempty" schema:"optional"`
+
 	// Included in hash (6 fields):
 	Category      string         `json:"category"`
 	CreatedAt     string         `json:"created_at"`
 	Key           string         `json:"key"`
-	Relationships []Relationship `json:"relationships"`
+	Relationships []Relationship `json:"relationships" schema:"optional"`
 	Source        string         `json:"source"`
 	Value         interface{}    `json:"value"`
 
-	// Excluded from hash:
-	UpdatedAt    string  `json:"updated_at"`
-	Version      int     `json:"version"`
-	AccessCount  int     `json:"access_count"`
-	LastAccessed string  `json:"last_accessed"`
-	Confidence   float64 `json:"confidence"`
+	// Excluded from hash, and optional on input:
+	UpdatedAt    string  `json:"updated_at" schema:"optional"`
+	Version      int     `json:"version" schema:"optional"`
+	AccessCount  int     `json:"access_count" schema:"optional"`
+	LastAccessed string  `json:"last_accessed" schema:"optional"`
+	Confidence   float64 `json:"confidence" schema:"optional"`
+	// Tenant scopes the object to one tenant of a shared store. It is
+	// omitted when empty so objects without tenants serialize as before.
+	Tenant string `json:"tenant,omitempty"`
+	// Supersedes is the content hash of the revision of the key this
+	// object replaces, empty for the key's first revision; see package
+	// lineage. It is omitted when empty.
+	Supersedes string `json:"supersedes,omitempty" schema:"pattern=^[0-9a-f]{64}$"`
+	// CommitmentSalt is the random salt of the object's salted commitment,
+	// in unpadded base64url; see hash.Commit. It is omitted when empty.
+	CommitmentSalt string `json:"commitment_salt,omitempty" schema:"pattern=^[A-Za-z0-9_-]{43}$"`
+	// Residency is the region the object must be stored in, such as "eu";
+	// only stores configured for that region accept it. It is omitted
+	// when empty.
+	Residency string `json:"residency,omitempty" schema:"pattern=^[a-z][a-z0-9-]{0,62}$"`
+	// LegalHold, when set, puts the object's key under legal hold in a
+	// store, with this reason; a write without it keeps the key's hold.
+	// It is omitted when empty.
+	LegalHold string `json:"legal_hold,omitempty"`
+}
+
+// Schema returns the object's schema version, SchemaV1 if unset.
+func (o MemoryObject) Schema() string {
+	if o.SchemaVersion == "" {
+		return SchemaV1
+	}
+	return o.SchemaVersion
 }
 
 // HashInput contains ONLY the 6 fields included in the content hash.
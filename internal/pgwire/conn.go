@@ -0,0 +1,728 @@
+package pgwire
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// protocolVersion is 3.0.
+const protocolVersion = 3 << 16
+
+// sslRequestCode asks the server to switch to TLS.
+const sslRequestCode = 80877103
+
+// maxMessage bounds a single backend message.
+const maxMessage = 1 << 30
+
+// Type OIDs with a non-string result conversion.
+const (
+	oidBool        = 16
+	oidBytea       = 17
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidOID         = 26
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidTimestamptz = 1184
+)
+
+// conn is one server connection. It is not safe for concurrent use;
+// database/sql serializes access.
+type conn struct {
+	nc  net.Conn
+	r   *bufio.Reader
+	w   []byte
+	bad bool
+
+	params   map[string]string
+	txStatus byte
+}
+
+// message appends a frontend message with type typ (0 for the untyped
+// startup messages) built by body to c.w.
+func (c *conn) message(typ byte, body func(b []byte) []byte) {
+	if typ != 0 {
+		c.w = append(c.w, typ)
+	}
+	start := len(c.w)
+	c.w = append(c.w, 0, 0, 0, 0)
+	c.w = body(c.w)
+	binary.BigEndian.PutUint32(c.w[start:], uint32(len(c.w)-start))
+}
+
+func (c *conn) flush() error {
+	_, err := c.nc.Write(c.w)
+	c.w = c.w[:0]
+	if err != nil {
+		c.bad = true
+	}
+	return err
+}
+
+// receive reads one backend message.
+func (c *conn) receive() (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		c.bad = true
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n < 4 || n > maxMessage {
+		c.bad = true
+		return 0, nil, fmt.Errorf("pgwire: invalid message length %d", n)
+	}
+	body := make([]byte, n-4)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		c.bad = true
+		return 0, nil, err
+	}
+	return hdr[0], body, nil
+}
+
+func cstring(b []byte, s string) []byte { return append(append(b, s...), 0) }
+
+func int16be(b []byte, v int) []byte { return binary.BigEndian.AppendUint16(b, uint16(v)) }
+
+func int32be(b []byte, v int) []byte { return binary.BigEndian.AppendUint32(b, uint32(v)) }
+
+// reader parses a backend message body.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = errors.New("pgwire: malformed message")
+	}
+	r.b = nil
+}
+
+func (r *reader) byte() byte {
+	if len(r.b) < 1 {
+		r.fail()
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) int16() int {
+	if len(r.b) < 2 {
+		r.fail()
+		return 0
+	}
+	v := int16(binary.BigEndian.Uint16(r.b))
+	r.b = r.b[2:]
+	return int(v)
+}
+
+func (r *reader) int32() int {
+	if len(r.b) < 4 {
+		r.fail()
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(r.b))
+	r.b = r.b[4:]
+	return int(v)
+}
+
+func (r *reader) cstring() string {
+	i := 0
+	for i < len(r.b) && r.b[i] != 0 {
+		i++
+	}
+	if i == len(r.b) {
+		r.fail()
+		return ""
+	}
+	s := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return s
+}
+
+func (r *reader) bytes(n int) []byte {
+	if n < 0 || n > len(r.b) {
+		r.fail()
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func parseError(body []byte) *Error {
+	e := &Error{}
+	r := &reader{b: body}
+	for r.err == nil {
+		field := r.byte()
+		if field == 0 {
+			break
+		}
+		v := r.cstring()
+		switch field {
+		case 'S':
+			e.Severity = v
+		case 'C':
+			e.Code = v
+		case 'M':
+			e.Message = v
+		case 'D':
+			e.Detail = v
+		}
+	}
+	return e
+}
+
+// startup negotiates TLS, authenticates, and waits for the first
+// ReadyForQuery.
+func startup(ctx context.Context, nc net.Conn, cfg Config) (*conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+		defer nc.SetDeadline(time.Time{})
+	}
+	c := &conn{nc: nc, params: map[string]string{}}
+
+	if cfg.SSLMode != "disable" {
+		c.message(0, func(b []byte) []byte { return int32be(b, sslRequestCode) })
+		if err := c.flush(); err != nil {
+			return nil, fmt.Errorf("pgwire: %w", err)
+		}
+		var answer [1]byte
+		if _, err := io.ReadFull(nc, answer[:]); err != nil {
+			return nil, fmt.Errorf("pgwire: %w", err)
+		}
+		switch {
+		case answer[0] == 'S':
+			tc := tls.Client(nc, &tls.Config{
+				ServerName:         cfg.Host,
+				InsecureSkipVerify: cfg.SSLMode != "verify-full",
+			})
+			if err := tc.HandshakeContext(ctx); err != nil {
+				return nil, fmt.Errorf("pgwire: TLS handshake: %w", err)
+			}
+			c.nc = tc
+		case cfg.SSLMode != "prefer":
+			return nil, fmt.Errorf("pgwire: server does not support TLS (sslmode=%s)", cfg.SSLMode)
+		}
+	}
+	c.r = bufio.NewReader(c.nc)
+
+	c.message(0, func(b []byte) []byte {
+		b = int32be(b, protocolVersion)
+		b = cstring(cstring(b, "user"), cfg.User)
+		b = cstring(cstring(b, "database"), cfg.Database)
+		b = cstring(cstring(b, "application_name"), cfg.ApplicationName)
+		b = cstring(cstring(b, "client_encoding"), "UTF8")
+		return append(b, 0)
+	})
+	if err := c.flush(); err != nil {
+		return nil, fmt.Errorf("pgwire: %w", err)
+	}
+
+	var sc *scramClient
+	for {
+		typ, body, err := c.receive()
+		if err != nil {
+			return nil, fmt.Errorf("pgwire: %w", err)
+		}
+		switch typ {
+		case 'R':
+			r := &reader{b: body}
+			switch kind := r.int32(); kind {
+			case 0: // AuthenticationOk
+			case 3: // AuthenticationCleartextPassword
+				c.message('p', func(b []byte) []byte { return cstring(b, cfg.Password) })
+			case 5: // AuthenticationMD5Password
+				salt := r.bytes(4)
+				c.message('p', func(b []byte) []byte { return cstring(b, md5Password(cfg.User, cfg.Password, salt)) })
+			case 10: // AuthenticationSASL
+				var mechs []string
+				for m := r.cstring(); m != "" && r.err == nil; m = r.cstring() {
+					mechs = append(mechs, m)
+				}
+				if !contains(mechs, scramMechanism) {
+					return nil, fmt.Errorf("pgwire: unsupported SASL mechanisms %v", mechs)
+				}
+				sc = newSCRAMClient(cfg.Password)
+				first := sc.clientFirst()
+				c.message('p', func(b []byte) []byte {
+					b = cstring(b, scramMechanism)
+					b = int32be(b, len(first))
+					return append(b, first...)
+				})
+			case 11: // AuthenticationSASLContinue
+				if sc == nil {
+					return nil, errors.New("pgwire: unexpected SASL continuation")
+				}
+				final, err := sc.clientFinal(r.b)
+				if err != nil {
+					return nil, err
+				}
+				c.message('p', func(b []byte) []byte { return append(b, final...) })
+			case 12: // AuthenticationSASLFinal
+				if sc == nil {
+					return nil, errors.New("pgwire: unexpected SASL final message")
+				}
+				if err := sc.verifyServer(r.b); err != nil {
+					return nil, err
+				}
+				continue
+			default:
+				return nil, fmt.Errorf("pgwire: unsupported authentication method %d", kind)
+			}
+			if r.err != nil {
+				return nil, r.err
+			}
+			if len(c.w) > 0 {
+				if err := c.flush(); err != nil {
+					return nil, fmt.Errorf("pgwire: %w", err)
+				}
+			}
+		case 'E':
+			return nil, parseError(body)
+		case 'Z':
+			c.txStatus = (&reader{b: body}).byte()
+			return c, nil
+		default:
+			if err := c.handleAsync(typ, body); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// handleAsync processes the messages that may arrive at any time.
+func (c *conn) handleAsync(typ byte, body []byte) error {
+	switch typ {
+	case 'S': // ParameterStatus
+		r := &reader{b: body}
+		k, v := r.cstring(), r.cstring()
+		c.params[k] = v
+	case 'K', 'N', 'A': // BackendKeyData, NoticeResponse, NotificationResponse
+	default:
+		c.bad = true
+		return fmt.Errorf("pgwire: unexpected message %q", typ)
+	}
+	return nil
+}
+
+func md5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// result is a fully read query result.
+type result struct {
+	columns []string
+	types   []int
+	rows    [][][]byte // nil cell = NULL
+	tag     string
+}
+
+// exec runs query and reads its whole result. Queries with arguments use
+// the extended protocol; queries without use the simple protocol, which
+// accepts several semicolon-separated statements and returns the result
+// of the last.
+func (c *conn) exec(ctx context.Context, query string, args []driver.NamedValue) (*result, error) {
+	if c.bad {
+		return nil, driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	params := make([][]byte, len(args))
+	formats := make([]int, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, fmt.Errorf("pgwire: named parameters are not supported (%s)", a.Name)
+		}
+		v, binaryFormat, err := encodeParam(a.Value)
+		if err != nil {
+			return nil, fmt.Errorf("pgwire: parameter $%d: %w", a.Ordinal, err)
+		}
+		params[i] = v
+		if binaryFormat {
+			formats[i] = 1
+		}
+	}
+
+	if len(args) == 0 {
+		// The simple protocol allows several statements in one query.
+		c.message('Q', func(b []byte) []byte { return cstring(b, query) })
+	} else {
+		c.extendedQuery(query, params, formats)
+	}
+
+	// A canceled context interrupts blocked I/O; the connection is then
+	// out of sync with the server and is discarded.
+	if done := ctx.Done(); done != nil {
+		stop := context.AfterFunc(ctx, func() { c.nc.SetDeadline(time.Unix(1, 0)) })
+		defer func() {
+			if !stop() {
+				c.bad = true
+			}
+		}()
+	}
+
+	if err := c.flush(); err != nil {
+		return nil, c.ioError(ctx, err)
+	}
+
+	res := &result{}
+	var qerr error
+	for {
+		typ, body, err := c.receive()
+		if err != nil {
+			return nil, c.ioError(ctx, err)
+		}
+		switch typ {
+		case '1', '2', 'n', 'I': // ParseComplete, BindComplete, NoData, EmptyQueryResponse
+		case 'T':
+			res.columns, res.types, res.rows = nil, nil, nil
+			r := &reader{b: body}
+			n := r.int16()
+			for i := 0; i < n && r.err == nil; i++ {
+				res.columns = append(res.columns, r.cstring())
+				r.int32() // table OID
+				r.int16() // column number
+				res.types = append(res.types, r.int32())
+				r.int16() // type size
+				r.int32() // type modifier
+				r.int16() // format
+			}
+			if r.err != nil {
+				c.bad = true
+				return nil, r.err
+			}
+		case 'D':
+			r := &reader{b: body}
+			n := r.int16()
+			row := make([][]byte, n)
+			for i := range row {
+				if size := r.int32(); size >= 0 {
+					row[i] = r.bytes(size)
+				}
+			}
+			if r.err != nil {
+				c.bad = true
+				return nil, r.err
+			}
+			res.rows = append(res.rows, row)
+		case 'C':
+			res.tag = (&reader{b: body}).cstring()
+		case 'E':
+			qerr = parseError(body)
+		case 'Z':
+			c.txStatus = (&reader{b: body}).byte()
+			if qerr != nil {
+				return nil, qerr
+			}
+			return res, nil
+		default:
+			if err := c.handleAsync(typ, body); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// extendedQuery queues Parse, Bind, Describe, Execute, and Sync for an
+// unnamed statement with parameters.
+func (c *conn) extendedQuery(query string, params [][]byte, formats []int) {
+	c.message('P', func(b []byte) []byte {
+		b = cstring(cstring(b, ""), query)
+		return int16be(b, 0)
+	})
+	c.message('B', func(b []byte) []byte {
+		b = cstring(cstring(b, ""), "")
+		b = int16be(b, len(formats))
+		for _, f := range formats {
+			b = int16be(b, f)
+		}
+		b = int16be(b, len(params))
+		for _, p := range params {
+			if p == nil {
+				b = int32be(b, -1)
+				continue
+			}
+			b = int32be(b, len(p))
+			b = append(b, p...)
+		}
+		return int16be(b, 0) // all results in text format
+	})
+	c.message('D', func(b []byte) []byte { return cstring(append(b, 'P'), "") })
+	c.message('E', func(b []byte) []byte { return int32be(cstring(b, ""), 0) })
+	c.message('S', func(b []byte) []byte { return b })
+}
+
+func (c *conn) ioError(ctx context.Context, err error) error {
+	c.bad = true
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("pgwire: %w", err)
+}
+
+// encodeParam returns the wire form of a parameter: binary for []byte,
+// text for everything else.
+func encodeParam(v driver.Value) ([]byte, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false, nil
+	case []byte:
+		if v == nil {
+			v = []byte{}
+		}
+		return v, true, nil
+	case string:
+		if strings.IndexByte(v, 0) >= 0 {
+			return nil, false, errors.New("text cannot contain NUL")
+		}
+		return []byte(v), false, nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), false, nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'g', -1, 64), false, nil
+	case bool:
+		if v {
+			return []byte("true"), false, nil
+		}
+		return []byte("false"), false, nil
+	case time.Time:
+		return []byte(v.Format("2006-01-02 15:04:05.999999999Z07:00")), false, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// decodeColumn converts a text-format result cell of type oid.
+func decodeColumn(oid int, cell []byte) (driver.Value, error) {
+	if cell == nil {
+		return nil, nil
+	}
+	switch oid {
+	case oidBool:
+		return len(cell) > 0 && cell[0] == 't', nil
+	case oidBytea:
+		s := string(cell)
+		if !strings.HasPrefix(s, `\x`) {
+			return nil, errors.New("pgwire: bytea_output must be hex")
+		}
+		return hex.DecodeString(s[2:])
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		return strconv.ParseInt(string(cell), 10, 64)
+	case oidFloat4, oidFloat8:
+		switch string(cell) {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return strconv.ParseFloat(string(cell), 64)
+	case oidTimestamptz:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999Z07:00:00", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07"} {
+			if t, err := time.Parse(layout, string(cell)); err == nil {
+				return t, nil
+			}
+		}
+		return string(cell), nil
+	default:
+		return string(cell), nil
+	}
+}
+
+// Prepare implements driver.Conn. Statements are re-parsed on every
+// execution.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+// Close implements driver.Conn.
+func (c *conn) Close() error {
+	if !c.bad {
+		c.message('X', func(b []byte) []byte { return b })
+		c.flush()
+	}
+	return c.nc.Close()
+}
+
+// Begin implements driver.Conn.
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	q := "BEGIN"
+	switch opts.Isolation {
+	case driver.IsolationLevel(0):
+	case driver.IsolationLevel(2): // sql.LevelReadCommitted
+		q += " ISOLATION LEVEL READ COMMITTED"
+	case driver.IsolationLevel(4): // sql.LevelRepeatableRead
+		q += " ISOLATION LEVEL REPEATABLE READ"
+	case driver.IsolationLevel(6): // sql.LevelSerializable
+		q += " ISOLATION LEVEL SERIALIZABLE"
+	default:
+		return nil, fmt.Errorf("pgwire: unsupported isolation level %d", opts.Isolation)
+	}
+	if opts.ReadOnly {
+		q += " READ ONLY"
+	}
+	if _, err := c.exec(ctx, q, nil); err != nil {
+		return nil, err
+	}
+	return &tx{c: c}, nil
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.exec(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return execResult(res.tag), nil
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.exec(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{res: res}, nil
+}
+
+// Ping implements driver.Pinger.
+func (c *conn) Ping(ctx context.Context) error {
+	_, err := c.exec(ctx, "", nil)
+	return err
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *conn) ResetSession(context.Context) error {
+	if c.bad {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *conn) IsValid() bool { return !c.bad }
+
+// execResult reports the row count from a CommandComplete tag such as
+// "INSERT 0 1" or "UPDATE 3".
+type execResult string
+
+func (r execResult) LastInsertId() (int64, error) {
+	return 0, errors.New("pgwire: LastInsertId is not supported; use RETURNING")
+}
+
+func (r execResult) RowsAffected() (int64, error) {
+	fields := strings.Fields(string(r))
+	if len(fields) < 2 {
+		return 0, nil
+	}
+	return strconv.ParseInt(fields[len(fields)-1], 10, 64)
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return out
+}
+
+type tx struct {
+	c *conn
+}
+
+func (t *tx) Commit() error {
+	res, err := t.c.exec(context.Background(), "COMMIT", nil)
+	if err != nil {
+		return err
+	}
+	// COMMIT of a failed transaction succeeds with a ROLLBACK tag.
+	if res.tag == "ROLLBACK" {
+		return errors.New("pgwire: transaction was rolled back")
+	}
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	_, err := t.c.exec(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+type rows struct {
+	res *result
+	i   int
+}
+
+func (r *rows) Columns() []string { return r.res.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.i >= len(r.res.rows) {
+		return io.EOF
+	}
+	row := r.res.rows[r.i]
+	r.i++
+	for i := range dest {
+		var cell []byte
+		if i < len(row) {
+			cell = row[i]
+		}
+		v, err := decodeColumn(r.res.types[i], cell)
+		if err != nil {
+			return err
+		}
+		dest[i] = v
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+// Package pii flags values of memory objects that look like personal data:
+// email addresses, phone numbers, payment card numbers, and terms from a
+// dictionary. Detection is pattern matching and nothing more; it finds
+// likely PII for a person or a policy to act on, and a clean scan is not
+// proof that a value holds none.
+//
+// Values are scanned leaf by leaf, as package valuetree splits them, so a
+// finding's path is the one a value-tree disclosure or redaction names.
+package pii
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/valuetree"
+)
+
+// Detector finds one kind of PII in text.
+type Detector interface {
+	// Name identifies the detector in findings.
+	Name() string
+	// Detect returns the byte ranges [start, end) of s that it flags, in
+	// order and not overlapping.
+	Detect(s string) [][2]int
+}
+
+type regexpDetector struct {
+	name  string
+	re    *regexp.Regexp
+	check func(match string) bool
+}
+
+// Regexp returns a detector flagging the matches of re for which check,
+// if not nil, returns true.
+func Regexp(name string, re *regexp.Regexp, check func(match string) bool) Detector {
+	return &regexpDetector{name: name, re: re, check: check}
+}
+
+func (d *regexpDetector) Name() string { return d.name }
+
+func (d *regexpDetector) Detect(s string) [][2]int {
+	var out [][2]int
+	for _, m := range d.re.FindAllStringIndex(s, -1) {
+		if d.check == nil || d.check(s[m[0]:m[1]]) {
+			out = append(out, [2]int{m[0], m[1]})
+		}
+	}
+	return out
+}
+
+// Dictionary returns a detector flagging whole-word, case-insensitive
+// occurrences of any of terms, such as the names of patients or projects.
+func Dictionary(name string, terms ...string) (Detector, error) {
+	var alts []string
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t != "" {
+			alts = append(alts, wordBound(t))
+		}
+	}
+	if len(alts) == 0 {
+		return nil, fmt.Errorf("dictionary %s has no terms", name)
+	}
+	// Longer terms first, so a term is not cut short by its own prefix.
+	sort.Slice(alts, func(i, j int) bool { return len(alts[i]) > len(alts[j]) })
+	re, err := regexp.Compile(`(?i)(?:` + strings.Join(alts, "|") + `)`)
+	if err != nil {
+		return nil, err
+	}
+	return Regexp(name, re, nil), nil
+}
+
+// wordBound returns the pattern matching t as a whole word: anchored at a
+// word boundary at each end that is a word character, so "C++" matches
+// before a comma as well as "Ada" does.
+func wordBound(t string) string {
+	re := regexp.QuoteMeta(t)
+	if isWord(t[0]) {
+		re = `\b` + re
+	}
+	if isWord(t[len(t)-1]) {
+		re += `\b`
+	}
+	return re
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// LoadDictionary reads a dictionary detector's terms from a file, one per
+// line; blank lines and lines starting with '#' are ignored. The detector
+// is named "dictionary:" and the file's path.
+func LoadDictionary(path string) (Detector, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	defer f.Close()
+	var terms []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			terms = append(terms, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	return Dictionary("dictionary:"+path, terms...)
+}
+
+var (
+	emailRE = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	cardRE  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	phoneRE = regexp.MustCompile(`(?:\+|\b)\d[\d ().-]{7,}\d\b`)
+)
+
+// Email flags email addresses.
+var Email = Regexp("email", emailRE, nil)
+
+// CreditCard flags runs of 13 to 19 digits, optionally grouped with spaces
+// or hyphens, that pass the Luhn check payment card numbers carry.
+var CreditCard = Regexp("credit_card", cardRE, func(m string) bool {
+	return luhn(digits(m))
+})
+
+// Phone flags runs of 10 to 15 digits written as phone numbers are: with a
+// leading '+' or grouped by spaces, dots, hyphens, or parentheses. Bare
+// runs of digits are left alone, as they are more often identifiers.
+var Phone = Regexp("phone", phoneRE, func(m string) bool {
+	n := len(digits(m))
+	return n >= 10 && n <= 15 && (m[0] == '+' || len(m) > n)
+})
+
+// Builtin returns the built-in detectors in the order a scanner should
+// run them: card numbers before phone numbers, which would otherwise claim
+// grouped card numbers.
+func Builtin() []Detector {
+	return []Detector{Email, CreditCard, Phone}
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// luhn reports whether the decimal digits ds pass the Luhn check.
+func luhn(ds string) bool {
+	sum := 0
+	for i := range ds {
+		d := int(ds[len(ds)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return len(ds) > 0 && sum%10 == 0
+}
+
+// Finding is one flagged span of a value.
+type Finding struct {
+	// Path locates the leaf, as in a value tree: "$.contact.email".
+	Path     string `json:"path"`
+	Detector string `json:"detector"`
+	// Start and End are the byte offsets of the span in the leaf's text.
+	// The text itself is left out, so that reports do not copy the PII
+	// they point at.
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s at bytes %d-%d", f.Path, f.Detector, f.Start, f.End)
+}
+
+// Scanner runs detectors over values.
+type Scanner struct {
+	detectors []Detector
+}
+
+// NewScanner returns a scanner running detectors in order, or the built-in
+// detectors if none are given. A span one detector flags is not reported
+// again by a later one.
+func NewScanner(detectors ...Detector) *Scanner {
+	if len(detectors) == 0 {
+		detectors = Builtin()
+	}
+	return &Scanner{detectors: detectors}
+}
+
+// Scan returns the findings in v, in path order. It scans the text of
+// string and number leaves; binary values are not scanned.
+func (s *Scanner) Scan(v interface{}) ([]Finding, error) {
+	tree, err := valuetree.Build(v, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out []Finding
+	for _, l := range tree.Leaves() {
+		text, ok := leafText(l.Value)
+		if !ok {
+			continue
+		}
+		var claimed [][2]int
+		for _, d := range s.detectors {
+			for _, span := range d.Detect(text) {
+				if overlaps(span, claimed) {
+					continue
+				}
+				claimed = append(claimed, span)
+				out = append(out, Finding{Path: l.Path, Detector: d.Name(), Start: span[0], End: span[1]})
+			}
+		}
+	}
+	return out, nil
+}
+
+// leafText returns the text of a leaf's canonical JSON: a string's
+// contents or a number's digits.
+func leafText(value []byte) (string, bool) {
+	if len(value) == 0 {
+		return "", false
+	}
+	switch c := value[0]; {
+	case c == '"':
+		var s string
+		if json.Unmarshal(value, &s) != nil {
+			return "", false
+		}
+		return s, true
+	case c == '-' || c >= '0' && c <= '9':
+		return string(value), true
+	}
+	return "", false
+}
+
+func overlaps(span [2]int, claimed [][2]int) bool {
+	for _, c := range claimed {
+		if span[0] < c[1] && c[0] < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Action is what a policy does with an object that has findings.
+type Action string
+
+const (
+	// Warn reports the findings and accepts the object.
+	Warn Action = "warn"
+	// Reject reports the findings and refuses the object.
+	Reject Action = "reject"
+	// Seal accepts the object only as a sealed envelope, with its value
+	// encrypted (package seal).
+	Seal Action = "seal"
+)
+
+// ParseAction parses the name of an action.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(s); a {
+	case Warn, Reject, Seal:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown PII action %q (want warn, reject, or seal)", s)
+}
@@ -0,0 +1,107 @@
+package pii
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+func TestBuiltinDetectors(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want string // detector names, in order
+	}{
+		{"mail ada@example.co.uk today", "email"},
+		{"card 4111 1111 1111 1111 on file", "credit_card"},
+		{"card 4111-1111-1111-1112", ""}, // fails the Luhn check
+		{"call +1 (555) 010-2030 or 555.010.2031", "phone phone"},
+		{"order 5550102030", ""}, // a bare run of digits
+		{"2025-01-15T10:30:00.000Z", ""},
+		{"version 1.2.3", ""},
+	} {
+		var got []string
+		for _, d := range Builtin() {
+			for range d.Detect(tc.text) {
+				got = append(got, d.Name())
+			}
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("%q: flagged %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	v, err := ingest.Decode([]byte(`{
+		"contact": {"email": "ada@example.com", "phones": ["+44 20 7946 0958"]},
+		"card": "4111 1111 1111 1111",
+		"amount": 4111111111111111,
+		"note": "met with Charles Babbage",
+		"scan": {"$bytes": "YWRhQGV4YW1wbGUuY29t"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := Dictionary("names", "babbage", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := NewScanner(append(Builtin(), dict)...).Scan(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Path+"="+f.Detector)
+	}
+	// The grouped card number is not also a phone number, and the binary
+	// value is not scanned.
+	want := []string{
+		"$.amount=credit_card",
+		"$.card=credit_card",
+		"$.contact.email=email",
+		"$.contact.phones[0]=phone",
+		"$.note=names",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("findings %q, want %q", got, want)
+	}
+	if f := findings[4]; f.Start != len("met with Charles ") || f.End != len("met with Charles Babbage") {
+		t.Errorf("dictionary span = %d-%d", f.Start, f.End)
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terms.txt")
+	if err := os.WriteFile(path, []byte("# patients\nAda Lovelace\n\nC++\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.Detect("ada lovelace wrote C++, not Adam Lovelace")); n != 2 {
+		t.Errorf("flagged %d terms, want 2", n)
+	}
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDictionary(empty); err == nil {
+		t.Error("accepted a dictionary with no terms")
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	for _, s := range []string{"warn", "reject", "seal"} {
+		if a, err := ParseAction(s); err != nil || string(a) != s {
+			t.Errorf("ParseAction(%q) = %q, %v", s, a, err)
+		}
+	}
+	if _, err := ParseAction("ignore"); err == nil {
+		t.Error("accepted an unknown action")
+	}
+}
@@ -0,0 +1,500 @@
+// Package repl is helios repl: a sandbox in which a user pastes JSON and
+// sees at once what canonicalization makes of it — the canonical form, the
+// changes normalization made on the way, the validation error if the
+// ingest rules reject it, and its hash.
+//
+// A fragment with "key" and "value" members is taken as a memory object
+// and gets its content hash; any other JSON is taken as a value, as the
+// value of an object would be canonicalized, and gets the SHA-256 of its
+// canonical bytes, since only objects have content hashes.
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+// Kinds of fragment.
+const (
+	KindObject = "memory object"
+	KindValue  = "value"
+)
+
+// Report is what canonicalization makes of a fragment.
+type Report struct {
+	Kind string
+	// Canonical is the canonical form: of an object, its hash input.
+	Canonical string
+	// Hash is an object's content hash, or the SHA-256 of a value's
+	// canonical bytes.
+	Hash string
+	// Changes describes, one per line, what normalization changed.
+	Changes []string
+	// Err is why the fragment was rejected, if it was.
+	Err error
+}
+
+// hashFields are the members of an object's hash input.
+var hashFields = map[string]bool{
+	"_helios_schema_version": true, "category": true, "created_at": true,
+	"key": true, "relationships": true, "source": true, "value": true,
+}
+
+// Eval canonicalizes the JSON fragment input.
+func Eval(input string) Report {
+	data := []byte(input)
+	v, err := ingest.Decode(data)
+	if err != nil {
+		return Report{Err: err}
+	}
+	m, isObject := v.(map[string]interface{})
+	_, hasKey := m["key"]
+	_, hasValue := m["value"]
+	if isObject && hasKey && hasValue {
+		return evalObject(data, m)
+	}
+	return evalValue(data, v)
+}
+
+func evalObject(data []byte, input map[string]interface{}) Report {
+	r := Report{Kind: KindObject}
+	if r.Err = ingest.ValidateInput(input); r.Err != nil {
+		return r
+	}
+	obj := ingest.ToMemoryObject(input)
+	fields, err := hash.HashFields(obj)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	canonical, err := canon.CanonicalizeObject(fields)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Canonical = string(canonical)
+	if r.Hash, r.Err = hash.ContentHash(obj); r.Err != nil {
+		return r
+	}
+
+	if _, ok := input["_helios_schema_version"]; !ok {
+		r.Changes = append(r.Changes, `_helios_schema_version: "1" added; an object without one is version 1`)
+	}
+	var excluded []string
+	for k := range input {
+		if !hashFields[k] {
+			excluded = append(excluded, k)
+		}
+	}
+	if len(excluded) > 0 {
+		sort.Strings(excluded)
+		r.Changes = append(r.Changes, "excluded from the hash: "+strings.Join(excluded, ", "))
+	}
+	for _, f := range []string{"category", "created_at", "key", "source", "value"} {
+		if before, ok := input[f].(string); ok {
+			if after, _ := fields[f].(string); after != before {
+				r.Changes = append(r.Changes, fmt.Sprintf("%s: %s -> %s", f, quote(before), quote(after)))
+			}
+		}
+	}
+	r.Changes = append(r.Changes, relationshipChanges(input["relationships"], fields["relationships"])...)
+	r.Changes = append(r.Changes, memberChanges(data, canonical)...)
+	return r
+}
+
+// relationshipChanges reports how the relationships were reordered and
+// their strings normalized.
+func relationshipChanges(in, out interface{}) []string {
+	before, _ := in.([]interface{})
+	after, _ := out.([]interface{})
+	var changes []string
+	var order []string
+	for i, rel := range before {
+		m, _ := rel.(map[string]interface{})
+		k, _ := m["key"].(string)
+		t, _ := m["type"].(string)
+		order = append(order, canon.NormalizeString(k)+"\x00"+canon.NormalizeString(t))
+		for _, f := range []string{"key", "type", canon.RelationshipNote} {
+			if s, ok := m[f].(string); ok && canon.NormalizeString(s) != s {
+				changes = append(changes, fmt.Sprintf("relationships[%d].%s: %s -> %s", i, f, quote(s), quote(canon.NormalizeString(s))))
+			}
+		}
+	}
+	for i, rel := range after {
+		m, _ := rel.(map[string]interface{})
+		k, _ := m["key"].(string)
+		t, _ := m["type"].(string)
+		if i < len(order) && order[i] != k+"\x00"+t {
+			return append([]string{"relationships: sorted by key, then type"}, changes...)
+		}
+	}
+	return changes
+}
+
+func evalValue(data []byte, v interface{}) Report {
+	r := Report{Kind: KindValue}
+	if r.Err = canon.ValidateIngestValue(v); r.Err != nil {
+		return r
+	}
+	if s, ok := v.(string); ok {
+		if n := canon.NormalizeString(s); n != s {
+			r.Changes = append(r.Changes, fmt.Sprintf("$: %s -> %s", quote(s), quote(n)))
+			v = n
+		}
+	}
+	canonical, err := canon.CanonicalizeValue(v)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Canonical = string(canonical)
+	sum := sha256.Sum256(canonical)
+	r.Hash = hex.EncodeToString(sum[:])
+	r.Changes = append(r.Changes, memberChanges(data, canonical)...)
+	return r
+}
+
+// memberChanges reports the objects of the input whose members the
+// canonical form orders differently, and the members the input repeats,
+// of which decoding keeps the last.
+func memberChanges(input, canonical []byte) []string {
+	before, dups, err := members(input)
+	if err != nil {
+		return nil
+	}
+	after, _, err := members(canonical)
+	if err != nil {
+		return nil
+	}
+	var changes []string
+	for _, path := range sortedKeys(after) {
+		// Compare the orders of the members both have, leaving out those
+		// the canonical form drops or adds.
+		got, want := common(before[path], after[path]), common(after[path], before[path])
+		if strings.Join(got, "\x00") != strings.Join(want, "\x00") {
+			changes = append(changes, fmt.Sprintf("%s: members sorted (%s -> %s)", path, strings.Join(got, ", "), strings.Join(want, ", ")))
+		}
+	}
+	for _, d := range dups {
+		changes = append(changes, d+": repeated member; the last one is kept")
+	}
+	return changes
+}
+
+// common returns the members of a that b has too, in a's order, once.
+func common(a, b []string) []string {
+	in := map[string]bool{}
+	for _, k := range b {
+		in[k] = true
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, k := range a {
+		if in[k] && !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// members returns the member names of every object in data, in order, by
+// path, and the paths of members repeated within an object.
+func members(data []byte) (map[string][]string, []string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	out := map[string][]string{}
+	var dups []string
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			seen := map[string]bool{}
+			out[path] = []string{}
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				k := tok.(string)
+				if seen[k] {
+					dups = append(dups, memberPath(path, k))
+				}
+				seen[k] = true
+				out[path] = append(out[path], k)
+				if err := walk(memberPath(path, k)); err != nil {
+					return err
+				}
+			}
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		default:
+			return nil
+		}
+		_, err = dec.Token()
+		return err
+	}
+	return out, dups, walk("$")
+}
+
+// memberPath returns the path of member k of the object at path, as
+// package canon writes paths.
+func memberPath(path, k string) string {
+	if k == "" || strings.ContainsAny(k, `.[]"`) {
+		return path + "[" + strconv.Quote(k) + "]"
+	}
+	return path + "." + k
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// quote quotes s with non-ASCII runes escaped, so that a normalization
+// change is visible even where the terminal renders both forms alike.
+func quote(s string) string { return strconv.QuoteToASCII(s) }
+
+// Write writes r to w. verbose renders a validation error with its code,
+// path, and input, as helios --verbose does.
+func (r Report) Write(w io.Writer, verbose bool) {
+	if r.Err != nil {
+		style := canon.Terse
+		if verbose {
+			style = canon.Verbose
+		}
+		if r.Kind != "" {
+			fmt.Fprintf(w, "kind:      %s\n", r.Kind)
+		}
+		fmt.Fprintf(w, "error:     %s\n", canon.FormatError(r.Err, style))
+		return
+	}
+	fmt.Fprintf(w, "kind:      %s\n", r.Kind)
+	fmt.Fprintf(w, "canonical: %s\n", r.Canonical)
+	if r.Kind == KindObject {
+		fmt.Fprintf(w, "hash:      %s\n", r.Hash)
+	} else {
+		fmt.Fprintf(w, "sha256:    %s (of the canonical bytes; only objects have content hashes)\n", r.Hash)
+	}
+	if len(r.Changes) == 0 {
+		fmt.Fprintln(w, "changes:   none; the input was already canonical apart from whitespace")
+		return
+	}
+	fmt.Fprintln(w, "changes:")
+	for _, c := range r.Changes {
+		fmt.Fprintf(w, "  %s\n", c)
+	}
+}
+
+// maxHistory bounds the entries a Session keeps and loads.
+const maxHistory = 500
+
+// Session is a REPL session: its history and settings.
+type Session struct {
+	// Prompt, if set, is printed before each fragment, and a continuation
+	// prompt before each further line of one.
+	Prompt  bool
+	Verbose bool
+	history []string
+	// histFile, if not nil, has each evaluated fragment appended to it.
+	histFile io.Writer
+}
+
+// NewSession returns a session whose history is kept in the file at path,
+// loading what it already holds; path "" keeps history only in memory.
+func NewSession(path string) (*Session, error) {
+	s := &Session{}
+	if path == "" {
+		return s, nil
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(nil, 16<<20)
+		for sc.Scan() {
+			var entry string
+			if json.Unmarshal(sc.Bytes(), &entry) == nil {
+				s.add(entry)
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	s.histFile = f
+	return s, nil
+}
+
+// Close closes the session's history file.
+func (s *Session) Close() error {
+	if c, ok := s.histFile.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// History returns the fragments evaluated, oldest first, including those
+// of earlier sessions sharing the history file.
+func (s *Session) History() []string { return s.history }
+
+func (s *Session) add(entry string) {
+	if n := len(s.history); n > 0 && s.history[n-1] == entry {
+		return
+	}
+	s.history = append(s.history, entry)
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+	}
+}
+
+func (s *Session) record(entry string) {
+	s.add(entry)
+	if s.histFile != nil {
+		line, _ := json.Marshal(entry)
+		s.histFile.Write(append(line, '\n'))
+	}
+}
+
+const help = `Paste JSON to see its canonical form, the changes normalization made,
+validation errors, and its hash. An object with "key" and "value" members
+is a memory object; anything else is a value. A fragment may span lines;
+a blank line evaluates an incomplete one, to show why it does not parse.
+
+  :history   list earlier fragments
+  :N         evaluate fragment N of the history again
+  :verbose   toggle detailed validation errors
+  :clear     discard the lines of an unfinished fragment
+  :help      show this help
+  :quit      leave (or end the input)
+`
+
+// Run reads fragments and commands from in until it ends or :quit, and
+// writes their reports to out.
+func (s *Session) Run(in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	var buf strings.Builder
+	for {
+		if s.Prompt {
+			if buf.Len() == 0 {
+				io.WriteString(out, "helios> ")
+			} else {
+				io.WriteString(out, "   ...> ")
+			}
+		}
+		line, err := r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		eof := err != nil
+		if eof && line == "" {
+			if buf.Len() > 0 {
+				s.eval(out, buf.String())
+			}
+			if s.Prompt {
+				fmt.Fprintln(out)
+			}
+			return nil
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == ":clear":
+			buf.Reset()
+		case buf.Len() == 0 && strings.HasPrefix(trimmed, ":"):
+			if !s.command(out, trimmed) {
+				return nil
+			}
+		case trimmed == "" && buf.Len() == 0:
+		case trimmed == "":
+			s.eval(out, buf.String())
+			buf.Reset()
+		default:
+			buf.WriteString(line)
+			if !eof && incomplete(buf.String()) {
+				continue
+			}
+			s.eval(out, buf.String())
+			buf.Reset()
+		}
+	}
+}
+
+// incomplete reports whether fragment is the start of a JSON document
+// that further lines could finish.
+func incomplete(fragment string) bool {
+	_, err := ingest.Decode([]byte(fragment))
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func (s *Session) eval(out io.Writer, fragment string) {
+	fragment = strings.TrimSpace(fragment)
+	s.record(fragment)
+	Eval(fragment).Write(out, s.Verbose)
+}
+
+// command runs a : command and reports whether the session goes on.
+func (s *Session) command(out io.Writer, cmd string) bool {
+	switch cmd {
+	case ":quit", ":q", ":exit":
+		return false
+	case ":help", ":h", ":?":
+		io.WriteString(out, help)
+	case ":verbose":
+		s.Verbose = !s.Verbose
+		fmt.Fprintf(out, "verbose errors %s\n", map[bool]string{true: "on", false: "off"}[s.Verbose])
+	case ":history":
+		for i, h := range s.history {
+			fmt.Fprintf(out, "%4d  %s\n", i+1, oneLine(h))
+		}
+	default:
+		n, err := strconv.Atoi(cmd[1:])
+		if err != nil {
+			fmt.Fprintf(out, "unknown command %s; :help lists them\n", cmd)
+			return true
+		}
+		if n < 1 || n > len(s.history) {
+			fmt.Fprintf(out, "no history entry %d\n", n)
+			return true
+		}
+		fragment := s.history[n-1]
+		fmt.Fprintln(out, oneLine(fragment))
+		s.eval(out, fragment)
+	}
+	return true
+}
+
+// oneLine shortens a fragment to one line for listing.
+func oneLine(fragment string) string {
+	s := strings.Join(strings.Fields(fragment), " ")
+	if r := []rune(s); len(r) > 100 {
+		s = string(r[:99]) + "…"
+	}
+	return s
+}
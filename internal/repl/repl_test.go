@@ -0,0 +1,130 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+)
+
+func TestEvalObject(t *testing.T) {
+	doc := `{"value": "cafe\u0301", "key": "k", "category": "c", "source": "s",
+		"created_at": "2025-01-01T00:00:00.000Z", "updated_at": "2025-01-02T00:00:00.000Z",
+		"relationships": [{"key": "z", "type": "t"}, {"key": "a", "type": "t"}]}`
+	r := Eval(doc)
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	obj, err := ingest.ParseObject([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := hash.ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Kind != KindObject || r.Hash != want {
+		t.Errorf("kind %q, hash %s; want %q, %s", r.Kind, r.Hash, KindObject, want)
+	}
+	if !strings.HasPrefix(r.Canonical, `{"_helios_schema_version":"1","category":"c"`) || strings.Contains(r.Canonical, "updated_at") {
+		t.Errorf("canonical = %s", r.Canonical)
+	}
+	changes := strings.Join(r.Changes, "\n")
+	for _, want := range []string{"_helios_schema_version", "updated_at", `"cafe\u0301" -> "caf\u00e9"`, "relationships", "members sorted"} {
+		if !strings.Contains(changes, want) {
+			t.Errorf("changes lack %q:\n%s", want, changes)
+		}
+	}
+}
+
+func TestEvalValue(t *testing.T) {
+	r := Eval(`{"b": [1, "x"], "a": true}`)
+	if r.Err != nil || r.Kind != KindValue || r.Canonical != `{"a":true,"b":[1,"x"]}` || len(r.Hash) != 64 {
+		t.Fatalf("report = %+v", r)
+	}
+	if len(r.Changes) != 1 || !strings.Contains(r.Changes[0], "b, a -> a, b") {
+		t.Errorf("changes = %q", r.Changes)
+	}
+	if r := Eval(`"same"`); r.Err != nil || len(r.Changes) != 0 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for doc, want := range map[string]string{
+		`{"a": 1.5}`:                       "CANON_ERR_FLOAT_PROHIBITED",
+		`[null]`:                           "CANON_ERR_NULL_PROHIBITED",
+		`{"key": "k", "value": 1, "x": 1}`: "",
+		`{"a": `:                           "",
+	} {
+		r := Eval(doc)
+		if r.Err == nil || !strings.Contains(r.Err.Error(), want) {
+			t.Errorf("%s: error %v, want %q", doc, r.Err, want)
+		}
+		if r.Hash != "" {
+			t.Errorf("%s: rejected fragment hashed", doc)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	s, err := NewSession(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.Join([]string{
+		`{"b": 1,`,
+		` "a": 2}`,
+		`[1,`,
+		`:clear`,
+		`"x"`,
+		`:history`,
+		`:1`,
+		`:nope`,
+		`:quit`,
+		`"never read"`,
+	}, "\n")
+	var out strings.Builder
+	if err := s.Run(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	got := out.String()
+	for _, want := range []string{
+		`canonical: {"a":2,"b":1}`,
+		"   1  {\"b\": 1, \"a\": 2}\n   2  \"x\"\n",
+		"unknown command :nope",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "never read") || strings.Count(got, `canonical: {"a":2,"b":1}`) != 2 {
+		t.Errorf("output:\n%s", got)
+	}
+
+	// The next session starts with the history, and adds to it.
+	s, err = NewSession(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	out.Reset()
+	if err := s.Run(strings.NewReader(`{"c": `+"\n\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "error:") {
+		t.Errorf("incomplete fragment at a blank line:\n%s", out.String())
+	}
+	want := []string{"{\"b\": 1,\n \"a\": 2}", `"x"`, `{"b": 1,` + "\n" + ` "a": 2}`, `{"c":`}
+	if h := s.History(); strings.Join(h, "|") != strings.Join(want, "|") {
+		t.Errorf("history = %q", h)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("history file: %v, %v", fi, err)
+	}
+}
@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/checkpoint"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/store"
+	"github.com/holeyfield33-art/helios/internal/verify"
+)
+
+// document is one published schema and the Go value it is derived from.
+type document struct {
+	title       string
+	description string
+	v           interface{}
+}
+
+var documents = map[string]document{
+	"memory-object": {"Memory object", "A Helios memory object, as hashed, stored, and served. Only category, created_at, key, relationships, source, and value are covered by the content hash.", object.MemoryObject{}},
+	"envelope":      {"Attestation envelope", "A DSSE envelope as written by helios attest; its payload is a base64 in-toto statement.", attest.Envelope{}},
+	"statement":     {"Attestation statement", "The in-toto statement carried in an attestation envelope's payload.", attest.Statement{}},
+	"test-vector":   {"Test vector", "One conformance vector of a vectors file.", verify.TestVector{}},
+	"vectors-file":  {"Vectors file", "A conformance vector corpus, such as test_vectors/vectors.json.", verify.VectorsFile{}},
+	"hash-response": {"Hash API response", "The body a hash API answers POST /hash with: the content hash, or a CANON_ERR_ code and message.", verify.HashResponse{}},
+	"checkpoint":    {"Checkpoint", "A signed Merkle checkpoint of a store, as logged by helios checkpoint.", checkpoint.Checkpoint{}},
+	"proof":         {"Inclusion proof", "An inclusion proof of a key's object, as served by GET /proofs/{key} and written by helios prove.", checkpoint.Proof{}},
+	"put-response":  {"PUT response", "The body of a successful PUT /keys/{key}.", store.PutResponse{}},
+	"key-page":      {"Key page", "One page of the key index, as served by GET /keys.", store.Page{}},
+	"changes":       {"Change page", "One page of the change feed, as served by GET /changes.", store.ChangesResponse{}},
+	"similar":       {"Similarity matches", "The body of GET /similar: the nearest keys, closest first.", []store.Match{}},
+	"error":         {"Error response", "The body of every error response of the store gateway.", store.ErrorResponse{}},
+	"quota-error":   {"Quota error response", "The body of a PUT refused by the store's quota policy.", store.QuotaErrorResponse{}},
+	"reload-event":  {"Reload event", "The audit record of a reload of the store's write rules, as answered by POST /admin/reload.", store.ReloadEvent{}},
+	"hold-request":  {"Hold request", "The body of PUT /holds/{key}: the reason of the legal hold.", store.HoldRequest{}},
+	"hold-event":    {"Hold event", "The audit record of a legal hold set or released, as answered by PUT and DELETE /holds/{key}.", store.HoldEvent{}},
+	"holds":         {"Held keys", "The body of GET /holds: the keys under legal hold.", store.HoldsResponse{}},
+	"health":        {"Health response", "The body of GET /healthz and GET /readyz: the status and, for readiness, each check.", store.HealthResponse{}},
+}
+
+// Names returns the names of the published schemas, sorted.
+func Names() []string {
+	names := make([]string, 0, len(documents))
+	for name := range documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Document returns the named schema, or false if there is none.
+func Document(name string) (*Schema, bool) {
+	d, ok := documents[name]
+	if !ok {
+		return nil, false
+	}
+	s := Generate(d.v)
+	s.Title, s.Description = d.title, d.description
+	return s, true
+}
+
+// Registry publishes the schemas, and the OpenAPI description of the
+// store gateway, to the gateway.
+type Registry struct {
+	// Version is the info.version of the OpenAPI description.
+	Version string
+}
+
+// Names returns the names of the published schemas.
+func (Registry) Names() []string { return Names() }
+
+// Get returns the named schema as indented JSON.
+func (Registry) Get(name string) ([]byte, bool) {
+	s, ok := Document(name)
+	if !ok {
+		return nil, false
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, false
+	}
+	return append(data, '\n'), true
+}
+
+// OpenAPI returns the OpenAPI description of routes.
+func (r Registry) OpenAPI(routes []store.Route) ([]byte, error) {
+	return OpenAPI(routes, r.Version)
+}
@@ -0,0 +1,233 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// OpenAPIVersion is the version of the OpenAPI documents OpenAPI writes.
+// 3.1 is the first whose schemas are JSON Schema 2020-12.
+const OpenAPIVersion = "3.1.0"
+
+type openAPIDoc struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       openAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas,omitempty"`
+	} `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type operation struct {
+	ID          string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []parameter          `json:"parameters,omitempty"`
+	RequestBody *body                `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type body struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// OpenAPI returns an OpenAPI 3.1 description of routes whose bodies refer
+// to the named schemas, as indented JSON. Each schema a route names, and
+// every $defs entry it uses, becomes a component; two documents that
+// define the same type differently keep apart under qualified names.
+func OpenAPI(routes []store.Route, version string) ([]byte, error) {
+	doc := openAPIDoc{
+		OpenAPI: OpenAPIVersion,
+		Info:    openAPIInfo{Title: "Helios store gateway", Version: version},
+		Paths:   make(map[string]map[string]*operation),
+	}
+	c := &componentSet{schemas: make(map[string]*Schema)}
+	ref := func(name string) (*Schema, error) {
+		if err := c.add(name); err != nil {
+			return nil, err
+		}
+		return &Schema{Ref: componentRef(name)}, nil
+	}
+
+	for _, rt := range routes {
+		op := &operation{ID: rt.ID, Summary: rt.Summary, Responses: make(map[string]*response)}
+		for _, p := range rt.Params {
+			op.Parameters = append(op.Parameters, parameter{
+				Name:        p.Name,
+				In:          p.In,
+				Description: p.Description,
+				Required:    p.Required,
+				Schema:      &Schema{Type: Types{p.Type}},
+			})
+		}
+		if rt.Body != "" {
+			s, err := ref(rt.Body)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", rt.Method, rt.Pattern, err)
+			}
+			op.RequestBody = &body{Required: true, Content: map[string]*mediaType{"application/json": {Schema: s}}}
+		}
+		for _, r := range rt.Responses {
+			status := strconv.Itoa(r.Status)
+			resp := op.Responses[status]
+			if resp == nil {
+				resp = &response{Description: r.Description}
+				op.Responses[status] = resp
+			} else {
+				resp.Description += "; or " + r.Description
+			}
+			switch {
+			case r.Schema != "":
+				s, err := ref(r.Schema)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %w", rt.Method, rt.Pattern, err)
+				}
+				resp.media()["application/json"] = &mediaType{Schema: s}
+			case r.ContentType != "":
+				resp.media()[r.ContentType] = &mediaType{}
+			}
+		}
+		path := rt.Path()
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*operation)
+		}
+		doc.Paths[path][strings.ToLower(rt.Method)] = op
+	}
+	doc.Components.Schemas = c.schemas
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (r *response) media() map[string]*mediaType {
+	if r.Content == nil {
+		r.Content = make(map[string]*mediaType)
+	}
+	return r.Content
+}
+
+func componentRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+// componentSet gathers published documents and their $defs as OpenAPI
+// components.
+type componentSet struct {
+	schemas map[string]*Schema
+}
+
+// add adds the named document and the definitions it uses, rewriting
+// their references to point at components.
+func (c *componentSet) add(name string) error {
+	if _, ok := c.schemas[name]; ok {
+		return nil
+	}
+	root, ok := Document(name)
+	if !ok {
+		return fmt.Errorf("no schema %q", name)
+	}
+	defs := root.Defs
+	root.Defs, root.Schema = nil, ""
+
+	// A definition keeps its own name unless another document already
+	// took it for a different type.
+	names := make(map[string]string, len(defs))
+	for def := range defs {
+		names[def] = def
+	}
+	rewrite := func(s *Schema) {
+		walk(s, func(s *Schema) {
+			switch {
+			case s.Ref == "#":
+				s.Ref = componentRef(name)
+			case strings.HasPrefix(s.Ref, "#/$defs/"):
+				s.Ref = componentRef(names[strings.TrimPrefix(s.Ref, "#/$defs/")])
+			}
+		})
+	}
+	for def, s := range defs {
+		if existing, ok := c.schemas[def]; ok && !sameSchema(existing, rewritten(s, rewrite)) {
+			names[def] = name + "." + def
+		}
+	}
+
+	rewrite(root)
+	c.schemas[name] = root
+	order := make([]string, 0, len(defs))
+	for def := range defs {
+		order = append(order, def)
+	}
+	sort.Strings(order)
+	for _, def := range order {
+		s := defs[def]
+		rewrite(s)
+		if _, ok := c.schemas[names[def]]; !ok {
+			c.schemas[names[def]] = s
+		}
+	}
+	return nil
+}
+
+// rewritten returns a rewritten copy of s, leaving s as it was.
+func rewritten(s *Schema, rewrite func(*Schema)) *Schema {
+	data, _ := json.Marshal(s)
+	var cp Schema
+	json.Unmarshal(data, &cp)
+	rewrite(&cp)
+	return &cp
+}
+
+func sameSchema(a, b *Schema) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+// walk calls fn on s and every subschema of it.
+func walk(s *Schema, fn func(*Schema)) {
+	if s == nil {
+		return
+	}
+	fn(s)
+	for _, p := range s.Properties {
+		walk(p, fn)
+	}
+	walk(s.Additional, fn)
+	walk(s.Items, fn)
+	for _, a := range s.AnyOf {
+		walk(a, fn)
+	}
+	for _, d := range s.Defs {
+		walk(d, fn)
+	}
+}
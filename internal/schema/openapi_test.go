@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+func TestOpenAPI(t *testing.T) {
+	routes := store.Routes(store.GatewayOptions{Writable: true, Tenants: true, Metrics: true, Schemas: Registry{}})
+	data, err := OpenAPI(routes, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc openAPIDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != OpenAPIVersion || doc.Info.Version != "1.2.3" {
+		t.Errorf("header: %+v %+v", doc.OpenAPI, doc.Info)
+	}
+	put := doc.Paths["/tenants/{tenant}/keys/{key}"]["put"]
+	if put == nil || put.ID != "tenantPutKey" || put.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/memory-object" {
+		t.Fatalf("PUT /tenants/{tenant}/keys/{key}: %+v", put)
+	}
+	if put.Parameters[0].Name != "tenant" || put.Responses["403"].Content["application/json"].Schema.Ref != "#/components/schemas/quota-error" {
+		t.Errorf("tenant PUT parameters or responses: %+v", put)
+	}
+
+	ids := make(map[string]bool)
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			if ids[op.ID] {
+				t.Errorf("duplicate operationId %q", op.ID)
+			}
+			ids[op.ID] = true
+			if strings.Contains(path, "...") {
+				t.Errorf("%s %s: wildcard left in path", method, path)
+			}
+		}
+	}
+	if len(ids) != len(routes) {
+		t.Errorf("%d operations for %d routes", len(ids), len(routes))
+	}
+
+	// Every reference resolves within the document.
+	check := func(s *Schema) {
+		walk(s, func(s *Schema) {
+			if s.Ref == "" {
+				return
+			}
+			name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+			if !ok || doc.Components.Schemas[name] == nil {
+				t.Errorf("dangling $ref %q", s.Ref)
+			}
+		})
+	}
+	for _, s := range doc.Components.Schemas {
+		check(s)
+	}
+	if doc.Components.Schemas["KeyEntry"] == nil {
+		t.Error("key-page's KeyEntry definition was not hoisted")
+	}
+}
+
+func TestOpenAPIComponentNames(t *testing.T) {
+	type Relationship struct {
+		Other int `json:"other"`
+	}
+	type clash struct {
+		Rel Relationship `json:"rel"`
+	}
+	documents["clash"] = document{"Clash", "A type whose definition's name another document uses.", clash{}}
+	defer delete(documents, "clash")
+
+	c := &componentSet{schemas: make(map[string]*Schema)}
+	for _, name := range []string{"memory-object", "proof", "checkpoint", "clash"} {
+		if err := c.add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.schemas["Signature"] == nil || c.schemas["checkpoint.Signature"] != nil {
+		t.Error("identical definitions from two documents were not shared")
+	}
+	if c.schemas["clash.Relationship"] == nil || c.schemas["clash"].Properties["rel"].Ref != "#/components/schemas/clash.Relationship" {
+		t.Errorf("clashing definition was not renamed: %v", c.schemas["clash"].Properties["rel"])
+	}
+	if c.schemas["Relationship"].Properties["other"] != nil {
+		t.Error("clashing definition replaced the first")
+	}
+	if err := c.add("nope"); err == nil {
+		t.Error("add of an unknown schema succeeded")
+	}
+}
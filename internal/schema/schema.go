@@ -0,0 +1,239 @@
+// Package schema derives JSON Schema (draft 2020-12) documents from the Go
+// types of Helios's wire formats, so the published schemas cannot drift
+// from what the code reads and writes.
+//
+// A struct field's schema follows from its type and json tag: a field is
+// required unless it is tagged omitempty, nil slices, maps, and pointers
+// may be null, and named struct types become $defs entries. A schema tag
+// refines the result with comma-separated options:
+//
+//	optional        the field may be absent even without omitempty
+//	nullable        the field may be null
+//	pattern=RE      the string must match RE
+//	enum=A|B        the string must be one of the listed values
+//	format=NAME     an annotation such as date-time
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the $schema of every generated document.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema, or one of its subschemas.
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	ID          string             `json:"$id,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        Types              `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *int               `json:"minimum,omitempty"`
+	Encoding    string             `json:"contentEncoding,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Additional  *Schema            `json:"additionalProperties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	AnyOf       []*Schema          `json:"anyOf,omitempty"`
+	Defs        map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Types is the type keyword: one type name, or several.
+type Types []string
+
+// MarshalJSON writes a single type as a string and several as an array.
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON accepts either form.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if json.Unmarshal(data, &one) == nil {
+		*t = Types{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rawType       = reflect.TypeOf(json.RawMessage(nil))
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Generate returns the schema of the JSON encoding of v's type, with every
+// named struct type it refers to under $defs.
+func Generate(v interface{}) *Schema {
+	g := &generator{defs: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var root *Schema
+	if t.Kind() == reflect.Struct {
+		// The root is described inline, not as a $ref to itself.
+		g.names[t] = ""
+		root = g.object(t)
+	} else {
+		root = g.schema(t)
+	}
+	root.Schema = Draft
+	if len(g.defs) > 0 {
+		root.Defs = g.defs
+	}
+	return root
+}
+
+type generator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case t == rawType:
+		return &Schema{}
+	case t.Kind() != reflect.Pointer && t.Implements(marshalerType):
+		// A custom encoding says nothing the type can show.
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0
+		return &Schema{Type: Types{"integer"}, Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nullable(&Schema{Type: Types{"string"}, Encoding: "base64"})
+		}
+		return nullable(&Schema{Type: Types{"array"}, Items: g.schema(t.Elem())})
+	case reflect.Array:
+		return &Schema{Type: Types{"array"}, Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return nullable(&Schema{Type: Types{"object"}, Additional: g.schema(t.Elem())})
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Struct:
+		return g.ref(t)
+	default:
+		// interface{} holds any JSON value.
+		return &Schema{}
+	}
+}
+
+// ref returns a reference to the $defs entry of struct type t, adding it
+// on first use.
+func (g *generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if ok && name == "" {
+		return &Schema{Ref: "#"}
+	}
+	if !ok {
+		name = t.Name()
+		if name == "" {
+			return g.object(t)
+		}
+		if _, taken := g.defs[name]; taken {
+			name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + name
+		}
+		g.names[t] = name
+		g.defs[name] = nil // reserve the name while the fields are generated
+		g.defs[name] = g.object(t)
+	}
+	return &Schema{Ref: "#/$defs/" + name}
+}
+
+// object returns the schema of struct type t's fields, including those of
+// embedded structs, as encoding/json flattens them.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				g.fields(et, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := g.schema(f.Type)
+		required := !strings.Contains(","+opts+",", ",omitempty,")
+		for _, opt := range strings.Split(f.Tag.Get("schema"), ",") {
+			key, val, _ := strings.Cut(opt, "=")
+			switch key {
+			case "optional":
+				required = false
+			case "nullable":
+				fs = nullable(fs)
+			case "pattern":
+				fs.Pattern = val
+			case "enum":
+				fs.Enum = strings.Split(val, "|")
+			case "format":
+				fs.Format = val
+			}
+		}
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// nullable returns s extended to also accept null.
+func nullable(s *Schema) *Schema {
+	switch {
+	case len(s.Type) > 0:
+		for _, t := range s.Type {
+			if t == "null" {
+				return s
+			}
+		}
+		s.Type = append(s.Type, "null")
+		return s
+	case s.Ref != "":
+		return &Schema{AnyOf: []*Schema{s, {Type: Types{"null"}}}}
+	default:
+		// An empty schema already accepts null.
+		return s
+	}
+}
@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/checkpoint"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+type inner struct {
+	Name string `json:"name"`
+}
+
+type node struct {
+	Next *node `json:"next"`
+}
+
+type sample struct {
+	inner
+	ID       string          `json:"id" schema:"pattern=^[a-z]+$"`
+	Kind     string          `json:"kind" schema:"enum=a|b"`
+	Count    uint            `json:"count,omitempty"`
+	Tags     []string        `json:"tags" schema:"optional"`
+	Attrs    map[string]int  `json:"attrs,omitempty"`
+	Child    *inner          `json:"child"`
+	Node     node            `json:"node"`
+	When     time.Time       `json:"when"`
+	Any      interface{}     `json:"any"`
+	Raw      json.RawMessage `json:"raw,omitempty"`
+	Hidden   string          `json:"-"`
+	private  string
+	Untagged bool
+	Nested   map[string][]byte `json:"nested,omitempty"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(sample{})
+	if s.Schema != Draft {
+		t.Errorf("$schema = %q", s.Schema)
+	}
+	want := "name id kind child node when any Untagged"
+	if got := strings.Join(s.Required, " "); got != want {
+		t.Errorf("required = %q, want %q", got, want)
+	}
+	if _, ok := s.Properties["Hidden"]; ok {
+		t.Error(`json:"-" field was described`)
+	}
+	if _, ok := s.Properties["name"]; !ok {
+		t.Error("embedded struct fields were not flattened")
+	}
+	if p := s.Properties["id"]; p.Pattern != "^[a-z]+$" {
+		t.Errorf("id pattern = %q", p.Pattern)
+	}
+	if p := s.Properties["count"]; p.Minimum == nil || *p.Minimum != 0 {
+		t.Error("uint has no minimum of 0")
+	}
+	if p := s.Properties["child"]; len(p.AnyOf) != 2 || p.AnyOf[0].Ref != "#/$defs/inner" {
+		t.Errorf("pointer to struct: %+v", p)
+	}
+	if s.Defs["node"] == nil || s.Defs["node"].Properties["next"].AnyOf[0].Ref != "#/$defs/node" {
+		t.Error("recursive type was not described through $defs")
+	}
+	if p := s.Properties["when"]; p.Format != "date-time" {
+		t.Errorf("time.Time: %+v", p)
+	}
+	if p := s.Properties["nested"].Additional; p.Encoding != "base64" {
+		t.Errorf("[]byte: %+v", p)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := Generate(sample{})
+	valid := `{"name": "n", "id": "abc", "kind": "a", "count": 2, "child": null,
+		"node": {"next": {"next": null}}, "when": "2025-01-01T00:00:00Z", "any": [1],
+		"Untagged": true, "extra": 1}`
+	if err := Validate(s, []byte(valid)); err != nil {
+		t.Fatal(err)
+	}
+	invalid := `{"name": 1, "id": "ABC", "kind": "c", "count": -1, "child": {"name": 2},
+		"node": {"next": {"next": 5}}, "when": "x", "any": null, "tags": [1]}`
+	err := Validate(s, []byte(invalid))
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Validate = %v, want ErrInvalid", err)
+	}
+	for _, want := range []string{
+		`$: missing required field "Untagged"`,
+		"$.name: got number, want string",
+		`$.id: "ABC" does not match`,
+		`$.kind: "c" is not one of a, b`,
+		"$.count: -1 is below the minimum 0",
+		"$.child: matches none",
+		"$.node.next: matches none",
+		"$.tags[0]: got number, want string",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %q:\n%v", want, err)
+		}
+	}
+	if err := Validate(s, []byte("{")); err == nil || errors.Is(err, ErrInvalid) {
+		t.Errorf("malformed JSON: %v", err)
+	}
+}
+
+// TestDocuments checks every published schema is well formed and that
+// the payloads Helios itself produces validate against them.
+func TestDocuments(t *testing.T) {
+	reg := Registry{}
+	for _, name := range reg.Names() {
+		data, ok := reg.Get(name)
+		if !ok {
+			t.Fatalf("Get(%q) failed", name)
+		}
+		var s Schema
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if s.Title == "" || s.Description == "" {
+			t.Errorf("%s has no title or description", name)
+		}
+	}
+	if _, ok := reg.Get("nope"); ok {
+		t.Error("Get of an unknown schema succeeded")
+	}
+
+	vectors, err := os.ReadFile("../../test_vectors/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := object.MemoryObject{Key: "k", Value: "v", Category: "fact", Source: "s", CreatedAt: "2025-01-01T00:00:00.000Z"}
+	st, err := attest.NewStatement([]object.MemoryObject{obj})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &attest.Envelope{PayloadType: attest.PayloadType, Payload: "e30=", Signatures: []attest.Signature{{KeyID: "id", Sig: "c2ln"}}}
+	cp := checkpoint.New("origin", checkpoint.Snapshot{Size: 1, Root: strings.Repeat("0", 64)}, time.Now())
+	cp.Seq = 1
+	page := store.Page{Keys: []store.KeyEntry{{Key: "k", Hash: strings.Repeat("a", 64), UpdatedAt: "2025-01-01T00:00:00.000Z"}}}
+	changes := store.ChangesResponse{Changes: []store.Change{{Seq: 1, Op: store.ChangePut, Key: "k", New: strings.Repeat("b", 64)}}, Next: 1}
+	quota := store.QuotaErrorResponse{Code: "STORE_ERR_QUOTA_EXCEEDED", Error: "over", QuotaError: &store.QuotaError{Limit: "objects", Max: 1, Used: 1, Requested: 2}}
+
+	for name, v := range map[string]interface{}{
+		"memory-object": obj,
+		"statement":     st,
+		"envelope":      env,
+		"checkpoint":    cp,
+		"key-page":      page,
+		"changes":       changes,
+		"quota-error":   quota,
+		"similar":       []store.Match{{Key: "k", Hash: "h", Score: 0.5}},
+		"health":        store.HealthResponse{Status: "unavailable", Checks: []store.HealthCheck{{Name: "store", OK: true}, {Name: "selftest", Error: "vectors failed"}}},
+	} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, _ := Document(name)
+		if err := Validate(s, data); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	s, _ := Document("vectors-file")
+	if err := Validate(s, vectors); err != nil {
+		t.Errorf("vectors.json: %v", err)
+	}
+	s, _ = Document("memory-object")
+	if err := Validate(s, []byte(`{"key": "k", "value": 1, "category": "c", "source": "s"}`)); err == nil {
+		t.Error("an object without created_at validated")
+	}
+}
@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrInvalid is wrapped by the errors Validate returns for a document that
+// does not match its schema.
+var ErrInvalid = errors.New("document does not match the schema")
+
+// Validate checks the JSON document data against root. It understands the
+// keywords Generate writes, which is enough to check Helios's own
+// payloads; it is not a general JSON Schema validator. Every mismatch is
+// reported, each prefixed with the JSONPath of the value.
+func Validate(root *Schema, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	v := &validator{root: root, patterns: make(map[string]*regexp.Regexp)}
+	v.check(root, doc, "$")
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n  %s", ErrInvalid, strings.Join(v.errs, "\n  "))
+}
+
+type validator struct {
+	root     *Schema
+	patterns map[string]*regexp.Regexp
+	errs     []string
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) check(s *Schema, val interface{}, path string) {
+	if s.Ref != "" {
+		target, err := v.resolve(s.Ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.check(target, val, path)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, alt := range s.AnyOf {
+			sub := &validator{root: v.root, patterns: v.patterns}
+			sub.check(alt, val, path)
+			if len(sub.errs) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "matches none of the allowed schemas")
+		}
+	}
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(val, t) }) {
+		v.fail(path, "got %s, want %s", typeName(val), strings.Join(s.Type, " or "))
+		return
+	}
+
+	switch x := val.(type) {
+	case string:
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, x) {
+			v.fail(path, "%q is not one of %s", x, strings.Join(s.Enum, ", "))
+		}
+		if s.Pattern != "" {
+			re, err := v.pattern(s.Pattern)
+			if err != nil {
+				v.fail(path, "invalid pattern %q in schema: %v", s.Pattern, err)
+			} else if !re.MatchString(x) {
+				v.fail(path, "%q does not match %s", x, s.Pattern)
+			}
+		}
+	case json.Number:
+		if s.Minimum != nil {
+			if f, err := x.Float64(); err == nil && f < float64(*s.Minimum) {
+				v.fail(path, "%s is below the minimum %d", x, *s.Minimum)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range x {
+				v.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok {
+				v.fail(path, "missing required field %q", name)
+			}
+		}
+		names := make([]string, 0, len(x))
+		for name := range x {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sub := path + "." + name
+			if p, ok := s.Properties[name]; ok {
+				v.check(p, x[name], sub)
+			} else if s.Additional != nil {
+				v.check(s.Additional, x[name], sub)
+			}
+		}
+	}
+}
+
+func (v *validator) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if s := v.root.Defs[name]; ok && s != nil {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unresolvable $ref %q in schema", ref)
+}
+
+func (v *validator) pattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	v.patterns[expr] = re
+	return re, nil
+}
+
+func hasType(val interface{}, t string) bool {
+	switch x := val.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		_, err := x.Int64()
+		return t == "integer" && err == nil
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	}
+	return false
+}
+
+func typeName(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
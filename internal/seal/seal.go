@@ -0,0 +1,336 @@
+// Package seal encrypts the values of confidential memory objects to
+// X25519 recipients while keeping their content hash verifiable. A sealed
+// envelope carries the object's other fields and its content hash in the
+// clear, so stores, checkpoints, and attestations can refer to it, and the
+// value encrypted; holders of a recipient's private key open it and check
+// the decrypted object against the hash.
+//
+// Recipients follow the construction of age's X25519 recipients: a fresh
+// random file key encrypts the object, and is wrapped for each recipient
+// under a key derived with HKDF-SHA256 from an X25519 exchange with a
+// fresh ephemeral key. Encryption is AES-256-GCM rather than age's
+// ChaCha20-Poly1305, which is not in the standard library, so envelopes
+// are not age files and keys are PEM, as `openssl genpkey -algorithm
+// x25519` writes them.
+package seal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Version identifies the envelope format.
+const Version = "helios-sealed/v1"
+
+// StanzaX25519 is the type of a recipient stanza wrapped for an X25519 key.
+const StanzaX25519 = "X25519"
+
+// wrapInfo is the HKDF info of a recipient's wrapping key.
+const wrapInfo = "helios-sealed/v1 X25519"
+
+// ErrNoIdentity is returned by Open when none of the identities is a
+// recipient of the envelope.
+var ErrNoIdentity = errors.New("sealed envelope is not encrypted to any of the identities")
+
+// Envelope is a memory object with its value encrypted.
+type Envelope struct {
+	// Sealed is the format, Version.
+	Sealed string `json:"sealed"`
+	// Object is the sealed object without its value.
+	Object object.MemoryObject `json:"object"`
+	// ContentHash is the content hash of the object with its value,
+	// under Profile.
+	ContentHash string `json:"content_hash"`
+	Profile     string `json:"profile"`
+	// Recipients hold the file key wrapped for each recipient.
+	Recipients []Stanza `json:"recipients"`
+	// Nonce and Ciphertext are the object's JSON encrypted under the file
+	// key, unpadded base64url.
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Stanza is the file key wrapped for one recipient. Like age's, it does
+// not name the recipient.
+type Stanza struct {
+	Type string `json:"type"`
+	// Ephemeral is the X25519 public key of the exchange.
+	Ephemeral string `json:"ephemeral"`
+	// WrappedKey is the file key encrypted under the derived key.
+	WrappedKey string `json:"wrapped_key"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// Seal encrypts obj to recipients under the current profile. The content
+// hash it records is over the plaintext, so anyone can compare it with a
+// hash published elsewhere, and one who guesses a value of little entropy
+// can confirm the guess by hashing it; a commitment (hash.Commit) hides
+// such values, and the recipients' keys alone reveal them.
+func Seal(obj object.MemoryObject, recipients ...*ecdh.PublicKey) (*Envelope, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	pipeline := hash.Current()
+	contentHash, err := pipeline.ContentHash(obj)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fileKey := make([]byte, 32)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+	defer clear(fileKey)
+
+	env := &Envelope{Sealed: Version, Object: obj, ContentHash: contentHash, Profile: pipeline.Profile.ID()}
+	env.Object.Value = nil
+	for _, r := range recipients {
+		s, err := wrap(fileKey, r)
+		if err != nil {
+			return nil, err
+		}
+		env.Recipients = append(env.Recipients, s)
+	}
+	aead, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	env.Nonce = b64.EncodeToString(nonce)
+	env.Ciphertext = b64.EncodeToString(aead.Seal(nil, nonce, plaintext, env.header()))
+	return env, nil
+}
+
+// Open decrypts env with the first of identities it is encrypted to, and
+// checks that the object has the envelope's content hash and the fields
+// it shows in the clear.
+func Open(env *Envelope, identities ...*ecdh.PrivateKey) (object.MemoryObject, error) {
+	if env.Sealed != Version {
+		return object.MemoryObject{}, fmt.Errorf("unsupported sealed envelope version %q", env.Sealed)
+	}
+	fileKey, err := env.unwrap(identities)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+	defer clear(fileKey)
+	aead, err := newGCM(fileKey)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+	nonce, err := b64.DecodeString(env.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return object.MemoryObject{}, fmt.Errorf("invalid nonce")
+	}
+	ciphertext, err := b64.DecodeString(env.Ciphertext)
+	if err != nil {
+		return object.MemoryObject{}, fmt.Errorf("invalid ciphertext encoding: %w", err)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, env.header())
+	if err != nil {
+		return object.MemoryObject{}, fmt.Errorf("sealed envelope has been tampered with: %w", err)
+	}
+	obj, err := ingest.ParseObject(plaintext)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+
+	pipeline, err := hash.LookupID(env.Profile)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+	got, err := pipeline.ContentHash(obj)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+	if !hash.Equal(env.ContentHash, got) {
+		return object.MemoryObject{}, fmt.Errorf("decrypted object hashes to %s, not the sealed content hash %s", got, env.ContentHash)
+	}
+	clearFields := obj
+	clearFields.Value = nil
+	want, err := json.Marshal(clearFields)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+	shown, err := json.Marshal(env.Object)
+	if err != nil {
+		return object.MemoryObject{}, err
+	}
+	if !bytes.Equal(want, shown) {
+		return object.MemoryObject{}, fmt.Errorf("the fields shown in the clear do not match the sealed object %q", obj.Key)
+	}
+	return obj, nil
+}
+
+// header returns the additional data the payload's seal authenticates:
+// the version, profile, content hash, and recipient stanzas, one per
+// line, so none can be swapped without failing to open.
+func (env *Envelope) header() []byte {
+	var b bytes.Buffer
+	b.WriteString(Version + "\n" + env.Profile + "\n" + env.ContentHash + "\n")
+	for _, s := range env.Recipients {
+		b.WriteString(s.Type + " " + s.Ephemeral + " " + s.WrappedKey + "\n")
+	}
+	return b.Bytes()
+}
+
+// wrap wraps fileKey for recipient.
+func wrap(fileKey []byte, recipient *ecdh.PublicKey) (Stanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return Stanza{}, err
+	}
+	share, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return Stanza{}, err
+	}
+	aead, err := wrapAEAD(share, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+	if err != nil {
+		return Stanza{}, err
+	}
+	wrapped := aead.Seal(nil, make([]byte, aead.NonceSize()), fileKey, nil)
+	return Stanza{
+		Type:       StanzaX25519,
+		Ephemeral:  b64.EncodeToString(ephemeral.PublicKey().Bytes()),
+		WrappedKey: b64.EncodeToString(wrapped),
+	}, nil
+}
+
+// unwrap returns the file key from the first stanza one of identities
+// opens.
+func (env *Envelope) unwrap(identities []*ecdh.PrivateKey) ([]byte, error) {
+	for _, s := range env.Recipients {
+		if s.Type != StanzaX25519 {
+			continue
+		}
+		eph, err := b64.DecodeString(s.Ephemeral)
+		if err != nil {
+			continue
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(eph)
+		if err != nil {
+			continue
+		}
+		wrapped, err := b64.DecodeString(s.WrappedKey)
+		if err != nil {
+			continue
+		}
+		for _, id := range identities {
+			share, err := id.ECDH(ephemeral)
+			if err != nil {
+				continue
+			}
+			aead, err := wrapAEAD(share, eph, id.PublicKey().Bytes())
+			if err != nil {
+				return nil, err
+			}
+			if fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), wrapped, nil); err == nil {
+				return fileKey, nil
+			}
+		}
+	}
+	return nil, ErrNoIdentity
+}
+
+// wrapAEAD derives the key wrapping a file key for one recipient from the
+// exchange's shared secret, salted with both public keys. Each wrapping
+// key is used once, so its nonce is zero.
+func wrapAEAD(share, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	key, err := hkdf.Key(sha256.New, share, salt, wrapInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	return newGCM(key)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+// ParseRecipient decodes a PEM-encoded PKIX X25519 public key.
+func ParseRecipient(data []byte) (*ecdh.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("expected a PEM PUBLIC KEY block")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*ecdh.PublicKey)
+	if !ok || pub.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("recipient is a %T, not an X25519 key", key)
+	}
+	return pub, nil
+}
+
+// ParseIdentity decodes a PEM-encoded PKCS#8 X25519 private key.
+func ParseIdentity(data []byte) (*ecdh.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("expected a PEM PRIVATE KEY block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(*ecdh.PrivateKey)
+	if !ok || priv.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("identity is a %T, not an X25519 key", key)
+	}
+	return priv, nil
+}
+
+// LoadRecipient reads a PEM X25519 public key.
+func LoadRecipient(path string) (*ecdh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipient: %w", err)
+	}
+	pub, err := ParseRecipient(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pub, nil
+}
+
+// LoadIdentity reads a PEM X25519 private key.
+func LoadIdentity(path string) (*ecdh.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity: %w", err)
+	}
+	priv, err := ParseIdentity(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return priv, nil
+}
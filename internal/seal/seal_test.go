@@ -0,0 +1,144 @@
+package seal
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func testObject() object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "patient",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           "patient/42/diagnosis",
+		Relationships: []object.Relationship{},
+		Source:        "clinic",
+		Value:         map[string]interface{}{"code": "E11.9", "note": "confidential"},
+	}
+}
+
+func testIdentity(t *testing.T) *ecdh.PrivateKey {
+	t.Helper()
+	id, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// roundTrip passes env through its JSON encoding, as a file would.
+func roundTrip(t *testing.T, env *Envelope) *Envelope {
+	t.Helper()
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Envelope
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	return &back
+}
+
+func TestSealOpen(t *testing.T) {
+	alice, bob, eve := testIdentity(t), testIdentity(t), testIdentity(t)
+	obj := testObject()
+	env, err := Seal(obj, alice.PublicKey(), bob.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := hash.ContentHash(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.ContentHash != want {
+		t.Errorf("sealed content hash %s, want the plaintext's %s", env.ContentHash, want)
+	}
+	data, _ := json.Marshal(env)
+	if strings.Contains(string(data), "confidential") || strings.Contains(string(data), "E11.9") {
+		t.Errorf("the value appears in the clear: %s", data)
+	}
+
+	for name, id := range map[string]*ecdh.PrivateKey{"alice": alice, "bob": bob} {
+		got, err := Open(roundTrip(t, env), eve, id)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if h, _ := hash.ContentHash(got); h != want {
+			t.Errorf("%s: opened object hashes to %s, want %s", name, h, want)
+		}
+	}
+	if _, err := Open(roundTrip(t, env), eve); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("open by a non-recipient: got %v", err)
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	id := testIdentity(t)
+	env, err := Seal(testObject(), id.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tamper := range map[string]func(*Envelope){
+		"content hash": func(e *Envelope) { e.ContentHash = strings.Repeat("0", 64) },
+		"clear field":  func(e *Envelope) { e.Object.Category = "public" },
+		"ciphertext": func(e *Envelope) {
+			c := []byte(e.Ciphertext)
+			c[0] ^= 'A' ^ 'B'
+			e.Ciphertext = string(c)
+		},
+		"swapped stanzas": func(e *Envelope) {
+			other, err := Seal(testObject(), id.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.Recipients = other.Recipients
+		},
+	} {
+		e := roundTrip(t, env)
+		tamper(e)
+		if _, err := Open(e, id); err == nil {
+			t.Errorf("%s: opened a tampered envelope", name)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	id := testIdentity(t)
+	der, err := x509.MarshalPKCS8PrivateKey(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseIdentity(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || !parsed.Equal(id) {
+		t.Fatalf("ParseIdentity = %v", err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(id.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParseRecipient(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || !pub.Equal(id.PublicKey()) {
+		t.Fatalf("ParseRecipient = %v", err)
+	}
+
+	p256, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(p256.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseRecipient(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})); err == nil {
+		t.Error("accepted a P-256 recipient")
+	}
+}
@@ -0,0 +1,295 @@
+// Package search is a keyword index over memory values. It maps the words
+// of each key's current value to that key, so a query finds memories
+// without exporting and grepping the store.
+//
+// The index is a journal of JSON lines, one per indexed or removed key,
+// replayed into memory by Open. Every write appends to the journal, so the
+// index survives restarts without an explicit save; Rebuild rewrites it
+// from scratch. The index is an aid to finding things, not a record: it
+// is never consulted for integrity, and a lost or stale index is repaired
+// by rebuilding it from the store.
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+)
+
+// Hit is one key matching a query.
+type Hit struct {
+	Tenant string `json:"tenant,omitempty"`
+	Key    string `json:"key"`
+	Hash   string `json:"hash"`
+	// Score is the number of occurrences of the query's words in the
+	// value.
+	Score int `json:"score"`
+}
+
+// doc is the indexed state of one key.
+type doc struct {
+	tenant string
+	key    string
+	hash   string
+	terms  map[string]int
+}
+
+// entry is one journal line.
+type entry struct {
+	Op     string         `json:"op"`
+	Tenant string         `json:"tenant,omitempty"`
+	Key    string         `json:"key"`
+	Hash   string         `json:"hash,omitempty"`
+	Terms  map[string]int `json:"terms,omitempty"`
+}
+
+// Journal operations.
+const (
+	opIndex  = "index"
+	opRemove = "remove"
+)
+
+// Index is an inverted index from words to keys. It implements
+// store.Indexer and is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	f        *os.File
+	docs     map[string]*doc
+	postings map[string]map[string]bool
+}
+
+// Open opens the index journal at path, creating it if it does not exist.
+// A torn final line, left by a crash mid-append, is dropped.
+func Open(path string) (*Index, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	idx := &Index{f: f, docs: make(map[string]*doc), postings: make(map[string]map[string]bool)}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	var good int64
+	for line := 1; sc.Scan(); line++ {
+		var e entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			// Only the last line may be torn.
+			if sc.Scan() {
+				f.Close()
+				return nil, fmt.Errorf("search index %s: line %d: %w", path, line, err)
+			}
+			break
+		}
+		idx.apply(e)
+		good += int64(len(sc.Bytes())) + 1
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("search index %s: %w", path, err)
+	}
+	// Drop a torn line, and end a complete last line that lost its
+	// newline, so the next append starts a line of its own.
+	fi, err := f.Stat()
+	if err == nil {
+		switch {
+		case fi.Size() > good:
+			err = f.Truncate(good)
+		case fi.Size() < good:
+			_, err = f.Write([]byte("\n"))
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Close closes the journal.
+func (idx *Index) Close() error {
+	return idx.f.Close()
+}
+
+// Len returns the number of indexed keys.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+func docID(tenant, key string) string { return tenant + "\x00" + key }
+
+// Index records that key in tenant ("" for the default namespace) now
+// holds the object hash with the given canonical bytes.
+func (idx *Index) Index(ctx context.Context, tenant, key, hash string, canonical []byte) error {
+	terms, err := valueTerms(canonical)
+	if err != nil {
+		return err
+	}
+	return idx.write(entry{Op: opIndex, Tenant: tenant, Key: key, Hash: hash, Terms: terms})
+}
+
+// Remove records that key in tenant was deleted.
+func (idx *Index) Remove(ctx context.Context, tenant, key string) error {
+	return idx.write(entry{Op: opRemove, Tenant: tenant, Key: key})
+}
+
+func (idx *Index) write(e entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, err := idx.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	idx.apply(e)
+	return nil
+}
+
+// apply updates the in-memory index; idx.mu must be held for writing, or
+// idx not yet shared.
+func (idx *Index) apply(e entry) {
+	id := docID(e.Tenant, e.Key)
+	if old, ok := idx.docs[id]; ok {
+		for t := range old.terms {
+			delete(idx.postings[t], id)
+			if len(idx.postings[t]) == 0 {
+				delete(idx.postings, t)
+			}
+		}
+		delete(idx.docs, id)
+	}
+	if e.Op != opIndex {
+		return
+	}
+	idx.docs[id] = &doc{tenant: e.Tenant, key: e.Key, hash: e.Hash, terms: e.Terms}
+	for t := range e.Terms {
+		if idx.postings[t] == nil {
+			idx.postings[t] = make(map[string]bool)
+		}
+		idx.postings[t][id] = true
+	}
+}
+
+// Search returns the keys of tenant whose values contain every word of
+// query, best first: by score, then by key. A query without words matches
+// nothing.
+func (idx *Index) Search(tenant, query string) []Hit {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// Walk the rarest word's postings and check the others.
+	sort.Slice(words, func(i, j int) bool { return len(idx.postings[words[i]]) < len(idx.postings[words[j]]) })
+	var hits []Hit
+	for id := range idx.postings[words[0]] {
+		d := idx.docs[id]
+		if d.tenant != tenant {
+			continue
+		}
+		score := 0
+		for _, w := range words {
+			n := d.terms[w]
+			if n == 0 {
+				score = 0
+				break
+			}
+			score += n
+		}
+		if score > 0 {
+			hits = append(hits, Hit{Tenant: d.tenant, Key: d.key, Hash: d.hash, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Key < hits[j].Key
+	})
+	return hits
+}
+
+// Rebuild replaces the journal at path with a fresh one holding only what
+// fill indexes into it, so stale entries and superseded lines are dropped.
+// The old journal is replaced only if fill succeeds.
+func Rebuild(path string, fill func(*Index) error) error {
+	tmp := path + ".rebuild"
+	os.Remove(tmp)
+	idx, err := Open(tmp)
+	if err != nil {
+		return err
+	}
+	if err := fill(idx); err != nil {
+		idx.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := idx.f.Sync(); err != nil {
+		idx.Close()
+		return err
+	}
+	if err := idx.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Tokenize splits text into index words: NFC-normalized, lower-cased runs
+// of letters and digits.
+func Tokenize(text string) []string {
+	text = strings.ToLower(canon.NormalizeString(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// valueTerms counts the words of the value of a canonical object: its
+// strings, numbers, and object member names.
+func valueTerms(canonical []byte) (map[string]int, error) {
+	dec := json.NewDecoder(bytes.NewReader(canonical))
+	dec.UseNumber()
+	var obj struct {
+		Value interface{} `json:"value"`
+	}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("search: object is not valid JSON: %w", err)
+	}
+	terms := make(map[string]int)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			for _, w := range Tokenize(v) {
+				terms[w]++
+			}
+		case json.Number:
+			for _, w := range Tokenize(v.String()) {
+				terms[w]++
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]interface{}:
+			for k, e := range v {
+				walk(k)
+				walk(e)
+			}
+		}
+	}
+	walk(obj.Value)
+	return terms, nil
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+func memory(key string, value interface{}) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "user",
+		Value:         value,
+	}
+}
+
+func keys(hits []Hit) []string {
+	out := []string{}
+	for _, h := range hits {
+		out = append(out, h.Key)
+	}
+	return out
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Error-budget: 99.9% CAFÉ, café")
+	want := []string{"error", "budget", "99", "9", "café", "café"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+}
+
+func TestIndexMaintainedByStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "search.jsonl")
+	idx, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Indexer: idx})
+
+	put := func(s *store.Store, key string, value interface{}) string {
+		t.Helper()
+		h, err := s.Put(ctx, memory(key, value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	h := put(s, "slo/api", "The error budget for the API is 0.1% per month")
+	put(s, "slo/web", map[string]interface{}{"note": "Error budget burned; error rate high"})
+	put(s, "misc", "budget meeting moved")
+	acme, err := s.Tenant(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	put(acme, "slo/api", "error budget for acme")
+
+	if got := keys(idx.Search("", "error budget")); !reflect.DeepEqual(got, []string{"slo/web", "slo/api"}) {
+		t.Errorf(`Search("error budget") = %v`, got)
+	}
+	if hits := idx.Search("", "month"); len(hits) != 1 || hits[0].Hash != h {
+		t.Errorf(`Search("month") = %+v`, hits)
+	}
+	if got := keys(idx.Search("acme", "budget")); !reflect.DeepEqual(got, []string{"slo/api"}) {
+		t.Errorf("tenant search = %v", got)
+	}
+	if hits := idx.Search("", "  ,. "); hits != nil {
+		t.Errorf("empty query matched %v", hits)
+	}
+
+	put(s, "slo/api", "replaced")
+	if err := s.Delete(ctx, "misc"); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(idx.Search("", "budget")); !reflect.DeepEqual(got, []string{"slo/web"}) {
+		t.Errorf("after overwrite and delete: %v", got)
+	}
+	idx.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if got := keys(reopened.Search("", "budget")); !reflect.DeepEqual(got, []string{"slo/web"}) || reopened.Len() != 3 {
+		t.Errorf("after reopen: %v, %d keys", got, reopened.Len())
+	}
+}
+
+func TestOpenTornJournal(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "search.jsonl")
+	idx, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical := []byte(`{"key":"a","value":"hello world"}`)
+	if err := idx.Index(ctx, "", "a", "h1", canonical); err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"op":"index","key":"b","ter`)
+	f.Close()
+
+	idx, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Index(ctx, "", "c", "h2", []byte(`{"key":"c","value":"hello again"}`)); err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+	idx, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	if got := keys(idx.Search("", "hello")); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("after torn tail: %v", got)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.jsonl")
+	os.WriteFile(bad, []byte("garbage\n{\"op\":\"remove\",\"key\":\"a\"}\n"), 0o644)
+	if _, err := Open(bad); err == nil {
+		t.Error("Open accepted a corrupt line before the tail")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "search.jsonl")
+	idx, _ := Open(path)
+	idx.Index(ctx, "", "stale", "h0", []byte(`{"value":"old words"}`))
+	idx.Close()
+
+	err := Rebuild(path, func(idx *Index) error {
+		return idx.Index(ctx, "", "fresh", "h1", []byte(`{"value":"new words"}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	if got := keys(idx.Search("", "words")); !reflect.DeepEqual(got, []string{"fresh"}) {
+		t.Errorf("after rebuild: %v", got)
+	}
+}
@@ -0,0 +1,131 @@
+// Package shamir splits secrets into shares with Shamir's secret sharing
+// over GF(2^8): any threshold of the shares recover the secret, and fewer
+// reveal nothing about it. Each byte of the secret is the constant term
+// of its own random polynomial of degree threshold-1, and share x holds
+// every polynomial's value at x.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// MaxShares is the most shares a secret can be split into: the nonzero
+// elements of GF(2^8).
+const MaxShares = 255
+
+// Share is one share of a secret.
+type Share struct {
+	// X is the share's point, 1 to 255.
+	X byte
+	// Y holds the value at X of the polynomial of each byte of the
+	// secret, so it is as long as the secret.
+	Y []byte
+}
+
+// Split splits secret into n shares, any k of which recover it. It
+// requires 2 <= k <= n <= MaxShares and a nonempty secret.
+func Split(secret []byte, n, k int) ([]Share, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, errors.New("shamir: empty secret")
+	case k < 2:
+		return nil, fmt.Errorf("shamir: threshold must be at least 2, got %d", k)
+	case n < k:
+		return nil, fmt.Errorf("shamir: %d shares cannot meet a threshold of %d", n, k)
+	case n > MaxShares:
+		return nil, fmt.Errorf("shamir: at most %d shares, got %d", MaxShares, n)
+	}
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Y: make([]byte, len(secret))}
+	}
+	coeffs := make([]byte, k)
+	for j, s := range secret {
+		coeffs[0] = s
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("shamir: %w", err)
+		}
+		for i := range shares {
+			shares[i].Y[j] = evaluate(coeffs, shares[i].X)
+		}
+	}
+	clear(coeffs)
+	return shares, nil
+}
+
+// Combine recovers a secret from at least the threshold of its shares.
+// Fewer shares, or shares of different splits, give a wrong secret
+// without an error, so callers check the result; it fails only if the
+// shares are malformed or repeat a point.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("shamir: at least 2 shares are needed, got %d", len(shares))
+	}
+	size := len(shares[0].Y)
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if s.X == 0 {
+			return nil, errors.New("shamir: share at point 0")
+		}
+		if seen[s.X] {
+			return nil, fmt.Errorf("shamir: two shares at point %d", s.X)
+		}
+		seen[s.X] = true
+		if len(s.Y) != size || size == 0 {
+			return nil, errors.New("shamir: shares of different lengths")
+		}
+	}
+
+	// Lagrange interpolation at 0: secret = sum of y_i * l_i(0), where
+	// l_i(0) is the product over j != i of x_j / (x_j - x_i), and
+	// subtraction in GF(2^8) is addition, XOR.
+	secret := make([]byte, size)
+	for i, si := range shares {
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = mul(basis, div(sj.X, sj.X^si.X))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= mul(si.Y[b], basis)
+		}
+	}
+	return secret, nil
+}
+
+// evaluate returns the polynomial with coefficients coeffs, constant
+// term first, at x, by Horner's rule.
+func evaluate(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// mul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x + 1, the AES
+// polynomial, without branching on its operands, so the time it takes
+// does not depend on the secret.
+func mul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= a & -(b & 1)
+		carry := -(a >> 7)
+		a = a<<1 ^ 0x1b&carry
+		b >>= 1
+	}
+	return p
+}
+
+// div divides a by b, which must be nonzero, in GF(2^8): a * b^254,
+// since b^255 = 1.
+func div(a, b byte) byte {
+	inv := b
+	for range 6 {
+		inv = mul(mul(inv, inv), b)
+	}
+	return mul(a, mul(inv, inv))
+}
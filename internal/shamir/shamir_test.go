@@ -0,0 +1,54 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFieldArithmetic(t *testing.T) {
+	// 0x53 and 0xca are inverses under the AES polynomial (FIPS 197).
+	if got := mul(0x53, 0xca); got != 1 {
+		t.Errorf("mul(0x53, 0xca) = %#x, want 1", got)
+	}
+	for a := 0; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if got := mul(div(byte(a), byte(b)), byte(b)); got != byte(a) {
+				t.Fatalf("div(%d, %d) * %d = %d", a, b, b, got)
+			}
+		}
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("a signing key of thirty-two byte")
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var pick []Share
+		for _, i := range subset {
+			pick = append(pick, shares[i])
+		}
+		got, err := Combine(pick)
+		if err != nil || !bytes.Equal(got, secret) {
+			t.Errorf("Combine(%v) = %q, %v", subset, got, err)
+		}
+	}
+	if got, _ := Combine(shares[:2]); bytes.Equal(got, secret) {
+		t.Error("two shares recovered a secret split with threshold 3")
+	}
+
+	if _, err := Combine([]Share{shares[0], shares[0]}); err == nil {
+		t.Error("a repeated share was accepted")
+	}
+	short := Share{X: shares[1].X, Y: shares[1].Y[:4]}
+	if _, err := Combine([]Share{shares[0], short}); err == nil {
+		t.Error("shares of different lengths were accepted")
+	}
+	for _, c := range []struct{ n, k int }{{5, 1}, {2, 3}, {256, 2}} {
+		if _, err := Split(secret, c.n, c.k); err == nil {
+			t.Errorf("Split(n=%d, k=%d) accepted", c.n, c.k)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Key algorithms GenerateKey accepts.
+const (
+	AlgorithmEd25519   = "ed25519"
+	AlgorithmECDSAP256 = "ecdsa-p256"
+)
+
+// GenerateKey returns a new private key of algorithm.
+func GenerateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	case AlgorithmECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	return nil, fmt.Errorf("unsupported key algorithm %q (want %s or %s)", algorithm, AlgorithmEd25519, AlgorithmECDSAP256)
+}
+
+// Algorithm names the algorithm of a public key.
+func Algorithm(pub crypto.PublicKey) (string, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return AlgorithmEd25519, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return AlgorithmECDSAP256, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported public key type %T", pub)
+}
+
+// Fingerprint returns the fingerprint of pub: its algorithm and key ID,
+// as "ed25519 SHA256:<key ID>". The key ID is the SHA-256 of the public
+// key's PKIX DER encoding, so anyone holding the PEM public key can
+// recompute it with
+//
+//	openssl pkey -pubin -in key.pub -outform DER | sha256sum
+func Fingerprint(pub crypto.PublicKey) (string, error) {
+	alg, err := Algorithm(pub)
+	if err != nil {
+		return "", err
+	}
+	id, err := KeyID(pub)
+	if err != nil {
+		return "", err
+	}
+	return alg + " SHA256:" + id, nil
+}
+
+// encryptedBlockType is the PEM block type of a private key encrypted
+// by EncryptPrivateKey.
+const encryptedBlockType = "HELIOS ENCRYPTED PRIVATE KEY"
+
+// pbkdf2Iterations is the PBKDF2-HMAC-SHA256 work factor of new
+// encrypted keys; the count is recorded in each key, so it can be raised
+// without breaking older ones. Tests lower it.
+var pbkdf2Iterations = 600000
+
+// maxPBKDF2Iterations bounds the work factor a key file may ask for, so a
+// crafted file cannot tie up a CPU before its seal is checked.
+const maxPBKDF2Iterations = 10000000
+
+// ErrPassphrase is returned for an encrypted private key when no
+// passphrase is given, or it is wrong.
+var ErrPassphrase = errors.New("wrong or missing passphrase for encrypted private key")
+
+// EncryptPrivateKey returns priv's PKCS#8 encoding encrypted at rest
+// under passphrase, as a PEM "HELIOS ENCRYPTED PRIVATE KEY" block: the key
+// is sealed with AES-256-GCM under a key derived from the passphrase by
+// PBKDF2-HMAC-SHA256 with a random salt. The block's headers carry the
+// KDF parameters and the public key, which the seal authenticates, so
+// the public key and fingerprint can be read without the passphrase.
+func EncryptPrivateKey(priv crypto.Signer, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(der)
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	block := &pem.Block{
+		Type: encryptedBlockType,
+		Headers: map[string]string{
+			"KDF":        "pbkdf2-sha256",
+			"Iterations": strconv.Itoa(pbkdf2Iterations),
+			"Salt":       base64.StdEncoding.EncodeToString(salt),
+			"Cipher":     "aes-256-gcm",
+			"Public-Key": base64.StdEncoding.EncodeToString(pubDER),
+		},
+	}
+	aead, err := keyAEAD(passphrase, salt, pbkdf2Iterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	block.Headers["Nonce"] = base64.StdEncoding.EncodeToString(nonce)
+	block.Bytes = aead.Seal(nil, nonce, der, sealedHeaders(block))
+	return pem.EncodeToMemory(block), nil
+}
+
+// DecryptPrivateKey opens a key EncryptPrivateKey encrypted.
+func DecryptPrivateKey(data, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptedBlockType {
+		return nil, fmt.Errorf("expected a PEM %q block", encryptedBlockType)
+	}
+	return decryptBlock(block, passphrase)
+}
+
+func decryptBlock(block *pem.Block, passphrase []byte) (crypto.Signer, error) {
+	h := block.Headers
+	if h["KDF"] != "pbkdf2-sha256" || h["Cipher"] != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported key encryption %s/%s", h["KDF"], h["Cipher"])
+	}
+	iter, err := strconv.Atoi(h["Iterations"])
+	if err != nil || iter < 1 || iter > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("invalid PBKDF2 iteration count %q (want 1 to %d)", h["Iterations"], maxPBKDF2Iterations)
+	}
+	salt, err := base64.StdEncoding.DecodeString(h["Salt"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(h["Nonce"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, ErrPassphrase
+	}
+	aead, err := keyAEAD(passphrase, salt, iter)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+	der, err := aead.Open(nil, nonce, block.Bytes, sealedHeaders(block))
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	defer clear(der)
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return priv, nil
+}
+
+// keyAEAD derives the AES-256-GCM key of an encrypted private key.
+func keyAEAD(passphrase, salt []byte, iter int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iter, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+// sealedHeaders returns the additional data an encrypted key's seal
+// authenticates: every header but the nonce, sorted, one per line, so
+// swapping the public key or weakening the parameters fails to open.
+func sealedHeaders(block *pem.Block) []byte {
+	var b bytes.Buffer
+	b.WriteString(encryptedBlockType + "\n")
+	for _, k := range []string{"Cipher", "Iterations", "KDF", "Public-Key", "Salt"} {
+		b.WriteString(k + ": " + block.Headers[k] + "\n")
+	}
+	return b.Bytes()
+}
+
+// Passphrase returns the passphrase for encrypted private keys: the
+// contents of the file $HELIOS_KEY_PASSPHRASE_FILE names, without a
+// trailing newline, or else $HELIOS_KEY_PASSPHRASE. It is nil if neither
+// is set.
+func Passphrase() ([]byte, error) {
+	if path := os.Getenv("HELIOS_KEY_PASSPHRASE_FILE"); path != "" {
+		return ReadPassphrase(path)
+	}
+	if p := os.Getenv("HELIOS_KEY_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	return nil, nil
+}
+
+// ReadPassphrase reads a passphrase from a file, dropping one trailing
+// newline.
+func ReadPassphrase(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return []byte(strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")), nil
+}
+
+// PublicKeyOf returns the public key of a PEM private key file's
+// contents, encrypted or not, or of a PEM public key. An encrypted key's
+// public key is read from its headers, without the passphrase.
+func PublicKeyOf(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("expected a PEM block")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		// Any key with a public half, including X25519 sealing identities.
+		priv, ok := key.(interface{ Public() crypto.PublicKey })
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return priv.Public(), nil
+	case encryptedBlockType:
+		der, err := base64.StdEncoding.DecodeString(block.Headers["Public-Key"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Public-Key header: %w", err)
+		}
+		return x509.ParsePKIXPublicKey(der)
+	}
+	return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+}
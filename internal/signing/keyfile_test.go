@@ -0,0 +1,115 @@
+package signing
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The work factor only slows the tests; keys record the count they were
+// encrypted with.
+func init() { pbkdf2Iterations = 1000 }
+
+func TestEncryptedPrivateKey(t *testing.T) {
+	for _, alg := range []string{AlgorithmEd25519, AlgorithmECDSAP256} {
+		priv, err := GenerateKey(alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := KeyID(priv.Public())
+		data, err := EncryptPrivateKey(priv, []byte("correct horse"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, _ := EncodePrivateKey(priv)
+		if bytes.Contains(data, der[30:60]) {
+			t.Fatal("the encrypted file contains the plain key")
+		}
+
+		got, err := DecryptPrivateKey(data, []byte("correct horse"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, _ := KeyID(got.Public()); id != want {
+			t.Errorf("%s: decrypted key %s, want %s", alg, id, want)
+		}
+		if _, err := DecryptPrivateKey(data, []byte("wrong")); !errors.Is(err, ErrPassphrase) {
+			t.Errorf("%s: wrong passphrase: %v", alg, err)
+		}
+
+		pub, err := PublicKeyOf(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp, err := Fingerprint(pub)
+		if err != nil || fp != alg+" SHA256:"+want {
+			t.Errorf("Fingerprint = %q, %v", fp, err)
+		}
+	}
+}
+
+func TestEncryptedKeyHeadersAreSealed(t *testing.T) {
+	priv, _ := GenerateKey(AlgorithmEd25519)
+	other, _ := GenerateKey(AlgorithmEd25519)
+	data, err := EncryptPrivateKey(priv, []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherData, _ := EncryptPrivateKey(other, []byte("pw"))
+	header := func(d []byte) string {
+		for _, line := range strings.Split(string(d), "\n") {
+			if strings.HasPrefix(line, "Public-Key: ") {
+				return line
+			}
+		}
+		return ""
+	}
+	// A file claiming another key's public key does not open.
+	swapped := strings.Replace(string(data), header(data), header(otherData), 1)
+	if _, err := DecryptPrivateKey([]byte(swapped), []byte("pw")); err == nil {
+		t.Error("opened a key with a swapped public key header")
+	}
+}
+
+func TestEncryptedKeyIterationsCapped(t *testing.T) {
+	priv, _ := GenerateKey(AlgorithmEd25519)
+	data, err := EncryptPrivateKey(priv, []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	costly := strings.Replace(string(data), "Iterations: 1000\n", "Iterations: 2000000000\n", 1)
+	if costly == string(data) {
+		t.Fatal("no Iterations header to replace")
+	}
+	_, err = DecryptPrivateKey([]byte(costly), []byte("pw"))
+	if err == nil || errors.Is(err, ErrPassphrase) || !strings.Contains(err.Error(), "iteration count") {
+		t.Errorf("expected the iteration count to be rejected before deriving, got %v", err)
+	}
+}
+
+func TestLoadEncryptedSigner(t *testing.T) {
+	priv, _ := GenerateKey(AlgorithmEd25519)
+	data, _ := EncryptPrivateKey(priv, []byte("s3cret"))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key.pem")
+	os.WriteFile(path, data, 0o600)
+
+	t.Setenv("HELIOS_KEY_PASSPHRASE", "")
+	t.Setenv("HELIOS_KEY_PASSPHRASE_FILE", "")
+	if _, err := LoadSigner(path); !errors.Is(err, ErrPassphrase) {
+		t.Errorf("loaded without a passphrase: %v", err)
+	}
+	pwPath := filepath.Join(dir, "pw")
+	os.WriteFile(pwPath, []byte("s3cret\n"), 0o600)
+	t.Setenv("HELIOS_KEY_PASSPHRASE_FILE", pwPath)
+	s, err := LoadSigner(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := KeyID(priv.Public()); s.KeyID() != want {
+		t.Errorf("loaded key %s, want %s", s.KeyID(), want)
+	}
+}
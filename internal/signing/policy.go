@@ -0,0 +1,213 @@
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// TrustPolicy says whose signatures a verifier accepts and how many it
+// requires, so that an environment can demand "two valid signatures from
+// this set" rather than trusting whichever keys the caller supplies. Keys
+// given to Check are only candidates: a signature counts only if its key
+// is one of Signers and the signing time is within that signer's window.
+type TrustPolicy struct {
+	// Signers are the keys whose signatures count.
+	Signers []TrustedSigner `json:"signers"`
+	// Threshold is the number of distinct signers whose valid signatures
+	// are required; zero means one.
+	Threshold int `json:"threshold,omitempty"`
+	// Revocations, if set, revokes signers: a signature by a revoked
+	// signer does not count.
+	Revocations *RevocationList `json:"-"`
+}
+
+// TrustedSigner is one key a TrustPolicy accepts.
+type TrustedSigner struct {
+	// Name labels the signer in reports.
+	Name string `json:"name,omitempty"`
+	// Fingerprint identifies the key, as Fingerprint writes it
+	// ("ed25519 SHA256:<key ID>"), as "SHA256:<key ID>", or as the bare
+	// key ID.
+	Fingerprint string `json:"fingerprint"`
+	// PublicKey optionally holds the PEM public key, so the policy can be
+	// checked without supplying keys separately. It must have the
+	// fingerprint.
+	PublicKey string `json:"public_key,omitempty"`
+	// NotBefore and NotAfter bound the signing times the signer is
+	// trusted for; either may be omitted.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+
+	keyID string
+	key   crypto.PublicKey
+}
+
+// LoadTrustPolicy reads a TrustPolicy from a JSON file.
+func LoadTrustPolicy(path string) (*TrustPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust policy: %w", err)
+	}
+	p, err := ParseTrustPolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParseTrustPolicy decodes and validates a JSON TrustPolicy. Unknown
+// fields are errors, so a misspelled threshold cannot silently weaken it.
+func ParseTrustPolicy(data []byte) (*TrustPolicy, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var p TrustPolicy
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid trust policy: %w", err)
+	}
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// init validates p and resolves each signer's key ID and key.
+func (p *TrustPolicy) init() error {
+	if len(p.Signers) == 0 {
+		return fmt.Errorf("trust policy lists no signers")
+	}
+	if p.Threshold < 0 || p.Threshold > len(p.Signers) {
+		return fmt.Errorf("trust policy threshold %d is not between 1 and its %d signer(s)", p.Threshold, len(p.Signers))
+	}
+	seen := make(map[string]bool, len(p.Signers))
+	for i := range p.Signers {
+		s := &p.Signers[i]
+		id, err := parseFingerprint(s.Fingerprint)
+		if err != nil {
+			return fmt.Errorf("trust policy signer %d: %w", i+1, err)
+		}
+		if seen[id] {
+			return fmt.Errorf("trust policy lists key %s twice", id)
+		}
+		seen[id] = true
+		s.keyID = id
+		if s.NotBefore != nil && s.NotAfter != nil && s.NotAfter.Before(*s.NotBefore) {
+			return fmt.Errorf("trust policy signer %s: not_after is before not_before", s.label())
+		}
+		if s.PublicKey != "" {
+			k, err := ParsePublicKey([]byte(s.PublicKey))
+			if err != nil {
+				return fmt.Errorf("trust policy signer %s: %w", s.label(), err)
+			}
+			if kid, err := KeyID(k); err != nil || kid != id {
+				return fmt.Errorf("trust policy signer %s: public_key does not have the fingerprint", s.label())
+			}
+			s.key = k
+		}
+	}
+	return nil
+}
+
+// parseFingerprint returns the key ID of a fingerprint in any of the forms
+// TrustedSigner.Fingerprint allows.
+func parseFingerprint(fp string) (string, error) {
+	id := fp
+	if _, rest, ok := strings.Cut(fp, " "); ok {
+		id = rest
+	}
+	id = strings.ToLower(strings.TrimPrefix(id, "SHA256:"))
+	if b, err := hex.DecodeString(id); err != nil || len(b) != 32 {
+		return "", fmt.Errorf("invalid fingerprint %q", fp)
+	}
+	return id, nil
+}
+
+func (s *TrustedSigner) label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.keyID
+}
+
+// Keys returns the public keys the policy carries itself.
+func (p *TrustPolicy) Keys() []crypto.PublicKey {
+	var keys []crypto.PublicKey
+	for _, s := range p.Signers {
+		if s.key != nil {
+			keys = append(keys, s.key)
+		}
+	}
+	return keys
+}
+
+// required returns the effective threshold.
+func (p *TrustPolicy) required() int {
+	if p.Threshold == 0 {
+		return 1
+	}
+	return p.Threshold
+}
+
+// Check counts the signers of p with a valid signature in sigs over
+// message made at time at, considering the policy's own keys and keys,
+// and fails unless the threshold is met. It returns the names of the
+// signers counted. A signature by a key outside the policy, by a signer
+// outside its window, or by a revoked signer is ignored; the error says
+// why each such candidate did not count. at must be a time the caller
+// trusts, such as now or one a witness vouched for, never one the signer
+// supplied: a signer holding a revoked key could otherwise backdate.
+func (p *TrustPolicy) Check(message []byte, sigs [][]byte, at time.Time, keys ...crypto.PublicKey) ([]string, error) {
+	byID := make(map[string]*TrustedSigner, len(p.Signers))
+	for i := range p.Signers {
+		byID[p.Signers[i].keyID] = &p.Signers[i]
+	}
+	var accepted, skipped []string
+	counted := make(map[string]bool)
+	for _, k := range append(p.Keys(), keys...) {
+		id, err := KeyID(k)
+		if err != nil || counted[id] {
+			continue
+		}
+		s, ok := byID[id]
+		if !ok {
+			continue
+		}
+		valid := false
+		for _, sig := range sigs {
+			if Verify(k, message, sig) == nil {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+		counted[id] = true
+		if r, ok := p.Revocations.Revoked(id, at); ok {
+			skipped = append(skipped, r.String())
+			continue
+		}
+		switch {
+		case s.NotBefore != nil && at.Before(*s.NotBefore):
+			skipped = append(skipped, fmt.Sprintf("%s is not trusted before %s", s.label(), s.NotBefore.UTC().Format(time.RFC3339)))
+		case s.NotAfter != nil && at.After(*s.NotAfter):
+			skipped = append(skipped, fmt.Sprintf("%s is not trusted after %s", s.label(), s.NotAfter.UTC().Format(time.RFC3339)))
+		default:
+			accepted = append(accepted, s.label())
+		}
+	}
+	if len(accepted) < p.required() {
+		msg := fmt.Sprintf("%d valid signature(s) from the trust policy's %d signer(s), %d required", len(accepted), len(p.Signers), p.required())
+		if len(skipped) > 0 {
+			msg += " (" + strings.Join(skipped, "; ") + ")"
+		}
+		return accepted, errors.New(msg)
+	}
+	return accepted, nil
+}
@@ -0,0 +1,165 @@
+package signing
+
+import (
+	"crypto"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// policyFor returns a policy over signers, requiring threshold of them.
+func policyFor(t *testing.T, threshold int, signers ...TrustedSigner) *TrustPolicy {
+	t.Helper()
+	data, err := json.Marshal(TrustPolicy{Signers: signers, Threshold: threshold})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParseTrustPolicy(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func trusted(t *testing.T, s Signer) TrustedSigner {
+	t.Helper()
+	fp, err := Fingerprint(s.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return TrustedSigner{Fingerprint: fp}
+}
+
+func TestTrustPolicyThreshold(t *testing.T) {
+	var signers []Signer
+	var pubs []crypto.PublicKey
+	for _, priv := range append(testKeys(t), testKeys(t)...) {
+		s, err := NewSigner(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, s)
+		pubs = append(pubs, s.Public())
+	}
+	msg := []byte("checkpoint")
+	sign := func(ss ...Signer) [][]byte {
+		var sigs [][]byte
+		for _, s := range ss {
+			sig, err := s.Sign(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigs = append(sigs, sig)
+		}
+		return sigs
+	}
+	now := time.Now()
+	p := policyFor(t, 2, trusted(t, signers[0]), trusted(t, signers[1]), trusted(t, signers[2]))
+
+	if _, err := p.Check(msg, sign(signers[0], signers[1]), now, pubs...); err != nil {
+		t.Errorf("two trusted signatures: %v", err)
+	}
+	// Two signatures by one key are one signer.
+	if _, err := p.Check(msg, sign(signers[0], signers[0]), now, pubs...); err == nil {
+		t.Error("one signer counted twice")
+	}
+	// A valid signature by a key outside the policy does not count.
+	if _, err := p.Check(msg, sign(signers[0], signers[3]), now, pubs...); err == nil {
+		t.Error("untrusted signer counted")
+	}
+	// Nor does one over another message.
+	if _, err := p.Check([]byte("other"), sign(signers[0], signers[1]), now, pubs...); err == nil {
+		t.Error("signatures over another message counted")
+	}
+	// Without the keys, a policy of fingerprints alone cannot verify.
+	if _, err := p.Check(msg, sign(signers[0], signers[1]), now); err == nil {
+		t.Error("verified without any keys")
+	}
+}
+
+func TestTrustPolicyEmbeddedKeys(t *testing.T) {
+	s, err := NewSigner(testKeys(t)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pem, err := EncodePublicKey(s.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := TrustedSigner{Name: "ops", Fingerprint: s.KeyID(), PublicKey: string(pem)}
+	p := policyFor(t, 0, ts)
+	sig, err := s.Sign([]byte("m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := p.Check([]byte("m"), [][]byte{sig}, time.Now())
+	if err != nil || len(names) != 1 || names[0] != "ops" {
+		t.Errorf("Check = %v, %v; want [ops]", names, err)
+	}
+
+	other, err := NewSigner(testKeys(t)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Fingerprint = other.KeyID()
+	data, _ := json.Marshal(TrustPolicy{Signers: []TrustedSigner{ts}})
+	if _, err := ParseTrustPolicy(data); err == nil || !strings.Contains(err.Error(), "fingerprint") {
+		t.Errorf("public_key of another key: got %v", err)
+	}
+}
+
+func TestTrustPolicyWindow(t *testing.T) {
+	s, err := NewSigner(testKeys(t)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := s.Sign([]byte("m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	ts := trusted(t, s)
+	ts.NotBefore, ts.NotAfter = &from, &until
+	p := policyFor(t, 1, ts)
+
+	for _, tc := range []struct {
+		at time.Time
+		ok bool
+	}{
+		{from.Add(-time.Second), false},
+		{from, true},
+		{until, true},
+		{until.Add(time.Second), false},
+	} {
+		_, err := p.Check([]byte("m"), [][]byte{sig}, tc.at, s.Public())
+		if (err == nil) != tc.ok {
+			t.Errorf("at %s: err = %v, want ok %v", tc.at, err, tc.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), "not trusted") {
+			t.Errorf("at %s: error does not explain the window: %v", tc.at, err)
+		}
+	}
+}
+
+func TestParseTrustPolicyRejects(t *testing.T) {
+	s, err := NewSigner(testKeys(t)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := `"` + s.KeyID() + `"`
+	for name, doc := range map[string]string{
+		"no signers":      `{"signers": [], "threshold": 1}`,
+		"threshold":       `{"signers": [{"fingerprint": ` + fp + `}], "threshold": 2}`,
+		"negative":        `{"signers": [{"fingerprint": ` + fp + `}], "threshold": -1}`,
+		"bad fingerprint": `{"signers": [{"fingerprint": "SHA256:abc"}]}`,
+		"duplicate":       `{"signers": [{"fingerprint": ` + fp + `}, {"fingerprint": "SHA256:` + s.KeyID() + `"}]}`,
+		"unknown field":   `{"signers": [{"fingerprint": ` + fp + `}], "treshold": 1}`,
+		"window":          `{"signers": [{"fingerprint": ` + fp + `, "not_before": "2026-02-01T00:00:00Z", "not_after": "2026-01-01T00:00:00Z"}]}`,
+	} {
+		if _, err := ParseTrustPolicy([]byte(doc)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// revocationHeader starts the signed body of every revocation list.
+const revocationHeader = "helios-revocations/v1"
+
+// revocationTime is the layout of revocation times, the layout of
+// checkpoint times.
+const revocationTime = "2006-01-02T15:04:05.000Z"
+
+// RevocationList is a signed list of revoked signing keys. A signature by
+// a revoked key made at or after its revocation time is rejected; one
+// made earlier still verifies, so revoking a compromised key does not
+// discard everything it signed while it was sound. Signatures whose time
+// is unknown, such as those of DSSE envelopes, are taken to be made at
+// verification and so are rejected outright.
+//
+// A list is honored only if a key the verifier already trusts signed it.
+// Revocation only ever causes rejections, so trusting any such key with it
+// cannot make a forged signature verify.
+type RevocationList struct {
+	Revocations []Revocation `json:"revocations"`
+	Signatures  []Signature  `json:"signatures"`
+}
+
+// Revocation revokes one key from a time on.
+type Revocation struct {
+	KeyID     string `json:"keyid"`
+	RevokedAt string `json:"revoked_at"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Signature is one signature over a revocation list's body.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// LoadRevocationList reads a revocation list from a JSON file. It does not
+// check signatures.
+func LoadRevocationList(path string) (*RevocationList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revocation list: %w", err)
+	}
+	var l RevocationList
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("%s: invalid revocation list: %w", path, err)
+	}
+	for _, r := range l.Revocations {
+		if id, err := parseFingerprint(r.KeyID); err != nil || id != r.KeyID {
+			return nil, fmt.Errorf("%s: invalid revoked key ID %q", path, r.KeyID)
+		}
+		if _, err := r.time(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return &l, nil
+}
+
+func (r Revocation) time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, r.RevokedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("revocation of key %s: invalid revoked_at %q", r.KeyID, r.RevokedAt)
+	}
+	return t, nil
+}
+
+// Body returns the bytes a revocation list's signatures sign: each
+// revocation, one per line, in list order.
+func (l *RevocationList) Body() []byte {
+	var b bytes.Buffer
+	b.WriteString(revocationHeader + "\n")
+	for _, r := range l.Revocations {
+		b.WriteString(r.KeyID + " " + r.RevokedAt + " " + strconv.Quote(r.Reason) + "\n")
+	}
+	return b.Bytes()
+}
+
+// Revoke appends the revocation of keyID from at on. It changes the body,
+// so it drops the list's signatures; sign the list again afterwards.
+func (l *RevocationList) Revoke(keyID string, at time.Time, reason string) error {
+	id, err := parseFingerprint(keyID)
+	if err != nil {
+		return err
+	}
+	for _, r := range l.Revocations {
+		if r.KeyID == id {
+			return fmt.Errorf("key %s is already revoked as of %s", id, r.RevokedAt)
+		}
+	}
+	l.Revocations = append(l.Revocations, Revocation{KeyID: id, RevokedAt: at.UTC().Format(revocationTime), Reason: reason})
+	l.Signatures = nil
+	return nil
+}
+
+// Sign adds a signature by each signer.
+func (l *RevocationList) Sign(signers ...Signer) error {
+	body := l.Body()
+	for _, s := range signers {
+		sig, err := s.Sign(body)
+		if err != nil {
+			return fmt.Errorf("signing failed: %w", err)
+		}
+		l.Signatures = append(l.Signatures, Signature{KeyID: s.KeyID(), Sig: base64.StdEncoding.EncodeToString(sig)})
+	}
+	return nil
+}
+
+// Verify checks that at least one signature on l was made by one of keys.
+func (l *RevocationList) Verify(keys ...crypto.PublicKey) error {
+	body := l.Body()
+	for _, sig := range l.Signatures {
+		raw, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+		for _, k := range keys {
+			if Verify(k, body, raw) == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("revocation list: no valid signature from a trusted key")
+}
+
+// Revoked returns the revocation of the key with keyID in effect for a
+// signature made at at, if any. A nil list revokes nothing.
+func (l *RevocationList) Revoked(keyID string, at time.Time) (Revocation, bool) {
+	if l == nil {
+		return Revocation{}, false
+	}
+	for _, r := range l.Revocations {
+		if r.KeyID != keyID {
+			continue
+		}
+		if t, err := r.time(); err != nil || !at.Before(t) {
+			return r, true
+		}
+	}
+	return Revocation{}, false
+}
+
+// Filter returns the keys not revoked for a signature made at at, and a
+// description of each revoked one, for error messages.
+func (l *RevocationList) Filter(at time.Time, keys ...crypto.PublicKey) ([]crypto.PublicKey, []string) {
+	var kept []crypto.PublicKey
+	var revoked []string
+	for _, k := range keys {
+		id, err := KeyID(k)
+		if err != nil {
+			kept = append(kept, k)
+			continue
+		}
+		if r, ok := l.Revoked(id, at); ok {
+			revoked = append(revoked, r.String())
+			continue
+		}
+		kept = append(kept, k)
+	}
+	return kept, revoked
+}
+
+// String describes the revocation.
+func (r Revocation) String() string {
+	s := fmt.Sprintf("key %s was revoked as of %s", r.KeyID, r.RevokedAt)
+	if r.Reason != "" {
+		s += " (" + r.Reason + ")"
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package signing
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevocationList(t *testing.T) {
+	authority, err := NewSigner(testKeys(t)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := NewSigner(testKeys(t)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var l RevocationList
+	if err := l.Revoke(revoked.KeyID(), at, "key compromise"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Revoke("SHA256:"+revoked.KeyID(), at, ""); err == nil {
+		t.Error("revoked one key twice")
+	}
+	if err := l.Sign(authority); err != nil {
+		t.Fatal(err)
+	}
+
+	// Survives a round trip through a file.
+	path := filepath.Join(t.TempDir(), "revocations.json")
+	data, _ := json.Marshal(&l)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	back, err := LoadRevocationList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := back.Verify(authority.Public()); err != nil {
+		t.Fatal(err)
+	}
+	if err := back.Verify(revoked.Public()); err == nil {
+		t.Error("verified under a key that did not sign the list")
+	}
+	back.Revocations[0].RevokedAt = "2026-03-02T12:00:00.000Z"
+	if err := back.Verify(authority.Public()); err == nil {
+		t.Error("verified a list whose revocation time was changed")
+	}
+
+	// Signatures made before the revocation time still count.
+	if _, ok := l.Revoked(revoked.KeyID(), at.Add(-time.Millisecond)); ok {
+		t.Error("revoked before its revocation time")
+	}
+	if r, ok := l.Revoked(revoked.KeyID(), at); !ok || r.Reason != "key compromise" {
+		t.Errorf("Revoked at the revocation time = %+v, %v", r, ok)
+	}
+	if _, ok := l.Revoked(authority.KeyID(), at); ok {
+		t.Error("revoked a key the list does not name")
+	}
+	kept, why := l.Filter(at.Add(time.Hour), authority.Public(), revoked.Public())
+	if len(kept) != 1 || len(why) != 1 || !strings.Contains(why[0], revoked.KeyID()) {
+		t.Errorf("Filter = %d kept, %q", len(kept), why)
+	}
+	var none *RevocationList
+	if kept, _ := none.Filter(at, revoked.Public()); len(kept) != 1 {
+		t.Error("a nil list revoked a key")
+	}
+}
+
+func TestTrustPolicyRevocations(t *testing.T) {
+	s, err := NewSigner(testKeys(t)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := s.Sign([]byte("m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := policyFor(t, 1, trusted(t, s))
+	p.Revocations = &RevocationList{}
+	if err := p.Revocations.Revoke(s.KeyID(), at, "retired"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Check([]byte("m"), [][]byte{sig}, at.Add(-time.Hour), s.Public()); err != nil {
+		t.Errorf("signature before the revocation: %v", err)
+	}
+	_, err = p.Check([]byte("m"), [][]byte{sig}, at, s.Public())
+	if err == nil || !strings.Contains(err.Error(), "retired") {
+		t.Errorf("signature at the revocation: got %v", err)
+	}
+}
+
+func TestLoadRevocationListRejects(t *testing.T) {
+	dir := t.TempDir()
+	for name, doc := range map[string]string{
+		"key ID": `{"revocations": [{"keyid": "abc", "revoked_at": "2026-01-01T00:00:00Z"}]}`,
+		"time":   `{"revocations": [{"keyid": "` + strings.Repeat("ab", 32) + `", "revoked_at": "yesterday"}]}`,
+	} {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadRevocationList(path); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
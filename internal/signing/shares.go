@@ -0,0 +1,152 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/holeyfield33-art/helios/internal/shamir"
+)
+
+// shareBlockType is the PEM block type of a key share.
+const shareBlockType = "HELIOS KEY SHARE"
+
+// SplitKey splits priv's PKCS#8 encoding into n PEM-encoded shares, any
+// threshold of which CombineKey turns back into the key, so that no
+// holder of fewer shares can sign. Each share records the key ID of the
+// key, its index, the share count, and the threshold in PEM headers.
+func SplitKey(priv crypto.Signer, n, threshold int) ([][]byte, error) {
+	s, err := NewSigner(priv)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(der)
+	shares, err := shamir.Split(der, n, threshold)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]byte, len(shares))
+	for i, sh := range shares {
+		out[i] = pem.EncodeToMemory(&pem.Block{
+			Type: shareBlockType,
+			Headers: map[string]string{
+				"Key-Id":    s.KeyID(),
+				"Index":     strconv.Itoa(int(sh.X)),
+				"Shares":    strconv.Itoa(n),
+				"Threshold": strconv.Itoa(threshold),
+			},
+			Bytes: sh.Y,
+		})
+	}
+	return out, nil
+}
+
+// KeyShare is a decoded key share.
+type KeyShare struct {
+	KeyID     string
+	Shares    int
+	Threshold int
+	share     shamir.Share
+}
+
+// ParseKeyShare decodes a PEM-encoded key share.
+func ParseKeyShare(data []byte) (KeyShare, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != shareBlockType {
+		return KeyShare{}, fmt.Errorf("expected a PEM %q block", shareBlockType)
+	}
+	ks := KeyShare{KeyID: block.Headers["Key-Id"], share: shamir.Share{Y: block.Bytes}}
+	index, err := strconv.Atoi(block.Headers["Index"])
+	if err != nil || index < 1 || index > shamir.MaxShares {
+		return KeyShare{}, fmt.Errorf("invalid share index %q", block.Headers["Index"])
+	}
+	ks.share.X = byte(index)
+	if ks.Shares, err = strconv.Atoi(block.Headers["Shares"]); err != nil {
+		return KeyShare{}, fmt.Errorf("invalid share count %q", block.Headers["Shares"])
+	}
+	if ks.Threshold, err = strconv.Atoi(block.Headers["Threshold"]); err != nil || ks.Threshold < 2 {
+		return KeyShare{}, fmt.Errorf("invalid threshold %q", block.Headers["Threshold"])
+	}
+	if ks.KeyID == "" {
+		return KeyShare{}, fmt.Errorf("share has no Key-Id")
+	}
+	return ks, nil
+}
+
+// Index returns the share's index, 1 to its share count.
+func (ks KeyShare) Index() int { return int(ks.share.X) }
+
+// CombineKey reconstructs the private key shares were split from. The
+// shares must be of one key and meet its threshold; the reconstructed key
+// must have the key ID the shares record, so a corrupted or foreign share
+// is an error rather than a wrong key.
+func CombineKey(shares []KeyShare) (crypto.Signer, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no key shares")
+	}
+	first := shares[0]
+	parts := make([]shamir.Share, len(shares))
+	for i, ks := range shares {
+		if ks.KeyID != first.KeyID || ks.Threshold != first.Threshold {
+			return nil, fmt.Errorf("share %d is of key %s, not %s", ks.Index(), ks.KeyID, first.KeyID)
+		}
+		parts[i] = ks.share
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("key %s needs %d shares, got %d", first.KeyID, first.Threshold, len(shares))
+	}
+	der, err := shamir.Combine(parts)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(der)
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("shares do not reconstruct key %s: %w", first.KeyID, err)
+	}
+	priv, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if id, err := KeyID(priv.Public()); err != nil || id != first.KeyID {
+		return nil, fmt.Errorf("shares do not reconstruct key %s", first.KeyID)
+	}
+	return priv, nil
+}
+
+// ReadKeyShares reads PEM-encoded key shares.
+func ReadKeyShares(paths []string) ([]KeyShare, error) {
+	shares := make([]KeyShare, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key share: %w", err)
+		}
+		if shares[i], err = ParseKeyShare(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return shares, nil
+}
+
+// LoadKeyShares reads PEM-encoded key shares, combines them with
+// CombineKey, and returns a signer of the key, which exists only in
+// memory.
+func LoadKeyShares(paths []string) (Signer, error) {
+	shares, err := ReadKeyShares(paths)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := CombineKey(shares)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(priv)
+}
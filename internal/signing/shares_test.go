@@ -0,0 +1,75 @@
+package signing
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitAndCombineKey(t *testing.T) {
+	for _, priv := range testKeys(t) {
+		want, err := NewSigner(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pems, err := SplitKey(priv, 5, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares := make([]KeyShare, len(pems))
+		for i, p := range pems {
+			if shares[i], err = ParseKeyShare(p); err != nil {
+				t.Fatal(err)
+			}
+			if shares[i].KeyID != want.KeyID() || shares[i].Index() != i+1 || shares[i].Threshold != 3 {
+				t.Errorf("share %d: %+v", i, shares[i])
+			}
+		}
+
+		priv, err := CombineKey([]KeyShare{shares[4], shares[1], shares[2]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewSigner(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := []byte("checkpoint")
+		sig, err := s.Sign(msg)
+		if err != nil || s.KeyID() != want.KeyID() {
+			t.Fatalf("combined signer %s: %v", s.KeyID(), err)
+		}
+		if err := Verify(want.Public(), msg, sig); err != nil {
+			t.Errorf("the combined key's signature does not verify: %v", err)
+		}
+
+		if _, err := CombineKey(shares[:2]); err == nil {
+			t.Error("two shares of a threshold of 3 combined")
+		}
+		bad := shares[0]
+		bad.share.Y = bytes.Clone(bad.share.Y)
+		bad.share.Y[0] ^= 1
+		if _, err := CombineKey([]KeyShare{bad, shares[1], shares[2]}); err == nil {
+			t.Error("a corrupted share combined")
+		}
+	}
+}
+
+func TestCombineKeyRejectsForeignShares(t *testing.T) {
+	keys := testKeys(t)
+	a, err := SplitKey(keys[0], 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := SplitKey(keys[1], 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa, _ := ParseKeyShare(a[0])
+	sb, _ := ParseKeyShare(b[1])
+	if _, err := CombineKey([]KeyShare{sa, sb}); err == nil {
+		t.Error("shares of two keys combined")
+	}
+	if _, err := ParseKeyShare([]byte("not pem")); err == nil {
+		t.Error("ParseKeyShare accepted garbage")
+	}
+}
@@ -0,0 +1,182 @@
+// Package signing loads signing keys and produces and checks signatures
+// for Helios attestations. Ed25519 and ECDSA P-256 keys are supported.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// Signer signs messages and identifies its public key.
+type Signer interface {
+	Sign(message []byte) ([]byte, error)
+	KeyID() string
+	Public() crypto.PublicKey
+}
+
+type keySigner struct {
+	priv  crypto.Signer
+	keyID string
+}
+
+// NewSigner wraps an Ed25519 or ECDSA P-256 private key.
+func NewSigner(priv crypto.Signer) (Signer, error) {
+	switch k := priv.(type) {
+	case ed25519.PrivateKey:
+	case *ecdsa.PrivateKey:
+		if err := checkCurve(&k.PublicKey); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+	id, err := KeyID(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &keySigner{priv: priv, keyID: id}, nil
+}
+
+func (s *keySigner) Sign(message []byte) ([]byte, error) {
+	if _, ok := s.priv.(ed25519.PrivateKey); ok {
+		return s.priv.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(message)
+	return s.priv.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (s *keySigner) KeyID() string            { return s.keyID }
+func (s *keySigner) Public() crypto.PublicKey { return s.priv.Public() }
+
+// checkCurve rejects ECDSA keys on curves other than P-256, the only one
+// attestations are specified for.
+func checkCurve(k *ecdsa.PublicKey) error {
+	if k.Curve != elliptic.P256() {
+		return fmt.Errorf("unsupported ECDSA curve %s (want P-256)", k.Params().Name)
+	}
+	return nil
+}
+
+// Verify checks sig over message with pub, an Ed25519 or ECDSA P-256 key.
+func Verify(pub crypto.PublicKey, message, sig []byte) error {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, message, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if err := checkCurve(k); err != nil {
+			return err
+		}
+		digest := sha256.Sum256(message)
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return nil
+}
+
+// KeyID returns the hex SHA-256 of the public key's PKIX DER encoding.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// LoadSigner reads a PEM-encoded PKCS#8 private key, as produced by
+// `openssl genpkey -algorithm ed25519`.
+func LoadSigner(path string) (Signer, error) {
+	priv, err := LoadPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(priv)
+}
+
+// LoadPrivateKey reads a PEM-encoded PKCS#8 private key, or one
+// EncryptPrivateKey encrypted, opened with the passphrase Passphrase
+// returns.
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block != nil && block.Type == encryptedBlockType {
+		passphrase, err := Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		priv, err := decryptBlock(block, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w (set HELIOS_KEY_PASSPHRASE_FILE or HELIOS_KEY_PASSPHRASE)", path, err)
+		}
+		return priv, nil
+	}
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: expected a PEM \"PRIVATE KEY\" block", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key type %T", path, key)
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads a PEM-encoded PKIX public key.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	pub, err := ParsePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pub, nil
+}
+
+// ParsePublicKey decodes a PEM-encoded PKIX public key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("expected a PEM \"PUBLIC KEY\" block")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// EncodePrivateKey returns the PKCS#8 PEM encoding of priv.
+func EncodePrivateKey(priv crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// EncodePublicKey returns the PKIX PEM encoding of pub.
+func EncodePublicKey(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
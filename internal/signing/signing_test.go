@@ -0,0 +1,105 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testKeys(t *testing.T) []crypto.Signer {
+	t.Helper()
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []crypto.Signer{ed, ec}
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, priv := range testKeys(t) {
+		s, err := NewSigner(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := []byte("hello")
+		sig, err := s.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(s.Public(), msg, sig); err != nil {
+			t.Errorf("%T: %v", priv, err)
+		}
+		if err := Verify(s.Public(), []byte("tampered"), sig); err == nil {
+			t.Errorf("%T: expected failure for tampered message", priv)
+		}
+		if len(s.KeyID()) != 64 {
+			t.Errorf("%T: key ID should be 64 hex chars, got %q", priv, s.KeyID())
+		}
+	}
+}
+
+func TestRejectsOtherCurves(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSigner(priv); err == nil {
+		t.Error("NewSigner accepted a P-384 key")
+	}
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(&priv.PublicKey, []byte("hello"), sig); err == nil {
+		t.Error("Verify accepted a P-384 signature")
+	}
+}
+
+func TestLoadKeysFromPEM(t *testing.T) {
+	dir := t.TempDir()
+	for i, priv := range testKeys(t) {
+		privPEM, err := EncodePrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubPEM, err := EncodePublicKey(priv.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		privPath := filepath.Join(dir, "key"+string(rune('0'+i))+".pem")
+		pubPath := privPath + ".pub"
+		os.WriteFile(privPath, privPEM, 0600)
+		os.WriteFile(pubPath, pubPEM, 0644)
+
+		s, err := LoadSigner(privPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := LoadPublicKey(pubPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := KeyID(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != s.KeyID() {
+			t.Errorf("%T: key IDs differ between private and public key", priv)
+		}
+	}
+
+	if _, err := LoadSigner(filepath.Join(dir, "key0.pem.pub")); err == nil {
+		t.Error("expected error loading a public key as a signer")
+	}
+}
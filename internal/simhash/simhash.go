@@ -0,0 +1,74 @@
+// Package simhash computes a locality-sensitive digest of a memory value.
+// Similar values produce digests with a small Hamming distance, which makes
+// near-duplicate detection cheap. A simhash is NOT a content hash: it is
+// never used for integrity and must not be compared with one.
+package simhash
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+)
+
+// Digest is a 64-bit simhash.
+type Digest uint64
+
+// String renders the digest as "simhash:" followed by 16 hex digits, so it
+// can never be mistaken for a 64-character content hash.
+func (d Digest) String() string {
+	return fmt.Sprintf("simhash:%016x", uint64(d))
+}
+
+// Distance returns the Hamming distance between two digests (0..64).
+func Distance(a, b Digest) int {
+	return bits.OnesCount64(uint64(a) ^ uint64(b))
+}
+
+// Value computes the simhash of v over its canonical text. The text is
+// NFC-normalized and lower-cased, then split into word tokens; each token
+// and each pair of adjacent tokens contributes one feature.
+func Value(v interface{}) (Digest, error) {
+	b, err := canon.CanonicalizeValue(v)
+	if err != nil {
+		return 0, err
+	}
+	text := strings.ToLower(canon.NormalizeString(string(b)))
+	tokens := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return fromTokens(tokens), nil
+}
+
+func fromTokens(tokens []string) Digest {
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	for i, tok := range tokens {
+		add(tok)
+		if i > 0 {
+			add(tokens[i-1] + " " + tok)
+		}
+	}
+
+	var d uint64
+	for i, w := range weights {
+		if w > 0 {
+			d |= 1 << uint(i)
+		}
+	}
+	return Digest(d)
+}
@@ -0,0 +1,53 @@
+package simhash
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIdenticalValuesHaveZeroDistance(t *testing.T) {
+	a, err := Value("the quick brown fox jumps over the lazy dog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Value("The Quick Brown Fox jumps over the lazy dog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Distance(a, b) != 0 {
+		t.Errorf("case-only differences should not change the simhash: %s vs %s", a, b)
+	}
+}
+
+func TestNearDuplicatesAreCloserThanUnrelated(t *testing.T) {
+	base := "deploy the payments service to the eu cluster after the error budget review on friday"
+	near := "deploy the payments service to the eu cluster after the error budget review on monday"
+	far := "grandma's lasagna recipe uses ricotta, basil, and a slow simmered tomato sauce"
+
+	db, _ := Value(base)
+	dn, _ := Value(near)
+	df, _ := Value(far)
+	if Distance(db, dn) >= Distance(db, df) {
+		t.Errorf("near duplicate distance %d should be below unrelated distance %d", Distance(db, dn), Distance(db, df))
+	}
+}
+
+func TestStructuredValue(t *testing.T) {
+	v := map[string]interface{}{"title": "runbook", "steps": []interface{}{"drain", "restart"}}
+	if _, err := Value(v); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Value(map[string]interface{}{"bad": nil}); err == nil {
+		t.Error("expected error for null value")
+	}
+}
+
+func TestDigestStringIsLabelled(t *testing.T) {
+	s := Digest(0xabc).String()
+	if s != "simhash:0000000000000abc" {
+		t.Errorf("unexpected rendering %q", s)
+	}
+	if len(strings.TrimPrefix(s, "simhash:")) == 64 {
+		t.Error("simhash must not look like a content hash")
+	}
+}
@@ -0,0 +1,79 @@
+// Package snappy decodes Snappy blocks, as Avro, Parquet, and Kafka use
+// them, without pulling in an external compression library.
+package snappy
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Decode decodes a Snappy block (not the framed stream format) whose
+// decoded length is at most maxLen.
+func Decode(src []byte, maxLen int) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 || n > uint64(maxLen) {
+		return nil, fmt.Errorf("snappy: invalid length header")
+	}
+	dst := make([]byte, 0, n)
+	s := src[k:]
+	for len(s) > 0 {
+		tag := s[0]
+		var length, offset int
+		switch tag & 3 {
+		case 0:
+			length = int(tag>>2) + 1
+			s = s[1:]
+			if length > 60 {
+				extra := length - 60
+				if len(s) < extra {
+					return nil, fmt.Errorf("snappy: truncated literal")
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(s[i])
+				}
+				length++
+				s = s[extra:]
+			}
+			if length > len(s) {
+				return nil, fmt.Errorf("snappy: truncated literal")
+			}
+			dst = append(dst, s[:length]...)
+			s = s[length:]
+			continue
+		case 1:
+			if len(s) < 2 {
+				return nil, fmt.Errorf("snappy: truncated copy")
+			}
+			length = 4 + int(tag>>2)&7
+			offset = int(tag&0xe0)<<3 | int(s[1])
+			s = s[2:]
+		case 2:
+			if len(s) < 3 {
+				return nil, fmt.Errorf("snappy: truncated copy")
+			}
+			length = int(tag>>2) + 1
+			offset = int(binary.LittleEndian.Uint16(s[1:]))
+			s = s[3:]
+		case 3:
+			if len(s) < 5 {
+				return nil, fmt.Errorf("snappy: truncated copy")
+			}
+			length = int(tag>>2) + 1
+			offset = int(binary.LittleEndian.Uint32(s[1:]))
+			s = s[5:]
+		}
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > n {
+			return nil, fmt.Errorf("snappy: invalid copy")
+		}
+		// Copies may overlap their own output, so copy byte by byte.
+		start := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, fmt.Errorf("snappy: decoded %d bytes, header says %d", len(dst), n)
+	}
+	return dst, nil
+}
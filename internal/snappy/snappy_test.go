@@ -0,0 +1,18 @@
+package snappy
+
+import "testing"
+
+func TestOverlappingCopy(t *testing.T) {
+	// "abcabcabcabc": literal "abc" then a 9-byte copy at offset 3
+	src := []byte{12, 2 << 2, 'a', 'b', 'c', 1 | (9-4)<<2, 3}
+	got, err := Decode(src, 64)
+	if err != nil || string(got) != "abcabcabcabc" {
+		t.Errorf("got %q (%v)", got, err)
+	}
+	if _, err := Decode([]byte{5, 1 | 1<<2, 9}, 64); err == nil {
+		t.Error("expected error for copy before start of output")
+	}
+	if _, err := Decode(src, 11); err == nil {
+		t.Error("expected error for a block longer than maxLen")
+	}
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// readyTimeout bounds the store check of GET /readyz.
+const readyTimeout = 5 * time.Second
+
+// HealthResponse is the body of GET /healthz and GET /readyz.
+type HealthResponse struct {
+	// Status is "ok", or "unavailable" if a check failed.
+	Status string        `json:"status" schema:"enum=ok|unavailable"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the outcome of one readiness check.
+type HealthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// healthz answers GET /healthz: the process is up and serving.
+func (g *gateway) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, HealthResponse{Status: "ok"})
+}
+
+// readyz answers GET /readyz with 200 if the store answers a one-key
+// listing and the self-test passes, and 503 otherwise. The self-test is
+// run on the first request only: its result cannot change while the
+// binary runs.
+func (g *gateway) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	_, err := g.s.List(ctx, "", "", 1, "")
+	checks := []HealthCheck{healthCheck("store", err)}
+	if g.selfTest != nil {
+		g.selfTestOnce.Do(func() { g.selfTestErr = g.selfTest() })
+		checks = append(checks, healthCheck("selftest", g.selfTestErr))
+	}
+	resp := HealthResponse{Status: "ok", Checks: checks}
+	for _, c := range checks {
+		if !c.OK {
+			resp.Status = "unavailable"
+		}
+	}
+	writeHealth(w, resp)
+}
+
+func healthCheck(name string, err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Name: name, Error: err.Error()}
+	}
+	return HealthCheck{Name: name, OK: true}
+}
+
+func writeHealth(w http.ResponseWriter, resp HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if resp.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// config answers GET /config with GatewayOptions.Config.
+func (g *gateway) config(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(g.runtimeConfig)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(append(data, '\n'))
+}
+
+// reload answers POST /admin/reload with the ReloadEvent of a reload of
+// the store's write rules: 200 if the rules were replaced, 422
+// STORE_ERR_RELOAD_FAILED with the event's error if they failed to load
+// and the old rules stay in place.
+func (g *gateway) reload(w http.ResponseWriter, r *http.Request) {
+	if who, ok := r.Context().Value(identityKey{}).(*identity); ok && !who.admin() {
+		writeError(w, http.StatusForbidden, "STORE_ERR_FORBIDDEN", fmt.Sprintf("%s may not reload the server", who.id))
+		return
+	}
+	ev, err := g.reloader.Reload(r.Context(), "api")
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "STORE_ERR_RELOAD_FAILED", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ev)
+}
@@ -0,0 +1,22 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+	"github.com/holeyfield33-art/helios/internal/store/storetest"
+)
+
+func TestFSBackend(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Backend {
+		b, err := store.InitFS(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
+
+func TestMemoryBackend(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Backend { return store.NewMemory() })
+}
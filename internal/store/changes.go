@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Change operations.
+const (
+	// ChangePut points a key at a new object.
+	ChangePut = "put"
+	// ChangeDelete removes a key from the index.
+	ChangeDelete = "delete"
+)
+
+// Change is one mutation of a key index, as recorded in a change feed.
+// Replaying a feed's changes in sequence order rebuilds the index it
+// records.
+type Change struct {
+	// Seq numbers the changes of a feed from 1, in the order they were
+	// made. Numbers are shared by every namespace, so one tenant's
+	// changes have gaps.
+	Seq    uint64 `json:"seq"`
+	Time   string `json:"time"`
+	Tenant string `json:"tenant,omitempty"`
+	Op     string `json:"op" schema:"enum=put|delete"`
+	Key    string `json:"key"`
+	// Old is the hash the key held before the change, "" if it did not
+	// exist.
+	Old string `json:"old,omitempty" schema:"pattern=^[0-9a-f]{64}$"`
+	// New is the hash the key holds after a put, "" after a delete.
+	New string `json:"new,omitempty" schema:"pattern=^[0-9a-f]{64}$"`
+}
+
+// ChangeLog records the changes made through a store. Append assigns c
+// the next sequence number, stores it durably before returning, and
+// returns the number.
+type ChangeLog interface {
+	Append(ctx context.Context, c Change) (uint64, error)
+}
+
+// changeLock serializes the writes of a store and its tenants while they
+// are recorded, so a feed lists them in the order the index saw them.
+type changeLock struct {
+	mu sync.Mutex
+}
+
+// recorded runs write, which changes key with op, and appends the change
+// to the store's change log with the hash the key held before. hash is
+// the new hash of a put. Without a change log it just runs write. Like
+// quota accounting, the order is exact only while this process is the
+// only writer.
+func (s *Store) recorded(ctx context.Context, op, key, hash string, write func() error) error {
+	log := s.opts.Changes
+	if log == nil {
+		return write()
+	}
+	s.changeLock.mu.Lock()
+	defer s.changeLock.mu.Unlock()
+	var old string
+	if e, err := s.b.ResolveKey(ctx, key); err == nil {
+		old = e.Hash
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	if op == ChangePut && old == hash {
+		// Rewriting a key's current object changes nothing to mirror.
+		return nil
+	}
+	c := Change{Time: s.now().UTC().Format("2006-01-02T15:04:05.000Z"), Tenant: s.tenantID, Op: op, Key: key, Old: old, New: hash}
+	if _, err := log.Append(ctx, c); err != nil {
+		return fmt.Errorf("%s of %q succeeded but was not recorded in the change log: %w", op, key, err)
+	}
+	return nil
+}
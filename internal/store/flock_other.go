@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package store
+
+import "os"
+
+// lockFile does nothing on a platform without flock: processes sharing a
+// store there are not serialized with each other.
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
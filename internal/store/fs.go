@@ -0,0 +1,405 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Format identifies the on-disk layout version.
+const Format = "helios-store/v1"
+
+// DescriptorName is the layout descriptor at the store root.
+const DescriptorName = "HELIOS_STORE"
+
+// DefaultShardWidth is the number of hex digits in a shard directory name.
+const DefaultShardWidth = 2
+
+// Layout is the content of the layout descriptor.
+type Layout struct {
+	Format     string `json:"format"`
+	ShardWidth int    `json:"shard_width"`
+}
+
+// FS is a Backend that keeps a store in a directory:
+//
+//	HELIOS_STORE              layout descriptor (JSON)
+//	LOCK                      locked while the key index or intent log changes
+//	objects/ab/abcdef...      canonical bytes, named by content hash
+//	keys/12/1234...json       {"key": ..., "hash": ...}, named by SHA-256 of the key
+//	wal/000...123.json        intents of commits in progress
+//	tenants/<id>/             a complete store per tenant
+//
+// The two-level directories are the first ShardWidth hex digits of the
+// file name. Every file is written to a temporary name and renamed into
+// place, so readers never see a partial file. Commit logs its intent
+// first, so a crash between writing an object and updating its key is
+// finished or undone when the store is next opened. Key updates and
+// commits hold the LOCK file, so SetKey's check is atomic with respect to
+// every writer, in this process or another, and recovery never mistakes
+// another process's commit in progress for an interrupted one.
+type FS struct {
+	root      string
+	layout    Layout
+	recovered []Recovery
+	tenants   tenantSet[*FS]
+
+	// keyMu serializes key updates within the process, and guards
+	// lockf, the open LOCK file.
+	keyMu sync.Mutex
+	lockf *os.File
+}
+
+// lockName is the file FS locks while it changes the key index or the
+// intent log.
+const lockName = "LOCK"
+
+// lock takes keyMu and the store's LOCK file, and returns the function
+// that releases both.
+func (b *FS) lock() (unlock func(), err error) {
+	b.keyMu.Lock()
+	if b.lockf == nil {
+		f, err := os.OpenFile(filepath.Join(b.root, lockName), os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			b.keyMu.Unlock()
+			return nil, fmt.Errorf("failed to open store lock: %w", err)
+		}
+		b.lockf = f
+	}
+	if err := lockFile(b.lockf); err != nil {
+		b.keyMu.Unlock()
+		return nil, fmt.Errorf("failed to lock store: %w", err)
+	}
+	return func() {
+		unlockFile(b.lockf)
+		b.keyMu.Unlock()
+	}, nil
+}
+
+// InitFS creates a store at root, or opens it if one already exists.
+func InitFS(root string) (*FS, error) {
+	if b, err := OpenFS(root); err == nil {
+		return b, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	layout := Layout{Format: Format, ShardWidth: DefaultShardWidth}
+	data, err := json.MarshalIndent(layout, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{"objects", "keys"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create store: %w", err)
+		}
+	}
+	if err := writeFileAtomic(filepath.Join(root, DescriptorName), append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+	return OpenFS(root)
+}
+
+// OpenFS opens an existing store and recovers interrupted commits; see
+// Recovered.
+func OpenFS(root string) (*FS, error) {
+	data, err := os.ReadFile(filepath.Join(root, DescriptorName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", root, err)
+	}
+	var layout Layout
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return nil, fmt.Errorf("failed to open store %s: invalid %s: %w", root, DescriptorName, err)
+	}
+	if layout.Format != Format {
+		return nil, fmt.Errorf("failed to open store %s: unsupported format %q", root, layout.Format)
+	}
+	if layout.ShardWidth < 0 || layout.ShardWidth > MaxShardWidth {
+		return nil, fmt.Errorf("failed to open store %s: invalid shard width %d", root, layout.ShardWidth)
+	}
+	b := &FS{root: root, layout: layout}
+	b.tenants.dir = filepath.Join(root, tenantsDir)
+	if b.recovered, err = b.recover(); err != nil {
+		return nil, fmt.Errorf("failed to open store %s: recovering intent log: %w", root, err)
+	}
+	return b, nil
+}
+
+// Tenant implements Tenanted. A tenant's store is created with the
+// default layout.
+func (b *FS) Tenant(ctx context.Context, id string, create bool) (Backend, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.tenants.get(id, func(dir string) (*FS, error) {
+		if _, err := os.Stat(filepath.Join(dir, DescriptorName)); errors.Is(err, fs.ErrNotExist) {
+			if !create {
+				return nil, ErrNotFound
+			}
+			return InitFS(dir)
+		}
+		return OpenFS(dir)
+	})
+}
+
+// Tenants implements Tenanted.
+func (b *FS) Tenants(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.tenants.ids()
+}
+
+// Root returns the store directory.
+func (b *FS) Root() string { return b.root }
+
+// Layout returns the store's layout descriptor.
+func (b *FS) Layout() Layout { return b.layout }
+
+// shardPath returns dir/<shard>/<name><ext>.
+func (b *FS) shardPath(dir, name, ext string) string {
+	return filepath.Join(b.root, dir, name[:b.layout.ShardWidth], name+ext)
+}
+
+func (b *FS) objectPath(h string) string { return b.shardPath("objects", h, "") }
+
+func (b *FS) keyPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return b.shardPath("keys", hex.EncodeToString(sum[:]), ".json")
+}
+
+// Put implements Backend.
+func (b *FS) Put(ctx context.Context, h string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := os.Stat(b.objectPath(h)); !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return writeFileAtomic(b.objectPath(h), data)
+}
+
+// Get implements Backend.
+func (b *FS) Get(ctx context.Context, h string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(b.objectPath(h))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Has implements Backend.
+func (b *FS) Has(ctx context.Context, h string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	_, err := os.Stat(b.objectPath(h))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List implements Backend. A prefix at least as long as the shard width
+// reads a single shard directory.
+func (b *FS) List(ctx context.Context, prefix string) ([]string, error) {
+	dir := filepath.Join(b.root, "objects")
+	if len(prefix) >= b.layout.ShardWidth {
+		dir = filepath.Join(dir, prefix[:b.layout.ShardWidth])
+	}
+	var hashes []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == dir {
+			return filepath.SkipAll
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if ValidHash(d.Name()) && strings.HasPrefix(d.Name(), prefix) {
+			hashes = append(hashes, d.Name())
+		}
+		return nil
+	})
+	sort.Strings(hashes)
+	return hashes, err
+}
+
+// Delete implements Backend.
+func (b *FS) Delete(ctx context.Context, h string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := os.Remove(b.objectPath(h))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// SetKey implements Backend.
+func (b *FS) SetKey(ctx context.Context, e KeyEntry, expected string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cur, err := b.ResolveKey(ctx, e.Key)
+	exists := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := CheckExpected(e.Key, cur, exists, expected); err != nil {
+		return err
+	}
+	return b.writeKey(e)
+}
+
+func (b *FS) writeKey(e KeyEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(b.keyPath(e.Key), append(data, '\n'))
+}
+
+// ResolveKey implements Backend.
+func (b *FS) ResolveKey(ctx context.Context, key string) (KeyEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return KeyEntry{}, err
+	}
+	data, err := os.ReadFile(b.keyPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return KeyEntry{}, ErrNotFound
+	}
+	if err != nil {
+		return KeyEntry{}, err
+	}
+	var e KeyEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return KeyEntry{}, fmt.Errorf("store: corrupt key index for %q: %w", key, err)
+	}
+	if e.Key != key {
+		return KeyEntry{}, fmt.Errorf("store: key index for %q holds %q", key, e.Key)
+	}
+	return e, nil
+}
+
+// ListKeys implements Backend. Key files are named by the hash of the key,
+// so every prefix reads the whole index.
+func (b *FS) ListKeys(ctx context.Context, prefix string) ([]KeyEntry, error) {
+	var entries []KeyEntry
+	err := filepath.WalkDir(filepath.Join(b.root, "keys"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".json") {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var e KeyEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("store: corrupt key index %s: %w", p, err)
+		}
+		if strings.HasPrefix(e.Key, prefix) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// DeleteKey implements Backend.
+func (b *FS) DeleteKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	err = os.Remove(b.keyPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeleteKeyIf implements KeyDeleter, under the same lock as SetKey.
+func (b *FS) DeleteKeyIf(ctx context.Context, e KeyEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cur, err := b.ResolveKey(ctx, e.Key)
+	if err != nil {
+		return err
+	}
+	if cur != e {
+		return fmt.Errorf("%w: %q changed", ErrConflict, e.Key)
+	}
+	err = os.Remove(b.keyPath(e.Key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames
+// it into place, so readers never observe a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
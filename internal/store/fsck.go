@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+)
+
+// Kinds of Problem.
+const (
+	// ProblemCorruptObject is an object whose bytes do not hash to its
+	// name: a torn write or storage corruption.
+	ProblemCorruptObject = "corrupt_object"
+	// ProblemNotCanonical is an object whose bytes match its hash but do
+	// not re-canonicalize to themselves: the encoder that wrote it has a
+	// bug hash comparison cannot see.
+	ProblemNotCanonical = "not_canonical"
+	// ProblemMisplacedObject is a file in objects/ that is not a hash, or
+	// is in the wrong shard.
+	ProblemMisplacedObject = "misplaced_object"
+	// ProblemCorruptKey is a key index file that does not parse or is
+	// not where its key belongs.
+	ProblemCorruptKey = "corrupt_key"
+	// ProblemDanglingKey is a key that points at a missing object.
+	ProblemDanglingKey = "dangling_key"
+	// ProblemUnknownStamp is a key whose stamp names a hashing pipeline
+	// this build does not have, so its object cannot be re-verified.
+	ProblemUnknownStamp = "unknown_stamp"
+	// ProblemStrayTemp is a temporary file left by an interrupted write.
+	ProblemStrayTemp = "stray_temp"
+	// ProblemPendingIntent is an intent log entry that recovery did not
+	// resolve, or that belongs to a commit still running.
+	ProblemPendingIntent = "pending_intent"
+)
+
+// Problem is one inconsistency found by Check.
+type Problem struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Key    string `json:"key,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// CheckReport is the result of Check.
+type CheckReport struct {
+	Objects   int        `json:"objects"`
+	Keys      int        `json:"keys"`
+	Recovered []Recovery `json:"recovered"`
+	Problems  []Problem  `json:"problems"`
+}
+
+// Check reads every object and key file and reports inconsistencies,
+// along with the interrupted commits that OpenFS recovered. It only reads;
+// the caller decides what to repair.
+func (b *FS) Check(ctx context.Context) (*CheckReport, error) {
+	r := &CheckReport{Recovered: append([]Recovery{}, b.recovered...), Problems: []Problem{}}
+	add := func(kind, path, key, h, detail string) {
+		r.Problems = append(r.Problems, Problem{Kind: kind, Path: path, Key: key, Hash: h, Detail: detail})
+	}
+
+	err := filepath.WalkDir(filepath.Join(b.root, "objects"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := d.Name()
+		switch {
+		case strings.HasPrefix(name, ".tmp-"):
+			add(ProblemStrayTemp, p, "", "", "")
+			return nil
+		case !ValidHash(name) || p != b.objectPath(name):
+			add(ProblemMisplacedObject, p, "", "", "")
+			return nil
+		}
+		r.Objects++
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != name {
+			add(ProblemCorruptObject, p, "", name, "content hashes to "+got)
+		} else if err := canon.VerifyRoundTrip(canonicalOf(data)); err != nil {
+			add(ProblemNotCanonical, p, "", name, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(filepath.Join(b.root, "keys"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".tmp-") {
+			add(ProblemStrayTemp, p, "", "", "")
+			return nil
+		}
+		r.Keys++
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var e KeyEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			add(ProblemCorruptKey, p, "", "", err.Error())
+			return nil
+		}
+		if p != b.keyPath(e.Key) || !ValidHash(e.Hash) {
+			add(ProblemCorruptKey, p, e.Key, e.Hash, "entry does not match its file name")
+			return nil
+		}
+		if _, err := os.Stat(b.objectPath(e.Hash)); err != nil {
+			add(ProblemDanglingKey, p, e.Key, e.Hash, "object is missing")
+		}
+		if _, err := e.Pipeline(); err != nil {
+			add(ProblemUnknownStamp, p, e.Key, e.Hash, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	intents, err := b.pendingIntents()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range intents {
+		add(ProblemPendingIntent, p, "", "", "")
+	}
+	return r, nil
+}
@@ -0,0 +1,896 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/abbrev"
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// GatewayOptions configures NewGateway.
+type GatewayOptions struct {
+	// Writable enables PUT /keys/{key...}.
+	Writable bool
+	// Metrics enables GET /metrics.
+	Metrics bool
+	// Monitor, if set, is told about every PUT, accepted or rejected, and
+	// its per-category statistics are added to GET /metrics.
+	Monitor *Monitor
+	// Tenants enables the /tenants/{tenant}/... routes.
+	Tenants bool
+	// Similar, if set, enables GET /similar?q=TEXT&k=N.
+	Similar Similarity
+	// Changes, if set, enables GET /changes.
+	Changes ChangeFeed
+	// Proofs, if set, enables GET /proofs/{key...}.
+	Proofs Prover
+	// Schemas, if set, enables GET /schemas, GET /schemas/{name}, and
+	// GET /openapi.json.
+	Schemas Schemas
+	// MaxBodyBytes bounds the size of a PUT request body; larger bodies
+	// are refused with 413 STORE_ERR_TOO_LARGE before they are parsed.
+	// Zero means 64 MiB. The store's Options.MaxCanonicalSize bounds the
+	// canonical form of the object itself.
+	MaxBodyBytes int64
+	// Identities, if set, authenticates clients by the SPIFFE ID of their
+	// verified TLS client certificate and limits each to the namespaces
+	// and keys its rules grant. The server must request and verify client
+	// certificates; see IdentityPolicy.
+	Identities *IdentityPolicy
+	// Admin enables GET /healthz, GET /readyz, and GET /config.
+	Admin bool
+	// SelfTest, if set, is a check of the hashing code, such as the
+	// built-in vectors, that GET /readyz also requires to pass.
+	SelfTest func() error
+	// Config is the runtime configuration GET /config serves as JSON. It
+	// must hold no secrets.
+	Config interface{}
+	// Reloader, if set with Admin, enables POST /admin/reload, which
+	// reloads the store's write rules.
+	Reloader *RuleReloader
+	// OnCorrupt, if set, is told about every stored object a read finds
+	// corrupt, with the tenant and the key the request named; key is ""
+	// for reads by hash. It runs in its own goroutine, so a slow hook
+	// does not hold up the response.
+	OnCorrupt func(tenant, key string, err *CorruptError)
+}
+
+// Schemas publishes JSON Schema documents for the gateway's wire formats,
+// and the OpenAPI description of its routes that refers to them. Get
+// returns the named document, or false if there is none.
+type Schemas interface {
+	Names() []string
+	Get(name string) ([]byte, bool)
+	OpenAPI(routes []Route) ([]byte, error)
+}
+
+// Prover proves that a key's current object is part of a signed
+// commitment to s, such as a checkpoint. The proof is served as JSON.
+// Prove fails with ErrUncommitted when no commitment covers the store as
+// it is now.
+type Prover interface {
+	Prove(ctx context.Context, s *Store, key string) (interface{}, error)
+}
+
+// ErrUncommitted is returned by a Prover for a store that has changed
+// since its last commitment.
+var ErrUncommitted = errors.New("store: no checkpoint covers the current state of the store")
+
+// ChangeFeed serves a change feed to the gateway. Since returns up to
+// limit changes to tenant's namespace numbered above seq, and the
+// sequence number to resume from; Wait blocks until the feed holds a
+// change numbered above seq or ctx is done.
+type ChangeFeed interface {
+	Since(tenant string, seq uint64, limit int) ([]Change, uint64)
+	Wait(ctx context.Context, seq uint64) error
+}
+
+// Similarity answers nearest-neighbor queries over the current values of
+// a namespace's keys. tenant is "" for the default namespace.
+type Similarity interface {
+	Similar(ctx context.Context, tenant, query string, k int) ([]Match, error)
+}
+
+// Match is one result of a similarity query. Score is higher for closer
+// values; it is a ranking aid, not evidence of equality.
+type Match struct {
+	Key   string  `json:"key"`
+	Hash  string  `json:"hash"`
+	Score float64 `json:"score"`
+}
+
+// maxPutBody is the default GatewayOptions.MaxBodyBytes.
+const maxPutBody = 64 << 20
+
+// NewGateway returns an HTTP handler over s:
+//
+//	GET /objects/{hash}  canonical bytes of the object with that content hash;
+//	                     a unique abbreviation redirects (302) to the full hash
+//	GET /keys            a page of the key index (prefix, category, limit, cursor)
+//	GET /keys/{key...}   canonical bytes of the key's current object, or of a
+//	                     past version with ?as_of=TIMESTAMP or ?version=N
+//	PUT /keys/{key...}   store a memory object under key (Writable only)
+//	GET /holds           the keys under legal hold
+//	PUT /holds/{key...}  put the key under legal hold (Writable only)
+//	DELETE /holds/{key...}  release the key's legal hold (Writable only)
+//	POST /hash           the content hash of the memory object in the body,
+//	                     as {"hash": ...}, without storing it; the object
+//	                     must carry _helios_schema_version
+//	GET /metrics         read counters in the Prometheus text format (Metrics only)
+//	GET /similar?q=&k=   the k keys with values nearest the text q (Similar only)
+//	GET /changes         the namespace's changes after ?since=SEQ (Changes only)
+//	GET /proofs/{key...} an inclusion proof of the key's current object in the
+//	                     namespace's latest checkpoint (Proofs only); 409
+//	                     STORE_ERR_UNCOMMITTED if the store has changed since
+//	GET /schemas         the names and URLs of the JSON Schemas (Schemas only)
+//	GET /schemas/{name}  one JSON Schema document for a wire format
+//	GET /openapi.json    an OpenAPI 3.1 description of these routes, as
+//	                     configured (Schemas only)
+//	GET /healthz         200 while the server is up (Admin only)
+//	GET /readyz          200 if the store answers and the self-test passes,
+//	                     503 otherwise (Admin only)
+//	GET /config          the runtime configuration (Admin only)
+//	POST /admin/reload   reload the store's write rules and answer the audit
+//	                     record of the reload (Admin and Reloader only)
+//
+// With Tenants, the same object and key routes under /tenants/{tenant}
+// address that tenant's store, which is isolated from the default one and
+// from every other tenant; a PUT creates the tenant on first use, and an
+// object whose tenant field names another tenant is refused with 400
+// STORE_ERR_TENANT_MISMATCH.
+//
+// GET /changes answers {"changes": [...], "next": SEQ} with at most
+// ?limit= changes; pass next as since to read on. With ?wait=DURATION
+// (at most a minute) a request that finds no changes waits for one. A
+// request that accepts text/event-stream gets a server-sent event stream
+// instead, one event per change with the sequence number as its id,
+// resuming after Last-Event-ID on reconnection.
+//
+// A PUT refused by the store's quota policy answers 403 with code
+// STORE_ERR_QUOTA_EXCEEDED; the body also carries the QuotaError fields
+// naming the limit and the usage it would have led to. A PUT of an object
+// whose residency region the store does not hold answers 403
+// STORE_ERR_RESIDENCY, and one whose residency is malformed 400
+// STORE_ERR_INVALID_RESIDENCY. A PUT whose body
+// exceeds MaxBodyBytes answers 413 STORE_ERR_TOO_LARGE, and one whose
+// object's canonical form exceeds the store's MaxCanonicalSize answers 413
+// CANON_ERR_TOO_LARGE.
+//
+// The content hash is the strong ETag of every object, and is also sent in
+// X-Helios-Hash. Reads support If-None-Match, If-Match, and Range requests
+// over the canonical bytes; key reads also carry the key's update time as
+// Last-Modified and support If-Modified-Since. Writes support If-Match
+// (compare-and-swap: the key must currently hold one of the listed hashes)
+// and If-None-Match: * (create only), answering 412 when the precondition
+// fails.
+//
+// PUT /holds/{key} with {"reason": ...} puts a key under legal hold and
+// DELETE /holds/{key} releases it, answering the audit record of the
+// change; releasing takes the rights to administer the server. GET /holds
+// lists the held keys. A held key keeps its hold across writes, and
+// neither deletion nor retention removes it or its versions.
+//
+// A PUT with an Idempotency-Key header is remembered by its namespace
+// for a bounded time (see Store.PutRequest). A retry with the same
+// Idempotency-Key answers 200 with Idempotent-Replayed: true and writes
+// nothing, so it adds no version and no change; a retry carrying a
+// different object or key answers 422 STORE_ERR_IDEMPOTENCY_MISMATCH.
+//
+// With Identities, a request without a verified client certificate
+// carrying a SPIFFE ID answers 401 STORE_ERR_UNAUTHENTICATED, and one
+// whose ID no rule matches, or whose rules do not grant the namespace,
+// key, or write it asks for, answers 403 STORE_ERR_FORBIDDEN. Any
+// matched ID may read the global routes: /metrics, /schemas, and
+// /openapi.json.
+//
+// A PUT that breaks the store's WriteRules answers 422 with the rule's
+// code: STORE_ERR_UNKNOWN_RELATIONSHIP_TYPE or STORE_ERR_SCHEMA_VIOLATION.
+// With Identities, POST /admin/reload is granted only to IDs with a rule
+// that writes anywhere in the default namespace.
+//
+// Every blob is re-hashed before it is served; a blob that no longer
+// matches its hash is reported as a 500 with code STORE_ERR_CORRUPT and
+// never returned, and to OnCorrupt. Errors are JSON bodies of the form
+// {"code": ..., "error": ...}.
+func NewGateway(s *Store, opts GatewayOptions) http.Handler {
+	g := &gateway{s: s, sim: opts.Similar, changes: opts.Changes, proofs: opts.Proofs, schemas: opts.Schemas, monitor: opts.Monitor, maxBody: opts.MaxBodyBytes, selfTest: opts.SelfTest, runtimeConfig: opts.Config, reloader: opts.Reloader, onCorrupt: opts.OnCorrupt}
+	if g.maxBody <= 0 {
+		g.maxBody = maxPutBody
+	}
+	mux := http.NewServeMux()
+	for _, rt := range g.routes(opts) {
+		mux.HandleFunc(rt.Method+" "+rt.Pattern, rt.handler)
+		g.docs = append(g.docs, rt.Route)
+	}
+	if opts.Identities != nil {
+		return authenticate(opts.Identities, mux)
+	}
+	return mux
+}
+
+type gateway struct {
+	s       *Store
+	sim     Similarity
+	changes ChangeFeed
+	proofs  Prover
+	schemas Schemas
+	monitor *Monitor
+	maxBody int64
+	docs    []Route
+
+	selfTest      func() error
+	selfTestOnce  sync.Once
+	selfTestErr   error
+	runtimeConfig interface{}
+	reloader      *RuleReloader
+	onCorrupt     func(tenant, key string, err *CorruptError)
+}
+
+// scope is the store a request addresses and the URL prefix of its
+// routes.
+type scope struct {
+	s    *Store
+	base string
+}
+
+// scoped resolves the request's tenant, if its route has one, and calls
+// fn with its store. Only writes create tenants.
+func (g *gateway) scoped(create bool, fn func(http.ResponseWriter, *http.Request, scope)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r, create) {
+			return
+		}
+		id := r.PathValue("tenant")
+		if id == "" {
+			fn(w, r, scope{s: g.s})
+			return
+		}
+		ts, err := g.s.tenant(r.Context(), id, create)
+		switch {
+		case errors.Is(err, ErrInvalidTenant):
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_TENANT", err.Error())
+		case errors.Is(err, ErrNotFound):
+			writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", "no such tenant")
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		default:
+			fn(w, r, scope{s: ts, base: "/tenants/" + id})
+		}
+	}
+}
+
+// PutResponse is the body of a successful PUT.
+type PutResponse struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash" schema:"pattern=^[0-9a-f]{64}$"`
+}
+
+func (g *gateway) put(w http.ResponseWriter, r *http.Request, sc scope) {
+	key := r.PathValue("key")
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, g.maxBody))
+	if err != nil {
+		g.monitor.RecordReject("", "STORE_ERR_TOO_LARGE")
+		writeError(w, http.StatusRequestEntityTooLarge, "STORE_ERR_TOO_LARGE", err.Error())
+		return
+	}
+	obj, err := ingest.ParseObject(data)
+	if err != nil {
+		g.monitor.RecordReject(categoryOf(data), writeCanonError(w, err))
+		return
+	}
+	if obj.Key != key {
+		g.monitor.RecordReject(obj.Category, "STORE_ERR_KEY_MISMATCH")
+		writeError(w, http.StatusBadRequest, "STORE_ERR_KEY_MISMATCH", fmt.Sprintf("object key %q does not match URL key %q", obj.Key, key))
+		return
+	}
+
+	requestID := r.Header.Get("Idempotency-Key")
+	if !validRequestID(requestID) {
+		g.monitor.RecordReject(obj.Category, "STORE_ERR_INVALID_IDEMPOTENCY_KEY")
+		writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_IDEMPOTENCY_KEY", fmt.Sprintf("Idempotency-Key must be 1 to %d printable ASCII characters", maxRequestID))
+		return
+	}
+
+	ctx := r.Context()
+	// A retry of an applied request is answered before the preconditions,
+	// which the first delivery's write may have made false.
+	if requestID != "" {
+		cur, ok, err := sc.s.replayed(ctx, obj, data, requestID)
+		if err != nil {
+			g.putFailed(w, obj, err)
+			return
+		}
+		if ok {
+			writePutResponse(w, sc, key, cur.Hash, http.StatusOK, true)
+			return
+		}
+	}
+	cur, err := sc.s.Resolve(ctx, key)
+	exists := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+
+	// Choose the CompareAndSwap expectation from the preconditions; the
+	// store re-checks it atomically with the write.
+	expected := Any
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	switch {
+	case ifMatch != "":
+		if !exists || !etagListMatches(ifMatch, cur.Hash) {
+			writePreconditionFailed(w, cur, exists)
+			return
+		}
+		expected = cur.Hash
+	case strings.TrimSpace(ifNoneMatch) == "*":
+		expected = Absent
+	case ifNoneMatch != "":
+		if exists && etagListMatches(ifNoneMatch, cur.Hash) {
+			writePreconditionFailed(w, cur, exists)
+			return
+		}
+		expected = Absent
+		if exists {
+			expected = cur.Hash
+		}
+	}
+
+	h, replayed, err := sc.s.putRequest(ctx, obj, data, expected, requestID)
+	if errors.Is(err, ErrConflict) {
+		cur, err := sc.s.Resolve(ctx, key)
+		writePreconditionFailed(w, cur, err == nil)
+		return
+	}
+	if err != nil {
+		g.putFailed(w, obj, err)
+		return
+	}
+	status := http.StatusOK
+	if !replayed {
+		g.monitor.RecordWrite(obj.Category, len(data))
+		if !exists {
+			status = http.StatusCreated
+		}
+	}
+	writePutResponse(w, sc, key, h, status, replayed)
+}
+
+// putFailed reports a write the store refused.
+func (g *gateway) putFailed(w http.ResponseWriter, obj object.MemoryObject, err error) {
+	if errors.Is(err, ErrTenantMismatch) {
+		g.monitor.RecordReject(obj.Category, "STORE_ERR_TENANT_MISMATCH")
+		writeError(w, http.StatusBadRequest, "STORE_ERR_TENANT_MISMATCH", err.Error())
+		return
+	}
+	if errors.Is(err, ErrResidency) {
+		g.monitor.RecordReject(obj.Category, "STORE_ERR_RESIDENCY")
+		writeError(w, http.StatusForbidden, "STORE_ERR_RESIDENCY", err.Error())
+		return
+	}
+	if errors.Is(err, ErrInvalidResidency) {
+		g.monitor.RecordReject(obj.Category, "STORE_ERR_INVALID_RESIDENCY")
+		writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_RESIDENCY", err.Error())
+		return
+	}
+	if errors.Is(err, ErrRequestMismatch) {
+		g.monitor.RecordReject(obj.Category, "STORE_ERR_IDEMPOTENCY_MISMATCH")
+		writeError(w, http.StatusUnprocessableEntity, "STORE_ERR_IDEMPOTENCY_MISMATCH", err.Error())
+		return
+	}
+	var re *RuleError
+	if errors.As(err, &re) {
+		g.monitor.RecordReject(obj.Category, re.Code)
+		writeError(w, http.StatusUnprocessableEntity, re.Code, err.Error())
+		return
+	}
+	var qe *QuotaError
+	if errors.As(err, &qe) {
+		g.monitor.RecordReject(obj.Category, "STORE_ERR_QUOTA_EXCEEDED")
+		writeQuotaError(w, qe)
+		return
+	}
+	var ce *canon.Error
+	if errors.As(err, &ce) {
+		g.monitor.RecordReject(obj.Category, ce.Code)
+		writeCanonError(w, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+}
+
+// writePutResponse answers a write of h to key; replayed marks the answer
+// to a retry that wrote nothing.
+func writePutResponse(w http.ResponseWriter, sc scope, key, h string, status int, replayed bool) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", `"`+h+`"`)
+	w.Header().Set("X-Helios-Hash", h)
+	w.Header().Set("Content-Location", sc.base+"/objects/"+h)
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(PutResponse{Key: key, Hash: h})
+}
+
+// maxRequestID bounds the length of an Idempotency-Key.
+const maxRequestID = 255
+
+// validRequestID reports whether id is empty or a usable Idempotency-Key.
+func validRequestID(id string) bool {
+	if len(id) > maxRequestID {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// etagListMatches reports whether header, an If-Match or If-None-Match
+// value, lists h or is "*". Weak tags never match: content hashes are
+// strong validators.
+func etagListMatches(header, h string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || hash.Equal(`"`+h+`"`, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func writePreconditionFailed(w http.ResponseWriter, cur KeyEntry, exists bool) {
+	if exists {
+		w.Header().Set("ETag", `"`+cur.Hash+`"`)
+	}
+	writeError(w, http.StatusPreconditionFailed, "STORE_ERR_PRECONDITION_FAILED", "key does not hold the expected hash")
+}
+
+// writeCanonError reports an ingest or hashing failure as a 400 carrying
+// the CANON_ERR_* code when there is one, or a 413 for an object over the
+// size limit, and returns the code it reported.
+func writeCanonError(w http.ResponseWriter, err error) string {
+	code := canon.ErrorCode(err)
+	if code == "" {
+		code = "STORE_ERR_INVALID_OBJECT"
+	}
+	status := http.StatusBadRequest
+	if code == canon.ErrCodeTooLarge {
+		status = http.StatusRequestEntityTooLarge
+	}
+	writeError(w, status, code, err.Error())
+	return code
+}
+
+// hash answers POST /hash with the content hash the default namespace's
+// write pipeline gives the object in the body, the hash API that
+// helios verify --endpoint checks against the test vectors. Unlike a
+// PUT, the body must carry _helios_schema_version, as RULE-001 requires
+// of hash input.
+func (g *gateway) hash(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, g.maxBody))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "STORE_ERR_TOO_LARGE", err.Error())
+		return
+	}
+	input, err := ingest.DecodeObject(data)
+	if err == nil {
+		err = canon.ValidateSchemaVersion(input)
+	}
+	if err == nil {
+		err = ingest.ValidateInput(input)
+	}
+	var h string
+	if err == nil {
+		obj := ingest.ToMemoryObject(input)
+		if err = hash.CheckSize(obj, g.s.opts.MaxCanonicalSize); err == nil {
+			h, err = g.s.writePipeline(g.s.rules.Load()).ContentHash(obj)
+		}
+	}
+	if err != nil {
+		writeCanonError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"hash": h})
+}
+
+func (g *gateway) object(w http.ResponseWriter, r *http.Request, sc scope) {
+	h := r.PathValue("hash")
+	if !ValidHash(h) {
+		g.expand(w, r, sc, h)
+		return
+	}
+	g.serve(w, r, sc, h, "public, max-age=31536000, immutable", time.Time{})
+}
+
+// expand redirects an abbreviated hash to the full /objects/{hash} URL.
+// The redirect is temporary: a prefix that is unique today may become
+// ambiguous as objects are added.
+func (g *gateway) expand(w http.ResponseWriter, r *http.Request, sc scope, ref string) {
+	h, err := sc.s.Expand(r.Context(), ref)
+	var amb *abbrev.AmbiguousError
+	switch {
+	case errors.Is(err, ErrInvalidRef):
+		writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_HASH",
+			fmt.Sprintf("hash must be 64 lowercase hex characters or a unique prefix of at least %d", abbrev.MinLength))
+	case errors.As(err, &amb):
+		writeError(w, http.StatusBadRequest, "STORE_ERR_AMBIGUOUS_HASH", err.Error())
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", "no such object")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+	default:
+		w.Header().Set("Cache-Control", "no-cache")
+		http.Redirect(w, r, sc.base+"/objects/"+h, http.StatusFound)
+	}
+}
+
+func (g *gateway) key(w http.ResponseWriter, r *http.Request, sc scope) {
+	q := r.URL.Query()
+	if q.Has("as_of") || q.Has("version") {
+		g.keyVersion(w, r, sc)
+		return
+	}
+	e, err := sc.s.Resolve(r.Context(), r.PathValue("key"))
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", "no such key")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	w.Header().Set("Content-Location", sc.base+"/objects/"+e.Hash)
+	updated, _ := time.Parse("2006-01-02T15:04:05.000Z", e.UpdatedAt)
+	g.serve(w, r, sc, e.Hash, "no-cache", updated)
+}
+
+// keyVersion serves a past version of a key, chosen by ?as_of=TIMESTAMP
+// (the version current then) or ?version=N, with its number in
+// X-Helios-Version.
+func (g *gateway) keyVersion(w http.ResponseWriter, r *http.Request, sc scope) {
+	q := r.URL.Query()
+	key := r.PathValue("key")
+	var v Version
+	var err error
+	if q.Has("as_of") {
+		t, perr := time.Parse(time.RFC3339Nano, q.Get("as_of"))
+		if perr != nil || q.Has("version") {
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", "as_of must be one RFC 3339 timestamp, without version")
+			return
+		}
+		v, err = sc.s.AsOf(r.Context(), key, t)
+	} else {
+		n, perr := strconv.Atoi(q.Get("version"))
+		if perr != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", "version must be a positive integer")
+			return
+		}
+		v, err = sc.s.VersionOf(r.Context(), key, n)
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	w.Header().Set("Content-Location", sc.base+"/objects/"+v.Hash)
+	w.Header().Set("X-Helios-Version", strconv.Itoa(v.Number))
+	g.serve(w, r, sc, v.Hash, "no-cache", time.Time{})
+}
+
+// serve writes the object h. modTime is the Last-Modified time, or zero
+// for none.
+func (g *gateway) serve(w http.ResponseWriter, r *http.Request, sc scope, h, cacheControl string, modTime time.Time) {
+	data, err := sc.s.get(r.Context(), h, true)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", "no such object")
+		return
+	case errors.Is(err, ErrCorrupt):
+		var ce *CorruptError
+		if errors.As(err, &ce) && g.onCorrupt != nil {
+			go g.onCorrupt(sc.s.tenantID, r.PathValue("key"), ce)
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_CORRUPT", "stored object does not match its content hash")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", `"`+h+`"`)
+	w.Header().Set("X-Helios-Hash", h)
+	http.ServeContent(w, r, "", modTime.Truncate(time.Second), bytes.NewReader(data))
+}
+
+func (g *gateway) metrics(w http.ResponseWriter, r *http.Request) {
+	st := g.s.ReadStats()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, `# HELP helios_store_reads_total Objects read from the store, by whether their hash was verified.
+# TYPE helios_store_reads_total counter
+helios_store_reads_total{verified="true"} %d
+helios_store_reads_total{verified="false"} %d
+# HELP helios_store_corrupt_reads_total Verified reads whose bytes did not match their content hash.
+# TYPE helios_store_corrupt_reads_total counter
+helios_store_corrupt_reads_total %d
+`, st.Verified, st.Reads-st.Verified, st.Corrupt)
+	if c := g.s.opts.HashCache; c != nil {
+		cs := c.Stats()
+		fmt.Fprintf(w, `# HELP helios_hash_cache_requests_total Hash cache lookups, by result.
+# TYPE helios_hash_cache_requests_total counter
+helios_hash_cache_requests_total{result="hit"} %d
+helios_hash_cache_requests_total{result="miss"} %d
+# HELP helios_hash_cache_evictions_total Hash cache entries evicted.
+# TYPE helios_hash_cache_evictions_total counter
+helios_hash_cache_evictions_total %d
+# HELP helios_hash_cache_entries Documents held in the hash cache.
+# TYPE helios_hash_cache_entries gauge
+helios_hash_cache_entries %d
+`, cs.Hits, cs.Misses, cs.Evictions, cs.Entries)
+	}
+	g.monitor.WritePrometheus(w)
+}
+
+// list answers GET /keys with one page of the key index as
+// {"keys": [...], "next_cursor": ...}; next_cursor is omitted on the last
+// page.
+func (g *gateway) list(w http.ResponseWriter, r *http.Request, sc scope) {
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageSize {
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+			return
+		}
+		limit = n
+	}
+	page, err := sc.s.List(r.Context(), q.Get("prefix"), q.Get("category"), limit, q.Get("cursor"))
+	switch {
+	case errors.Is(err, ErrInvalidCursor):
+		writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_CURSOR", err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// similar answers a nearest-neighbor query with a JSON array of matches.
+func (g *gateway) similar(w http.ResponseWriter, r *http.Request, sc scope) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", "missing query parameter q")
+		return
+	}
+	k := 10
+	if v := r.URL.Query().Get("k"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", "k must be between 1 and 1000")
+			return
+		}
+		k = n
+	}
+	matches, err := g.sim.Similar(r.Context(), sc.s.TenantID(), q, k)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	if matches == nil {
+		matches = []Match{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
+
+// Change feed limits: the longest long poll, and how often an idle event
+// stream is sent a comment to keep proxies from closing it.
+const (
+	maxChangeWait    = time.Minute
+	changeStreamPing = 15 * time.Second
+)
+
+// ChangesResponse is the body of a GET /changes.
+type ChangesResponse struct {
+	Changes []Change `json:"changes"`
+	Next    uint64   `json:"next"`
+}
+
+// changeFeed answers GET /changes as a page or, for a client that accepts
+// text/event-stream, as an event stream.
+func (g *gateway) changeFeed(w http.ResponseWriter, r *http.Request, sc scope) {
+	q := r.URL.Query()
+	var since uint64
+	if v := q.Get("since"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", "since must be a sequence number")
+			return
+		}
+		since = n
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", "Last-Event-ID must be a sequence number")
+				return
+			}
+			since = n
+		}
+		g.streamChanges(w, r, sc.s.TenantID(), since)
+		return
+	}
+
+	limit := DefaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageSize {
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+			return
+		}
+		limit = n
+	}
+	var wait time.Duration
+	if v := q.Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 || d > maxChangeWait {
+			writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_QUERY", fmt.Sprintf("wait must be a duration of at most %s", maxChangeWait))
+			return
+		}
+		wait = d
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	tenant := sc.s.TenantID()
+	changes, next := g.changes.Since(tenant, since, limit)
+	// Another tenant's changes advance next without answering the poll.
+	for len(changes) == 0 && g.changes.Wait(ctx, next) == nil {
+		changes, next = g.changes.Since(tenant, next, limit)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(ChangesResponse{Changes: changes, Next: next})
+}
+
+// streamChanges sends tenant's changes after since as server-sent events
+// until the client goes away.
+func (g *gateway) streamChanges(w http.ResponseWriter, r *http.Request, tenant string, since uint64) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+	for {
+		changes, next := g.changes.Since(tenant, since, MaxPageSize)
+		for _, c := range changes {
+			data, _ := json.Marshal(c)
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.Seq, c.Op, data)
+		}
+		if len(changes) > 0 {
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+		if next != since {
+			since = next
+			continue
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), changeStreamPing)
+		err := g.changes.Wait(ctx, since)
+		cancel()
+		switch {
+		case r.Context().Err() != nil:
+			return
+		case err != nil:
+			fmt.Fprint(w, ": ping\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// proof answers GET /proofs/{key...} with the key's inclusion proof.
+func (g *gateway) proof(w http.ResponseWriter, r *http.Request, sc scope) {
+	p, err := g.proofs.Prove(r.Context(), sc.s, r.PathValue("key"))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", "no such key")
+		return
+	case errors.Is(err, ErrUncommitted):
+		writeError(w, http.StatusConflict, "STORE_ERR_UNCOMMITTED", err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(p)
+}
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// QuotaErrorResponse is the body of a PUT refused by the quota policy.
+type QuotaErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+	*QuotaError
+}
+
+// writeQuotaError reports a put refused by a quota.
+func writeQuotaError(w http.ResponseWriter, qe *QuotaError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(QuotaErrorResponse{"STORE_ERR_QUOTA_EXCEEDED", qe.Error(), qe})
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Code: code, Error: msg})
+}
+
+// schemaIndex answers GET /schemas with {"schemas": {NAME: URL, ...}}.
+func (g *gateway) schemaIndex(w http.ResponseWriter, r *http.Request) {
+	index := make(map[string]string)
+	for _, name := range g.schemas.Names() {
+		index[name] = "/schemas/" + name + ".json"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"schemas": index})
+}
+
+// schema answers GET /schemas/{name}, with or without a .json suffix.
+func (g *gateway) schema(w http.ResponseWriter, r *http.Request) {
+	doc, ok := g.schemas.Get(strings.TrimSuffix(r.PathValue("name"), ".json"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", "no such schema")
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(doc)
+}
+
+// openapi answers GET /openapi.json with the description of g's routes.
+func (g *gateway) openapi(w http.ResponseWriter, r *http.Request) {
+	doc, err := g.schemas.OpenAPI(g.docs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
@@ -0,0 +1,392 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+func get(t *testing.T, srv *httptest.Server, path string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestGateway(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	h, _ := s.Put(ctx, testObject("notes/é a", "hello"))
+	canonical, _ := s.Get(ctx, h)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{}))
+	defer srv.Close()
+
+	resp, body := get(t, srv, "/objects/"+h, nil)
+	if resp.StatusCode != 200 || body != string(canonical) {
+		t.Fatalf("GET object: %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("ETag") != `"`+h+`"` || resp.Header.Get("X-Helios-Hash") != h {
+		t.Errorf("headers %v", resp.Header)
+	}
+
+	resp, body = get(t, srv, "/keys/"+url.PathEscape("notes/é a"), nil)
+	if resp.StatusCode != 200 || body != string(canonical) {
+		t.Fatalf("GET key: %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Content-Location") != "/objects/"+h {
+		t.Errorf("Content-Location %q", resp.Header.Get("Content-Location"))
+	}
+
+	resp, _ = get(t, srv, "/objects/"+h, map[string]string{"If-None-Match": `"` + h + `"`})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("If-None-Match: %d", resp.StatusCode)
+	}
+	resp, body = get(t, srv, "/objects/"+h, map[string]string{"Range": "bytes=1-29"})
+	if resp.StatusCode != http.StatusPartialContent || body != string(canonical[1:30]) {
+		t.Errorf("Range: %d %q", resp.StatusCode, body)
+	}
+
+	resp, body = get(t, srv, "/objects/"+h[:8], nil)
+	if resp.StatusCode != 200 || body != string(canonical) || resp.Request.URL.Path != "/objects/"+h {
+		t.Errorf("abbreviated hash: %d at %s", resp.StatusCode, resp.Request.URL.Path)
+	}
+
+	for path, want := range map[string]int{
+		"/objects/xyz":             400,
+		"/objects/abc":             400,
+		"/objects/" + h[:63] + "0": 404,
+		"/keys/missing":            404,
+	} {
+		resp, body := get(t, srv, path, nil)
+		var e struct{ Code, Error string }
+		if resp.StatusCode != want || json.Unmarshal([]byte(body), &e) != nil || e.Code == "" {
+			t.Errorf("%s: %d %s", path, resp.StatusCode, body)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/objects/"+h, nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("PUT on read-only gateway: %v %v", resp.StatusCode, err)
+	}
+}
+
+func TestGatewayRefusesCorruptObject(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	h, _ := s.Put(ctx, testObject("k", "hello"))
+	os.WriteFile(s.Backend().(*FS).objectPath(h), []byte(`{"tampered":true}`), 0644)
+	type report struct {
+		key string
+		err *CorruptError
+	}
+	reports := make(chan report, 2)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Metrics: true, OnCorrupt: func(tenant, key string, err *CorruptError) {
+		reports <- report{key, err}
+	}}))
+	defer srv.Close()
+
+	resp, body := get(t, srv, "/objects/"+h, nil)
+	if resp.StatusCode != 500 || !json.Valid([]byte(body)) {
+		t.Fatalf("corrupt object: %d %s", resp.StatusCode, body)
+	}
+	var e struct{ Code string }
+	json.Unmarshal([]byte(body), &e)
+	if e.Code != "STORE_ERR_CORRUPT" {
+		t.Errorf("code %q", e.Code)
+	}
+
+	get(t, srv, "/keys/k", nil)
+	// The hook runs in its own goroutine, so the reports may be in
+	// either order.
+	keys := map[string]bool{}
+	for range 2 {
+		r := <-reports
+		keys[r.key] = true
+		if r.err.Hash != h || r.err.Actual == h {
+			t.Errorf("OnCorrupt(%q, %v), want object %s", r.key, r.err, h)
+		}
+	}
+	if !keys[""] || !keys["k"] {
+		t.Errorf("OnCorrupt keys %v, want the hash read's and k", keys)
+	}
+
+	_, metrics := get(t, srv, "/metrics", nil)
+	for _, line := range []string{`helios_store_reads_total{verified="true"} 2`, "helios_store_corrupt_reads_total 2"} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("/metrics lacks %q:\n%s", line, metrics)
+		}
+	}
+}
+
+func put(t *testing.T, srv *httptest.Server, key, body string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/keys/"+key, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func objectJSON(key, value string) string {
+	return `{"category":"project","created_at":"2025-01-15T10:30:00.000Z","key":"` + key + `","relationships":[],"source":"user","value":"` + value + `"}`
+}
+
+func TestGatewayConditionalWrites(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true}))
+	defer srv.Close()
+
+	resp, body := put(t, srv, "notes/a", objectJSON("notes/a", "v1"), map[string]string{"If-None-Match": "*"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: %d %s", resp.StatusCode, body)
+	}
+	v1 := strings.Trim(resp.Header.Get("ETag"), `"`)
+	if e, _ := s.Resolve(ctx, "notes/a"); e.Hash != v1 {
+		t.Errorf("store holds %s, ETag %s", e.Hash, v1)
+	}
+
+	// Create-only fails once the key exists.
+	resp, _ = put(t, srv, "notes/a", objectJSON("notes/a", "v2"), map[string]string{"If-None-Match": "*"})
+	if resp.StatusCode != http.StatusPreconditionFailed || resp.Header.Get("ETag") != `"`+v1+`"` {
+		t.Errorf("second create: %d ETag %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	// Compare-and-swap succeeds against the current hash only.
+	resp, _ = put(t, srv, "notes/a", objectJSON("notes/a", "v2"), map[string]string{"If-Match": `"` + v1 + `"`})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("CAS from v1: %d", resp.StatusCode)
+	}
+	v2 := strings.Trim(resp.Header.Get("ETag"), `"`)
+	resp, _ = put(t, srv, "notes/a", objectJSON("notes/a", "v3"), map[string]string{"If-Match": `"` + v1 + `", W/"` + v2 + `"`})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("stale CAS (weak tag for current): %d", resp.StatusCode)
+	}
+	if e, _ := s.Resolve(ctx, "notes/a"); e.Hash != v2 {
+		t.Errorf("failed CAS changed the key to %s", e.Hash)
+	}
+	resp, _ = put(t, srv, "notes/b", objectJSON("notes/b", "v1"), map[string]string{"If-Match": "*"})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("If-Match * on a missing key: %d", resp.StatusCode)
+	}
+
+	// Reads honor If-Match too.
+	resp, _ = get(t, srv, "/keys/notes/a", map[string]string{"If-Match": `"` + v1 + `"`})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("GET with stale If-Match: %d", resp.StatusCode)
+	}
+	resp, _ = get(t, srv, "/keys/notes/a", map[string]string{"If-None-Match": `"` + v2 + `"`})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("GET with current If-None-Match: %d", resp.StatusCode)
+	}
+
+	for name, tc := range map[string]struct {
+		key, body, code string
+	}{
+		"key mismatch": {"notes/c", objectJSON("notes/other", "x"), "STORE_ERR_KEY_MISMATCH"},
+		"float value":  {"notes/c", `{"key":"notes/c","created_at":"2025-01-15T10:30:00.000Z","value":1.5}`, "CANON_ERR_FLOAT_PROHIBITED"},
+		"bad json":     {"notes/c", `{`, "STORE_ERR_INVALID_OBJECT"},
+	} {
+		resp, body := put(t, srv, tc.key, tc.body, nil)
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, tc.code) {
+			t.Errorf("%s: %d %s", name, resp.StatusCode, body)
+		}
+	}
+}
+
+type fakeSchemas map[string]string
+
+func (f fakeSchemas) Names() []string {
+	var names []string
+	for name := range f {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (f fakeSchemas) Get(name string) ([]byte, bool) {
+	doc, ok := f[name]
+	return []byte(doc), ok
+}
+
+func (f fakeSchemas) OpenAPI(routes []Route) ([]byte, error) {
+	return json.Marshal(routes)
+}
+
+func TestGatewaySchemas(t *testing.T) {
+	s := newTestStore(t)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{}))
+	if resp, _ := get(t, srv, "/schemas", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /schemas without Schemas: %d", resp.StatusCode)
+	}
+	srv.Close()
+
+	srv = httptest.NewServer(NewGateway(s, GatewayOptions{Schemas: fakeSchemas{"memory-object": `{"type":"object"}`}}))
+	defer srv.Close()
+	resp, body := get(t, srv, "/schemas", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"memory-object":"/schemas/memory-object.json"`) {
+		t.Errorf("GET /schemas: %d %s", resp.StatusCode, body)
+	}
+	for _, path := range []string{"/schemas/memory-object", "/schemas/memory-object.json"} {
+		resp, body := get(t, srv, path, nil)
+		if resp.StatusCode != http.StatusOK || body != `{"type":"object"}` || resp.Header.Get("Content-Type") != "application/schema+json" {
+			t.Errorf("GET %s: %d %q %s", path, resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		}
+	}
+	if resp, _ := get(t, srv, "/schemas/nope.json", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of an unknown schema: %d", resp.StatusCode)
+	}
+
+	// /openapi.json describes exactly the routes the gateway serves.
+	resp, body = get(t, srv, "/openapi.json", nil)
+	var routes []Route
+	if err := json.Unmarshal([]byte(body), &routes); resp.StatusCode != http.StatusOK || err != nil {
+		t.Fatalf("GET /openapi.json: %d %v", resp.StatusCode, err)
+	}
+	var patterns []string
+	for _, rt := range routes {
+		patterns = append(patterns, rt.Method+" "+rt.Pattern)
+	}
+	want := "GET /objects/{hash} GET /keys GET /keys/{key...} GET /holds POST /hash GET /schemas GET /schemas/{name} GET /openapi.json"
+	if got := strings.Join(patterns, " "); got != want {
+		t.Errorf("described routes:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGatewayIdempotentWrites(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true}))
+	defer srv.Close()
+
+	create := map[string]string{"If-None-Match": "*", "Idempotency-Key": "req-1"}
+	resp, body := put(t, srv, "notes/a", objectJSON("notes/a", "v1"), create)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first delivery: %d %v %s", resp.StatusCode, resp.Header, body)
+	}
+	first, _ := s.Resolve(ctx, "notes/a")
+	if first.RequestID != "req-1" {
+		t.Errorf("entry request id = %q, want req-1", first.RequestID)
+	}
+
+	// The retry's If-None-Match no longer holds, but it is answered as
+	// the write it repeats.
+	resp, body = put(t, srv, "notes/a", objectJSON("notes/a", "v1"), create)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry: %d %v %s", resp.StatusCode, resp.Header, body)
+	}
+	if got := strings.Trim(resp.Header.Get("ETag"), `"`); got != first.Hash {
+		t.Errorf("retry ETag = %s, want %s", got, first.Hash)
+	}
+	if e, _ := s.Resolve(ctx, "notes/a"); e != first {
+		t.Errorf("retry rewrote the entry: %+v, was %+v", e, first)
+	}
+
+	resp, body = put(t, srv, "notes/a", objectJSON("notes/a", "v2"), map[string]string{"Idempotency-Key": "req-1"})
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(body, "STORE_ERR_IDEMPOTENCY_MISMATCH") {
+		t.Errorf("reused key: %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = put(t, srv, "notes/a", objectJSON("notes/a", "v2"), map[string]string{"Idempotency-Key": "req 2"})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "STORE_ERR_INVALID_IDEMPOTENCY_KEY") {
+		t.Errorf("invalid key: %d %s", resp.StatusCode, body)
+	}
+
+	// A late retry, after another request overwrote the key, is still
+	// answered as the write it repeats and leaves the key alone.
+	put(t, srv, "notes/a", objectJSON("notes/a", "v2"), map[string]string{"Idempotency-Key": "req-2"})
+	second, _ := s.Resolve(ctx, "notes/a")
+	resp, body = put(t, srv, "notes/a", objectJSON("notes/a", "v1"), map[string]string{"Idempotency-Key": "req-1"})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("late retry: %d %v %s", resp.StatusCode, resp.Header, body)
+	}
+	if e, _ := s.Resolve(ctx, "notes/a"); e != second {
+		t.Errorf("late retry rewrote the entry: %+v, was %+v", e, second)
+	}
+}
+
+func TestGatewayHashCache(t *testing.T) {
+	ctx := context.Background()
+	cache := hash.NewCache(10, 0)
+	s := NewWithOptions(NewMemory(), Options{HashCache: cache})
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true, Metrics: true}))
+	defer srv.Close()
+
+	body := objectJSON("notes/a", "v1")
+	for i, id := range []string{"", "", "req-1", "req-1"} {
+		resp, out := put(t, srv, "notes/a", body, map[string]string{"Idempotency-Key": id})
+		if resp.StatusCode/100 != 2 {
+			t.Fatalf("put %d: %d %s", i, resp.StatusCode, out)
+		}
+	}
+	want, _ := hash.ContentHash(testObject("notes/a", "v1"))
+	if e, _ := s.Resolve(ctx, "notes/a"); e.Hash != want {
+		t.Errorf("stored %s, want %s", e.Hash, want)
+	}
+	// The first write canonicalizes; the later writes and the replay
+	// check are served from the cache.
+	if st := cache.Stats(); st.Misses != 1 || st.Hits != 3 {
+		t.Errorf("cache stats %+v", st)
+	}
+	_, metrics := get(t, srv, "/metrics", nil)
+	if !strings.Contains(metrics, `helios_hash_cache_requests_total{result="hit"} 3`+"\n") {
+		t.Errorf("/metrics lacks the cache hits:\n%s", metrics)
+	}
+}
+
+func TestGatewayAdmin(t *testing.T) {
+	s := newTestStore(t)
+	selfTest := errors.New("vectors failed")
+	calls := 0
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{
+		Admin:    true,
+		SelfTest: func() error { calls++; return selfTest },
+		Config:   map[string]interface{}{"addr": "127.0.0.1:8080", "writable": false},
+	}))
+	defer srv.Close()
+
+	if resp, body := get(t, srv, "/healthz", nil); resp.StatusCode != http.StatusOK || body != "{\"status\":\"ok\"}\n" {
+		t.Errorf("GET /healthz: %d %s", resp.StatusCode, body)
+	}
+	for i := 0; i < 2; i++ {
+		resp, body := get(t, srv, "/readyz", nil)
+		if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(body, `{"name":"selftest","ok":false,"error":"vectors failed"}`) {
+			t.Errorf("GET /readyz: %d %s", resp.StatusCode, body)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("self-test ran %d times, want once", calls)
+	}
+	if resp, body := get(t, srv, "/config", nil); resp.StatusCode != http.StatusOK || body != "{\"addr\":\"127.0.0.1:8080\",\"writable\":false}\n" {
+		t.Errorf("GET /config: %d %s", resp.StatusCode, body)
+	}
+
+	ready := httptest.NewServer(NewGateway(s, GatewayOptions{Admin: true}))
+	defer ready.Close()
+	if resp, body := get(t, ready, "/readyz", nil); resp.StatusCode != http.StatusOK || !strings.Contains(body, `"status":"ok"`) {
+		t.Errorf("GET /readyz without a self-test: %d %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Version is one entry of a key's history.
+type Version struct {
+	// Number counts the key's versions from 1, oldest first.
+	Number    int    `json:"version"`
+	Hash      string `json:"hash"`
+	CreatedAt string `json:"created_at"`
+	Category  string `json:"category"`
+	// Current is set on the version the key points at now.
+	Current bool `json:"current"`
+}
+
+// version is a stored object as history and retention see it.
+type version struct {
+	hash      string
+	category  string
+	createdAt string
+	created   time.Time
+	size      int64
+}
+
+// versions reads every stored object and groups those whose key match
+// accepts (all of them if match is nil) by key. It also returns the
+// number of objects read.
+//
+// A key's versions are the stored objects that carry its key, which are
+// exactly the objects the key has ever pointed at: the key is part of
+// every content hash, so no object belongs to two keys. Versions stay
+// until deleted by a retention policy.
+func (s *Store) versions(ctx context.Context, match func(key string) bool) (map[string][]version, int, error) {
+	hashes, err := s.b.List(ctx, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	byKey := make(map[string][]version)
+	for _, h := range hashes {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		data, err := s.b.Get(ctx, h)
+		if err != nil {
+			return nil, 0, err
+		}
+		var head struct {
+			Category  string `json:"category"`
+			CreatedAt string `json:"created_at"`
+			Key       string `json:"key"`
+		}
+		if err := json.Unmarshal(canonicalOf(data), &head); err != nil {
+			return nil, 0, fmt.Errorf("stored object %s: %w", h, err)
+		}
+		if match != nil && !match(head.Key) {
+			continue
+		}
+		v := version{hash: h, category: head.Category, createdAt: head.CreatedAt, size: int64(len(data))}
+		v.created, _ = time.Parse(time.RFC3339Nano, head.CreatedAt)
+		byKey[head.Key] = append(byKey[head.Key], v)
+	}
+	return byKey, len(hashes), nil
+}
+
+// History returns every stored version of key, oldest first by created_at
+// (ties broken by hash), or ErrNotFound if there are none. A deleted key
+// keeps its history. History reads every stored object; it is meant for
+// investigations, not hot paths.
+func (s *Store) History(ctx context.Context, key string) ([]Version, error) {
+	byKey, _, err := s.versions(ctx, func(k string) bool { return k == key })
+	if err != nil {
+		return nil, err
+	}
+	vs := byKey[key]
+	if len(vs) == 0 {
+		return nil, fmt.Errorf("%w: no versions of key %q", ErrNotFound, key)
+	}
+	sort.Slice(vs, func(i, j int) bool {
+		if !vs[i].created.Equal(vs[j].created) {
+			return vs[i].created.Before(vs[j].created)
+		}
+		return vs[i].hash < vs[j].hash
+	})
+	cur := ""
+	if e, err := s.b.ResolveKey(ctx, key); err == nil {
+		cur = e.Hash
+	}
+	out := make([]Version, len(vs))
+	for i, v := range vs {
+		out[i] = Version{Number: i + 1, Hash: v.hash, CreatedAt: v.createdAt, Category: v.category, Current: v.hash == cur}
+	}
+	return out, nil
+}
+
+// AsOf returns the version of key that was current at t: the newest by
+// created_at that is not after t. It fails with ErrNotFound if every
+// version is newer.
+func (s *Store) AsOf(ctx context.Context, key string, t time.Time) (Version, error) {
+	history, err := s.History(ctx, key)
+	if err != nil {
+		return Version{}, err
+	}
+	i := sort.Search(len(history), func(i int) bool {
+		created, err := time.Parse(time.RFC3339Nano, history[i].CreatedAt)
+		return err == nil && created.After(t)
+	})
+	if i == 0 {
+		return Version{}, fmt.Errorf("%w: key %q has no version created by %s", ErrNotFound, key, t.UTC().Format(time.RFC3339Nano))
+	}
+	return history[i-1], nil
+}
+
+// VersionOf returns version n of key, counting from 1 as History does.
+func (s *Store) VersionOf(ctx context.Context, key string, n int) (Version, error) {
+	history, err := s.History(ctx, key)
+	if err != nil {
+		return Version{}, err
+	}
+	if n < 1 || n > len(history) {
+		return Version{}, fmt.Errorf("%w: key %q has %d versions, not %d", ErrNotFound, key, len(history), n)
+	}
+	return history[n-1], nil
+}
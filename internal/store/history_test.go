@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	put := func(value, created string) string {
+		t.Helper()
+		obj := testObject("notes/a", value)
+		obj.CreatedAt = created
+		h, err := s.Put(ctx, obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	v2 := put("second", "2025-02-01T00:00:00.000Z")
+	v1 := put("first", "2025-01-01T00:00:00.000Z")
+	v3 := put("third", "2025-03-01T00:00:00.000Z")
+	if _, err := s.Put(ctx, testObject("notes/b", "other key")); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := s.History(ctx, "notes/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Hash != v1 || history[1].Hash != v2 || history[2].Hash != v3 {
+		t.Fatalf("History = %+v", history)
+	}
+	for i, v := range history {
+		if v.Number != i+1 || v.Current != (i == 2) {
+			t.Errorf("version %d: %+v", i+1, v)
+		}
+	}
+
+	for at, want := range map[string]string{
+		"2025-01-01T00:00:00.000Z": v1,
+		"2025-02-15T12:00:00Z":     v2,
+		"2026-01-01T00:00:00Z":     v3,
+	} {
+		tm, _ := time.Parse(time.RFC3339Nano, at)
+		if v, err := s.AsOf(ctx, "notes/a", tm); err != nil || v.Hash != want {
+			t.Errorf("AsOf(%s) = %+v, %v", at, v, err)
+		}
+	}
+	if _, err := s.AsOf(ctx, "notes/a", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AsOf before the first version: %v", err)
+	}
+	if v, err := s.VersionOf(ctx, "notes/a", 2); err != nil || v.Hash != v2 {
+		t.Errorf("VersionOf(2) = %+v, %v", v, err)
+	}
+	if _, err := s.VersionOf(ctx, "notes/a", 4); !errors.Is(err, ErrNotFound) {
+		t.Errorf("VersionOf(4): %v", err)
+	}
+
+	// A deleted key keeps its history.
+	if err := s.Delete(ctx, "notes/a"); err != nil {
+		t.Fatal(err)
+	}
+	if history, err := s.History(ctx, "notes/a"); err != nil || len(history) != 3 || history[2].Current {
+		t.Errorf("History after delete = %+v, %v", history, err)
+	}
+	if _, err := s.History(ctx, "notes/zz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("History of an unknown key: %v", err)
+	}
+}
+
+func TestGatewayKeyVersions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	old := testObject("k", "old")
+	old.CreatedAt = "2024-01-01T00:00:00.000Z"
+	h1, _ := s.Put(ctx, old)
+	h2, _ := s.Put(ctx, testObject("k", "new"))
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{}))
+	defer srv.Close()
+
+	for path, want := range map[string]string{
+		"/keys/k?as_of=2024-06-01T00:00:00Z": h1,
+		"/keys/k?version=2":                  h2,
+		"/keys/k":                            h2,
+	} {
+		resp, body := get(t, srv, path, nil)
+		if resp.StatusCode != 200 || resp.Header.Get("X-Helios-Hash") != want {
+			t.Errorf("GET %s: %d %s", path, resp.StatusCode, body)
+		}
+	}
+	if resp, _ := get(t, srv, "/keys/k?version=1", nil); resp.Header.Get("X-Helios-Version") != "1" {
+		t.Errorf("X-Helios-Version = %q", resp.Header.Get("X-Helios-Version"))
+	}
+	for path, want := range map[string]int{
+		"/keys/k?as_of=2000-01-01T00:00:00Z":           http.StatusNotFound,
+		"/keys/k?version=3":                            http.StatusNotFound,
+		"/keys/k?version=0":                            http.StatusBadRequest,
+		"/keys/k?as_of=yesterday":                      http.StatusBadRequest,
+		"/keys/k?as_of=2024-06-01T00:00:00Z&version=1": http.StatusBadRequest,
+	} {
+		if resp, body := get(t, srv, path, nil); resp.StatusCode != want {
+			t.Errorf("GET %s: %d %s, want %d", path, resp.StatusCode, body, want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Hold operations, as recorded in a HoldEvent.
+const (
+	HoldSet     = "hold"
+	HoldRelease = "release"
+)
+
+// maxHoldReason bounds the reason recorded with a legal hold.
+const maxHoldReason = 256
+
+// Errors for legal holds.
+var (
+	ErrInvalidHold = errors.New("store: a legal hold's reason is 1-256 printable characters on one line")
+	// ErrHeld is returned when deleting a key under a legal hold.
+	ErrHeld = errors.New("store: key is under legal hold")
+	// ErrNotHeld is returned when releasing a key without a legal hold.
+	ErrNotHeld = errors.New("store: key is not under legal hold")
+)
+
+// HoldEvent is the audit record of a legal hold set or released.
+type HoldEvent struct {
+	Time   string `json:"time"`
+	Tenant string `json:"tenant,omitempty"`
+	Op     string `json:"op" schema:"enum=hold|release"`
+	Key    string `json:"key"`
+	// Hash is the object the key held at the time.
+	Hash string `json:"hash" schema:"pattern=^[0-9a-f]{64}$"`
+	// Reason is the hold's reason, such as a matter number; on release,
+	// the reason of the hold released.
+	Reason string `json:"reason"`
+	// Trigger names what set or released the hold, such as "cli", "api",
+	// or "put" for an object written with a legal_hold field.
+	Trigger string `json:"trigger"`
+	// Identity is the SPIFFE ID of the client that asked, if any.
+	Identity string `json:"identity,omitempty"`
+}
+
+// ValidHoldReason reports whether reason may be recorded with a legal hold.
+func ValidHoldReason(reason string) bool {
+	if reason == "" || len(reason) > maxHoldReason {
+		return false
+	}
+	for _, c := range reason {
+		if !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hold puts key under legal hold for reason, or replaces the reason of its
+// hold. A held key cannot be deleted, and retention deletes none of its
+// versions, until the hold is released; writes to it keep the hold.
+func (s *Store) Hold(ctx context.Context, key, reason, trigger string) (HoldEvent, error) {
+	if !ValidHoldReason(reason) {
+		return HoldEvent{}, ErrInvalidHold
+	}
+	return s.setHold(ctx, key, reason, trigger)
+}
+
+// Release lifts the legal hold on key.
+func (s *Store) Release(ctx context.Context, key, trigger string) (HoldEvent, error) {
+	return s.setHold(ctx, key, "", trigger)
+}
+
+// setHold sets the hold of key's index entry to reason, "" releasing it,
+// retrying if the key is rewritten meanwhile, and audits the change.
+func (s *Store) setHold(ctx context.Context, key, reason, trigger string) (HoldEvent, error) {
+	for {
+		e, err := s.b.ResolveKey(ctx, key)
+		if err != nil {
+			return HoldEvent{}, err
+		}
+		if reason == "" && e.LegalHold == "" {
+			return HoldEvent{}, fmt.Errorf("%w: %q", ErrNotHeld, key)
+		}
+		ev := s.holdEvent(ctx, HoldSet, e, reason, trigger)
+		if reason == "" {
+			ev.Op, ev.Reason = HoldRelease, e.LegalHold
+		}
+		e.LegalHold = reason
+		err = s.b.SetKey(ctx, e, e.Hash)
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return HoldEvent{}, err
+		}
+		s.audit(ev)
+		return ev, nil
+	}
+}
+
+func (s *Store) holdEvent(ctx context.Context, op string, e KeyEntry, reason, trigger string) HoldEvent {
+	ev := HoldEvent{
+		Time: s.now().UTC().Format("2006-01-02T15:04:05.000Z"), Tenant: s.tenantID,
+		Op: op, Key: e.Key, Hash: e.Hash, Reason: reason, Trigger: trigger,
+	}
+	if who, ok := ctx.Value(identityKey{}).(*identity); ok {
+		ev.Identity = who.id
+	}
+	return ev
+}
+
+func (s *Store) audit(ev HoldEvent) {
+	if s.opts.HoldAudit != nil {
+		s.opts.HoldAudit(ev)
+	}
+}
+
+// heldReason returns the hold a put of obj leaves on its key: the
+// object's legal_hold field if set, and otherwise the hold the key
+// already has, so that only Release lifts one. Like quota accounting, a
+// hold set concurrently by another process can be lost to a racing put.
+func (s *Store) heldReason(ctx context.Context, obj object.MemoryObject) (reason string, changed bool, err error) {
+	want := obj.LegalHold
+	if want != "" && !ValidHoldReason(want) {
+		return "", false, fmt.Errorf("%w: key %q", ErrInvalidHold, obj.Key)
+	}
+	cur, err := s.b.ResolveKey(ctx, obj.Key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", false, err
+	}
+	if want == "" {
+		return cur.LegalHold, false, nil
+	}
+	return want, want != cur.LegalHold, nil
+}
+
+// HeldKey is one key of a hold report.
+type HeldKey struct {
+	Key      string `json:"key"`
+	Hash     string `json:"hash" schema:"pattern=^[0-9a-f]{64}$"`
+	Category string `json:"category,omitempty"`
+	// Reason is the key's legal hold reason, empty for a key held only by
+	// the retention policy.
+	Reason string `json:"reason,omitempty"`
+	// Policy says why the retention policy holds the key, if it does:
+	// "prefix P" or "category C".
+	Policy string `json:"policy,omitempty"`
+}
+
+// Holds reports every key under legal hold, sorted by key: the keys with a
+// hold of their own and, if p is not nil, those p's legal holds cover.
+func (s *Store) Holds(ctx context.Context, p *RetentionPolicy) ([]HeldKey, error) {
+	entries, err := s.b.ListKeys(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	out := []HeldKey{}
+	for _, e := range entries {
+		h := HeldKey{Key: e.Key, Hash: e.Hash, Category: e.Category, Reason: e.LegalHold}
+		if p != nil {
+			h.Policy = p.holdOf(e.Key, e.Category)
+		}
+		if h.Reason != "" || h.Policy != "" {
+			out = append(out, h)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// holdOf says why p holds key, whose current object has category, or
+// returns "" if it does not.
+func (p *RetentionPolicy) holdOf(key, category string) string {
+	for _, prefix := range p.LegalHold {
+		if strings.HasPrefix(key, prefix) {
+			return "prefix " + prefix
+		}
+	}
+	if r, ok := p.rule(category); ok && r.LegalHold {
+		return "category " + category
+	}
+	return ""
+}
+
+// HoldRequest is the body of PUT /holds/{key}.
+type HoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+// HoldsResponse is the body of GET /holds.
+type HoldsResponse struct {
+	Holds []HeldKey `json:"holds"`
+}
+
+// holds answers GET /holds with the keys under legal hold.
+func (g *gateway) holds(w http.ResponseWriter, r *http.Request, sc scope) {
+	held, err := sc.s.Holds(r.Context(), nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(HoldsResponse{Holds: held})
+}
+
+// hold answers PUT /holds/{key} with the HoldEvent of putting the key
+// under legal hold.
+func (g *gateway) hold(w http.ResponseWriter, r *http.Request, sc scope) {
+	var req HoldRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_HOLD", "the body must be {\"reason\": \"...\"}")
+		return
+	}
+	ev, err := sc.s.Hold(r.Context(), r.PathValue("key"), req.Reason, "api")
+	writeHoldEvent(w, ev, err)
+}
+
+// release answers DELETE /holds/{key} with the HoldEvent of lifting the
+// key's legal hold. Releasing takes the rights to administer the server,
+// not just to write the key.
+func (g *gateway) release(w http.ResponseWriter, r *http.Request, sc scope) {
+	if who, ok := r.Context().Value(identityKey{}).(*identity); ok && !who.admin() {
+		writeError(w, http.StatusForbidden, "STORE_ERR_FORBIDDEN", fmt.Sprintf("%s may not release legal holds", who.id))
+		return
+	}
+	ev, err := sc.s.Release(r.Context(), r.PathValue("key"), "api")
+	writeHoldEvent(w, ev, err)
+}
+
+func writeHoldEvent(w http.ResponseWriter, ev HoldEvent, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidHold):
+		writeError(w, http.StatusBadRequest, "STORE_ERR_INVALID_HOLD", err.Error())
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_FOUND", "no such key")
+	case errors.Is(err, ErrNotHeld):
+		writeError(w, http.StatusNotFound, "STORE_ERR_NOT_HELD", err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "STORE_ERR_INTERNAL", err.Error())
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ev)
+	}
+}
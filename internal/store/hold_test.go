@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLegalHold(t *testing.T) {
+	ctx := context.Background()
+	var events []HoldEvent
+	s := NewWithOptions(NewMemory(), Options{HoldAudit: func(ev HoldEvent) { events = append(events, ev) }})
+	old := testObject("case/a", "v1")
+	old.CreatedAt = "2020-01-01T00:00:00.000Z"
+	h1, err := s.Put(ctx, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Hold(ctx, "case/a", "", "test"); !errors.Is(err, ErrInvalidHold) {
+		t.Errorf("empty reason: got %v", err)
+	}
+	if _, err := s.Hold(ctx, "case/a", "line\nbreak", "test"); !errors.Is(err, ErrInvalidHold) {
+		t.Errorf("multi-line reason: got %v", err)
+	}
+	if _, err := s.Hold(ctx, "case/missing", "matter 7", "test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing key: got %v", err)
+	}
+	ev, err := s.Hold(ctx, "case/a", "matter 7", "test")
+	if err != nil || ev.Op != HoldSet || ev.Hash != h1 || ev.Reason != "matter 7" {
+		t.Fatalf("Hold = %+v, %v", ev, err)
+	}
+
+	// A write keeps the hold, and the held key cannot be deleted.
+	if _, err := s.Put(ctx, testObject("case/a", "v2")); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := s.Resolve(ctx, "case/a"); e.LegalHold != "matter 7" {
+		t.Errorf("hold after a write = %q", e.LegalHold)
+	}
+	if err := s.Delete(ctx, "case/a"); !errors.Is(err, ErrHeld) {
+		t.Errorf("Delete of a held key: got %v", err)
+	}
+
+	// Retention deletes none of the held key's versions.
+	p := &RetentionPolicy{Categories: map[string]RetentionRule{"*": {KeepVersions: 1}}}
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	plan, err := s.PlanRetention(ctx, p, now)
+	if err != nil || len(plan.Actions) != 0 || plan.Held != 2 {
+		t.Fatalf("plan of a held key = %+v, %v", plan, err)
+	}
+	held, err := s.Holds(ctx, nil)
+	if err != nil || len(held) != 1 || held[0].Key != "case/a" || held[0].Reason != "matter 7" {
+		t.Errorf("Holds = %+v, %v", held, err)
+	}
+
+	// A hold set after planning still stops the deletion.
+	if _, err := s.Release(ctx, "case/a", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Release(ctx, "case/a", "test"); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("second release: got %v", err)
+	}
+	plan, err = s.PlanRetention(ctx, p, now)
+	if err != nil || len(plan.Actions) != 1 || plan.Actions[0].Hash != h1 {
+		t.Fatalf("plan after release = %+v, %v", plan, err)
+	}
+	if _, err := s.Hold(ctx, "case/a", "matter 8", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := s.ApplyRetention(ctx, plan); err != nil || len(done) != 0 {
+		t.Errorf("ApplyRetention on a newly held key = %+v, %v", done, err)
+	}
+	if ok, _ := s.Has(ctx, h1); !ok {
+		t.Error("retention deleted a version of a held key")
+	}
+
+	// An object written with legal_hold sets the hold once.
+	obj := testObject("case/b", "v")
+	obj.LegalHold = "matter 9"
+	for i := 0; i < 2; i++ {
+		if _, err := s.Put(ctx, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ops []string
+	for _, ev := range events {
+		ops = append(ops, ev.Op+" "+ev.Key+" "+ev.Trigger)
+	}
+	want := "hold case/a test,release case/a test,hold case/a test,hold case/b put"
+	if strings.Join(ops, ",") != want {
+		t.Errorf("audit events %q, want %q", ops, want)
+	}
+}
+
+func TestHoldsReportsPolicy(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemory())
+	for _, key := range []string{"case-7/fact", "misc"} {
+		if _, err := s.Put(ctx, testObject(key, "v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &RetentionPolicy{LegalHold: []string{"case-7/"}}
+	held, err := s.Holds(ctx, p)
+	if err != nil || len(held) != 1 || held[0].Policy != "prefix case-7/" || held[0].Reason != "" {
+		t.Errorf("Holds = %+v, %v", held, err)
+	}
+}
+
+func TestGatewayHolds(t *testing.T) {
+	s := newTestStore(t)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true}))
+	defer srv.Close()
+	put(t, srv, "notes/a", objectJSON("notes/a", "v"), nil)
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	if code, body := do(http.MethodPut, "/holds/notes/a", `{"reason": "matter 7"}`); code != http.StatusOK || !strings.Contains(body, `"op":"hold"`) {
+		t.Errorf("PUT /holds: %d %s", code, body)
+	}
+	if code, body := do(http.MethodPut, "/holds/notes/a", `{}`); code != http.StatusBadRequest || !strings.Contains(body, "STORE_ERR_INVALID_HOLD") {
+		t.Errorf("PUT /holds without a reason: %d %s", code, body)
+	}
+	if code, body := do(http.MethodPut, "/holds/notes/b", `{"reason": "matter 7"}`); code != http.StatusNotFound {
+		t.Errorf("PUT /holds of a missing key: %d %s", code, body)
+	}
+	if code, body := do(http.MethodGet, "/holds", ""); code != http.StatusOK || !strings.Contains(body, `"reason":"matter 7"`) {
+		t.Errorf("GET /holds: %d %s", code, body)
+	}
+	if code, body := do(http.MethodDelete, "/holds/notes/a", ""); code != http.StatusOK || !strings.Contains(body, `"op":"release"`) {
+		t.Errorf("DELETE /holds: %d %s", code, body)
+	}
+	if code, body := do(http.MethodDelete, "/holds/notes/a", ""); code != http.StatusNotFound || !strings.Contains(body, "STORE_ERR_NOT_HELD") {
+		t.Errorf("DELETE /holds of a key without one: %d %s", code, body)
+	}
+}
+
+// holdOnResolve puts its key under legal hold right after the first
+// ResolveKey reads it, as another writer could.
+type holdOnResolve struct {
+	*Memory
+	held bool
+}
+
+func (b *holdOnResolve) ResolveKey(ctx context.Context, key string) (KeyEntry, error) {
+	e, err := b.Memory.ResolveKey(ctx, key)
+	if err == nil && !b.held {
+		b.held = true
+		held := e
+		held.LegalHold = "matter 7"
+		if err := b.Memory.SetKey(ctx, held, e.Hash); err != nil {
+			return KeyEntry{}, err
+		}
+	}
+	return e, err
+}
+
+func TestDeleteChecksHoldAtomically(t *testing.T) {
+	ctx := context.Background()
+	b := &holdOnResolve{Memory: NewMemory(), held: true}
+	s := New(b)
+	if _, err := s.Put(ctx, testObject("case/a", "v1")); err != nil {
+		t.Fatal(err)
+	}
+	b.held = false
+	if err := s.Delete(ctx, "case/a"); !errors.Is(err, ErrHeld) {
+		t.Errorf("Delete of a key held while deleting: got %v", err)
+	}
+	if e, err := s.Resolve(ctx, "case/a"); err != nil || e.LegalHold != "matter 7" {
+		t.Errorf("key after the refused delete = %+v, %v", e, err)
+	}
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// IdentityPolicy maps the SPIFFE IDs of mTLS clients to what they may
+// address: a gateway with an IdentityPolicy serves only clients that
+// present a verified X.509 SVID whose ID some rule matches, and only the
+// routes those rules grant.
+type IdentityPolicy struct {
+	Rules []IdentityRule `json:"rules"`
+}
+
+// IdentityRule grants the workloads whose SPIFFE ID matches ID access to
+// one namespace. A client's rules are additive: it may make any request
+// one of them grants.
+type IdentityRule struct {
+	// ID is a SPIFFE ID such as spiffe://mesh.example/ns/prod/sa/ingest,
+	// or ends in "/*" to match every ID below that path, such as
+	// spiffe://mesh.example/ns/prod/* or spiffe://mesh.example/*.
+	ID string `json:"id"`
+	// Tenant is the tenant the rule grants, "" for the default namespace
+	// and "*" for every tenant, but not the default namespace.
+	Tenant string `json:"tenant,omitempty"`
+	// Prefixes limits the rule to keys beginning with one of them: key
+	// routes must name such a key and GET /keys must list under one with
+	// ?prefix=. Routes that are not confined to a key, such as
+	// /objects/{hash} and /changes, need a rule without Prefixes.
+	Prefixes []string `json:"prefixes,omitempty"`
+	// Write grants PUT as well as reads.
+	Write bool `json:"write,omitempty"`
+}
+
+// LoadIdentityPolicy reads an IdentityPolicy from a JSON file.
+func LoadIdentityPolicy(path string) (*IdentityPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p IdentityPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid identity policy %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid identity policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *IdentityPolicy) validate() error {
+	for i, r := range p.Rules {
+		if err := validSPIFFEID(strings.TrimSuffix(r.ID, "/*")); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if r.Tenant != "" && r.Tenant != "*" && !ValidTenant(r.Tenant) {
+			return fmt.Errorf("rule %d: %w: %q", i, ErrInvalidTenant, r.Tenant)
+		}
+		for _, prefix := range r.Prefixes {
+			if prefix == "" {
+				return fmt.Errorf("rule %d: empty prefix; omit prefixes to grant every key", i)
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule's ID matches id.
+func (r IdentityRule) matches(id string) bool {
+	if base, ok := strings.CutSuffix(r.ID, "/*"); ok {
+		return strings.HasPrefix(id, base+"/")
+	}
+	return id == r.ID
+}
+
+// grants reports whether the rule allows a request to tenant. key is the
+// key the request is confined to, and confined is false for a request
+// that may reach any key.
+func (r IdentityRule) grants(tenant, key string, confined, write bool) bool {
+	if write && !r.Write {
+		return false
+	}
+	switch {
+	case r.Tenant == "*":
+		if tenant == "" {
+			return false
+		}
+	case r.Tenant != tenant:
+		return false
+	}
+	if len(r.Prefixes) == 0 {
+		return true
+	}
+	if !confined {
+		return false
+	}
+	for _, p := range r.Prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// validSPIFFEID checks id against the SPIFFE ID format: the spiffe
+// scheme, a trust domain of lowercase letters, digits, '.', '-' and '_',
+// and a path of non-empty segments other than "." and "..", with nothing
+// else.
+func validSPIFFEID(id string) error {
+	rest, ok := strings.CutPrefix(id, "spiffe://")
+	if !ok {
+		return fmt.Errorf("%q is not a spiffe:// ID", id)
+	}
+	td, path, _ := strings.Cut(rest, "/")
+	if td == "" {
+		return fmt.Errorf("SPIFFE ID %q has no trust domain", id)
+	}
+	for i := 0; i < len(td); i++ {
+		c := td[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return fmt.Errorf("SPIFFE ID %q has an invalid trust domain", id)
+		}
+	}
+	if path == "" {
+		if strings.HasSuffix(rest, "/") {
+			return fmt.Errorf("SPIFFE ID %q ends in a slash", id)
+		}
+		return nil
+	}
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return fmt.Errorf("SPIFFE ID %q has an empty, '.' or '..' path segment", id)
+		}
+		for i := 0; i < len(seg); i++ {
+			c := seg[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+				return fmt.Errorf("SPIFFE ID %q has an invalid path character %q", id, c)
+			}
+		}
+	}
+	return nil
+}
+
+// SPIFFEID returns the SPIFFE ID of an X.509 SVID: the certificate's one
+// URI SAN, which must be a valid spiffe:// ID.
+func SPIFFEID(cert *x509.Certificate) (string, error) {
+	var id string
+	for _, u := range cert.URIs {
+		if u.Scheme != "spiffe" {
+			continue
+		}
+		if id != "" {
+			return "", fmt.Errorf("client certificate carries more than one SPIFFE ID")
+		}
+		id = u.String()
+	}
+	if id == "" {
+		return "", fmt.Errorf("client certificate carries no SPIFFE ID")
+	}
+	if len(cert.URIs) != 1 {
+		return "", fmt.Errorf("client certificate carries URI SANs besides its SPIFFE ID")
+	}
+	if err := validSPIFFEID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+type identityKey struct{}
+
+// identity is the authenticated client of a request and the rules its ID
+// matches.
+type identity struct {
+	id    string
+	rules []IdentityRule
+}
+
+// authenticate serves only requests from clients whose verified
+// certificate carries a SPIFFE ID some rule of p matches, answering 401
+// STORE_ERR_UNAUTHENTICATED for the others and 403 STORE_ERR_FORBIDDEN
+// for IDs no rule names.
+func authenticate(p *IdentityPolicy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			writeError(w, http.StatusUnauthorized, "STORE_ERR_UNAUTHENTICATED", "a verified client certificate is required")
+			return
+		}
+		id, err := SPIFFEID(r.TLS.VerifiedChains[0][0])
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "STORE_ERR_UNAUTHENTICATED", err.Error())
+			return
+		}
+		who := &identity{id: id}
+		for _, rule := range p.Rules {
+			if rule.matches(id) {
+				who.rules = append(who.rules, rule)
+			}
+		}
+		if len(who.rules) == 0 {
+			writeError(w, http.StatusForbidden, "STORE_ERR_FORBIDDEN", fmt.Sprintf("%s is not authorized", id))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, who)))
+	})
+}
+
+// admin reports whether the identity may administer the server: whether
+// a rule grants it writes to every key of the default namespace.
+func (who *identity) admin() bool {
+	for _, rule := range who.rules {
+		if rule.grants("", "", false, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized reports whether the request's identity, if the gateway
+// authenticates clients, may make a request to a namespace route, and
+// answers 403 STORE_ERR_FORBIDDEN if not.
+func authorized(w http.ResponseWriter, r *http.Request, write bool) bool {
+	who, ok := r.Context().Value(identityKey{}).(*identity)
+	if !ok {
+		return true
+	}
+	tenant := r.PathValue("tenant")
+	key, confined, listing := r.PathValue("key"), false, false
+	switch {
+	case strings.Contains(r.Pattern, "{key...}"):
+		confined = true
+	case strings.HasSuffix(r.Pattern, "/keys"):
+		key, confined, listing = r.URL.Query().Get("prefix"), true, true
+	}
+	for _, rule := range who.rules {
+		if rule.grants(tenant, key, confined, write) {
+			return true
+		}
+	}
+	what := "the default namespace"
+	if tenant != "" {
+		what = "tenant " + tenant
+	}
+	verb := "read"
+	switch {
+	case write:
+		verb = "write"
+	case listing:
+		verb, what = "list", fmt.Sprintf("keys under %q in %s", key, what)
+	}
+	if confined && !listing {
+		what = fmt.Sprintf("key %q in %s", key, what)
+	}
+	writeError(w, http.StatusForbidden, "STORE_ERR_FORBIDDEN", fmt.Sprintf("%s may not %s %s", who.id, verb, what))
+	return false
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSVIDs is a CA that issues X.509 SVIDs.
+type testSVIDs struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestSVIDs(t *testing.T) *testSVIDs {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test mesh CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testSVIDs{cert: cert, key: key, pool: pool}
+}
+
+// issue returns a client certificate with the given URI SANs.
+func (ca *testSVIDs) issue(t *testing.T, uris ...string) tls.Certificate {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, u := range uris {
+		parsed, _ := url.Parse(u)
+		tmpl.URIs = append(tmpl.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestGatewayIdentities(t *testing.T) {
+	s := newTestStore(t)
+	ca := newTestSVIDs(t)
+	policy := &IdentityPolicy{Rules: []IdentityRule{
+		{ID: "spiffe://mesh.test/ns/prod/sa/ingest", Prefixes: []string{"notes/"}, Write: true},
+		{ID: "spiffe://mesh.test/ns/prod/*", Tenant: "acme"},
+		{ID: "spiffe://mesh.test/ns/ops/sa/audit"},
+	}}
+	if err := policy.validate(); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(NewGateway(s, GatewayOptions{Writable: true, Tenants: true, Metrics: true, Identities: policy}))
+	srv.TLS = &tls.Config{ClientCAs: ca.pool, ClientAuth: tls.VerifyClientCertIfGiven}
+	srv.StartTLS()
+	defer srv.Close()
+
+	do := func(cert *tls.Certificate, method, path, body string) (int, string) {
+		t.Helper()
+		tr := srv.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			tr.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := (&http.Client{Transport: tr}).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	ingest := ca.issue(t, "spiffe://mesh.test/ns/prod/sa/ingest")
+	reader := ca.issue(t, "spiffe://mesh.test/ns/prod/sa/reader")
+	audit := ca.issue(t, "spiffe://mesh.test/ns/ops/sa/audit")
+	stranger := ca.issue(t, "spiffe://mesh.test/ns/dev/sa/x")
+	notSVID := ca.issue(t, "https://mesh.test/x")
+	for _, tc := range []struct {
+		name         string
+		cert         *tls.Certificate
+		method, path string
+		body         string
+		want         int
+	}{
+		{"no certificate", nil, "GET", "/keys", "", http.StatusUnauthorized},
+		{"no SPIFFE ID", &notSVID, "GET", "/keys", "", http.StatusUnauthorized},
+		{"unknown ID", &stranger, "GET", "/keys", "", http.StatusForbidden},
+		{"write in prefix", &ingest, "PUT", "/keys/notes/a", objectJSON("notes/a", "v"), http.StatusCreated},
+		{"write outside prefix", &ingest, "PUT", "/keys/other", objectJSON("other", "v"), http.StatusForbidden},
+		{"read in prefix", &ingest, "GET", "/keys/notes/a", "", http.StatusOK},
+		{"list in prefix", &ingest, "GET", "/keys?prefix=notes/", "", http.StatusOK},
+		{"list everything", &ingest, "GET", "/keys", "", http.StatusForbidden},
+		{"objects need every key", &ingest, "GET", "/objects/abc", "", http.StatusForbidden},
+		{"other tenant", &audit, "GET", "/tenants/acme/keys", "", http.StatusForbidden},
+		{"rules add up", &ingest, "GET", "/tenants/acme/keys", "", http.StatusNotFound},
+		{"wildcard tenant read", &reader, "GET", "/tenants/acme/keys/x", "", http.StatusNotFound},
+		{"wildcard tenant write", &reader, "PUT", "/tenants/acme/keys/x", objectJSON("x", "v"), http.StatusForbidden},
+		{"wildcard default namespace", &reader, "GET", "/keys/notes/a", "", http.StatusForbidden},
+		{"whole namespace", &audit, "GET", "/keys", "", http.StatusOK},
+		{"read only", &audit, "PUT", "/keys/notes/b", objectJSON("notes/b", "v"), http.StatusForbidden},
+		{"global route", &reader, "GET", "/metrics", "", http.StatusOK},
+	} {
+		if got, body := do(tc.cert, tc.method, tc.path, tc.body); got != tc.want {
+			t.Errorf("%s: %s %s = %d %s, want %d", tc.name, tc.method, tc.path, got, body, tc.want)
+		}
+	}
+}
+
+func TestSPIFFEID(t *testing.T) {
+	ca := newTestSVIDs(t)
+	for _, tc := range []struct {
+		uris []string
+		want string
+	}{
+		{[]string{"spiffe://mesh.test/ns/prod"}, "spiffe://mesh.test/ns/prod"},
+		{[]string{"spiffe://mesh.test/a", "spiffe://mesh.test/b"}, ""},
+		{[]string{"spiffe://mesh.test/a", "https://mesh.test/"}, ""},
+		{[]string{"spiffe://Mesh.test/a"}, ""},
+		{[]string{"spiffe://mesh.test/a/../b"}, ""},
+		{nil, ""},
+	} {
+		cert, _ := x509.ParseCertificate(ca.issue(t, tc.uris...).Certificate[0])
+		got, err := SPIFFEID(cert)
+		if got != tc.want || (err == nil) != (tc.want != "") {
+			t.Errorf("SPIFFEID(%v) = %q, %v, want %q", tc.uris, got, err, tc.want)
+		}
+	}
+	for _, r := range []IdentityRule{
+		{ID: "https://mesh.test/x"},
+		{ID: "spiffe://mesh.test/x/"},
+		{ID: "spiffe://mesh.test/x", Tenant: "Acme"},
+		{ID: "spiffe://mesh.test/x", Prefixes: []string{""}},
+	} {
+		if err := (&IdentityPolicy{Rules: []IdentityRule{r}}).validate(); err == nil {
+			t.Errorf("rule %+v was accepted", r)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Page sizes for ListByPrefix and ListByCategory.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// ErrInvalidCursor is returned for a cursor that ListByPrefix or
+// ListByCategory did not produce.
+var ErrInvalidCursor = errors.New("store: invalid page cursor")
+
+// KeyQuery selects a page of the key index: keys after After that begin
+// with Prefix and, if Category is set, hold an object of that category,
+// in key order, at most Limit of them.
+type KeyQuery struct {
+	Prefix   string
+	Category string
+	After    string
+	Limit    int
+}
+
+// KeyPager is implemented by backends that can read one page of the key
+// index without reading all of it. Other backends are paged by filtering
+// ListKeys. A KeyPager must have a category for every entry.
+type KeyPager interface {
+	ListKeysPage(ctx context.Context, q KeyQuery) ([]KeyEntry, error)
+}
+
+// Page is one page of a key listing. Next is the cursor for the following
+// page, or "" on the last one.
+type Page struct {
+	Keys []KeyEntry `json:"keys"`
+	Next string     `json:"next_cursor,omitempty"`
+}
+
+// ListByPrefix returns the page of keys beginning with prefix that
+// follows cursor ("" for the first page). limit is clamped to
+// 1..MaxPageSize, 0 meaning DefaultPageSize. Cursors stay valid while
+// keys are written or deleted: a page resumes after the last key of the
+// one before.
+func (s *Store) ListByPrefix(ctx context.Context, prefix string, limit int, cursor string) (Page, error) {
+	return s.list(ctx, KeyQuery{Prefix: prefix}, limit, cursor)
+}
+
+// ListByCategory is ListByPrefix for the keys whose current object is in
+// category.
+func (s *Store) ListByCategory(ctx context.Context, category string, limit int, cursor string) (Page, error) {
+	if category == "" {
+		return Page{}, errors.New("store: empty category")
+	}
+	return s.list(ctx, KeyQuery{Category: category}, limit, cursor)
+}
+
+// List returns one page of keys matching both a prefix and, if set, a
+// category.
+func (s *Store) List(ctx context.Context, prefix, category string, limit int, cursor string) (Page, error) {
+	return s.list(ctx, KeyQuery{Prefix: prefix, Category: category}, limit, cursor)
+}
+
+func (s *Store) list(ctx context.Context, q KeyQuery, limit int, cursor string) (Page, error) {
+	switch {
+	case limit <= 0:
+		limit = DefaultPageSize
+	case limit > MaxPageSize:
+		limit = MaxPageSize
+	}
+	if cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(after) == 0 {
+			return Page{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+		q.After = string(after)
+	}
+	// One extra entry tells whether there is a next page.
+	q.Limit = limit + 1
+
+	var entries []KeyEntry
+	var err error
+	if p, ok := s.b.(KeyPager); ok {
+		entries, err = p.ListKeysPage(ctx, q)
+	} else {
+		entries, err = s.scanKeys(ctx, q)
+	}
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{Keys: entries}
+	if len(entries) > limit {
+		page.Keys = entries[:limit]
+		page.Next = base64.RawURLEncoding.EncodeToString([]byte(page.Keys[limit-1].Key))
+	}
+	if page.Keys == nil {
+		page.Keys = []KeyEntry{}
+	}
+	return page, nil
+}
+
+// scanKeys pages by filtering the full listing. Entries from before the
+// index recorded categories get theirs from their objects.
+func (s *Store) scanKeys(ctx context.Context, q KeyQuery) ([]KeyEntry, error) {
+	all, err := s.b.ListKeys(ctx, q.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(all), func(i int) bool { return all[i].Key > q.After })
+	var out []KeyEntry
+	for _, e := range all[i:] {
+		if len(out) == q.Limit {
+			break
+		}
+		if q.Category != "" {
+			if e.Category == "" {
+				if _, e.Category, err = s.objectCount(ctx, e.Hash); err != nil {
+					return nil, fmt.Errorf("key %q: %w", e.Key, err)
+				}
+			}
+			if e.Category != q.Category {
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
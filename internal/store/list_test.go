@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestListPages(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	var all, facts []string
+	for i := 0; i < 7; i++ {
+		obj := testObject(fmt.Sprintf("notes/%02d", i), "v")
+		if i%3 == 0 {
+			obj.Category = "fact"
+			facts = append(facts, obj.Key)
+		}
+		if _, err := s.Put(ctx, obj); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, obj.Key)
+	}
+	if _, err := s.Put(ctx, testObject("other", "v")); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(list func(cursor string) (Page, error)) (keys []string, pages int) {
+		t.Helper()
+		cursor := ""
+		for {
+			page, err := list(cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			for _, e := range page.Keys {
+				keys = append(keys, e.Key)
+			}
+			if page.Next == "" {
+				return keys, pages
+			}
+			cursor = page.Next
+		}
+	}
+	got, pages := collect(func(c string) (Page, error) { return s.ListByPrefix(ctx, "notes/", 3, c) })
+	if !reflect.DeepEqual(got, all) || pages != 3 {
+		t.Errorf("ListByPrefix pages: %v in %d pages", got, pages)
+	}
+	got, pages = collect(func(c string) (Page, error) { return s.ListByCategory(ctx, "fact", 2, c) })
+	if !reflect.DeepEqual(got, facts) || pages != 2 {
+		t.Errorf("ListByCategory pages: %v in %d pages", got, pages)
+	}
+
+	// A page resumes after the last key of the one before, even if that
+	// key is gone.
+	first, _ := s.ListByPrefix(ctx, "notes/", 2, "")
+	if err := s.Delete(ctx, "notes/01"); err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.ListByPrefix(ctx, "notes/", 2, first.Next)
+	if err != nil || len(next.Keys) != 2 || next.Keys[0].Key != "notes/02" {
+		t.Errorf("page after a deleted cursor key: %+v, %v", next, err)
+	}
+
+	if _, err := s.ListByPrefix(ctx, "", 10, "!!"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor: %v", err)
+	}
+	if page, _ := s.ListByCategory(ctx, "nope", 0, ""); page.Keys == nil || len(page.Keys) != 0 || page.Next != "" {
+		t.Errorf("empty page = %+v", page)
+	}
+}
+
+func TestListLegacyCategories(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemory())
+	h, err := s.Put(ctx, testObject("a", "v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An entry written before the index recorded categories.
+	s.Backend().SetKey(ctx, KeyEntry{Key: "a", Hash: h, UpdatedAt: "2025-01-15T10:30:00.000Z"}, Any)
+	if page, err := s.ListByCategory(ctx, "project", 0, ""); err != nil || len(page.Keys) != 1 || page.Keys[0].Category != "project" {
+		t.Errorf("legacy entry: %+v, %v", page, err)
+	}
+}
+
+func TestGatewayList(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	for _, k := range []string{"a", "b", "c"} {
+		if _, err := s.Put(ctx, testObject(k, "v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{}))
+	defer srv.Close()
+
+	resp, body := get(t, srv, "/keys?limit=2&category=project", nil)
+	var page Page
+	if err := json.Unmarshal([]byte(body), &page); err != nil || resp.StatusCode != 200 || len(page.Keys) != 2 || page.Next == "" {
+		t.Fatalf("first page: %d %s", resp.StatusCode, body)
+	}
+	resp, body = get(t, srv, "/keys?limit=2&cursor="+url.QueryEscape(page.Next), nil)
+	page = Page{}
+	if err := json.Unmarshal([]byte(body), &page); err != nil || len(page.Keys) != 1 || page.Keys[0].Key != "c" || page.Next != "" {
+		t.Errorf("second page: %d %s", resp.StatusCode, body)
+	}
+	for path, want := range map[string]int{
+		"/keys?limit=0":       400,
+		"/keys?limit=5000":    400,
+		"/keys?cursor=%21%21": 400,
+		"/keys?prefix=zz":     200,
+	} {
+		if resp, body := get(t, srv, path, nil); resp.StatusCode != want {
+			t.Errorf("GET %s: %d %s", path, resp.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,917 @@
+// Package logstore is an embedded, log-structured store.Backend for
+// single-node deployments that write faster than one file per object
+// allows. Every change is a record appended to a segment file; an
+// in-memory index maps hashes and keys to their latest record, so reads
+// are one positioned read and prefix listings walk a sorted slice.
+//
+// Writers that arrive while a batch is being flushed are grouped into the
+// next batch, which is written with one write call and made durable with
+// one fsync (group commit). Overwritten and deleted records stay in their
+// segments until Compact rewrites the live ones.
+//
+// A directory is used by one process at a time: Open locks it, and fails
+// while another process has it open.
+package logstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// Format identifies the on-disk layout version. It is recorded in the
+// store.DescriptorName file, as the directory layout records its own.
+const Format = "helios-logstore/v1"
+
+// DefaultSegmentSize is the size at which the active segment is sealed and
+// a new one started.
+const DefaultSegmentSize = 256 << 20
+
+// Options tune a Backend. The zero value is the default.
+type Options struct {
+	// SegmentSize overrides DefaultSegmentSize.
+	SegmentSize int64
+	// NoSync skips the fsync after each batch. A crash may then lose
+	// recently acknowledged writes, but never corrupts older ones.
+	NoSync bool
+}
+
+// Record kinds.
+const (
+	kindBlob    = 1
+	kindBlobDel = 2
+	kindKey     = 3
+	kindKeyDel  = 4
+)
+
+// headerSize is the record header: CRC-32C of the rest of the record, the
+// kind, and the key and value lengths.
+const headerSize = 4 + 1 + 4 + 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type record struct {
+	kind  byte
+	key   string
+	value []byte
+}
+
+func (r record) size() int64 { return headerSize + int64(len(r.key)) + int64(len(r.value)) }
+
+func (r record) appendTo(buf []byte) []byte {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0, r.kind)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.key)))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.value)))
+	buf = append(buf, r.key...)
+	buf = append(buf, r.value...)
+	binary.BigEndian.PutUint32(buf[start:], crc32.Checksum(buf[start+4:], castagnoli))
+	return buf
+}
+
+// loc is where a record lives.
+type loc struct {
+	seg  uint32
+	off  int64
+	size int64
+}
+
+// keyValue encodes a key entry as a record value: its hash, update time,
+// category, stamp, request id, residency, and legal hold, separated by
+// NULs. Records from before categories were indexed have no category
+// field, records from before stamping no stamp field, and so on for each
+// field added since.
+func keyValue(e store.KeyEntry) []byte {
+	return []byte(e.Hash + "\x00" + e.UpdatedAt + "\x00" + e.Category + "\x00" + e.Stamp + "\x00" + e.RequestID + "\x00" + e.Residency + "\x00" + e.LegalHold)
+}
+
+// write is one caller's records waiting for a group commit.
+type write struct {
+	recs []record
+	done chan error
+}
+
+// Backend is a store.Backend over a directory of segment files:
+//
+//	HELIOS_STORE      layout descriptor (JSON)
+//	000001.seg ...    append-only record logs, replayed in order on open
+//	tenants/<id>/     a complete store per tenant
+type Backend struct {
+	dir  string
+	opts Options
+	// lock holds the directory's exclusive lock until Close.
+	lock *os.File
+
+	// mu guards the index and the read handles.
+	mu      sync.RWMutex
+	blobs   map[string]loc
+	sorted  []string // hashes in byte order, excluding pending
+	pending []string // hashes added since the last merge into sorted
+	keys    map[string]store.KeyEntry
+	keyLocs map[string]loc
+	segs    map[uint32]*os.File
+	live    int64
+	total   int64
+
+	// keyMu serializes SetKey and DeleteKey so their checks are atomic.
+	keyMu sync.Mutex
+
+	// wmu guards the commit queue; fileMu is held while the active
+	// segment is written, rotated, or compacted.
+	wmu      sync.Mutex
+	queue    []*write
+	flushing bool
+	fileMu   sync.Mutex
+	active   *os.File
+	activeID uint32
+	size     int64
+	closed   bool
+
+	tenantMu sync.Mutex
+	tenants  map[string]*Backend
+}
+
+// Init creates a store in dir, or opens it if one already exists.
+func Init(dir string, opts Options) (*Backend, error) {
+	if IsStore(dir) {
+		return Open(dir, opts)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, store.DescriptorName)); err == nil {
+		return nil, fmt.Errorf("failed to create store %s: it already holds a store of another format", dir)
+	}
+	data, err := json.MarshalIndent(map[string]string{"format": Format}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, store.DescriptorName), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+	return Open(dir, opts)
+}
+
+// IsStore reports whether dir holds a log-structured store.
+func IsStore(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, store.DescriptorName))
+	if err != nil {
+		return false
+	}
+	var d struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &d) == nil && d.Format == Format
+}
+
+// Open opens an existing store, replaying its segments to rebuild the
+// index. A torn record at the end of the newest segment, left by a crash
+// mid-write, is truncated away; damage anywhere else is an error. The
+// directory stays locked against other processes until Close.
+func Open(dir string, opts Options) (*Backend, error) {
+	if !IsStore(dir) {
+		return nil, fmt.Errorf("failed to open store %s: no %s descriptor with format %q", dir, store.DescriptorName, Format)
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	b := &Backend{
+		dir:     dir,
+		opts:    opts,
+		lock:    lock,
+		blobs:   make(map[string]loc),
+		keys:    make(map[string]store.KeyEntry),
+		keyLocs: make(map[string]loc),
+		segs:    make(map[uint32]*os.File),
+	}
+	ids, err := segmentIDs(dir)
+	if err != nil {
+		b.closeFiles()
+		return nil, err
+	}
+	for i, id := range ids {
+		if err := b.replay(id, i == len(ids)-1); err != nil {
+			b.closeFiles()
+			return nil, fmt.Errorf("failed to open store %s: %w", dir, err)
+		}
+	}
+	sort.Strings(b.pending)
+	b.sorted, b.pending = b.pending, nil
+
+	next := uint32(1)
+	if len(ids) > 0 {
+		next = ids[len(ids)-1]
+	}
+	if err := b.openActive(next); err != nil {
+		b.closeFiles()
+		return nil, err
+	}
+	return b, nil
+}
+
+func segmentName(id uint32) string { return fmt.Sprintf("%06d.seg", id) }
+
+func segmentIDs(dir string) ([]uint32, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint32
+	for _, name := range names {
+		var id uint32
+		if _, err := fmt.Sscanf(filepath.Base(name), "%06d.seg", &id); err == nil && segmentName(id) == filepath.Base(name) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// replay applies the records of segment id to the index.
+func (b *Backend) replay(id uint32, last bool) error {
+	f, err := os.OpenFile(filepath.Join(b.dir, segmentName(id)), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	b.segs[id] = f
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r := io.NewSectionReader(f, 0, info.Size())
+	var off int64
+	for off < info.Size() {
+		rec, err := readRecord(r, off, info.Size()-off)
+		if err != nil {
+			if !last {
+				return fmt.Errorf("%s at offset %d: %w", segmentName(id), off, err)
+			}
+			torn, terr := tornTail(r, off, info.Size())
+			if terr != nil {
+				return terr
+			}
+			if !torn {
+				return fmt.Errorf("%s at offset %d: %w, with intact records after it", segmentName(id), off, err)
+			}
+			if err := f.Truncate(off); err != nil {
+				return err
+			}
+			break
+		}
+		b.apply(rec, loc{seg: id, off: off, size: rec.size()})
+		off += rec.size()
+	}
+	return nil
+}
+
+var errCorrupt = errors.New("corrupt record")
+
+// tornTail reports whether the unreadable record at off in a segment of
+// size bytes is a torn tail: the last record, cut short by a crash, with
+// no intact record starting anywhere after it. Records that follow mean
+// the damage is to data already made durable, which truncating would
+// silently drop.
+func tornTail(r io.ReaderAt, off, size int64) (bool, error) {
+	rest := make([]byte, size-off)
+	if _, err := r.ReadAt(rest, off); err != nil {
+		return false, err
+	}
+	for i := 1; i < len(rest); i++ {
+		if intact(rest[i:]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// intact reports whether data begins with a complete record whose
+// checksum matches.
+func intact(data []byte) bool {
+	if len(data) < headerSize {
+		return false
+	}
+	n := headerSize + int64(binary.BigEndian.Uint32(data[5:])) + int64(binary.BigEndian.Uint32(data[9:]))
+	if n > int64(len(data)) {
+		return false
+	}
+	return crc32.Checksum(data[4:n], castagnoli) == binary.BigEndian.Uint32(data)
+}
+
+// readRecord reads the record at off, of which at most max bytes exist.
+func readRecord(r io.ReaderAt, off, max int64) (record, error) {
+	var hdr [headerSize]byte
+	if max < headerSize {
+		return record{}, errCorrupt
+	}
+	if _, err := r.ReadAt(hdr[:], off); err != nil {
+		return record{}, err
+	}
+	klen, vlen := int64(binary.BigEndian.Uint32(hdr[5:])), int64(binary.BigEndian.Uint32(hdr[9:]))
+	if headerSize+klen+vlen > max {
+		return record{}, errCorrupt
+	}
+	body := make([]byte, klen+vlen)
+	if _, err := r.ReadAt(body, off+headerSize); err != nil {
+		return record{}, err
+	}
+	crc := crc32.Update(crc32.Checksum(hdr[4:], castagnoli), castagnoli, body)
+	if crc != binary.BigEndian.Uint32(hdr[:4]) {
+		return record{}, errCorrupt
+	}
+	return record{kind: hdr[4], key: string(body[:klen]), value: body[klen:]}, nil
+}
+
+// apply updates the index for rec, written at l. The caller holds mu or
+// has exclusive access.
+func (b *Backend) apply(rec record, l loc) {
+	b.total += l.size
+	switch rec.kind {
+	case kindBlob:
+		if old, ok := b.blobs[rec.key]; ok {
+			b.live -= old.size
+		} else {
+			b.pending = append(b.pending, rec.key)
+		}
+		b.blobs[rec.key] = l
+		b.live += l.size
+	case kindBlobDel:
+		if old, ok := b.blobs[rec.key]; ok {
+			b.live -= old.size
+			delete(b.blobs, rec.key)
+			b.unlist(rec.key)
+		}
+	case kindKey:
+		if old, ok := b.keyLocs[rec.key]; ok {
+			b.live -= old.size
+		}
+		h, rest, _ := strings.Cut(string(rec.value), "\x00")
+		at, rest, _ := strings.Cut(rest, "\x00")
+		category, rest, _ := strings.Cut(rest, "\x00")
+		stamp, rest, _ := strings.Cut(rest, "\x00")
+		requestID, rest, _ := strings.Cut(rest, "\x00")
+		residency, hold, _ := strings.Cut(rest, "\x00")
+		b.keys[rec.key] = store.KeyEntry{Key: rec.key, Hash: h, UpdatedAt: at, Category: category, Stamp: stamp, RequestID: requestID, Residency: residency, LegalHold: hold}
+		b.keyLocs[rec.key] = l
+		b.live += l.size
+	case kindKeyDel:
+		if old, ok := b.keyLocs[rec.key]; ok {
+			b.live -= old.size
+			delete(b.keys, rec.key)
+			delete(b.keyLocs, rec.key)
+		}
+	}
+}
+
+// unlist removes h from sorted or pending.
+func (b *Backend) unlist(h string) {
+	if i := sort.SearchStrings(b.sorted, h); i < len(b.sorted) && b.sorted[i] == h {
+		b.sorted = append(b.sorted[:i], b.sorted[i+1:]...)
+		return
+	}
+	for i, p := range b.pending {
+		if p == h {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// openActive opens segment id for appending and makes it the active one.
+func (b *Backend) openActive(id uint32) error {
+	f, ok := b.segs[id]
+	if !ok {
+		var err error
+		f, err = os.OpenFile(filepath.Join(b.dir, segmentName(id)), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		if err := syncDir(b.dir); err != nil {
+			f.Close()
+			return err
+		}
+		b.segs[id] = f
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	b.active, b.activeID, b.size = f, id, size
+	return nil
+}
+
+// commit appends recs as part of the next batch and returns once the
+// batch is durable and indexed.
+func (b *Backend) commit(recs ...record) error {
+	w := &write{recs: recs, done: make(chan error, 1)}
+	b.wmu.Lock()
+	b.queue = append(b.queue, w)
+	if b.flushing {
+		b.wmu.Unlock()
+		return <-w.done
+	}
+	b.flushing = true
+	for len(b.queue) > 0 {
+		batch := b.queue
+		b.queue = nil
+		b.wmu.Unlock()
+		b.flush(batch)
+		b.wmu.Lock()
+	}
+	b.flushing = false
+	b.wmu.Unlock()
+	return <-w.done
+}
+
+// flush writes batch to the active segment and reports the result to
+// each writer in it.
+func (b *Backend) flush(batch []*write) {
+	err := b.writeBatch(batch)
+	for _, w := range batch {
+		w.done <- err
+	}
+}
+
+func (b *Backend) writeBatch(batch []*write) error {
+	b.fileMu.Lock()
+	defer b.fileMu.Unlock()
+	if b.closed {
+		return errors.New("logstore: store is closed")
+	}
+	var buf []byte
+	for _, w := range batch {
+		for _, rec := range w.recs {
+			buf = rec.appendTo(buf)
+		}
+	}
+	if b.size > 0 && b.size+int64(len(buf)) > b.opts.SegmentSize {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := b.active.Write(buf); err != nil {
+		b.rollback()
+		return err
+	}
+	if !b.opts.NoSync {
+		if err := b.active.Sync(); err != nil {
+			b.rollback()
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	off := b.size
+	for _, w := range batch {
+		for _, rec := range w.recs {
+			b.apply(rec, loc{seg: b.activeID, off: off, size: rec.size()})
+			off += rec.size()
+		}
+	}
+	b.mu.Unlock()
+	b.size = off
+	return nil
+}
+
+// rollback drops a batch that failed to be written or synced, so the
+// segment ends at b.size and the next batch starts at a record boundary.
+// The caller holds fileMu.
+func (b *Backend) rollback() {
+	b.active.Truncate(b.size)
+	b.active.Seek(b.size, io.SeekStart)
+}
+
+// rotate seals the active segment and starts the next one. The caller
+// holds fileMu.
+func (b *Backend) rotate() error {
+	if err := b.active.Sync(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openActive(b.activeID + 1)
+}
+
+// read returns the record at l. The caller holds mu for reading.
+func (b *Backend) read(l loc) (record, error) {
+	f, ok := b.segs[l.seg]
+	if !ok {
+		return record{}, fmt.Errorf("logstore: missing segment %s", segmentName(l.seg))
+	}
+	rec, err := readRecord(f, l.off, l.size)
+	if err != nil {
+		return record{}, fmt.Errorf("logstore: %s at offset %d: %w", segmentName(l.seg), l.off, err)
+	}
+	return rec, nil
+}
+
+// Put implements store.Backend.
+func (b *Backend) Put(ctx context.Context, h string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.RLock()
+	_, ok := b.blobs[h]
+	b.mu.RUnlock()
+	if ok {
+		return nil
+	}
+	return b.commit(record{kind: kindBlob, key: h, value: data})
+}
+
+// Get implements store.Backend. The record's checksum is verified on
+// every read.
+func (b *Backend) Get(ctx context.Context, h string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	l, ok := b.blobs[h]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	rec, err := b.read(l)
+	if err != nil {
+		return nil, err
+	}
+	return rec.value, nil
+}
+
+// Has implements store.Backend.
+func (b *Backend) Has(ctx context.Context, h string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.blobs[h]
+	return ok, nil
+}
+
+// List implements store.Backend. Hashes written since the last listing
+// are merged into the sorted index first.
+func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) > 0 {
+		sort.Strings(b.pending)
+		b.sorted = mergeSorted(b.sorted, b.pending)
+		b.pending = nil
+	}
+	var hashes []string
+	for i := sort.SearchStrings(b.sorted, prefix); i < len(b.sorted) && strings.HasPrefix(b.sorted[i], prefix); i++ {
+		hashes = append(hashes, b.sorted[i])
+	}
+	return hashes, nil
+}
+
+func mergeSorted(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] <= b[0] {
+			out, a = append(out, a[0]), a[1:]
+		} else {
+			out, b = append(out, b[0]), b[1:]
+		}
+	}
+	return append(append(out, a...), b...)
+}
+
+// Delete implements store.Backend.
+func (b *Backend) Delete(ctx context.Context, h string) error {
+	if ok, err := b.Has(ctx, h); err != nil {
+		return err
+	} else if !ok {
+		return store.ErrNotFound
+	}
+	return b.commit(record{kind: kindBlobDel, key: h})
+}
+
+// SetKey implements store.Backend.
+func (b *Backend) SetKey(ctx context.Context, e store.KeyEntry, expected string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.keyMu.Lock()
+	defer b.keyMu.Unlock()
+	b.mu.RLock()
+	cur, exists := b.keys[e.Key]
+	b.mu.RUnlock()
+	if err := store.CheckExpected(e.Key, cur, exists, expected); err != nil {
+		return err
+	}
+	return b.commit(record{kind: kindKey, key: e.Key, value: keyValue(e)})
+}
+
+// ResolveKey implements store.Backend.
+func (b *Backend) ResolveKey(ctx context.Context, key string) (store.KeyEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return store.KeyEntry{}, err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	e, ok := b.keys[key]
+	if !ok {
+		return store.KeyEntry{}, store.ErrNotFound
+	}
+	return e, nil
+}
+
+// ListKeys implements store.Backend.
+func (b *Backend) ListKeys(ctx context.Context, prefix string) ([]store.KeyEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.mu.RLock()
+	var entries []store.KeyEntry
+	for k, e := range b.keys {
+		if strings.HasPrefix(k, prefix) {
+			entries = append(entries, e)
+		}
+	}
+	b.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// DeleteKey implements store.Backend.
+func (b *Backend) DeleteKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.keyMu.Lock()
+	defer b.keyMu.Unlock()
+	b.mu.RLock()
+	_, ok := b.keys[key]
+	b.mu.RUnlock()
+	if !ok {
+		return store.ErrNotFound
+	}
+	return b.commit(record{kind: kindKeyDel, key: key})
+}
+
+// DeleteKeyIf implements store.KeyDeleter.
+func (b *Backend) DeleteKeyIf(ctx context.Context, e store.KeyEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.keyMu.Lock()
+	defer b.keyMu.Unlock()
+	b.mu.RLock()
+	cur, ok := b.keys[e.Key]
+	b.mu.RUnlock()
+	if !ok {
+		return store.ErrNotFound
+	}
+	if cur != e {
+		return fmt.Errorf("%w: %q changed", store.ErrConflict, e.Key)
+	}
+	return b.commit(record{kind: kindKeyDel, key: e.Key})
+}
+
+// Stats describes the segment files.
+type Stats struct {
+	Segments int   `json:"segments"`
+	Blobs    int   `json:"blobs"`
+	Keys     int   `json:"keys"`
+	Bytes    int64 `json:"bytes"`
+	// LiveBytes is the part of Bytes that Compact would keep.
+	LiveBytes int64 `json:"live_bytes"`
+}
+
+// Stats returns the current sizes.
+func (b *Backend) Stats() Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return Stats{Segments: len(b.segs), Blobs: len(b.blobs), Keys: len(b.keys), Bytes: b.total, LiveBytes: b.live}
+}
+
+// Compact rewrites the live records into a new segment and removes the
+// old ones. Writes wait while it runs; reads continue until the switch.
+// A crash part-way leaves the old segments in place, and replaying them
+// before the new one yields the same index.
+func (b *Backend) Compact(ctx context.Context) error {
+	b.fileMu.Lock()
+	defer b.fileMu.Unlock()
+	if b.closed {
+		return errors.New("logstore: store is closed")
+	}
+
+	id := b.activeID + 1
+	f, err := os.OpenFile(filepath.Join(b.dir, segmentName(id)), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	abort := func(err error) error {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	// The index cannot change while fileMu is held: every change goes
+	// through writeBatch.
+	b.mu.RLock()
+	hashes := make([]string, 0, len(b.blobs))
+	for h := range b.blobs {
+		hashes = append(hashes, h)
+	}
+	keys := make([]string, 0, len(b.keys))
+	for k := range b.keys {
+		keys = append(keys, k)
+	}
+	b.mu.RUnlock()
+	sort.Strings(hashes)
+	sort.Strings(keys)
+
+	newBlobs := make(map[string]loc, len(hashes))
+	newKeys := make(map[string]loc, len(keys))
+	var off int64
+	var buf []byte
+	emit := func(rec record) error {
+		buf = rec.appendTo(buf[:0])
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+		off += int64(len(buf))
+		return nil
+	}
+	for _, h := range hashes {
+		if err := ctx.Err(); err != nil {
+			return abort(err)
+		}
+		b.mu.RLock()
+		rec, err := b.read(b.blobs[h])
+		b.mu.RUnlock()
+		if err != nil {
+			return abort(err)
+		}
+		start := off
+		if err := emit(rec); err != nil {
+			return abort(err)
+		}
+		newBlobs[h] = loc{seg: id, off: start, size: off - start}
+	}
+	for _, k := range keys {
+		start := off
+		if err := emit(record{kind: kindKey, key: k, value: keyValue(b.keys[k])}); err != nil {
+			return abort(err)
+		}
+		newKeys[k] = loc{seg: id, off: start, size: off - start}
+	}
+	if err := f.Sync(); err != nil {
+		return abort(err)
+	}
+	if err := syncDir(b.dir); err != nil {
+		return abort(err)
+	}
+
+	b.mu.Lock()
+	old := b.segs
+	b.segs = map[uint32]*os.File{id: f}
+	b.blobs, b.keyLocs = newBlobs, newKeys
+	b.live, b.total = off, off
+	b.active, b.activeID, b.size = f, id, off
+	b.mu.Unlock()
+
+	// Oldest first, so a crash part-way never leaves a tombstone's
+	// segment removed while an older segment with the put survives.
+	ids := make([]uint32, 0, len(old))
+	for oid := range old {
+		ids = append(ids, oid)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, oid := range ids {
+		old[oid].Close()
+		if err := os.Remove(filepath.Join(b.dir, segmentName(oid))); err != nil {
+			return err
+		}
+	}
+	return syncDir(b.dir)
+}
+
+// Tenant implements store.Tenanted. Each tenant is a log store of its own
+// in tenants/<id>, with the parent's options.
+func (b *Backend) Tenant(ctx context.Context, id string, create bool) (store.Backend, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !store.ValidTenant(id) {
+		return nil, fmt.Errorf("%w: %q", store.ErrInvalidTenant, id)
+	}
+	b.tenantMu.Lock()
+	defer b.tenantMu.Unlock()
+	if t, ok := b.tenants[id]; ok {
+		return t, nil
+	}
+	dir := filepath.Join(b.dir, "tenants", id)
+	var t *Backend
+	var err error
+	switch {
+	case IsStore(dir):
+		t, err = Open(dir, b.opts)
+	case create:
+		t, err = Init(dir, b.opts)
+	default:
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.tenants == nil {
+		b.tenants = make(map[string]*Backend)
+	}
+	b.tenants[id] = t
+	return t, nil
+}
+
+// Tenants implements store.Tenanted.
+func (b *Backend) Tenants(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(b.dir, "tenants"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() && store.ValidTenant(e.Name()) && IsStore(filepath.Join(b.dir, "tenants", e.Name())) {
+			ids = append(ids, e.Name())
+		}
+	}
+	return ids, nil
+}
+
+// Close waits for in-flight writes and closes the segment files, and
+// those of every open tenant.
+func (b *Backend) Close() error {
+	b.tenantMu.Lock()
+	for _, t := range b.tenants {
+		t.Close()
+	}
+	b.tenantMu.Unlock()
+
+	b.fileMu.Lock()
+	defer b.fileMu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	var err error
+	if !b.opts.NoSync {
+		err = b.active.Sync()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cerr := b.closeFiles(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (b *Backend) closeFiles() error {
+	var err error
+	for id, f := range b.segs {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		delete(b.segs, id)
+	}
+	if b.lock != nil {
+		b.lock.Close()
+		b.lock = nil
+	}
+	return err
+}
+
+// syncDir makes file creations and removals in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, fs.ErrInvalid) {
+		return err
+	}
+	return nil
+}
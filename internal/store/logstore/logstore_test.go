@@ -0,0 +1,274 @@
+package logstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+	"github.com/holeyfield33-art/helios/internal/store/storetest"
+)
+
+func TestBackend(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Backend { return newBackend(t, t.TempDir(), Options{}) })
+}
+
+func newBackend(t *testing.T, dir string, opts Options) *Backend {
+	t.Helper()
+	b, err := Init(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func blob(i int) (string, []byte) {
+	data := []byte(fmt.Sprintf(`{"n":%d}`, i))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), data
+}
+
+// fill writes n blobs from concurrent writers and keys every tenth one.
+func fill(t *testing.T, b *Backend, n int) {
+	t.Helper()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < n; i += 8 {
+				h, data := blob(i)
+				if err := b.Put(ctx, h, data); err != nil {
+					t.Error(err)
+					return
+				}
+				if i%10 == 0 {
+					e := store.KeyEntry{Key: fmt.Sprintf("k/%04d", i), Hash: h, UpdatedAt: "2025-01-15T10:30:00.000Z", Category: "fact"}
+					if err := b.SetKey(ctx, e, store.Any); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// check verifies that b holds blobs 0 to n-1 except deleted, and the keys
+// fill(filled) wrote.
+func check(t *testing.T, b *Backend, n, filled int, deleted map[int]bool) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		h, data := blob(i)
+		got, err := b.Get(ctx, h)
+		if deleted[i] {
+			if !errors.Is(err, store.ErrNotFound) {
+				t.Fatalf("blob %d: deleted, but Get returned %v", i, err)
+			}
+			continue
+		}
+		if err != nil || string(got) != string(data) {
+			t.Fatalf("blob %d: %q, %v", i, got, err)
+		}
+	}
+	all, err := b.List(ctx, "")
+	if err != nil || len(all) != n-len(deleted) {
+		t.Fatalf("List: %d hashes, %v; want %d", len(all), err, n-len(deleted))
+	}
+	keys, err := b.ListKeys(ctx, "k/")
+	if err != nil || len(keys) != (filled+9)/10 {
+		t.Fatalf("ListKeys: %d entries, %v", len(keys), err)
+	}
+	for _, e := range keys {
+		if e.Category != "fact" {
+			t.Fatalf("key %s lost its category: %+v", e.Key, e)
+		}
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	b := newBackend(t, dir, Options{SegmentSize: 4096})
+	fill(t, b, 500)
+	if s := b.Stats(); s.Segments < 2 {
+		t.Errorf("Stats().Segments = %d, want several with a 4 KiB segment size", s.Segments)
+	}
+	ctx := context.Background()
+	deleted := map[int]bool{}
+	for _, i := range []int{3, 77, 499} {
+		h, _ := blob(i)
+		if err := b.Delete(ctx, h); err != nil {
+			t.Fatal(err)
+		}
+		deleted[i] = true
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b = newBackend(t, dir, Options{SegmentSize: 4096})
+	check(t, b, 500, 500, deleted)
+}
+
+func TestTornTail(t *testing.T) {
+	dir := t.TempDir()
+	b := newBackend(t, dir, Options{})
+	fill(t, b, 20)
+	seg := filepath.Join(dir, segmentName(b.activeID))
+	b.Close()
+
+	// A crash mid-write leaves part of a record at the end.
+	f, err := os.OpenFile(seg, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := record{kind: kindBlob, key: "ab", value: []byte("lost")}.appendTo(nil)
+	f.Write(partial[:len(partial)-2])
+	f.Close()
+
+	b = newBackend(t, dir, Options{})
+	check(t, b, 20, 20, nil)
+	h, data := blob(20)
+	if err := b.Put(context.Background(), h, data); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	b = newBackend(t, dir, Options{})
+	check(t, b, 21, 20, nil)
+}
+
+func TestCorruptSealedSegment(t *testing.T) {
+	dir := t.TempDir()
+	b := newBackend(t, dir, Options{SegmentSize: 1024})
+	fill(t, b, 100)
+	b.Close()
+
+	seg := filepath.Join(dir, segmentName(1))
+	data, err := os.ReadFile(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[headerSize+3] ^= 0xff
+	if err := os.WriteFile(seg, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir, Options{}); err == nil {
+		t.Fatal("Open accepted a corrupt sealed segment")
+	}
+}
+
+func TestCorruptActiveSegment(t *testing.T) {
+	dir := t.TempDir()
+	b := newBackend(t, dir, Options{})
+	fill(t, b, 10)
+	seg := filepath.Join(dir, segmentName(b.activeID))
+	b.Close()
+
+	// Damage to the first record is not a torn tail: the records after it
+	// are durable, and truncating would drop them.
+	data, err := os.ReadFile(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[headerSize+3] ^= 0xff
+	if err := os.WriteFile(seg, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir, Options{}); err == nil {
+		t.Fatal("Open accepted a corrupt record followed by intact ones")
+	}
+	if info, err := os.Stat(seg); err != nil || info.Size() != int64(len(data)) {
+		t.Fatalf("segment was truncated: %v, %v", info, err)
+	}
+}
+
+func TestOpenLocks(t *testing.T) {
+	dir := t.TempDir()
+	b := newBackend(t, dir, Options{})
+	if _, err := Open(dir, Options{}); err == nil && runtime.GOOS != "windows" {
+		t.Fatal("a second Open of a store in use succeeded")
+	}
+	b.Close()
+	b, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open after Close: %v", err)
+	}
+	b.Close()
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	b := newBackend(t, dir, Options{SegmentSize: 4096})
+	fill(t, b, 300)
+	ctx := context.Background()
+	deleted := map[int]bool{}
+	for i := 0; i < 300; i += 3 {
+		h, _ := blob(i)
+		if err := b.Delete(ctx, h); err != nil {
+			t.Fatal(err)
+		}
+		deleted[i] = true
+	}
+	before := b.Stats()
+	if before.LiveBytes >= before.Bytes {
+		t.Fatalf("Stats() = %+v, want dead bytes after deletes", before)
+	}
+	if err := b.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+	after := b.Stats()
+	if after.Segments != 1 || after.Bytes != before.LiveBytes || after.LiveBytes != after.Bytes {
+		t.Errorf("Stats() after Compact = %+v, want one segment of %d live bytes", after, before.LiveBytes)
+	}
+	check(t, b, 300, 300, deleted)
+
+	// Writes continue in the compacted segment and survive a reopen.
+	h, data := blob(300)
+	if err := b.Put(ctx, h, data); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	b = newBackend(t, dir, Options{SegmentSize: 4096})
+	check(t, b, 301, 300, deleted)
+}
+
+func TestInitRejectsDirectoryStore(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := store.InitFS(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Init(dir, Options{}); err == nil {
+		t.Fatal("Init took over a directory-layout store")
+	}
+}
+
+var benchN atomic.Int64
+
+func BenchmarkPut(b *testing.B) {
+	s, err := Init(b.TempDir(), Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			h, data := blob(int(benchN.Add(1)))
+			if err := s.Put(ctx, h, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is a Backend that keeps everything in process memory, for tests
+// and short-lived tools. The zero value is not usable; call NewMemory.
+type Memory struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+	keys  map[string]KeyEntry
+
+	tenants tenantSet[*Memory]
+}
+
+// NewMemory returns an empty in-memory backend.
+func NewMemory() *Memory {
+	return &Memory{blobs: make(map[string][]byte), keys: make(map[string]KeyEntry)}
+}
+
+// Put implements Backend.
+func (m *Memory) Put(ctx context.Context, h string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.blobs[h]; !ok {
+		m.blobs[h] = append([]byte(nil), data...)
+	}
+	return nil
+}
+
+// Get implements Backend.
+func (m *Memory) Get(ctx context.Context, h string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.blobs[h]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Has implements Backend.
+func (m *Memory) Has(ctx context.Context, h string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.blobs[h]
+	return ok, nil
+}
+
+// List implements Backend.
+func (m *Memory) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var hashes []string
+	for h := range m.blobs {
+		if strings.HasPrefix(h, prefix) {
+			hashes = append(hashes, h)
+		}
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// Delete implements Backend.
+func (m *Memory) Delete(ctx context.Context, h string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.blobs[h]; !ok {
+		return ErrNotFound
+	}
+	delete(m.blobs, h)
+	return nil
+}
+
+// SetKey implements Backend.
+func (m *Memory) SetKey(ctx context.Context, e KeyEntry, expected string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, exists := m.keys[e.Key]
+	if err := CheckExpected(e.Key, cur, exists, expected); err != nil {
+		return err
+	}
+	m.keys[e.Key] = e
+	return nil
+}
+
+// ResolveKey implements Backend.
+func (m *Memory) ResolveKey(ctx context.Context, key string) (KeyEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return KeyEntry{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.keys[key]
+	if !ok {
+		return KeyEntry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+// ListKeys implements Backend.
+func (m *Memory) ListKeys(ctx context.Context, prefix string) ([]KeyEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var entries []KeyEntry
+	for k, e := range m.keys {
+		if strings.HasPrefix(k, prefix) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// DeleteKey implements Backend.
+func (m *Memory) DeleteKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.keys, key)
+	return nil
+}
+
+// DeleteKeyIf implements KeyDeleter.
+func (m *Memory) DeleteKeyIf(ctx context.Context, e KeyEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.keys[e.Key]
+	if !ok {
+		return ErrNotFound
+	}
+	if cur != e {
+		return fmt.Errorf("%w: %q changed", ErrConflict, e.Key)
+	}
+	delete(m.keys, e.Key)
+	return nil
+}
+
+// Tenant implements Tenanted.
+func (m *Memory) Tenant(ctx context.Context, id string, create bool) (Backend, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.tenants.get(id, func(string) (*Memory, error) {
+		if !create {
+			return nil, ErrNotFound
+		}
+		return NewMemory(), nil
+	})
+}
+
+// Tenants implements Tenanted.
+func (m *Memory) Tenants(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.tenants.ids()
+}
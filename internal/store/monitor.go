@@ -0,0 +1,362 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+)
+
+// Anomaly rule metrics, each computed per category over the rule's
+// window.
+const (
+	// MetricWrites is the number of accepted writes.
+	MetricWrites = "writes"
+	// MetricRejects is the number of rejected writes.
+	MetricRejects = "rejects"
+	// MetricRejectRatio is the rejected share of all writes, from 0 to 1.
+	MetricRejectRatio = "reject_ratio"
+	// MetricBytes is the request body bytes of accepted writes.
+	MetricBytes = "bytes"
+)
+
+// defaultMinEvents is AnomalyRule.MinEvents when unset.
+const defaultMinEvents = 10
+
+// sizeBounds are the upper bounds of the write size histogram buckets.
+var sizeBounds = []int64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+// AnomalyPolicy lists the rules a Monitor alerts on.
+type AnomalyPolicy struct {
+	Rules []AnomalyRule `json:"rules"`
+}
+
+// AnomalyRule fires when Metric, over the last Window, reaches Threshold
+// in a category. It fires once on crossing the threshold, and again only
+// after the metric has fallen back below it.
+type AnomalyRule struct {
+	Name string `json:"name"`
+	// Category limits the rule to one category; empty applies it to
+	// every category separately.
+	Category string `json:"category,omitempty"`
+	Metric   string `json:"metric"`
+	// Window is a Go duration such as "1m".
+	Window    string  `json:"window"`
+	Threshold float64 `json:"threshold"`
+	// MinEvents is the number of writes, accepted or rejected, a window
+	// must hold before a reject_ratio rule is evaluated. Default 10.
+	MinEvents int `json:"min_events,omitempty"`
+
+	window time.Duration
+}
+
+// LoadAnomalyPolicy reads an AnomalyPolicy from a JSON file.
+func LoadAnomalyPolicy(path string) (*AnomalyPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p AnomalyPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid anomaly policy %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid anomaly policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *AnomalyPolicy) validate() error {
+	names := make(map[string]bool)
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" || names[r.Name] {
+			return fmt.Errorf("rule %d: name must be set and unique", i)
+		}
+		names[r.Name] = true
+		switch r.Metric {
+		case MetricWrites, MetricRejects, MetricRejectRatio, MetricBytes:
+		default:
+			return fmt.Errorf("rule %q: unknown metric %q (want writes, rejects, reject_ratio, or bytes)", r.Name, r.Metric)
+		}
+		d, err := time.ParseDuration(r.Window)
+		if err != nil || d < time.Second {
+			return fmt.Errorf("rule %q: window must be a duration of at least 1s", r.Name)
+		}
+		r.window = d
+		if r.Threshold <= 0 || r.MinEvents < 0 {
+			return fmt.Errorf("rule %q: threshold must be positive and min_events not negative", r.Name)
+		}
+		if r.Metric == MetricRejectRatio && r.Threshold > 1 {
+			return fmt.Errorf("rule %q: a reject_ratio threshold is at most 1", r.Name)
+		}
+	}
+	return nil
+}
+
+// Anomaly is a rule firing for a category.
+type Anomaly struct {
+	Rule      string        `json:"rule"`
+	Category  string        `json:"category"`
+	Metric    string        `json:"metric"`
+	Value     float64       `json:"value"`
+	Threshold float64       `json:"threshold"`
+	Window    time.Duration `json:"window"`
+	Time      time.Time     `json:"time"`
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s: %s of category %q reached %g over %s (threshold %g)", a.Rule, a.Metric, a.Category, a.Value, a.Window, a.Threshold)
+}
+
+// Monitor keeps per-category statistics of the writes a gateway handles:
+// accepted and rejected counts, rejections by error code, and the size
+// distribution of accepted objects. It evaluates an AnomalyPolicy as
+// writes arrive and reports each rule that fires to a callback, which
+// runs in its own goroutine so alerting never delays a write. The nil
+// Monitor records nothing.
+type Monitor struct {
+	mu        sync.Mutex
+	rules     []AnomalyRule
+	maxWindow time.Duration
+	cats      map[string]*categoryStats
+	firing    map[[2]string]bool
+	fired     map[string]uint64
+	onAnomaly func(Anomaly)
+	now       func() time.Time
+}
+
+type categoryStats struct {
+	accepted, rejected uint64
+	codes              map[string]uint64
+	sizes              []uint64
+	sizeSum            uint64
+	// recent holds per-second counts covering the longest rule window.
+	recent []secondStats
+}
+
+type secondStats struct {
+	sec                       int64
+	accepted, rejected, bytes uint64
+}
+
+// NewMonitor returns a monitor alerting on p's rules, if p is not nil,
+// through onAnomaly, if it is not nil. It fails if a rule is invalid.
+func NewMonitor(p *AnomalyPolicy, onAnomaly func(Anomaly)) (*Monitor, error) {
+	m := &Monitor{
+		cats:      make(map[string]*categoryStats),
+		firing:    make(map[[2]string]bool),
+		fired:     make(map[string]uint64),
+		onAnomaly: onAnomaly,
+		now:       time.Now,
+	}
+	if p != nil {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		m.rules = p.Rules
+		for _, r := range p.Rules {
+			m.maxWindow = max(m.maxWindow, r.window)
+		}
+	}
+	return m, nil
+}
+
+// RecordWrite records an accepted write of size bytes to category.
+// Categories are recorded in NFC, as the key index holds them.
+func (m *Monitor) RecordWrite(category string, size int) {
+	if m == nil {
+		return
+	}
+	category = canon.NormalizeString(category)
+	m.mu.Lock()
+	st := m.stats(category)
+	st.accepted++
+	st.sizeSum += uint64(size)
+	i := sort.Search(len(sizeBounds), func(i int) bool { return int64(size) <= sizeBounds[i] })
+	st.sizes[i]++
+	sec := m.second(st)
+	sec.accepted++
+	sec.bytes += uint64(size)
+	fired := m.evaluate(category, st)
+	m.mu.Unlock()
+	m.report(fired)
+}
+
+// RecordReject records a write to category rejected with an error code.
+// A category no write has been accepted under is recorded as the empty
+// category, so malformed input cannot create arbitrarily many.
+func (m *Monitor) RecordReject(category, code string) {
+	if m == nil {
+		return
+	}
+	category = canon.NormalizeString(category)
+	m.mu.Lock()
+	if st, ok := m.cats[category]; !ok || st.accepted == 0 {
+		category = ""
+	}
+	st := m.stats(category)
+	st.rejected++
+	st.codes[code]++
+	m.second(st).rejected++
+	fired := m.evaluate(category, st)
+	m.mu.Unlock()
+	m.report(fired)
+}
+
+func (m *Monitor) stats(category string) *categoryStats {
+	st, ok := m.cats[category]
+	if !ok {
+		st = &categoryStats{codes: make(map[string]uint64), sizes: make([]uint64, len(sizeBounds)+1)}
+		m.cats[category] = st
+	}
+	return st
+}
+
+// second returns the counts of the current second, dropping seconds that
+// have left every window.
+func (m *Monitor) second(st *categoryStats) *secondStats {
+	now := m.now().Unix()
+	keep := now - int64(m.maxWindow/time.Second)
+	i := 0
+	for i < len(st.recent) && st.recent[i].sec <= keep {
+		i++
+	}
+	st.recent = st.recent[i:]
+	if n := len(st.recent); n == 0 || st.recent[n-1].sec != now {
+		st.recent = append(st.recent, secondStats{sec: now})
+	}
+	return &st.recent[len(st.recent)-1]
+}
+
+// evaluate checks the rules for category and returns those that started
+// firing.
+func (m *Monitor) evaluate(category string, st *categoryStats) []Anomaly {
+	var fired []Anomaly
+	now := m.now()
+	for _, r := range m.rules {
+		if r.Category != "" && r.Category != category {
+			continue
+		}
+		var w secondStats
+		from := now.Unix() - int64(r.window/time.Second)
+		for _, s := range st.recent {
+			if s.sec > from {
+				w.accepted += s.accepted
+				w.rejected += s.rejected
+				w.bytes += s.bytes
+			}
+		}
+		value, ok := r.value(w)
+		id := [2]string{r.Name, category}
+		switch {
+		case ok && value >= r.Threshold:
+			if !m.firing[id] {
+				m.firing[id] = true
+				m.fired[r.Name]++
+				fired = append(fired, Anomaly{Rule: r.Name, Category: category, Metric: r.Metric, Value: value, Threshold: r.Threshold, Window: r.window, Time: now})
+			}
+		default:
+			delete(m.firing, id)
+		}
+	}
+	return fired
+}
+
+func (m *Monitor) report(fired []Anomaly) {
+	if m.onAnomaly == nil {
+		return
+	}
+	for _, a := range fired {
+		go m.onAnomaly(a)
+	}
+}
+
+// value computes the rule's metric over a window; ok is false if the
+// window holds too few writes to judge.
+func (r AnomalyRule) value(w secondStats) (v float64, ok bool) {
+	switch r.Metric {
+	case MetricWrites:
+		return float64(w.accepted), true
+	case MetricRejects:
+		return float64(w.rejected), true
+	case MetricBytes:
+		return float64(w.bytes), true
+	}
+	minEvents := r.MinEvents
+	if minEvents == 0 {
+		minEvents = defaultMinEvents
+	}
+	total := w.accepted + w.rejected
+	if total == 0 || total < uint64(minEvents) {
+		return 0, false
+	}
+	return float64(w.rejected) / float64(total), true
+}
+
+// WritePrometheus writes the statistics in the Prometheus text format.
+func (m *Monitor) WritePrometheus(w io.Writer) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cats := make([]string, 0, len(m.cats))
+	for c := range m.cats {
+		cats = append(cats, c)
+	}
+	sort.Strings(cats)
+
+	var b strings.Builder
+	b.WriteString("# HELP helios_store_writes_total Writes handled by the gateway, by category and outcome.\n# TYPE helios_store_writes_total counter\n")
+	for _, c := range cats {
+		st := m.cats[c]
+		fmt.Fprintf(&b, "helios_store_writes_total{category=%s,outcome=\"accepted\"} %d\n", promLabel(c), st.accepted)
+		fmt.Fprintf(&b, "helios_store_writes_total{category=%s,outcome=\"rejected\"} %d\n", promLabel(c), st.rejected)
+	}
+	b.WriteString("# HELP helios_store_write_rejects_total Rejected writes, by category and error code.\n# TYPE helios_store_write_rejects_total counter\n")
+	for _, c := range cats {
+		codes := make([]string, 0, len(m.cats[c].codes))
+		for code := range m.cats[c].codes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "helios_store_write_rejects_total{category=%s,code=%s} %d\n", promLabel(c), promLabel(code), m.cats[c].codes[code])
+		}
+	}
+	b.WriteString("# HELP helios_store_write_bytes Request body sizes of accepted writes, by category.\n# TYPE helios_store_write_bytes histogram\n")
+	for _, c := range cats {
+		st := m.cats[c]
+		if st.accepted == 0 {
+			continue
+		}
+		var n uint64
+		for i, bound := range sizeBounds {
+			n += st.sizes[i]
+			fmt.Fprintf(&b, "helios_store_write_bytes_bucket{category=%s,le=\"%d\"} %d\n", promLabel(c), bound, n)
+		}
+		fmt.Fprintf(&b, "helios_store_write_bytes_bucket{category=%s,le=\"+Inf\"} %d\n", promLabel(c), st.accepted)
+		fmt.Fprintf(&b, "helios_store_write_bytes_sum{category=%s} %d\n", promLabel(c), st.sizeSum)
+		fmt.Fprintf(&b, "helios_store_write_bytes_count{category=%s} %d\n", promLabel(c), st.accepted)
+	}
+	b.WriteString("# HELP helios_store_anomalies_total Times each anomaly rule fired.\n# TYPE helios_store_anomalies_total counter\n")
+	for _, r := range m.rules {
+		fmt.Fprintf(&b, "helios_store_anomalies_total{rule=%s} %d\n", promLabel(r.Name), m.fired[r.Name])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabel quotes a Prometheus label value.
+func promLabel(v string) string {
+	return `"` + promEscaper.Replace(v) + `"`
+}
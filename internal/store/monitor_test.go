@@ -0,0 +1,140 @@
+package store
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMonitor(t *testing.T, rules ...AnomalyRule) (*Monitor, *time.Time, chan Anomaly) {
+	t.Helper()
+	fired := make(chan Anomaly, 16)
+	m, err := NewMonitor(&AnomalyPolicy{Rules: rules}, func(a Anomaly) { fired <- a })
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_800_000_000, 0)
+	m.now = func() time.Time { return now }
+	return m, &now, fired
+}
+
+func expectAnomalies(t *testing.T, fired chan Anomaly, rules ...string) {
+	t.Helper()
+	for _, want := range rules {
+		select {
+		case a := <-fired:
+			if a.Rule != want {
+				t.Errorf("fired %v, want rule %q", a, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("rule %q did not fire", want)
+		}
+	}
+	select {
+	case a := <-fired:
+		t.Errorf("unexpected anomaly %v", a)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestMonitorRejectFlood(t *testing.T) {
+	m, now, fired := testMonitor(t, AnomalyRule{Name: "flood", Metric: MetricRejects, Window: "10s", Threshold: 3})
+	m.RecordWrite("notes", 100)
+	for i := 0; i < 5; i++ {
+		m.RecordReject("notes", "CANON_ERR_FLOAT_PROHIBITED")
+	}
+	expectAnomalies(t, fired, "flood")
+
+	// The rule stays quiet while firing, and re-arms once the window
+	// drains below the threshold.
+	*now = now.Add(11 * time.Second)
+	m.RecordReject("notes", "CANON_ERR_FLOAT_PROHIBITED")
+	expectAnomalies(t, fired)
+	m.RecordReject("notes", "CANON_ERR_FLOAT_PROHIBITED")
+	m.RecordReject("notes", "CANON_ERR_FLOAT_PROHIBITED")
+	expectAnomalies(t, fired, "flood")
+}
+
+func TestMonitorRejectRatio(t *testing.T) {
+	m, _, fired := testMonitor(t,
+		AnomalyRule{Name: "ratio", Category: "notes", Metric: MetricRejectRatio, Window: "1m", Threshold: 0.5, MinEvents: 4},
+		AnomalyRule{Name: "bytes", Metric: MetricBytes, Window: "1m", Threshold: 1000},
+	)
+	m.RecordWrite("notes", 10)
+	m.RecordReject("notes", "X")
+	m.RecordReject("notes", "X")
+	expectAnomalies(t, fired) // too few events to judge
+	m.RecordReject("notes", "X")
+	expectAnomalies(t, fired, "ratio")
+	m.RecordWrite("images", 2000)
+	expectAnomalies(t, fired, "bytes")
+}
+
+func TestMonitorMetrics(t *testing.T) {
+	m, _, _ := testMonitor(t, AnomalyRule{Name: "flood", Metric: MetricRejects, Window: "1s", Threshold: 1})
+	m.RecordWrite("notes", 300)
+	m.RecordWrite("notes", 10)
+	m.RecordReject("notes", "CANON_ERR_FLOAT_PROHIBITED")
+	m.RecordReject("never-accepted", "STORE_ERR_KEY_MISMATCH")
+
+	var b strings.Builder
+	if err := m.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`helios_store_writes_total{category="notes",outcome="accepted"} 2`,
+		`helios_store_writes_total{category="notes",outcome="rejected"} 1`,
+		`helios_store_write_rejects_total{category="",code="STORE_ERR_KEY_MISMATCH"} 1`,
+		`helios_store_write_bytes_bucket{category="notes",le="256"} 1`,
+		`helios_store_write_bytes_bucket{category="notes",le="1024"} 2`,
+		`helios_store_write_bytes_sum{category="notes"} 310`,
+		`helios_store_anomalies_total{rule="flood"} 2`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("metrics lack %q:\n%s", line, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "never-accepted") {
+		t.Errorf("a category never accepted was recorded")
+	}
+}
+
+func TestAnomalyPolicyValidation(t *testing.T) {
+	for _, r := range []AnomalyRule{
+		{Metric: MetricWrites, Window: "1m", Threshold: 1},
+		{Name: "a", Metric: "latency", Window: "1m", Threshold: 1},
+		{Name: "a", Metric: MetricWrites, Window: "10ms", Threshold: 1},
+		{Name: "a", Metric: MetricWrites, Window: "1m"},
+		{Name: "a", Metric: MetricRejectRatio, Window: "1m", Threshold: 2},
+	} {
+		if _, err := NewMonitor(&AnomalyPolicy{Rules: []AnomalyRule{r}}, nil); err == nil {
+			t.Errorf("rule %+v was accepted", r)
+		}
+	}
+}
+
+func TestGatewayMonitorsWrites(t *testing.T) {
+	s := newTestStore(t)
+	m, _ := NewMonitor(nil, nil)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true, Metrics: true, Monitor: m}))
+	defer srv.Close()
+
+	if resp, body := put(t, srv, "a", objectJSON("a", "v"), nil); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT: %d %s", resp.StatusCode, body)
+	}
+	put(t, srv, "b", objectJSON("other", "v"), nil)
+	put(t, srv, "c", strings.Replace(objectJSON("c", "v"), `"v"`, `1.5`, 1), nil)
+
+	_, metrics := get(t, srv, "/metrics", nil)
+	for _, line := range []string{
+		`helios_store_writes_total{category="project",outcome="accepted"} 1`,
+		`helios_store_write_rejects_total{category="project",code="STORE_ERR_KEY_MISMATCH"} 1`,
+		`helios_store_write_rejects_total{category="project",code="CANON_ERR_FLOAT_PROHIBITED"} 1`,
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("/metrics lacks %q:\n%s", line, metrics)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+-- Blobs are canonical bytes named by their content hash. The "C"
+-- collation makes ORDER BY and LIKE 'prefix%' use byte order, matching
+-- the other backends, and lets prefix scans use the primary key index.
+CREATE TABLE helios_blobs (
+	hash      text COLLATE "C" PRIMARY KEY,
+	data      bytea NOT NULL,
+	stored_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE helios_keys (
+	key        text COLLATE "C" PRIMARY KEY,
+	hash       text COLLATE "C" NOT NULL,
+	updated_at text NOT NULL
+);
+
+CREATE INDEX helios_keys_hash ON helios_keys (hash);
@@ -0,0 +1,11 @@
+-- The category of each key's current object, so listings can filter on
+-- it without reading objects. Existing keys take it from their blobs,
+-- which are canonical JSON.
+ALTER TABLE helios_keys ADD COLUMN category text COLLATE "C" NOT NULL DEFAULT '';
+
+UPDATE helios_keys k
+SET category = convert_from(b.data, 'UTF8')::jsonb ->> 'category'
+FROM helios_blobs b
+WHERE b.hash = k.hash;
+
+CREATE INDEX helios_keys_category ON helios_keys (category, key);
@@ -0,0 +1,3 @@
+-- The stamp of the pipeline that hashed each key's current object.
+-- Existing keys keep an empty stamp, which names version 1.
+ALTER TABLE helios_keys ADD COLUMN stamp text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- The idempotency key of the request that wrote each key's current
+-- object, so a retried request is answered without writing again.
+ALTER TABLE helios_keys ADD COLUMN request_id text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- The residency region of each key's current object, which its canonical
+-- form leaves out, so exports can filter on it.
+ALTER TABLE helios_keys ADD COLUMN residency text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- The reason of each key's legal hold, empty for keys without one; held
+-- keys are neither deleted nor pruned by retention.
+ALTER TABLE helios_keys ADD COLUMN legal_hold text NOT NULL DEFAULT '';
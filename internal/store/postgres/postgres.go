@@ -0,0 +1,339 @@
+// Package postgres is a store.Backend in PostgreSQL. Blobs are bytea rows
+// in helios_blobs and the key index is helios_keys; the schema is created
+// and upgraded by Migrate from SQL files embedded in the package, and
+// Migrations exposes them for teams that apply schema changes with their
+// own tooling.
+//
+// Key updates take a transaction-scoped advisory lock on the key, so
+// compare-and-swap is atomic across every process using the database,
+// including for keys that do not exist yet.
+//
+// The package works with any database/sql driver for Postgres; the helios
+// CLI uses the stdlib-only pgwire driver.
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// lockClass is the first key of every advisory lock the package takes
+// ("HELI"), so its locks do not collide with other applications' locks on
+// single bigint keys.
+const lockClass = 0x48454c49
+
+// migrationLock is the second key of the advisory lock held by Migrate.
+// Key locks use other values, so a hash collision only costs a spurious
+// wait.
+const migrationLock = 0
+
+// Migration is one schema change.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the schema migrations in order. Migrate records the
+// applied versions in helios_schema_migrations.
+func Migrations() []Migration {
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		panic(err)
+	}
+	sort.Strings(names)
+	var out []Migration
+	for _, name := range names {
+		base := strings.TrimPrefix(name, "migrations/")
+		num, _, _ := strings.Cut(base, "_")
+		v, err := strconv.Atoi(num)
+		if err != nil {
+			panic("postgres: migration file without a version: " + name)
+		}
+		data, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, Migration{Version: v, Name: strings.TrimSuffix(base, ".sql"), SQL: string(data)})
+	}
+	return out
+}
+
+// Migrate applies the migrations the database has not seen yet, each in
+// its own transaction. Concurrent callers serialize on an advisory lock,
+// so every instance of a service can call Migrate at startup.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1, $2)", lockClass, migrationLock); err != nil {
+		return fmt.Errorf("postgres: failed to take the migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1, $2)", lockClass, migrationLock)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS helios_schema_migrations (
+	version    integer PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`); err != nil {
+		return fmt.Errorf("postgres: failed to create the migrations table: %w", err)
+	}
+
+	applied := map[int]bool{}
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM helios_schema_migrations")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[v] = true
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, m := range Migrations() {
+		if applied[m.Version] {
+			continue
+		}
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("postgres: migration %s: %w", m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO helios_schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("postgres: migration %s: %w", m.Name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("postgres: migration %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Backend is a store.Backend over a Postgres database.
+type Backend struct {
+	db *sql.DB
+}
+
+// New returns a backend over db, whose schema must be current; see Open.
+func New(db *sql.DB) *Backend { return &Backend{db: db} }
+
+// Open migrates db and returns a backend over it.
+func Open(ctx context.Context, db *sql.DB) (*Backend, error) {
+	if err := Migrate(ctx, db); err != nil {
+		return nil, err
+	}
+	return New(db), nil
+}
+
+// keyLock returns the second advisory lock key for key.
+func keyLock(key string) int32 {
+	sum := sha256.Sum256([]byte(key))
+	id := int32(binary.BigEndian.Uint32(sum[:]))
+	if id == migrationLock {
+		id = 1
+	}
+	return id
+}
+
+// likePrefix returns a LIKE pattern matching strings that begin with
+// prefix.
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
+
+// Put implements store.Backend.
+func (b *Backend) Put(ctx context.Context, h string, data []byte) error {
+	_, err := b.db.ExecContext(ctx, "INSERT INTO helios_blobs (hash, data) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING", h, data)
+	return err
+}
+
+// Get implements store.Backend.
+func (b *Backend) Get(ctx context.Context, h string) ([]byte, error) {
+	var data []byte
+	err := b.db.QueryRowContext(ctx, "SELECT data FROM helios_blobs WHERE hash = $1", h).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return data, err
+}
+
+// Has implements store.Backend.
+func (b *Backend) Has(ctx context.Context, h string) (bool, error) {
+	var one int
+	err := b.db.QueryRowContext(ctx, "SELECT 1 FROM helios_blobs WHERE hash = $1", h).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List implements store.Backend.
+func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := b.db.QueryContext(ctx, "SELECT hash FROM helios_blobs WHERE hash LIKE $1 ORDER BY hash", likePrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hashes []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, h)
+	}
+	return hashes, rows.Err()
+}
+
+// Delete implements store.Backend.
+func (b *Backend) Delete(ctx context.Context, h string) error {
+	res, err := b.db.ExecContext(ctx, "DELETE FROM helios_blobs WHERE hash = $1", h)
+	return deleted(res, err)
+}
+
+// SetKey implements store.Backend.
+func (b *Backend) SetKey(ctx context.Context, e store.KeyEntry, expected string) error {
+	return b.withKeyLock(ctx, e.Key, func(tx *sql.Tx) error {
+		var cur store.KeyEntry
+		err := tx.QueryRowContext(ctx, "SELECT key, hash, updated_at, category, stamp, request_id, residency, legal_hold FROM helios_keys WHERE key = $1", e.Key).Scan(&cur.Key, &cur.Hash, &cur.UpdatedAt, &cur.Category, &cur.Stamp, &cur.RequestID, &cur.Residency, &cur.LegalHold)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err := store.CheckExpected(e.Key, cur, err == nil, expected); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO helios_keys (key, hash, updated_at, category, stamp, request_id, residency, legal_hold) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (key) DO UPDATE SET hash = EXCLUDED.hash, updated_at = EXCLUDED.updated_at, category = EXCLUDED.category, stamp = EXCLUDED.stamp, request_id = EXCLUDED.request_id, residency = EXCLUDED.residency, legal_hold = EXCLUDED.legal_hold`, e.Key, e.Hash, e.UpdatedAt, e.Category, e.Stamp, e.RequestID, e.Residency, e.LegalHold)
+		return err
+	})
+}
+
+// ResolveKey implements store.Backend.
+func (b *Backend) ResolveKey(ctx context.Context, key string) (store.KeyEntry, error) {
+	var e store.KeyEntry
+	err := b.db.QueryRowContext(ctx, "SELECT key, hash, updated_at, category, stamp, request_id, residency, legal_hold FROM helios_keys WHERE key = $1", key).Scan(&e.Key, &e.Hash, &e.UpdatedAt, &e.Category, &e.Stamp, &e.RequestID, &e.Residency, &e.LegalHold)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.KeyEntry{}, store.ErrNotFound
+	}
+	return e, err
+}
+
+// ListKeys implements store.Backend.
+func (b *Backend) ListKeys(ctx context.Context, prefix string) ([]store.KeyEntry, error) {
+	return b.queryKeys(ctx, "SELECT key, hash, updated_at, category, stamp, request_id, residency, legal_hold FROM helios_keys WHERE key LIKE $1 ORDER BY key", likePrefix(prefix))
+}
+
+// ListKeysPage implements store.KeyPager with an index range scan, so a
+// page costs the same however many keys there are.
+func (b *Backend) ListKeysPage(ctx context.Context, q store.KeyQuery) ([]store.KeyEntry, error) {
+	query := "SELECT key, hash, updated_at, category, stamp, request_id, residency, legal_hold FROM helios_keys WHERE key LIKE $1 AND key > $2"
+	args := []any{likePrefix(q.Prefix), q.After}
+	if q.Category != "" {
+		query += " AND category = $3"
+		args = append(args, q.Category)
+	}
+	query += fmt.Sprintf(" ORDER BY key LIMIT %d", q.Limit)
+	return b.queryKeys(ctx, query, args...)
+}
+
+func (b *Backend) queryKeys(ctx context.Context, query string, args ...any) ([]store.KeyEntry, error) {
+	rows, err := b.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []store.KeyEntry
+	for rows.Next() {
+		var e store.KeyEntry
+		if err := rows.Scan(&e.Key, &e.Hash, &e.UpdatedAt, &e.Category, &e.Stamp, &e.RequestID, &e.Residency, &e.LegalHold); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteKey implements store.Backend. It takes the key's lock so it
+// cannot interleave with a compare-and-swap on the same key.
+func (b *Backend) DeleteKey(ctx context.Context, key string) error {
+	return b.withKeyLock(ctx, key, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM helios_keys WHERE key = $1", key)
+		return deleted(res, err)
+	})
+}
+
+// DeleteKeyIf implements store.KeyDeleter. It compares every column of
+// the entry under the key's lock.
+func (b *Backend) DeleteKeyIf(ctx context.Context, e store.KeyEntry) error {
+	return b.withKeyLock(ctx, e.Key, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM helios_keys WHERE key = $1 AND hash = $2 AND updated_at = $3 AND category = $4 AND stamp = $5 AND request_id = $6 AND residency = $7 AND legal_hold = $8`,
+			e.Key, e.Hash, e.UpdatedAt, e.Category, e.Stamp, e.RequestID, e.Residency, e.LegalHold)
+		if err := deleted(res, err); !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM helios_keys WHERE key = $1)", e.Key).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: %q changed", store.ErrConflict, e.Key)
+		}
+		return store.ErrNotFound
+	})
+}
+
+// withKeyLock runs fn in a transaction holding the advisory lock for key.
+func (b *Backend) withKeyLock(ctx context.Context, key string, fn func(*sql.Tx) error) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, $2)", lockClass, keyLock(key)); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleted maps a DELETE that matched no rows to store.ErrNotFound.
+func deleted(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/holeyfield33-art/helios/internal/pgwire"
+	"github.com/holeyfield33-art/helios/internal/store"
+	"github.com/holeyfield33-art/helios/internal/store/storetest"
+)
+
+func TestMigrations(t *testing.T) {
+	ms := Migrations()
+	if len(ms) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range ms {
+		if m.Version != i+1 {
+			t.Errorf("migration %d (%s) has version %d; versions must be consecutive from 1", i, m.Name, m.Version)
+		}
+		if m.SQL == "" {
+			t.Errorf("migration %s is empty", m.Name)
+		}
+	}
+}
+
+func TestLikePrefix(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"":        "%",
+		"abc":     "abc%",
+		"50%_off": `50\%\_off%`,
+		`a\b`:     `a\\b%`,
+	} {
+		if got := likePrefix(prefix); got != want {
+			t.Errorf("likePrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+// TestBackend runs the conformance suite against the database named by
+// HELIOS_TEST_POSTGRES_DSN. It drops and recreates the helios tables, so
+// point it at a scratch database.
+func TestBackend(t *testing.T) {
+	dsn := os.Getenv("HELIOS_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("HELIOS_TEST_POSTGRES_DSN not set")
+	}
+	db, err := sql.Open("pgwire", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	storetest.Run(t, func(t *testing.T) store.Backend {
+		ctx := context.Background()
+		if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS helios_keys, helios_blobs, helios_schema_migrations"); err != nil {
+			t.Fatal(err)
+		}
+		b, err := Open(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Migrate(ctx, db); err != nil {
+			t.Fatalf("second Migrate: %v", err)
+		}
+		return b
+	})
+}
@@ -0,0 +1,268 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrOverQuota is matched by a *QuotaError.
+var ErrOverQuota = errors.New("store: quota exceeded")
+
+// Quota limits a namespace or a category. Zero fields are unlimited.
+type Quota struct {
+	MaxObjects int64 `json:"max_objects,omitempty"`
+	MaxBytes   int64 `json:"max_bytes,omitempty"`
+}
+
+// QuotaPolicy sets the quotas enforced by Options.Quotas. Usage counts the
+// current object of every key: overwriting a key replaces its object's
+// contribution, and objects no key points at do not count.
+type QuotaPolicy struct {
+	// Tenants limits whole namespaces by tenant id. "" is the default
+	// namespace and "*" applies to tenants without an entry of their own.
+	Tenants map[string]Quota `json:"tenants,omitempty"`
+	// Categories limits the objects of each category within a namespace.
+	// "*" applies to categories without an entry of their own.
+	Categories map[string]Quota `json:"categories,omitempty"`
+}
+
+// LoadQuotaPolicy reads a QuotaPolicy from a JSON file.
+func LoadQuotaPolicy(path string) (*QuotaPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p QuotaPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid quota policy %s: %w", path, err)
+	}
+	for name, m := range map[string]map[string]Quota{"tenant": p.Tenants, "category": p.Categories} {
+		for k, q := range m {
+			if q.MaxObjects < 0 || q.MaxBytes < 0 {
+				return nil, fmt.Errorf("invalid quota policy %s: negative limit for %s %q", path, name, k)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// TenantQuota returns the quota for the namespace of tenant, "" being the
+// default one.
+func (p *QuotaPolicy) TenantQuota(tenant string) Quota {
+	if q, ok := p.Tenants[tenant]; ok || tenant == "" {
+		return q
+	}
+	return p.Tenants["*"]
+}
+
+// CategoryQuota returns the quota for a category.
+func (p *QuotaPolicy) CategoryQuota(category string) Quota {
+	if q, ok := p.Categories[category]; ok {
+		return q
+	}
+	return p.Categories["*"]
+}
+
+// Count is a number of objects and their total stored size.
+type Count struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+func (c *Count) add(d Count) { c.Objects += d.Objects; c.Bytes += d.Bytes }
+
+// Usage is the quota usage of one namespace.
+type Usage struct {
+	Count
+	Categories map[string]Count `json:"categories"`
+}
+
+// QuotaError reports a put refused by a quota.
+type QuotaError struct {
+	Tenant string `json:"tenant"`
+	// Category is set when a category quota was exceeded, and empty when
+	// the namespace quota was.
+	Category string `json:"category,omitempty"`
+	// Limit is "objects" or "bytes".
+	Limit string `json:"limit"`
+	Max   int64  `json:"max"`
+	// Used is the usage before the put, and Requested the usage it would
+	// have led to.
+	Used      int64 `json:"used"`
+	Requested int64 `json:"requested"`
+}
+
+func (e *QuotaError) Error() string {
+	scope := "default namespace"
+	if e.Tenant != "" {
+		scope = "tenant " + e.Tenant
+	}
+	if e.Category != "" {
+		scope += ", category " + e.Category
+	}
+	return fmt.Sprintf("store: quota exceeded for %s: %d %s would exceed the limit of %d (%d in use)", scope, e.Requested, e.Limit, e.Max, e.Used)
+}
+
+// Is makes errors.Is(err, ErrOverQuota) match.
+func (e *QuotaError) Is(target error) bool { return target == ErrOverQuota }
+
+// quotaState tracks usage for a store and its tenants, so every Store
+// value for a namespace shares one lock and one running total.
+type quotaState struct {
+	mu         sync.Mutex
+	namespaces map[string]*nsUsage
+}
+
+// nsUsage is the running usage of one namespace. mu serializes the
+// check, the write, and the update of a quota-checked put.
+type nsUsage struct {
+	mu     sync.Mutex
+	loaded bool
+	usage  Usage
+}
+
+func (q *quotaState) namespace(tenant string) *nsUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.namespaces == nil {
+		q.namespaces = make(map[string]*nsUsage)
+	}
+	ns, ok := q.namespaces[tenant]
+	if !ok {
+		ns = &nsUsage{}
+		q.namespaces[tenant] = ns
+	}
+	return ns
+}
+
+// Usage computes the quota usage of s from scratch, reading the current
+// object of every key.
+func (s *Store) Usage(ctx context.Context) (Usage, error) {
+	entries, err := s.b.ListKeys(ctx, "")
+	if err != nil {
+		return Usage{}, err
+	}
+	u := Usage{Categories: map[string]Count{}}
+	for _, e := range entries {
+		c, category, err := s.objectCount(ctx, e.Hash)
+		if err != nil {
+			return Usage{}, fmt.Errorf("key %q: %w", e.Key, err)
+		}
+		u.add(c)
+		cc := u.Categories[category]
+		cc.add(c)
+		u.Categories[category] = cc
+	}
+	return u, nil
+}
+
+// objectCount returns the usage of the stored object h and its category.
+func (s *Store) objectCount(ctx context.Context, h string) (Count, string, error) {
+	data, err := s.b.Get(ctx, h)
+	if err != nil {
+		return Count{}, "", err
+	}
+	var head struct {
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal(canonicalOf(data), &head); err != nil {
+		return Count{}, "", fmt.Errorf("stored object %s: %w", h, err)
+	}
+	return Count{Objects: 1, Bytes: int64(len(data))}, head.Category, nil
+}
+
+// withQuota runs write, which points key at an object of the given
+// category and stored size, if the namespace stays within its quotas,
+// and then updates the running usage. Without a policy it just runs
+// write.
+func (s *Store) withQuota(ctx context.Context, key, category string, size int64, write func() error) error {
+	p := s.opts.Quotas
+	if p == nil {
+		return write()
+	}
+	ns := s.quota.namespace(s.tenantID)
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if !ns.loaded {
+		u, err := s.Usage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to compute quota usage: %w", err)
+		}
+		ns.usage, ns.loaded = u, true
+	}
+
+	// The put replaces the key's current object, if any.
+	var old Count
+	var oldCategory string
+	if e, err := s.b.ResolveKey(ctx, key); err == nil {
+		if old, oldCategory, err = s.objectCount(ctx, e.Hash); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	next := ns.usage.Count
+	next.add(Count{Objects: 1 - old.Objects, Bytes: size - old.Bytes})
+	nextCat := ns.usage.Categories[category]
+	if oldCategory == category {
+		nextCat.add(Count{Objects: -old.Objects, Bytes: -old.Bytes})
+	}
+	nextCat.add(Count{Objects: 1, Bytes: size})
+
+	if err := checkQuota(p.TenantQuota(s.tenantID), ns.usage.Count, next, s.tenantID, ""); err != nil {
+		return err
+	}
+	if err := checkQuota(p.CategoryQuota(category), ns.usage.Categories[category], nextCat, s.tenantID, category); err != nil {
+		return err
+	}
+
+	if err := write(); err != nil {
+		return err
+	}
+	ns.usage.Count = next
+	if old.Objects > 0 && oldCategory != category {
+		oc := ns.usage.Categories[oldCategory]
+		oc.add(Count{Objects: -old.Objects, Bytes: -old.Bytes})
+		ns.usage.Categories[oldCategory] = oc
+	}
+	ns.usage.Categories[category] = nextCat
+	return nil
+}
+
+// checkQuota fails if next exceeds q where it grew from used.
+func checkQuota(q Quota, used, next Count, tenant, category string) error {
+	if q.MaxObjects > 0 && next.Objects > q.MaxObjects && next.Objects > used.Objects {
+		return &QuotaError{Tenant: tenant, Category: category, Limit: "objects", Max: q.MaxObjects, Used: used.Objects, Requested: next.Objects}
+	}
+	if q.MaxBytes > 0 && next.Bytes > q.MaxBytes && next.Bytes > used.Bytes {
+		return &QuotaError{Tenant: tenant, Category: category, Limit: "bytes", Max: q.MaxBytes, Used: used.Bytes, Requested: next.Bytes}
+	}
+	return nil
+}
+
+// deleteWithQuota deletes key and takes its object out of the running
+// usage.
+func (s *Store) deleteWithQuota(ctx context.Context, key string) error {
+	ns := s.quota.namespace(s.tenantID)
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	e, err := s.deleteUnheld(ctx, key)
+	if err != nil || !ns.loaded {
+		return err
+	}
+	c, category, err := s.objectCount(ctx, e.Hash)
+	if err != nil {
+		// The key is gone; recount the namespace on its next put.
+		ns.loaded = false
+		return nil
+	}
+	ns.usage.add(Count{Objects: -c.Objects, Bytes: -c.Bytes})
+	cc := ns.usage.Categories[category]
+	cc.add(Count{Objects: -c.Objects, Bytes: -c.Bytes})
+	ns.usage.Categories[category] = cc
+	return nil
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+func TestQuotaObjects(t *testing.T) {
+	ctx := context.Background()
+	s := NewWithOptions(NewMemory(), Options{Quotas: &QuotaPolicy{Tenants: map[string]Quota{"": {MaxObjects: 2}}}})
+
+	for _, k := range []string{"a", "b"} {
+		if _, err := s.Put(ctx, testObject(k, "v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Put(ctx, testObject("a", "overwritten")); err != nil {
+		t.Errorf("overwrite at the limit: %v", err)
+	}
+	_, err := s.Put(ctx, testObject("c", "v"))
+	var qe *QuotaError
+	if !errors.As(err, &qe) || !errors.Is(err, ErrOverQuota) {
+		t.Fatalf("third object: %v", err)
+	}
+	if qe.Limit != "objects" || qe.Max != 2 || qe.Used != 2 || qe.Requested != 3 || qe.Category != "" {
+		t.Errorf("QuotaError = %+v", qe)
+	}
+	if _, err := s.Resolve(ctx, "c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("refused put wrote its key: %v", err)
+	}
+
+	if err := s.Delete(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(ctx, testObject("c", "v")); err != nil {
+		t.Errorf("put after a delete freed quota: %v", err)
+	}
+	u, err := s.Usage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Objects != 2 || u.Categories["project"].Objects != 2 {
+		t.Errorf("Usage() = %+v", u)
+	}
+}
+
+func TestQuotaBytesAndCategories(t *testing.T) {
+	ctx := context.Background()
+	obj := testObject("a", "v")
+	canonical, err := hash.CanonicalBytes(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(canonical))
+	s := NewWithOptions(NewMemory(), Options{Quotas: &QuotaPolicy{
+		Categories: map[string]Quota{"project": {MaxBytes: 2 * size}, "*": {MaxObjects: 1}},
+	}})
+
+	if _, err := s.Put(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(ctx, testObject("b", "v")); err != nil {
+		t.Fatal(err)
+	}
+	var qe *QuotaError
+	if _, err := s.Put(ctx, testObject("c", "v")); !errors.As(err, &qe) || qe.Limit != "bytes" || qe.Category != "project" {
+		t.Errorf("third project object: %v", err)
+	}
+
+	other := testObject("d", "v")
+	other.Category = "fact"
+	if _, err := s.Put(ctx, other); err != nil {
+		t.Errorf("first fact: %v", err)
+	}
+	other.Key = "e"
+	if _, err := s.Put(ctx, other); !errors.As(err, &qe) || qe.Limit != "objects" || qe.Category != "fact" {
+		t.Errorf("second fact under the \"*\" quota: %v", err)
+	}
+
+	// Moving a key to another category frees its old category's quota.
+	moved := testObject("a", "v")
+	moved.Category = "decision"
+	if _, err := s.Put(ctx, moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(ctx, testObject("c", "v")); err != nil {
+		t.Errorf("project object after a key moved out: %v", err)
+	}
+}
+
+func TestQuotaTenantFallback(t *testing.T) {
+	ctx := context.Background()
+	s := NewWithOptions(NewMemory(), Options{Quotas: &QuotaPolicy{Tenants: map[string]Quota{
+		"*":    {MaxObjects: 1},
+		"acme": {MaxObjects: 2},
+	}}})
+	for id, limit := range map[string]int{"acme": 2, "globex": 1} {
+		ts, err := s.Tenant(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < limit; i++ {
+			if _, err := ts.Put(ctx, testObject(string(rune('a'+i)), "v")); err != nil {
+				t.Fatalf("%s object %d: %v", id, i, err)
+			}
+		}
+		var qe *QuotaError
+		if _, err := ts.Put(ctx, testObject("z", "v")); !errors.As(err, &qe) || qe.Tenant != id {
+			t.Errorf("%s over its quota: %v", id, err)
+		}
+	}
+	// The default namespace has no entry, so it is unlimited.
+	for _, k := range []string{"a", "b", "c"} {
+		if _, err := s.Put(ctx, testObject(k, "v")); err != nil {
+			t.Errorf("default namespace: %v", err)
+		}
+	}
+}
+
+func TestGatewayQuota(t *testing.T) {
+	s := NewWithOptions(NewMemory(), Options{Quotas: &QuotaPolicy{Tenants: map[string]Quota{"": {MaxObjects: 1}}}})
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true}))
+	defer srv.Close()
+
+	if resp, body := putPath(t, srv, "/keys/a", objectJSON("a", "v")); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("first PUT: %d %s", resp.StatusCode, body)
+	}
+	resp, body := putPath(t, srv, "/keys/b", objectJSON("b", "v"))
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(body, `"code":"STORE_ERR_QUOTA_EXCEEDED"`) || !strings.Contains(body, `"limit":"objects"`) {
+		t.Errorf("PUT over quota: %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestMaxCanonicalSize(t *testing.T) {
+	ctx := context.Background()
+	small := testObject("a", "v")
+	n, err := hash.CanonicalSize(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewWithOptions(NewMemory(), Options{MaxCanonicalSize: n})
+	if _, err := s.Put(ctx, small); err != nil {
+		t.Fatalf("Put at the limit: %v", err)
+	}
+	_, err = s.Put(ctx, testObject("b", strings.Repeat("v", 100)))
+	var ce *canon.Error
+	if !errors.As(err, &ce) || ce.Code != canon.ErrCodeTooLarge {
+		t.Fatalf("Put over the limit = %v", err)
+	}
+	if _, err := s.Resolve(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("an object over the limit was stored: %v", err)
+	}
+
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true}))
+	defer srv.Close()
+	resp, body := putPath(t, srv, "/keys/c", objectJSON("c", strings.Repeat("v", 100)))
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || !strings.Contains(body, `"code":"CANON_ERR_TOO_LARGE"`) {
+		t.Errorf("PUT over the canonical limit: %d %s", resp.StatusCode, body)
+	}
+
+	srv = httptest.NewServer(NewGateway(NewWithOptions(NewMemory(), Options{}), GatewayOptions{Writable: true, MaxBodyBytes: 64}))
+	defer srv.Close()
+	resp, body = putPath(t, srv, "/keys/c", objectJSON("c", "v"))
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || !strings.Contains(body, `"code":"STORE_ERR_TOO_LARGE"`) {
+		t.Errorf("PUT over the body limit: %d %s", resp.StatusCode, body)
+	}
+}
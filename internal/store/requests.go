@@ -0,0 +1,105 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRequestTTL is how long PutRequest remembers a request id
+	// when Options.RequestTTL is unset.
+	DefaultRequestTTL = 24 * time.Hour
+	// DefaultMaxRequests is how many request ids PutRequest remembers per
+	// namespace when Options.MaxRequests is unset.
+	DefaultMaxRequests = 10000
+)
+
+// requestLog remembers the writes PutRequest applied for a store and its
+// tenants, so a retry is recognized even after another request has
+// rewritten its key, or when it names another key. Each namespace keeps
+// at most maxRequests ids, each for ttl; like quota usage, the record is
+// kept in memory, so it is exact only while this process is the only
+// writer, and a restart falls back to the request id in each key's index
+// entry.
+type requestLog struct {
+	mu         sync.Mutex
+	namespaces map[string]*nsRequests
+}
+
+// nsRequests is the request record of one namespace, oldest first.
+type nsRequests struct {
+	ids   map[string]*list.Element
+	order *list.List
+}
+
+// appliedRequest is one remembered write.
+type appliedRequest struct {
+	entry KeyEntry
+	at    time.Time
+}
+
+func (o Options) requestTTL() time.Duration {
+	if o.RequestTTL > 0 {
+		return o.RequestTTL
+	}
+	return DefaultRequestTTL
+}
+
+func (o Options) maxRequests() int {
+	if o.MaxRequests > 0 {
+		return o.MaxRequests
+	}
+	return DefaultMaxRequests
+}
+
+// lookup returns the entry written by request id in tenant, if it is
+// still remembered at now.
+func (l *requestLog) lookup(tenant, id string, now time.Time, ttl time.Duration) (KeyEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ns := l.namespaces[tenant]
+	if ns == nil {
+		return KeyEntry{}, false
+	}
+	ns.expire(now, ttl)
+	el, ok := ns.ids[id]
+	if !ok {
+		return KeyEntry{}, false
+	}
+	return el.Value.(*appliedRequest).entry, true
+}
+
+// record remembers that e.RequestID wrote e in tenant at now, forgetting
+// the oldest requests beyond limit.
+func (l *requestLog) record(tenant string, e KeyEntry, now time.Time, ttl time.Duration, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.namespaces == nil {
+		l.namespaces = make(map[string]*nsRequests)
+	}
+	ns := l.namespaces[tenant]
+	if ns == nil {
+		ns = &nsRequests{ids: make(map[string]*list.Element), order: list.New()}
+		l.namespaces[tenant] = ns
+	}
+	if el, ok := ns.ids[e.RequestID]; ok {
+		ns.order.Remove(el)
+	}
+	ns.ids[e.RequestID] = ns.order.PushBack(&appliedRequest{entry: e, at: now})
+	for ns.order.Len() > limit {
+		ns.forget(ns.order.Front())
+	}
+	ns.expire(now, ttl)
+}
+
+// expire forgets the requests older than ttl at now.
+func (ns *nsRequests) expire(now time.Time, ttl time.Duration) {
+	for el := ns.order.Front(); el != nil && now.Sub(el.Value.(*appliedRequest).at) >= ttl; el = ns.order.Front() {
+		ns.forget(el)
+	}
+}
+
+func (ns *nsRequests) forget(el *list.Element) {
+	delete(ns.ids, ns.order.Remove(el).(*appliedRequest).entry.RequestID)
+}
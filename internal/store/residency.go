@@ -0,0 +1,66 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors for data residency.
+var (
+	ErrInvalidResidency = errors.New("store: residency regions are 1-63 characters of a-z, 0-9 and '-', starting with a letter")
+	// ErrResidency is returned when an object's residency names a region
+	// the store is not configured to hold.
+	ErrResidency = errors.New("store: object's residency region is not held by this store")
+)
+
+// ValidResidency reports whether r is a well-formed residency region, such
+// as "eu" or "us-east".
+func ValidResidency(r string) bool {
+	if r == "" || len(r) > 63 || r[0] < 'a' || r[0] > 'z' {
+		return false
+	}
+	for i := 0; i < len(r); i++ {
+		if c := r[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseRegions parses a comma-separated list of residency regions, as
+// Options.Regions takes them. An empty string is no regions.
+func ParseRegions(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var regions []string
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if !ValidResidency(r) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidResidency, r)
+		}
+		regions = append(regions, r)
+	}
+	return regions, nil
+}
+
+// checkResidency rejects an object whose residency names a region not in
+// Options.Regions. An object without a residency may be stored anywhere.
+func (s *Store) checkResidency(residency string) error {
+	if residency == "" {
+		return nil
+	}
+	if !ValidResidency(residency) {
+		return fmt.Errorf("%w: %q", ErrInvalidResidency, residency)
+	}
+	for _, r := range s.opts.Regions {
+		if r == residency {
+			return nil
+		}
+	}
+	if len(s.opts.Regions) == 0 {
+		return fmt.Errorf("%w: object must reside in %q, and this store holds no residency regions", ErrResidency, residency)
+	}
+	return fmt.Errorf("%w: object must reside in %q, and this store holds %s", ErrResidency, residency, strings.Join(s.opts.Regions, ", "))
+}
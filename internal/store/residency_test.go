@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRegions(t *testing.T) {
+	if got, err := ParseRegions("eu, us-east"); err != nil || strings.Join(got, ",") != "eu,us-east" {
+		t.Errorf("ParseRegions = %q, %v", got, err)
+	}
+	if got, err := ParseRegions(""); err != nil || got != nil {
+		t.Errorf("ParseRegions(\"\") = %q, %v", got, err)
+	}
+	for _, bad := range []string{"EU", "eu,", "1eu", "eu_west"} {
+		if _, err := ParseRegions(bad); !errors.Is(err, ErrInvalidResidency) {
+			t.Errorf("ParseRegions(%q): got %v", bad, err)
+		}
+	}
+}
+
+func TestResidency(t *testing.T) {
+	ctx := context.Background()
+	eu := NewWithOptions(NewMemory(), Options{Regions: []string{"eu"}})
+	plain := New(NewMemory())
+
+	obj := testObject("patients/1", "v")
+	obj.Residency = "eu"
+	if _, err := eu.Put(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := eu.Resolve(ctx, "patients/1"); err != nil || e.Residency != "eu" {
+		t.Errorf("entry = %+v, %v", e, err)
+	}
+	if _, err := plain.Put(ctx, obj); !errors.Is(err, ErrResidency) {
+		t.Errorf("store without regions: got %v", err)
+	}
+	obj.Residency = "us"
+	if _, err := eu.Put(ctx, obj); !errors.Is(err, ErrResidency) {
+		t.Errorf("store for another region: got %v", err)
+	}
+	obj.Residency = "E U"
+	if _, err := eu.Put(ctx, obj); !errors.Is(err, ErrInvalidResidency) {
+		t.Errorf("malformed residency: got %v", err)
+	}
+
+	// Untagged objects are stored anywhere, and tenants share the
+	// store's regions.
+	if _, err := eu.Put(ctx, testObject("notes/a", "v")); err != nil {
+		t.Errorf("untagged object: %v", err)
+	}
+	acme, err := plain.Tenant(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj.Residency = "eu"
+	if _, err := acme.Put(ctx, obj); !errors.Is(err, ErrResidency) {
+		t.Errorf("tenant of a store without regions: got %v", err)
+	}
+}
+
+func TestGatewayResidency(t *testing.T) {
+	s := NewWithOptions(NewMemory(), Options{Regions: []string{"eu"}})
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true}))
+	defer srv.Close()
+
+	tagged := func(residency string) string {
+		return strings.Replace(objectJSON("notes/a", "v"), "{", `{"residency":"`+residency+`",`, 1)
+	}
+	if resp, body := put(t, srv, "notes/a", tagged("eu"), nil); resp.StatusCode != http.StatusCreated {
+		t.Errorf("eu object: %d %s", resp.StatusCode, body)
+	}
+	if resp, body := put(t, srv, "notes/a", tagged("us"), nil); resp.StatusCode != http.StatusForbidden || !strings.Contains(body, "STORE_ERR_RESIDENCY") {
+		t.Errorf("us object: %d %s", resp.StatusCode, body)
+	}
+	if resp, body := put(t, srv, "notes/a", tagged("U S"), nil); resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "STORE_ERR_INVALID_RESIDENCY") {
+		t.Errorf("malformed residency: %d %s", resp.StatusCode, body)
+	}
+}
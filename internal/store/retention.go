@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention actions.
+const (
+	// RetainPruneVersion deletes a superseded version of a key.
+	RetainPruneVersion = "prune_version"
+	// RetainExpireKey deletes a key whose current object is older than
+	// its rule's max age, along with that object.
+	RetainExpireKey = "expire_key"
+)
+
+// RetentionPolicy declares how long objects are kept, in terms of the key
+// versions History lists.
+type RetentionPolicy struct {
+	// Categories holds the rule for each category, chosen by the
+	// category of a key's newest version. "*" applies to categories
+	// without a rule of their own; with neither, nothing is deleted.
+	Categories map[string]RetentionRule `json:"categories"`
+	// LegalHold lists key prefixes that are never deleted, whatever
+	// their rule says.
+	LegalHold []string `json:"legal_hold,omitempty"`
+}
+
+// RetentionRule limits the versions kept for each key of a category. Zero
+// fields keep everything.
+type RetentionRule struct {
+	// KeepVersions keeps the newest N versions of each key, the current
+	// one always among them.
+	KeepVersions int `json:"keep_versions,omitempty"`
+	// MaxAge deletes versions whose created_at is older than this. An
+	// expired current version expires its key too.
+	MaxAge Age `json:"max_age,omitempty"`
+	// LegalHold keeps every version of the category.
+	LegalHold bool `json:"legal_hold,omitempty"`
+}
+
+// Age is a duration written as a Go duration ("36h") or a number of days
+// ("30d").
+type Age time.Duration
+
+func (a *Age) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("max_age must be a string like \"30d\" or \"12h\"")
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_age %q", s)
+		}
+		*a = Age(time.Duration(n) * 24 * time.Hour)
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return fmt.Errorf("invalid max_age %q", s)
+	}
+	*a = Age(d)
+	return nil
+}
+
+func (a Age) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(a).String())
+}
+
+// LoadRetentionPolicy reads a RetentionPolicy from a JSON file.
+func LoadRetentionPolicy(path string) (*RetentionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p RetentionPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid retention policy %s: %w", path, err)
+	}
+	for c, r := range p.Categories {
+		if r.KeepVersions < 0 {
+			return nil, fmt.Errorf("invalid retention policy %s: negative keep_versions for category %q", path, c)
+		}
+	}
+	return &p, nil
+}
+
+// rule returns the rule for category and whether there is one.
+func (p *RetentionPolicy) rule(category string) (RetentionRule, bool) {
+	if r, ok := p.Categories[category]; ok {
+		return r, true
+	}
+	r, ok := p.Categories["*"]
+	return r, ok
+}
+
+// held reports whether key is under a legal hold prefix.
+func (p *RetentionPolicy) held(key string) bool {
+	for _, prefix := range p.LegalHold {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetentionAction is one deletion planned by PlanRetention.
+type RetentionAction struct {
+	Action    string `json:"action"`
+	Key       string `json:"key"`
+	Hash      string `json:"hash"`
+	Category  string `json:"category"`
+	CreatedAt string `json:"created_at"`
+	Reason    string `json:"reason"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// RetentionPlan is the result of PlanRetention.
+type RetentionPlan struct {
+	Keys     int               `json:"keys"`
+	Versions int               `json:"versions"`
+	Held     int               `json:"held"`
+	Actions  []RetentionAction `json:"actions"`
+}
+
+// PlanRetention evaluates p against every stored object as of now and
+// returns the deletions it calls for, without making them. Actions are
+// sorted by key, newest version first.
+func (s *Store) PlanRetention(ctx context.Context, p *RetentionPolicy, now time.Time) (*RetentionPlan, error) {
+	byKey, n, err := s.versions(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.b.ListKeys(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]string, len(entries))
+	onHold := make(map[string]bool)
+	for _, e := range entries {
+		current[e.Key] = e.Hash
+		if e.LegalHold != "" {
+			onHold[e.Key] = true
+		}
+	}
+
+	plan := &RetentionPlan{Keys: len(byKey), Versions: n, Actions: []RetentionAction{}}
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		vs := byKey[key]
+		cur := current[key]
+		// Newest first; the current version leads whatever its created_at.
+		sort.Slice(vs, func(i, j int) bool {
+			if (vs[i].hash == cur) != (vs[j].hash == cur) {
+				return vs[i].hash == cur
+			}
+			if !vs[i].created.Equal(vs[j].created) {
+				return vs[i].created.After(vs[j].created)
+			}
+			return vs[i].hash < vs[j].hash
+		})
+		if onHold[key] {
+			plan.Held += len(vs)
+			continue
+		}
+		r, ok := p.rule(vs[0].category)
+		if !ok {
+			continue
+		}
+		if r.LegalHold || p.held(key) {
+			plan.Held += len(vs)
+			continue
+		}
+		for i, v := range vs {
+			var reason string
+			switch {
+			case r.MaxAge > 0 && !v.created.IsZero() && now.Sub(v.created) > time.Duration(r.MaxAge):
+				reason = fmt.Sprintf("older than %s", time.Duration(r.MaxAge))
+			case r.KeepVersions > 0 && i >= r.KeepVersions:
+				reason = fmt.Sprintf("beyond the newest %d versions", r.KeepVersions)
+			default:
+				continue
+			}
+			action := RetainPruneVersion
+			if v.hash == cur {
+				action = RetainExpireKey
+			}
+			plan.Actions = append(plan.Actions, RetentionAction{
+				Action: action, Key: key, Hash: v.hash, Category: v.category,
+				CreatedAt: v.createdAt, Reason: reason, Bytes: v.size,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// ApplyRetention carries out plan. A key is only expired if it still
+// points at the planned object, so a key rewritten since planning keeps
+// its new object; that object's old version is still deleted. Keys put
+// under legal hold since planning are left alone. It returns the actions
+// taken.
+func (s *Store) ApplyRetention(ctx context.Context, plan *RetentionPlan) ([]RetentionAction, error) {
+	done := make([]RetentionAction, 0, len(plan.Actions))
+	for _, a := range plan.Actions {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		e, err := s.b.ResolveKey(ctx, a.Key)
+		if err == nil && e.LegalHold != "" {
+			continue
+		}
+		if a.Action == RetainExpireKey {
+			switch {
+			case err == nil && e.Hash == a.Hash:
+				if err := s.Delete(ctx, a.Key); err != nil && !errors.Is(err, ErrNotFound) {
+					return done, fmt.Errorf("failed to expire key %q: %w", a.Key, err)
+				}
+			case err != nil && !errors.Is(err, ErrNotFound):
+				return done, err
+			}
+		}
+		if e, err := s.b.ResolveKey(ctx, a.Key); err == nil && e.Hash == a.Hash {
+			// Rewritten back to this version since planning.
+			continue
+		}
+		if err := s.b.Delete(ctx, a.Hash); err != nil && !errors.Is(err, ErrNotFound) {
+			return done, fmt.Errorf("failed to delete %s: %w", a.Hash, err)
+		}
+		done = append(done, a)
+	}
+	return done, nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemory())
+	put := func(key, category, value, created string) string {
+		t.Helper()
+		obj := testObject(key, value)
+		obj.Category, obj.CreatedAt = category, created
+		h, err := s.Put(ctx, obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	v1 := put("notes/a", "project", "v1", "2025-01-01T00:00:00.000Z")
+	v2 := put("notes/a", "project", "v2", "2025-01-02T00:00:00.000Z")
+	v3 := put("notes/a", "project", "v3", "2025-01-03T00:00:00.000Z")
+	stale := put("facts/old", "fact", "v", "2024-01-01T00:00:00.000Z")
+	fresh := put("facts/new", "fact", "v", "2025-01-30T00:00:00.000Z")
+	held := put("case-7/fact", "fact", "v", "2024-01-01T00:00:00.000Z")
+	other := put("misc", "decision", "v", "2020-01-01T00:00:00.000Z")
+
+	p := &RetentionPolicy{
+		Categories: map[string]RetentionRule{
+			"project": {KeepVersions: 2},
+			"fact":    {MaxAge: Age(30 * 24 * time.Hour)},
+		},
+		LegalHold: []string{"case-7/"},
+	}
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	plan, err := s.PlanRetention(ctx, p, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Actions) != 2 ||
+		plan.Actions[0].Action != RetainExpireKey || plan.Actions[0].Hash != stale ||
+		plan.Actions[1].Action != RetainPruneVersion || plan.Actions[1].Hash != v1 {
+		t.Fatalf("plan actions = %+v", plan.Actions)
+	}
+	if plan.Keys != 5 || plan.Versions != 7 || plan.Held != 1 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if ok, _ := s.Has(ctx, v1); !ok {
+		t.Error("planning deleted an object")
+	}
+
+	done, err := s.ApplyRetention(ctx, plan)
+	if err != nil || len(done) != 2 {
+		t.Fatalf("ApplyRetention: %v, %v", done, err)
+	}
+	for h, want := range map[string]bool{v1: false, v2: true, v3: true, stale: false, fresh: true, held: true, other: true} {
+		if ok, _ := s.Has(ctx, h); ok != want {
+			t.Errorf("Has(%s) = %v after apply, want %v", h[:8], ok, want)
+		}
+	}
+	if _, err := s.Resolve(ctx, "facts/old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired key still resolves: %v", err)
+	}
+	if e, err := s.Resolve(ctx, "notes/a"); err != nil || e.Hash != v3 {
+		t.Errorf("notes/a after apply: %+v, %v", e, err)
+	}
+
+	if plan, err := s.PlanRetention(ctx, p, now); err != nil || len(plan.Actions) != 0 {
+		t.Errorf("second plan: %+v, %v", plan, err)
+	}
+}
+
+func TestRetentionKeepsRewrittenKey(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemory())
+	old := testObject("k", "old")
+	old.CreatedAt = "2020-01-01T00:00:00.000Z"
+	h, err := s.Put(ctx, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &RetentionPolicy{Categories: map[string]RetentionRule{"*": {MaxAge: Age(time.Hour)}}}
+	plan, err := s.PlanRetention(ctx, p, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(plan.Actions) != 1 {
+		t.Fatalf("plan: %+v, %v", plan, err)
+	}
+
+	nh, err := s.Put(ctx, testObject("k", "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ApplyRetention(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := s.Resolve(ctx, "k"); err != nil || e.Hash != nh {
+		t.Errorf("rewritten key after apply: %+v, %v", e, err)
+	}
+	if ok, _ := s.Has(ctx, h); ok {
+		t.Error("the superseded version was kept")
+	}
+}
+
+func TestLoadRetentionPolicy(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct {
+		json string
+		ok   bool
+	}{
+		"days":     {`{"categories":{"fact":{"max_age":"30d","keep_versions":3}}}`, true},
+		"duration": {`{"categories":{"*":{"max_age":"36h"}},"legal_hold":["case-"]}`, true},
+		"number":   {`{"categories":{"fact":{"max_age":30}}}`, false},
+		"negative": {`{"categories":{"fact":{"keep_versions":-1}}}`, false},
+		"bad-days": {`{"categories":{"fact":{"max_age":"xd"}}}`, false},
+	} {
+		path := filepath.Join(dir, name+".json")
+		os.WriteFile(path, []byte(tc.json), 0o644)
+		p, err := LoadRetentionPolicy(path)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: %v", name, err)
+		}
+		if name == "days" && err == nil {
+			if r := p.Categories["fact"]; time.Duration(r.MaxAge) != 30*24*time.Hour || r.KeepVersions != 3 {
+				t.Errorf("days: %+v", r)
+			}
+			if out, _ := json.Marshal(p.Categories["fact"].MaxAge); string(out) != `"720h0m0s"` {
+				t.Errorf("MaxAge marshals as %s", out)
+			}
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package store
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Route documents one route of the gateway. NewGateway registers its
+// handlers from the same table Routes returns, so the documentation
+// cannot list a route the gateway does not serve, or miss one it does.
+type Route struct {
+	Method string
+	// Pattern is the path of the ServeMux pattern, such as
+	// /keys/{key...}; a trailing "..." wildcard matches slashes.
+	Pattern string
+	// ID names the operation, unique among the routes.
+	ID      string
+	Summary string
+	Params  []Param
+	// Body names the schema of the JSON request body, "" for none.
+	Body      string
+	Responses []Response
+}
+
+// Param is a path, query, or header parameter of a route.
+type Param struct {
+	Name string
+	// In is "path", "query", or "header".
+	In          string
+	Description string
+	// Type is the JSON Schema type of the value.
+	Type     string
+	Required bool
+}
+
+// Response is one possible response of a route. A status may be listed
+// more than once with different content types.
+type Response struct {
+	Status      int
+	Description string
+	// Schema names the schema of a JSON body, "" for none.
+	Schema string
+	// ContentType is the media type of a body that is not described by a
+	// schema, such as text/event-stream.
+	ContentType string
+}
+
+// Path returns the route's path in OpenAPI form, without the "..." of a
+// wildcard that matches slashes.
+func (r Route) Path() string {
+	return strings.ReplaceAll(r.Pattern, "...}", "}")
+}
+
+// route is a documented route and its handler.
+type route struct {
+	Route
+	handler http.HandlerFunc
+}
+
+// Routes returns the routes NewGateway serves with opts.
+func Routes(opts GatewayOptions) []Route {
+	var out []Route
+	for _, rt := range (&gateway{}).routes(opts) {
+		out = append(out, rt.Route)
+	}
+	return out
+}
+
+func errorResponse(status int, desc string) Response {
+	return Response{Status: status, Description: desc, Schema: "error"}
+}
+
+var (
+	keyParam     = Param{Name: "key", In: "path", Type: "string", Required: true, Description: "the key; may contain slashes"}
+	tenantParam  = Param{Name: "tenant", In: "path", Type: "string", Required: true, Description: "the tenant whose store the route addresses"}
+	objectBodies = []Response{
+		{Status: http.StatusOK, Description: "the object's canonical bytes; the content hash is the ETag", Schema: "memory-object"},
+		{Status: http.StatusPartialContent, Description: "the requested range of the canonical bytes", ContentType: "application/json"},
+		{Status: http.StatusNotModified, Description: "the object matches If-None-Match"},
+	}
+)
+
+// routes returns the gateway's routes for opts: the namespace routes,
+// once more under /tenants/{tenant} with Tenants, then the global ones.
+func (g *gateway) routes(opts GatewayOptions) []route {
+	var out []route
+	prefixes := []string{""}
+	if opts.Tenants {
+		prefixes = append(prefixes, "/tenants/{tenant}")
+	}
+	for _, p := range prefixes {
+		add := func(rt Route, create bool, fn func(http.ResponseWriter, *http.Request, scope)) {
+			rt.Pattern = p + rt.Pattern
+			if p != "" {
+				rt.ID = "tenant" + strings.ToUpper(rt.ID[:1]) + rt.ID[1:]
+				rt.Params = append([]Param{tenantParam}, rt.Params...)
+				rt.Responses = append(rt.Responses, errorResponse(http.StatusNotFound, "no such tenant"))
+			}
+			out = append(out, route{rt, g.scoped(create, fn)})
+		}
+		add(Route{
+			Method: http.MethodGet, Pattern: "/objects/{hash}", ID: "getObject",
+			Summary: "Read the object with a content hash",
+			Params:  []Param{{Name: "hash", In: "path", Type: "string", Required: true, Description: "a content hash, or a unique prefix of one"}},
+			Responses: append(append([]Response(nil), objectBodies...),
+				Response{Status: http.StatusFound, Description: "a unique abbreviation; Location has the full hash"},
+				errorResponse(http.StatusBadRequest, "an invalid or ambiguous hash"),
+				errorResponse(http.StatusNotFound, "no such object")),
+		}, false, g.object)
+		add(Route{
+			Method: http.MethodGet, Pattern: "/keys", ID: "listKeys",
+			Summary: "List a page of the key index",
+			Params: []Param{
+				{Name: "prefix", In: "query", Type: "string", Description: "only keys beginning with this prefix"},
+				{Name: "category", In: "query", Type: "string", Description: "only keys whose object has this category"},
+				{Name: "limit", In: "query", Type: "integer", Description: "keys per page"},
+				{Name: "cursor", In: "query", Type: "string", Description: "next_cursor of the previous page"},
+			},
+			Responses: []Response{
+				{Status: http.StatusOK, Description: "a page of keys", Schema: "key-page"},
+				errorResponse(http.StatusBadRequest, "an invalid limit or cursor"),
+			},
+		}, false, g.list)
+		add(Route{
+			Method: http.MethodGet, Pattern: "/keys/{key...}", ID: "getKey",
+			Summary: "Read the object a key holds, now or in the past",
+			Params: []Param{
+				keyParam,
+				{Name: "as_of", In: "query", Type: "string", Description: "read the version current at this RFC 3339 time"},
+				{Name: "version", In: "query", Type: "integer", Description: "read this version, counting from 1"},
+			},
+			Responses: append(append([]Response(nil), objectBodies...),
+				errorResponse(http.StatusBadRequest, "an invalid as_of or version"),
+				errorResponse(http.StatusNotFound, "no such key or version")),
+		}, false, g.key)
+		if opts.Writable {
+			add(Route{
+				Method: http.MethodPut, Pattern: "/keys/{key...}", ID: "putKey",
+				Summary: "Store a memory object under a key",
+				Params: []Param{
+					keyParam,
+					{Name: "If-Match", In: "header", Type: "string", Description: "write only if the key holds one of these hashes"},
+					{Name: "If-None-Match", In: "header", Type: "string", Description: "* to write only if the key does not exist"},
+					{Name: "Idempotency-Key", In: "header", Type: "string", Description: "a unique id for the write; a retry with the same id is answered without writing again"},
+				},
+				Body: "memory-object",
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "the key was updated, or already holds the object from this Idempotency-Key (Idempotent-Replayed: true)", Schema: "put-response"},
+					{Status: http.StatusCreated, Description: "the key was created", Schema: "put-response"},
+					errorResponse(http.StatusBadRequest, "an invalid object, or one whose key or tenant does not match the URL"),
+					{Status: http.StatusForbidden, Description: "the write would exceed a quota", Schema: "quota-error"},
+					errorResponse(http.StatusPreconditionFailed, "the key does not hold the expected hash"),
+					errorResponse(http.StatusRequestEntityTooLarge, "the body is too large"),
+					errorResponse(http.StatusUnprocessableEntity, "the Idempotency-Key was used for a different object, or the object breaks the write rules"),
+				},
+			}, true, g.put)
+			add(Route{
+				Method: http.MethodPut, Pattern: "/holds/{key...}", ID: "holdKey",
+				Summary: "Put a key under legal hold",
+				Params:  []Param{keyParam},
+				Body:    "hold-request",
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "the key is held; the audit record of the hold", Schema: "hold-event"},
+					errorResponse(http.StatusBadRequest, "a missing or invalid reason"),
+					errorResponse(http.StatusNotFound, "no such key"),
+				},
+			}, true, g.hold)
+			add(Route{
+				Method: http.MethodDelete, Pattern: "/holds/{key...}", ID: "releaseKey",
+				Summary: "Release a key's legal hold",
+				Params:  []Param{keyParam},
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "the hold is released; the audit record of the release", Schema: "hold-event"},
+					errorResponse(http.StatusForbidden, "the client may not administer the server"),
+					errorResponse(http.StatusNotFound, "no such key, or the key is not held"),
+				},
+			}, true, g.release)
+		}
+		add(Route{
+			Method: http.MethodGet, Pattern: "/holds", ID: "listHolds",
+			Summary: "List the keys under legal hold",
+			Responses: []Response{
+				{Status: http.StatusOK, Description: "the held keys", Schema: "holds"},
+			},
+		}, false, g.holds)
+		if opts.Similar != nil {
+			add(Route{
+				Method: http.MethodGet, Pattern: "/similar", ID: "similar",
+				Summary: "Find the keys with values nearest a text",
+				Params: []Param{
+					{Name: "q", In: "query", Type: "string", Required: true, Description: "the query text"},
+					{Name: "k", In: "query", Type: "integer", Description: "the number of matches, at most 1000"},
+				},
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "the matches, closest first", Schema: "similar"},
+					errorResponse(http.StatusBadRequest, "a missing q or invalid k"),
+				},
+			}, false, g.similar)
+		}
+		if opts.Changes != nil {
+			add(Route{
+				Method: http.MethodGet, Pattern: "/changes", ID: "changes",
+				Summary: "Read the change feed",
+				Params: []Param{
+					{Name: "since", In: "query", Type: "integer", Description: "return changes numbered above this"},
+					{Name: "limit", In: "query", Type: "integer", Description: "changes per page"},
+					{Name: "wait", In: "query", Type: "string", Description: "wait up to this duration, at most 1m, for a change"},
+					{Name: "Last-Event-ID", In: "header", Type: "string", Description: "resume an event stream after this change"},
+				},
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "a page of changes", Schema: "changes"},
+					{Status: http.StatusOK, Description: "an event stream of changes, for clients that accept it", ContentType: "text/event-stream"},
+					errorResponse(http.StatusBadRequest, "an invalid since, limit, or wait"),
+				},
+			}, false, g.changeFeed)
+		}
+		if opts.Proofs != nil {
+			add(Route{
+				Method: http.MethodGet, Pattern: "/proofs/{key...}", ID: "getProof",
+				Summary: "Prove a key's object is in the latest checkpoint",
+				Params:  []Param{keyParam},
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "an inclusion proof with its checkpoint", Schema: "proof"},
+					errorResponse(http.StatusNotFound, "no such key"),
+					errorResponse(http.StatusConflict, "the store has changed since its latest checkpoint"),
+				},
+			}, false, g.proof)
+		}
+	}
+	out = append(out, route{Route{
+		Method: http.MethodPost, Pattern: "/hash", ID: "hashObject",
+		Summary: "Compute a memory object's content hash without storing it",
+		Body:    "memory-object",
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "the content hash PUT would store the object under", Schema: "hash-response"},
+			errorResponse(http.StatusBadRequest, "an invalid object, or one without _helios_schema_version; code is its CANON_ERR_ code"),
+			errorResponse(http.StatusRequestEntityTooLarge, "the body or the object's canonical form is too large"),
+		},
+	}, g.hash})
+	if opts.Metrics {
+		out = append(out, route{Route{
+			Method: http.MethodGet, Pattern: "/metrics", ID: "metrics",
+			Summary:   "Read counters in the Prometheus text format",
+			Responses: []Response{{Status: http.StatusOK, Description: "the counters", ContentType: "text/plain"}},
+		}, g.metrics})
+	}
+	if opts.Schemas != nil {
+		out = append(out,
+			route{Route{
+				Method: http.MethodGet, Pattern: "/schemas", ID: "listSchemas",
+				Summary:   "List the JSON Schemas of the wire formats",
+				Responses: []Response{{Status: http.StatusOK, Description: "the URL of each schema by name", ContentType: "application/json"}},
+			}, g.schemaIndex},
+			route{Route{
+				Method: http.MethodGet, Pattern: "/schemas/{name}", ID: "getSchema",
+				Summary: "Read one JSON Schema",
+				Params:  []Param{{Name: "name", In: "path", Type: "string", Required: true, Description: "the schema's name, with or without .json"}},
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "the schema", ContentType: "application/schema+json"},
+					errorResponse(http.StatusNotFound, "no such schema"),
+				},
+			}, g.schema},
+			route{Route{
+				Method: http.MethodGet, Pattern: "/openapi.json", ID: "openapi",
+				Summary:   "Read this description of the gateway",
+				Responses: []Response{{Status: http.StatusOK, Description: "an OpenAPI 3.1 document", ContentType: "application/json"}},
+			}, g.openapi})
+	}
+	if opts.Admin {
+		out = append(out,
+			route{Route{
+				Method: http.MethodGet, Pattern: "/healthz", ID: "healthz",
+				Summary:   "Report that the server is up",
+				Responses: []Response{{Status: http.StatusOK, Description: "the server is up", Schema: "health"}},
+			}, g.healthz},
+			route{Route{
+				Method: http.MethodGet, Pattern: "/readyz", ID: "readyz",
+				Summary: "Check that the store answers and the self-test passes",
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "every check passed", Schema: "health"},
+					{Status: http.StatusServiceUnavailable, Description: "a check failed", Schema: "health"},
+				},
+			}, g.readyz},
+			route{Route{
+				Method: http.MethodGet, Pattern: "/config", ID: "config",
+				Summary:   "Read the server's runtime configuration",
+				Responses: []Response{{Status: http.StatusOK, Description: "the configuration", ContentType: "application/json"}},
+			}, g.config})
+		if opts.Reloader != nil {
+			out = append(out, route{Route{
+				Method: http.MethodPost, Pattern: "/admin/reload", ID: "reload",
+				Summary: "Reload the store's write rules",
+				Responses: []Response{
+					{Status: http.StatusOK, Description: "the rules were reloaded; the audit record of the reload", Schema: "reload-event"},
+					errorResponse(http.StatusUnprocessableEntity, "the rules failed to load; the old ones stay in place"),
+				},
+			}, g.reload})
+		}
+	}
+	if opts.Identities != nil {
+		for i := range out {
+			out[i].Responses = append(append([]Response(nil), out[i].Responses...),
+				errorResponse(http.StatusUnauthorized, "no verified client certificate with a SPIFFE ID"),
+				errorResponse(http.StatusForbidden, "the client's SPIFFE ID is not granted the request"))
+		}
+	}
+	return out
+}
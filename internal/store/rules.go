@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// WriteRules are the checks every put must pass that a serving store can
+// replace without restarting: see SetWriteRules and RuleReloader.
+type WriteRules struct {
+	// Pipeline, if set, hashes and validates every put in place of
+	// Options.Pipeline.
+	Pipeline *hash.Pipeline
+	// RelationshipTypes, if set, is the registry of relationship types
+	// objects may use; an object with any other type is refused with
+	// STORE_ERR_UNKNOWN_RELATIONSHIP_TYPE. Names are NFC normalized.
+	RelationshipTypes map[string]bool
+	// Validate, if set, checks every object after the registry, such as
+	// against a JSON Schema for its category. Its errors are reported
+	// with code STORE_ERR_SCHEMA_VIOLATION.
+	Validate func(object.MemoryObject) error
+	// Digest identifies the configuration the rules were loaded from, for
+	// the audit log of reloads.
+	Digest string
+}
+
+// RuleError is returned for a put that breaks the store's WriteRules.
+type RuleError struct {
+	Code string
+	Key  string
+	Err  error
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("object %q: %v", e.Key, e.Err)
+}
+
+func (e *RuleError) Unwrap() error { return e.Err }
+
+// SetWriteRules replaces the write rules of s and of every tenant of the
+// store s belongs to; puts already past the checks are unaffected. nil
+// removes them.
+func (s *Store) SetWriteRules(r *WriteRules) {
+	s.rules.Store(r)
+}
+
+// WriteRules returns the write rules set with SetWriteRules, or nil.
+func (s *Store) WriteRules() *WriteRules {
+	return s.rules.Load()
+}
+
+// WriteProfile returns the profile hash new puts are stamped with.
+func (s *Store) WriteProfile() string {
+	return s.writePipeline(s.rules.Load()).Profile.ID()
+}
+
+func (s *Store) writePipeline(r *WriteRules) *hash.Pipeline {
+	switch {
+	case r != nil && r.Pipeline != nil:
+		return r.Pipeline
+	case s.opts.Pipeline != nil:
+		return s.opts.Pipeline
+	}
+	return hash.Current()
+}
+
+// check applies the registry and the validator of r to obj.
+func (r *WriteRules) check(obj object.MemoryObject) error {
+	if r == nil {
+		return nil
+	}
+	if r.RelationshipTypes != nil {
+		for _, rel := range obj.Relationships {
+			if !r.RelationshipTypes[canon.NormalizeString(rel.Type)] {
+				return &RuleError{Code: "STORE_ERR_UNKNOWN_RELATIONSHIP_TYPE", Key: obj.Key, Err: fmt.Errorf("relationship type %q is not registered", rel.Type)}
+			}
+		}
+	}
+	if r.Validate != nil {
+		if err := r.Validate(obj); err != nil {
+			return &RuleError{Code: "STORE_ERR_SCHEMA_VIOLATION", Key: obj.Key, Err: err}
+		}
+	}
+	return nil
+}
+
+// ReloadEvent is the audit record of one reload of a store's write
+// rules. A failed reload leaves the old rules in place and records the
+// error with New fields empty.
+type ReloadEvent struct {
+	Time string `json:"time"`
+	// Trigger names what asked for the reload, such as "SIGHUP" or "api".
+	Trigger string `json:"trigger"`
+	// Identity is the SPIFFE ID of the client that asked, if any.
+	Identity   string `json:"identity,omitempty"`
+	OldProfile string `json:"old_profile"`
+	NewProfile string `json:"new_profile,omitempty"`
+	OldDigest  string `json:"old_digest,omitempty"`
+	NewDigest  string `json:"new_digest,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RuleReloader reloads a store's write rules on demand.
+type RuleReloader struct {
+	Store *Store
+	// Load reads the rules afresh.
+	Load func() (*WriteRules, error)
+	// Audit, if set, is told about every reload, failed or not.
+	Audit func(ReloadEvent)
+
+	mu sync.Mutex
+}
+
+// Reload loads the rules and, if they load, sets them on the store.
+// trigger is recorded in the event, with the client's SPIFFE ID if ctx
+// is a request a gateway authenticated. Concurrent reloads are applied
+// one at a time.
+func (rl *RuleReloader) Reload(ctx context.Context, trigger string) (ReloadEvent, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	old := rl.Store.WriteRules()
+	ev := ReloadEvent{
+		Time:       rl.Store.now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		Trigger:    trigger,
+		OldProfile: rl.Store.writePipeline(old).Profile.ID(),
+	}
+	if who, ok := ctx.Value(identityKey{}).(*identity); ok {
+		ev.Identity = who.id
+	}
+	if old != nil {
+		ev.OldDigest = old.Digest
+	}
+	rules, err := rl.Load()
+	if err != nil {
+		ev.Error = err.Error()
+	} else {
+		rl.Store.SetWriteRules(rules)
+		ev.NewProfile = rl.Store.writePipeline(rules).Profile.ID()
+		if rules != nil {
+			ev.NewDigest = rules.Digest
+		}
+	}
+	if rl.Audit != nil {
+		rl.Audit(ev)
+	}
+	return ev, err
+}
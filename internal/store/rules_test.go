@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func TestWriteRules(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemory())
+	acme, err := s.Tenant(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetWriteRules(&WriteRules{
+		RelationshipTypes: map[string]bool{"cites": true},
+		Validate: func(obj object.MemoryObject) error {
+			if obj.Value == "forbidden" {
+				return errors.New("value is forbidden")
+			}
+			return nil
+		},
+	})
+
+	cites := testObject("a", "v")
+	cites.Relationships = []object.Relationship{{Key: "b", Type: "cites"}}
+	mentions := testObject("a", "v")
+	mentions.Relationships = []object.Relationship{{Key: "b", Type: "mentions"}}
+	for _, tc := range []struct {
+		obj  object.MemoryObject
+		code string
+	}{
+		{cites, ""},
+		{mentions, "STORE_ERR_UNKNOWN_RELATIONSHIP_TYPE"},
+		{testObject("a", "forbidden"), "STORE_ERR_SCHEMA_VIOLATION"},
+	} {
+		// The rules apply to the store's tenants too.
+		for _, st := range []*Store{s, acme} {
+			_, err := st.Put(ctx, tc.obj)
+			code := ""
+			var re *RuleError
+			if errors.As(err, &re) {
+				code = re.Code
+			} else if err != nil {
+				code = err.Error()
+			}
+			if code != tc.code {
+				t.Errorf("Put(%+v) = %v, want %q", tc.obj, err, tc.code)
+			}
+		}
+	}
+
+	if s.WriteProfile() != hash.Current().Profile.ID() {
+		t.Errorf("WriteProfile = %s, want the current profile", s.WriteProfile())
+	}
+	s.SetWriteRules(&WriteRules{Pipeline: hash.V1Strict})
+	if _, err := acme.Put(ctx, mentions); err != nil {
+		t.Errorf("put after the registry was dropped: %v", err)
+	}
+	h, _ := acme.Put(ctx, testObject("k", "v"))
+	if e, _ := acme.Resolve(ctx, "k"); e.Stamp != hash.V1Strict.Stamp().String() {
+		t.Errorf("entry %s stamped %s, want the strict profile", h, e.Stamp)
+	}
+}
+
+func TestGatewayReload(t *testing.T) {
+	s := newTestStore(t)
+	var audit []ReloadEvent
+	next := &WriteRules{Pipeline: hash.V1Strict, Digest: "d1"}
+	var loadErr error
+	rl := &RuleReloader{
+		Store: s,
+		Load:  func() (*WriteRules, error) { return next, loadErr },
+		Audit: func(ev ReloadEvent) { audit = append(audit, ev) },
+	}
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true, Admin: true, Reloader: rl}))
+	defer srv.Close()
+	post := func() (int, string) {
+		resp, err := http.Post(srv.URL+"/admin/reload", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	if code, body := post(); code != http.StatusOK || !strings.Contains(body, `"new_digest":"d1"`) {
+		t.Fatalf("reload: %d %s", code, body)
+	}
+	if s.WriteProfile() != hash.V1Strict.Profile.ID() {
+		t.Errorf("profile after reload = %s", s.WriteProfile())
+	}
+
+	loadErr = errors.New("bad rules")
+	if code, body := post(); code != http.StatusUnprocessableEntity || !strings.Contains(body, "STORE_ERR_RELOAD_FAILED") {
+		t.Errorf("failed reload: %d %s", code, body)
+	}
+	if r := s.WriteRules(); r.Digest != "d1" {
+		t.Errorf("a failed reload replaced the rules")
+	}
+
+	loadErr = nil
+	next = &WriteRules{RelationshipTypes: map[string]bool{}, Digest: "d2"}
+	post()
+	body := `{"category":"project","created_at":"2025-01-15T10:30:00.000Z","key":"a","relationships":[{"key":"b","type":"cites"}],"source":"user","value":"v"}`
+	if resp, body := put(t, srv, "a", body, nil); resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(body, "STORE_ERR_UNKNOWN_RELATIONSHIP_TYPE") {
+		t.Errorf("PUT breaking the reloaded rules: %d %s", resp.StatusCode, body)
+	}
+
+	if len(audit) != 3 {
+		t.Fatalf("audit = %+v, want 3 events", audit)
+	}
+	first, failed, last := audit[0], audit[1], audit[2]
+	if first.Trigger != "api" || first.OldProfile != hash.Current().Profile.ID() || first.NewProfile != hash.V1Strict.Profile.ID() {
+		t.Errorf("first reload = %+v", first)
+	}
+	if failed.Error != "bad rules" || failed.NewProfile != "" || failed.OldDigest != "d1" {
+		t.Errorf("failed reload = %+v", failed)
+	}
+	if last.OldDigest != "d1" || last.NewDigest != "d2" || last.NewProfile != hash.Current().Profile.ID() {
+		t.Errorf("last reload = %+v", last)
+	}
+}
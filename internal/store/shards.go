@@ -0,0 +1,128 @@
+package store
+
+import (
+	"math"
+	"sort"
+)
+
+// MaxShardWidth is the widest shard directory name Open accepts.
+const MaxShardWidth = 8
+
+// DefaultMaxPerShard is the directory size RecommendShardWidth aims to stay
+// under. Most filesystems list and look up directories of this size
+// without trouble; far larger ones get slow on ext4 without dir_index and
+// on network filesystems.
+const DefaultMaxPerShard = 4096
+
+// clusterPValue is the chi-square p-value below which a distribution is
+// reported as clustered. Content hashes are uniform, so a correct corpus
+// fails this test about once in a million runs.
+const clusterPValue = 1e-6
+
+// ShardCount is the number of objects in one shard directory.
+type ShardCount struct {
+	Prefix string `json:"prefix"`
+	Count  int    `json:"count"`
+}
+
+// ShardStats describes how a set of hashes spreads over the shard
+// directories of one shard width.
+type ShardStats struct {
+	Width   int     `json:"width"`
+	Shards  int     `json:"shards"`
+	Objects int     `json:"objects"`
+	Mean    float64 `json:"mean"`
+	Min     int     `json:"min"`
+	Max     int     `json:"max"`
+	Empty   int     `json:"empty"`
+
+	// Tested reports whether the chi-square uniformity test was run; it
+	// needs an expected count of at least 5 objects per shard.
+	Tested    bool    `json:"tested"`
+	ChiSquare float64 `json:"chi_square,omitempty"`
+	PValue    float64 `json:"p_value,omitempty"`
+	// Clustered is set when the test rejects uniformity, which means the
+	// hashes are not SHA-256 content hashes or the corpus was filtered by
+	// hash prefix, and shard sizes will not be bounded by Mean.
+	Clustered bool `json:"clustered"`
+	// Hot lists up to ten tested shards holding far more than Mean (more
+	// than six standard deviations of a uniform distribution), fullest
+	// first.
+	Hot []ShardCount `json:"hot,omitempty"`
+}
+
+// AnalyzeShards reports the distribution of hashes over the shard
+// directories of the given width. Duplicate hashes count once, as they do
+// in a store. Hashes shorter than width are ignored.
+func AnalyzeShards(hashes []string, width int) ShardStats {
+	counts := make(map[string]int)
+	seen := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		if len(h) < width || seen[h] {
+			continue
+		}
+		seen[h] = true
+		counts[h[:width]]++
+	}
+
+	st := ShardStats{Width: width, Shards: 1 << (4 * width), Objects: len(seen)}
+	st.Mean = float64(st.Objects) / float64(st.Shards)
+	st.Empty = st.Shards - len(counts)
+	if st.Empty == 0 {
+		st.Min = math.MaxInt
+	}
+	for _, n := range counts {
+		st.Min = min(st.Min, n)
+		st.Max = max(st.Max, n)
+	}
+
+	if st.Mean < 5 || st.Shards < 2 {
+		return st
+	}
+
+	st.Tested = true
+	for _, n := range counts {
+		d := float64(n) - st.Mean
+		st.ChiSquare += d * d / st.Mean
+	}
+	st.ChiSquare += float64(st.Empty) * st.Mean
+	st.PValue = chiSquareSurvival(st.ChiSquare, float64(st.Shards-1))
+	st.Clustered = st.PValue < clusterPValue
+
+	hot := st.Mean + 6*math.Sqrt(st.Mean)
+	for p, n := range counts {
+		if float64(n) > hot {
+			st.Hot = append(st.Hot, ShardCount{Prefix: p, Count: n})
+		}
+	}
+	sort.Slice(st.Hot, func(i, j int) bool {
+		if st.Hot[i].Count != st.Hot[j].Count {
+			return st.Hot[i].Count > st.Hot[j].Count
+		}
+		return st.Hot[i].Prefix < st.Hot[j].Prefix
+	})
+	if len(st.Hot) > 10 {
+		st.Hot = st.Hot[:10]
+	}
+	return st
+}
+
+// RecommendShardWidth returns the narrowest shard width, at least 1, that
+// keeps the expected shard size for objects objects at or below maxPerShard.
+func RecommendShardWidth(objects, maxPerShard int) int {
+	for w := 1; w < MaxShardWidth; w++ {
+		if float64(objects)/float64(int(1)<<(4*w)) <= float64(maxPerShard) {
+			return w
+		}
+	}
+	return MaxShardWidth
+}
+
+// chiSquareSurvival returns P(X >= x) for a chi-square variable with df
+// degrees of freedom, using the Wilson–Hilferty normal approximation,
+// which is accurate to well under 1% for the df >= 15 of any shard width.
+func chiSquareSurvival(x, df float64) float64 {
+	v := 2 / (9 * df)
+	z := (math.Cbrt(x/df) - (1 - v)) / math.Sqrt(v)
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
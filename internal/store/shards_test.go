@@ -0,0 +1,79 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func sampleHashes(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+		out[i] = hex.EncodeToString(sum[:])
+	}
+	return out
+}
+
+func TestAnalyzeShardsUniform(t *testing.T) {
+	hashes := sampleHashes(20000)
+	st := AnalyzeShards(append(hashes, hashes[:100]...), 2)
+	if st.Objects != 20000 || st.Shards != 256 || st.Empty != 0 {
+		t.Fatalf("stats %+v", st)
+	}
+	if !st.Tested || st.Clustered || len(st.Hot) != 0 {
+		t.Errorf("uniform hashes reported as clustered: %+v", st)
+	}
+	if st.Min > int(st.Mean) || st.Max < int(st.Mean) {
+		t.Errorf("min %d max %d mean %.1f", st.Min, st.Max, st.Mean)
+	}
+
+	if st := AnalyzeShards(hashes[:100], 2); st.Tested || st.Clustered {
+		t.Errorf("too few objects to test, got %+v", st)
+	}
+}
+
+func TestAnalyzeShardsClustered(t *testing.T) {
+	hashes := sampleHashes(20000)
+	for i := 0; i < 2000; i++ {
+		hashes[i] = "00" + hashes[i][2:]
+	}
+	st := AnalyzeShards(hashes, 2)
+	if !st.Clustered || len(st.Hot) != 1 || st.Hot[0].Prefix != "00" {
+		t.Errorf("clustering not detected: %+v", st)
+	}
+	// A coarser fan-out still sees the skew.
+	if st := AnalyzeShards(hashes, 1); !st.Clustered {
+		t.Errorf("width 1 should still see the skew: %+v", st)
+	}
+}
+
+func TestRecommendShardWidth(t *testing.T) {
+	for _, c := range []struct{ objects, want int }{
+		{0, 1}, {100, 1}, {16 * 4096, 1}, {16*4096 + 1, 2}, {5_000_000, 3}, {math.MaxInt32, 5},
+	} {
+		if got := RecommendShardWidth(c.objects, DefaultMaxPerShard); got != c.want {
+			t.Errorf("RecommendShardWidth(%d) = %d, want %d", c.objects, got, c.want)
+		}
+	}
+	// Counts are ints, so the cap is reached with a small maxPerShard
+	// rather than a count a 32-bit int cannot hold.
+	if got := RecommendShardWidth(math.MaxInt32, 1); got != MaxShardWidth {
+		t.Errorf("RecommendShardWidth beyond the widest shards = %d, want %d", got, MaxShardWidth)
+	}
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	// Reference values from the exact distribution.
+	for _, c := range []struct{ x, df, want float64 }{
+		{255, 255, 0.488},
+		{300, 255, 0.0282},
+		{15, 15, 0.451},
+	} {
+		if got := chiSquareSurvival(c.x, c.df); math.Abs(got-c.want) > 0.01 {
+			t.Errorf("P(X >= %v; df %v) = %.4f, want %.4f", c.x, c.df, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,710 @@
+// Package store is a content-addressed object store for memory objects.
+// Objects are stored as their hash input: their canonical bytes, preceded
+// by a domain line when the store's profile gives their category a domain
+// tag. The SHA-256 of a stored blob is therefore its content hash, and any
+// copy can be verified without helios. A key index maps each object key
+// to the hash of its current object.
+//
+// A Store validates, canonicalizes, and hashes objects and keeps its data
+// in a Backend. FS keeps a store in a directory tree and Memory keeps it
+// in process memory; other backends implement the Backend interface and
+// can be checked with the storetest package.
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/abbrev"
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+// Errors returned by stores and backends.
+var (
+	ErrNotFound   = errors.New("store: not found")
+	ErrInvalidRef = errors.New("store: invalid content hash")
+	// ErrConflict is returned by CompareAndSwap when the key does not
+	// point at the expected hash.
+	ErrConflict = errors.New("store: key does not hold the expected hash")
+	// ErrCorrupt is matched by a *CorruptError.
+	ErrCorrupt = errors.New("store: stored object does not match its content hash")
+	// ErrRequestMismatch is returned by PutRequest and Replayed when the
+	// request id already wrote a different object, or another key.
+	ErrRequestMismatch = errors.New("store: request id was used for a different object")
+)
+
+// CorruptError reports a blob whose bytes no longer hash to the hash it is
+// stored under.
+type CorruptError struct {
+	Hash string
+	// Actual is the hash of the bytes read.
+	Actual string
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("store: object %s is corrupt: its bytes hash to %s", e.Hash, e.Actual)
+}
+
+// Is makes errors.Is(err, ErrCorrupt) match.
+func (e *CorruptError) Is(target error) bool { return target == ErrCorrupt }
+
+// Expected hashes for CompareAndSwap and Backend.SetKey with special
+// meaning.
+const (
+	// Absent means the key must not exist yet.
+	Absent = ""
+	// Any means the key may hold anything or not exist.
+	Any = "*"
+)
+
+// KeyEntry is the index record for one key.
+type KeyEntry struct {
+	Key       string `json:"key"`
+	Hash      string `json:"hash" schema:"pattern=^[0-9a-f]{64}$"`
+	UpdatedAt string `json:"updated_at"`
+	// Category is the category of the object at Hash, kept in the index
+	// so listings can filter on it. It is empty in entries written before
+	// the index recorded categories.
+	Category string `json:"category,omitempty"`
+	// Stamp is the hash.Stamp of the pipeline that computed Hash, in its
+	// String form. It is empty in entries written before stamping, which
+	// were all hashed under version 1.
+	Stamp string `json:"stamp,omitempty"`
+	// RequestID is the idempotency key of the write that set Hash, if it
+	// had one; see PutRequest.
+	RequestID string `json:"request_id,omitempty"`
+	// Residency is the residency region of the object at Hash, which its
+	// canonical form leaves out, kept in the index so exports can filter
+	// on it and carry it along. It is empty for objects without one.
+	Residency string `json:"residency,omitempty"`
+	// LegalHold is the reason of the key's legal hold, empty if it has
+	// none; see Store.Hold.
+	LegalHold string `json:"legal_hold,omitempty"`
+}
+
+// Pipeline returns the hashing pipeline e's stamp names.
+func (e KeyEntry) Pipeline() (*hash.Pipeline, error) {
+	if e.Stamp == "" {
+		return hash.Lookup(hash.Stamp{})
+	}
+	st, err := hash.ParseStamp(e.Stamp)
+	if err != nil {
+		return nil, err
+	}
+	return hash.Lookup(st)
+}
+
+// Backend is the storage under a Store: immutable blobs named by their
+// content hash, and a key index. Store hashes and validates everything it
+// passes to a backend, so a backend only stores and retrieves.
+// Implementations must be safe for concurrent use, and report missing
+// blobs and keys with ErrNotFound.
+type Backend interface {
+	// Put stores a blob under its content hash h. Storing a hash that
+	// already exists is a no-op.
+	Put(ctx context.Context, h string, data []byte) error
+	// Get returns the blob stored under h.
+	Get(ctx context.Context, h string) ([]byte, error)
+	// Has reports whether a blob is stored under h.
+	Has(ctx context.Context, h string) (bool, error)
+	// List returns the hashes of all stored blobs that begin with prefix,
+	// sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the blob stored under h.
+	Delete(ctx context.Context, h string) error
+
+	// SetKey points e.Key at e.Hash, atomically with checking that the
+	// key currently holds expected (or Absent, or Any). It returns an
+	// error wrapping ErrConflict when the check fails.
+	SetKey(ctx context.Context, e KeyEntry, expected string) error
+	// ResolveKey returns the index entry for key.
+	ResolveKey(ctx context.Context, key string) (KeyEntry, error)
+	// ListKeys returns the index entries of all keys that begin with
+	// prefix, sorted by key.
+	ListKeys(ctx context.Context, prefix string) ([]KeyEntry, error)
+	// DeleteKey removes key from the index.
+	DeleteKey(ctx context.Context, key string) error
+}
+
+// Committer is implemented by backends that can store a blob and point a
+// key at it as one crash-atomic step. CompareAndSwap uses Commit when the
+// backend has it, and Put followed by SetKey otherwise.
+type Committer interface {
+	// Commit stores data under h and points e.Key at it if the key
+	// currently holds expected, with the semantics of Put and SetKey.
+	Commit(ctx context.Context, h string, data []byte, e KeyEntry, expected string) error
+}
+
+// KeyDeleter is implemented by backends that can remove a key atomically
+// with checking its index entry. Delete uses it when the backend has it,
+// so a legal hold set between reading the entry and removing the key
+// still stops the delete; on other backends the two are separate steps.
+type KeyDeleter interface {
+	// DeleteKeyIf removes e.Key if its index entry is exactly e. It
+	// returns ErrNotFound if the key does not exist, and an error
+	// wrapping ErrConflict if its entry is another.
+	DeleteKeyIf(ctx context.Context, e KeyEntry) error
+}
+
+// Options configures NewWithOptions.
+type Options struct {
+	// VerifyReads re-hashes every blob Get returns and fails with a
+	// *CorruptError if it does not match, so storage corruption surfaces
+	// when an object is read rather than at the next fsck.
+	VerifyReads bool
+	// Quotas, if set, is enforced on every put. Usage is computed on the
+	// first put to each namespace and then kept up to date in memory, so
+	// the limits are exact only while this process is the only writer.
+	Quotas *QuotaPolicy
+	// Indexer, if set, is told about every key written or deleted
+	// through the store, in every namespace.
+	Indexer Indexer
+	// Changes, if set, records every put and delete made through the
+	// store, in every namespace, in the order they were made.
+	Changes ChangeLog
+	// Pipeline, if set, hashes every put instead of hash.Current, for a
+	// store whose objects must satisfy a stricter profile such as
+	// hash.V1Strict. Objects it rejects are not stored.
+	Pipeline *hash.Pipeline
+	// MaxCanonicalSize, if positive, refuses puts whose canonical form is
+	// longer than this many bytes with CANON_ERR_TOO_LARGE. The size is
+	// measured before the object is canonicalized.
+	MaxCanonicalSize int64
+	// Instrumentation, if set, is told about every object a put hashes.
+	// A put refused before hashing, by MaxCanonicalSize or by the rule
+	// checks measuring the size runs, is reported as a validation error
+	// only.
+	Instrumentation hash.Instrumentation
+	// Regions lists the residency regions the store may hold. An object
+	// whose residency field names another region, or any region when
+	// Regions is empty, is refused with ErrResidency; objects without a
+	// residency are accepted everywhere.
+	Regions []string
+	// HoldAudit, if set, is told about every legal hold set or released
+	// through the store, in every namespace.
+	HoldAudit func(HoldEvent)
+	// HashCache, if set, memoizes the canonical bytes and hash of the
+	// objects the gateway writes by the bytes of the request body, so a
+	// retried PUT skips canonicalization.
+	HashCache *hash.Cache
+	// RequestTTL is how long PutRequest remembers a request id, and
+	// MaxRequests how many it remembers per namespace; zero means
+	// DefaultRequestTTL and DefaultMaxRequests.
+	RequestTTL  time.Duration
+	MaxRequests int
+}
+
+// Indexer maintains a secondary index, such as a search index, over the
+// current object of every key. tenant is "" for the default namespace.
+// A failed update fails the write that caused it, after the store itself
+// has been updated; rebuilding the index from the store repairs it.
+type Indexer interface {
+	Index(ctx context.Context, tenant, key, hash string, canonical []byte) error
+	Remove(ctx context.Context, tenant, key string) error
+}
+
+// Indexers returns an Indexer that updates each of ix in turn, stopping at
+// the first error.
+func Indexers(ix ...Indexer) Indexer { return indexers(ix) }
+
+type indexers []Indexer
+
+func (ix indexers) Index(ctx context.Context, tenant, key, hash string, canonical []byte) error {
+	for _, i := range ix {
+		if err := i.Index(ctx, tenant, key, hash, canonical); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ix indexers) Remove(ctx context.Context, tenant, key string) error {
+	for _, i := range ix {
+		if err := i.Remove(ctx, tenant, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Store is a content-addressed store over a Backend. It is safe for
+// concurrent use.
+type Store struct {
+	b        Backend
+	opts     Options
+	tenantID string
+	now      func() time.Time
+
+	// stats, quota, changeLock, rules, and requests are shared with the
+	// store's tenants.
+	stats      *readCounters
+	quota      *quotaState
+	changeLock *changeLock
+	rules      *atomic.Pointer[WriteRules]
+	requests   *requestLog
+}
+
+type readCounters struct {
+	reads, verified, corrupt atomic.Uint64
+}
+
+// New returns a store over b with default options.
+func New(b Backend) *Store {
+	return NewWithOptions(b, Options{})
+}
+
+// NewWithOptions returns a store over b.
+func NewWithOptions(b Backend, opts Options) *Store {
+	return &Store{b: b, opts: opts, now: time.Now, stats: new(readCounters), quota: new(quotaState), changeLock: new(changeLock), rules: new(atomic.Pointer[WriteRules]), requests: new(requestLog)}
+}
+
+// ReadStats counts Get calls that returned or failed on a blob.
+type ReadStats struct {
+	Reads uint64 `json:"reads"`
+	// Verified is the number of reads whose bytes were re-hashed.
+	Verified uint64 `json:"verified"`
+	// Corrupt is the number of verified reads that failed with a
+	// *CorruptError.
+	Corrupt uint64 `json:"corrupt"`
+}
+
+// ReadStats returns the read counters since the store was opened,
+// including reads through its tenants.
+func (s *Store) ReadStats() ReadStats {
+	return ReadStats{Reads: s.stats.reads.Load(), Verified: s.stats.verified.Load(), Corrupt: s.stats.corrupt.Load()}
+}
+
+// Init creates a filesystem store at root, or opens it if one already
+// exists. See InitFS.
+func Init(root string) (*Store, error) {
+	b, err := InitFS(root)
+	if err != nil {
+		return nil, err
+	}
+	return New(b), nil
+}
+
+// Open opens an existing filesystem store. See OpenFS.
+func Open(root string) (*Store, error) {
+	b, err := OpenFS(root)
+	if err != nil {
+		return nil, err
+	}
+	return New(b), nil
+}
+
+// Backend returns the store's backend.
+func (s *Store) Backend() Backend { return s.b }
+
+// ValidHash reports whether h is a lowercase hex SHA-256 digest.
+func ValidHash(h string) bool {
+	if len(h) != 64 {
+		return false
+	}
+	for i := 0; i < len(h); i++ {
+		c := h[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Put stores obj and points its key at it. It returns the content hash.
+func (s *Store) Put(ctx context.Context, obj object.MemoryObject) (string, error) {
+	return s.CompareAndSwap(ctx, obj, Any)
+}
+
+// CompareAndSwap is Put conditioned on the key currently pointing at
+// expected, or not existing when expected is Absent. It returns
+// ErrConflict, wrapped with the current hash, when the condition fails.
+// Unless the backend is a Committer, the object itself is stored even
+// when the condition fails; an unreferenced blob is harmless.
+func (s *Store) CompareAndSwap(ctx context.Context, obj object.MemoryObject, expected string) (string, error) {
+	return s.compareAndSwap(ctx, obj, nil, expected, "")
+}
+
+// PutRequest is CompareAndSwap for a write identified by requestID, a
+// client's idempotency key. If requestID was applied within the last
+// Options.RequestTTL, PutRequest writes nothing, records no change, and
+// returns the hash it wrote with replayed set, whatever expected is and
+// even if the key has since been rewritten: the request has already been
+// applied. It fails with ErrRequestMismatch if that write stored a
+// different object or key.
+//
+// The store remembers at most Options.MaxRequests request ids per
+// namespace, in memory; the id is also recorded in the index entry it
+// writes, so after a restart a retry is recognized while the key still
+// holds its write. With expected Any, the write is made conditional on
+// the entry PutRequest read, so two concurrent deliveries of one request
+// write once.
+func (s *Store) PutRequest(ctx context.Context, obj object.MemoryObject, expected, requestID string) (h string, replayed bool, err error) {
+	return s.putRequest(ctx, obj, nil, expected, requestID)
+}
+
+// putRequest is PutRequest for obj parsed from the document raw, which
+// keys Options.HashCache; raw is nil if obj has no document.
+func (s *Store) putRequest(ctx context.Context, obj object.MemoryObject, raw []byte, expected, requestID string) (h string, replayed bool, err error) {
+	if requestID == "" {
+		h, err := s.compareAndSwap(ctx, obj, raw, expected, "")
+		return h, false, err
+	}
+	var conflict error
+	for {
+		cur, ok, err := s.applied(ctx, obj, raw, requestID)
+		if err != nil || ok {
+			return cur.Hash, ok, err
+		}
+		cur, err = s.b.ResolveKey(ctx, obj.Key)
+		exists := err == nil
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return "", false, err
+		}
+		if conflict != nil && expected != Any {
+			return "", false, conflict
+		}
+		cas := expected
+		if expected == Any {
+			cas = Absent
+			if exists {
+				cas = cur.Hash
+			}
+		}
+		h, err := s.compareAndSwap(ctx, obj, raw, cas, requestID)
+		if !errors.Is(err, ErrConflict) {
+			return h, false, err
+		}
+		conflict = err
+	}
+}
+
+// Replayed reports whether requestID has already been applied, as
+// PutRequest would find, and returns the entry it wrote if so. It fails
+// with ErrRequestMismatch if that write stored an object other than obj.
+func (s *Store) Replayed(ctx context.Context, obj object.MemoryObject, requestID string) (KeyEntry, bool, error) {
+	return s.replayed(ctx, obj, nil, requestID)
+}
+
+// replayed is Replayed for obj parsed from the document raw; see
+// putRequest.
+func (s *Store) replayed(ctx context.Context, obj object.MemoryObject, raw []byte, requestID string) (KeyEntry, bool, error) {
+	if requestID == "" {
+		return KeyEntry{}, false, nil
+	}
+	return s.applied(ctx, obj, raw, requestID)
+}
+
+// applied looks requestID up in the store's request record, then in the
+// index entry of obj's key, and checks that the write it finds is obj.
+func (s *Store) applied(ctx context.Context, obj object.MemoryObject, raw []byte, requestID string) (KeyEntry, bool, error) {
+	now, ttl := s.now(), s.opts.requestTTL()
+	cur, ok := s.requests.lookup(s.tenantID, requestID, now, ttl)
+	if !ok {
+		var err error
+		cur, err = s.b.ResolveKey(ctx, obj.Key)
+		if errors.Is(err, ErrNotFound) {
+			return KeyEntry{}, false, nil
+		}
+		if err != nil {
+			return KeyEntry{}, false, err
+		}
+		updated, err := time.Parse("2006-01-02T15:04:05.000Z", cur.UpdatedAt)
+		if cur.RequestID != requestID || err != nil || now.Sub(updated) >= ttl {
+			return KeyEntry{}, false, nil
+		}
+	}
+	if err := s.checkReplay(obj, raw, cur); err != nil {
+		return KeyEntry{}, false, err
+	}
+	return cur, true, nil
+}
+
+// checkReplay checks that obj is the object of cur, which its request id
+// wrote.
+func (s *Store) checkReplay(obj object.MemoryObject, raw []byte, cur KeyEntry) error {
+	if obj.Key != cur.Key {
+		return fmt.Errorf("%w: request %q wrote key %q, not %q", ErrRequestMismatch, cur.RequestID, cur.Key, obj.Key)
+	}
+	pipeline, err := cur.Pipeline()
+	if err != nil {
+		return err
+	}
+	_, h, err := s.opts.HashCache.Hash(pipeline, raw, obj)
+	if err != nil {
+		return err
+	}
+	if !hash.Equal(cur.Hash, h) {
+		return fmt.Errorf("%w: key %q holds %s from request %q, not %s", ErrRequestMismatch, cur.Key, cur.Hash, cur.RequestID, h)
+	}
+	return nil
+}
+
+func (s *Store) compareAndSwap(ctx context.Context, obj object.MemoryObject, raw []byte, expected, requestID string) (string, error) {
+	if err := s.checkTenant(obj.Tenant); err != nil {
+		return "", err
+	}
+	if err := s.checkResidency(obj.Residency); err != nil {
+		return "", err
+	}
+	hold, newHold, err := s.heldReason(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	rules := s.rules.Load()
+	if err := rules.check(obj); err != nil {
+		return "", err
+	}
+	pipeline := s.writePipeline(rules)
+	if err := hash.CheckSize(obj, s.opts.MaxCanonicalSize); err != nil {
+		if in := s.opts.Instrumentation; in != nil {
+			in.OnValidationError(hash.ValidationError{Key: obj.Key, Profile: pipeline.Profile.ID(), Code: canon.ErrorCode(err), Err: err})
+		}
+		return "", err
+	}
+	if s.opts.Instrumentation != nil {
+		pipeline = pipeline.WithInstrumentation(s.opts.Instrumentation)
+	}
+	canonical, h, err := s.opts.HashCache.Hash(pipeline, raw, obj)
+	if err != nil {
+		return "", err
+	}
+	blob := pipeline.HashInput(obj, canonical)
+
+	category := categoryOf(canonical)
+	entry := KeyEntry{Key: obj.Key, Hash: h, UpdatedAt: s.now().UTC().Format("2006-01-02T15:04:05.000Z"), Category: category, Stamp: pipeline.Stamp().String(), RequestID: requestID, Residency: obj.Residency, LegalHold: hold}
+	err = s.recorded(ctx, ChangePut, obj.Key, h, func() error {
+		return s.withQuota(ctx, obj.Key, category, int64(len(blob)), func() error {
+			return s.commit(ctx, h, blob, entry, expected)
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	if requestID != "" {
+		s.requests.record(s.tenantID, entry, s.now(), s.opts.requestTTL(), s.opts.maxRequests())
+	}
+	if newHold {
+		s.audit(s.holdEvent(ctx, HoldSet, entry, hold, "put"))
+	}
+	if s.opts.Indexer != nil {
+		if err := s.opts.Indexer.Index(ctx, s.tenantID, obj.Key, h, canonical); err != nil {
+			return "", fmt.Errorf("stored %s but failed to index it: %w", h, err)
+		}
+	}
+	return h, nil
+}
+
+// commit stores blob under h and points entry.Key at it if the key holds
+// expected, in one step if the backend is a Committer.
+func (s *Store) commit(ctx context.Context, h string, blob []byte, entry KeyEntry, expected string) error {
+	if c, ok := s.b.(Committer); ok {
+		if err := c.Commit(ctx, h, blob, entry, expected); err != nil {
+			if errors.Is(err, ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("failed to commit object: %w", err)
+		}
+		return nil
+	}
+	if err := s.b.Put(ctx, h, blob); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := s.b.SetKey(ctx, entry, expected); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to write key index: %w", err)
+	}
+	return nil
+}
+
+// Get returns the canonical bytes of the object stored under content hash
+// h. With Options.VerifyReads, a blob that does not hash to h fails with a
+// *CorruptError.
+func (s *Store) Get(ctx context.Context, h string) ([]byte, error) {
+	return s.get(ctx, h, s.opts.VerifyReads)
+}
+
+// get is Get with verification chosen by the caller; the gateway always
+// verifies what it serves.
+func (s *Store) get(ctx context.Context, h string, verify bool) ([]byte, error) {
+	if !ValidHash(h) {
+		return nil, ErrInvalidRef
+	}
+	data, err := s.b.Get(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	s.stats.reads.Add(1)
+	if !verify {
+		return canonicalOf(data), nil
+	}
+	s.stats.verified.Add(1)
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); !hash.Equal(h, actual) {
+		s.stats.corrupt.Add(1)
+		return nil, &CorruptError{Hash: h, Actual: actual}
+	}
+	return canonicalOf(data), nil
+}
+
+// canonicalOf returns the canonical bytes of the object a blob holds,
+// without its domain line.
+func canonicalOf(blob []byte) []byte {
+	_, canonical := hash.SplitHashInput(blob)
+	return canonical
+}
+
+// Has reports whether an object with content hash h is stored.
+func (s *Store) Has(ctx context.Context, h string) (bool, error) {
+	if !ValidHash(h) {
+		return false, ErrInvalidRef
+	}
+	return s.b.Has(ctx, h)
+}
+
+// Resolve returns the index entry for key.
+func (s *Store) Resolve(ctx context.Context, key string) (KeyEntry, error) {
+	return s.b.ResolveKey(ctx, key)
+}
+
+// Keys returns every index entry, sorted by key.
+func (s *Store) Keys(ctx context.Context) ([]KeyEntry, error) {
+	return s.b.ListKeys(ctx, "")
+}
+
+// Hashes returns the content hash of every stored object, sorted.
+func (s *Store) Hashes(ctx context.Context) ([]string, error) {
+	return s.b.List(ctx, "")
+}
+
+// Stats summarizes the contents of a store.
+type Stats struct {
+	Objects int `json:"objects"`
+	Keys    int `json:"keys"`
+	// Bytes is the total size of every stored blob.
+	Bytes int64 `json:"bytes"`
+}
+
+// Stats counts the objects and keys of s, not including its tenants, and
+// sums the object sizes. It reads every object.
+func (s *Store) Stats(ctx context.Context) (Stats, error) {
+	hashes, err := s.b.List(ctx, "")
+	if err != nil {
+		return Stats{}, err
+	}
+	keys, err := s.b.ListKeys(ctx, "")
+	if err != nil {
+		return Stats{}, err
+	}
+	st := Stats{Objects: len(hashes), Keys: len(keys)}
+	for _, h := range hashes {
+		data, err := s.b.Get(ctx, h)
+		if err != nil {
+			return Stats{}, err
+		}
+		st.Bytes += int64(len(data))
+	}
+	return st, nil
+}
+
+// Delete removes key from the index. The object it pointed at stays in
+// the store: objects are immutable and may be shared by other keys or
+// referenced by hash.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	err := s.recorded(ctx, ChangeDelete, key, "", func() error {
+		if s.opts.Quotas != nil {
+			return s.deleteWithQuota(ctx, key)
+		}
+		_, err := s.deleteUnheld(ctx, key)
+		return err
+	})
+	if err == nil && s.opts.Indexer != nil {
+		if err := s.opts.Indexer.Remove(ctx, s.tenantID, key); err != nil {
+			return fmt.Errorf("deleted %q but failed to unindex it: %w", key, err)
+		}
+	}
+	return err
+}
+
+// deleteUnheld removes key unless it is under legal hold, returning the
+// entry it removed. The hold is checked in the same step as the removal
+// if the backend is a KeyDeleter, retrying if the key is rewritten
+// meanwhile.
+func (s *Store) deleteUnheld(ctx context.Context, key string) (KeyEntry, error) {
+	for {
+		e, err := s.b.ResolveKey(ctx, key)
+		if err != nil {
+			return KeyEntry{}, err
+		}
+		if e.LegalHold != "" {
+			return KeyEntry{}, fmt.Errorf("%w: %q (%s)", ErrHeld, key, e.LegalHold)
+		}
+		d, ok := s.b.(KeyDeleter)
+		if !ok {
+			return e, s.b.DeleteKey(ctx, key)
+		}
+		err = d.DeleteKeyIf(ctx, e)
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		return e, err
+	}
+}
+
+// categoryOf returns the category of canonical object bytes.
+func categoryOf(canonical []byte) string {
+	var head struct {
+		Category string `json:"category"`
+	}
+	json.Unmarshal(canonical, &head)
+	return head.Category
+}
+
+// Expand resolves ref, a full content hash or a unique abbreviation of at
+// least abbrev.MinLength hex digits, to the full hash of a stored object.
+// A full hash is returned as-is without checking that it is stored. An
+// abbreviation matching several objects fails with *abbrev.AmbiguousError;
+// one matching none fails with ErrNotFound.
+func (s *Store) Expand(ctx context.Context, ref string) (string, error) {
+	if ValidHash(ref) {
+		return ref, nil
+	}
+	if err := abbrev.Check(ref); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRef, err)
+	}
+	candidates, err := s.b.List(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	h, err := abbrev.Resolve(ref, candidates)
+	if errors.Is(err, abbrev.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return h, err
+}
+
+// CheckExpected reports whether a key in state (cur, exists) satisfies the
+// SetKey expectation, returning a wrapped ErrConflict if not. Backends use
+// it so they agree on the semantics and the error text.
+func CheckExpected(key string, cur KeyEntry, exists bool, expected string) error {
+	switch {
+	case expected == Any:
+		return nil
+	case expected == Absent && exists:
+		return fmt.Errorf("%w: %q exists at %s", ErrConflict, key, cur.Hash)
+	case expected != Absent && !exists:
+		return fmt.Errorf("%w: %q does not exist", ErrConflict, key)
+	case expected != Absent && !hash.Equal(expected, cur.Hash):
+		return fmt.Errorf("%w: %q is at %s", ErrConflict, key, cur.Hash)
+	}
+	return nil
+}
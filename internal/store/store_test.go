@@ -0,0 +1,450 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/abbrev"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+)
+
+func testObject(key, value string) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "user",
+		Value:         value,
+	}
+}
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPutGet(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	obj := testObject("notes/a", "hello")
+	h, err := s.Put(ctx, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := hash.ContentHash(obj); h != want {
+		t.Errorf("Put returned %s, want content hash %s", h, want)
+	}
+
+	data, err := s.Get(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != h {
+		t.Error("stored blob does not hash to its name")
+	}
+	if want, _ := hash.CanonicalBytes(obj); string(data) != string(want) {
+		t.Errorf("stored %s, want canonical bytes %s", data, want)
+	}
+
+	e, err := s.Resolve(ctx, "notes/a")
+	if err != nil || e.Hash != h || e.Key != "notes/a" {
+		t.Errorf("Resolve: %+v, %v", e, err)
+	}
+
+	// Re-pointing a key keeps the old object.
+	h2, _ := s.Put(ctx, testObject("notes/a", "updated"))
+	if e, _ := s.Resolve(ctx, "notes/a"); e.Hash != h2 {
+		t.Errorf("key points at %s after update, want %s", e.Hash, h2)
+	}
+	if _, err := s.Get(ctx, h); err != nil {
+		t.Errorf("old object: %v", err)
+	}
+	hashes, _ := s.Hashes(ctx)
+	keys, _ := s.Keys(ctx)
+	if len(hashes) != 2 || len(keys) != 1 {
+		t.Errorf("got %d hashes and %d keys", len(hashes), len(keys))
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	if _, err := s.Get(ctx, "nothex"); !errors.Is(err, ErrInvalidRef) {
+		t.Errorf("Get invalid: %v", err)
+	}
+	if _, err := s.Get(ctx, hex.EncodeToString(make([]byte, 32))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing: %v", err)
+	}
+	if _, err := s.Resolve(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve missing: %v", err)
+	}
+	if _, err := s.Put(ctx, object.MemoryObject{Key: "bad", CreatedAt: "2025-01-15T10:30:00.000Z"}); err == nil {
+		t.Error("Put accepted a null value")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if _, err := Open(dir); err == nil {
+		t.Error("Open succeeded without a descriptor")
+	}
+	s, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _ := s.Put(ctx, testObject("k", "v"))
+	again, err := Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := again.Get(ctx, h); err != nil {
+		t.Errorf("reopened store lost %s: %v", h, err)
+	}
+	if l := again.Backend().(*FS).Layout(); l.Format != Format || l.ShardWidth != DefaultShardWidth {
+		t.Errorf("layout %+v", l)
+	}
+
+	os.WriteFile(dir+"/"+DescriptorName, []byte(`{"format":"other/v9"}`), 0644)
+	if _, err := Open(dir); err == nil {
+		t.Error("Open accepted an unknown format")
+	}
+}
+
+func TestCompareAndSwapIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	base, _ := s.Put(ctx, testObject("k", "base"))
+
+	var wg sync.WaitGroup
+	var wins atomic.Int32
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.CompareAndSwap(ctx, testObject("k", fmt.Sprint("writer", i)), base)
+			if err == nil {
+				wins.Add(1)
+			} else if !errors.Is(err, ErrConflict) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if wins.Load() != 1 {
+		t.Errorf("%d writers won the swap, want 1", wins.Load())
+	}
+}
+
+func TestExpand(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	for i := 0; i < 1000; i++ {
+		if _, err := s.Put(ctx, testObject(fmt.Sprintf("k%d", i), "v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hashes, err := s.Hashes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ambiguous := 0
+	for h, n := range abbrev.Shortest(hashes, abbrev.MinLength) {
+		if got, err := s.Expand(ctx, h[:n]); err != nil || got != h {
+			t.Errorf("Expand(%s) = %s, %v; want %s", h[:n], got, err, h)
+		}
+		if n > abbrev.MinLength {
+			ambiguous++
+			var amb *abbrev.AmbiguousError
+			if _, err := s.Expand(ctx, h[:n-1]); !errors.As(err, &amb) {
+				t.Errorf("Expand(%s): got %v, want ambiguity", h[:n-1], err)
+			}
+		}
+	}
+	if ambiguous == 0 {
+		t.Fatal("corpus has no ambiguous 4-digit prefixes; the test needs more objects")
+	}
+
+	if _, err := s.Expand(ctx, "abc"); !errors.Is(err, ErrInvalidRef) {
+		t.Errorf("short prefix: %v", err)
+	}
+	if _, err := s.Expand(ctx, "ABCD"); !errors.Is(err, ErrInvalidRef) {
+		t.Errorf("uppercase prefix: %v", err)
+	}
+	missing := ""
+	for i := 0; missing == ""; i++ {
+		p := fmt.Sprintf("%04x", i)
+		if _, err := abbrev.Resolve(p, hashes); errors.Is(err, abbrev.ErrNotFound) {
+			missing = p
+		}
+	}
+	if _, err := s.Expand(ctx, missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unmatched prefix %s: %v", missing, err)
+	}
+}
+
+func TestStoreOverMemory(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemory())
+	h, err := s.Put(ctx, testObject("notes/a", "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full, err := s.Expand(ctx, h[:6]); err != nil || full != h {
+		t.Errorf("Expand: %s, %v", full, err)
+	}
+	if _, err := s.CompareAndSwap(ctx, testObject("notes/a", "x"), Absent); !errors.Is(err, ErrConflict) {
+		t.Errorf("create-only CAS on an existing key: %v", err)
+	}
+
+	if err := s.Delete(ctx, "notes/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Resolve(ctx, "notes/a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve after Delete: %v", err)
+	}
+	if ok, _ := s.Has(ctx, h); !ok {
+		t.Error("Delete removed the object as well as the key")
+	}
+}
+
+func TestPutStampsEntries(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemory())
+	h, err := s.Put(ctx, testObject("k", "v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.Resolve(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := hash.Current().Stamp().String(); e.Stamp != want {
+		t.Errorf("stamp = %q, want %q", e.Stamp, want)
+	}
+	p, err := e.Pipeline()
+	if err != nil || p != hash.Current() {
+		t.Fatalf("Pipeline = %v, %v", p, err)
+	}
+	if got, err := p.ContentHash(testObject("k", "v")); err != nil || got != h {
+		t.Errorf("rehash under the stamped pipeline = %s, %v; stored %s", got, err, h)
+	}
+
+	if p, err := (KeyEntry{}).Pipeline(); err != nil || p != hash.V1 {
+		t.Errorf("unstamped entry: %v, %v", p, err)
+	}
+	for _, stamp := range []string{"future/sha256/unknown", "garbage"} {
+		if _, err := (KeyEntry{Stamp: stamp}).Pipeline(); err == nil {
+			t.Errorf("stamp %q resolved", stamp)
+		}
+	}
+}
+
+func TestVerifyReads(t *testing.T) {
+	ctx := context.Background()
+	b, err := InitFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, verifying := New(b), NewWithOptions(b, Options{VerifyReads: true})
+	h, err := plain.Put(ctx, testObject("k", "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifying.Get(ctx, h); err != nil {
+		t.Fatalf("Get of an intact object: %v", err)
+	}
+	os.WriteFile(b.objectPath(h), []byte(`{"tampered":true}`), 0644)
+
+	if _, err := plain.Get(ctx, h); err != nil {
+		t.Errorf("Get without VerifyReads: %v", err)
+	}
+	_, err = verifying.Get(ctx, h)
+	var ce *CorruptError
+	if !errors.Is(err, ErrCorrupt) || !errors.As(err, &ce) || ce.Hash != h || ce.Actual == h {
+		t.Fatalf("Get of a corrupt object: %v", err)
+	}
+	if st := verifying.ReadStats(); st != (ReadStats{Reads: 2, Verified: 2, Corrupt: 1}) {
+		t.Errorf("ReadStats() = %+v", st)
+	}
+	if st := plain.ReadStats(); st != (ReadStats{Reads: 1}) {
+		t.Errorf("ReadStats() without VerifyReads = %+v", st)
+	}
+}
+
+func TestCategoryDomains(t *testing.T) {
+	ctx := context.Background()
+	domains, err := hash.NewCategoryDomains(map[string]string{"project": "proj"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline := hash.V1.WithCategoryDomains(domains)
+	b, err := InitFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewWithOptions(b, Options{Pipeline: pipeline, VerifyReads: true})
+	obj := testObject("k", "v")
+	h, err := s.Put(ctx, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := must(pipeline.ContentHash(obj)); h != want || h == must(hash.V1.ContentHash(obj)) {
+		t.Fatalf("Put = %s, want the domained hash %s", h, want)
+	}
+
+	// The blob is the hash input, so it verifies as plain SHA-256, and
+	// readers get the canonical bytes.
+	data, err := s.Get(ctx, h)
+	if err != nil {
+		t.Fatalf("verified Get: %v", err)
+	}
+	if want, _ := hash.CanonicalBytes(obj); string(data) != string(want) {
+		t.Errorf("Get = %s, want %s", data, want)
+	}
+	e, err := s.Resolve(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := e.Pipeline(); err != nil || p.Profile != pipeline.Profile {
+		t.Errorf("Pipeline of a domained stamp = %v, %v", p, err)
+	}
+	r, err := b.Check(ctx)
+	if err != nil || len(r.Problems) != 0 {
+		t.Errorf("Check = %+v, %v", r, err)
+	}
+	if ok, err := b.blobIntact(h); !ok || err != nil {
+		t.Errorf("blobIntact = %v, %v", ok, err)
+	}
+	if vs, err := s.History(ctx, "k"); err != nil || len(vs) != 1 {
+		t.Errorf("History = %+v, %v", vs, err)
+	}
+}
+
+func must(h string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+type countingInstrumentation struct {
+	hash.NopInstrumentation
+	done, invalid atomic.Int64
+	lastCode      atomic.Value
+}
+
+func (c *countingInstrumentation) OnHashDone(hash.HashDone) { c.done.Add(1) }
+
+func (c *countingInstrumentation) OnValidationError(e hash.ValidationError) {
+	c.invalid.Add(1)
+	c.lastCode.Store(e.Code)
+}
+
+func TestInstrumentation(t *testing.T) {
+	ctx := context.Background()
+	in := &countingInstrumentation{}
+	s := NewWithOptions(NewMemory(), Options{Instrumentation: in, MaxCanonicalSize: 200})
+	if _, err := s.Put(ctx, testObject("a", "v")); err != nil {
+		t.Fatal(err)
+	}
+	bad := testObject("b", "v")
+	bad.CreatedAt = "2025-01-15T10:30:00Z"
+	if _, err := s.Put(ctx, bad); err == nil {
+		t.Fatal("stored an object with an invalid timestamp")
+	}
+	if _, err := s.Put(ctx, testObject("c", fmt.Sprintf("%0300d", 0))); err == nil {
+		t.Fatal("stored an object over the size limit")
+	}
+	// Measuring the size rejects both invalid objects before hashing.
+	if in.done.Load() != 1 {
+		t.Errorf("OnHashDone called %d times, want 1", in.done.Load())
+	}
+	if in.invalid.Load() != 2 || in.lastCode.Load() != "CANON_ERR_TOO_LARGE" {
+		t.Errorf("OnValidationError called %d times, last %v", in.invalid.Load(), in.lastCode.Load())
+	}
+}
+
+func TestPutRequestWritesOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	var wg sync.WaitGroup
+	var writes atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, replayed, err := s.PutRequest(ctx, testObject("k", "v"), Any, "req")
+			if err != nil {
+				t.Error(err)
+			}
+			if !replayed {
+				writes.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := writes.Load(); n != 1 {
+		t.Errorf("%d deliveries wrote, want 1", n)
+	}
+	if _, _, err := s.PutRequest(ctx, testObject("k", "other"), Any, "req"); !errors.Is(err, ErrRequestMismatch) {
+		t.Errorf("reused request id: err = %v, want ErrRequestMismatch", err)
+	}
+}
+
+func TestPutRequestRememberedForWindow(t *testing.T) {
+	ctx := context.Background()
+	s := NewWithOptions(NewMemory(), Options{RequestTTL: time.Hour, MaxRequests: 2})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	first, _, err := s.PutRequest(ctx, testObject("k", "v"), Any, "req-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(ctx, testObject("k", "w")); err != nil {
+		t.Fatal(err)
+	}
+	// The retry is recognized though the key has been rewritten since.
+	h, replayed, err := s.PutRequest(ctx, testObject("k", "v"), Any, "req-1")
+	if err != nil || !replayed || h != first {
+		t.Errorf("retry after overwrite: %s, %v, %v; want %s replayed", h, replayed, err, first)
+	}
+	if _, _, err := s.PutRequest(ctx, testObject("other", "v"), Any, "req-1"); !errors.Is(err, ErrRequestMismatch) {
+		t.Errorf("request id reused for another key: err = %v, want ErrRequestMismatch", err)
+	}
+
+	// Past the window the request id is forgotten.
+	now = now.Add(time.Hour)
+	if _, replayed, err := s.PutRequest(ctx, testObject("k", "v"), Any, "req-1"); err != nil || replayed {
+		t.Errorf("retry after window: replayed %v, %v", replayed, err)
+	}
+
+	// Beyond MaxRequests the oldest request id is forgotten.
+	for _, id := range []string{"req-2", "req-3"} {
+		if _, _, err := s.PutRequest(ctx, testObject(id, "v"), Any, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Put(ctx, testObject("k", "w")); err != nil {
+		t.Fatal(err)
+	}
+	if _, replayed, err := s.PutRequest(ctx, testObject("k", "v"), Any, "req-1"); err != nil || replayed {
+		t.Errorf("retry of evicted request: replayed %v, %v", replayed, err)
+	}
+}
@@ -0,0 +1,397 @@
+// Package storetest checks that a store.Backend implementation behaves the
+// way store.Store relies on. A backend's tests call Run with a constructor
+// for empty instances:
+//
+//	func TestBackend(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Backend { return newEmptyBackend(t) })
+//	}
+package storetest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// Run runs the conformance tests as subtests of t. newBackend must return
+// a new, empty backend each time it is called.
+func Run(t *testing.T, newBackend func(t *testing.T) store.Backend) {
+	for _, tc := range []struct {
+		name string
+		fn   func(*testing.T, store.Backend)
+	}{
+		{"Blobs", testBlobs},
+		{"List", testList},
+		{"Keys", testKeys},
+		{"ListKeys", testListKeys},
+		{"KeyCategory", testKeyCategory},
+		{"KeyStamp", testKeyStamp},
+		{"KeyRequestID", testKeyRequestID},
+		{"KeyResidency", testKeyResidency},
+		{"KeyLegalHold", testKeyLegalHold},
+		{"DeleteKeyIf", testDeleteKeyIf},
+		{"ConcurrentSetKey", testConcurrentSetKey},
+		{"Canceled", testCanceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) { tc.fn(t, newBackend(t)) })
+	}
+}
+
+// blob returns test data and its content hash.
+func blob(s string) (string, []byte) {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:]), []byte(s)
+}
+
+func entry(key, h string) store.KeyEntry {
+	return store.KeyEntry{Key: key, Hash: h, UpdatedAt: "2025-01-15T10:30:00.000Z"}
+}
+
+func testBlobs(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h, data := blob("one")
+	if ok, err := b.Has(ctx, h); ok || err != nil {
+		t.Fatalf("Has on empty backend: %v, %v", ok, err)
+	}
+	if _, err := b.Get(ctx, h); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Get missing: %v", err)
+	}
+	if err := b.Put(ctx, h, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(ctx, h, data); err != nil {
+		t.Fatalf("second Put of the same blob: %v", err)
+	}
+	got, err := b.Get(ctx, h)
+	if err != nil || string(got) != "one" {
+		t.Fatalf("Get: %q, %v", got, err)
+	}
+	got[0] = 'X'
+	if again, _ := b.Get(ctx, h); string(again) != "one" {
+		t.Error("modifying a returned blob changed the stored one")
+	}
+	if ok, err := b.Has(ctx, h); !ok || err != nil {
+		t.Errorf("Has: %v, %v", ok, err)
+	}
+
+	if err := b.Delete(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := b.Has(ctx, h); ok {
+		t.Error("blob still present after Delete")
+	}
+	if err := b.Delete(ctx, h); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Delete missing: %v", err)
+	}
+}
+
+func testList(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	var all []string
+	for i := 0; i < 300; i++ {
+		h, data := blob(fmt.Sprint(i))
+		if err := b.Put(ctx, h, data); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, h)
+	}
+	got, err := b.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(all) {
+		t.Fatalf("List returned %d hashes, want %d", len(got), len(all))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i-1] >= got[i] {
+			t.Fatalf("List is not sorted at %d", i)
+		}
+	}
+
+	for _, prefix := range []string{all[0][:1], all[0][:2], all[0][:3], all[0]} {
+		var want []string
+		for _, h := range got {
+			if len(h) >= len(prefix) && h[:len(prefix)] == prefix {
+				want = append(want, h)
+			}
+		}
+		matched, err := b.List(ctx, prefix)
+		if err != nil || !reflect.DeepEqual(matched, want) {
+			t.Errorf("List(%q): %d hashes, %v; want %d", prefix, len(matched), err, len(want))
+		}
+	}
+	if none, err := b.List(ctx, "zz"); len(none) != 0 || err != nil {
+		t.Errorf("List with an unmatched prefix: %v, %v", none, err)
+	}
+}
+
+func testKeys(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h1, _ := blob("v1")
+	h2, _ := blob("v2")
+
+	if _, err := b.ResolveKey(ctx, "k"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("ResolveKey missing: %v", err)
+	}
+	if err := b.SetKey(ctx, entry("k", h1), h2); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("SetKey expecting a hash on a missing key: %v", err)
+	}
+	if err := b.SetKey(ctx, entry("k", h1), store.Absent); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := b.ResolveKey(ctx, "k"); err != nil || e != entry("k", h1) {
+		t.Errorf("ResolveKey: %+v, %v", e, err)
+	}
+	if err := b.SetKey(ctx, entry("k", h2), store.Absent); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("SetKey expecting Absent on an existing key: %v", err)
+	}
+	if err := b.SetKey(ctx, entry("k", h2), h2); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("SetKey with a stale expectation: %v", err)
+	}
+	if err := b.SetKey(ctx, entry("k", h2), h1); err != nil {
+		t.Errorf("SetKey with the current hash: %v", err)
+	}
+	if err := b.SetKey(ctx, entry("k", h1), store.Any); err != nil {
+		t.Errorf("SetKey with Any: %v", err)
+	}
+	if e, _ := b.ResolveKey(ctx, "k"); e.Hash != h1 {
+		t.Errorf("key holds %s, want %s", e.Hash, h1)
+	}
+
+	if err := b.DeleteKey(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ResolveKey(ctx, "k"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("ResolveKey after DeleteKey: %v", err)
+	}
+	if err := b.DeleteKey(ctx, "k"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteKey missing: %v", err)
+	}
+}
+
+func testListKeys(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h, _ := blob("v")
+	keys := []string{"notes/b", "notes/a", "todo/x", "notes/é", "n"}
+	for _, k := range keys {
+		if err := b.SetKey(ctx, entry(k, h), store.Any); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for prefix, want := range map[string][]string{
+		"":       {"n", "notes/a", "notes/b", "notes/é", "todo/x"},
+		"notes/": {"notes/a", "notes/b", "notes/é"},
+		"todo":   {"todo/x"},
+		"zz":     nil,
+	} {
+		entries, err := b.ListKeys(ctx, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Key)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ListKeys(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func testKeyStamp(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h, _ := blob("v")
+	stamped := entry("stamped", h)
+	stamped.Stamp = "spec/sha256/profile"
+	for _, e := range []store.KeyEntry{stamped, entry("unstamped", h)} {
+		if err := b.SetKey(ctx, e, store.Any); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e, err := b.ResolveKey(ctx, "stamped"); err != nil || e.Stamp != stamped.Stamp {
+		t.Errorf("ResolveKey: %+v, %v", e, err)
+	}
+	entries, err := b.ListKeys(ctx, "")
+	if err != nil || len(entries) != 2 || entries[0].Stamp != stamped.Stamp || entries[1].Stamp != "" {
+		t.Errorf("ListKeys: %+v, %v", entries, err)
+	}
+}
+
+func testKeyRequestID(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h, _ := blob("v")
+	e := entry("k", h)
+	e.RequestID = "req-1"
+	if err := b.SetKey(ctx, e, store.Any); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.ResolveKey(ctx, "k"); err != nil || got.RequestID != "req-1" {
+		t.Errorf("ResolveKey: %+v, %v", got, err)
+	}
+	// A write without a request id clears the previous one.
+	if err := b.SetKey(ctx, entry("k", h), store.Any); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := b.ListKeys(ctx, ""); err != nil || len(entries) != 1 || entries[0].RequestID != "" {
+		t.Errorf("ListKeys: %+v, %v", entries, err)
+	}
+}
+
+func testKeyResidency(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h, _ := blob("v")
+	e := entry("k", h)
+	e.Residency = "eu"
+	if err := b.SetKey(ctx, e, store.Any); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.ResolveKey(ctx, "k"); err != nil || got.Residency != "eu" {
+		t.Errorf("ResolveKey: %+v, %v", got, err)
+	}
+	if entries, err := b.ListKeys(ctx, ""); err != nil || len(entries) != 1 || entries[0].Residency != "eu" {
+		t.Errorf("ListKeys: %+v, %v", entries, err)
+	}
+}
+
+func testKeyLegalHold(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h, _ := blob("v")
+	e := entry("k", h)
+	e.LegalHold = "matter 7"
+	if err := b.SetKey(ctx, e, store.Any); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.ResolveKey(ctx, "k"); err != nil || got.LegalHold != "matter 7" {
+		t.Errorf("ResolveKey: %+v, %v", got, err)
+	}
+	if entries, err := b.ListKeys(ctx, ""); err != nil || len(entries) != 1 || entries[0].LegalHold != "matter 7" {
+		t.Errorf("ListKeys: %+v, %v", entries, err)
+	}
+}
+
+func testDeleteKeyIf(t *testing.T, b store.Backend) {
+	d, ok := b.(store.KeyDeleter)
+	if !ok {
+		return
+	}
+	ctx := context.Background()
+	h, _ := blob("v")
+	e := entry("k", h)
+	if err := b.SetKey(ctx, e, store.Absent); err != nil {
+		t.Fatal(err)
+	}
+	held := e
+	held.LegalHold = "matter 7"
+	if err := b.SetKey(ctx, held, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DeleteKeyIf(ctx, e); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("DeleteKeyIf of a changed entry: %v", err)
+	}
+	if _, err := b.ResolveKey(ctx, "k"); err != nil {
+		t.Errorf("ResolveKey after a refused DeleteKeyIf: %v", err)
+	}
+	if err := d.DeleteKeyIf(ctx, held); err != nil {
+		t.Errorf("DeleteKeyIf: %v", err)
+	}
+	if _, err := b.ResolveKey(ctx, "k"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("ResolveKey after DeleteKeyIf: %v", err)
+	}
+	if err := d.DeleteKeyIf(ctx, held); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteKeyIf of a missing key: %v", err)
+	}
+}
+
+func testKeyCategory(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	h, _ := blob("v")
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		e := entry(k, h)
+		e.Category = []string{"fact", "project"}[i%2]
+		if err := b.SetKey(ctx, e, store.Any); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e, err := b.ResolveKey(ctx, "b"); err != nil || e.Category != "project" {
+		t.Errorf("ResolveKey: %+v, %v", e, err)
+	}
+	if entries, err := b.ListKeys(ctx, "a"); err != nil || len(entries) != 1 || entries[0].Category != "fact" {
+		t.Errorf("ListKeys: %+v, %v", entries, err)
+	}
+
+	p, ok := b.(store.KeyPager)
+	if !ok {
+		return
+	}
+	for _, tc := range []struct {
+		q    store.KeyQuery
+		want []string
+	}{
+		{store.KeyQuery{Limit: 2}, []string{"a", "b"}},
+		{store.KeyQuery{After: "b", Limit: 2}, []string{"c", "d"}},
+		{store.KeyQuery{Category: "fact", Limit: 10}, []string{"a", "c", "e"}},
+		{store.KeyQuery{Category: "fact", After: "a", Limit: 1}, []string{"c"}},
+		{store.KeyQuery{Prefix: "d", Category: "project", Limit: 10}, []string{"d"}},
+		{store.KeyQuery{Prefix: "d", Category: "fact", Limit: 10}, nil},
+	} {
+		entries, err := p.ListKeysPage(ctx, tc.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Key)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ListKeysPage(%+v) = %q, want %q", tc.q, got, tc.want)
+		}
+	}
+}
+
+func testConcurrentSetKey(t *testing.T, b store.Backend) {
+	ctx := context.Background()
+	base, _ := blob("base")
+	if err := b.SetKey(ctx, entry("k", base), store.Absent); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var wins atomic.Int32
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, _ := blob(fmt.Sprint("writer", i))
+			err := b.SetKey(ctx, entry("k", h), base)
+			if err == nil {
+				wins.Add(1)
+			} else if !errors.Is(err, store.ErrConflict) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if wins.Load() != 1 {
+		t.Errorf("%d writers won the swap, want 1", wins.Load())
+	}
+}
+
+func testCanceled(t *testing.T, b store.Backend) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h, data := blob("x")
+	if err := b.Put(ctx, h, data); err == nil {
+		t.Error("Put succeeded with a canceled context")
+	}
+	if err := b.SetKey(ctx, entry("k", h), store.Any); err == nil {
+		t.Error("SetKey succeeded with a canceled context")
+	}
+}
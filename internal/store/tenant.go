@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// tenantsDir holds one complete store per tenant under a directory store.
+const tenantsDir = "tenants"
+
+// maxTenantLength bounds tenant ids, which become directory names.
+const maxTenantLength = 63
+
+// Errors for tenant scoping.
+var (
+	ErrInvalidTenant = errors.New("store: tenant ids are 1-63 characters of a-z, 0-9, '-' and '_', starting with a letter or digit")
+	// ErrTenantMismatch is returned when an object's tenant field names
+	// a tenant other than the store's.
+	ErrTenantMismatch = errors.New("store: object belongs to another tenant")
+	// ErrNoTenants is returned by Tenant on a backend that does not
+	// implement Tenanted.
+	ErrNoTenants = errors.New("store: backend does not support tenants")
+)
+
+// Tenanted is implemented by backends that keep a separate, isolated
+// backend per tenant. Tenant backends share nothing with each other or
+// with the parent, not even blobs with equal hashes, so no request
+// scoped to one tenant can read another's data.
+type Tenanted interface {
+	// Tenant returns the backend for tenant id, creating it if create is
+	// set and failing with ErrNotFound otherwise. Repeated calls return
+	// the same backend.
+	Tenant(ctx context.Context, id string, create bool) (Backend, error)
+	// Tenants returns the ids of all tenants, sorted.
+	Tenants(ctx context.Context) ([]string, error)
+}
+
+// ValidTenant reports whether id is a well-formed tenant id.
+func ValidTenant(id string) bool {
+	if id == "" || len(id) > maxTenantLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case (c == '-' || c == '_') && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Tenant returns the store scoped to tenant id, creating the tenant if it
+// does not exist. The tenant store has the same options as s, and its
+// reads count toward s.ReadStats.
+func (s *Store) Tenant(ctx context.Context, id string) (*Store, error) {
+	return s.tenant(ctx, id, true)
+}
+
+// OpenTenant is Tenant for an existing tenant; it fails with ErrNotFound
+// if the tenant has never been written to.
+func (s *Store) OpenTenant(ctx context.Context, id string) (*Store, error) {
+	return s.tenant(ctx, id, false)
+}
+
+func (s *Store) tenant(ctx context.Context, id string, create bool) (*Store, error) {
+	if !ValidTenant(id) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTenant, id)
+	}
+	if s.tenantID != "" {
+		return nil, fmt.Errorf("store: tenant %q cannot hold tenants", s.tenantID)
+	}
+	t, ok := s.b.(Tenanted)
+	if !ok {
+		return nil, ErrNoTenants
+	}
+	b, err := t.Tenant(ctx, id, create)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{b: b, opts: s.opts, tenantID: id, now: s.now, stats: s.stats, quota: s.quota, changeLock: s.changeLock, rules: s.rules, requests: s.requests}, nil
+}
+
+// TenantID returns the tenant s is scoped to, or "" for the default
+// namespace.
+func (s *Store) TenantID() string { return s.tenantID }
+
+// Tenants returns the ids of the store's tenants, sorted. A backend
+// without tenant support has none.
+func (s *Store) Tenants(ctx context.Context) ([]string, error) {
+	t, ok := s.b.(Tenanted)
+	if !ok || s.tenantID != "" {
+		return nil, nil
+	}
+	return t.Tenants(ctx)
+}
+
+// checkTenant rejects an object whose tenant field names a tenant other
+// than the store's. An object without a tenant may be stored anywhere.
+func (s *Store) checkTenant(tenant string) error {
+	if tenant != "" && tenant != s.tenantID {
+		if s.tenantID == "" {
+			return fmt.Errorf("%w: object is for tenant %q; store it in that tenant", ErrTenantMismatch, tenant)
+		}
+		return fmt.Errorf("%w: object is for tenant %q, not %q", ErrTenantMismatch, tenant, s.tenantID)
+	}
+	return nil
+}
+
+// tenantSet caches open tenant backends so that each tenant has exactly
+// one, whose locks then serialize all of its writers.
+type tenantSet[B Backend] struct {
+	mu   sync.Mutex
+	open map[string]B
+	// dir is the directory holding the tenants, or "" for in-memory
+	// backends.
+	dir string
+}
+
+// get returns the cached backend for id, or calls openFn with the
+// tenant's directory to open or create it.
+func (ts *tenantSet[B]) get(id string, openFn func(dir string) (B, error)) (B, error) {
+	var zero B
+	if !ValidTenant(id) {
+		return zero, fmt.Errorf("%w: %q", ErrInvalidTenant, id)
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if b, ok := ts.open[id]; ok {
+		return b, nil
+	}
+	b, err := openFn(filepath.Join(ts.dir, id))
+	if err != nil {
+		return zero, err
+	}
+	if ts.open == nil {
+		ts.open = make(map[string]B)
+	}
+	ts.open[id] = b
+	return b, nil
+}
+
+// ids returns the tenants in dir, or the open ones for in-memory
+// backends.
+func (ts *tenantSet[B]) ids() ([]string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	seen := make(map[string]bool, len(ts.open))
+	for id := range ts.open {
+		seen[id] = true
+	}
+	if ts.dir != "" {
+		entries, err := os.ReadDir(ts.dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() && ValidTenant(e.Name()) {
+				if _, err := os.Stat(filepath.Join(ts.dir, e.Name(), DescriptorName)); err == nil {
+					seen[e.Name()] = true
+				}
+			}
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidTenant(t *testing.T) {
+	for id, want := range map[string]bool{
+		"acme":                  true,
+		"team-7_b":              true,
+		"0":                     true,
+		"":                      false,
+		"-acme":                 false,
+		"Acme":                  false,
+		"a/b":                   false,
+		"..":                    false,
+		strings.Repeat("a", 63): true,
+		strings.Repeat("a", 64): false,
+	} {
+		if got := ValidTenant(id); got != want {
+			t.Errorf("ValidTenant(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range map[string]*Store{"fs": newTestStore(t), "memory": New(NewMemory())} {
+		t.Run(name, func(t *testing.T) {
+			acme, err := s.Tenant(ctx, "acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			globex, err := s.Tenant(ctx, "globex")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ha, err := acme.Put(ctx, testObject("k", "acme's"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			hg, err := globex.Put(ctx, testObject("k", "globex's"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if e, err := acme.Resolve(ctx, "k"); err != nil || e.Hash != ha {
+				t.Errorf("acme k: %+v, %v", e, err)
+			}
+			if e, err := globex.Resolve(ctx, "k"); err != nil || e.Hash != hg {
+				t.Errorf("globex k: %+v, %v", e, err)
+			}
+			if _, err := s.Resolve(ctx, "k"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("default namespace sees a tenant key: %v", err)
+			}
+			for _, other := range []*Store{s, globex} {
+				if ok, _ := other.Has(ctx, ha); ok {
+					t.Errorf("acme's object is visible outside acme")
+				}
+			}
+			if got, err := s.Tenants(ctx); err != nil || !reflect.DeepEqual(got, []string{"acme", "globex"}) {
+				t.Errorf("Tenants() = %v, %v", got, err)
+			}
+
+			if _, err := s.OpenTenant(ctx, "initech"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("OpenTenant of a missing tenant: %v", err)
+			}
+			if _, err := s.Tenant(ctx, "../x"); !errors.Is(err, ErrInvalidTenant) {
+				t.Errorf("Tenant with an invalid id: %v", err)
+			}
+			if _, err := acme.Tenant(ctx, "nested"); err == nil {
+				t.Error("a tenant opened a tenant of its own")
+			}
+
+			obj := testObject("j", "v")
+			obj.Tenant = "globex"
+			if _, err := acme.Put(ctx, obj); !errors.Is(err, ErrTenantMismatch) {
+				t.Errorf("Put of globex's object into acme: %v", err)
+			}
+			if _, err := s.Put(ctx, obj); !errors.Is(err, ErrTenantMismatch) {
+				t.Errorf("Put of globex's object into the default namespace: %v", err)
+			}
+			h, err := globex.Put(ctx, obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj.Tenant = ""
+			if plain, _ := globex.Put(ctx, obj); plain != h {
+				t.Error("the tenant field changed the content hash")
+			}
+		})
+	}
+}
+
+func TestTenantsSurviveReopen(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	acme, err := s.Tenant(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := acme.Put(ctx, testObject("k", "v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(s.Backend().(*FS).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Tenants(ctx); !reflect.DeepEqual(got, []string{"acme"}) {
+		t.Errorf("Tenants() after reopen = %v", got)
+	}
+	acme, err = s.OpenTenant(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := acme.Resolve(ctx, "k"); err != nil || e.Hash != h {
+		t.Errorf("acme k after reopen: %+v, %v", e, err)
+	}
+}
+
+func TestGatewayTenants(t *testing.T) {
+	s := newTestStore(t)
+	srv := httptest.NewServer(NewGateway(s, GatewayOptions{Writable: true, Tenants: true}))
+	defer srv.Close()
+
+	resp, body := putPath(t, srv, "/tenants/acme/keys/k", objectJSON("k", "v"))
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT into acme: %d %s", resp.StatusCode, body)
+	}
+	h := resp.Header.Get("X-Helios-Hash")
+	if loc := resp.Header.Get("Content-Location"); loc != "/tenants/acme/objects/"+h {
+		t.Errorf("Content-Location = %q", loc)
+	}
+
+	for path, want := range map[string]int{
+		"/tenants/acme/objects/" + h: 200,
+		"/tenants/acme/keys/k":       200,
+		"/objects/" + h:              404,
+		"/keys/k":                    404,
+		"/tenants/globex/keys/k":     404,
+		"/tenants/Globex/keys/k":     400,
+	} {
+		if resp, body := get(t, srv, path, nil); resp.StatusCode != want {
+			t.Errorf("GET %s: %d %s, want %d", path, resp.StatusCode, body, want)
+		}
+	}
+	if got, _ := s.Tenants(context.Background()); !reflect.DeepEqual(got, []string{"acme"}) {
+		t.Errorf("reads created tenants: %v", got)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(srv.URL + "/tenants/acme/objects/" + h[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusFound || loc != "/tenants/acme/objects/"+h {
+		t.Errorf("abbreviated GET: %d Location %q", resp.StatusCode, loc)
+	}
+
+	foreign := strings.Replace(objectJSON("j", "v"), `"key"`, `"tenant":"globex","key"`, 1)
+	resp, body = putPath(t, srv, "/tenants/acme/keys/j", foreign)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "STORE_ERR_TENANT_MISMATCH") {
+		t.Errorf("PUT of globex's object into acme: %d %s", resp.StatusCode, body)
+	}
+}
+
+func putPath(t *testing.T, srv *httptest.Server, path, body string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+path, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
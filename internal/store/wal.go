@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+// walDir holds the intent log: one file per mutation in progress.
+const walDir = "wal"
+
+// intent is a write-ahead record of a blob write followed by a key
+// update. It is made durable before either step and removed after both,
+// or after either fails.
+type intent struct {
+	Op    string   `json:"op"`
+	Hash  string   `json:"hash"`
+	Entry KeyEntry `json:"entry"`
+	// Prev is the key's entry when the intent was logged, with an empty
+	// Key if the key did not exist. Recovery completes the commit only
+	// if the key still holds it. Intents logged before Prev was recorded
+	// lack it.
+	Prev *KeyEntry `json:"prev,omitempty"`
+}
+
+// Recovery describes an interrupted mutation that OpenFS finished or
+// undid.
+type Recovery struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+	// Action is "completed" when the blob was intact and the key was
+	// pointed at it, "rolled back" when the blob was missing or torn and
+	// the key was left as it was, or "superseded" when the key had been
+	// written since and was left as it was.
+	Action string `json:"action"`
+}
+
+var intentSeq atomic.Int64
+
+func init() { intentSeq.Store(time.Now().UnixNano()) }
+
+// Commit implements Committer. It records an intent in the write-ahead
+// log, writes the blob, points the key at it, and removes the intent;
+// OpenFS finishes or undoes a commit that a crash interrupted. A commit
+// that fails part-way removes its intent, so that recovery does not
+// complete what the caller was told failed.
+func (b *FS) Commit(ctx context.Context, h string, data []byte, e KeyEntry, expected string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cur, err := b.ResolveKey(ctx, e.Key)
+	exists := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := CheckExpected(e.Key, cur, exists, expected); err != nil {
+		return err
+	}
+
+	path, err := b.writeIntent(intent{Op: "put", Hash: h, Entry: e, Prev: &cur})
+	if err != nil {
+		return fmt.Errorf("failed to write intent log: %w", err)
+	}
+	if err := b.apply(ctx, h, data, e); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// apply is the work an intent logs: it stores data under h and points
+// e.Key at it, durably.
+func (b *FS) apply(ctx context.Context, h string, data []byte, e KeyEntry) error {
+	if err := b.Put(ctx, h, data); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(b.objectPath(h))); err != nil {
+		return err
+	}
+	if err := b.writeKey(e); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(b.keyPath(e.Key)))
+}
+
+// writeIntent makes rec durable in the intent log and returns its path.
+// Intents are named by a sequence number, so recovery replays them in
+// the order they were written.
+func (b *FS) writeIntent(rec intent) (string, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(b.root, walDir, fmt.Sprintf("%020d.json", intentSeq.Add(1)))
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return "", err
+	}
+	return path, syncDir(filepath.Dir(path))
+}
+
+// pendingIntents returns the paths of the intents in the log, oldest
+// first.
+func (b *FS) pendingIntents() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(b.root, walDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, d := range entries {
+		if strings.HasSuffix(d.Name(), ".json") {
+			paths = append(paths, filepath.Join(b.root, walDir, d.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// recover finishes or undoes every mutation left in the intent log. A
+// commit whose blob is intact is completed, since its compare-and-swap
+// check passed before the crash, unless the key has been written since;
+// otherwise the torn blob is removed and the key keeps its old value.
+// Recovery holds the store's lock, which a commit in progress holds
+// until it removes its intent, so every intent it sees was abandoned.
+func (b *FS) recover() ([]Recovery, error) {
+	if paths, err := b.pendingIntents(); err != nil || len(paths) == 0 {
+		return nil, err
+	}
+	unlock, err := b.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	paths, err := b.pendingIntents()
+	if err != nil {
+		return nil, err
+	}
+	var done []Recovery
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return done, err
+		}
+		var rec intent
+		if err := json.Unmarshal(data, &rec); err != nil || rec.Op != "put" || !ValidHash(rec.Hash) {
+			return done, fmt.Errorf("corrupt intent %s", path)
+		}
+		r := Recovery{Key: rec.Entry.Key, Hash: rec.Hash, Action: "rolled back"}
+		switch ok, err := b.blobIntact(rec.Hash); {
+		case err != nil:
+			return done, err
+		case !ok:
+			if err := os.Remove(b.objectPath(rec.Hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return done, err
+			}
+		case !b.unchangedSince(rec):
+			r.Action = "superseded"
+		default:
+			if err := b.writeKey(rec.Entry); err != nil {
+				return done, err
+			}
+			r.Action = "completed"
+		}
+		if err := os.Remove(path); err != nil {
+			return done, err
+		}
+		done = append(done, r)
+	}
+	return done, nil
+}
+
+// unchangedSince reports whether rec's key still holds what it did when
+// rec was logged, or already holds rec's own entry, so that completing rec
+// overwrites nothing written after it. For an intent without Prev, a key
+// updated later than rec's entry was written after it.
+func (b *FS) unchangedSince(rec intent) bool {
+	cur, err := b.ResolveKey(context.Background(), rec.Entry.Key)
+	exists := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false
+	}
+	switch {
+	case exists && cur == rec.Entry:
+		return true
+	case rec.Prev == nil:
+		return !exists || cur.UpdatedAt <= rec.Entry.UpdatedAt
+	case rec.Prev.Key == "":
+		return !exists
+	}
+	return exists && cur == *rec.Prev
+}
+
+// blobIntact reports whether the blob for h exists and hashes to h.
+func (b *FS) blobIntact(h string) (bool, error) {
+	data, err := os.ReadFile(b.objectPath(h))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	return hash.Equal(h, hex.EncodeToString(sum[:])), nil
+}
+
+// Recovered returns the interrupted mutations that OpenFS finished or
+// undid.
+func (b *FS) Recovered() []Recovery { return b.recovered }
+
+// syncDir makes renames into dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, fs.ErrInvalid) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+// crashAfterBlob leaves s as a crash between writing an object's blob and
+// pointing its key at it would: the intent is logged and the blob, with
+// the bytes blob returns, is in place. It returns the object's hash.
+func crashAfterBlob(t *testing.T, s *Store, key, value string, blob func([]byte) []byte) string {
+	t.Helper()
+	b := s.Backend().(*FS)
+	canonical, err := hash.CanonicalBytes(testObject(key, value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(canonical)
+	h := hex.EncodeToString(sum[:])
+	e := KeyEntry{Key: key, Hash: h, UpdatedAt: "2025-01-16T08:00:00.000Z"}
+	prev, err := b.ResolveKey(context.Background(), key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	if _, err := b.writeIntent(intent{Op: "put", Hash: h, Entry: e, Prev: &prev}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(b.objectPath(h), blob(canonical)); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestRecoverCompletesIntactCommit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	root := s.Backend().(*FS).Root()
+	if _, err := s.Put(ctx, testObject("k", "old")); err != nil {
+		t.Fatal(err)
+	}
+	h := crashAfterBlob(t, s, "k", "new", func(b []byte) []byte { return b })
+
+	b, err := OpenFS(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Recovered(); len(got) != 1 || got[0].Action != "completed" || got[0].Hash != h {
+		t.Fatalf("Recovered() = %+v", got)
+	}
+	if e, err := b.ResolveKey(ctx, "k"); err != nil || e.Hash != h {
+		t.Errorf("key after recovery: %+v, %v; want %s", e, err, h)
+	}
+	if pending, _ := b.pendingIntents(); len(pending) != 0 {
+		t.Errorf("intent log still holds %v", pending)
+	}
+}
+
+func TestRecoverRollsBackTornBlob(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	root := s.Backend().(*FS).Root()
+	old, err := s.Put(ctx, testObject("k", "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := crashAfterBlob(t, s, "k", "new", func(b []byte) []byte { return b[:len(b)/2] })
+
+	b, err := OpenFS(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Recovered(); len(got) != 1 || got[0].Action != "rolled back" {
+		t.Fatalf("Recovered() = %+v", got)
+	}
+	if e, err := b.ResolveKey(ctx, "k"); err != nil || e.Hash != old {
+		t.Errorf("key after rollback: %+v, %v; want the old hash %s", e, err, old)
+	}
+	if ok, _ := b.Has(ctx, h); ok {
+		t.Error("torn blob was not removed")
+	}
+}
+
+func TestRecoverLeavesLaterWrite(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	root := s.Backend().(*FS).Root()
+	if _, err := s.Put(ctx, testObject("k", "old")); err != nil {
+		t.Fatal(err)
+	}
+	// The commit's intent outlived it, and the key was written again.
+	crashAfterBlob(t, s, "k", "new", func(b []byte) []byte { return b })
+	later, err := s.Put(ctx, testObject("k", "later"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := OpenFS(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Recovered(); len(got) != 1 || got[0].Action != "superseded" {
+		t.Fatalf("Recovered() = %+v", got)
+	}
+	if e, err := b.ResolveKey(ctx, "k"); err != nil || e.Hash != later {
+		t.Errorf("key after recovery: %+v, %v; want the later hash %s", e, err, later)
+	}
+}
+
+func TestFailedCommitRemovesIntent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	b := s.Backend().(*FS)
+	// A file where the key's shard directory belongs fails the key write.
+	shard := filepath.Dir(b.keyPath("k"))
+	if err := os.WriteFile(shard, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(ctx, testObject("k", "v")); err == nil {
+		t.Fatal("Put succeeded without a key directory")
+	}
+	if pending, _ := b.pendingIntents(); len(pending) != 0 {
+		t.Errorf("failed commit left intents %v", pending)
+	}
+}
+
+func TestOpenWaitsForCommitInProgress(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no flock")
+	}
+	ctx := context.Background()
+	s := newTestStore(t)
+	b := s.Backend().(*FS)
+	// Hold the lock as a commit in another process would, between logging
+	// its intent and removing it.
+	unlock, err := b.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := crashAfterBlob(t, s, "k", "v", func(b []byte) []byte { return b[:1] })
+	opened := make(chan *FS)
+	go func() {
+		other, err := OpenFS(b.Root())
+		if err != nil {
+			t.Error(err)
+		}
+		opened <- other
+	}()
+	select {
+	case <-opened:
+		t.Fatal("OpenFS recovered an intent while its commit held the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	pending, _ := b.pendingIntents()
+	for _, p := range pending {
+		os.Remove(p)
+	}
+	unlock()
+	if other := <-opened; other != nil && len(other.Recovered()) != 0 {
+		t.Errorf("Recovered() = %+v", other.Recovered())
+	}
+	if ok, _ := b.Has(ctx, h); !ok {
+		t.Error("the commit's blob was removed")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	b := s.Backend().(*FS)
+	var hashes []string
+	for _, key := range []string{"a", "b", "c"} {
+		h, err := s.Put(ctx, testObject(key, "v-"+key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, h)
+	}
+	r, err := b.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Objects != 3 || r.Keys != 3 || len(r.Problems) != 0 {
+		t.Fatalf("Check on a healthy store = %+v", r)
+	}
+
+	// Damage the store the ways a crash or a bad disk would.
+	if err := os.WriteFile(b.objectPath(hashes[0]), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(b.objectPath(hashes[1])); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetKey(ctx, KeyEntry{Key: "c", Hash: hashes[2], Stamp: "future/sha256/unknown"}, Any); err != nil {
+		t.Fatal(err)
+	}
+	// Bytes an encoder wrote with an escape the canonical form omits.
+	loose := []byte(`{"category":"project","value":"\u0041"}`)
+	sum := sha256.Sum256(loose)
+	looseHash := hex.EncodeToString(sum[:])
+	if err := b.Put(ctx, looseHash, loose); err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(filepath.Dir(b.objectPath(hashes[2])), ".tmp-123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = b.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]string{}
+	for _, p := range r.Problems {
+		kinds[p.Kind] = p.Hash
+	}
+	want := map[string]string{
+		ProblemCorruptObject: hashes[0],
+		ProblemDanglingKey:   hashes[1],
+		ProblemUnknownStamp:  hashes[2],
+		ProblemNotCanonical:  looseHash,
+		ProblemStrayTemp:     "",
+	}
+	if len(kinds) != len(want) || len(r.Problems) != len(want) {
+		t.Fatalf("Problems = %+v", r.Problems)
+	}
+	for kind, h := range want {
+		if got, ok := kinds[kind]; !ok || got != h {
+			t.Errorf("%s: got hash %q (reported %v), want %q", kind, got, ok, h)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package tabular writes reports as CSV or TSV, so that the results of the
+// analytical commands load straight into spreadsheets and BI tools.
+package tabular
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats are the formats of Write.
+var Formats = []string{"csv", "tsv"}
+
+// Table is a report of rows under a header of column names.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// New returns an empty table with the given columns.
+func New(header ...string) *Table {
+	return &Table{Header: header}
+}
+
+// Add appends a row, formatting each cell: strings as they are, floats in
+// the shortest form that round-trips, and anything else as fmt.Sprint
+// does. It panics if the row does not have a cell for every column.
+func (t *Table) Add(cells ...interface{}) {
+	if len(cells) != len(t.Header) {
+		panic(fmt.Sprintf("tabular: row of %d cells in a table of %d columns", len(cells), len(t.Header)))
+	}
+	row := make([]string, len(cells))
+	for i, c := range cells {
+		switch c := c.(type) {
+		case string:
+			row[i] = c
+		case float64:
+			row[i] = strconv.FormatFloat(c, 'f', -1, 64)
+		default:
+			row[i] = fmt.Sprint(c)
+		}
+	}
+	t.Rows = append(t.Rows, row)
+}
+
+// Write writes t to w in format, "csv" or "tsv", header first. Both are
+// written as RFC 4180 describes CSV, with a tab for the TSV delimiter:
+// a cell holding the delimiter, a quote, or a line break is quoted.
+func Write(w io.Writer, t *Table, format string) error {
+	cw := csv.NewWriter(w)
+	switch format {
+	case "csv":
+	case "tsv":
+		cw.Comma = '\t'
+	default:
+		return fmt.Errorf("unknown table format %q (want %s)", format, strings.Join(Formats, ", "))
+	}
+	cw.Write(t.Header)
+	cw.WriteAll(t.Rows)
+	return cw.Error()
+}
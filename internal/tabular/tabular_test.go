@@ -0,0 +1,38 @@
+package tabular
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tab := New("key", "count", "mean", "keep")
+	tab.Add("a", 3, 1.5, true)
+	tab.Add("b,\"c\"", 0, 2.0, false)
+	tab.Add("tab\there", -1, 0.125, false)
+
+	for _, tc := range []struct{ format, want string }{
+		{"csv", "key,count,mean,keep\na,3,1.5,true\n\"b,\"\"c\"\"\",0,2,false\ntab\there,-1,0.125,false\n"},
+		{"tsv", "key\tcount\tmean\tkeep\na\t3\t1.5\ttrue\n\"b,\"\"c\"\"\"\t0\t2\tfalse\n\"tab\there\"\t-1\t0.125\tfalse\n"},
+	} {
+		var b strings.Builder
+		if err := Write(&b, tab, tc.format); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tc.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tc.format, b.String(), tc.want)
+		}
+	}
+	if err := Write(&strings.Builder{}, tab, "xlsx"); err == nil {
+		t.Error("accepted an unknown format")
+	}
+}
+
+func TestAddPanicsOnShortRow(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Add accepted a row missing a cell")
+		}
+	}()
+	New("a", "b").Add("x")
+}
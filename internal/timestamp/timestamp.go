@@ -0,0 +1,389 @@
+// Package timestamp obtains and verifies RFC 3161 timestamp tokens over
+// Helios content hashes. A token proves that the hashed content existed no
+// later than the time asserted by the timestamping authority (TSA).
+//
+// Because a content hash is itself the SHA-256 of the canonical bytes, the
+// hash is used directly as the SHA-256 message imprint.
+package timestamp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+var (
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional,default:false"`
+}
+
+type timeStampResp struct {
+	Status asn1.RawValue
+	Token  asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional,default:false"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// Info describes a verified timestamp token.
+type Info struct {
+	GenTime      time.Time
+	SerialNumber *big.Int
+	Policy       asn1.ObjectIdentifier
+	Nonce        *big.Int
+	Signer       *x509.Certificate
+}
+
+// NewRequest returns a DER TimeStampReq for a SHA-256 digest with a random
+// nonce. The TSA is asked to include its certificate in the response.
+func NewRequest(digest []byte) (req []byte, nonce *big.Int, err error) {
+	if len(digest) != 32 {
+		return nil, nil, fmt.Errorf("expected a 32-byte SHA-256 digest, got %d bytes", len(digest))
+	}
+	nonce, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 63))
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err = asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: digest,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	return req, nonce, err
+}
+
+// Fetch requests a timestamp token for digest from the TSA at url and
+// returns the DER token. The token is checked to cover digest and echo the
+// request nonce; its signature is not checked here, see Verify.
+func Fetch(client *http.Client, url string, digest []byte) ([]byte, error) {
+	req, nonce, err := NewRequest(digest)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(url, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA %s returned HTTP %d", url, resp.StatusCode)
+	}
+
+	token, err := ParseResponse(data)
+	if err != nil {
+		return nil, err
+	}
+	info, err := parseTSTInfo(token)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, fmt.Errorf("TSA token covers a different digest")
+	}
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, fmt.Errorf("TSA token nonce does not match request")
+	}
+	return token, nil
+}
+
+// ParseResponse extracts the token from a DER TimeStampResp, failing if
+// the TSA did not grant the request.
+func ParseResponse(data []byte) ([]byte, error) {
+	var resp timeStampResp
+	if rest, err := asn1.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("invalid timestamp response: %w", err)
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("invalid timestamp response: trailing data")
+	}
+	var status int
+	if _, err := asn1.Unmarshal(resp.Status.Bytes, &status); err != nil {
+		return nil, fmt.Errorf("invalid timestamp response status: %w", err)
+	}
+	// 0 granted, 1 grantedWithMods
+	if status != 0 && status != 1 {
+		return nil, fmt.Errorf("TSA rejected the request (PKIStatus %d)", status)
+	}
+	if len(resp.Token.FullBytes) == 0 {
+		return nil, fmt.Errorf("TSA granted the request but returned no token")
+	}
+	return resp.Token.FullBytes, nil
+}
+
+// Verify checks that token is a valid timestamp over digest, signed by a
+// certificate chaining to roots and authorized for timestamping.
+func Verify(token, digest []byte, roots *x509.CertPool) (*Info, error) {
+	sd, err := parseSignedData(token)
+	if err != nil {
+		return nil, err
+	}
+	tst, err := parseTSTInfo(token)
+	if err != nil {
+		return nil, err
+	}
+	if !tst.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) {
+		return nil, fmt.Errorf("unsupported message imprint algorithm %v", tst.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	if !bytes.Equal(tst.MessageImprint.HashedMessage, digest) {
+		return nil, fmt.Errorf("timestamp covers %x, not %x", tst.MessageImprint.HashedMessage, digest)
+	}
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificates in token: %w", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected one signer, got %d", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+	signer := findSigner(si.SID, certs)
+	if signer == nil {
+		return nil, fmt.Errorf("signer certificate not included in token")
+	}
+	if err := checkSignerInfo(si, sd.EncapContentInfo.EContent, signer); err != nil {
+		return nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		if c != signer {
+			intermediates.AddCert(c)
+		}
+	}
+	if _, err := signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   tst.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}); err != nil {
+		return nil, fmt.Errorf("TSA certificate verification failed: %w", err)
+	}
+
+	return &Info{
+		GenTime:      tst.GenTime,
+		SerialNumber: tst.SerialNumber,
+		Policy:       tst.Policy,
+		Nonce:        tst.Nonce,
+		Signer:       signer,
+	}, nil
+}
+
+func parseSignedData(token []byte) (*signedData, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(token, &ci); err != nil {
+		return nil, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("timestamp token is not CMS SignedData")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("invalid SignedData: %w", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("timestamp token does not contain TSTInfo")
+	}
+	return &sd, nil
+}
+
+func parseTSTInfo(token []byte) (*tstInfo, error) {
+	sd, err := parseSignedData(token)
+	if err != nil {
+		return nil, err
+	}
+	var tst tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &tst); err != nil {
+		return nil, fmt.Errorf("invalid TSTInfo: %w", err)
+	}
+	return &tst, nil
+}
+
+func findSigner(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, c := range certs {
+			if bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+				return c
+			}
+		}
+		return nil
+	}
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, c := range certs {
+		if c.SerialNumber.Cmp(ias.Serial) == 0 && bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) {
+			return c
+		}
+	}
+	return nil
+}
+
+// checkSignerInfo verifies the signed attributes bind eContent and carry a
+// valid signature by cert.
+func checkSignerInfo(si signerInfo, eContent []byte, cert *x509.Certificate) error {
+	if len(si.SignedAttrs.Bytes) == 0 {
+		return fmt.Errorf("timestamp token has no signed attributes")
+	}
+	h, err := hashFor(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	var digest []byte
+	var contentTypeOK bool
+	for rest := si.SignedAttrs.Bytes; len(rest) > 0; {
+		var attr attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return fmt.Errorf("invalid signed attribute: %w", err)
+		}
+		switch {
+		case attr.Type.Equal(oidMessageDigest):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &digest); err != nil {
+				return fmt.Errorf("invalid message digest attribute: %w", err)
+			}
+		case attr.Type.Equal(oidContentType):
+			var ct asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &ct); err == nil && ct.Equal(oidTSTInfo) {
+				contentTypeOK = true
+			}
+		}
+	}
+	if !contentTypeOK {
+		return fmt.Errorf("signed attributes do not declare TSTInfo content")
+	}
+	hh := h.New()
+	hh.Write(eContent)
+	if !bytes.Equal(digest, hh.Sum(nil)) {
+		return fmt.Errorf("signed message digest does not match TSTInfo")
+	}
+
+	// The signature covers the attributes encoded as a SET, not with the
+	// implicit [0] tag they carry inside SignerInfo.
+	signed := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	hh = h.New()
+	hh.Write(signed)
+	sum := hh.Sum(nil)
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(pub, h, sum, si.Signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, sum, si.Signature) {
+			err = fmt.Errorf("bad ECDSA signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, signed, si.Signature) {
+			err = fmt.Errorf("bad Ed25519 signature")
+		}
+	default:
+		err = fmt.Errorf("unsupported TSA key type %T", pub)
+	}
+	if err != nil {
+		return fmt.Errorf("timestamp signature verification failed: %w", err)
+	}
+	return nil
+}
+
+func hashFor(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported digest algorithm %v", oid)
+}
@@ -0,0 +1,203 @@
+package timestamp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testGenTime = time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+// testTSA is a minimal RFC 3161 authority signing with a throwaway CA.
+type testTSA struct {
+	key   *ecdsa.PrivateKey
+	cert  *x509.Certificate
+	roots *x509.CertPool
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test root"},
+		NotBefore:             testGenTime.Add(-24 * time.Hour),
+		NotAfter:              testGenTime.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test tsa"},
+		NotBefore:    testGenTime.Add(-time.Hour),
+		NotAfter:     testGenTime.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return &testTSA{key: key, cert: cert, roots: roots}
+}
+
+func marshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func setOf(t *testing.T, v interface{}) asn1.RawValue {
+	return asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: marshal(t, v)}
+}
+
+// token builds a DER timestamp token over digest.
+func (a *testTSA) token(t *testing.T, digest []byte, nonce *big.Int) []byte {
+	t.Helper()
+	sha := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	eContent := marshal(t, tstInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+		MessageImprint: messageImprint{HashAlgorithm: sha, HashedMessage: digest},
+		SerialNumber:   big.NewInt(42),
+		GenTime:        testGenTime,
+		Nonce:          nonce,
+	})
+	sum := sha256.Sum256(eContent)
+	attrs := append(
+		marshal(t, attribute{Type: oidContentType, Values: setOf(t, oidTSTInfo)}),
+		marshal(t, attribute{Type: oidMessageDigest, Values: setOf(t, sum[:])})...)
+	signedAttrs := marshal(t, asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attrs})
+	attrSum := sha256.Sum256(signedAttrs)
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, attrSum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sd := signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha},
+		EncapContentInfo: encapContentInfo{EContentType: oidTSTInfo, EContent: eContent},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: a.cert.Raw},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: marshal(t, issuerAndSerial{Issuer: asn1.RawValue{FullBytes: a.cert.RawIssuer}, Serial: a.cert.SerialNumber})},
+			DigestAlgorithm:    sha,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrs},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+			Signature:          sig,
+		}},
+	}
+	return marshal(t, struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidSignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: marshal(t, sd)}})
+}
+
+func (a *testTSA) server(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req timeStampReq
+		if _, err := asn1.Unmarshal(body, &req); err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		status := marshal(t, struct{ Status int }{0})
+		resp := marshal(t, timeStampResp{
+			Status: asn1.RawValue{FullBytes: status},
+			Token:  asn1.RawValue{FullBytes: a.token(t, req.MessageImprint.HashedMessage, req.Nonce)},
+		})
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchVerifyRoundTrip(t *testing.T) {
+	tsa := newTestTSA(t)
+	srv := tsa.server(t)
+	digest := sha256.Sum256([]byte("canonical bytes"))
+
+	token, err := Fetch(srv.Client(), srv.URL, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := Verify(token, digest[:], tsa.roots)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !info.GenTime.Equal(testGenTime) {
+		t.Errorf("expected gen time %s, got %s", testGenTime, info.GenTime)
+	}
+	if info.SerialNumber.Int64() != 42 {
+		t.Errorf("expected serial 42, got %s", info.SerialNumber)
+	}
+}
+
+func TestVerifyRejectsOtherDigest(t *testing.T) {
+	tsa := newTestTSA(t)
+	digest := sha256.Sum256([]byte("a"))
+	other := sha256.Sum256([]byte("b"))
+	token := tsa.token(t, digest[:], nil)
+	if _, err := Verify(token, other[:], tsa.roots); err == nil || !strings.Contains(err.Error(), "timestamp covers") {
+		t.Errorf("expected digest mismatch, got %v", err)
+	}
+}
+
+func TestVerifyRejectsUntrustedTSA(t *testing.T) {
+	tsa := newTestTSA(t)
+	digest := sha256.Sum256([]byte("a"))
+	token := tsa.token(t, digest[:], nil)
+	if _, err := Verify(token, digest[:], newTestTSA(t).roots); err == nil {
+		t.Error("expected untrusted TSA to fail")
+	}
+}
+
+func TestVerifyRejectsTamperedToken(t *testing.T) {
+	tsa := newTestTSA(t)
+	digest := sha256.Sum256([]byte("a"))
+	token := tsa.token(t, digest[:], nil)
+	// Flip a bit in the serial number inside TSTInfo
+	i := strings.Index(string(token), "\x02\x01\x2a")
+	if i < 0 {
+		t.Fatal("serial number not found in token")
+	}
+	token[i+2] ^= 1
+	if _, err := Verify(token, digest[:], tsa.roots); err == nil || !strings.Contains(err.Error(), "message digest") {
+		t.Errorf("expected message digest mismatch, got %v", err)
+	}
+}
+
+func TestParseResponseRejection(t *testing.T) {
+	status := marshal(t, struct{ Status int }{2})
+	resp := marshal(t, struct{ Status asn1.RawValue }{asn1.RawValue{FullBytes: status}})
+	if _, err := ParseResponse(resp); err == nil || !strings.Contains(err.Error(), "PKIStatus 2") {
+		t.Errorf("expected rejection, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+// Package valuetree hashes a memory object's value as a Merkle tree over
+// its canonical leaves, so that single fields of a large structured value
+// can be disclosed with inclusion proofs while the rest stay redacted, and
+// every disclosure checks out against one published value digest.
+//
+// A leaf is a scalar, an empty object or array, or a binary value,
+// together with its path from the value's root. The tree is the RFC 6962
+// tree of package merkle over the leaves in path order; its root is the
+// value digest. Leaves may be salted, so that a proof's sibling hashes do
+// not let anyone confirm guesses at the redacted fields next to it.
+package valuetree
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/merkle"
+)
+
+// leafSaltLabel keys the HMAC that derives each leaf's salt from the
+// tree's salt.
+const leafSaltLabel = "helios-value-leaf:"
+
+// Leaf is one leaf of a value tree.
+type Leaf struct {
+	// Path locates the leaf from the value's root, as canon.ParsePath
+	// reads it: "$", "$.name", "$.items[2]", `$["key.with.dots"]`.
+	Path string
+	// Value is the leaf's canonical JSON.
+	Value []byte
+	// Salt is the leaf's salt, nil in an unsalted tree.
+	Salt []byte
+}
+
+// Tree is the Merkle tree of a value.
+type Tree struct {
+	leaves []Leaf
+	hashes []merkle.Hash
+}
+
+// Build returns the tree of v, which must canonicalize. With a salt, each
+// leaf is salted with HMAC-SHA256(salt, "helios-value-leaf:" + path); a
+// disclosure then reveals the salts of the disclosed leaves only. The
+// same value under two salts has unrelated digests.
+func Build(v interface{}, salt []byte) (*Tree, error) {
+	data, err := canon.CanonicalizeValue(v)
+	if err != nil {
+		return nil, err
+	}
+	// Reading the canonical form back gives the value with its strings
+	// and map keys normalized, as the content hash sees it.
+	norm, err := canon.ParseCanonical(data)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{}
+	if err := t.collect("$", norm); err != nil {
+		return nil, err
+	}
+	sort.Slice(t.leaves, func(i, j int) bool { return t.leaves[i].Path < t.leaves[j].Path })
+	t.hashes = make([]merkle.Hash, len(t.leaves))
+	for i := range t.leaves {
+		if salt != nil {
+			t.leaves[i].Salt = leafSalt(salt, t.leaves[i].Path)
+		}
+		t.hashes[i] = merkle.LeafHash(t.leaves[i].data())
+	}
+	return t, nil
+}
+
+func (t *Tree) collect(path string, v interface{}) error {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if _, ok, _ := canon.ParseBytes(x); !ok && len(x) > 0 {
+			for k, child := range x {
+				if err := t.collect(path+member(k), child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case []interface{}:
+		if len(x) > 0 {
+			for i, child := range x {
+				if err := t.collect(path+"["+strconv.Itoa(i)+"]", child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	data, err := canon.CanonicalizeValue(v)
+	if err != nil {
+		return err
+	}
+	t.leaves = append(t.leaves, Leaf{Path: path, Value: data})
+	return nil
+}
+
+// member returns the path step to the map member k: ".k" for names of
+// letters, digits, and '_' that do not start with a digit, and ["k"] in
+// JSON string syntax otherwise, so every path has one spelling.
+func member(k string) string {
+	plain := k != ""
+	for i, c := range k {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return "." + k
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(k)
+	return "[" + strings.TrimSuffix(b.String(), "\n") + "]"
+}
+
+func leafSalt(salt []byte, path string) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(leafSaltLabel + path))
+	return mac.Sum(nil)
+}
+
+// data returns the leaf's input to merkle.LeafHash: the 4-byte big-endian
+// length of the path, the path, the salt if any, and the canonical value.
+// The salt has a fixed length, so the fields cannot run together.
+func (l Leaf) data() []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(l.Path)))
+	out = append(out, l.Path...)
+	out = append(out, l.Salt...)
+	return append(out, l.Value...)
+}
+
+// Root returns the value digest, the hex root of the tree.
+func (t *Tree) Root() string {
+	r := merkle.Root(t.hashes)
+	return hex.EncodeToString(r[:])
+}
+
+// Leaves returns the tree's leaves in order.
+func (t *Tree) Leaves() []Leaf { return t.leaves }
+
+// Disclosure reveals some leaves of a value tree with their inclusion
+// proofs.
+type Disclosure struct {
+	// Root is the value digest the leaves are proven against.
+	Root string `json:"root" schema:"pattern=^[0-9a-f]{64}$"`
+	// Size is the number of leaves of the tree.
+	Size   int             `json:"size"`
+	Leaves []DisclosedLeaf `json:"leaves"`
+}
+
+// DisclosedLeaf is one revealed leaf.
+type DisclosedLeaf struct {
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+	// Salt is the leaf's salt in unpadded base64url, empty in an unsalted
+	// tree.
+	Salt  string `json:"salt,omitempty"`
+	Index int    `json:"index"`
+	// Proof holds the hex sibling hashes from the leaf to the root.
+	Proof []string `json:"proof"`
+}
+
+// ErrNoLeaves is returned by Disclose when no leaf is under any of the
+// paths.
+var ErrNoLeaves = errors.New("no leaves under the disclosed paths")
+
+// Disclose reveals every leaf at or under each of paths, which are given
+// as canon.ParsePath reads them; the others stay redacted.
+func (t *Tree) Disclose(paths ...string) (*Disclosure, error) {
+	prefixes := make([]string, len(paths))
+	for i, p := range paths {
+		segs, err := canon.ParsePath(p)
+		if err != nil {
+			return nil, err
+		}
+		prefixes[i] = spell(segs)
+	}
+	d := &Disclosure{Root: t.Root(), Size: len(t.leaves)}
+	for i, l := range t.leaves {
+		if !under(l.Path, prefixes) {
+			continue
+		}
+		proof, err := merkle.InclusionProof(t.hashes, i)
+		if err != nil {
+			return nil, err
+		}
+		dl := DisclosedLeaf{Path: l.Path, Value: l.Value, Index: i, Proof: make([]string, len(proof))}
+		if l.Salt != nil {
+			dl.Salt = base64.RawURLEncoding.EncodeToString(l.Salt)
+		}
+		for j, h := range proof {
+			dl.Proof[j] = hex.EncodeToString(h[:])
+		}
+		d.Leaves = append(d.Leaves, dl)
+	}
+	if len(d.Leaves) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoLeaves, strings.Join(paths, ", "))
+	}
+	return d, nil
+}
+
+// spell returns the one spelling of a parsed path that leaf paths use.
+func spell(segs []canon.PathSegment) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, s := range segs {
+		if s.IsIndex {
+			b.WriteString("[" + strconv.Itoa(s.Index) + "]")
+		} else {
+			b.WriteString(member(canon.NormalizeString(s.Key)))
+		}
+	}
+	return b.String()
+}
+
+func under(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if path == p || p == "$" || strings.HasPrefix(path, p) && (path[len(p)] == '.' || path[len(p)] == '[') {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks every leaf of d against root, which must be d's root, and
+// that each leaf's value is canonical JSON and its path well formed. It
+// fails with merkle.ErrInvalidProof for a leaf that is not in the tree.
+func Verify(root string, d *Disclosure) error {
+	if !strings.EqualFold(root, d.Root) {
+		return fmt.Errorf("disclosure is against value digest %s, not %s", d.Root, root)
+	}
+	want, err := decodeHash(root)
+	if err != nil {
+		return fmt.Errorf("invalid value digest: %w", err)
+	}
+	if len(d.Leaves) == 0 {
+		return fmt.Errorf("disclosure reveals no leaves")
+	}
+	for _, dl := range d.Leaves {
+		segs, err := canon.ParsePath(dl.Path)
+		if err != nil {
+			return err
+		}
+		if spell(segs) != dl.Path {
+			return fmt.Errorf("leaf path %q is not in canonical form", dl.Path)
+		}
+		if err := canon.VerifyRoundTrip(dl.Value); err != nil {
+			return fmt.Errorf("leaf %s: value is not canonical JSON: %w", dl.Path, err)
+		}
+		l := Leaf{Path: dl.Path, Value: dl.Value}
+		if dl.Salt != "" {
+			if l.Salt, err = base64.RawURLEncoding.Strict().DecodeString(dl.Salt); err != nil || len(l.Salt) != sha256.Size {
+				return fmt.Errorf("leaf %s: invalid salt", dl.Path)
+			}
+		}
+		proof := make([]merkle.Hash, len(dl.Proof))
+		for i, s := range dl.Proof {
+			if proof[i], err = decodeHash(s); err != nil {
+				return fmt.Errorf("%w: leaf %s: proof entry %d: %v", merkle.ErrInvalidProof, dl.Path, i, err)
+			}
+		}
+		if err := merkle.VerifyInclusion(want, merkle.LeafHash(l.data()), dl.Index, d.Size, proof); err != nil {
+			return fmt.Errorf("leaf %s: %w", dl.Path, err)
+		}
+	}
+	return nil
+}
+
+func decodeHash(s string) (merkle.Hash, error) {
+	var h merkle.Hash
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(h) {
+		return h, fmt.Errorf("invalid hash %q", s)
+	}
+	copy(h[:], b)
+	return h, nil
+}
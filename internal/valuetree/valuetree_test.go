@@ -0,0 +1,149 @@
+package valuetree
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/merkle"
+)
+
+func testValue(t *testing.T) interface{} {
+	t.Helper()
+	v, err := ingest.Decode([]byte(`{
+		"name": "Ada",
+		"age": 36,
+		"dx": {"code": "E11.9", "notes": ["a", "b"]},
+		"scan": {"$bytes": "AQID"},
+		"empty": {},
+		"a.b": true
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestLeaves(t *testing.T) {
+	tree, err := Build(testValue(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range tree.Leaves() {
+		got = append(got, l.Path+"="+string(l.Value))
+	}
+	want := []string{
+		`$.age=36`,
+		`$.dx.code="E11.9"`,
+		`$.dx.notes[0]="a"`,
+		`$.dx.notes[1]="b"`,
+		`$.empty={}`,
+		`$.name="Ada"`,
+		`$.scan={"$bytes":"AQID"}`,
+		`$["a.b"]=true`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("leaves:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// A scalar value is a single leaf at the root.
+	scalar, err := Build("hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := scalar.Leaves(); len(l) != 1 || l[0].Path != "$" {
+		t.Errorf("scalar leaves = %+v", l)
+	}
+}
+
+func TestDiscloseVerify(t *testing.T) {
+	for _, salt := range [][]byte{nil, []byte(strings.Repeat("s", 32))} {
+		tree, err := Build(testValue(t), salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := tree.Root()
+		d, err := tree.Disclose("$.dx.notes", "$.age")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Leaves) != 3 {
+			t.Fatalf("disclosed %d leaves, want 3", len(d.Leaves))
+		}
+		if (d.Leaves[0].Salt != "") != (salt != nil) {
+			t.Errorf("salt %q disclosed for tree salt %q", d.Leaves[0].Salt, salt)
+		}
+		data, _ := json.Marshal(d)
+		if strings.Contains(string(data), "Ada") || strings.Contains(string(data), "E11.9") {
+			t.Errorf("redacted leaves appear in the disclosure: %s", data)
+		}
+		var back Disclosure
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(root, &back); err != nil {
+			t.Fatal(err)
+		}
+
+		back.Leaves[0].Value = json.RawMessage(`37`)
+		if err := Verify(root, &back); !errors.Is(err, merkle.ErrInvalidProof) {
+			t.Errorf("changed leaf value: got %v", err)
+		}
+		back.Leaves[0].Value = json.RawMessage(`36`)
+		back.Leaves[0].Path = "$.name"
+		if err := Verify(root, &back); !errors.Is(err, merkle.ErrInvalidProof) {
+			t.Errorf("moved leaf: got %v", err)
+		}
+		back.Leaves[0].Path = "$.age"
+		back.Leaves[0].Value = json.RawMessage(`36.0`)
+		if err := Verify(root, &back); err == nil {
+			t.Error("accepted a value that is not canonical JSON")
+		}
+	}
+}
+
+func TestRootDependsOnSaltAndContent(t *testing.T) {
+	v := testValue(t)
+	plain, err := Build(v, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salted, err := Build(v, []byte(strings.Repeat("s", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Root() == salted.Root() {
+		t.Error("salting does not change the digest")
+	}
+	v.(map[string]interface{})["name"] = "Bob"
+	changed, err := Build(v, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Root() == changed.Root() {
+		t.Error("changing a leaf does not change the digest")
+	}
+}
+
+func TestDisclosePaths(t *testing.T) {
+	tree, err := Build(testValue(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Either spelling of a member name selects it.
+	for _, p := range []string{`$["a.b"]`, `$.dx["code"]`} {
+		if d, err := tree.Disclose(p); err != nil || len(d.Leaves) != 1 {
+			t.Errorf("Disclose(%s) = %v", p, err)
+		}
+	}
+	// A path that is only a prefix of a member name selects nothing.
+	if _, err := tree.Disclose("$.dx.note"); !errors.Is(err, ErrNoLeaves) {
+		t.Errorf("prefix of a name: got %v", err)
+	}
+	if d, err := tree.Disclose("$"); err != nil || len(d.Leaves) != d.Size {
+		t.Errorf("Disclose($) = %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+// Package vector keeps vector embeddings of stored values for similarity
+// search. Embedders register an EmbedFunc by name; an Index computes the
+// embedding of each value written to a store in the background and
+// answers nearest-neighbor queries over the current object of every key.
+//
+// Embeddings are keyed by content hash, so identical values are embedded
+// once. They are an aid to finding things and nothing more: the content
+// hash stays authoritative, and a similarity score never stands in for
+// equality.
+package vector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/search"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// EmbedFunc computes the embedding of a memory value. value is decoded
+// from canonical JSON, with numbers as json.Number. All embeddings from
+// one function must have the same dimension.
+type EmbedFunc func(ctx context.Context, value interface{}) ([]float32, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]EmbedFunc{}
+)
+
+// Register makes an embedder available by name. It panics if the name is
+// taken, as registering two embedders under one name is a programming
+// error.
+func Register(name string, fn EmbedFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("vector: Register called twice for embedder " + name)
+	}
+	registry[name] = fn
+}
+
+// Lookup returns the embedder registered under name.
+func Lookup(name string) (EmbedFunc, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	fn, ok := registry[name]
+	return fn, ok
+}
+
+// Embedders returns the registered embedder names, sorted.
+func Embedders() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("bow", BagOfWords)
+}
+
+// bagOfWordsDim is the dimension of BagOfWords embeddings.
+const bagOfWordsDim = 256
+
+// BagOfWords is the built-in "bow" embedder: the words of the value's
+// canonical text, hashed into 256 signed buckets and normalized to unit
+// length. It captures shared vocabulary, not meaning, but needs no model.
+func BagOfWords(ctx context.Context, value interface{}) ([]float32, error) {
+	b, err := canon.CanonicalizeValue(value)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]float32, bagOfWordsDim)
+	for _, w := range search.Tokenize(string(b)) {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		sum := h.Sum32()
+		if sum&(1<<31) != 0 {
+			v[sum%bagOfWordsDim]--
+		} else {
+			v[sum%bagOfWordsDim]++
+		}
+	}
+	normalize(v)
+	return v, nil
+}
+
+func normalize(v []float32) {
+	var n float64
+	for _, x := range v {
+		n += float64(x) * float64(x)
+	}
+	if n == 0 {
+		return
+	}
+	n = math.Sqrt(n)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / n)
+	}
+}
+
+// cosine returns the cosine similarity of a and b, which must have the
+// same dimension.
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Options configures an Index.
+type Options struct {
+	// Embed computes embeddings. It is required.
+	Embed EmbedFunc
+	// Workers is the number of embeddings computed at once; 0 means 1.
+	Workers int
+}
+
+// entry is one journal line: a key pointed at a hash, a key removed, or
+// the embedding of a hash.
+type entry struct {
+	Op     string    `json:"op"`
+	Tenant string    `json:"tenant,omitempty"`
+	Key    string    `json:"key,omitempty"`
+	Hash   string    `json:"hash,omitempty"`
+	Vector []float32 `json:"vector,omitempty"`
+}
+
+// Journal operations.
+const (
+	opKey    = "key"
+	opRemove = "remove"
+	opVector = "vector"
+)
+
+type job struct {
+	hash  string
+	value interface{}
+}
+
+type keyRef struct {
+	tenant, key string
+}
+
+// Index holds the embeddings of a store's values and implements
+// store.Indexer and store.Similarity. Index records the key at once and
+// queues the embedding; Wait blocks until the queue is empty.
+//
+// Like the search index, it is a journal of JSON lines replayed by Open.
+type Index struct {
+	embed EmbedFunc
+
+	mu      sync.RWMutex
+	f       *os.File
+	keys    map[keyRef]string
+	vectors map[string][]float32
+	queued  map[string]bool
+	err     error
+
+	jobs    chan job
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+// Open opens the embedding journal at path, creating it if it does not
+// exist, and starts the workers. Keys whose embedding was still queued
+// when the journal was last closed have none until they are written
+// again or the index is rebuilt.
+func Open(path string, opts Options) (*Index, error) {
+	if opts.Embed == nil {
+		return nil, fmt.Errorf("vector: Options.Embed is required")
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	idx := &Index{
+		embed:   opts.Embed,
+		f:       f,
+		keys:    make(map[keyRef]string),
+		vectors: make(map[string][]float32),
+		queued:  make(map[string]bool),
+		jobs:    make(chan job, 256),
+	}
+	if err := idx.replay(path); err != nil {
+		f.Close()
+		return nil, err
+	}
+	n := opts.Workers
+	if n <= 0 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		idx.workers.Add(1)
+		go idx.work()
+	}
+	return idx, nil
+}
+
+// replay loads the journal, dropping a torn final line.
+func (idx *Index) replay(path string) error {
+	sc := bufio.NewScanner(idx.f)
+	sc.Buffer(nil, 64<<20)
+	var good int64
+	for line := 1; sc.Scan(); line++ {
+		var e entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			if sc.Scan() {
+				return fmt.Errorf("vector index %s: line %d: %w", path, line, err)
+			}
+			break
+		}
+		idx.apply(e)
+		good += int64(len(sc.Bytes())) + 1
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("vector index %s: %w", path, err)
+	}
+	fi, err := idx.f.Stat()
+	if err != nil {
+		return err
+	}
+	switch {
+	case fi.Size() > good:
+		return idx.f.Truncate(good)
+	case fi.Size() < good:
+		_, err = idx.f.Write([]byte("\n"))
+	}
+	return err
+}
+
+// apply updates the in-memory state; idx.mu must be held for writing, or
+// idx not yet shared.
+func (idx *Index) apply(e entry) {
+	ref := keyRef{e.Tenant, e.Key}
+	switch e.Op {
+	case opKey:
+		idx.keys[ref] = e.Hash
+	case opRemove:
+		delete(idx.keys, ref)
+	case opVector:
+		idx.vectors[e.Hash] = e.Vector
+	}
+}
+
+// write journals e and applies it; idx.mu must be held for writing.
+func (idx *Index) write(e entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := idx.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	idx.apply(e)
+	return nil
+}
+
+// Index records that key in tenant now holds hash and queues the
+// embedding of its value unless hash already has one.
+func (idx *Index) Index(ctx context.Context, tenant, key, hash string, canonical []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(canonical))
+	dec.UseNumber()
+	var obj struct {
+		Value interface{} `json:"value"`
+	}
+	if err := dec.Decode(&obj); err != nil {
+		return fmt.Errorf("vector: object is not valid JSON: %w", err)
+	}
+
+	idx.mu.Lock()
+	if err := idx.write(entry{Op: opKey, Tenant: tenant, Key: key, Hash: hash}); err != nil {
+		idx.mu.Unlock()
+		return err
+	}
+	_, have := idx.vectors[hash]
+	queue := !have && !idx.queued[hash]
+	if queue {
+		idx.queued[hash] = true
+		idx.pending.Add(1)
+	}
+	idx.mu.Unlock()
+
+	if queue {
+		idx.jobs <- job{hash: hash, value: obj.Value}
+	}
+	return nil
+}
+
+// Remove records that key in tenant was deleted. Its embedding is kept,
+// as other keys or later writes may share the hash.
+func (idx *Index) Remove(ctx context.Context, tenant, key string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.write(entry{Op: opRemove, Tenant: tenant, Key: key})
+}
+
+func (idx *Index) work() {
+	defer idx.workers.Done()
+	for j := range idx.jobs {
+		v, err := idx.embed(context.Background(), j.value)
+		idx.mu.Lock()
+		delete(idx.queued, j.hash)
+		if err == nil {
+			err = idx.write(entry{Op: opVector, Hash: j.hash, Vector: v})
+		}
+		if err != nil && idx.err == nil {
+			idx.err = fmt.Errorf("vector: embedding %s: %w", j.hash, err)
+		}
+		idx.mu.Unlock()
+		idx.pending.Done()
+	}
+}
+
+// Wait blocks until every queued embedding has been computed, and returns
+// the first embedding error since the last Wait.
+func (idx *Index) Wait() error {
+	idx.pending.Wait()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	err := idx.err
+	idx.err = nil
+	return err
+}
+
+// Close waits for queued embeddings, stops the workers, and closes the
+// journal.
+func (idx *Index) Close() error {
+	err := idx.Wait()
+	close(idx.jobs)
+	idx.workers.Wait()
+	if cerr := idx.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Stats reports the index's size.
+type Stats struct {
+	Keys     int `json:"keys"`
+	Embedded int `json:"embedded"`
+	Vectors  int `json:"vectors"`
+	Queued   int `json:"queued"`
+}
+
+// Stats returns the number of keys, keys with an embedding, distinct
+// embeddings, and embeddings still queued.
+func (idx *Index) Stats() Stats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	st := Stats{Keys: len(idx.keys), Vectors: len(idx.vectors), Queued: len(idx.queued)}
+	for _, h := range idx.keys {
+		if _, ok := idx.vectors[h]; ok {
+			st.Embedded++
+		}
+	}
+	return st
+}
+
+// Nearest returns the k keys of tenant whose embeddings are most similar
+// to query by cosine similarity, best first. Keys still waiting for an
+// embedding, or with one of another dimension, are skipped.
+func (idx *Index) Nearest(tenant string, query []float32, k int) []store.Match {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	var matches []store.Match
+	for ref, h := range idx.keys {
+		if ref.tenant != tenant {
+			continue
+		}
+		v, ok := idx.vectors[h]
+		if !ok || len(v) != len(query) {
+			continue
+		}
+		matches = append(matches, store.Match{Key: ref.key, Hash: h, Score: cosine(query, v)})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Key < matches[j].Key
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Similar embeds query as a string value and returns its k nearest keys
+// in tenant.
+func (idx *Index) Similar(ctx context.Context, tenant, query string, k int) ([]store.Match, error) {
+	v, err := idx.embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Nearest(tenant, v, k), nil
+}
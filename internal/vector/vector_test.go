@@ -0,0 +1,191 @@
+package vector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+func memory(key string, value interface{}) object.MemoryObject {
+	return object.MemoryObject{
+		Category:      "project",
+		CreatedAt:     "2025-01-15T10:30:00.000Z",
+		Key:           key,
+		Relationships: []object.Relationship{},
+		Source:        "user",
+		Value:         value,
+	}
+}
+
+func openIndex(t *testing.T, path string, fn EmbedFunc) *Index {
+	t.Helper()
+	idx, err := Open(path, Options{Embed: fn, Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestRegistry(t *testing.T) {
+	if fn, ok := Lookup("bow"); !ok || fn == nil {
+		t.Fatal(`"bow" is not registered`)
+	}
+	Register("test-registry", BagOfWords)
+	if _, ok := Lookup("test-registry"); !ok {
+		t.Error("registered embedder not found")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate Register did not panic")
+		}
+	}()
+	Register("bow", BagOfWords)
+}
+
+func TestBagOfWords(t *testing.T) {
+	ctx := context.Background()
+	a, _ := BagOfWords(ctx, "the error budget is spent")
+	b, _ := BagOfWords(ctx, "Error budget spent!")
+	c, _ := BagOfWords(ctx, "lunch order for friday")
+	if len(a) != bagOfWordsDim {
+		t.Fatalf("dimension %d", len(a))
+	}
+	if ab, ac := cosine(a, b), cosine(a, c); ab <= ac || ab < 0.5 {
+		t.Errorf("cosine(similar) = %.3f, cosine(unrelated) = %.3f", ab, ac)
+	}
+	if s := cosine(a, a); s < 0.999 {
+		t.Errorf("self-similarity %.3f", s)
+	}
+}
+
+func TestIndexMaintainedByStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vectors.jsonl")
+	calls := 0
+	counting := func(ctx context.Context, v interface{}) ([]float32, error) {
+		calls++
+		return BagOfWords(ctx, v)
+	}
+	idx, err := Open(path, Options{Embed: counting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Indexer: idx})
+	for key, value := range map[string]string{
+		"slo/api": "the error budget for the api is nearly spent",
+		"slo/web": "web error budget is healthy",
+		"lunch":   "lunch order for friday",
+		"copy":    "lunch order for friday",
+	} {
+		obj := memory(key, value)
+		if key == "copy" {
+			obj.Category = "fact"
+		}
+		if _, err := s.Put(ctx, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	acme, _ := s.Tenant(ctx, "acme")
+	if _, err := acme.Put(ctx, memory("slo/api", "error budget")); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 5 {
+		t.Errorf("embedded %d values, want 5", calls)
+	}
+
+	matches, err := idx.Similar(ctx, "", "error budget spent", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].Key != "slo/api" || matches[1].Key != "slo/web" {
+		t.Errorf("Similar = %+v", matches)
+	}
+	if e, _ := s.Resolve(ctx, "slo/api"); matches[0].Hash != e.Hash {
+		t.Error("match hash is not the key's current hash")
+	}
+	if m, _ := idx.Similar(ctx, "acme", "budget", 0); len(m) != 1 {
+		t.Errorf("tenant Similar = %+v", m)
+	}
+
+	if err := s.Delete(ctx, "slo/api"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	idx = openIndex(t, path, BagOfWords)
+	defer idx.Close()
+	if st := idx.Stats(); st.Keys != 4 || st.Embedded != 4 || st.Queued != 0 {
+		t.Errorf("Stats after reopen = %+v", st)
+	}
+	if m, _ := idx.Similar(ctx, "", "error budget spent", 1); len(m) != 1 || m[0].Key != "slo/web" {
+		t.Errorf("Similar after delete and reopen = %+v", m)
+	}
+}
+
+func TestEmbedErrors(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("model unavailable")
+	idx := openIndex(t, filepath.Join(t.TempDir(), "v.jsonl"), func(context.Context, interface{}) ([]float32, error) {
+		return nil, boom
+	})
+	defer idx.Close()
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Indexer: idx})
+	if _, err := s.Put(ctx, memory("k", "v")); err != nil {
+		t.Fatalf("a failing embedder failed the put: %v", err)
+	}
+	if err := idx.Wait(); !errors.Is(err, boom) {
+		t.Errorf("Wait() = %v", err)
+	}
+	if err := idx.Wait(); err != nil {
+		t.Errorf("second Wait() = %v", err)
+	}
+	if st := idx.Stats(); st.Keys != 1 || st.Embedded != 0 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+func TestGatewaySimilar(t *testing.T) {
+	ctx := context.Background()
+	idx := openIndex(t, filepath.Join(t.TempDir(), "v.jsonl"), BagOfWords)
+	defer idx.Close()
+	s := store.NewWithOptions(store.NewMemory(), store.Options{Indexer: idx})
+	s.Put(ctx, memory("a", "error budget"))
+	s.Put(ctx, memory("b", "lunch order"))
+	idx.Wait()
+
+	srv := httptest.NewServer(store.NewGateway(s, store.GatewayOptions{Similar: idx}))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/similar?q=budget&k=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var matches []store.Match
+	if err := json.Unmarshal(body, &matches); err != nil || resp.StatusCode != 200 || len(matches) != 1 || matches[0].Key != "a" {
+		t.Errorf("GET /similar: %d %s", resp.StatusCode, body)
+	}
+	for _, q := range []string{"", "?q=x&k=0", "?q=x&k=many"} {
+		resp, err := http.Get(srv.URL + "/similar" + q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET /similar%s: %d", q, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package verify
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NamedVectors is a vectors file and the path it was loaded from.
+type NamedVectors struct {
+	Path string
+	File *VectorsFile
+}
+
+// CoverageReport is the matrix of spec rules against the vectors and
+// tests that exercise them.
+type CoverageReport struct {
+	Rules []RuleCoverage `json:"rules"`
+	// Uncovered lists the rules no vector exercises. Tests alone do not
+	// cover a rule: only vectors are shared with other implementations.
+	Uncovered []string `json:"uncovered"`
+	// DeadVectors lists the vectors that exercise no registered rule.
+	DeadVectors []DeadVector `json:"dead_vectors"`
+}
+
+// RuleCoverage is one row of the matrix.
+type RuleCoverage struct {
+	Rule
+	Vectors []string `json:"vectors"`
+	Tests   []string `json:"tests"`
+}
+
+// DeadVector is a vector that exercises no registered rule.
+type DeadVector struct {
+	File     string `json:"file"`
+	VectorID string `json:"vector_id"`
+	Reason   string `json:"reason"`
+}
+
+// Coverage builds the coverage matrix of the registered rules from the
+// rule_coverage of the vectors in files and the rule mentions in tests,
+// a map from rule ID to test names as ScanTestRules returns.
+func Coverage(files []NamedVectors, tests map[string][]string) *CoverageReport {
+	vectors := make(map[string][]string)
+	known := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		known[r.ID] = true
+	}
+	rep := &CoverageReport{Uncovered: []string{}, DeadVectors: []DeadVector{}}
+	for _, f := range files {
+		for _, vec := range f.File.Vectors {
+			var unknown []string
+			covers := false
+			for _, id := range vec.RuleCoverage {
+				if known[id] {
+					vectors[id] = append(vectors[id], vec.VectorID)
+					covers = true
+				} else {
+					unknown = append(unknown, id)
+				}
+			}
+			switch {
+			case covers:
+			case len(unknown) > 0:
+				rep.DeadVectors = append(rep.DeadVectors, DeadVector{f.Path, vec.VectorID, "only unregistered rules: " + strings.Join(unknown, ", ")})
+			default:
+				rep.DeadVectors = append(rep.DeadVectors, DeadVector{f.Path, vec.VectorID, "no rule_coverage"})
+			}
+		}
+	}
+	for _, r := range rules {
+		rc := RuleCoverage{Rule: r, Vectors: vectors[r.ID], Tests: tests[r.ID]}
+		if rc.Vectors == nil {
+			rc.Vectors = []string{}
+			rep.Uncovered = append(rep.Uncovered, r.ID)
+		}
+		if rc.Tests == nil {
+			rc.Tests = []string{}
+		}
+		rep.Rules = append(rep.Rules, rc)
+	}
+	return rep
+}
+
+var ruleMention = regexp.MustCompile(`RULE-\d{3}`)
+
+// ScanTestRules finds the rules mentioned by the Go tests under root and
+// returns the names of the tests, as dir.TestName, mentioning each. A
+// test mentions a rule in its doc comment or in a comment in its body.
+// Section comments standing between declarations are not attributed: the
+// tests a section spans cannot be told from the layout of the file.
+func ScanTestRules(root string) (map[string][]string, error) {
+	found := make(map[string][]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		dir, _ := filepath.Rel(root, filepath.Dir(path))
+		scanFile(file, filepath.ToSlash(dir), found)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for id, tests := range found {
+		sort.Strings(tests)
+		found[id] = compactStrings(tests)
+	}
+	return found, nil
+}
+
+func scanFile(file *ast.File, dir string, found map[string][]string) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Test") {
+			continue
+		}
+		start := fn.Pos()
+		if fn.Doc != nil {
+			start = fn.Doc.Pos()
+		}
+		name := dir + "." + fn.Name.Name
+		for _, cg := range file.Comments {
+			if cg.Pos() < start || cg.End() > fn.End() {
+				continue
+			}
+			for _, id := range ruleMention.FindAllString(cg.Text(), -1) {
+				found[id] = append(found[id], name)
+			}
+		}
+	}
+}
+
+func compactStrings(s []string) []string {
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
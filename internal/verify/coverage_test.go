@@ -0,0 +1,93 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFrozenVectorsCoverEveryRule(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "test_vectors", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []NamedVectors
+	for _, path := range paths {
+		vf, err := LoadVectors(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vf.SpecVersion == "" {
+			continue
+		}
+		files = append(files, NamedVectors{Path: path, File: vf})
+	}
+	rep := Coverage(files, nil)
+	if len(rep.Uncovered) != 0 {
+		t.Errorf("rules without vectors: %v", rep.Uncovered)
+	}
+	if len(rep.DeadVectors) != 0 {
+		t.Errorf("dead vectors: %+v", rep.DeadVectors)
+	}
+	if len(rep.Rules) != len(Rules()) {
+		t.Errorf("report has %d rules, registry %d", len(rep.Rules), len(Rules()))
+	}
+}
+
+func TestCoverageFlagsDeadVectorsAndUncoveredRules(t *testing.T) {
+	vf := &VectorsFile{Vectors: []TestVector{
+		{VectorID: "A", RuleCoverage: []string{"RULE-001"}},
+		{VectorID: "B", RuleCoverage: []string{"RULE-999"}},
+		{VectorID: "C"},
+	}}
+	rep := Coverage([]NamedVectors{{Path: "v.json", File: vf}}, map[string][]string{"RULE-002": {"x.TestFloat"}})
+	want := []DeadVector{
+		{File: "v.json", VectorID: "B", Reason: "only unregistered rules: RULE-999"},
+		{File: "v.json", VectorID: "C", Reason: "no rule_coverage"},
+	}
+	if !reflect.DeepEqual(rep.DeadVectors, want) {
+		t.Errorf("dead vectors = %+v, want %+v", rep.DeadVectors, want)
+	}
+	if len(rep.Uncovered) != len(Rules())-1 || rep.Uncovered[0] != "RULE-002" {
+		t.Errorf("uncovered = %v", rep.Uncovered)
+	}
+	if got := rep.Rules[1].Tests; !reflect.DeepEqual(got, []string{"x.TestFloat"}) {
+		t.Errorf("RULE-002 tests = %v", got)
+	}
+}
+
+func TestScanTestRules(t *testing.T) {
+	root := t.TempDir()
+	src := `package p
+
+import "testing"
+
+// --- Floats (RULE-002) ---
+
+// TestA checks RULE-004.
+func TestA(t *testing.T) {
+	// Also RULE-006, twice: RULE-006.
+}
+
+func TestB(t *testing.T) {}
+
+func helper() {
+	// RULE-010
+}
+`
+	if err := os.MkdirAll(filepath.Join(root, "p"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "p", "p_test.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ScanTestRules(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"RULE-004": {"p.TestA"}, "RULE-006": {"p.TestA"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanTestRules = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,342 @@
+package verify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+// Kinds of VectorImpact.
+const (
+	// ImpactRehashed is a vector accepted under both profiles whose hash
+	// differs; NewHash is the hash under the new profile.
+	ImpactRehashed = "rehashed"
+	// ImpactRejected is a vector accepted under the old profile and
+	// rejected under the new one.
+	ImpactRejected = "rejected"
+	// ImpactAccepted is a vector rejected under the old profile and
+	// accepted under the new one.
+	ImpactAccepted = "accepted"
+	// ImpactChanges is a vector whose hash changes under the new profile
+	// to a value this build cannot compute.
+	ImpactChanges = "changes"
+	// ImpactMayChange is a vector whose hash may change under the new
+	// profile: it depends on a rule this build cannot evaluate.
+	ImpactMayChange = "may_change"
+)
+
+// RuleChange is a profile field that differs between two profiles.
+type RuleChange struct {
+	Rule   string `json:"rule"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Effect string `json:"effect"`
+}
+
+// VectorImpact is a vector a profile change affects.
+type VectorImpact struct {
+	VectorID string `json:"vector_id"`
+	Impact   string `json:"impact" schema:"enum=rehashed|rejected|accepted|changes|may_change"`
+	OldHash  string `json:"old_hash,omitempty"`
+	NewHash  string `json:"new_hash,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+
+	index int
+}
+
+// Evolution is the predicted effect of moving a vectors file from one
+// profile to another.
+type Evolution struct {
+	From  hash.Profile `json:"from"`
+	To    hash.Profile `json:"to"`
+	Rules []RuleChange `json:"rules"`
+	// Exact is set when this build has pipelines for both profiles, so
+	// every vector was hashed under both. Otherwise the new key policy is
+	// applied exactly and the other rule changes are predicted.
+	Exact   bool           `json:"exact"`
+	Checked int            `json:"checked"`
+	Vectors []VectorImpact `json:"vectors"`
+}
+
+// ProfileChanges lists the fields that differ between from and to, with
+// the effect each has on content hashes.
+func ProfileChanges(from, to hash.Profile) []RuleChange {
+	var rules []RuleChange
+	add := func(rule, old, new, effect string) {
+		if old != new {
+			rules = append(rules, RuleChange{Rule: rule, Old: old, New: new, Effect: effect})
+		}
+	}
+	add("spec_version", from.SpecVersion, to.SpecVersion, "the canonical serialization changed; every hash may change")
+	add("schema_version", from.SchemaVersion, to.SchemaVersion, "the memory object schema changed; every hash may change")
+	add("hash_algorithm", from.HashAlgorithm, to.HashAlgorithm, "the digest changed; every hash changes")
+	add("unicode_version", from.UnicodeVersion, to.UnicodeVersion, "the NFC tables changed; hashes of objects with non-ASCII text may change")
+	add("key_policy", from.KeyPolicy.String(), to.KeyPolicy.String(), "the map keys inside values are checked under another policy; objects may be rejected or accepted, but accepted hashes do not change")
+	add("category_domains", domainsString(from.CategoryDomains), domainsString(to.CategoryDomains), "the domain tags of categories changed; hashes of objects in the categories whose tag changed change")
+	add("extensions", strconv.FormatBool(from.Extensions), strconv.FormatBool(to.Extensions), "reserved keys and binary values are checked or no longer checked; objects may be rejected or accepted, but accepted hashes do not change")
+	return rules
+}
+
+// domainsString returns d as its JSON object.
+func domainsString(d hash.CategoryDomains) string {
+	if d == "" {
+		return "{}"
+	}
+	return string(d)
+}
+
+// Evolve predicts which vectors of vf a move from profile from to
+// profile to affects. Vectors are checked under the old profile's
+// pipeline, or taken at their frozen outcome if this build has none,
+// and under the new profile's pipeline, or the current pipeline with
+// the new key policy if this build has none, whose hashes the other
+// rule changes then qualify.
+func Evolve(vf *VectorsFile, from, to hash.Profile) (*Evolution, error) {
+	schemas, err := vf.schemas()
+	if err != nil {
+		return nil, err
+	}
+	evo := &Evolution{From: from, To: to, Rules: ProfileChanges(from, to), Checked: len(vf.Vectors), Vectors: []VectorImpact{}}
+	if len(evo.Rules) == 0 {
+		return evo, nil
+	}
+	oldP, _ := hash.LookupProfile(from)
+	newP, err := hash.LookupProfile(to)
+	evo.Exact = oldP != nil && err == nil
+	if err != nil {
+		if newP, err = hash.ForKeyPolicy(to.KeyPolicy); err != nil {
+			return nil, fmt.Errorf("key policy %q: %w", to.KeyPolicy, err)
+		}
+		if to.Extensions {
+			if newP, err = hash.WithExtensions(newP); err != nil {
+				return nil, err
+			}
+		}
+		newP = newP.WithCategoryDomains(to.CategoryDomains)
+	}
+	changed := make(map[string]bool)
+	for _, r := range evo.Rules {
+		changed[r.Rule] = true
+	}
+
+	for i, vec := range vf.Vectors {
+		var oldHash string
+		var oldErr error
+		if oldP != nil {
+			oldHash, oldErr = contentHashUnder(oldP, schemas, vec)
+		} else if vec.VectorType == "negative" {
+			oldErr = fmt.Errorf("frozen as rejected")
+		} else {
+			oldHash = vec.Hash
+		}
+		newHash, newErr := contentHashUnder(newP, schemas, vec)
+
+		imp := VectorImpact{VectorID: vec.VectorID, OldHash: oldHash, index: i}
+		switch {
+		case oldErr != nil && newErr != nil:
+			continue
+		case oldErr == nil && newErr != nil:
+			imp.Impact, imp.Reason = ImpactRejected, newErr.Error()
+		case oldErr != nil:
+			imp.Impact, imp.Reason = ImpactAccepted, oldErr.Error()
+			if evo.Exact {
+				imp.NewHash = newHash
+			}
+		case evo.Exact:
+			if hash.Equal(oldHash, newHash) {
+				continue
+			}
+			imp.Impact, imp.NewHash = ImpactRehashed, newHash
+		case changed["hash_algorithm"]:
+			imp.Impact, imp.Reason = ImpactChanges, "hash_algorithm changed"
+		case changed["category_domains"] && domainChanged(from, to, vec.Input):
+			imp.Impact, imp.Reason = ImpactChanges, "category domain tag changed"
+		case changed["spec_version"] || changed["schema_version"]:
+			imp.Impact, imp.Reason = ImpactMayChange, "spec or schema version changed"
+		case changed["unicode_version"] && !isASCIIValue(vec.Input):
+			imp.Impact, imp.Reason = ImpactMayChange, "non-ASCII text under new NFC tables"
+		default:
+			continue
+		}
+		evo.Vectors = append(evo.Vectors, imp)
+	}
+	return evo, nil
+}
+
+// domainChanged reports whether the category of input, a vector input,
+// has another domain tag under to than under from.
+func domainChanged(from, to hash.Profile, input interface{}) bool {
+	fields, _ := input.(map[string]interface{})
+	cat, _ := fields["category"].(string)
+	cat = canon.NormalizeString(cat)
+	oldTag, hadTag := from.CategoryDomains.Tags()[cat]
+	newTag, hasTag := to.CategoryDomains.Tags()[cat]
+	return hadTag != hasTag || oldTag != newTag
+}
+
+// contentHashUnder hashes vec's input under p.
+func contentHashUnder(p *hash.Pipeline, schemas []string, vec TestVector) (string, error) {
+	obj, err := inputToMemoryObject(vec.Input, schemas...)
+	if err != nil {
+		return "", err
+	}
+	return p.ContentHash(obj)
+}
+
+// isASCIIValue reports whether every string and map key in v is ASCII.
+func isASCIIValue(v interface{}) bool {
+	switch val := v.(type) {
+	case string:
+		return isASCII(val)
+	case map[string]interface{}:
+		for k, e := range val {
+			if !isASCII(k) || !isASCIIValue(e) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, e := range val {
+			if !isASCIIValue(e) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// MigrationNotes describes the evolution in Markdown: the rule changes,
+// the affected vectors, and what to do about each kind.
+func (e *Evolution) MigrationNotes() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Migration notes\n\nFrom profile %s to %s.\n\n## Rule changes\n\n", e.From.ID(), e.To.ID())
+	if len(e.Rules) == 0 {
+		b.WriteString("None: the profiles are identical, and no hash changes.\n")
+		return b.String()
+	}
+	for _, r := range e.Rules {
+		fmt.Fprintf(&b, "- %s: %q -> %q. %s.\n", r.Rule, r.Old, r.New, upperFirst(r.Effect))
+	}
+
+	how := "predicted: this build has no pipeline for one of the profiles, so only the key policy was applied exactly"
+	if e.Exact {
+		how = "exact: every vector was hashed under both profiles"
+	}
+	fmt.Fprintf(&b, "\n## Vectors\n\n%d of %d vectors affected (%s).\n", len(e.Vectors), e.Checked, how)
+	byImpact := make(map[string][]VectorImpact)
+	for _, v := range e.Vectors {
+		byImpact[v.Impact] = append(byImpact[v.Impact], v)
+	}
+	sections := []struct{ impact, title, action string }{
+		{ImpactRehashed, "Re-hashed", "Re-freeze them with the new hashes; the candidate vectors file does."},
+		{ImpactChanges, "Hash changes", "Re-freeze them with a build that implements the new profile."},
+		{ImpactMayChange, "Hash may change", "Verify them with a build that implements the new profile, and re-freeze those that fail."},
+		{ImpactRejected, "Newly rejected", "Turn them into negative vectors with the rejection code, or change their input; re-freezing cannot."},
+		{ImpactAccepted, "Newly accepted", "Turn them into positive vectors, or remove them if the rule they tested is gone."},
+	}
+	for _, s := range sections {
+		vs := byImpact[s.impact]
+		if len(vs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s (%d)\n\n%s\n\n", s.title, len(vs), s.action)
+		for _, v := range vs {
+			fmt.Fprintf(&b, "- %s", v.VectorID)
+			if v.NewHash != "" && v.OldHash != "" {
+				fmt.Fprintf(&b, ": %s -> %s", v.OldHash, v.NewHash)
+			}
+			if v.Reason != "" {
+				fmt.Fprintf(&b, " (%s)", v.Reason)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// Candidate returns the vectors file data moved to the new profile: the
+// re-hashed vectors re-frozen with their new hashes, canonical_json, and
+// canonical_input, recorded as a re-freeze with reason and the date of
+// now, and the key_policy, category_domains, and extensions members set to the new
+// profile's. Vectors the move rejects or accepts are left for the
+// migration notes to describe. It fails unless the evolution is exact,
+// since otherwise the new hashes are unknown.
+func (e *Evolution) Candidate(data []byte, reason string, now time.Time) ([]byte, error) {
+	if !e.Exact {
+		return nil, fmt.Errorf("this build cannot hash under the new profile, so a candidate vectors file cannot be computed")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+	vf, err := ParseVectors(data)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := vf.schemas()
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := hash.LookupProfile(e.To)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &Refreeze{Date: now.UTC().Format("2006-01-02"), Reason: reason, Vectors: []RefreezeChange{}}
+	refrozen := make(map[int]refrozenVector)
+	for _, v := range e.Vectors {
+		if v.Impact != ImpactRehashed {
+			continue
+		}
+		obj, err := inputToMemoryObject(vf.Vectors[v.index].Input, schemas...)
+		if err != nil {
+			return nil, fmt.Errorf("vector %q: %w", v.VectorID, err)
+		}
+		canonical, h, err := pipeline.Hash(obj)
+		if err != nil {
+			return nil, fmt.Errorf("vector %q: %w", v.VectorID, err)
+		}
+		refrozen[v.index] = refrozenVector{canonical: canonical, hash: h}
+		rf.Vectors = append(rf.Vectors, RefreezeChange{VectorID: v.VectorID, OldHash: vf.Vectors[v.index].Hash, NewHash: h})
+	}
+	out := data
+	if len(refrozen) > 0 {
+		if out, err = rewriteVectors(data, rf, refrozen); err != nil {
+			return nil, err
+		}
+	}
+	top, err := scanMembers(out, 0)
+	if err != nil {
+		return nil, err
+	}
+	var edits []edit
+	if e.From.KeyPolicy != e.To.KeyPolicy {
+		edits = append(edits, setMember(out, top, "key_policy", marshalRaw(string(e.To.KeyPolicy))))
+	}
+	if e.From.CategoryDomains != e.To.CategoryDomains {
+		edits = append(edits, setMember(out, top, "category_domains", []byte(domainsString(e.To.CategoryDomains))))
+	}
+	if e.From.Extensions != e.To.Extensions {
+		edits = append(edits, setMember(out, top, "extensions", marshalRaw(e.To.Extensions)))
+	}
+	return applyEdits(out, edits), nil
+}
@@ -0,0 +1,155 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+func impacts(evo *Evolution) map[string]int {
+	m := make(map[string]int)
+	for _, v := range evo.Vectors {
+		m[v.Impact]++
+	}
+	return m
+}
+
+func TestEvolveKeyPolicyIsExact(t *testing.T) {
+	path := filepath.Join("..", "..", "test_vectors", "key_policy_strict_vectors.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vf, err := ParseVectors(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evo, err := Evolve(vf, hash.V1Strict.Profile, hash.V1.Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !evo.Exact || len(evo.Rules) != 1 || evo.Rules[0].Rule != "key_policy" {
+		t.Fatalf("expected an exact key policy change, got %+v", evo)
+	}
+	got := impacts(evo)
+	if got[ImpactAccepted] == 0 || len(got) != 1 {
+		t.Errorf("relaxing the key policy should only accept vectors, got %v", got)
+	}
+	for _, v := range evo.Vectors {
+		if v.NewHash == "" {
+			t.Errorf("%s: accepted without a hash", v.VectorID)
+		}
+	}
+
+	back, err := Evolve(vf, hash.V1.Profile, hash.V1Strict.Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := impacts(back); got[ImpactRejected] != len(evo.Vectors) || len(got) != 1 {
+		t.Errorf("tightening the key policy back should reject what relaxing it accepted, got %v", got)
+	}
+
+	out, err := evo.Candidate(data, "relax key policy", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cand, err := ParseVectors(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cand.KeyPolicy != "" || len(cand.Refreezes) != len(vf.Refreezes) {
+		t.Errorf("candidate key policy %q, %d refreezes", cand.KeyPolicy, len(cand.Refreezes))
+	}
+	notes := evo.MigrationNotes()
+	if !strings.Contains(notes, "Newly accepted") || !strings.Contains(notes, "exact") {
+		t.Errorf("notes do not describe the change:\n%s", notes)
+	}
+}
+
+func TestEvolvePredictsRulesBeyondTheBuild(t *testing.T) {
+	vf := &VectorsFile{Vectors: []TestVector{
+		{VectorID: "ascii", VectorType: "positive", Input: vectorInput("plain"), Hash: "h1"},
+		{VectorID: "accent", VectorType: "positive", Input: vectorInput("café"), Hash: "h2"},
+	}}
+	unicode := hash.V1.Profile
+	unicode.UnicodeVersion = "99.0.0"
+	evo, err := Evolve(vf, hash.V1.Profile, unicode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evo.Exact || len(evo.Vectors) != 1 || evo.Vectors[0].VectorID != "accent" || evo.Vectors[0].Impact != ImpactMayChange {
+		t.Errorf("expected only the non-ASCII vector to be at risk, got %+v", evo.Vectors)
+	}
+	if _, err := evo.Candidate(nil, "upgrade", time.Now()); err == nil {
+		t.Error("a candidate was computed for a profile this build cannot hash under")
+	}
+
+	digest := hash.V1.Profile
+	digest.HashAlgorithm = "sha3-256"
+	evo, err = Evolve(vf, hash.V1.Profile, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := impacts(evo); got[ImpactChanges] != 2 {
+		t.Errorf("a digest change should change every hash, got %v", got)
+	}
+}
+
+func vectorInput(value string) map[string]interface{} {
+	return map[string]interface{}{
+		"_helios_schema_version": "1",
+		"category":               "note",
+		"created_at":             "2025-01-15T10:30:00.000Z",
+		"key":                    "k",
+		"relationships":          []interface{}{},
+		"source":                 "test",
+		"value":                  value,
+	}
+}
+
+func TestEvolveCategoryDomainsRefreezes(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "test_vectors", "key_policy_vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vf, err := ParseVectors(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := hash.V1.Profile
+	if to.CategoryDomains, err = hash.NewCategoryDomains(map[string]string{"key-policy": "kp"}); err != nil {
+		t.Fatal(err)
+	}
+	evo, err := Evolve(vf, hash.V1.Profile, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := impacts(evo); !evo.Exact || got[ImpactRehashed] != len(vf.Vectors) {
+		t.Fatalf("expected every vector re-hashed exactly, got %v", got)
+	}
+
+	out, err := evo.Candidate(data, "separate key policy hashes", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cand, err := ParseVectors(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cand.CategoryDomains != to.CategoryDomains {
+		t.Errorf("candidate domains %q, want %q", cand.CategoryDomains, to.CategoryDomains)
+	}
+	results, err := VerifyVectorsFile(cand, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if !r.Pass {
+			t.Errorf("%s fails under the candidate's domains", r.VectorID)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+//go:build mutation
+
+package verify
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+)
+
+// TestMutantsAreCaught runs the frozen vectors against every canonicalizer
+// mutant and fails for each one no vector catches. Run it with
+//
+//	go test -tags mutation -run TestMutants ./internal/verify
+func TestMutantsAreCaught(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "test_vectors", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*VectorsFile
+	for _, path := range paths {
+		vf, err := LoadVectors(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vf.SpecVersion != "" {
+			files = append(files, vf)
+		}
+	}
+	if failed := corpusFailures(files); len(failed) != 0 {
+		t.Fatalf("vectors fail without a mutant: %v", failed)
+	}
+	for _, m := range canon.Mutants() {
+		restore := canon.SetMutant(m)
+		failed := corpusFailures(files)
+		restore()
+		if len(failed) == 0 {
+			t.Errorf("mutant %s survived: no vector catches it", m)
+			continue
+		}
+		t.Logf("mutant %s killed by %d vectors, first %s", m, len(failed), failed[0])
+	}
+}
+
+// corpusFailures returns the IDs of the vectors that fail, counting a
+// vector the pipeline cannot hash at all as failing.
+func corpusFailures(files []*VectorsFile) []string {
+	var failed []string
+	for _, vf := range files {
+		pipeline, err := vf.pipeline()
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		schemas, _ := vf.schemas()
+		for _, vec := range vf.Vectors {
+			r, err := verifyVector(pipeline, schemas, vec)
+			if err != nil || !r.Pass {
+				failed = append(failed, vec.VectorID)
+			}
+		}
+	}
+	return failed
+}
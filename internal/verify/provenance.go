@@ -0,0 +1,84 @@
+package verify
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/holeyfield33-art/helios/internal/attest"
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+// Provenance records where a vectors file came from. The vectors are, in
+// effect, the spec: provenance says which generator and spec version
+// produced the expected hashes, when, and who vouched for them.
+type Provenance struct {
+	// Generator names the tool and version that computed the hashes,
+	// such as "helios 1.0.0".
+	Generator   string `json:"generator"`
+	SpecVersion string `json:"spec_version"`
+	Date        string `json:"date"`
+	Author      string `json:"author"`
+}
+
+// VectorsPayloadType is the DSSE payload type of a signed vectors file.
+const VectorsPayloadType = "application/vnd.helios.vectors+json"
+
+// StampProvenance returns the vectors file data with its provenance set
+// to p, leaving the rest of the file as it is.
+func StampProvenance(data []byte, p Provenance) ([]byte, error) {
+	top, err := scanMembers(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := top["vectors"]; !ok {
+		return nil, fmt.Errorf("vectors file has no vectors")
+	}
+	return applyEdits(data, []edit{setMember(data, top, "provenance", marshalRaw(p))}), nil
+}
+
+// SignVectors signs the exact bytes of a vectors file into a detached
+// DSSE envelope, conventionally stored next to it with a .sig suffix.
+func SignVectors(data []byte, signers ...signing.Signer) (*attest.Envelope, error) {
+	return attest.SignPayload(VectorsPayloadType, data, signers...)
+}
+
+// VerifyVectorsSignature checks that env is a valid signature by one of
+// keys over exactly data.
+func VerifyVectorsSignature(data []byte, env *attest.Envelope, keys ...crypto.PublicKey) error {
+	payload, err := attest.VerifyPayload(env, VectorsPayloadType, keys...)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(payload, data) {
+		return fmt.Errorf("the signature is over different contents than the vectors file")
+	}
+	return nil
+}
+
+// LoadSignedVectors is LoadVectors for a file whose expected hashes are
+// trusted only with a valid signature: it fails unless the envelope at
+// sigPath verifies over the file with one of keys.
+func LoadSignedVectors(path, sigPath string, keys ...crypto.PublicKey) (*VectorsFile, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one trusted key is required to check a vectors signature")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vectors file: %w", err)
+	}
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vectors signature: %w", err)
+	}
+	var env attest.Envelope
+	if err := json.Unmarshal(sig, &env); err != nil {
+		return nil, fmt.Errorf("invalid vectors signature %s: %w", sigPath, err)
+	}
+	if err := VerifyVectorsSignature(data, &env, keys...); err != nil {
+		return nil, fmt.Errorf("%s: %w", sigPath, err)
+	}
+	return ParseVectors(data)
+}
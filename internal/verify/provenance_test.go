@@ -0,0 +1,79 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/signing"
+)
+
+func TestSignedVectors(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := signing.NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(filepath.Join("..", "..", "test_vectors", "collation_vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov := Provenance{Generator: "helios test", SpecVersion: "1", Date: "2026-10-16", Author: "maintainers"}
+	data, err := StampProvenance(original, prov)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := bytes.Index(original, []byte(`"vectors": [`)); !bytes.HasSuffix(data, original[i:]) {
+		t.Error("stamping provenance reformatted the vectors")
+	}
+	if again, err := StampProvenance(data, prov); err != nil || !bytes.Equal(again, data) {
+		t.Errorf("re-stamping the same provenance changed the file: %v", err)
+	}
+
+	env, err := SignVectors(data, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path, sigPath := filepath.Join(dir, "v.json"), filepath.Join(dir, "v.json.sig")
+	sig, _ := json.Marshal(env)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sigPath, sig, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	vf, err := LoadSignedVectors(path, sigPath, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vf.Provenance == nil || *vf.Provenance != prov {
+		t.Errorf("provenance = %+v", vf.Provenance)
+	}
+	if _, err := VerifyVectorsFile(vf, Options{}); err != nil {
+		t.Errorf("signed vectors fail: %v", err)
+	}
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := LoadSignedVectors(path, sigPath, other); err == nil {
+		t.Error("vectors signed by another key were trusted")
+	}
+	if _, err := LoadSignedVectors(path, sigPath); err == nil {
+		t.Error("vectors were trusted without any key")
+	}
+	tampered := bytes.Replace(data, []byte(vf.Vectors[0].Hash), []byte(vf.Vectors[1].Hash), 1)
+	if err := os.WriteFile(path, tampered, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSignedVectors(path, sigPath, pub); err == nil {
+		t.Error("a tampered vectors file was trusted")
+	}
+}
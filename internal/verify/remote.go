@@ -0,0 +1,100 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+)
+
+// HashResponse is the body returned by a hash API endpoint: Hash on
+// success, Code and Error on rejection.
+type HashResponse struct {
+	Hash  string `json:"hash,omitempty" schema:"pattern=^[0-9a-f]{64}$"`
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// RejectionError reports that a remote endpoint rejected an input.
+type RejectionError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *RejectionError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("HTTP %d: %s", e.Status, e.Message)
+	}
+	return e.Code + ": " + e.Message
+}
+
+// RemoteHash sends input to the hash API at endpoint and returns the hash.
+//
+// A conforming endpoint accepts POST <endpoint>/hash with a memory object
+// as the JSON body and responds with 200 {"hash": "<64 hex>"} on success,
+// or a 4xx status with {"code": "CANON_ERR_...", "error": "<message>"}
+// when the input is rejected. Rejections are returned as *RejectionError;
+// any other error indicates a transport or protocol failure.
+func RemoteHash(client *http.Client, endpoint string, input map[string]interface{}) (string, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	url := strings.TrimRight(endpoint, "/") + "/hash"
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+
+	var hr HashResponse
+	if err := json.Unmarshal(data, &hr); err != nil {
+		return "", fmt.Errorf("invalid response from %s (HTTP %d): %w", url, resp.StatusCode, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		if len(hr.Hash) != 64 {
+			return "", fmt.Errorf("invalid response from %s: hash must be 64 hex characters, got %q", url, hr.Hash)
+		}
+		return hr.Hash, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return "", &RejectionError{Status: resp.StatusCode, Code: hr.Code, Message: hr.Error}
+	default:
+		return "", fmt.Errorf("unexpected HTTP %d from %s: %s", resp.StatusCode, url, hr.Error)
+	}
+}
+
+// verifyRemote checks a single vector against a remote hash API.
+// Transport failures are returned as errors; rejections are results.
+func verifyRemote(client *http.Client, endpoint string, vec TestVector) (VerifyResult, error) {
+	got, err := RemoteHash(client, endpoint, vec.Input)
+	rej, rejected := err.(*RejectionError)
+	if err != nil && !rejected {
+		return VerifyResult{}, fmt.Errorf("vector %q: %w", vec.VectorID, err)
+	}
+
+	if vec.VectorType == "negative" {
+		if !rejected {
+			return VerifyResult{Expected: "REJECT", Got: "ACCEPT (unexpected)", Pass: false}, nil
+		}
+		pass := vec.RejectionCode != nil && strings.Contains(rej.Error(), *vec.RejectionCode)
+		return VerifyResult{Expected: "REJECT", Got: rej.Error(), Pass: pass}, nil
+	}
+
+	if rejected {
+		return VerifyResult{Expected: vec.Hash, Got: rej.Error(), Pass: false}, nil
+	}
+	return VerifyResult{Expected: vec.Hash, Got: got, Pass: hash.Equal(vec.Hash, got)}, nil
+}
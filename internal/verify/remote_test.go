@@ -0,0 +1,108 @@
+package verify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// referenceServer implements the hash API using the local implementation.
+func referenceServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/hash" {
+			http.NotFound(w, r)
+			return
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		var input map[string]interface{}
+		if err := dec.Decode(&input); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		obj, err := inputToMemoryObject(input)
+		var h string
+		if err == nil {
+			h, err = hash.ContentHash(obj)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"code": canon.ErrorCode(err), "error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"hash": h})
+	}))
+}
+
+func TestRemoteVerifyAgainstReferenceServer(t *testing.T) {
+	srv := referenceServer(t)
+	defer srv.Close()
+
+	path := filepath.Join("..", "..", "test_vectors", "vectors.json")
+	results, err := VerifyVectorsWithOptions(path, Options{Endpoint: srv.URL, Workers: 4})
+	if err != nil {
+		t.Fatalf("reference server should pass all vectors: %v", err)
+	}
+	if len(results) != 17 {
+		t.Errorf("expected 17 results, got %d", len(results))
+	}
+}
+
+func TestRemoteVerifyAgainstGateway(t *testing.T) {
+	srv := httptest.NewServer(store.NewGateway(store.New(store.NewMemory()), store.GatewayOptions{}))
+	defer srv.Close()
+
+	path := filepath.Join("..", "..", "test_vectors", "vectors.json")
+	results, err := VerifyVectorsWithOptions(path, Options{Endpoint: srv.URL, Workers: 4})
+	if err != nil {
+		for _, r := range results {
+			if !r.Pass {
+				t.Errorf("%s: expected %s, got %s", r.VectorID, r.Expected, r.Got)
+			}
+		}
+		t.Fatalf("the gateway should pass all vectors: %v", err)
+	}
+	if len(results) != 17 {
+		t.Errorf("expected 17 results, got %d", len(results))
+	}
+}
+
+func TestRemoteVerifyDetectsWrongHash(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"hash": strings.Repeat("0", 64)})
+	}))
+	defer srv.Close()
+
+	path := filepath.Join("..", "..", "test_vectors", "vectors.json")
+	results, err := VerifyVectorsWithOptions(path, Options{Endpoint: srv.URL})
+	if err == nil {
+		t.Fatal("expected verification failure, got nil")
+	}
+	for _, r := range results {
+		if r.Pass {
+			t.Errorf("%s should fail against a server that accepts everything", r.VectorID)
+		}
+	}
+}
+
+func TestRemoteVerifyTransportFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"boom"}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join("..", "..", "test_vectors", "vectors.json")
+	if _, err := VerifyVectorsWithOptions(path, Options{Endpoint: srv.URL}); err == nil || !strings.Contains(err.Error(), "HTTP 500") {
+		t.Errorf("expected HTTP 500 error, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package verify
+
+// Rule is a normative rule of the canonical serialization spec. Vectors
+// name the rules they exercise in rule_coverage.
+type Rule struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Section string `json:"section"`
+}
+
+// rules is the registry of spec rules, in order. A rule is added here,
+// in the spec, and in at least one vector together.
+var rules = []Rule{
+	{"RULE-001", "_helios_schema_version is present and is the string \"1\"", "7.1"},
+	{"RULE-002", "Float values are rejected", "6"},
+	{"RULE-003", "String fields are NFC-normalized before serialization", "4"},
+	{"RULE-004", "Object keys sort by code point at every level", "3.1"},
+	{"RULE-005", "Timestamps are UTC with exactly millisecond precision", "5"},
+	{"RULE-006", "Canonical JSON is compact UTF-8 with minimal escaping", "3.2, 3.5"},
+	{"RULE-007", "The hash input is exactly the six included fields", "7"},
+	{"RULE-008", "Relationships sort by key, then type, as explicit maps", "8"},
+	{"RULE-009", "Integers are within signed 64-bit bounds", "6"},
+	{"RULE-010", "Null values are rejected", "3.3"},
+	{"RULE-011", "Booleans serialize as native JSON true and false", "3"},
+	{"RULE-012", "Map keys inside values satisfy the profile's key policy", "3.7"},
+	{"RULE-013", "Binary values are {\"$bytes\": ...} in unpadded base64url", "3.8"},
+	{"RULE-014", "Keys with a reserved prefix are rejected unless a registered extension", "3.9"},
+	{"RULE-015", "Schema version 2 relationships may carry an integer weight and string note", "8.3"},
+}
+
+// Rules returns the registry of spec rules, in order.
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
@@ -0,0 +1,183 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/holeyfield33-art/helios/internal/canon"
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+// UnicodeImpact reports how the NFC tables of the running build treat a
+// corpus hashed under other tables. Run from a build with upgraded
+// tables, Changed lists every hash the upgrade breaks; run from the
+// current build, it should be empty, and AtRisk lists the entries an
+// upgrade could break.
+type UnicodeImpact struct {
+	// Tables is the Unicode version of the build's NFC tables, Pinned
+	// the version the repository's vectors were frozen with.
+	Tables  string `json:"tables"`
+	Pinned  string `json:"pinned"`
+	Checked int    `json:"checked"`
+	// Changed lists the entries whose content hash the build's tables
+	// compute differently from the recorded one.
+	Changed []UnicodeChange `json:"changed"`
+	// AtRisk lists the entries with code points unassigned in the build's
+	// tables, whose normalization a later Unicode version may define.
+	AtRisk []UnicodeRisk `json:"at_risk"`
+}
+
+// UnicodeChange is an entry whose hash the build's tables change.
+type UnicodeChange struct {
+	// ID is the content hash of a stored object, or a vector_id.
+	ID   string   `json:"id"`
+	Keys []string `json:"keys,omitempty"`
+	Hash string   `json:"hash"`
+	// Rehash is the hash under the build's tables.
+	Rehash string `json:"rehash"`
+}
+
+// UnicodeRisk is an entry containing unassigned code points.
+type UnicodeRisk struct {
+	ID         string   `json:"id"`
+	Keys       []string `json:"keys,omitempty"`
+	CodePoints []string `json:"code_points"`
+}
+
+func newUnicodeImpact() *UnicodeImpact {
+	return &UnicodeImpact{
+		Tables:  canon.TablesVersion(),
+		Pinned:  canon.UnicodeVersion,
+		Changed: []UnicodeChange{},
+		AtRisk:  []UnicodeRisk{},
+	}
+}
+
+// StoreUnicodeImpact rehashes every object of s under the build's tables.
+// Objects are stored in canonical form, which is NFC under the tables
+// that stored them, so an object rehashes to its own hash unless the
+// build's tables normalize it differently. Objects whose bytes do not
+// match their hash fail with a *store.CorruptError: repair the store
+// first.
+func StoreUnicodeImpact(ctx context.Context, s *store.Store) (*UnicodeImpact, error) {
+	entries, err := s.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string][]string)
+	for _, e := range entries {
+		keys[e.Hash] = append(keys[e.Hash], e.Key)
+	}
+	hashes, err := s.Hashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newUnicodeImpact()
+	for _, h := range hashes {
+		data, err := s.Get(ctx, h)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", h, err)
+		}
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != h {
+			return nil, &store.CorruptError{Hash: h, Actual: actual}
+		}
+		obj, err := ingest.ParseObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", h, err)
+		}
+		rehash, err := hash.ContentHash(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", h, err)
+		}
+		r.Checked++
+		if rehash != h {
+			r.Changed = append(r.Changed, UnicodeChange{ID: h, Keys: keys[h], Hash: h, Rehash: rehash})
+		}
+		if cps := unassignedIn(data); len(cps) > 0 {
+			r.AtRisk = append(r.AtRisk, UnicodeRisk{ID: h, Keys: keys[h], CodePoints: cps})
+		}
+	}
+	return r, nil
+}
+
+// VectorsUnicodeImpact rehashes the positive vectors of vf under the
+// build's tables.
+func VectorsUnicodeImpact(vf *VectorsFile) (*UnicodeImpact, error) {
+	schemas, err := vf.schemas()
+	if err != nil {
+		return nil, err
+	}
+	r := newUnicodeImpact()
+	for _, vec := range vf.Vectors {
+		if vec.VectorType != "positive" {
+			continue
+		}
+		obj, err := inputToMemoryObject(vec.Input, schemas...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vec.VectorID, err)
+		}
+		rehash, err := hash.ContentHash(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vec.VectorID, err)
+		}
+		r.Checked++
+		if rehash != vec.Hash {
+			r.Changed = append(r.Changed, UnicodeChange{ID: vec.VectorID, Hash: vec.Hash, Rehash: rehash})
+		}
+		input, err := json.Marshal(vec.Input)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vec.VectorID, err)
+		}
+		if cps := unassignedIn(input); len(cps) > 0 {
+			r.AtRisk = append(r.AtRisk, UnicodeRisk{ID: vec.VectorID, CodePoints: cps})
+		}
+	}
+	return r, nil
+}
+
+// unassignedIn returns the unassigned code points in the keys and strings
+// of a JSON document, as U+XXXX, sorted.
+func unassignedIn(data []byte) []string {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	seen := make(map[rune]bool)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			for _, r := range canon.UnassignedRunes(v) {
+				seen[r] = true
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]interface{}:
+			for k, e := range v {
+				walk(k)
+				walk(e)
+			}
+		}
+	}
+	walk(v)
+	runes := make([]rune, 0, len(seen))
+	for r := range seen {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	var out []string
+	for _, r := range runes {
+		out = append(out, fmt.Sprintf("%U", r))
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/object"
+	"github.com/holeyfield33-art/helios/internal/store"
+)
+
+func TestStoreUnicodeImpact(t *testing.T) {
+	ctx := context.Background()
+	b := store.NewMemory()
+	s := store.New(b)
+	obj := object.MemoryObject{Key: "plain", Value: "caf\u00e9", Category: "fact", Source: "s", CreatedAt: "2025-01-01T00:00:00.000Z"}
+	if _, err := s.Put(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	obj.Key, obj.Value = "risky", "x\u0378"
+	risky, err := s.Put(ctx, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An object stored by tables that did not compose e + U+0301: its
+	// bytes are canonical but for not being NFC under the build's tables.
+	obj.Key, obj.Value = "old", "caf\u00e9"
+	canonical, err := hash.CanonicalBytes(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := bytes.Replace(canonical, []byte("\u00e9"), []byte("e\u0301"), 1)
+	sum := sha256.Sum256(old)
+	oldHash := hex.EncodeToString(sum[:])
+	if err := b.Put(ctx, oldHash, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetKey(ctx, store.KeyEntry{Key: "old", Hash: oldHash}, store.Any); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := StoreUnicodeImpact(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Checked != 3 {
+		t.Errorf("checked %d objects, want 3", r.Checked)
+	}
+	if len(r.Changed) != 1 || r.Changed[0].Hash != oldHash || r.Changed[0].Keys[0] != "old" || r.Changed[0].Rehash == oldHash {
+		t.Errorf("changed = %+v", r.Changed)
+	}
+	if len(r.AtRisk) != 1 || r.AtRisk[0].ID != risky || r.AtRisk[0].CodePoints[0] != "U+0378" {
+		t.Errorf("at risk = %+v", r.AtRisk)
+	}
+
+	if err := b.Put(ctx, risky[:63]+"0", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	var corrupt *store.CorruptError
+	if _, err := StoreUnicodeImpact(ctx, s); !errors.As(err, &corrupt) {
+		t.Errorf("corrupt object: %v", err)
+	}
+}
+
+func TestVectorsUnicodeImpact(t *testing.T) {
+	vf, err := LoadVectors(filepath.Join("..", "..", "test_vectors", "collation_vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vf.UnicodeVersion == "" {
+		t.Error("collation vectors do not record a Unicode version")
+	}
+	r, err := VectorsUnicodeImpact(vf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Checked != len(vf.Vectors) || len(r.Changed) != 0 {
+		t.Errorf("report = %+v", r)
+	}
+}
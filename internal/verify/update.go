@@ -0,0 +1,281 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Refreeze records one intentional re-freeze of a vectors file.
+type Refreeze struct {
+	Date    string           `json:"date"`
+	Reason  string           `json:"reason"`
+	Vectors []RefreezeChange `json:"vectors"`
+}
+
+// RefreezeChange is a vector whose expected hash a re-freeze replaced.
+type RefreezeChange struct {
+	VectorID string `json:"vector_id"`
+	OldHash  string `json:"old_hash"`
+	NewHash  string `json:"new_hash"`
+}
+
+// ErrReasonRequired is returned by UpdateVectors without a reason.
+var ErrReasonRequired = errors.New("re-freezing vectors requires a reason")
+
+// UpdateVectors re-freezes the vectors file at path after an intentional
+// spec change: every positive vector whose hash no longer verifies gets
+// the hash, canonical_json, and canonical_input this build computes, and
+// the change is appended to the file's refreezes with reason and the
+// date of now. The rest of the file is kept as it is, down to the order
+// of its fields.
+//
+// Negative vectors cannot be re-frozen: one that fails means the rule it
+// tests changed, and must be edited by hand. UpdateVectors then fails
+// without writing anything, as it does when a positive vector can no
+// longer be hashed at all. It returns nil and leaves the file alone when
+// every vector passes.
+func UpdateVectors(path, reason string, now time.Time) (*Refreeze, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vectors file: %w", err)
+	}
+	vf, err := ParseVectors(data)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := vf.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	schemas, _ := vf.schemas()
+
+	rf := &Refreeze{Date: now.UTC().Format("2006-01-02"), Reason: reason}
+	refrozen := make(map[int]refrozenVector)
+	for i, vec := range vf.Vectors {
+		r, err := verifyVector(pipeline, schemas, vec)
+		if err != nil {
+			return nil, err
+		}
+		if r.Pass {
+			continue
+		}
+		if vec.VectorType == "negative" {
+			return nil, fmt.Errorf("vector %q: negative vectors cannot be re-frozen (got %s); edit it by hand", vec.VectorID, r.Got)
+		}
+		obj, err := inputToMemoryObject(vec.Input, schemas...)
+		if err != nil {
+			return nil, fmt.Errorf("vector %q: %w", vec.VectorID, err)
+		}
+		b, err := pipeline.CanonicalBytes(obj)
+		if err != nil {
+			return nil, fmt.Errorf("vector %q: %w", vec.VectorID, err)
+		}
+		refrozen[i] = refrozenVector{canonical: b, hash: r.Got}
+		rf.Vectors = append(rf.Vectors, RefreezeChange{VectorID: vec.VectorID, OldHash: vec.Hash, NewHash: r.Got})
+	}
+	if len(rf.Vectors) == 0 {
+		return nil, nil
+	}
+
+	out, err := rewriteVectors(data, rf, refrozen)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// refrozenVector is the new frozen form of a vector.
+type refrozenVector struct {
+	canonical []byte
+	hash      string
+}
+
+// rewriteVectors applies rf to the vectors file data, replacing the
+// frozen fields of the vectors indexed in refrozen. It splices the new
+// values into data rather than re-encoding it, so everything else keeps
+// its formatting.
+func rewriteVectors(data []byte, rf *Refreeze, refrozen map[int]refrozenVector) ([]byte, error) {
+	top, err := scanMembers(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	var edits []edit
+	vectors, ok := top["vectors"]
+	if !ok {
+		return nil, fmt.Errorf("vectors file has no vectors")
+	}
+	elems, err := scanElements(data, vectors.value)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range refrozen {
+		fields, err := scanMembers(data, elems[i])
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := fields["hash"]; ok {
+			edits = append(edits, edit{f.value, f.end, marshalRaw(v.hash)})
+		}
+		if f, ok := fields["canonical_json"]; ok {
+			edits = append(edits, edit{f.value, f.end, marshalRaw(string(v.canonical))})
+		}
+		if f, ok := fields["canonical_input"]; ok {
+			edits = append(edits, edit{f.value, f.end, indentAt(data, f.key, v.canonical)})
+		}
+	}
+	if f, ok := top["frozen_date"]; ok {
+		edits = append(edits, edit{f.value, f.end, marshalRaw(rf.Date)})
+	}
+	refreezes := []json.RawMessage{}
+	if f, ok := top["refreezes"]; ok {
+		if err := json.Unmarshal(data[f.value:f.end], &refreezes); err != nil {
+			return nil, fmt.Errorf("refreezes: %w", err)
+		}
+	}
+	edits = append(edits, setMember(data, top, "refreezes", marshalRaw(append(refreezes, marshalRaw(rf)))))
+	return applyEdits(data, edits), nil
+}
+
+// setMember returns the edit that sets the top-level member key of the
+// vectors file data to value: in place if it exists, or as a new member
+// on its own line before "vectors".
+func setMember(data []byte, top map[string]span, key string, value []byte) edit {
+	if f, ok := top[key]; ok {
+		return edit{f.value, f.end, indentAt(data, f.key, value)}
+	}
+	at := top["vectors"].key
+	text := append(marshalRaw(key), ": "...)
+	text = append(text, indentAt(data, at, value)...)
+	text = append(text, ",\n"...)
+	text = append(text, data[lineStart(data, at):at]...)
+	return edit{at, at, text}
+}
+
+// applyEdits returns data with the non-overlapping edits applied.
+func applyEdits(data []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte{}, data...)
+	for _, e := range edits {
+		out = append(out[:e.start], append(e.text, out[e.end:]...)...)
+	}
+	return out
+}
+
+// edit replaces data[start:end] with text.
+type edit struct {
+	start, end int
+	text       []byte
+}
+
+// span locates an object member in a document: the offset of its key and
+// the bounds of its value.
+type span struct {
+	key, value, end int
+}
+
+// scanMembers returns the members of the JSON object at data[at:].
+func scanMembers(data []byte, at int) (map[string]span, error) {
+	dec := json.NewDecoder(bytes.NewReader(data[at:]))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object at byte %d", at)
+	}
+	ms := make(map[string]span)
+	for dec.More() {
+		key := at + skipSpace(data[at:], int(dec.InputOffset()))
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value := at + skipSpace(data[at:], int(dec.InputOffset()))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		ms[t.(string)] = span{key: key, value: value, end: at + int(dec.InputOffset())}
+	}
+	return ms, nil
+}
+
+// scanElements returns the offsets of the elements of the JSON array at
+// data[at:].
+func scanElements(data []byte, at int) ([]int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data[at:]))
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		return nil, fmt.Errorf("expected a JSON array at byte %d", at)
+	}
+	var elems []int
+	for dec.More() {
+		elems = append(elems, at+skipSpace(data[at:], int(dec.InputOffset())))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+	}
+	return elems, nil
+}
+
+// skipSpace returns the offset of the first byte of data at or after i
+// that is neither whitespace nor a separator.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && strings.IndexByte(" \t\r\n,:", data[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// lineStart returns the offset of the start of the line holding i.
+func lineStart(data []byte, i int) int {
+	return bytes.LastIndexByte(data[:i], '\n') + 1
+}
+
+// indentAt formats v to continue a line indented like the one at offset
+// i of data, two spaces per level.
+func indentAt(data []byte, i int, v []byte) []byte {
+	prefix := data[lineStart(data, i):i]
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, v, string(prefix), "  "); err != nil {
+		return v
+	}
+	return buf.Bytes()
+}
+
+// marshalRaw encodes v as the vectors files are written: HTML characters
+// are left alone, and characters that do not print, such as C1 controls,
+// are escaped so they stay visible.
+func marshalRaw(v interface{}) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		// Only strings and decoded JSON are encoded, which cannot fail.
+		panic(err)
+	}
+	var out []byte
+	for _, r := range string(bytes.TrimRight(buf.Bytes(), "\n")) {
+		switch {
+		case r < utf8.RuneSelf || unicode.IsPrint(r):
+			out = utf8.AppendRune(out, r)
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			out = fmt.Appendf(out, `\u%04x\u%04x`, r1, r2)
+		default:
+			out = fmt.Appendf(out, `\u%04x`, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// staleVectors copies vectors.json to a temporary file with the hash of
+// POS-002 replaced, as a spec change would leave it.
+func staleVectors(t *testing.T) (path string, original []byte, stale string) {
+	t.Helper()
+	original, err := os.ReadFile(filepath.Join("..", "..", "test_vectors", "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vf, err := ParseVectors(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale = vf.Vectors[1].Hash
+	path = filepath.Join(t.TempDir(), "vectors.json")
+	if err := os.WriteFile(path, bytes.Replace(original, []byte(stale), []byte(strings.Repeat("0", 64)), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, original, stale
+}
+
+func TestUpdateVectors(t *testing.T) {
+	path, original, want := staleVectors(t)
+	if _, err := UpdateVectors(path, " ", time.Now()); !errors.Is(err, ErrReasonRequired) {
+		t.Fatalf("UpdateVectors without a reason = %v", err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rf, err := UpdateVectors(path, "hash input now sorts relationships", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rf.Vectors) != 1 || rf.Vectors[0].VectorID != "POS-002" || rf.Vectors[0].NewHash != want || rf.Date != "2026-03-01" {
+		t.Fatalf("refreeze = %+v", rf)
+	}
+	if _, err := VerifyVectors(path); err != nil {
+		t.Fatalf("re-frozen vectors fail: %v", err)
+	}
+	updated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the metadata changed: the vectors themselves are byte for byte
+	// the originals, formatting included.
+	i := bytes.Index(original, []byte(`"vectors": [`))
+	if !bytes.HasSuffix(updated, original[i:]) {
+		t.Error("re-freezing reformatted the vectors")
+	}
+
+	vf, err := LoadVectors(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vf.Refreezes) != 1 || vf.Refreezes[0].Reason != "hash input now sorts relationships" {
+		t.Errorf("refreezes = %+v", vf.Refreezes)
+	}
+
+	if rf, err := UpdateVectors(path, "again", now); err != nil || rf != nil {
+		t.Errorf("UpdateVectors of passing vectors = %+v, %v", rf, err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(updated, []byte(`"hash": "`+want), []byte(`"hash": "`+strings.Repeat("1", 64)), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateVectors(path, "second change", now); err != nil {
+		t.Fatal(err)
+	}
+	if vf, err := LoadVectors(path); err != nil || len(vf.Refreezes) != 2 || vf.Refreezes[1].Reason != "second change" {
+		t.Errorf("second refreeze not appended: %+v, %v", vf, err)
+	}
+}
+
+func TestUpdateVectorsRefusesNegativeVectors(t *testing.T) {
+	path, _, _ := staleVectors(t)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(`"rejection_code": "CANON_ERR_NULL_PROHIBITED"`), []byte(`"rejection_code": "CANON_ERR_SOMETHING_ELSE"`), 1)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateVectors(path, "reason", time.Now()); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Fatalf("UpdateVectors = %v, want a negative vector error", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Error("a refused update wrote the file")
+	}
+}
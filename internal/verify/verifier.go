@@ -4,11 +4,15 @@ package verify
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/holeyfield33-art/helios/internal/canon"
 	"github.com/holeyfield33-art/helios/internal/hash"
+	"github.com/holeyfield33-art/helios/internal/ingest"
 	"github.com/holeyfield33-art/helios/internal/object"
 )
 
@@ -17,17 +21,78 @@ type TestVector struct {
 	VectorID        string                 `json:"vector_id"`
 	Description     string                 `json:"description"`
 	Input           map[string]interface{} `json:"input"`
-	Hash            string                 `json:"hash"`
-	VectorType      string                 `json:"vector_type"`
-	ExpectedOutcome string                 `json:"expected_outcome"`
+	Hash            string                 `json:"hash" schema:"nullable"`
+	VectorType      string                 `json:"vector_type" schema:"enum=positive|negative"`
+	ExpectedOutcome string                 `json:"expected_outcome" schema:"enum=ACCEPT|REJECT"`
 	RejectionCode   *string                `json:"rejection_code"`
+	// RuleCoverage names the spec rules the vector exercises; see Rules.
+	RuleCoverage []string `json:"rule_coverage" schema:"optional"`
 }
 
 // VectorsFile is the top-level structure of vectors.json.
 type VectorsFile struct {
-	SpecVersion    string       `json:"spec_version"`
-	VectorsVersion string       `json:"vectors_version"`
-	Vectors        []TestVector `json:"vectors"`
+	SpecVersion    string `json:"spec_version"`
+	VectorsVersion string `json:"vectors_version"`
+	// UnicodeVersion is the Unicode version of the NFC tables the vectors
+	// were hashed with, if recorded.
+	UnicodeVersion string `json:"unicode_version,omitempty"`
+	// Provenance, if recorded, says who generated the vectors and how.
+	Provenance *Provenance `json:"provenance,omitempty"`
+	// KeyPolicy is the key policy the vectors are verified under, empty
+	// for the permissive policy of version 1.
+	KeyPolicy string `json:"key_policy,omitempty"`
+	// CategoryDomains are the category domain tags the vectors are
+	// hashed under, if any.
+	CategoryDomains hash.CategoryDomains `json:"category_domains,omitempty"`
+	// Extensions records that the vectors are verified with the reserved
+	// keys and binary values of Sections 3.8 and 3.9 enabled.
+	Extensions bool `json:"extensions,omitempty"`
+	// SchemaVersion is the highest _helios_schema_version the vectors'
+	// objects may declare, empty for version 1 only.
+	SchemaVersion string `json:"schema_version,omitempty"`
+	// Refreezes records every intentional re-freeze of the file by
+	// UpdateVectors, oldest first.
+	Refreezes []Refreeze   `json:"refreezes,omitempty"`
+	Vectors   []TestVector `json:"vectors"`
+}
+
+// schemas returns the schema versions the vectors' objects may declare.
+func (vf *VectorsFile) schemas() ([]string, error) {
+	switch vf.SchemaVersion {
+	case "", object.SchemaV1:
+		return []string{object.SchemaV1}, nil
+	case object.SchemaV2:
+		return []string{object.SchemaV1, object.SchemaV2}, nil
+	}
+	return nil, fmt.Errorf("unknown schema_version %q (want 1 or 2)", vf.SchemaVersion)
+}
+
+// pipeline returns the pipeline the vectors are hashed with.
+func (vf *VectorsFile) pipeline() (*hash.Pipeline, error) {
+	if _, err := vf.schemas(); err != nil {
+		return nil, err
+	}
+	policy, err := canon.ParseKeyPolicy(vf.KeyPolicy)
+	if err != nil {
+		return nil, err
+	}
+	p, err := hash.ForKeyPolicy(policy)
+	if err == nil && vf.Extensions {
+		p, err = hash.WithExtensions(p)
+	}
+	if err != nil || vf.CategoryDomains == "" {
+		return p, err
+	}
+	return p.WithCategoryDomains(vf.CategoryDomains), nil
+}
+
+// Profile returns the profile the vectors are hashed under.
+func (vf *VectorsFile) Profile() (hash.Profile, error) {
+	p, err := vf.pipeline()
+	if err != nil {
+		return hash.Profile{}, err
+	}
+	return p.Profile, nil
 }
 
 // VerifyResult holds the result of verifying a single vector.
@@ -36,16 +101,45 @@ type VerifyResult struct {
 	Expected string
 	Got      string
 	Pass     bool
+
+	// VectorID is the vector_id from the vectors file and Index its
+	// position in the file. Results are always returned in file order.
+	VectorID string
+	Index    int
+
+	// Duration is the time spent verifying the vector and Size the length
+	// of its canonical bytes; Size is zero for vectors that were rejected
+	// or verified against an endpoint.
+	Duration time.Duration
+	Size     int
 }
 
-// VerifyVectors loads a vectors JSON file, computes the hash for each vector,
-// and compares to the expected hash. Returns an error if ANY vector mismatches.
-func VerifyVectors(path string) ([]VerifyResult, error) {
+// Options controls how vectors are verified.
+type Options struct {
+	// Workers is the number of vectors verified concurrently.
+	// Values below 2 verify sequentially.
+	Workers int
+
+	// Endpoint, when set, verifies a running service instead of the local
+	// implementation: each vector input is sent to the endpoint's hash API
+	// (see RemoteHash) and the returned hash or rejection is compared.
+	Endpoint string
+	// Client is used for Endpoint requests. Nil means a client with a
+	// 30 second timeout.
+	Client *http.Client
+}
+
+// LoadVectors reads and parses a vectors JSON file.
+func LoadVectors(path string) (*VectorsFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read vectors file: %w", err)
 	}
+	return ParseVectors(data)
+}
 
+// ParseVectors parses the contents of a vectors JSON file.
+func ParseVectors(data []byte) (*VectorsFile, error) {
 	dec := json.NewDecoder(strings.NewReader(string(data)))
 	dec.UseNumber()
 
@@ -53,74 +147,78 @@ func VerifyVectors(path string) ([]VerifyResult, error) {
 	if err := dec.Decode(&vf); err != nil {
 		return nil, fmt.Errorf("failed to parse vectors file: %w", err)
 	}
+	return &vf, nil
+}
 
-	results := make([]VerifyResult, 0, len(vf.Vectors))
-	var failures int
+// VerifyVectors loads a vectors JSON file, computes the hash for each vector,
+// and compares to the expected hash. Returns an error if ANY vector mismatches.
+func VerifyVectors(path string) ([]VerifyResult, error) {
+	return VerifyVectorsWithOptions(path, Options{})
+}
 
-	for _, vec := range vf.Vectors {
-		if vec.VectorType == "negative" {
-			// Negative vectors: expect an error during ingest or hashing
-			obj, err := inputToMemoryObject(vec.Input)
-			if err != nil {
-				// Correctly rejected at ingest
-				pass := vec.RejectionCode != nil && strings.Contains(err.Error(), *vec.RejectionCode)
-				results = append(results, VerifyResult{
-					Name:     vec.VectorID,
-					Expected: "REJECT",
-					Got:      err.Error(),
-					Pass:     pass,
-				})
-				if !pass {
-					failures++
-				}
-				continue
-			}
-			_, err = hash.ContentHash(obj)
-			if err != nil {
-				// Correctly rejected at hash time
-				pass := vec.RejectionCode != nil && strings.Contains(err.Error(), *vec.RejectionCode)
-				results = append(results, VerifyResult{
-					Name:     vec.VectorID,
-					Expected: "REJECT",
-					Got:      err.Error(),
-					Pass:     pass,
-				})
-				if !pass {
-					failures++
-				}
-				continue
-			}
-			// Should have been rejected but wasn't
-			results = append(results, VerifyResult{
-				Name:     vec.VectorID,
-				Expected: "REJECT",
-				Got:      "ACCEPT (unexpected)",
-				Pass:     false,
-			})
-			failures++
-			continue
-		}
+// VerifyVectorsWithOptions is VerifyVectors with explicit options. The
+// returned slice is in vectors-file order regardless of Workers.
+func VerifyVectorsWithOptions(path string, opts Options) ([]VerifyResult, error) {
+	vf, err := LoadVectors(path)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyVectorsFile(vf, opts)
+}
 
-		// Positive vectors: expect successful hashing with matching hash
-		obj, err := inputToMemoryObject(vec.Input)
-		if err != nil {
-			return nil, fmt.Errorf("vector %q: %w", vec.VectorID, err)
+// VerifyVectorsFile verifies already-loaded vectors; see
+// VerifyVectorsWithOptions.
+func VerifyVectorsFile(vf *VectorsFile, opts Options) ([]VerifyResult, error) {
+	pipeline, err := vf.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	schemas, _ := vf.schemas()
+	check := func(vec TestVector) (VerifyResult, error) {
+		return verifyVector(pipeline, schemas, vec)
+	}
+	if opts.Endpoint != "" {
+		client := opts.Client
+		if client == nil {
+			client = &http.Client{Timeout: 30 * time.Second}
 		}
-
-		got, err := hash.ContentHash(obj)
-		if err != nil {
-			return nil, fmt.Errorf("vector %q hash failed: %w", vec.VectorID, err)
+		check = func(vec TestVector) (VerifyResult, error) {
+			return verifyRemote(client, opts.Endpoint, vec)
 		}
+	}
 
-		pass := got == vec.Hash
-		results = append(results, VerifyResult{
-			Name:     vec.VectorID,
-			Expected: vec.Hash,
-			Got:      got,
-			Pass:     pass,
-		})
+	results := make([]VerifyResult, len(vf.Vectors))
+	errs := make([]error, len(vf.Vectors))
 
-		if !pass {
+	if opts.Workers < 2 {
+		for i, vec := range vf.Vectors {
+			results[i], errs[i] = timedVerify(i, vec, check)
+		}
+	} else {
+		var wg sync.WaitGroup
+		next := make(chan int)
+		for w := 0; w < opts.Workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					results[i], errs[i] = timedVerify(i, vf.Vectors[i], check)
+				}
+			}()
+		}
+		for i := range vf.Vectors {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	var failures int
+	for i, r := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if !r.Pass {
 			failures++
 		}
 	}
@@ -132,86 +230,65 @@ func VerifyVectors(path string) ([]VerifyResult, error) {
 	return results, nil
 }
 
-// inputToMemoryObject converts a raw JSON map into a MemoryObject.
-// Validates ingest rules: RULE-001 (schema version), RULE-002 (no floats), RULE-009 (integer range), RULE-010 (no nulls).
-func inputToMemoryObject(input map[string]interface{}) (object.MemoryObject, error) {
-	// RULE-001: schema version validation
-	if err := canon.ValidateSchemaVersion(input); err != nil {
-		return object.MemoryObject{}, err
-	}
+func timedVerify(index int, vec TestVector, check func(TestVector) (VerifyResult, error)) (VerifyResult, error) {
+	start := time.Now()
+	r, err := check(vec)
+	r.Name = vec.VectorID
+	r.VectorID = vec.VectorID
+	r.Index = index
+	r.Duration = time.Since(start)
+	return r, err
+}
 
-	// Ingest validation — check the raw parsed value for spec violations
-	if err := canon.ValidateIngestValue(input["value"]); err != nil {
-		return object.MemoryObject{}, err
+// verifyVector checks a single vector under pipeline, accepting objects of
+// the given schema versions. A non-nil error means the vector itself is
+// unusable (a positive vector that cannot be ingested).
+func verifyVector(pipeline *hash.Pipeline, schemas []string, vec TestVector) (VerifyResult, error) {
+	if vec.VectorType == "negative" {
+		// Negative vectors: expect an error during ingest or hashing
+		obj, err := inputToMemoryObject(vec.Input, schemas...)
+		if err == nil {
+			_, err = pipeline.ContentHash(obj)
+		}
+		if err != nil {
+			// Correctly rejected at ingest or hash time
+			pass := vec.RejectionCode != nil && strings.Contains(err.Error(), *vec.RejectionCode)
+			return VerifyResult{Expected: "REJECT", Got: err.Error(), Pass: pass}, nil
+		}
+		// Should have been rejected but wasn't
+		return VerifyResult{Expected: "REJECT", Got: "ACCEPT (unexpected)", Pass: false}, nil
 	}
 
-	obj := object.MemoryObject{}
-
-	if v, ok := input["category"].(string); ok {
-		obj.Category = v
-	}
-	if v, ok := input["created_at"].(string); ok {
-		obj.CreatedAt = v
-	}
-	if v, ok := input["key"].(string); ok {
-		obj.Key = v
-	}
-	if v, ok := input["source"].(string); ok {
-		obj.Source = v
+	// Positive vectors: expect successful hashing with matching hash
+	obj, err := inputToMemoryObject(vec.Input, schemas...)
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("vector %q: %w", vec.VectorID, err)
 	}
-	obj.Value = input["value"]
 
-	if rels, ok := input["relationships"].([]interface{}); ok {
-		for _, r := range rels {
-			if rm, ok := r.(map[string]interface{}); ok {
-				rel := object.Relationship{}
-				if k, ok := rm["key"].(string); ok {
-					rel.Key = k
-				}
-				if t, ok := rm["type"].(string); ok {
-					rel.Type = t
-				}
-				obj.Relationships = append(obj.Relationships, rel)
-			}
-		}
-	}
-	if _, exists := input["relationships"]; exists && obj.Relationships == nil {
-		obj.Relationships = []object.Relationship{}
+	canonical, got, err := pipeline.Hash(obj)
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("vector %q hash failed: %w", vec.VectorID, err)
 	}
 
-	if v, ok := input["updated_at"].(string); ok {
-		obj.UpdatedAt = v
-	}
-	if v, ok := input["version"]; ok {
-		switch vv := v.(type) {
-		case json.Number:
-			n, _ := vv.Int64()
-			obj.Version = int(n)
-		case float64:
-			obj.Version = int(vv)
-		}
-	}
-	if v, ok := input["access_count"]; ok {
-		switch vv := v.(type) {
-		case json.Number:
-			n, _ := vv.Int64()
-			obj.AccessCount = int(n)
-		case float64:
-			obj.AccessCount = int(vv)
-		}
+	return VerifyResult{Expected: vec.Hash, Got: got, Pass: hash.Equal(vec.Hash, got), Size: len(canonical)}, nil
+}
+
+// inputToMemoryObject converts a raw JSON map into a MemoryObject.
+// Validates ingest rules: RULE-001 (schema version, "1" unless schemas
+// lists the accepted versions), then those of ingest.ValidateInput.
+func inputToMemoryObject(input map[string]interface{}, schemas ...string) (object.MemoryObject, error) {
+	if len(schemas) == 0 {
+		schemas = []string{object.SchemaV1}
 	}
-	if v, ok := input["last_accessed"].(string); ok {
-		obj.LastAccessed = v
+	// RULE-001: schema version validation
+	if err := canon.ValidateSchemaVersionIn(input, schemas...); err != nil {
+		return object.MemoryObject{}, err
 	}
-	if v, ok := input["confidence"]; ok {
-		switch vv := v.(type) {
-		case json.Number:
-			f, _ := vv.Float64()
-			obj.Confidence = f
-		case float64:
-			obj.Confidence = vv
-		}
+
+	// Ingest validation — check the raw parsed value and relationships for spec violations
+	if err := ingest.ValidateInput(input); err != nil {
+		return object.MemoryObject{}, err
 	}
 
-	return obj, nil
+	return ingest.ToMemoryObject(input), nil
 }
@@ -129,3 +129,152 @@ func TestVerifierPassesOnCorrectHash(t *testing.T) {
 		t.Error("expected verification to pass")
 	}
 }
+
+func TestFrozenVectorsPass(t *testing.T) {
+	results, err := VerifyVectors(filepath.Join("..", "..", "test_vectors", "vectors.json"))
+	if err != nil {
+		t.Fatalf("frozen vectors should pass: %v", err)
+	}
+	if len(results) != 17 {
+		t.Errorf("expected 17 results, got %d", len(results))
+	}
+}
+
+func TestCollationVectorsPass(t *testing.T) {
+	results, err := VerifyVectors(filepath.Join("..", "..", "test_vectors", "collation_vectors.json"))
+	if err != nil {
+		t.Fatalf("collation vectors should pass: %v", err)
+	}
+	if len(results) != 7 {
+		t.Errorf("expected 7 results, got %d", len(results))
+	}
+}
+
+func TestMutationVectorsPass(t *testing.T) {
+	results, err := VerifyVectors(filepath.Join("..", "..", "test_vectors", "mutation_vectors.json"))
+	if err != nil {
+		t.Fatalf("mutation vectors should pass: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected 2 results, got %d", len(results))
+	}
+}
+
+func TestBytesVectorsPass(t *testing.T) {
+	results, err := VerifyVectors(filepath.Join("..", "..", "test_vectors", "bytes_vectors.json"))
+	if err != nil {
+		t.Fatalf("bytes vectors should pass: %v", err)
+	}
+	if len(results) != 7 {
+		t.Errorf("expected 7 results, got %d", len(results))
+	}
+}
+
+func TestReservedKeyVectorsPass(t *testing.T) {
+	results, err := VerifyVectors(filepath.Join("..", "..", "test_vectors", "reserved_key_vectors.json"))
+	if err != nil {
+		t.Fatalf("reserved key vectors should pass: %v", err)
+	}
+	if len(results) != 7 {
+		t.Errorf("expected 7 results, got %d", len(results))
+	}
+}
+
+func TestRelationshipAttrVectorsPass(t *testing.T) {
+	results, err := VerifyVectors(filepath.Join("..", "..", "test_vectors", "relationship_attr_vectors.json"))
+	if err != nil {
+		t.Fatalf("relationship attribute vectors should pass: %v", err)
+	}
+	if len(results) != 10 {
+		t.Errorf("expected 10 results, got %d", len(results))
+	}
+}
+
+func TestSchemaV2RequiresOptIn(t *testing.T) {
+	vf, err := LoadVectors(filepath.Join("..", "..", "test_vectors", "relationship_attr_vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vf.SchemaVersion = ""
+	results, err := VerifyVectorsFile(vf, Options{})
+	if err == nil {
+		t.Fatal("schema version 2 objects verified in a file that does not declare schema_version 2")
+	}
+	if len(results) != 0 {
+		t.Errorf("expected an unusable positive vector, got %d results", len(results))
+	}
+}
+
+func TestKeyPolicyVectorsPass(t *testing.T) {
+	for file, n := range map[string]int{"key_policy_vectors.json": 5, "key_policy_strict_vectors.json": 9} {
+		results, err := VerifyVectors(filepath.Join("..", "..", "test_vectors", file))
+		if err != nil {
+			t.Errorf("%s should pass: %v", file, err)
+			continue
+		}
+		if len(results) != n {
+			t.Errorf("%s: expected %d results, got %d", file, n, len(results))
+		}
+	}
+}
+
+func TestStrictVectorsFailUnderPermissivePolicy(t *testing.T) {
+	vf, err := LoadVectors(filepath.Join("..", "..", "test_vectors", "key_policy_strict_vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vf.KeyPolicy = ""
+	if _, err := VerifyVectorsFile(vf, Options{}); err == nil {
+		t.Error("strict negative vectors passed under the permissive policy")
+	}
+	vf.KeyPolicy = "lenient"
+	if _, err := VerifyVectorsFile(vf, Options{}); err == nil {
+		t.Error("an unknown key policy was accepted")
+	}
+}
+
+func TestParallelResultsKeepFileOrder(t *testing.T) {
+	path := filepath.Join("..", "..", "test_vectors", "vectors.json")
+	sequential, err := VerifyVectors(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parallel, err := VerifyVectorsWithOptions(path, Options{Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parallel) != len(sequential) {
+		t.Fatalf("expected %d results, got %d", len(sequential), len(parallel))
+	}
+	for i := range parallel {
+		if parallel[i].Index != i {
+			t.Errorf("result %d has index %d", i, parallel[i].Index)
+		}
+		if parallel[i].VectorID != sequential[i].VectorID || parallel[i].Got != sequential[i].Got {
+			t.Errorf("result %d differs: parallel %s, sequential %s", i, parallel[i].VectorID, sequential[i].VectorID)
+		}
+	}
+}
+
+func TestResultsReportDurationAndSize(t *testing.T) {
+	vf, err := LoadVectors(filepath.Join("..", "..", "test_vectors", "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := VerifyVectorsFile(vf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if r.Duration <= 0 {
+			t.Errorf("%s: duration %v", r.VectorID, r.Duration)
+		}
+		positive := vf.Vectors[i].VectorType != "negative"
+		if positive && r.Size == 0 {
+			t.Errorf("%s: positive vector has no canonical size", r.VectorID)
+		}
+		if !positive && r.Size != 0 {
+			t.Errorf("%s: rejected vector has canonical size %d", r.VectorID, r.Size)
+		}
+	}
+}
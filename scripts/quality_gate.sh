@@ -12,6 +12,12 @@ go vet ./...
 echo "[quality-gate] Running Go tests"
 go test ./...
 
+echo "[quality-gate] Running Go tests with a 32-bit int"
+GOARCH=386 go test ./...
+
+echo "[quality-gate] Running canonicalizer mutants against the vectors"
+go test -tags mutation -run TestMutants ./internal/verify
+
 echo "[quality-gate] Running Python tests with warnings treated as errors"
 python -m pytest implementations/python/tests -W error -q
 
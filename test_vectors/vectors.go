@@ -0,0 +1,10 @@
+// Package testvectors embeds the frozen conformance vectors, so a helios
+// binary can check its own hashes without a checkout of the repository.
+package testvectors
+
+import _ "embed"
+
+// Vectors is the contents of vectors.json.
+//
+//go:embed vectors.json
+var Vectors []byte